@@ -5,9 +5,14 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"math"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime"
 	"strconv"
+	"strings"
 )
 
 const (
@@ -21,6 +26,20 @@ const (
 	DefaultIdleTimeoutSeconds     = 120
 	DefaultShutdownTimeoutSeconds = 30
 
+	// 目录监听设置
+	DefaultWatchDebounceMillis      = 2000
+	DefaultWatchPollIntervalSeconds = 300
+
+	// DefaultLibraryName 是只配置了 directory 时使用的音乐库名称，与 models.DefaultLibraryName 一致
+	DefaultLibraryName = "default"
+
+	// 歌曲 ID 别名保留天数
+	DefaultIDAliasRetentionDays = 90
+
+	// 回收站设置
+	DefaultTrashDirectory     = "data/trash"
+	DefaultTrashRetentionDays = 30
+
 	// JWT 设置
 	DefaultJWTSecret      = "zero-music-secret-key-please-change-in-production"
 	DefaultJWTExpireHours = 24 * 7 // 7 天
@@ -30,11 +49,32 @@ const (
 	DefaultSearchLimit = 50
 	MaxSearchLimit     = 100
 
+	// 封面设置
+	DefaultCoverCacheDirectory = "data/covers"
+	DefaultMaxThumbnailSize    = 1024
+	MinAllowedThumbnailSize    = 16
+	MaxAllowedThumbnailSize    = 4096
+
 	// 约束
 	MaxAllowedRangeSize              = 500 * 1024 * 1024
 	MaxAllowedCacheTTL               = 1440
 	MaxAllowedTimeoutSeconds         = 600
 	MaxAllowedShutdownTimeoutSeconds = 300
+	MaxAllowedWatchDebounceMillis    = 60000
+	MaxAllowedWatchPollSeconds       = 86400
+	MaxAllowedScanConcurrency        = 64
+	MaxAllowedIDAliasRetentionDays   = 3650
+	MaxAllowedTrashRetentionDays     = 3650
+	MaxAllowedUploadFileSizeMB       = 10240
+	MaxAllowedUploadSessionHours     = 720
+)
+
+const (
+	// 上传设置
+	DefaultUploadPathTemplate  = "{albumartist}/{album}/{track} {title}"
+	DefaultUploadMaxFileSizeMB = 1024
+	DefaultUploadStagingDir    = "data/uploads"
+	DefaultUploadSessionHours  = 24
 )
 
 // Config 定义了应用程序的所有配置项。
@@ -44,6 +84,8 @@ type Config struct {
 	Auth     AuthConfig     `json:"auth"`
 	Database DatabaseConfig `json:"database"`
 	Search   SearchConfig   `json:"search"`
+	Cover    CoverConfig    `json:"cover"`
+	Upload   UploadConfig   `json:"upload"`
 }
 
 // ServerConfig 定义了服务器相关的配置。
@@ -55,13 +97,42 @@ type ServerConfig struct {
 	WriteTimeoutSeconds    int    `json:"write_timeout_seconds"`
 	IdleTimeoutSeconds     int    `json:"idle_timeout_seconds"`
 	ShutdownTimeoutSeconds int    `json:"shutdown_timeout_seconds"`
+	// PublicURL 是客户端访问服务的基础地址（如 https://music.example.com），用于生成导出播放列表中的音频流地址。
+	// 为空时根据请求推断。
+	PublicURL string `json:"public_url,omitempty"`
+}
+
+// LibraryConfig 定义了一个命名的音乐库根目录。
+type LibraryConfig struct {
+	// Name 是音乐库名称，用于 API 中的 library 筛选参数。
+	Name string `json:"name"`
+	// Directory 是音乐库的根目录。
+	Directory string `json:"directory"`
 }
 
 // MusicConfig 定义了音乐库相关的配置。
 type MusicConfig struct {
-	Directory        string   `json:"directory"`
-	SupportedFormats []string `json:"supported_formats"`
-	CacheTTLMinutes  int      `json:"cache_ttl_minutes"`
+	// Directory 是单个音乐库的根目录。配置了 Libraries 时以 Libraries 为准。
+	Directory string `json:"directory"`
+	// Libraries 是多个命名的音乐库，例如分别存放在不同磁盘上的无损、有损和有声书收藏。
+	Libraries        []LibraryConfig `json:"libraries,omitempty"`
+	SupportedFormats []string        `json:"supported_formats"`
+	CacheTTLMinutes  int             `json:"cache_ttl_minutes"`
+	// WatchDebounceMillis 是目录监听合并一批文件事件的防抖时间（毫秒）。
+	WatchDebounceMillis int `json:"watch_debounce_millis"`
+	// WatchPollIntervalSeconds 是无法创建目录监听时退化为轮询扫描的间隔（秒）。
+	WatchPollIntervalSeconds int `json:"watch_poll_interval_seconds"`
+	// ScanConcurrency 是扫描时并发解析元数据的工作协程数量，默认为 CPU 核心数。
+	ScanConcurrency int `json:"scan_concurrency"`
+	// IDAliasRetentionDays 是歌曲文件被重命名或移动后旧 ID 继续可用的天数，通过 API 移动的歌曲的旧 ID 永久可用。
+	IDAliasRetentionDays int `json:"id_alias_retention_days"`
+	// TrashDirectory 是通过 API 删除的歌曲文件所在的回收站目录。
+	TrashDirectory string `json:"trash_directory"`
+	// TrashRetentionDays 是删除的歌曲文件在回收站中保留的天数，过期后被彻底删除。
+	TrashRetentionDays int `json:"trash_retention_days"`
+	// ArtistSeparators 是拆分艺术家标签的分隔符（如 "A feat. B"、"A; B"），不区分大小写。
+	// 以字母开头或结尾的分隔符（如 feat.）两侧必须有空白，避免拆开艺术家名称中的单词。
+	ArtistSeparators []string `json:"artist_separators"`
 }
 
 // AuthConfig 定义了认证相关的配置。
@@ -69,6 +140,8 @@ type AuthConfig struct {
 	JWTSecret      string `json:"jwt_secret"`
 	JWTExpireHours int    `json:"jwt_expire_hours"`
 	AllowRegister  bool   `json:"allow_register"`
+	// RoleCapabilities 是授予各角色的权限（如 {"user": ["upload"]}），管理员始终拥有所有权限。
+	RoleCapabilities map[string][]string `json:"role_capabilities,omitempty"`
 }
 
 // DatabaseConfig 定义了数据库相关的配置。
@@ -85,6 +158,50 @@ type SearchConfig struct {
 	MaxLimit     int `json:"max_limit"`
 }
 
+// CoverConfig 定义了封面图片相关的配置。
+type CoverConfig struct {
+	// CacheDirectory 是缩略图的磁盘缓存目录。
+	CacheDirectory string `json:"cache_directory"`
+	// MaxThumbnailSize 是 ?size= 参数允许请求的最大缩略图边长（像素）。
+	MaxThumbnailSize int `json:"max_thumbnail_size"`
+	// FilePatterns 是歌曲所在目录中封面文件的文件名模式（不区分大小写），按优先级排列。
+	// 仅在音频文件没有嵌入封面时使用。
+	FilePatterns []string `json:"file_patterns"`
+	// ArtistImagePatterns 是艺术家目录中艺术家图片的文件名模式（不区分大小写），按优先级排列。
+	ArtistImagePatterns []string `json:"artist_image_patterns"`
+}
+
+// UploadConfig 定义了通过 HTTP 上传歌曲的配置。
+type UploadConfig struct {
+	// Library 是上传的歌曲存放的音乐库名称，默认为第一个音乐库。
+	Library string `json:"library"`
+	// PathTemplate 是上传的歌曲在音乐库中的存放路径模板（不含扩展名），如 "{albumartist}/{album}/{track} {title}"。
+	PathTemplate string `json:"path_template"`
+	// MaxFileSizeMB 是单个上传文件（包括 zip 压缩包）的最大大小（MB）。
+	MaxFileSizeMB int `json:"max_file_size_mb"`
+	// DefaultQuotaMB 是每个用户默认可以上传的总大小（MB），0 表示不限制。管理员可以为用户单独设置配额。
+	DefaultQuotaMB int `json:"default_quota_mb"`
+	// StagingDirectory 是断点续传（tus 协议）上传过程中暂存已接收数据的目录。
+	StagingDirectory string `json:"staging_directory"`
+	// SessionExpiryHours 是断点续传会话在最后一次接收数据后保留的小时数，过期后已接收的数据被清理。
+	SessionExpiryHours int `json:"session_expiry_hours"`
+}
+
+// DefaultCoverFilePatterns 返回默认的封面文件名模式。
+func DefaultCoverFilePatterns() []string {
+	return []string{"cover.*", "folder.*", "front.*", "album.*"}
+}
+
+// DefaultArtistSeparators 返回默认的艺术家分隔符。"/" 和 "&" 常见于艺术家名称本身（如 AC/DC），默认不作为分隔符。
+func DefaultArtistSeparators() []string {
+	return []string{";", "feat.", "ft.", "featuring", "、"}
+}
+
+// DefaultArtistImagePatterns 返回默认的艺术家图片文件名模式。
+func DefaultArtistImagePatterns() []string {
+	return []string{"artist.*"}
+}
+
 // Load 从指定路径加载配置文件，如果为空则返回默认配置。
 func Load(configPath string) (*Config, error) {
 	var cfg *Config
@@ -103,7 +220,7 @@ func Load(configPath string) (*Config, error) {
 	}
 
 	applyEnvOverrides(cfg)
-	cfg.Music.Directory = ensureAbsolutePath(cfg.Music.Directory)
+	resolveLibraries(cfg)
 
 	if err := validateConfig(cfg); err != nil {
 		return nil, fmt.Errorf("配置验证失败: %w", err)
@@ -141,9 +258,30 @@ func ensureDefaults(cfg *Config) {
 	if cfg.Music.CacheTTLMinutes <= 0 {
 		cfg.Music.CacheTTLMinutes = DefaultCacheTTLMinutes
 	}
-	if cfg.Music.Directory == "" {
+	if cfg.Music.Directory == "" && len(cfg.Music.Libraries) == 0 {
 		cfg.Music.Directory = determineDefaultMusicDirectory()
 	}
+	if cfg.Music.WatchDebounceMillis <= 0 {
+		cfg.Music.WatchDebounceMillis = DefaultWatchDebounceMillis
+	}
+	if cfg.Music.WatchPollIntervalSeconds <= 0 {
+		cfg.Music.WatchPollIntervalSeconds = DefaultWatchPollIntervalSeconds
+	}
+	if cfg.Music.ScanConcurrency <= 0 {
+		cfg.Music.ScanConcurrency = defaultScanConcurrency()
+	}
+	if cfg.Music.IDAliasRetentionDays <= 0 {
+		cfg.Music.IDAliasRetentionDays = DefaultIDAliasRetentionDays
+	}
+	if cfg.Music.TrashDirectory == "" {
+		cfg.Music.TrashDirectory = DefaultTrashDirectory
+	}
+	if cfg.Music.TrashRetentionDays <= 0 {
+		cfg.Music.TrashRetentionDays = DefaultTrashRetentionDays
+	}
+	if cfg.Music.ArtistSeparators == nil {
+		cfg.Music.ArtistSeparators = DefaultArtistSeparators()
+	}
 	// Auth 默认值
 	if cfg.Auth.JWTSecret == "" {
 		cfg.Auth.JWTSecret = DefaultJWTSecret
@@ -165,6 +303,32 @@ func ensureDefaults(cfg *Config) {
 	if cfg.Search.MaxLimit <= 0 {
 		cfg.Search.MaxLimit = MaxSearchLimit
 	}
+	// Cover 默认值
+	if cfg.Cover.CacheDirectory == "" {
+		cfg.Cover.CacheDirectory = DefaultCoverCacheDirectory
+	}
+	if cfg.Cover.MaxThumbnailSize <= 0 {
+		cfg.Cover.MaxThumbnailSize = DefaultMaxThumbnailSize
+	}
+	if cfg.Cover.FilePatterns == nil {
+		cfg.Cover.FilePatterns = DefaultCoverFilePatterns()
+	}
+	if cfg.Cover.ArtistImagePatterns == nil {
+		cfg.Cover.ArtistImagePatterns = DefaultArtistImagePatterns()
+	}
+	// Upload 默认值
+	if strings.TrimSpace(cfg.Upload.PathTemplate) == "" {
+		cfg.Upload.PathTemplate = DefaultUploadPathTemplate
+	}
+	if cfg.Upload.MaxFileSizeMB <= 0 {
+		cfg.Upload.MaxFileSizeMB = DefaultUploadMaxFileSizeMB
+	}
+	if cfg.Upload.StagingDirectory == "" {
+		cfg.Upload.StagingDirectory = DefaultUploadStagingDir
+	}
+	if cfg.Upload.SessionExpiryHours <= 0 {
+		cfg.Upload.SessionExpiryHours = DefaultUploadSessionHours
+	}
 }
 
 // applyEnvOverrides 使用环境变量覆盖配置。
@@ -190,6 +354,9 @@ func applyEnvOverrides(cfg *Config) {
 	if shutdownTimeout := parseEnvInt("ZERO_MUSIC_SERVER_SHUTDOWN_TIMEOUT_SECONDS", 1, MaxAllowedShutdownTimeoutSeconds); shutdownTimeout != nil {
 		cfg.Server.ShutdownTimeoutSeconds = *shutdownTimeout
 	}
+	if publicURL := os.Getenv("ZERO_MUSIC_SERVER_PUBLIC_URL"); publicURL != "" {
+		cfg.Server.PublicURL = publicURL
+	}
 
 	if musicDir := os.Getenv("ZERO_MUSIC_MUSIC_DIRECTORY"); musicDir != "" {
 		cfg.Music.Directory = ensureAbsolutePath(musicDir)
@@ -197,6 +364,27 @@ func applyEnvOverrides(cfg *Config) {
 	if cacheTTL := parseEnvInt("ZERO_MUSIC_CACHE_TTL_MINUTES", 1, MaxAllowedCacheTTL); cacheTTL != nil {
 		cfg.Music.CacheTTLMinutes = *cacheTTL
 	}
+	if debounce := parseEnvInt("ZERO_MUSIC_WATCH_DEBOUNCE_MILLIS", 1, MaxAllowedWatchDebounceMillis); debounce != nil {
+		cfg.Music.WatchDebounceMillis = *debounce
+	}
+	if pollInterval := parseEnvInt("ZERO_MUSIC_WATCH_POLL_INTERVAL_SECONDS", 1, MaxAllowedWatchPollSeconds); pollInterval != nil {
+		cfg.Music.WatchPollIntervalSeconds = *pollInterval
+	}
+	if concurrency := parseEnvInt("ZERO_MUSIC_SCAN_CONCURRENCY", 1, MaxAllowedScanConcurrency); concurrency != nil {
+		cfg.Music.ScanConcurrency = *concurrency
+	}
+	if retention := parseEnvInt("ZERO_MUSIC_ID_ALIAS_RETENTION_DAYS", 1, MaxAllowedIDAliasRetentionDays); retention != nil {
+		cfg.Music.IDAliasRetentionDays = *retention
+	}
+	if trashDir := os.Getenv("ZERO_MUSIC_TRASH_DIRECTORY"); trashDir != "" {
+		cfg.Music.TrashDirectory = trashDir
+	}
+	if retention := parseEnvInt("ZERO_MUSIC_TRASH_RETENTION_DAYS", 1, MaxAllowedTrashRetentionDays); retention != nil {
+		cfg.Music.TrashRetentionDays = *retention
+	}
+	if separators := parseEnvList("ZERO_MUSIC_ARTIST_SEPARATORS"); separators != nil {
+		cfg.Music.ArtistSeparators = separators
+	}
 
 	// Auth 环境变量覆盖
 	if jwtSecret := os.Getenv("ZERO_MUSIC_JWT_SECRET"); jwtSecret != "" {
@@ -208,11 +396,66 @@ func applyEnvOverrides(cfg *Config) {
 	if allowRegister := os.Getenv("ZERO_MUSIC_ALLOW_REGISTER"); allowRegister != "" {
 		cfg.Auth.AllowRegister = allowRegister == "true" || allowRegister == "1"
 	}
+	if capabilities := parseEnvList("ZERO_MUSIC_USER_CAPABILITIES"); capabilities != nil {
+		if cfg.Auth.RoleCapabilities == nil {
+			cfg.Auth.RoleCapabilities = make(map[string][]string)
+		}
+		cfg.Auth.RoleCapabilities["user"] = capabilities
+	}
 
 	// Database 环境变量覆盖
 	if dbPath := os.Getenv("ZERO_MUSIC_DATABASE_PATH"); dbPath != "" {
 		cfg.Database.Path = dbPath
 	}
+
+	// Cover 环境变量覆盖
+	if coverCacheDir := os.Getenv("ZERO_MUSIC_COVER_CACHE_DIRECTORY"); coverCacheDir != "" {
+		cfg.Cover.CacheDirectory = coverCacheDir
+	}
+	if maxThumb := parseEnvInt("ZERO_MUSIC_MAX_THUMBNAIL_SIZE", MinAllowedThumbnailSize, MaxAllowedThumbnailSize); maxThumb != nil {
+		cfg.Cover.MaxThumbnailSize = *maxThumb
+	}
+	if patterns := parseEnvList("ZERO_MUSIC_COVER_FILE_PATTERNS"); patterns != nil {
+		cfg.Cover.FilePatterns = patterns
+	}
+	if patterns := parseEnvList("ZERO_MUSIC_ARTIST_IMAGE_PATTERNS"); patterns != nil {
+		cfg.Cover.ArtistImagePatterns = patterns
+	}
+
+	// Upload 环境变量覆盖
+	if library := os.Getenv("ZERO_MUSIC_UPLOAD_LIBRARY"); library != "" {
+		cfg.Upload.Library = library
+	}
+	if template := os.Getenv("ZERO_MUSIC_UPLOAD_PATH_TEMPLATE"); template != "" {
+		cfg.Upload.PathTemplate = template
+	}
+	if maxSize := parseEnvInt("ZERO_MUSIC_UPLOAD_MAX_FILE_SIZE_MB", 1, MaxAllowedUploadFileSizeMB); maxSize != nil {
+		cfg.Upload.MaxFileSizeMB = *maxSize
+	}
+	if quota := parseEnvInt("ZERO_MUSIC_UPLOAD_DEFAULT_QUOTA_MB", 0, math.MaxInt32); quota != nil {
+		cfg.Upload.DefaultQuotaMB = *quota
+	}
+	if stagingDir := os.Getenv("ZERO_MUSIC_UPLOAD_STAGING_DIRECTORY"); stagingDir != "" {
+		cfg.Upload.StagingDirectory = stagingDir
+	}
+	if expiry := parseEnvInt("ZERO_MUSIC_UPLOAD_SESSION_EXPIRY_HOURS", 1, MaxAllowedUploadSessionHours); expiry != nil {
+		cfg.Upload.SessionExpiryHours = *expiry
+	}
+}
+
+// parseEnvList 解析以逗号分隔的环境变量，未设置时返回 nil。
+func parseEnvList(key string) []string {
+	raw, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	values := []string{}
+	for _, value := range strings.Split(raw, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
 }
 
 func parseEnvInt(key string, min, max int) *int {
@@ -265,18 +508,147 @@ func validateConfig(cfg *Config) error {
 	if cfg.Server.ShutdownTimeoutSeconds < 1 || cfg.Server.ShutdownTimeoutSeconds > MaxAllowedShutdownTimeoutSeconds {
 		return fmt.Errorf("ShutdownTimeoutSeconds 必须在 1-%d 范围内", MaxAllowedShutdownTimeoutSeconds)
 	}
+	if cfg.Server.PublicURL != "" {
+		publicURL, err := url.Parse(cfg.Server.PublicURL)
+		if err != nil || (publicURL.Scheme != "http" && publicURL.Scheme != "https") || publicURL.Host == "" ||
+			publicURL.User != nil || publicURL.RawQuery != "" || publicURL.Fragment != "" {
+			return fmt.Errorf("PublicURL 必须是 http 或 https 的绝对地址，当前值: %s", cfg.Server.PublicURL)
+		}
+		cfg.Server.PublicURL = strings.TrimSuffix(cfg.Server.PublicURL, "/")
+	}
 	if cfg.Music.CacheTTLMinutes < 1 || cfg.Music.CacheTTLMinutes > MaxAllowedCacheTTL {
 		return fmt.Errorf("CacheTTLMinutes 必须在 1-%d 范围内，当前值: %d", MaxAllowedCacheTTL, cfg.Music.CacheTTLMinutes)
 	}
+	if cfg.Music.WatchDebounceMillis < 1 || cfg.Music.WatchDebounceMillis > MaxAllowedWatchDebounceMillis {
+		return fmt.Errorf("WatchDebounceMillis 必须在 1-%d 范围内，当前值: %d", MaxAllowedWatchDebounceMillis, cfg.Music.WatchDebounceMillis)
+	}
+	if cfg.Music.WatchPollIntervalSeconds < 1 || cfg.Music.WatchPollIntervalSeconds > MaxAllowedWatchPollSeconds {
+		return fmt.Errorf("WatchPollIntervalSeconds 必须在 1-%d 范围内，当前值: %d", MaxAllowedWatchPollSeconds, cfg.Music.WatchPollIntervalSeconds)
+	}
+	if cfg.Music.ScanConcurrency < 1 || cfg.Music.ScanConcurrency > MaxAllowedScanConcurrency {
+		return fmt.Errorf("ScanConcurrency 必须在 1-%d 范围内，当前值: %d", MaxAllowedScanConcurrency, cfg.Music.ScanConcurrency)
+	}
+	if cfg.Music.IDAliasRetentionDays < 1 || cfg.Music.IDAliasRetentionDays > MaxAllowedIDAliasRetentionDays {
+		return fmt.Errorf("IDAliasRetentionDays 必须在 1-%d 范围内，当前值: %d", MaxAllowedIDAliasRetentionDays, cfg.Music.IDAliasRetentionDays)
+	}
+	if cfg.Music.TrashRetentionDays < 1 || cfg.Music.TrashRetentionDays > MaxAllowedTrashRetentionDays {
+		return fmt.Errorf("TrashRetentionDays 必须在 1-%d 范围内，当前值: %d", MaxAllowedTrashRetentionDays, cfg.Music.TrashRetentionDays)
+	}
+	for _, separator := range cfg.Music.ArtistSeparators {
+		if strings.TrimSpace(separator) == "" {
+			return fmt.Errorf("艺术家分隔符不能为空")
+		}
+	}
+	if cfg.Cover.MaxThumbnailSize < MinAllowedThumbnailSize || cfg.Cover.MaxThumbnailSize > MaxAllowedThumbnailSize {
+		return fmt.Errorf("MaxThumbnailSize 必须在 %d-%d 范围内，当前值: %d", MinAllowedThumbnailSize, MaxAllowedThumbnailSize, cfg.Cover.MaxThumbnailSize)
+	}
+	for _, pattern := range append(append([]string{}, cfg.Cover.FilePatterns...), cfg.Cover.ArtistImagePatterns...) {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("图片文件名模式无效: %q", pattern)
+		}
+	}
 	if cfg.Music.Directory == "" {
 		return fmt.Errorf("音乐目录不能为空")
 	}
 	if _, err := os.Stat(cfg.Music.Directory); err != nil {
 		return fmt.Errorf("音乐目录不可访问: %w", err)
 	}
+	if err := validateLibraries(cfg.Music.Libraries); err != nil {
+		return err
+	}
+	if err := validateUpload(&cfg.Upload, cfg.Music.LibraryList()); err != nil {
+		return err
+	}
+	return nil
+}
+
+// validateUpload 验证上传配置，未指定音乐库时使用第一个音乐库。路径模板的占位符由上传服务解析时检查。
+func validateUpload(upload *UploadConfig, libraries []LibraryConfig) error {
+	if upload.MaxFileSizeMB < 1 || upload.MaxFileSizeMB > MaxAllowedUploadFileSizeMB {
+		return fmt.Errorf("上传文件大小上限必须在 1-%d MB 范围内，当前值: %d", MaxAllowedUploadFileSizeMB, upload.MaxFileSizeMB)
+	}
+	if upload.DefaultQuotaMB < 0 {
+		return fmt.Errorf("默认上传配额不能为负数，当前值: %d", upload.DefaultQuotaMB)
+	}
+	if upload.SessionExpiryHours < 1 || upload.SessionExpiryHours > MaxAllowedUploadSessionHours {
+		return fmt.Errorf("上传会话保留时间必须在 1-%d 小时范围内，当前值: %d", MaxAllowedUploadSessionHours, upload.SessionExpiryHours)
+	}
+	upload.Library = strings.TrimSpace(upload.Library)
+	if upload.Library == "" {
+		upload.Library = libraries[0].Name
+		return nil
+	}
+	for _, lib := range libraries {
+		if lib.Name == upload.Library {
+			return nil
+		}
+	}
+	return fmt.Errorf("上传目标音乐库不存在: %s", upload.Library)
+}
+
+// libraryNameRegex 限制音乐库名称只包含字母、数字、下划线和连字符，便于在查询参数中使用。
+var libraryNameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// validateLibraries 验证音乐库名称唯一、目录可访问且互不嵌套。
+func validateLibraries(libraries []LibraryConfig) error {
+	seen := make(map[string]bool, len(libraries))
+	for i, lib := range libraries {
+		if !libraryNameRegex.MatchString(lib.Name) {
+			return fmt.Errorf("音乐库名称无效: %q（只能包含字母、数字、下划线和连字符，长度 1-64）", lib.Name)
+		}
+		if seen[lib.Name] {
+			return fmt.Errorf("音乐库名称重复: %s", lib.Name)
+		}
+		seen[lib.Name] = true
+
+		if lib.Directory == "" {
+			return fmt.Errorf("音乐库 %s 的目录不能为空", lib.Name)
+		}
+		if _, err := os.Stat(lib.Directory); err != nil {
+			return fmt.Errorf("音乐库 %s 的目录不可访问: %w", lib.Name, err)
+		}
+
+		// 嵌套的库会导致同一文件被索引两次
+		for _, other := range libraries[:i] {
+			if isSubPath(other.Directory, lib.Directory) || isSubPath(lib.Directory, other.Directory) {
+				return fmt.Errorf("音乐库 %s 与 %s 的目录相互嵌套", lib.Name, other.Name)
+			}
+		}
+	}
 	return nil
 }
 
+// isSubPath 判断 path 是否等于 root 或位于 root 之下。
+func isSubPath(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// resolveLibraries 将音乐库目录转换为绝对路径。未配置 Libraries 时以 Directory 作为默认库；
+// 配置了 Libraries 时 Directory 同步为第一个库的目录，供只关心单一目录的功能（如健康检查）使用。
+func resolveLibraries(cfg *Config) {
+	cfg.Music.Directory = ensureAbsolutePath(cfg.Music.Directory)
+	if len(cfg.Music.Libraries) == 0 {
+		if cfg.Music.Directory != "" {
+			cfg.Music.Libraries = []LibraryConfig{{Name: DefaultLibraryName, Directory: cfg.Music.Directory}}
+		}
+		return
+	}
+	for i := range cfg.Music.Libraries {
+		cfg.Music.Libraries[i].Name = strings.TrimSpace(cfg.Music.Libraries[i].Name)
+		cfg.Music.Libraries[i].Directory = ensureAbsolutePath(cfg.Music.Libraries[i].Directory)
+	}
+	cfg.Music.Directory = cfg.Music.Libraries[0].Directory
+}
+
+// LibraryList 返回配置的音乐库列表。未配置 Libraries 时返回以 Directory 为根目录的默认库。
+func (m *MusicConfig) LibraryList() []LibraryConfig {
+	if len(m.Libraries) > 0 {
+		return m.Libraries
+	}
+	return []LibraryConfig{{Name: DefaultLibraryName, Directory: m.Directory}}
+}
+
 // ensureAbsolutePath 将路径转换为绝对路径。
 func ensureAbsolutePath(path string) string {
 	if path == "" {
@@ -320,6 +692,15 @@ func determineDefaultMusicDirectory() string {
 	return ensureAbsolutePath(candidates[len(candidates)-1])
 }
 
+// defaultScanConcurrency 返回默认的扫描并发数（CPU 核心数，不超过允许的上限）。
+func defaultScanConcurrency() int {
+	n := runtime.NumCPU()
+	if n > MaxAllowedScanConcurrency {
+		n = MaxAllowedScanConcurrency
+	}
+	return n
+}
+
 // GetDefaultConfig 返回包含默认设置的配置实例。
 func GetDefaultConfig() *Config {
 	cfg := &Config{
@@ -333,9 +714,16 @@ func GetDefaultConfig() *Config {
 			ShutdownTimeoutSeconds: DefaultShutdownTimeoutSeconds,
 		},
 		Music: MusicConfig{
-			Directory:        determineDefaultMusicDirectory(),
-			SupportedFormats: []string{".mp3", ".flac", ".wav", ".m4a", ".ogg"},
-			CacheTTLMinutes:  DefaultCacheTTLMinutes,
+			Directory:                determineDefaultMusicDirectory(),
+			SupportedFormats:         []string{".mp3", ".flac", ".wav", ".m4a", ".ogg"},
+			CacheTTLMinutes:          DefaultCacheTTLMinutes,
+			WatchDebounceMillis:      DefaultWatchDebounceMillis,
+			WatchPollIntervalSeconds: DefaultWatchPollIntervalSeconds,
+			ScanConcurrency:          defaultScanConcurrency(),
+			IDAliasRetentionDays:     DefaultIDAliasRetentionDays,
+			TrashDirectory:           DefaultTrashDirectory,
+			TrashRetentionDays:       DefaultTrashRetentionDays,
+			ArtistSeparators:         DefaultArtistSeparators(),
 		},
 		Auth: AuthConfig{
 			JWTSecret:      DefaultJWTSecret,
@@ -350,6 +738,18 @@ func GetDefaultConfig() *Config {
 			DefaultLimit: DefaultSearchLimit,
 			MaxLimit:     MaxSearchLimit,
 		},
+		Cover: CoverConfig{
+			CacheDirectory:      DefaultCoverCacheDirectory,
+			MaxThumbnailSize:    DefaultMaxThumbnailSize,
+			FilePatterns:        DefaultCoverFilePatterns(),
+			ArtistImagePatterns: DefaultArtistImagePatterns(),
+		},
+		Upload: UploadConfig{
+			PathTemplate:       DefaultUploadPathTemplate,
+			MaxFileSizeMB:      DefaultUploadMaxFileSizeMB,
+			StagingDirectory:   DefaultUploadStagingDir,
+			SessionExpiryHours: DefaultUploadSessionHours,
+		},
 	}
 	return cfg
 }
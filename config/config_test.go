@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -44,6 +45,13 @@ func TestLoadAppliesEnvOverrides(t *testing.T) {
 	t.Setenv("ZERO_MUSIC_MAX_RANGE_SIZE", "2048")
 	t.Setenv("ZERO_MUSIC_CACHE_TTL_MINUTES", "30")
 	t.Setenv("ZERO_MUSIC_MUSIC_DIRECTORY", musicDir)
+	t.Setenv("ZERO_MUSIC_WATCH_DEBOUNCE_MILLIS", "500")
+	t.Setenv("ZERO_MUSIC_WATCH_POLL_INTERVAL_SECONDS", "60")
+	t.Setenv("ZERO_MUSIC_SCAN_CONCURRENCY", "3")
+	t.Setenv("ZERO_MUSIC_ID_ALIAS_RETENTION_DAYS", "30")
+	t.Setenv("ZERO_MUSIC_TRASH_DIRECTORY", "/tmp/trash")
+	t.Setenv("ZERO_MUSIC_TRASH_RETENTION_DAYS", "7")
+	t.Setenv("ZERO_MUSIC_ARTIST_SEPARATORS", ";, feat. ,/")
 
 	cfg, err := Load(cfgPath)
 	if err != nil {
@@ -71,6 +79,44 @@ func TestLoadAppliesEnvOverrides(t *testing.T) {
 	if cfg.Music.CacheTTLMinutes != 30 {
 		t.Fatalf("期望 CacheTTLMinutes=30, 实际 %d", cfg.Music.CacheTTLMinutes)
 	}
+	if cfg.Music.WatchDebounceMillis != 500 {
+		t.Fatalf("期望 WatchDebounceMillis=500, 实际 %d", cfg.Music.WatchDebounceMillis)
+	}
+	if cfg.Music.WatchPollIntervalSeconds != 60 {
+		t.Fatalf("期望 WatchPollIntervalSeconds=60, 实际 %d", cfg.Music.WatchPollIntervalSeconds)
+	}
+	if cfg.Music.ScanConcurrency != 3 {
+		t.Fatalf("期望 ScanConcurrency=3, 实际 %d", cfg.Music.ScanConcurrency)
+	}
+	if cfg.Music.IDAliasRetentionDays != 30 {
+		t.Fatalf("期望 IDAliasRetentionDays=30, 实际 %d", cfg.Music.IDAliasRetentionDays)
+	}
+	if cfg.Music.TrashDirectory != "/tmp/trash" || cfg.Music.TrashRetentionDays != 7 {
+		t.Fatalf("期望回收站配置被环境变量覆盖, 实际 %s %d", cfg.Music.TrashDirectory, cfg.Music.TrashRetentionDays)
+	}
+	if got := strings.Join(cfg.Music.ArtistSeparators, "|"); got != ";|feat.|/" {
+		t.Fatalf("期望 ArtistSeparators=;|feat.|/, 实际 %s", got)
+	}
+}
+
+func TestLoadPublicURL(t *testing.T) {
+	cfgPath := writeConfigFile(t, &Config{Music: MusicConfig{Directory: t.TempDir()}})
+
+	t.Setenv("ZERO_MUSIC_SERVER_PUBLIC_URL", "https://music.example.com/zero/")
+	cfg, err := Load(cfgPath)
+	if err != nil {
+		t.Fatalf("期望加载成功, 但出现错误: %v", err)
+	}
+	if cfg.Server.PublicURL != "https://music.example.com/zero" {
+		t.Fatalf("期望 PublicURL 去掉末尾的 /, 实际 %s", cfg.Server.PublicURL)
+	}
+
+	for _, publicURL := range []string{"javascript:alert(1)", "ftp://music.example.com", "music.example.com", "https://music.example.com/?a=1"} {
+		t.Setenv("ZERO_MUSIC_SERVER_PUBLIC_URL", publicURL)
+		if _, err := Load(cfgPath); err == nil {
+			t.Errorf("%s: 无效的 PublicURL 应返回错误", publicURL)
+		}
+	}
 }
 
 func TestLoadRejectsInvalidPort(t *testing.T) {
@@ -92,3 +138,108 @@ func TestLoadRejectsInvalidPort(t *testing.T) {
 		t.Fatal("端口超过范围时应返回错误")
 	}
 }
+
+func TestLoadDefaultsToSingleLibrary(t *testing.T) {
+	musicDir := t.TempDir()
+	cfgPath := writeConfigFile(t, &Config{
+		Music: MusicConfig{Directory: musicDir},
+	})
+
+	cfg, err := Load(cfgPath)
+	if err != nil {
+		t.Fatalf("期望加载成功, 但出现错误: %v", err)
+	}
+	if len(cfg.Music.Libraries) != 1 || cfg.Music.Libraries[0].Name != DefaultLibraryName || cfg.Music.Libraries[0].Directory != musicDir {
+		t.Fatalf("期望以 directory 作为默认库, 实际 %+v", cfg.Music.Libraries)
+	}
+}
+
+func TestLoadMultipleLibraries(t *testing.T) {
+	lossless := t.TempDir()
+	lossy := t.TempDir()
+	cfgPath := writeConfigFile(t, &Config{
+		Music: MusicConfig{
+			Libraries: []LibraryConfig{
+				{Name: "lossless", Directory: lossless},
+				{Name: "lossy", Directory: lossy},
+			},
+		},
+	})
+
+	cfg, err := Load(cfgPath)
+	if err != nil {
+		t.Fatalf("期望加载成功, 但出现错误: %v", err)
+	}
+	if len(cfg.Music.LibraryList()) != 2 {
+		t.Fatalf("期望 2 个音乐库, 实际 %+v", cfg.Music.Libraries)
+	}
+	if cfg.Music.Directory != lossless {
+		t.Fatalf("期望 Directory 同步为第一个库的目录, 实际 %s", cfg.Music.Directory)
+	}
+}
+
+func TestLoadRejectsInvalidLibraries(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "nested")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	other := t.TempDir()
+
+	testCases := map[string][]LibraryConfig{
+		"名称重复":  {{Name: "music", Directory: root}, {Name: "music", Directory: other}},
+		"名称非法":  {{Name: "bad name", Directory: root}},
+		"目录嵌套":  {{Name: "all", Directory: root}, {Name: "part", Directory: nested}},
+		"目录不存在": {{Name: "missing", Directory: filepath.Join(other, "missing")}},
+	}
+	for name, libraries := range testCases {
+		t.Run(name, func(t *testing.T) {
+			cfgPath := writeConfigFile(t, &Config{Music: MusicConfig{Libraries: libraries}})
+			if _, err := Load(cfgPath); err == nil {
+				t.Fatal("期望返回错误")
+			}
+		})
+	}
+}
+
+func TestLoadUploadConfig(t *testing.T) {
+	lossless := t.TempDir()
+	lossy := t.TempDir()
+	libraries := []LibraryConfig{
+		{Name: "lossless", Directory: lossless},
+		{Name: "lossy", Directory: lossy},
+	}
+
+	cfg, err := Load(writeConfigFile(t, &Config{Music: MusicConfig{Libraries: libraries}}))
+	if err != nil {
+		t.Fatalf("期望加载成功, 但出现错误: %v", err)
+	}
+	if cfg.Upload.Library != "lossless" || cfg.Upload.PathTemplate != DefaultUploadPathTemplate || cfg.Upload.MaxFileSizeMB != DefaultUploadMaxFileSizeMB || cfg.Upload.DefaultQuotaMB != 0 ||
+		cfg.Upload.StagingDirectory != DefaultUploadStagingDir || cfg.Upload.SessionExpiryHours != DefaultUploadSessionHours {
+		t.Fatalf("期望上传配置使用默认值和第一个音乐库, 实际 %+v", cfg.Upload)
+	}
+
+	t.Setenv("ZERO_MUSIC_UPLOAD_LIBRARY", "lossy")
+	t.Setenv("ZERO_MUSIC_UPLOAD_PATH_TEMPLATE", "{artist}/{title}")
+	t.Setenv("ZERO_MUSIC_UPLOAD_MAX_FILE_SIZE_MB", "50")
+	t.Setenv("ZERO_MUSIC_UPLOAD_DEFAULT_QUOTA_MB", "200")
+	t.Setenv("ZERO_MUSIC_UPLOAD_STAGING_DIRECTORY", "/tmp/staging")
+	t.Setenv("ZERO_MUSIC_UPLOAD_SESSION_EXPIRY_HOURS", "48")
+	t.Setenv("ZERO_MUSIC_USER_CAPABILITIES", "upload")
+	cfg, err = Load(writeConfigFile(t, &Config{Music: MusicConfig{Libraries: libraries}}))
+	if err != nil {
+		t.Fatalf("期望加载成功, 但出现错误: %v", err)
+	}
+	if cfg.Upload.Library != "lossy" || cfg.Upload.PathTemplate != "{artist}/{title}" || cfg.Upload.MaxFileSizeMB != 50 || cfg.Upload.DefaultQuotaMB != 200 ||
+		cfg.Upload.StagingDirectory != "/tmp/staging" || cfg.Upload.SessionExpiryHours != 48 {
+		t.Fatalf("期望环境变量覆盖上传配置, 实际 %+v", cfg.Upload)
+	}
+	if got := strings.Join(cfg.Auth.RoleCapabilities["user"], ","); got != "upload" {
+		t.Fatalf("期望普通用户被授予 upload 权限, 实际 %q", got)
+	}
+
+	t.Setenv("ZERO_MUSIC_UPLOAD_LIBRARY", "missing")
+	if _, err := Load(writeConfigFile(t, &Config{Music: MusicConfig{Libraries: libraries}})); err == nil {
+		t.Fatal("期望上传目标音乐库不存在时返回错误")
+	}
+}
@@ -7,10 +7,61 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
+// songsTableSchema 是歌曲库表的结构。CUE 整轨文件拆分出的曲目共用同一个 file_path，因此 file_path 不是唯一的。
+const songsTableSchema = `CREATE TABLE IF NOT EXISTS songs (
+	id TEXT PRIMARY KEY,
+	file_path TEXT NOT NULL,
+	file_name TEXT NOT NULL,
+	title TEXT NOT NULL DEFAULT '',
+	artist TEXT NOT NULL DEFAULT '',
+	artists TEXT NOT NULL DEFAULT '',
+	album_artist TEXT NOT NULL DEFAULT '',
+	composer TEXT NOT NULL DEFAULT '',
+	album TEXT NOT NULL DEFAULT '',
+	genre TEXT NOT NULL DEFAULT '',
+	year INTEGER DEFAULT 0,
+	track INTEGER DEFAULT 0,
+	disc INTEGER DEFAULT 0,
+	duration INTEGER DEFAULT 0,
+	duration_estimated BOOLEAN DEFAULT FALSE,
+	codec TEXT NOT NULL DEFAULT '',
+	lossless BOOLEAN DEFAULT FALSE,
+	bitrate INTEGER DEFAULT 0,
+	bitrate_mode TEXT NOT NULL DEFAULT '',
+	sample_rate INTEGER DEFAULT 0,
+	bit_depth INTEGER DEFAULT 0,
+	channels INTEGER DEFAULT 0,
+	track_gain REAL,
+	track_peak REAL,
+	album_gain REAL,
+	album_peak REAL,
+	loudness REAL,
+	loudness_source TEXT NOT NULL DEFAULT '',
+	cue_sheet_path TEXT NOT NULL DEFAULT '',
+	start_ms INTEGER NOT NULL DEFAULT 0,
+	end_ms INTEGER NOT NULL DEFAULT 0,
+	issues TEXT NOT NULL DEFAULT '',
+	file_size INTEGER DEFAULT 0,
+	mod_time INTEGER DEFAULT 0,
+	format TEXT NOT NULL DEFAULT '',
+	has_cover BOOLEAN DEFAULT FALSE,
+	cover_source TEXT NOT NULL DEFAULT '',
+	cover_path TEXT NOT NULL DEFAULT '',
+	artist_image_path TEXT NOT NULL DEFAULT '',
+	has_lyrics BOOLEAN DEFAULT FALSE,
+	embedded_lyrics BOOLEAN DEFAULT FALSE,
+	lyrics_path TEXT NOT NULL DEFAULT '',
+	library TEXT NOT NULL DEFAULT '',
+	rel_path TEXT NOT NULL DEFAULT '',
+	content_hash TEXT NOT NULL DEFAULT '',
+	scanned_at DATETIME DEFAULT CURRENT_TIMESTAMP
+)`
+
 // SQLiteProvider 是 SQLite 数据库的提供者实现。
 type SQLiteProvider struct{}
 
@@ -129,6 +180,97 @@ func (p *SQLiteProvider) Migrate(db DB) error {
 			UNIQUE(playlist_id, song_id),
 			FOREIGN KEY (playlist_id) REFERENCES playlists(id) ON DELETE CASCADE
 		)`,
+		// 歌曲库表（持久化扫描结果，避免每次启动都重新解析全部文件）
+		songsTableSchema,
+		// 专辑表（由 songs 表汇总生成）
+		`CREATE TABLE IF NOT EXISTS albums (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			artist TEXT NOT NULL,
+			year INTEGER DEFAULT 0,
+			song_count INTEGER DEFAULT 0,
+			UNIQUE(name, artist)
+		)`,
+		// 艺术家表（由 songs 表汇总生成）
+		`CREATE TABLE IF NOT EXISTS artists (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT UNIQUE NOT NULL,
+			song_count INTEGER DEFAULT 0
+		)`,
+		// 歌曲 ID 别名表（文件重命名或移动后，旧 ID 在过渡期内继续解析到新 ID；通过 API 移动的歌曲的别名永久保留）
+		`CREATE TABLE IF NOT EXISTS song_id_aliases (
+			old_id TEXT PRIMARY KEY,
+			new_id TEXT NOT NULL,
+			permanent BOOLEAN NOT NULL DEFAULT FALSE,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		// 重复歌曲的首选副本（被隐藏的副本 -> 管理员选定的首选副本）
+		`CREATE TABLE IF NOT EXISTS duplicate_preferences (
+			song_id TEXT PRIMARY KEY,
+			preferred_id TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		// 管理员保存的歌曲元数据覆盖，为 NULL 的字段不覆盖
+		`CREATE TABLE IF NOT EXISTS metadata_overrides (
+			song_id TEXT PRIMARY KEY,
+			title TEXT,
+			artist TEXT,
+			album TEXT,
+			genre TEXT,
+			year INTEGER,
+			cover BLOB,
+			cover_mime TEXT NOT NULL DEFAULT '',
+			cover_hash TEXT NOT NULL DEFAULT '',
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		// 用户上传到音乐库的文件，用于统计上传配额
+		`CREATE TABLE IF NOT EXISTS uploads (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			song_id TEXT NOT NULL,
+			library TEXT NOT NULL,
+			rel_path TEXT NOT NULL,
+			size INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(library, rel_path),
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+		// 管理员为用户单独设置的上传配额，没有记录的用户使用默认配额
+		`CREATE TABLE IF NOT EXISTS user_quotas (
+			user_id INTEGER PRIMARY KEY,
+			quota_bytes INTEGER NOT NULL DEFAULT 0,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+		// 断点续传（tus 协议）的上传会话，已接收的数据暂存在磁盘上
+		`CREATE TABLE IF NOT EXISTS upload_sessions (
+			id TEXT PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			filename TEXT NOT NULL,
+			metadata TEXT NOT NULL DEFAULT '',
+			length INTEGER NOT NULL,
+			upload_offset INTEGER NOT NULL DEFAULT 0,
+			song_ids TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			expires_at DATETIME NOT NULL,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+		// 通过 API 删除的歌曲的墓碑记录，文件在回收站中保留到过期
+		`CREATE TABLE IF NOT EXISTS trashed_songs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			song_id TEXT NOT NULL,
+			library TEXT NOT NULL,
+			rel_path TEXT NOT NULL,
+			title TEXT NOT NULL DEFAULT '',
+			artist TEXT NOT NULL DEFAULT '',
+			album TEXT NOT NULL DEFAULT '',
+			size INTEGER NOT NULL DEFAULT 0,
+			trash_path TEXT NOT NULL,
+			deleted_by INTEGER NOT NULL DEFAULT 0,
+			deleted_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			purged_at DATETIME
+		)`,
 		// 索引
 		`CREATE INDEX IF NOT EXISTS idx_play_history_user_id ON play_history(user_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_play_history_song_id ON play_history(song_id)`,
@@ -138,6 +280,12 @@ func (p *SQLiteProvider) Migrate(db DB) error {
 		`CREATE INDEX IF NOT EXISTS idx_favorites_user_id ON favorites(user_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_playlists_user_id ON playlists(user_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_playlist_songs_playlist_id ON playlist_songs(playlist_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_songs_artist ON songs(artist)`,
+		`CREATE INDEX IF NOT EXISTS idx_songs_album ON songs(album)`,
+		`CREATE INDEX IF NOT EXISTS idx_duplicate_preferences_preferred_id ON duplicate_preferences(preferred_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_uploads_user_id ON uploads(user_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_upload_sessions_expires_at ON upload_sessions(expires_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_trashed_songs_song_id ON trashed_songs(song_id)`,
 	}
 
 	for _, schema := range schemas {
@@ -146,6 +294,143 @@ func (p *SQLiteProvider) Migrate(db DB) error {
 		}
 	}
 
+	// 为早期版本创建的表补充后来新增的列
+	columns := []struct {
+		table, name, definition string
+	}{
+		{"songs", "library", "TEXT NOT NULL DEFAULT ''"},
+		{"songs", "rel_path", "TEXT NOT NULL DEFAULT ''"},
+		{"songs", "content_hash", "TEXT NOT NULL DEFAULT ''"},
+		{"songs", "duration_estimated", "BOOLEAN DEFAULT FALSE"},
+		{"songs", "codec", "TEXT NOT NULL DEFAULT ''"},
+		{"songs", "lossless", "BOOLEAN DEFAULT FALSE"},
+		{"songs", "bitrate", "INTEGER DEFAULT 0"},
+		{"songs", "bitrate_mode", "TEXT NOT NULL DEFAULT ''"},
+		{"songs", "sample_rate", "INTEGER DEFAULT 0"},
+		{"songs", "bit_depth", "INTEGER DEFAULT 0"},
+		{"songs", "channels", "INTEGER DEFAULT 0"},
+		{"songs", "cover_source", "TEXT NOT NULL DEFAULT ''"},
+		{"songs", "cover_path", "TEXT NOT NULL DEFAULT ''"},
+		{"songs", "artist_image_path", "TEXT NOT NULL DEFAULT ''"},
+		{"songs", "has_lyrics", "BOOLEAN DEFAULT FALSE"},
+		{"songs", "embedded_lyrics", "BOOLEAN DEFAULT FALSE"},
+		{"songs", "lyrics_path", "TEXT NOT NULL DEFAULT ''"},
+		{"songs", "artists", "TEXT NOT NULL DEFAULT ''"},
+		{"songs", "album_artist", "TEXT NOT NULL DEFAULT ''"},
+		{"songs", "composer", "TEXT NOT NULL DEFAULT ''"},
+		{"songs", "track_gain", "REAL"},
+		{"songs", "track_peak", "REAL"},
+		{"songs", "album_gain", "REAL"},
+		{"songs", "album_peak", "REAL"},
+		{"songs", "loudness", "REAL"},
+		{"songs", "loudness_source", "TEXT NOT NULL DEFAULT ''"},
+		{"songs", "cue_sheet_path", "TEXT NOT NULL DEFAULT ''"},
+		{"songs", "start_ms", "INTEGER NOT NULL DEFAULT 0"},
+		{"songs", "end_ms", "INTEGER NOT NULL DEFAULT 0"},
+		{"songs", "issues", "TEXT NOT NULL DEFAULT ''"},
+		{"songs", "disc", "INTEGER DEFAULT 0"},
+		{"song_id_aliases", "permanent", "BOOLEAN NOT NULL DEFAULT FALSE"},
+	}
+	for _, col := range columns {
+		if err := addColumnIfMissing(db, col.table, col.name, col.definition); err != nil {
+			return err
+		}
+	}
+
+	return dropSongsFilePathUnique(db)
+}
+
+// dropSongsFilePathUnique 重建早期版本创建的 songs 表，去掉 file_path 上的唯一约束。
+// 有唯一约束时，保存同一个整轨文件的多个 CUE 曲目会互相替换，只剩最后一个曲目。
+func dropSongsFilePathUnique(db DB) error {
+	var unique int
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM pragma_index_list('songs') AS l
+		WHERE l."unique" = 1
+		  AND (SELECT COUNT(*) FROM pragma_index_info(l.name)) = 1
+		  AND (SELECT name FROM pragma_index_info(l.name)) = 'file_path'
+	`).Scan(&unique)
+	if err != nil {
+		return fmt.Errorf("读取表 songs 的索引失败: %w", err)
+	}
+	if unique == 0 {
+		return nil
+	}
+
+	rows, err := db.Query(`SELECT name FROM pragma_table_info('songs')`)
+	if err != nil {
+		return fmt.Errorf("读取表 songs 结构失败: %w", err)
+	}
+	var columns []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return fmt.Errorf("读取表 songs 结构失败: %w", err)
+		}
+		columns = append(columns, `"`+name+`"`)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("读取表 songs 结构失败: %w", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	list := strings.Join(columns, ", ")
+	statements := []string{
+		`ALTER TABLE songs RENAME TO songs_legacy`,
+		songsTableSchema,
+		fmt.Sprintf(`INSERT INTO songs (%s) SELECT %s FROM songs_legacy`, list, list),
+		`DROP TABLE songs_legacy`,
+		// 索引随旧表一起删除，需要在新表上重新创建
+		`CREATE INDEX IF NOT EXISTS idx_songs_artist ON songs(artist)`,
+		`CREATE INDEX IF NOT EXISTS idx_songs_album ON songs(album)`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement); err != nil {
+			return fmt.Errorf("重建表 songs 失败: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// addColumnIfMissing 在表中不存在指定列时通过 ALTER TABLE 添加该列。
+func addColumnIfMissing(db DB, table, column, definition string) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("读取表 %s 结构失败: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return fmt.Errorf("读取表 %s 结构失败: %w", table, err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("读取表 %s 结构失败: %w", table, err)
+	}
+	rows.Close()
+
+	if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("为表 %s 添加列 %s 失败: %w", table, column, err)
+	}
 	return nil
 }
 
@@ -77,7 +77,7 @@ func TestSQLiteProvider_Migrate(t *testing.T) {
 	require.NoError(t, err)
 
 	// 验证表已创建
-	tables := []string{"users", "user_preferences", "play_history", "play_stats", "favorites", "playlists", "playlist_songs"}
+	tables := []string{"users", "user_preferences", "play_history", "play_stats", "favorites", "playlists", "playlist_songs", "songs", "albums", "artists", "song_id_aliases", "duplicate_preferences"}
 
 	for _, table := range tables {
 		t.Run("table_exists_"+table, func(t *testing.T) {
@@ -110,6 +110,80 @@ func TestSQLiteProvider_Migrate_Idempotent(t *testing.T) {
 	}
 }
 
+func TestSQLiteProvider_Migrate_AddsMissingColumns(t *testing.T) {
+	provider := NewSQLiteProvider()
+
+	db, err := provider.Open(&DBConfig{DSN: filepath.Join(t.TempDir(), "legacy_test.db")})
+	require.NoError(t, err)
+	defer db.Close()
+
+	// 模拟早期版本创建的 songs 表
+	_, err = db.Exec(`CREATE TABLE songs (
+		id TEXT PRIMARY KEY,
+		file_path TEXT UNIQUE NOT NULL,
+		file_name TEXT NOT NULL,
+		title TEXT NOT NULL DEFAULT '',
+		artist TEXT NOT NULL DEFAULT '',
+		album TEXT NOT NULL DEFAULT '',
+		genre TEXT NOT NULL DEFAULT '',
+		year INTEGER DEFAULT 0,
+		track INTEGER DEFAULT 0,
+		duration INTEGER DEFAULT 0,
+		file_size INTEGER DEFAULT 0,
+		mod_time INTEGER DEFAULT 0,
+		format TEXT NOT NULL DEFAULT '',
+		has_cover BOOLEAN DEFAULT FALSE,
+		scanned_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO songs (id, file_path, file_name) VALUES ('a', '/music/a.mp3', 'a.mp3')`)
+	require.NoError(t, err)
+
+	require.NoError(t, provider.Migrate(db))
+
+	var relPath, contentHash string
+	err = db.QueryRow(`SELECT rel_path, content_hash FROM songs WHERE id = 'a'`).Scan(&relPath, &contentHash)
+	require.NoError(t, err)
+	assert.Empty(t, relPath)
+	assert.Empty(t, contentHash)
+}
+
+func TestSQLiteProvider_Migrate_DropsFilePathUnique(t *testing.T) {
+	provider := NewSQLiteProvider()
+
+	db, err := provider.Open(&DBConfig{DSN: filepath.Join(t.TempDir(), "legacy_test.db")})
+	require.NoError(t, err)
+	defer db.Close()
+
+	// 早期版本的 songs 表在 file_path 上有唯一约束
+	_, err = db.Exec(`CREATE TABLE songs (
+		id TEXT PRIMARY KEY,
+		file_path TEXT UNIQUE NOT NULL,
+		file_name TEXT NOT NULL,
+		title TEXT NOT NULL DEFAULT '',
+		artist TEXT NOT NULL DEFAULT '',
+		album TEXT NOT NULL DEFAULT ''
+	)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO songs (id, file_path, file_name, title) VALUES ('a', '/music/album.flac', 'album.flac', 'Kept')`)
+	require.NoError(t, err)
+
+	require.NoError(t, provider.Migrate(db))
+	require.NoError(t, provider.Migrate(db))
+
+	var title string
+	require.NoError(t, db.QueryRow(`SELECT title FROM songs WHERE id = 'a'`).Scan(&title))
+	assert.Equal(t, "Kept", title)
+
+	// 同一个整轨文件的多个 CUE 曲目可以同时保存
+	_, err = db.Exec(`INSERT INTO songs (id, file_path, file_name, start_ms) VALUES ('b', '/music/album.flac', 'album.flac', 1000)`)
+	require.NoError(t, err)
+
+	var indexes int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND tbl_name = 'songs' AND name LIKE 'idx_songs_%'`).Scan(&indexes))
+	assert.Equal(t, 2, indexes)
+}
+
 func TestSqlDBWrapper_Exec(t *testing.T) {
 	provider := NewSQLiteProvider()
 
@@ -0,0 +1,488 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"zero-music/config"
+	"zero-music/models"
+	"zero-music/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminHandler 负责处理仅管理员可访问的 API 请求。
+type AdminHandler struct {
+	scans      *services.ScanJobManager
+	loudness   *services.LoudnessJobManager
+	duplicates *services.DuplicateService
+	library    *services.MusicScanner
+	tags       *services.TagEditor
+	overrides  *services.MetadataOverrideService
+	songs      *services.SongManager
+}
+
+// NewAdminHandler 创建一个新的 AdminHandler 实例。
+func NewAdminHandler(scans *services.ScanJobManager, loudness *services.LoudnessJobManager, duplicates *services.DuplicateService, library *services.MusicScanner, tags *services.TagEditor, overrides *services.MetadataOverrideService, songs *services.SongManager) *AdminHandler {
+	return &AdminHandler{scans: scans, loudness: loudness, duplicates: duplicates, library: library, tags: tags, overrides: overrides, songs: songs}
+}
+
+// StartScan 在后台发起一次音乐库扫描。
+// 查询参数 mode 可选 full 或 incremental（默认）。已有扫描在运行时不会重复发起，
+// 而是返回正在运行的扫描状态，并将 attached 置为 true。
+func (h *AdminHandler) StartScan(c *gin.Context) {
+	mode := services.ScanMode(c.DefaultQuery("mode", string(services.ScanModeIncremental)))
+	if mode != services.ScanModeFull && mode != services.ScanModeIncremental {
+		c.JSON(http.StatusBadRequest, NewBadRequestError("扫描模式只能是 full 或 incremental"))
+		return
+	}
+
+	status, attached := h.scans.Start(mode)
+	c.JSON(http.StatusAccepted, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"attached": attached,
+			"status":   status,
+		},
+	})
+}
+
+// GetScanStatus 返回当前或最近一次扫描的进度。
+func (h *AdminHandler) GetScanStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.scans.Status(),
+	})
+}
+
+// CancelScan 取消正在运行的扫描。
+func (h *AdminHandler) CancelScan(c *gin.Context) {
+	status, ok := h.scans.Cancel()
+	if !ok {
+		c.JSON(http.StatusConflict, NewConflictError("当前没有正在运行的扫描"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    status,
+	})
+}
+
+// StartLoudnessAnalysis 在后台为没有音量标签的 WAV/FLAC 歌曲分析响度。
+// 查询参数 force=true 时重新分析之前由服务器分析过的歌曲。已有分析在运行时不会重复发起，
+// 而是返回正在运行的分析状态，并将 attached 置为 true。
+func (h *AdminHandler) StartLoudnessAnalysis(c *gin.Context) {
+	force := c.Query("force") == "true"
+
+	status, attached := h.loudness.Start(force)
+	c.JSON(http.StatusAccepted, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"attached": attached,
+			"status":   status,
+		},
+	})
+}
+
+// GetLoudnessStatus 返回当前或最近一次响度分析的进度。
+func (h *AdminHandler) GetLoudnessStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.loudness.Status(),
+	})
+}
+
+// CancelLoudnessAnalysis 取消正在运行的响度分析，已分析完成的专辑会保留结果。
+func (h *AdminHandler) CancelLoudnessAnalysis(c *gin.Context) {
+	status, ok := h.loudness.Cancel()
+	if !ok {
+		c.JSON(http.StatusConflict, NewConflictError("当前没有正在运行的响度分析"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    status,
+	})
+}
+
+// GetDuplicates 返回重复歌曲报告：内容指纹相同，或者艺术家和标题相同且时长接近的歌曲分为一组，
+// 组内副本按音质从高到低排列。
+func (h *AdminHandler) GetDuplicates(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.duplicates.Report(),
+	})
+}
+
+// PreferDuplicate 将歌曲设为其所在重复组的首选副本，同组的其他副本不再出现在歌曲列表和搜索结果中。
+func (h *AdminHandler) PreferDuplicate(c *gin.Context) {
+	id := c.Param("id")
+	if !ValidateSongID(c, id) {
+		return
+	}
+
+	group, err := h.duplicates.Prefer(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrSongNotFound):
+			c.JSON(http.StatusNotFound, NewNotFoundError("歌曲"))
+		case errors.Is(err, services.ErrNotDuplicate):
+			c.JSON(http.StatusBadRequest, NewBadRequestError("歌曲没有重复的副本"))
+		default:
+			c.JSON(http.StatusInternalServerError, NewInternalError(err))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    group,
+	})
+}
+
+// ClearDuplicatePreference 取消以歌曲为首选副本的首选项，同组被隐藏的副本重新显示。
+func (h *AdminHandler) ClearDuplicatePreference(c *gin.Context) {
+	id := c.Param("id")
+	if !ValidateSongID(c, id) {
+		return
+	}
+
+	restored, err := h.duplicates.ClearPreference(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, NewInternalError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"restored": restored,
+		},
+	})
+}
+
+// GetLibraryIssues 返回歌曲库健康报告：标签损坏或缺失、时长为 0、无法识别音频流以及无法访问的文件。
+// 查询参数 kind 可以用逗号分隔多个问题类型，library 按音乐库筛选，q 匹配相对路径或标题，limit 和 offset 用于分页。
+func (h *AdminHandler) GetLibraryIssues(c *gin.Context) {
+	filter := services.IssueFilter{
+		Library: strings.TrimSpace(c.Query("library")),
+		Query:   c.Query("q"),
+	}
+	if kinds := strings.TrimSpace(c.Query("kind")); kinds != "" {
+		for _, kind := range strings.Split(kinds, ",") {
+			kind = strings.TrimSpace(kind)
+			if !slices.Contains(models.IssueKinds, kind) {
+				c.JSON(http.StatusBadRequest, NewBadRequestError(fmt.Sprintf("未知的问题类型: %s", kind)))
+				return
+			}
+			filter.Kinds = append(filter.Kinds, kind)
+		}
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > config.MaxSearchLimit {
+		limit = config.DefaultSearchLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    services.NewIssueReport(h.library.Issues(), filter, limit, offset),
+	})
+}
+
+// maxBulkTagEditSongs 是批量修改标签时一次最多修改的歌曲数。
+const maxBulkTagEditSongs = 500
+
+// BulkTagEditRequest 批量修改标签请求
+type BulkTagEditRequest struct {
+	IDs  []string       `json:"ids" binding:"required"`
+	Tags models.TagEdit `json:"tags"`
+}
+
+// UpdateSongTags 修改一首歌曲的标签并写回音频文件，返回更新后的歌曲信息。
+// 请求体中省略的字段保持不变，空字符串或 0 表示删除该标签，cover 为 base64 编码的 JPEG 或 PNG 图片。
+func (h *AdminHandler) UpdateSongTags(c *gin.Context) {
+	id := c.Param("id")
+	if !ValidateSongID(c, id) {
+		return
+	}
+
+	var edit models.TagEdit
+	if err := c.ShouldBindJSON(&edit); err != nil {
+		c.JSON(http.StatusBadRequest, NewBadRequestError("请求参数错误"))
+		return
+	}
+	if err := edit.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, NewBadRequestError(err.Error()))
+		return
+	}
+
+	results, err := h.tags.Edit(c.Request.Context(), []string{id}, &edit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, NewInternalError(err))
+		return
+	}
+	result := results[0]
+	if err := result.Err(); err != nil {
+		switch {
+		case errors.Is(err, services.ErrSongNotFound):
+			c.JSON(http.StatusNotFound, NewNotFoundError("歌曲"))
+		case errors.Is(err, services.ErrCueTrackTags), errors.Is(err, services.ErrUnsupportedTagFormat):
+			c.JSON(http.StatusBadRequest, NewBadRequestError(err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, NewInternalError(err))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    result.Song,
+	})
+}
+
+// BulkUpdateSongTags 对多首歌曲应用同一组标签修改，例如统一专辑名或封面。
+// 单首歌曲失败不影响其他歌曲，每首歌曲的结果按请求中的顺序返回。
+func (h *AdminHandler) BulkUpdateSongTags(c *gin.Context) {
+	var req BulkTagEditRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, NewBadRequestError("请求参数错误"))
+		return
+	}
+	if len(req.IDs) == 0 || len(req.IDs) > maxBulkTagEditSongs {
+		c.JSON(http.StatusBadRequest, NewBadRequestError(fmt.Sprintf("歌曲数量必须在 1 到 %d 之间", maxBulkTagEditSongs)))
+		return
+	}
+	for _, id := range req.IDs {
+		if !ValidateSongID(c, id) {
+			return
+		}
+	}
+	if err := req.Tags.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, NewBadRequestError(err.Error()))
+		return
+	}
+
+	results, err := h.tags.Edit(c.Request.Context(), req.IDs, &req.Tags)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, NewInternalError(err))
+		return
+	}
+	updated := 0
+	for _, result := range results {
+		if result.Err() == nil {
+			updated++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"updated": updated,
+			"failed":  len(results) - updated,
+			"results": results,
+		},
+	})
+}
+
+// GetMetadataOverrides 返回所有歌曲的元数据覆盖。
+func (h *AdminHandler) GetMetadataOverrides(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.overrides.List(),
+	})
+}
+
+// GetSongMetadataOverride 返回一首歌曲的元数据覆盖。
+func (h *AdminHandler) GetSongMetadataOverride(c *gin.Context) {
+	id := c.Param("id")
+	if !ValidateSongID(c, id) {
+		return
+	}
+
+	override, err := h.overrides.Get(id)
+	if err != nil {
+		writeMetadataOverrideError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    override,
+	})
+}
+
+// UpdateSongMetadataOverride 修改歌曲的元数据覆盖，不写入音频文件，返回应用覆盖后的歌曲信息。
+// 请求体中省略的字段保持原有的覆盖，clear 中列出的字段恢复为文件中的值，cover 为 base64 编码的 JPEG 或 PNG 图片。
+func (h *AdminHandler) UpdateSongMetadataOverride(c *gin.Context) {
+	id := c.Param("id")
+	if !ValidateSongID(c, id) {
+		return
+	}
+
+	var edit models.MetadataOverrideEdit
+	if err := c.ShouldBindJSON(&edit); err != nil {
+		c.JSON(http.StatusBadRequest, NewBadRequestError("请求参数错误"))
+		return
+	}
+	if err := edit.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, NewBadRequestError(err.Error()))
+		return
+	}
+
+	song, err := h.overrides.Update(id, &edit)
+	if err != nil {
+		writeMetadataOverrideError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    song,
+	})
+}
+
+// DeleteSongMetadataOverride 删除歌曲的全部元数据覆盖，返回恢复为文件中元数据的歌曲信息。
+func (h *AdminHandler) DeleteSongMetadataOverride(c *gin.Context) {
+	id := c.Param("id")
+	if !ValidateSongID(c, id) {
+		return
+	}
+
+	song, err := h.overrides.Delete(id)
+	if err != nil {
+		writeMetadataOverrideError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    song,
+	})
+}
+
+// writeMetadataOverrideError 将元数据覆盖服务的错误写入响应。
+func writeMetadataOverrideError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrSongNotFound):
+		c.JSON(http.StatusNotFound, NewNotFoundError("歌曲"))
+	case errors.Is(err, services.ErrNoMetadataOverride):
+		c.JSON(http.StatusNotFound, NewNotFoundError("元数据覆盖"))
+	default:
+		c.JSON(http.StatusInternalServerError, NewInternalError(err))
+	}
+}
+
+// MoveSongRequest 定义了移动歌曲的请求体。
+type MoveSongRequest struct {
+	// Library 是目标音乐库的名称，为空时在歌曲所在的音乐库内移动
+	Library string `json:"library"`
+	// Path 是目标位置在音乐库中的相对路径（使用 / 分隔），扩展名必须与原文件相同
+	Path string `json:"path" binding:"required"`
+}
+
+// MoveSong 移动或重命名音乐库中的歌曲文件，返回移动后的歌曲信息。
+// 歌曲 ID 随路径变化，旧 ID 作为别名继续可用，收藏、播放列表和播放统计迁移到新 ID。
+func (h *AdminHandler) MoveSong(c *gin.Context) {
+	id := c.Param("id")
+	if !ValidateSongID(c, id) {
+		return
+	}
+
+	var req MoveSongRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, NewBadRequestError("请求参数错误"))
+		return
+	}
+
+	song, err := h.songs.Move(c.Request.Context(), id, req.Library, req.Path)
+	if err != nil {
+		writeSongManagerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    song,
+	})
+}
+
+// DeleteSong 删除歌曲：文件移入回收站，收藏、播放列表和播放统计中对歌曲的引用被清理，播放历史保留。
+// 返回歌曲在回收站中的记录。
+func (h *AdminHandler) DeleteSong(c *gin.Context) {
+	userID, ok := getUserIDOrAbort(c)
+	if !ok {
+		return
+	}
+	id := c.Param("id")
+	if !ValidateSongID(c, id) {
+		return
+	}
+
+	entry, err := h.songs.Delete(c.Request.Context(), id, userID)
+	if err != nil {
+		writeSongManagerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    entry,
+	})
+}
+
+// GetTrash 返回回收站中尚未被彻底删除的歌曲。
+func (h *AdminHandler) GetTrash(c *gin.Context) {
+	entries, err := h.songs.Trash()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, NewInternalError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    entries,
+	})
+}
+
+// writeSongManagerError 将歌曲管理服务的错误写入响应。
+func writeSongManagerError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrSongNotFound):
+		c.JSON(http.StatusNotFound, NewNotFoundError("歌曲"))
+	case errors.Is(err, services.ErrCueTrackFile), errors.Is(err, services.ErrInvalidSongPath):
+		c.JSON(http.StatusBadRequest, NewBadRequestError(err.Error()))
+	case errors.Is(err, services.ErrSongPathExists):
+		c.JSON(http.StatusConflict, NewConflictError(err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, NewInternalError(err))
+	}
+}
@@ -0,0 +1,635 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"zero-music/models"
+	"zero-music/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setupAdminTestEnv 初始化一个用于管理员处理器测试的环境。
+func setupAdminTestEnv(t *testing.T) (*gin.Engine, *services.ScanJobManager) {
+	gin.SetMode(gin.TestMode)
+
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "test.mp3"), []byte("fake mp3 data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	scanner := services.NewMusicScanner(tmpDir, []string{".mp3"}, 5)
+	manager := services.NewScanJobManager(scanner)
+	duplicates := services.NewDuplicateService(scanner, newMemoryDuplicateRepository())
+	songs, err := services.NewSongManager(scanner, &memoryTrashRepository{}, &memoryUploadRepository{quotas: make(map[int64]int64)}, duplicates, t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := NewAdminHandler(manager, services.NewLoudnessJobManager(scanner, 1), duplicates, scanner, services.NewTagEditor(scanner),
+		services.NewMetadataOverrideService(scanner, newMemoryMetadataOverrideRepository()), songs)
+
+	router := gin.New()
+	router.POST("/api/admin/library/scan", handler.StartScan)
+	router.GET("/api/admin/library/scan", handler.GetScanStatus)
+	router.DELETE("/api/admin/library/scan", handler.CancelScan)
+	router.POST("/api/admin/library/loudness", handler.StartLoudnessAnalysis)
+	router.GET("/api/admin/library/loudness", handler.GetLoudnessStatus)
+	router.DELETE("/api/admin/library/loudness", handler.CancelLoudnessAnalysis)
+	router.GET("/api/admin/library/issues", handler.GetLibraryIssues)
+	router.PUT("/api/admin/songs/tags", handler.BulkUpdateSongTags)
+	router.PUT("/api/admin/songs/:id/tags", handler.UpdateSongTags)
+	router.GET("/api/admin/overrides", handler.GetMetadataOverrides)
+	router.GET("/api/admin/songs/:id/overrides", handler.GetSongMetadataOverride)
+	router.PUT("/api/admin/songs/:id/overrides", handler.UpdateSongMetadataOverride)
+	router.DELETE("/api/admin/songs/:id/overrides", handler.DeleteSongMetadataOverride)
+	router.POST("/api/admin/songs/:id/move", handler.MoveSong)
+	router.DELETE("/api/admin/songs/:id", func(c *gin.Context) { c.Set("user_id", int64(1)) }, handler.DeleteSong)
+	router.GET("/api/admin/trash", handler.GetTrash)
+	return router, manager
+}
+
+// TestStartScan 测试发起扫描并查询扫描状态。
+func TestStartScan(t *testing.T) {
+	router, manager := setupAdminTestEnv(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/admin/library/scan?mode=full", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("期望状态码 %d, 得到 %d", http.StatusAccepted, w.Code)
+	}
+	var started struct {
+		Data struct {
+			Attached bool                `json:"attached"`
+			Status   services.ScanStatus `json:"status"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &started); err != nil {
+		t.Fatalf("无法解析响应 JSON: %v", err)
+	}
+	if started.Data.Attached || started.Data.Status.Mode != services.ScanModeFull {
+		t.Errorf("期望发起新的 full 扫描, 得到 %+v", started.Data)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := manager.Wait(ctx); err != nil {
+		t.Fatalf("等待扫描结束失败: %v", err)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/admin/library/scan", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("期望状态码 %d, 得到 %d", http.StatusOK, w.Code)
+	}
+	var response struct {
+		Data services.ScanStatus `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("无法解析响应 JSON: %v", err)
+	}
+	if response.Data.State != services.ScanStateCompleted || response.Data.FilesVisited != 1 {
+		t.Errorf("期望扫描完成并遍历 1 个文件, 得到 %+v", response.Data)
+	}
+}
+
+// TestStartScan_InvalidMode 测试非法的扫描模式。
+func TestStartScan_InvalidMode(t *testing.T) {
+	router, _ := setupAdminTestEnv(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/admin/library/scan?mode=quick", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("期望状态码 %d, 得到 %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+// TestCancelScan_NotRunning 测试没有正在运行的扫描时取消返回冲突。
+func TestCancelScan_NotRunning(t *testing.T) {
+	router, _ := setupAdminTestEnv(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/api/admin/library/scan", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("期望状态码 %d, 得到 %d", http.StatusConflict, w.Code)
+	}
+}
+
+// TestLoudnessAnalysis 测试发起响度分析、查询状态以及没有正在运行的分析时取消返回冲突。
+func TestLoudnessAnalysis(t *testing.T) {
+	router, _ := setupAdminTestEnv(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/admin/library/loudness", nil)
+	router.ServeHTTP(w, req)
+	var response struct {
+		Data services.LoudnessStatus `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("无法解析响应 JSON: %v", err)
+	}
+	if w.Code != http.StatusOK || response.Data.State != services.ScanStateIdle {
+		t.Fatalf("期望状态为 idle, 得到 %d %+v", w.Code, response.Data)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/admin/library/loudness?force=true", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("期望状态码 %d, 得到 %d", http.StatusAccepted, w.Code)
+	}
+	var started struct {
+		Data struct {
+			Attached bool                    `json:"attached"`
+			Status   services.LoudnessStatus `json:"status"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &started); err != nil {
+		t.Fatalf("无法解析响应 JSON: %v", err)
+	}
+	if started.Data.Attached || !started.Data.Status.Force {
+		t.Errorf("期望发起新的 force 分析, 得到 %+v", started.Data)
+	}
+
+	// 测试库中没有可以分析的歌曲，分析很快结束
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/api/admin/library/loudness", nil)
+		router.ServeHTTP(w, req)
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("无法解析响应 JSON: %v", err)
+		}
+		if response.Data.State != services.ScanStateRunning || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if response.Data.State != services.ScanStateCompleted || response.Data.Total != 0 {
+		t.Errorf("期望分析完成且没有需要分析的歌曲, 得到 %+v", response.Data)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/admin/library/loudness", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusConflict {
+		t.Errorf("期望状态码 %d, 得到 %d", http.StatusConflict, w.Code)
+	}
+}
+
+// memoryDuplicateRepository 是保存在内存中的 DuplicateRepository 实现。
+type memoryDuplicateRepository struct {
+	hidden map[string]string
+}
+
+func newMemoryDuplicateRepository() *memoryDuplicateRepository {
+	return &memoryDuplicateRepository{hidden: make(map[string]string)}
+}
+
+func (r *memoryDuplicateRepository) LoadHidden() (map[string]string, error) {
+	hidden := make(map[string]string, len(r.hidden))
+	for id, preferred := range r.hidden {
+		hidden[id] = preferred
+	}
+	return hidden, nil
+}
+
+func (r *memoryDuplicateRepository) SetPreferred(preferredID string, hiddenIDs []string) error {
+	for id, preferred := range r.hidden {
+		if id == preferredID || preferred == preferredID {
+			delete(r.hidden, id)
+		}
+	}
+	for _, id := range hiddenIDs {
+		r.hidden[id] = preferredID
+	}
+	return nil
+}
+
+func (r *memoryDuplicateRepository) ClearPreferred(preferredID string) (int64, error) {
+	var cleared int64
+	for id, preferred := range r.hidden {
+		if preferred == preferredID {
+			delete(r.hidden, id)
+			cleared++
+		}
+	}
+	return cleared, nil
+}
+
+// TestDuplicates 测试重复歌曲报告、选定首选副本后歌曲列表和搜索结果隐藏其他副本，以及取消首选项。
+func TestDuplicates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const (
+		flacID    = "0123456789abcdef0123456789abcdef"
+		mp3ID     = "fedcba9876543210fedcba9876543210"
+		otherID   = "00000000000000000000000000000001"
+		missingID = "00000000000000000000000000000002"
+	)
+	scanner := &staticScanner{songs: []*models.Song{
+		{ID: flacID, Artist: "Artist", Title: "Song", Duration: 200, FilePath: "/music/song.flac", Codec: models.CodecFLAC, Lossless: true, SampleRate: 44100, BitDepth: 16},
+		{ID: mp3ID, Artist: "Artist", Title: "Song", Duration: 200, FilePath: "/music/song.mp3", Codec: models.CodecMP3, Bitrate: 320},
+		{ID: otherID, Artist: "Artist", Title: "Other", Duration: 180, FilePath: "/music/other.mp3"},
+	}}
+	duplicates := services.NewDuplicateService(scanner, newMemoryDuplicateRepository())
+	handler := NewAdminHandler(nil, nil, duplicates, nil, nil, nil, nil)
+	visible := services.NewVisibleScanner(scanner, duplicates)
+
+	router := gin.New()
+	router.GET("/api/admin/library/duplicates", handler.GetDuplicates)
+	router.PUT("/api/admin/library/duplicates/:id/preferred", handler.PreferDuplicate)
+	router.DELETE("/api/admin/library/duplicates/:id/preferred", handler.ClearDuplicatePreference)
+	router.GET("/api/search", NewSearchHandler(visible).Search)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/admin/library/duplicates", nil)
+	router.ServeHTTP(w, req)
+	var report struct {
+		Data services.DuplicateReport `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("无法解析响应 JSON: %v", err)
+	}
+	if w.Code != http.StatusOK || report.Data.GroupCount != 1 || report.Data.Groups[0].Copies[0].ID != flacID {
+		t.Fatalf("期望 1 组重复歌曲且 flac 排在前面, 得到 %d %+v", w.Code, report.Data)
+	}
+
+	testCases := map[string]int{
+		otherID:   http.StatusBadRequest,
+		missingID: http.StatusNotFound,
+		"bad id!": http.StatusBadRequest,
+		mp3ID:     http.StatusOK,
+	}
+	for id, want := range testCases {
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("PUT", "/api/admin/library/duplicates/"+url.PathEscape(id)+"/preferred", nil)
+		router.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("%s: 期望状态码 %d, 得到 %d", id, want, w.Code)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/search?q=song", nil)
+	router.ServeHTTP(w, req)
+	var search struct {
+		Data struct {
+			Songs []*models.Song `json:"songs"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &search); err != nil {
+		t.Fatalf("无法解析响应 JSON: %v", err)
+	}
+	if len(search.Data.Songs) != 1 || search.Data.Songs[0].ID != mp3ID {
+		t.Errorf("期望搜索结果只包含首选副本, 得到 %+v", search.Data.Songs)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/admin/library/duplicates/"+mp3ID+"/preferred", nil)
+	router.ServeHTTP(w, req)
+	var cleared struct {
+		Data struct {
+			Restored int `json:"restored"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &cleared); err != nil {
+		t.Fatalf("无法解析响应 JSON: %v", err)
+	}
+	if w.Code != http.StatusOK || cleared.Data.Restored != 1 || visible.GetSongCount() != 3 {
+		t.Errorf("期望重新显示 1 个副本, 得到 %d %+v", w.Code, cleared.Data)
+	}
+}
+
+// TestGetLibraryIssues 测试歌曲库健康报告的筛选和非法的问题类型。
+func TestGetLibraryIssues(t *testing.T) {
+	router, manager := setupAdminTestEnv(t)
+
+	manager.Start(services.ScanModeFull)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := manager.Wait(ctx); err != nil {
+		t.Fatalf("等待扫描结束失败: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/admin/library/issues?kind=tag_error,missing_title&library=default", nil)
+	router.ServeHTTP(w, req)
+	var response struct {
+		Data services.IssueReport `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("无法解析响应 JSON: %v", err)
+	}
+	// 测试文件太短，标签无法解析
+	if w.Code != http.StatusOK || response.Data.Total != 1 || response.Data.Issues[0].Kind != models.IssueTagError {
+		t.Fatalf("期望 1 个标签错误, 得到 %d %+v", w.Code, response.Data)
+	}
+	if response.Data.Issues[0].RelPath != "test.mp3" || response.Data.Counts[models.IssueTagError] != 1 {
+		t.Errorf("问题应指向 test.mp3, 得到 %+v", response.Data)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/admin/library/issues?kind=broken", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("期望状态码 %d, 得到 %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+// TestUpdateSongTags 测试修改单首歌曲和批量修改标签。
+func TestUpdateSongTags(t *testing.T) {
+	router, manager := setupAdminTestEnv(t)
+
+	manager.Start(services.ScanModeFull)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := manager.Wait(ctx); err != nil {
+		t.Fatalf("等待扫描结束失败: %v", err)
+	}
+	id := models.LibrarySongID("default", "test.mp3")
+	missingID := "0123456789abcdef0123456789abcdef"
+
+	tests := []struct {
+		name string
+		path string
+		body string
+		code int
+	}{
+		{"修改成功", "/api/admin/songs/" + id + "/tags", `{"title":" 新标题 ","year":2024,"track":3}`, http.StatusOK},
+		{"没有修改", "/api/admin/songs/" + id + "/tags", `{}`, http.StatusBadRequest},
+		{"无效的年份", "/api/admin/songs/" + id + "/tags", `{"year":-1}`, http.StatusBadRequest},
+		{"无效的封面", "/api/admin/songs/" + id + "/tags", `{"cover":"bm90IGFuIGltYWdl"}`, http.StatusBadRequest},
+		{"歌曲不存在", "/api/admin/songs/" + missingID + "/tags", `{"title":"x"}`, http.StatusNotFound},
+		{"无效的 ID", "/api/admin/songs/bad/tags", `{"title":"x"}`, http.StatusBadRequest},
+		{"批量没有歌曲", "/api/admin/songs/tags", `{"ids":[],"tags":{"title":"x"}}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("PUT", tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+			if w.Code != tt.code {
+				t.Errorf("期望状态码 %d, 得到 %d: %s", tt.code, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/api/admin/songs/"+id+"/tags", strings.NewReader(`{"artist":"Artist"}`))
+	router.ServeHTTP(w, req)
+	var single struct {
+		Data models.Song `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &single); err != nil {
+		t.Fatalf("无法解析响应 JSON: %v", err)
+	}
+	if song := single.Data; song.ID != id || song.Title != "新标题" || song.Artist != "Artist" || song.Year != 2024 || song.Track != 3 {
+		t.Errorf("期望返回更新后的歌曲, 得到 %+v", song)
+	}
+
+	w = httptest.NewRecorder()
+	body := `{"ids":["` + id + `","` + missingID + `"],"tags":{"album":"Album"}}`
+	req, _ = http.NewRequest("PUT", "/api/admin/songs/tags", strings.NewReader(body))
+	router.ServeHTTP(w, req)
+	var bulk struct {
+		Data struct {
+			Updated int                      `json:"updated"`
+			Failed  int                      `json:"failed"`
+			Results []services.TagEditResult `json:"results"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &bulk); err != nil {
+		t.Fatalf("无法解析响应 JSON: %v", err)
+	}
+	if w.Code != http.StatusOK || bulk.Data.Updated != 1 || bulk.Data.Failed != 1 || len(bulk.Data.Results) != 2 {
+		t.Fatalf("期望 1 首成功 1 首失败, 得到 %d %+v", w.Code, bulk.Data)
+	}
+	if result := bulk.Data.Results[0]; result.Song == nil || result.Song.Album != "Album" || result.Song.Title != "新标题" {
+		t.Errorf("期望第一首歌曲的专辑被修改, 得到 %+v", result.Song)
+	}
+	if result := bulk.Data.Results[1]; result.ID != missingID || result.Error == "" || result.Song != nil {
+		t.Errorf("期望第二首歌曲返回错误, 得到 %+v", result)
+	}
+}
+
+// memoryMetadataOverrideRepository 是保存在内存中的 MetadataOverrideRepository 实现。
+type memoryMetadataOverrideRepository struct {
+	overrides map[string]models.MetadataOverride
+	covers    map[string][]byte
+}
+
+func newMemoryMetadataOverrideRepository() *memoryMetadataOverrideRepository {
+	return &memoryMetadataOverrideRepository{overrides: make(map[string]models.MetadataOverride), covers: make(map[string][]byte)}
+}
+
+func (r *memoryMetadataOverrideRepository) LoadAll() ([]*models.MetadataOverride, error) {
+	var overrides []*models.MetadataOverride
+	for _, override := range r.overrides {
+		copied := override
+		overrides = append(overrides, &copied)
+	}
+	return overrides, nil
+}
+
+func (r *memoryMetadataOverrideRepository) Save(override *models.MetadataOverride, cover []byte) error {
+	r.overrides[override.SongID] = *override
+	if override.CoverMIME == "" {
+		delete(r.covers, override.SongID)
+	} else if cover != nil {
+		r.covers[override.SongID] = cover
+	}
+	return nil
+}
+
+func (r *memoryMetadataOverrideRepository) Delete(songID string) (bool, error) {
+	_, ok := r.overrides[songID]
+	delete(r.overrides, songID)
+	delete(r.covers, songID)
+	return ok, nil
+}
+
+func (r *memoryMetadataOverrideRepository) GetCover(songID string) ([]byte, error) {
+	return r.covers[songID], nil
+}
+
+// TestMetadataOverrides 测试保存、查询和删除歌曲的元数据覆盖。
+func TestMetadataOverrides(t *testing.T) {
+	router, manager := setupAdminTestEnv(t)
+
+	manager.Start(services.ScanModeFull)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := manager.Wait(ctx); err != nil {
+		t.Fatalf("等待扫描结束失败: %v", err)
+	}
+	id := models.LibrarySongID("default", "test.mp3")
+	path := "/api/admin/songs/" + id + "/overrides"
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := request("GET", path, ""); w.Code != http.StatusNotFound {
+		t.Errorf("期望没有覆盖时状态码 %d, 得到 %d", http.StatusNotFound, w.Code)
+	}
+	for _, body := range []string{`{}`, `{"year":10000}`, `{"clear":["composer"]}`} {
+		if w := request("PUT", path, body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: 期望状态码 %d, 得到 %d", body, http.StatusBadRequest, w.Code)
+		}
+	}
+	if w := request("PUT", "/api/admin/songs/0123456789abcdef0123456789abcdef/overrides", `{"title":"x"}`); w.Code != http.StatusNotFound {
+		t.Errorf("期望歌曲不存在时状态码 %d, 得到 %d", http.StatusNotFound, w.Code)
+	}
+
+	w := request("PUT", path, `{"title":"Fixed","year":1999}`)
+	var updated struct {
+		Data models.Song `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &updated); err != nil {
+		t.Fatalf("无法解析响应 JSON: %v", err)
+	}
+	if w.Code != http.StatusOK || updated.Data.Title != "Fixed" || updated.Data.Year != 1999 || len(updated.Data.Overridden) != 2 {
+		t.Fatalf("期望返回应用覆盖后的歌曲, 得到 %d %+v", w.Code, updated.Data)
+	}
+
+	w = request("GET", "/api/admin/overrides", "")
+	var listed struct {
+		Data []models.MetadataOverride `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &listed); err != nil {
+		t.Fatalf("无法解析响应 JSON: %v", err)
+	}
+	if len(listed.Data) != 1 || listed.Data[0].SongID != id || *listed.Data[0].Title != "Fixed" {
+		t.Errorf("期望列出 1 个覆盖, 得到 %+v", listed.Data)
+	}
+
+	w = request("DELETE", path, "")
+	var restored struct {
+		Data models.Song `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &restored); err != nil {
+		t.Fatalf("无法解析响应 JSON: %v", err)
+	}
+	if w.Code != http.StatusOK || restored.Data.Title != "test" || restored.Data.Year != 0 || len(restored.Data.Overridden) != 0 {
+		t.Errorf("期望恢复为文件中的元数据, 得到 %d %+v", w.Code, restored.Data)
+	}
+	if w := request("DELETE", path, ""); w.Code != http.StatusNotFound {
+		t.Errorf("期望状态码 %d, 得到 %d", http.StatusNotFound, w.Code)
+	}
+}
+
+// memoryTrashRepository 是用于测试的内存回收站仓储。
+type memoryTrashRepository struct {
+	entries []*models.TrashedSong
+}
+
+func (r *memoryTrashRepository) Trash(entry *models.TrashedSong) error {
+	entry.ID = int64(len(r.entries) + 1)
+	entry.DeletedAt = time.Now()
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+func (r *memoryTrashRepository) List() ([]*models.TrashedSong, error) {
+	return r.entries, nil
+}
+
+func (r *memoryTrashRepository) ListDeletedBefore(before time.Time) ([]*models.TrashedSong, error) {
+	return nil, nil
+}
+
+func (r *memoryTrashRepository) MarkPurged(id int64) error {
+	return nil
+}
+
+// TestMoveAndDeleteSong 测试移动歌曲后旧 ID 仍可访问，删除的歌曲出现在回收站中。
+func TestMoveAndDeleteSong(t *testing.T) {
+	router, manager := setupAdminTestEnv(t)
+
+	manager.Start(services.ScanModeFull)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := manager.Wait(ctx); err != nil {
+		t.Fatalf("等待扫描结束失败: %v", err)
+	}
+	oldID := models.LibrarySongID("default", "test.mp3")
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"缺少路径", `{}`, http.StatusBadRequest},
+		{"指向库外", `{"path":"../test.mp3"}`, http.StatusBadRequest},
+		{"修改扩展名", `{"path":"test.flac"}`, http.StatusBadRequest},
+		{"音乐库不存在", `{"library":"missing","path":"a.mp3"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := request("POST", "/api/admin/songs/"+oldID+"/move", tt.body); w.Code != tt.code {
+				t.Errorf("期望状态码 %d, 得到 %d: %s", tt.code, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	w := request("POST", "/api/admin/songs/"+oldID+"/move", `{"path":"Artist/renamed.mp3"}`)
+	var moved struct {
+		Data models.Song `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &moved); err != nil {
+		t.Fatalf("无法解析响应 JSON: %v", err)
+	}
+	newID := models.LibrarySongID("default", "Artist/renamed.mp3")
+	if w.Code != http.StatusOK || moved.Data.ID != newID {
+		t.Fatalf("期望移动后的歌曲 ID 为 %s, 得到 %d %s", newID, w.Code, w.Body.String())
+	}
+
+	// 通过旧 ID 删除移动后的歌曲
+	w = request("DELETE", "/api/admin/songs/"+oldID, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("期望状态码 %d, 得到 %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if w := request("DELETE", "/api/admin/songs/"+oldID, ""); w.Code != http.StatusNotFound {
+		t.Errorf("期望重复删除时状态码 %d, 得到 %d", http.StatusNotFound, w.Code)
+	}
+
+	w = request("GET", "/api/admin/trash", "")
+	var trash struct {
+		Data []models.TrashedSong `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &trash); err != nil {
+		t.Fatalf("无法解析响应 JSON: %v", err)
+	}
+	if len(trash.Data) != 1 || trash.Data[0].SongID != newID || trash.Data[0].RelPath != "Artist/renamed.mp3" || trash.Data[0].DeletedBy != 1 {
+		t.Errorf("期望回收站中有移动后的歌曲, 得到 %+v", trash.Data)
+	}
+}
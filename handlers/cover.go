@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"zero-music/logger"
+	"zero-music/middleware"
+	"zero-music/models"
+	"zero-music/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// coverCacheControl 是封面响应的缓存策略。ETag 随文件变化，客户端可在过期后用 If-None-Match 重新验证。
+const coverCacheControl = "public, max-age=86400"
+
+// CoverHandler 负责处理封面图片相关的 API 请求。
+type CoverHandler struct {
+	scanner services.Scanner
+	covers  *services.CoverService
+}
+
+// NewCoverHandler 创建一个新的 CoverHandler 实例。
+func NewCoverHandler(scanner services.Scanner, covers *services.CoverService) *CoverHandler {
+	return &CoverHandler{
+		scanner: scanner,
+		covers:  covers,
+	}
+}
+
+// GetSongCover 返回歌曲的封面，嵌入的封面优先，其次是歌曲所在目录中的封面文件。
+// @Summary 获取歌曲封面
+// @Description 返回歌曲的封面图片，指定 size 时返回长边不超过 size 像素的缩略图
+// @Tags cover
+// @Produce image/jpeg,image/png
+// @Param id path string true "歌曲ID"
+// @Param size query int false "缩略图边长（像素）"
+// @Param If-None-Match header string false "上次响应的 ETag"
+// @Success 200 {file} binary "封面图片"
+// @Success 304 "封面未修改"
+// @Failure 400 {object} APIError "请求参数错误"
+// @Failure 404 {object} APIError "歌曲或封面未找到"
+// @Failure 500 {object} APIError "服务器错误"
+// @Router /api/song/{id}/cover [get]
+func (h *CoverHandler) GetSongCover(c *gin.Context) {
+	id := c.Param("id")
+	if !ValidateSongID(c, id) {
+		return
+	}
+
+	song := h.scanner.GetSongByID(id)
+	if song == nil {
+		c.JSON(http.StatusNotFound, NewNotFoundError("歌曲"))
+		return
+	}
+	h.serveCover(c, song)
+}
+
+// GetAlbumCover 返回专辑的封面，即专辑中曲目编号最小的带封面歌曲的封面。
+// @Summary 获取专辑封面
+// @Description 返回专辑中第一首带封面歌曲的封面图片，可通过 artist 区分同名专辑
+// @Tags cover
+// @Produce image/jpeg,image/png
+// @Param name path string true "专辑名称"
+// @Param artist query string false "专辑艺术家"
+// @Param library query string false "音乐库名称"
+// @Param size query int false "缩略图边长（像素）"
+// @Param If-None-Match header string false "上次响应的 ETag"
+// @Success 200 {file} binary "封面图片"
+// @Success 304 "封面未修改"
+// @Failure 400 {object} APIError "请求参数错误"
+// @Failure 404 {object} APIError "专辑封面未找到"
+// @Failure 500 {object} APIError "服务器错误"
+// @Router /api/albums/{name}/cover [get]
+func (h *CoverHandler) GetAlbumCover(c *gin.Context) {
+	album := c.Param("name")
+	if album == "" {
+		c.JSON(http.StatusBadRequest, NewBadRequestError("专辑名称不能为空"))
+		return
+	}
+	artist := strings.TrimSpace(c.Query("artist"))
+
+	var best *models.Song
+	for _, song := range filterByLibrary(c, h.scanner.GetSongs()) {
+		if !song.HasCover || !strings.EqualFold(song.Album, album) {
+			continue
+		}
+		if artist != "" && !strings.EqualFold(song.AlbumArtistName(), artist) {
+			continue
+		}
+		// 按曲目编号和路径选择，保证同一专辑每次返回同一张封面
+		if best == nil || song.Track < best.Track || (song.Track == best.Track && song.FilePath < best.FilePath) {
+			best = song
+		}
+	}
+	if best == nil {
+		c.JSON(http.StatusNotFound, NewNotFoundError("专辑封面"))
+		return
+	}
+	h.serveCover(c, best)
+}
+
+// GetArtistImage 返回艺术家目录中的艺术家图片（如 artist.jpg）。
+// @Summary 获取艺术家图片
+// @Description 返回艺术家目录中的艺术家图片，指定 size 时返回长边不超过 size 像素的缩略图
+// @Tags cover
+// @Produce image/jpeg,image/png
+// @Param name path string true "艺术家名称"
+// @Param library query string false "音乐库名称"
+// @Param size query int false "缩略图边长（像素）"
+// @Param If-None-Match header string false "上次响应的 ETag"
+// @Success 200 {file} binary "艺术家图片"
+// @Success 304 "图片未修改"
+// @Failure 400 {object} APIError "请求参数错误"
+// @Failure 404 {object} APIError "艺术家图片未找到"
+// @Failure 500 {object} APIError "服务器错误"
+// @Router /api/artists/{name}/image [get]
+func (h *CoverHandler) GetArtistImage(c *gin.Context) {
+	artist := c.Param("name")
+	if artist == "" {
+		c.JSON(http.StatusBadRequest, NewBadRequestError("艺术家名称不能为空"))
+		return
+	}
+
+	path := artistImages(filterByLibrary(c, h.scanner.GetSongs()))[strings.ToLower(artist)]
+	if path == "" {
+		c.JSON(http.StatusNotFound, NewNotFoundError("艺术家图片"))
+		return
+	}
+	h.serveImage(c, "艺术家图片 "+path,
+		func(size int) string { return h.covers.ImageETag(path, size) },
+		func(size int) (*services.Cover, error) { return h.covers.GetImage(path, size) },
+	)
+}
+
+// serveCover 输出歌曲的封面。
+func (h *CoverHandler) serveCover(c *gin.Context, song *models.Song) {
+	if !song.HasCover {
+		c.JSON(http.StatusNotFound, NewNotFoundError("封面"))
+		return
+	}
+	h.serveImage(c, "歌曲 "+song.ID+" 的封面",
+		func(size int) string { return h.covers.ETag(song, size) },
+		func(size int) (*services.Cover, error) { return h.covers.Get(song, size) },
+	)
+}
+
+// serveImage 处理 size 参数和 If-None-Match 条件请求并输出图片，name 用于日志。
+func (h *CoverHandler) serveImage(c *gin.Context, name string, etagFor func(size int) string, load func(size int) (*services.Cover, error)) {
+	requestID := middleware.GetRequestID(c)
+
+	size, err := parseCoverSize(c.Query("size"), h.covers.MaxSize())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewBadRequestError(err.Error()))
+		return
+	}
+
+	etag := etagFor(size)
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", coverCacheControl)
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	cover, err := load(size)
+	if errors.Is(err, services.ErrNoCover) {
+		c.JSON(http.StatusNotFound, NewNotFoundError("封面"))
+		return
+	}
+	if err != nil {
+		logger.WithRequestID(requestID).Errorf("读取%s失败: %v", name, err)
+		c.JSON(http.StatusInternalServerError, NewInternalError(err))
+		return
+	}
+	c.Data(http.StatusOK, cover.MIMEType, cover.Data)
+}
+
+// parseCoverSize 解析 size 参数，为空时返回 0 表示原图。
+func parseCoverSize(value string, maxSize int) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	size, err := strconv.Atoi(value)
+	if err != nil || size < 1 || size > maxSize {
+		return 0, fmt.Errorf("size 参数必须是 1-%d 之间的整数", maxSize)
+	}
+	return size, nil
+}
+
+// etagMatches 判断 If-None-Match 请求头是否匹配 etag。If-None-Match 使用弱比较，忽略 W/ 前缀。
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"zero-music/models"
+	"zero-music/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setupCoverTestEnv 初始化一个用于封面处理器测试的环境，歌曲文件并不存在，只能命中条件请求。
+func setupCoverTestEnv(t *testing.T) (*gin.Engine, *services.CoverService, []*models.Song) {
+	gin.SetMode(gin.TestMode)
+
+	songs := []*models.Song{
+		{ID: strings.Repeat("a", models.SongIDHexLength), Album: "Blue", Artist: "A", Track: 2, HasCover: true, FilePath: "/missing/2.mp3"},
+		{ID: strings.Repeat("b", models.SongIDHexLength), Album: "Blue", Artist: "A", Track: 1, HasCover: true, FilePath: "/missing/1.mp3"},
+		{ID: strings.Repeat("c", models.SongIDHexLength), Album: "Blue", Artist: "B", Track: 1, FilePath: "/missing/3.mp3"},
+	}
+	covers := services.NewCoverService(t.TempDir(), 512)
+	handler := NewCoverHandler(&staticScanner{songs: songs}, covers)
+
+	router := gin.New()
+	router.GET("/api/song/:id/cover", handler.GetSongCover)
+	router.GET("/api/albums/:name/cover", handler.GetAlbumCover)
+	return router, covers, songs
+}
+
+func TestGetSongCover_NotModified(t *testing.T) {
+	router, covers, songs := setupCoverTestEnv(t)
+	etag := covers.ETag(songs[0], 128)
+
+	req, _ := http.NewRequest("GET", "/api/song/"+songs[0].ID+"/cover?size=128", nil)
+	req.Header.Set("If-None-Match", `"other", W/`+etag)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("期望状态码 %d, 得到 %d", http.StatusNotModified, w.Code)
+	}
+	if w.Header().Get("ETag") != etag {
+		t.Errorf("期望 ETag %s, 得到 %s", etag, w.Header().Get("ETag"))
+	}
+}
+
+func TestGetSongCover_Errors(t *testing.T) {
+	router, _, songs := setupCoverTestEnv(t)
+
+	testCases := map[string]int{
+		"/api/song/" + songs[0].ID + "/cover?size=0":      http.StatusBadRequest,
+		"/api/song/" + songs[0].ID + "/cover?size=1024":   http.StatusBadRequest,
+		"/api/song/" + songs[0].ID + "/cover?size=big":    http.StatusBadRequest,
+		"/api/song/not-an-id/cover":                       http.StatusBadRequest,
+		"/api/song/" + songs[2].ID + "/cover":             http.StatusNotFound,
+		"/api/song/" + strings.Repeat("d", 32) + "/cover": http.StatusNotFound,
+		"/api/albums/Blue/cover?artist=B":                 http.StatusNotFound,
+		"/api/albums/Red/cover":                           http.StatusNotFound,
+	}
+	for path, expected := range testCases {
+		req, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != expected {
+			t.Errorf("%s: 期望状态码 %d, 得到 %d", path, expected, w.Code)
+		}
+	}
+}
+
+// TestGetAlbumCover_PicksFirstTrack 测试专辑封面取自曲目编号最小的带封面歌曲。
+func TestGetAlbumCover_PicksFirstTrack(t *testing.T) {
+	router, covers, songs := setupCoverTestEnv(t)
+
+	req, _ := http.NewRequest("GET", "/api/albums/blue/cover", nil)
+	req.Header.Set("If-None-Match", covers.ETag(songs[1], 0))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotModified {
+		t.Errorf("期望状态码 %d, 得到 %d", http.StatusNotModified, w.Code)
+	}
+}
+
+// TestGetArtistImage 测试返回艺术家目录中的艺术家图片，并在艺术家列表中标记。
+func TestGetArtistImage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	imagePath := filepath.Join(t.TempDir(), "artist.png")
+	if err := os.WriteFile(imagePath, []byte("\x89PNG\r\n\x1a\nfake"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	scanner := &staticScanner{songs: []*models.Song{
+		{ID: "1", Artist: "Alpha", ArtistImagePath: imagePath},
+		{ID: "2", Artist: "Beta"},
+	}}
+	covers := services.NewCoverService(t.TempDir(), 512)
+
+	router := gin.New()
+	router.GET("/api/artists", NewSearchHandler(scanner).GetArtists)
+	router.GET("/api/artists/:name/image", NewCoverHandler(scanner, covers).GetArtistImage)
+
+	req, _ := http.NewRequest("GET", "/api/artists/alpha/image", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("期望返回 PNG 图片, 得到状态码 %d, 类型 %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	if w.Header().Get("ETag") != covers.ImageETag(imagePath, 0) {
+		t.Errorf("ETag 不符合预期: %s", w.Header().Get("ETag"))
+	}
+
+	req, _ = http.NewRequest("GET", "/api/artists/Beta/image", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("期望状态码 %d, 得到 %d", http.StatusNotFound, w.Code)
+	}
+
+	req, _ = http.NewRequest("GET", "/api/artists", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var response struct {
+		Data struct {
+			Artists []struct {
+				Name     string `json:"name"`
+				HasImage bool   `json:"has_image"`
+			} `json:"artists"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	for _, artist := range response.Data.Artists {
+		if artist.HasImage != (artist.Name == "Alpha") {
+			t.Errorf("艺术家 %s 的 has_image 不符合预期: %v", artist.Name, artist.HasImage)
+		}
+	}
+}
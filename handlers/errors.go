@@ -104,3 +104,35 @@ func NewConflictError(message string) *APIError {
 		Message: message,
 	}
 }
+
+// NewPayloadTooLargeError 创建一个表示请求内容过大的 APIError。
+func NewPayloadTooLargeError(message string) *APIError {
+	return &APIError{
+		Code:    "PAYLOAD_TOO_LARGE",
+		Message: message,
+	}
+}
+
+// NewPreconditionFailedError 创建一个表示请求的前提条件不满足的 APIError。
+func NewPreconditionFailedError(message string) *APIError {
+	return &APIError{
+		Code:    "PRECONDITION_FAILED",
+		Message: message,
+	}
+}
+
+// NewUnsupportedMediaTypeError 创建一个表示请求内容类型不受支持的 APIError。
+func NewUnsupportedMediaTypeError(message string) *APIError {
+	return &APIError{
+		Code:    "UNSUPPORTED_MEDIA_TYPE",
+		Message: message,
+	}
+}
+
+// NewLockedError 创建一个表示资源正被其他请求占用的 APIError。
+func NewLockedError(message string) *APIError {
+	return &APIError{
+		Code:    "LOCKED",
+		Message: message,
+	}
+}
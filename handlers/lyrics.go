@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"zero-music/logger"
+	"zero-music/middleware"
+	"zero-music/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LyricsHandler 负责处理歌词相关的 API 请求。
+type LyricsHandler struct {
+	scanner services.Scanner
+}
+
+// NewLyricsHandler 创建一个新的 LyricsHandler 实例。
+func NewLyricsHandler(scanner services.Scanner) *LyricsHandler {
+	return &LyricsHandler{scanner: scanner}
+}
+
+// GetSongLyrics 返回歌曲的歌词。
+// @Summary 获取歌曲歌词
+// @Description 返回歌曲的歌词，来自同名的 .lrc 文件或嵌入的 USLT/SYLT 标签。带时间轴的歌词以毫秒时间的行列表返回，LRC 的 offset 已应用
+// @Tags lyrics
+// @Produce json
+// @Param id path string true "歌曲ID"
+// @Success 200 {object} models.Lyrics "歌词"
+// @Failure 400 {object} APIError "请求参数错误"
+// @Failure 404 {object} APIError "歌曲或歌词未找到"
+// @Failure 500 {object} APIError "服务器错误"
+// @Router /api/song/{id}/lyrics [get]
+func (h *LyricsHandler) GetSongLyrics(c *gin.Context) {
+	id := c.Param("id")
+	requestID := middleware.GetRequestID(c)
+
+	if !ValidateSongID(c, id) {
+		return
+	}
+
+	song := h.scanner.GetSongByID(id)
+	if song == nil {
+		c.JSON(http.StatusNotFound, NewNotFoundError("歌曲"))
+		return
+	}
+	if !song.HasLyrics {
+		c.JSON(http.StatusNotFound, NewNotFoundError("歌词"))
+		return
+	}
+
+	lyrics, err := services.LoadLyrics(song)
+	if errors.Is(err, services.ErrNoLyrics) {
+		c.JSON(http.StatusNotFound, NewNotFoundError("歌词"))
+		return
+	}
+	if err != nil {
+		logger.WithRequestID(requestID).Errorf("读取歌曲 %s 的歌词失败: %v", song.ID, err)
+		c.JSON(http.StatusInternalServerError, NewInternalError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    lyrics,
+	})
+}
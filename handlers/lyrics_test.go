@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"zero-music/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestGetSongLyrics 测试从 .lrc 文件返回带时间轴的歌词。
+func TestGetSongLyrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	lrcPath := filepath.Join(t.TempDir(), "song.lrc")
+	if err := os.WriteFile(lrcPath, []byte("[offset:100]\n[00:01.00]Hello\n[00:03.50]World\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	withLyrics := strings.Repeat("a", models.SongIDHexLength)
+	withoutLyrics := strings.Repeat("b", models.SongIDHexLength)
+	scanner := &staticScanner{songs: []*models.Song{
+		{ID: withLyrics, HasLyrics: true, LyricsPath: lrcPath},
+		{ID: withoutLyrics},
+	}}
+	router := gin.New()
+	router.GET("/api/song/:id/lyrics", NewLyricsHandler(scanner).GetSongLyrics)
+
+	req, _ := http.NewRequest("GET", "/api/song/"+withLyrics+"/lyrics", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("期望状态码 %d, 得到 %d", http.StatusOK, w.Code)
+	}
+	var response struct {
+		Data models.Lyrics `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	lyrics := response.Data
+	if lyrics.Source != models.LyricsSourceLRC || !lyrics.Synced || len(lyrics.Lines) != 2 {
+		t.Fatalf("歌词不符合预期: %+v", lyrics)
+	}
+	if lyrics.Lines[0].TimeMs != 900 || lyrics.Lines[1].Text != "World" {
+		t.Errorf("歌词行不符合预期: %+v", lyrics.Lines)
+	}
+
+	for _, id := range []string{withoutLyrics, strings.Repeat("c", models.SongIDHexLength)} {
+		req, _ = http.NewRequest("GET", "/api/song/"+id+"/lyrics", nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s: 期望状态码 %d, 得到 %d", id, http.StatusNotFound, w.Code)
+		}
+	}
+}
@@ -26,12 +26,25 @@ func NewPlaylistHandler(scanner services.Scanner) *PlaylistHandler {
 // @Description 返回音乐目录中所有可用的歌曲列表
 // @Tags playlist
 // @Produce json
+// @Param library query string false "音乐库名称"
+// @Param codec query string false "音频编码（如 flac、mp3）"
+// @Param lossless query bool false "是否只返回无损（或有损）音频"
+// @Param min_sample_rate query int false "最低采样率（Hz）"
+// @Param min_bit_depth query int false "最低位深"
+// @Param min_bitrate query int false "最低比特率（kbps）"
 // @Success 200 {object} map[string]interface{} "成功返回歌曲列表"
+// @Failure 400 {object} APIError "筛选参数错误"
 // @Failure 500 {object} APIError "服务器错误"
 // @Router /api/songs [get]
 func (h *PlaylistHandler) GetAllSongs(c *gin.Context) {
 	requestID := middleware.GetRequestID(c)
 
+	filter, err := parseAudioFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewBadRequestError(err.Error()))
+		return
+	}
+
 	// 扫描音乐文件。
 	songs, err := h.scanner.Scan(c.Request.Context())
 	if err != nil {
@@ -39,6 +52,7 @@ func (h *PlaylistHandler) GetAllSongs(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, NewInternalError(err))
 		return
 	}
+	songs = filter.apply(filterByLibrary(c, songs))
 
 	// 返回歌曲列表。
 	c.JSON(http.StatusOK, gin.H{
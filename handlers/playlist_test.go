@@ -168,3 +168,43 @@ func TestGetSongByID_InvalidFormat(t *testing.T) {
 		})
 	}
 }
+
+// TestGetAllSongs_LibraryFilter 测试 library 查询参数只返回指定音乐库中的歌曲。
+func TestGetAllSongs_LibraryFilter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	lossless := t.TempDir()
+	audiobooks := t.TempDir()
+	for _, f := range []string{filepath.Join(lossless, "a.mp3"), filepath.Join(lossless, "b.mp3"), filepath.Join(audiobooks, "c.mp3")} {
+		if err := os.WriteFile(f, []byte("fake mp3 "+f), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scanner := services.NewLibraryScanner([]services.Library{
+		{Name: "lossless", Directory: lossless},
+		{Name: "audiobooks", Directory: audiobooks},
+	}, []string{".mp3"}, 5, nil)
+	router := gin.New()
+	router.GET("/api/songs", NewPlaylistHandler(scanner).GetAllSongs)
+
+	testCases := map[string]int{
+		"":            3,
+		"lossless":    2,
+		"audiobooks":  1,
+		"nonexistent": 0,
+	}
+	for library, expected := range testCases {
+		req, _ := http.NewRequest("GET", "/api/songs?library="+library, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("解析响应失败: %v", err)
+		}
+		if total := int(response["total"].(float64)); total != expected {
+			t.Errorf("library=%q: 期望 %d 首歌曲, 得到 %d", library, expected, total)
+		}
+	}
+}
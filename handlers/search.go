@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"sort"
 	"strconv"
@@ -34,7 +35,13 @@ type SearchResult struct {
 // Search 综合搜索
 func (h *SearchHandler) Search(c *gin.Context) {
 	query := strings.TrimSpace(c.Query("q"))
-	if query == "" {
+	filter, err := parseAudioFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewBadRequestError(err.Error()))
+		return
+	}
+	// 只按音频属性筛选时可以不提供关键词
+	if query == "" && !filter.active() {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"code":    400,
 			"message": "搜索关键词不能为空",
@@ -53,7 +60,7 @@ func (h *SearchHandler) Search(c *gin.Context) {
 		offset = 0
 	}
 
-	songs := h.scanner.GetSongs()
+	songs := filter.apply(filterByLibrary(c, h.scanner.GetSongs()))
 	queryLower := strings.ToLower(query)
 
 	var matchedSongs []*models.Song
@@ -69,7 +76,9 @@ func (h *SearchHandler) Search(c *gin.Context) {
 				matched = true
 			}
 		case "artist":
-			if containsIgnoreCase(song.Artist, queryLower) {
+			if containsIgnoreCase(song.Artist, queryLower) ||
+				containsIgnoreCase(song.AlbumArtist, queryLower) ||
+				containsIgnoreCase(song.Composer, queryLower) {
 				matched = true
 			}
 		case "album":
@@ -79,6 +88,8 @@ func (h *SearchHandler) Search(c *gin.Context) {
 		default: // all
 			if containsIgnoreCase(song.Title, queryLower) ||
 				containsIgnoreCase(song.Artist, queryLower) ||
+				containsIgnoreCase(song.AlbumArtist, queryLower) ||
+				containsIgnoreCase(song.Composer, queryLower) ||
 				containsIgnoreCase(song.Album, queryLower) {
 				matched = true
 			}
@@ -86,8 +97,8 @@ func (h *SearchHandler) Search(c *gin.Context) {
 
 		if matched {
 			matchedSongs = append(matchedSongs, song)
-			if song.Artist != "" {
-				artistSet[song.Artist] = true
+			for _, artist := range song.ArtistNames() {
+				artistSet[artist] = true
 			}
 			if song.Album != "" {
 				albumSet[song.Album] = true
@@ -146,23 +157,26 @@ func (h *SearchHandler) Search(c *gin.Context) {
 
 // GetArtists 获取所有艺术家列表
 func (h *SearchHandler) GetArtists(c *gin.Context) {
-	songs := h.scanner.GetSongs()
+	songs := filterByLibrary(c, h.scanner.GetSongs())
 
+	// 多位艺术家合作的歌曲计入每一位艺术家
 	artistMap := make(map[string]int) // 艺术家 -> 歌曲数量
 	for _, song := range songs {
-		if song.Artist != "" {
-			artistMap[song.Artist]++
+		for _, artist := range song.ArtistNames() {
+			artistMap[artist]++
 		}
 	}
+	images := artistImages(songs)
 
 	type ArtistInfo struct {
 		Name      string `json:"name"`
 		SongCount int    `json:"song_count"`
+		HasImage  bool   `json:"has_image"`
 	}
 
 	var artists []ArtistInfo
 	for name, count := range artistMap {
-		artists = append(artists, ArtistInfo{Name: name, SongCount: count})
+		artists = append(artists, ArtistInfo{Name: name, SongCount: count, HasImage: images[strings.ToLower(name)] != ""})
 	}
 
 	// 按歌曲数量排序
@@ -183,7 +197,7 @@ func (h *SearchHandler) GetArtists(c *gin.Context) {
 	})
 }
 
-// GetArtistSongs 获取指定艺术家的歌曲
+// GetArtistSongs 获取指定艺术家的歌曲，包括该艺术家参与合作的歌曲和作为专辑艺术家的专辑中的歌曲
 func (h *SearchHandler) GetArtistSongs(c *gin.Context) {
 	artist := c.Param("name")
 	if artist == "" {
@@ -194,12 +208,12 @@ func (h *SearchHandler) GetArtistSongs(c *gin.Context) {
 		return
 	}
 
-	songs := h.scanner.GetSongs()
+	songs := filterByLibrary(c, h.scanner.GetSongs())
 	var artistSongs []*models.Song
 	albumSet := make(map[string]bool)
 
 	for _, song := range songs {
-		if strings.EqualFold(song.Artist, artist) {
+		if songHasArtist(song, artist) {
 			artistSongs = append(artistSongs, song)
 			if song.Album != "" {
 				albumSet[song.Album] = true
@@ -225,17 +239,19 @@ func (h *SearchHandler) GetArtistSongs(c *gin.Context) {
 		"code":    0,
 		"message": "success",
 		"data": gin.H{
-			"artist": artist,
-			"songs":  artistSongs,
-			"albums": albums,
-			"total":  len(artistSongs),
+			"artist":    artist,
+			"has_image": artistImages(artistSongs)[strings.ToLower(artist)] != "",
+			"songs":     artistSongs,
+			"albums":    albums,
+			"total":     len(artistSongs),
 		},
 	})
 }
 
-// GetAlbums 获取所有专辑列表
+// GetAlbums 获取所有专辑列表。专辑按专辑艺术家和专辑名分组，没有专辑艺术家标签时使用第一位艺术家，
+// 合辑中不同艺术家的曲目归入同一张专辑
 func (h *SearchHandler) GetAlbums(c *gin.Context) {
-	songs := h.scanner.GetSongs()
+	songs := filterByLibrary(c, h.scanner.GetSongs())
 
 	type AlbumInfo struct {
 		Name      string `json:"name"`
@@ -249,13 +265,14 @@ func (h *SearchHandler) GetAlbums(c *gin.Context) {
 		if song.Album == "" {
 			continue
 		}
-		key := song.Album + "|" + song.Artist
+		albumArtist := song.AlbumArtistName()
+		key := song.Album + "|" + albumArtist
 		if info, exists := albumMap[key]; exists {
 			info.SongCount++
 		} else {
 			albumMap[key] = &AlbumInfo{
 				Name:      song.Album,
-				Artist:    song.Artist,
+				Artist:    albumArtist,
 				SongCount: 1,
 				Year:      song.Year,
 			}
@@ -267,9 +284,12 @@ func (h *SearchHandler) GetAlbums(c *gin.Context) {
 		albums = append(albums, info)
 	}
 
-	// 按专辑名排序
+	// 按专辑名排序，同名专辑按艺术家排序
 	sort.Slice(albums, func(i, j int) bool {
-		return albums[i].Name < albums[j].Name
+		if albums[i].Name != albums[j].Name {
+			return albums[i].Name < albums[j].Name
+		}
+		return albums[i].Artist < albums[j].Artist
 	})
 
 	c.JSON(http.StatusOK, gin.H{
@@ -282,7 +302,7 @@ func (h *SearchHandler) GetAlbums(c *gin.Context) {
 	})
 }
 
-// GetAlbumSongs 获取指定专辑的歌曲
+// GetAlbumSongs 获取指定专辑的歌曲，可通过 artist 查询参数按专辑艺术家区分同名专辑
 func (h *SearchHandler) GetAlbumSongs(c *gin.Context) {
 	album := c.Param("name")
 	if album == "" {
@@ -293,20 +313,24 @@ func (h *SearchHandler) GetAlbumSongs(c *gin.Context) {
 		return
 	}
 
-	songs := h.scanner.GetSongs()
+	artist := strings.TrimSpace(c.Query("artist"))
+	songs := filterByLibrary(c, h.scanner.GetSongs())
 	var albumSongs []*models.Song
-	var artist string
 	var year int
 
 	for _, song := range songs {
-		if strings.EqualFold(song.Album, album) {
-			albumSongs = append(albumSongs, song)
-			if artist == "" && song.Artist != "" {
-				artist = song.Artist
-			}
-			if year == 0 && song.Year > 0 {
-				year = song.Year
-			}
+		if !strings.EqualFold(song.Album, album) {
+			continue
+		}
+		if artist != "" && !strings.EqualFold(song.AlbumArtistName(), artist) {
+			continue
+		}
+		albumSongs = append(albumSongs, song)
+		if artist == "" {
+			artist = song.AlbumArtistName()
+		}
+		if year == 0 && song.Year > 0 {
+			year = song.Year
 		}
 	}
 
@@ -335,3 +359,127 @@ func (h *SearchHandler) GetAlbumSongs(c *gin.Context) {
 func containsIgnoreCase(s, substr string) bool {
 	return strings.Contains(strings.ToLower(s), substr)
 }
+
+// songHasArtist 判断艺术家是否是歌曲的艺术家之一或专辑艺术家（不区分大小写）。
+func songHasArtist(song *models.Song, artist string) bool {
+	if strings.EqualFold(song.AlbumArtist, artist) {
+		return true
+	}
+	for _, name := range song.ArtistNames() {
+		if strings.EqualFold(name, artist) {
+			return true
+		}
+	}
+	return false
+}
+
+// artistImages 返回小写艺术家名称 -> 艺术家图片路径。艺术家目录通常按专辑艺术家组织，图片归属于歌曲的专辑艺术家。
+// 同一艺术家有多张图片时取路径最小的一张，保证结果稳定。
+func artistImages(songs []*models.Song) map[string]string {
+	images := make(map[string]string)
+	for _, song := range songs {
+		artist := song.AlbumArtistName()
+		if song.ArtistImagePath == "" || artist == "" {
+			continue
+		}
+		key := strings.ToLower(artist)
+		if current, ok := images[key]; !ok || song.ArtistImagePath < current {
+			images[key] = song.ArtistImagePath
+		}
+	}
+	return images
+}
+
+// filterByLibrary 按 library 查询参数筛选歌曲，未指定该参数时返回全部歌曲。
+func filterByLibrary(c *gin.Context, songs []*models.Song) []*models.Song {
+	library := strings.TrimSpace(c.Query("library"))
+	if library == "" {
+		return songs
+	}
+
+	filtered := make([]*models.Song, 0, len(songs))
+	for _, song := range songs {
+		name := song.Library
+		if name == "" {
+			name = models.DefaultLibraryName
+		}
+		if name == library {
+			filtered = append(filtered, song)
+		}
+	}
+	return filtered
+}
+
+// audioFilter 是按音频技术属性筛选歌曲的条件，零值表示不限制。
+type audioFilter struct {
+	codec         string
+	lossless      *bool
+	minSampleRate int
+	minBitDepth   int
+	minBitrate    int
+}
+
+// parseAudioFilter 从查询参数 codec、lossless、min_sample_rate、min_bit_depth 和 min_bitrate 解析筛选条件。
+func parseAudioFilter(c *gin.Context) (*audioFilter, error) {
+	filter := &audioFilter{codec: strings.ToLower(strings.TrimSpace(c.Query("codec")))}
+
+	if value := c.Query("lossless"); value != "" {
+		lossless, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("lossless 参数必须是布尔值")
+		}
+		filter.lossless = &lossless
+	}
+
+	minimums := []struct {
+		name   string
+		target *int
+	}{
+		{"min_sample_rate", &filter.minSampleRate},
+		{"min_bit_depth", &filter.minBitDepth},
+		{"min_bitrate", &filter.minBitrate},
+	}
+	for _, m := range minimums {
+		value := c.Query(m.name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%s 参数必须是非负整数", m.name)
+		}
+		*m.target = n
+	}
+	return filter, nil
+}
+
+// active 判断是否设置了任何筛选条件。
+func (f *audioFilter) active() bool {
+	return f.codec != "" || f.lossless != nil || f.minSampleRate > 0 || f.minBitDepth > 0 || f.minBitrate > 0
+}
+
+// matches 判断歌曲是否满足全部筛选条件。
+func (f *audioFilter) matches(song *models.Song) bool {
+	if f.codec != "" && song.Codec != f.codec {
+		return false
+	}
+	if f.lossless != nil && song.Lossless != *f.lossless {
+		return false
+	}
+	return song.SampleRate >= f.minSampleRate && song.BitDepth >= f.minBitDepth && song.Bitrate >= f.minBitrate
+}
+
+// apply 返回满足筛选条件的歌曲，未设置条件时原样返回。
+func (f *audioFilter) apply(songs []*models.Song) []*models.Song {
+	if !f.active() {
+		return songs
+	}
+
+	filtered := make([]*models.Song, 0, len(songs))
+	for _, song := range songs {
+		if f.matches(song) {
+			filtered = append(filtered, song)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"zero-music/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// staticScanner 是返回固定歌曲列表的 Scanner 实现，用于不依赖磁盘文件的处理器测试。
+type staticScanner struct {
+	songs []*models.Song
+}
+
+func (s *staticScanner) Scan(ctx context.Context) ([]*models.Song, error) { return s.songs, nil }
+func (s *staticScanner) Refresh(ctx context.Context) error                { return nil }
+func (s *staticScanner) GetSongs() []*models.Song                         { return s.songs }
+func (s *staticScanner) GetSongCount() int                                { return len(s.songs) }
+
+func (s *staticScanner) GetSongByID(id string) *models.Song {
+	for _, song := range s.songs {
+		if song.ID == id {
+			return song
+		}
+	}
+	return nil
+}
+
+// TestSearch_AudioFilters 测试按音频技术属性筛选搜索结果。
+func TestSearch_AudioFilters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	scanner := &staticScanner{songs: []*models.Song{
+		{ID: "1", Title: "Song Hires", Codec: models.CodecFLAC, Lossless: true, SampleRate: 96000, BitDepth: 24, Bitrate: 2800},
+		{ID: "2", Title: "Song CD", Codec: models.CodecFLAC, Lossless: true, SampleRate: 44100, BitDepth: 16, Bitrate: 900},
+		{ID: "3", Title: "Song Lossy", Codec: models.CodecMP3, SampleRate: 44100, Bitrate: 320},
+	}}
+	router := gin.New()
+	router.GET("/api/search", NewSearchHandler(scanner).Search)
+
+	testCases := map[string]int{
+		"q=song":                                 3,
+		"q=song&lossless=true":                   2,
+		"q=song&lossless=false":                  1,
+		"q=song&codec=MP3":                       1,
+		"lossless=true&min_sample_rate=96000":    1,
+		"min_bit_depth=16&min_bitrate=1000":      1,
+		"q=lossy&lossless=true":                  0,
+		"codec=flac&min_sample_rate=44100&q=cd":  1,
+		"min_bitrate=0&codec=opus&lossless=true": 0,
+	}
+	for query, expected := range testCases {
+		req, _ := http.NewRequest("GET", "/api/search?"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: 期望状态码 %d, 得到 %d", query, http.StatusOK, w.Code)
+			continue
+		}
+		var response struct {
+			Data SearchResult `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("解析响应失败: %v", err)
+		}
+		if response.Data.Total != expected {
+			t.Errorf("%s: 期望 %d 首歌曲, 得到 %d", query, expected, response.Data.Total)
+		}
+	}
+
+	for _, query := range []string{"", "q=song&lossless=maybe", "min_sample_rate=-1", "q=song&min_bitrate=high"} {
+		req, _ := http.NewRequest("GET", "/api/search?"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%q: 期望状态码 %d, 得到 %d", query, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+// TestAlbumsAndArtists_MultiArtist 测试合辑按专辑艺术家分组，以及合作歌曲出现在每位艺术家名下。
+func TestAlbumsAndArtists_MultiArtist(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	scanner := &staticScanner{songs: []*models.Song{
+		{ID: "1", Title: "One", Artist: "A", Album: "Hits", AlbumArtist: "Various Artists", Track: 2},
+		{ID: "2", Title: "Two", Artist: "B feat. C", Artists: []string{"B", "C"}, Album: "Hits", AlbumArtist: "Various Artists", Track: 1},
+		{ID: "3", Title: "Three", Artist: "C", Album: "Solo"},
+		{ID: "4", Title: "Four", Artist: "C feat. D", Artists: []string{"C", "D"}, Album: "Solo"},
+		{ID: "5", Title: "Five", Artist: "E", Album: "Hits"},
+	}}
+	handler := NewSearchHandler(scanner)
+	router := gin.New()
+	router.GET("/api/albums", handler.GetAlbums)
+	router.GET("/api/albums/:name", handler.GetAlbumSongs)
+	router.GET("/api/artists", handler.GetArtists)
+	router.GET("/api/artists/:name", handler.GetArtistSongs)
+
+	get := func(path string, out interface{}) {
+		t.Helper()
+		req, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: 期望状态码 %d, 得到 %d", path, http.StatusOK, w.Code)
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s: 解析响应失败: %v", path, err)
+		}
+	}
+
+	var albums struct {
+		Data struct {
+			Albums []struct {
+				Name      string `json:"name"`
+				Artist    string `json:"artist"`
+				SongCount int    `json:"song_count"`
+			} `json:"albums"`
+		} `json:"data"`
+	}
+	get("/api/albums", &albums)
+	var got []string
+	for _, album := range albums.Data.Albums {
+		got = append(got, fmt.Sprintf("%s/%s/%d", album.Name, album.Artist, album.SongCount))
+	}
+	if expected := "[Hits/E/1 Hits/Various Artists/2 Solo/C/2]"; fmt.Sprint(got) != expected {
+		t.Errorf("期望专辑 %s, 得到 %v", expected, got)
+	}
+
+	var albumSongs struct {
+		Data struct {
+			Artist string         `json:"artist"`
+			Songs  []*models.Song `json:"songs"`
+		} `json:"data"`
+	}
+	get("/api/albums/Hits?artist=various%20artists", &albumSongs)
+	if len(albumSongs.Data.Songs) != 2 || albumSongs.Data.Songs[0].ID != "2" || albumSongs.Data.Artist != "various artists" {
+		t.Errorf("期望按专辑艺术家筛选出 2 首歌曲, 得到 %+v", albumSongs.Data)
+	}
+
+	var artists struct {
+		Data struct {
+			Artists []struct {
+				Name      string `json:"name"`
+				SongCount int    `json:"song_count"`
+			} `json:"artists"`
+		} `json:"data"`
+	}
+	get("/api/artists", &artists)
+	counts := make(map[string]int)
+	for _, artist := range artists.Data.Artists {
+		counts[artist.Name] = artist.SongCount
+	}
+	if fmt.Sprint(counts) != "map[A:1 B:1 C:3 D:1 E:1]" {
+		t.Errorf("艺术家统计不符合预期: %v", counts)
+	}
+
+	var artistSongs struct {
+		Data struct {
+			Total int `json:"total"`
+		} `json:"data"`
+	}
+	get("/api/artists/c", &artistSongs)
+	if artistSongs.Data.Total != 3 {
+		t.Errorf("期望 C 名下有 3 首歌曲, 得到 %d", artistSongs.Data.Total)
+	}
+	get("/api/artists/Various%20Artists", &artistSongs)
+	if artistSongs.Data.Total != 2 {
+		t.Errorf("期望专辑艺术家名下有 2 首歌曲, 得到 %d", artistSongs.Data.Total)
+	}
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"zero-music/models"
+	"zero-music/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SharedPlaylistHandler 负责处理音乐目录中播放列表文件的 API 请求，这些播放列表对所有用户只读共享。
+type SharedPlaylistHandler struct {
+	library *services.MusicScanner
+	scanner services.Scanner
+}
+
+// NewSharedPlaylistHandler 创建一个新的 SharedPlaylistHandler 实例。
+// library 提供音乐目录中的播放列表，播放列表中的歌曲通过 scanner 查找，与其他接口返回的歌曲一致。
+func NewSharedPlaylistHandler(library *services.MusicScanner, scanner services.Scanner) *SharedPlaylistHandler {
+	return &SharedPlaylistHandler{library: library, scanner: scanner}
+}
+
+// GetSharedPlaylists 返回音乐目录中的所有共享播放列表。
+// @Summary 获取共享播放列表
+// @Description 返回音乐目录中的 M3U、M3U8、PLS 和 XSPF 播放列表，列表中不包含无法解析的条目明细
+// @Tags playlists
+// @Produce json
+// @Param library query string false "音乐库名称"
+// @Success 200 {array} models.SharedPlaylist "共享播放列表"
+// @Router /api/playlists [get]
+func (h *SharedPlaylistHandler) GetSharedPlaylists(c *gin.Context) {
+	library := strings.TrimSpace(c.Query("library"))
+	playlists := make([]*models.SharedPlaylist, 0)
+	for _, playlist := range h.library.Playlists() {
+		if library != "" && playlist.Library != library {
+			continue
+		}
+		playlist.Unresolved = nil
+		playlists = append(playlists, playlist)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    playlists,
+	})
+}
+
+// GetSharedPlaylist 返回共享播放列表及其中的歌曲。
+// @Summary 获取共享播放列表详情
+// @Description 按播放列表文件中的顺序返回歌曲，无法对应到歌曲库中歌曲的条目在 unresolved 中列出
+// @Tags playlists
+// @Produce json
+// @Param id path string true "播放列表ID"
+// @Success 200 {object} map[string]interface{} "播放列表和歌曲"
+// @Failure 400 {object} APIError "请求参数错误"
+// @Failure 404 {object} APIError "播放列表未找到"
+// @Router /api/playlists/{id} [get]
+func (h *SharedPlaylistHandler) GetSharedPlaylist(c *gin.Context) {
+	id := c.Param("id")
+	if !models.ValidIDRegex.MatchString(id) {
+		c.JSON(http.StatusBadRequest, NewBadRequestError("无效的播放列表 ID 格式"))
+		return
+	}
+
+	playlist := h.library.GetPlaylistByID(id)
+	if playlist == nil {
+		c.JSON(http.StatusNotFound, NewNotFoundError("播放列表"))
+		return
+	}
+
+	songs := make([]*models.Song, 0, len(playlist.SongIDs))
+	for _, songID := range playlist.SongIDs {
+		if song := h.scanner.GetSongByID(songID); song != nil {
+			songs = append(songs, song)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"playlist": playlist,
+			"songs":    songs,
+		},
+	})
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"zero-music/models"
+	"zero-music/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// retitledScanner 修改按 ID 查找到的歌曲的标题，用于确认歌曲通过注入的 Scanner 查找。
+type retitledScanner struct {
+	services.Scanner
+	title string
+}
+
+func (s *retitledScanner) GetSongByID(id string) *models.Song {
+	song := s.Scanner.GetSongByID(id)
+	if song != nil {
+		song.Title = s.title
+	}
+	return song
+}
+
+// TestSharedPlaylists 测试列出音乐目录中的播放列表并按 ID 获取其中的歌曲和无法解析的条目。
+func TestSharedPlaylists(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "song.mp3"), make([]byte, 4096), 0644); err != nil {
+		t.Fatal(err)
+	}
+	list := "[playlist]\nFile1=song.mp3\nFile2=gone.mp3\nNumberOfEntries=2\n"
+	if err := os.WriteFile(filepath.Join(dir, "best.pls"), []byte(list), 0644); err != nil {
+		t.Fatal(err)
+	}
+	scanner := services.NewMusicScanner(dir, []string{".mp3"}, 5)
+	if _, err := scanner.Scan(context.Background()); err != nil {
+		t.Fatalf("扫描失败: %v", err)
+	}
+
+	handler := NewSharedPlaylistHandler(scanner, &retitledScanner{Scanner: scanner, title: "Overridden"})
+	router := gin.New()
+	router.GET("/api/playlists", handler.GetSharedPlaylists)
+	router.GET("/api/playlists/:id", handler.GetSharedPlaylist)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/playlists", nil)
+	router.ServeHTTP(w, req)
+	var listed struct {
+		Data []*models.SharedPlaylist `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &listed); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	if w.Code != http.StatusOK || len(listed.Data) != 1 {
+		t.Fatalf("期望 1 个播放列表, 得到 %d %s", w.Code, w.Body.String())
+	}
+	playlist := listed.Data[0]
+	if playlist.Name != "best" || playlist.SongCount != 1 || playlist.UnresolvedCount != 1 || playlist.Unresolved != nil {
+		t.Errorf("列表中的播放列表不符合预期: %+v", playlist)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/playlists/"+playlist.ID, nil)
+	router.ServeHTTP(w, req)
+	var detail struct {
+		Data struct {
+			Playlist *models.SharedPlaylist `json:"playlist"`
+			Songs    []*models.Song         `json:"songs"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &detail); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	if w.Code != http.StatusOK || len(detail.Data.Songs) != 1 || detail.Data.Songs[0].FileName != "song.mp3" {
+		t.Fatalf("期望播放列表包含 song.mp3, 得到 %d %s", w.Code, w.Body.String())
+	}
+	if detail.Data.Songs[0].Title != "Overridden" {
+		t.Errorf("期望歌曲通过注入的 Scanner 查找, 得到标题 %q", detail.Data.Songs[0].Title)
+	}
+	if unresolved := detail.Data.Playlist.Unresolved; len(unresolved) != 1 || unresolved[0].Location != "gone.mp3" || unresolved[0].Position != 2 {
+		t.Errorf("期望列出无法解析的 gone.mp3, 得到 %+v", unresolved)
+	}
+
+	testCases := map[string]int{
+		strings.Repeat("a", models.SongIDHexLength): http.StatusNotFound,
+		"not-an-id": http.StatusBadRequest,
+	}
+	for id, want := range testCases {
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/api/playlists/"+id, nil)
+		router.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("%s: 期望状态码 %d, 得到 %d", id, want, w.Code)
+		}
+	}
+}
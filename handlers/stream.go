@@ -11,6 +11,7 @@ import (
 	"zero-music/config"
 	"zero-music/logger"
 	"zero-music/middleware"
+	"zero-music/models"
 	"zero-music/services"
 	"zero-music/utils"
 
@@ -20,30 +21,42 @@ import (
 // StreamHandler 负责处理音频流相关的 API 请求。
 type StreamHandler struct {
 	scanner      services.Scanner
-	musicDir     string
-	musicDirAbs  string // 预先计算的音乐目录绝对路径，用于安全检查。
-	maxRangeSize int64  // 单次 Range 请求允许的最大字节数。
+	libraryRoots map[string]string // 音乐库名称 -> 预先计算的根目录绝对路径，用于安全检查。
+	maxRangeSize int64             // 单次 Range 请求允许的最大字节数。
 }
 
 // NewStreamHandler 创建一个新的 StreamHandler 实例。
 func NewStreamHandler(scanner services.Scanner, cfg *config.Config) *StreamHandler {
-	musicDirAbs, err := filepath.Abs(cfg.Music.Directory)
-	if err != nil {
-		logger.Warnf("获取音乐目录的绝对路径失败: %v", err)
-		musicDirAbs = cfg.Music.Directory
+	libraryRoots := make(map[string]string)
+	for _, lib := range cfg.Music.LibraryList() {
+		root, err := filepath.Abs(lib.Directory)
+		if err != nil {
+			logger.Warnf("获取音乐库 %s 的绝对路径失败: %v", lib.Name, err)
+			root = lib.Directory
+		}
+		libraryRoots[lib.Name] = root
 	}
 	return &StreamHandler{
 		scanner:      scanner,
-		musicDir:     cfg.Music.Directory,
-		musicDirAbs:  musicDirAbs,
+		libraryRoots: libraryRoots,
 		maxRangeSize: cfg.Server.MaxRangeSize,
 	}
 }
 
+// libraryRoot 返回歌曲所属音乐库的根目录，旧版本的记录没有库名，视为默认库。
+func (h *StreamHandler) libraryRoot(song *models.Song) (string, bool) {
+	name := song.Library
+	if name == "" {
+		name = models.DefaultLibraryName
+	}
+	root, ok := h.libraryRoots[name]
+	return root, ok
+}
+
 // StreamAudio 处理流式传输音频文件的请求。
 // 它支持完整的音频文件传输和基于 Range 请求的部分内容传输。
 // @Summary 流式传输音频
-// @Description 通过 HTTP 流式传输指定的音频文件
+// @Description 通过 HTTP 流式传输指定的音频文件，CUE 曲目只传输整轨文件中属于该曲目的部分。歌曲有音量信息时通过 X-ReplayGain-* 响应头返回增益和峰值
 // @Tags stream
 // @Produce audio/mpeg
 // @Param id path string true "歌曲ID"
@@ -80,10 +93,16 @@ func (h *StreamHandler) StreamAudio(c *gin.Context) {
 		return
 	}
 
-	// 确保请求的路径位于配置的音乐目录内，使用更严格的路径验证防止目录遍历攻击。
-	relPath, err := filepath.Rel(h.musicDirAbs, cleanPath)
+	// 确保请求的路径位于歌曲所属音乐库的根目录内，使用更严格的路径验证防止目录遍历攻击。
+	libraryRoot, ok := h.libraryRoot(song)
+	if !ok {
+		logger.WithRequestID(requestID).Warnf("安全警告: 歌曲 %s 所属的音乐库 %s 未配置", id, song.Library)
+		c.JSON(http.StatusForbidden, NewForbiddenError("拒绝访问"))
+		return
+	}
+	relPath, err := filepath.Rel(libraryRoot, cleanPath)
 	if err != nil || strings.HasPrefix(relPath, "..") || filepath.IsAbs(relPath) {
-		logger.WithRequestID(requestID).Warnf("安全警告: 路径遍历尝试 - 路径 %s 不在音乐目录 %s 内", cleanPath, h.musicDirAbs)
+		logger.WithRequestID(requestID).Warnf("安全警告: 路径遍历尝试 - 路径 %s 不在音乐库目录 %s 内", cleanPath, libraryRoot)
 		c.JSON(http.StatusForbidden, NewForbiddenError("拒绝访问"))
 		return
 	}
@@ -116,7 +135,18 @@ func (h *StreamHandler) StreamAudio(c *gin.Context) {
 	}
 	defer file.Close()
 
+	// CUE 曲目只传输整轨文件中属于该曲目的部分，由新的文件头和对应的音频数据组成
+	var content io.ReadSeeker = file
 	fileSize := fileInfo.Size()
+	if song.IsCueTrack() {
+		track, err := services.OpenCueTrack(file, fileSize, song)
+		if err != nil {
+			logger.WithRequestID(requestID).Errorf("截取 CUE 曲目失败 %s: %v", cleanPath, err)
+			c.JSON(http.StatusInternalServerError, NewInternalError(err))
+			return
+		}
+		content, fileSize = track, track.Size()
+	}
 
 	// 记录访问日志（非调试模式下不记录路径信息以保护隐私）。
 	logFields := map[string]interface{}{
@@ -126,15 +156,17 @@ func (h *StreamHandler) StreamAudio(c *gin.Context) {
 	}
 	// 仅在调试模式下记录相对路径
 	if isDebugMode() {
-		relFilePath, _ := filepath.Rel(h.musicDirAbs, cleanPath)
-		logFields["rel_path"] = relFilePath
+		logFields["library"] = song.Library
+		logFields["rel_path"] = relPath
 	}
 	logger.WithRequestID(requestID).WithFields(logFields).Info("音频流请求")
 
+	setReplayGainHeaders(c, song.ReplayGain)
+
 	// 处理 Range 请求以支持断点续传。
 	rangeHeader := c.GetHeader("Range")
 	if rangeHeader != "" {
-		h.serveRange(c, file, fileSize, rangeHeader, filepath.Base(cleanPath), requestID)
+		h.serveRange(c, content, fileSize, rangeHeader, filepath.Base(cleanPath), requestID)
 		return
 	}
 
@@ -147,14 +179,35 @@ func (h *StreamHandler) StreamAudio(c *gin.Context) {
 
 	// 流式传输整个文件。
 	c.Status(http.StatusOK)
-	written, err := io.Copy(c.Writer, file)
+	written, err := io.Copy(c.Writer, content)
 	if err != nil {
 		logger.WithRequestID(requestID).Errorf("流式传输音频时出错 (已写入 %d/%d 字节): %v", written, fileSize, err)
 	}
 }
 
+// setReplayGainHeaders 以响应头的形式提供歌曲的音量平衡信息，供播放器在播放时调整音量。
+// 增益的格式与 ReplayGain 标签一致，例如 "-6.52 dB"。
+func setReplayGainHeaders(c *gin.Context, rg *models.ReplayGain) {
+	if rg == nil {
+		return
+	}
+	if rg.TrackGain != nil {
+		c.Header("X-ReplayGain-Track-Gain", fmt.Sprintf("%.2f dB", *rg.TrackGain))
+	}
+	if rg.TrackPeak != nil {
+		c.Header("X-ReplayGain-Track-Peak", fmt.Sprintf("%.6f", *rg.TrackPeak))
+	}
+	if rg.AlbumGain != nil {
+		c.Header("X-ReplayGain-Album-Gain", fmt.Sprintf("%.2f dB", *rg.AlbumGain))
+	}
+	if rg.AlbumPeak != nil {
+		c.Header("X-ReplayGain-Album-Peak", fmt.Sprintf("%.6f", *rg.AlbumPeak))
+	}
+	c.Header("X-ReplayGain-Source", rg.Source)
+}
+
 // serveRange 处理 HTTP Range 请求，用于支持音频的断点续传。
-func (h *StreamHandler) serveRange(c *gin.Context, file *os.File, fileSize int64, rangeHeader string, filename string, requestID string) {
+func (h *StreamHandler) serveRange(c *gin.Context, file io.ReadSeeker, fileSize int64, rangeHeader string, filename string, requestID string) {
 	ranges := strings.TrimPrefix(rangeHeader, "bytes=")
 	parts := strings.Split(ranges, "-")
 
@@ -212,7 +265,7 @@ func (h *StreamHandler) serveRange(c *gin.Context, file *os.File, fileSize int64
 	c.Status(http.StatusPartialContent)
 
 	// 将文件指针移动到请求的起始位置。
-	_, err := file.Seek(start, 0)
+	_, err := file.Seek(start, io.SeekStart)
 	if err != nil {
 		logger.WithRequestID(requestID).Errorf("定位文件到 %d 位置失败: %v", start, err)
 		c.JSON(http.StatusInternalServerError, NewInternalError(err))
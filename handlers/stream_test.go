@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -8,6 +9,7 @@ import (
 	"path/filepath"
 	"testing"
 	"zero-music/config"
+	"zero-music/models"
 	"zero-music/services"
 
 	"github.com/gin-gonic/gin"
@@ -207,3 +209,136 @@ func TestStreamAudio_RangeTooLarge(t *testing.T) {
 		t.Fatalf("期望状态码 400, 得到 %d", w.Code)
 	}
 }
+
+// setupMultiLibraryStreamEnv 创建两个音乐库，扫描器按 scannerLibs 索引，处理器按 handlerLibs 进行路径限制。
+func setupMultiLibraryStreamEnv(t *testing.T, scannerLibs []services.Library, handlerLibs []config.LibraryConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{MaxRangeSize: 100 * 1024 * 1024},
+		Music: config.MusicConfig{
+			Libraries:        handlerLibs,
+			SupportedFormats: []string{".mp3"},
+			CacheTTLMinutes:  5,
+		},
+	}
+	scanner := services.NewLibraryScanner(scannerLibs, cfg.Music.SupportedFormats, cfg.Music.CacheTTLMinutes, nil)
+
+	router := gin.New()
+	router.GET("/api/songs", NewPlaylistHandler(scanner).GetAllSongs)
+	router.GET("/api/stream/:id", NewStreamHandler(scanner, cfg).StreamAudio)
+	return router
+}
+
+// TestStreamAudio_MultipleLibraries 测试每首歌曲只允许从其所属音乐库的根目录中读取。
+func TestStreamAudio_MultipleLibraries(t *testing.T) {
+	lossless := t.TempDir()
+	lossy := t.TempDir()
+	if err := os.WriteFile(filepath.Join(lossy, "track.mp3"), []byte("fake mp3 data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	handlerLibs := []config.LibraryConfig{
+		{Name: "lossless", Directory: lossless},
+		{Name: "lossy", Directory: lossy},
+	}
+
+	t.Run("歌曲位于所属库内", func(t *testing.T) {
+		router := setupMultiLibraryStreamEnv(t, []services.Library{{Name: "lossy", Directory: lossy}}, handlerLibs)
+		req, _ := http.NewRequest("GET", "/api/stream/"+getSongID(t, router), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("期望状态码 200, 得到 %d", w.Code)
+		}
+	})
+
+	t.Run("歌曲不在所属库内", func(t *testing.T) {
+		// 歌曲被标记为 lossless 库，但文件位于 lossy 库中，即使该路径属于另一个已配置的库也应该被拒绝
+		router := setupMultiLibraryStreamEnv(t, []services.Library{{Name: "lossless", Directory: lossy}}, handlerLibs)
+		req, _ := http.NewRequest("GET", "/api/stream/"+getSongID(t, router), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("期望状态码 403, 得到 %d", w.Code)
+		}
+	})
+}
+
+// TestSetReplayGainHeaders 测试音量信息以 X-ReplayGain-* 响应头返回，未知的字段不输出。
+func TestSetReplayGainHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	gain, peak := -6.5, 0.988553
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	setReplayGainHeaders(c, &models.ReplayGain{TrackGain: &gain, TrackPeak: &peak, Source: models.LoudnessSourceReplayGain})
+
+	want := map[string]string{
+		"X-ReplayGain-Track-Gain": "-6.50 dB",
+		"X-ReplayGain-Track-Peak": "0.988553",
+		"X-ReplayGain-Album-Gain": "",
+		"X-ReplayGain-Album-Peak": "",
+		"X-ReplayGain-Source":     "replaygain",
+	}
+	for name, value := range want {
+		if got := w.Header().Get(name); got != value {
+			t.Errorf("期望 %s 为 %q, 得到 %q", name, value, got)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	setReplayGainHeaders(c, nil)
+	if len(w.Header()) != 0 {
+		t.Errorf("没有音量信息时不应设置响应头, 得到 %v", w.Header())
+	}
+}
+
+// TestStreamAudio_CueTrack 测试 CUE 曲目只传输整轨文件中属于该曲目的部分，并支持 Range 请求。
+func TestStreamAudio_CueTrack(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tmpDir := t.TempDir()
+
+	// 8kHz 单声道 8 位 WAV，每个采样 1 字节
+	samples := make([]byte, 4000)
+	for i := range samples {
+		samples[i] = byte(i)
+	}
+	wav := append([]byte("RIFF\x00\x00\x00\x00WAVEfmt \x10\x00\x00\x00\x01\x00\x01\x00\x40\x1f\x00\x00\x40\x1f\x00\x00\x01\x00\x08\x00data\xa0\x0f\x00\x00"), samples...)
+	imagePath := filepath.Join(tmpDir, "image.wav")
+	if err := os.WriteFile(imagePath, wav, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 第二个曲目从 00:00:15（200 毫秒，第 1600 个采样）开始
+	id := models.LibrarySongID(models.DefaultLibraryName, "image.wav#2")
+	scanner := &staticScanner{songs: []*models.Song{{
+		ID: id, FilePath: imagePath, Library: models.DefaultLibraryName,
+		CueSheetPath: filepath.Join(tmpDir, "image.cue"), StartMs: 200,
+	}}}
+	cfg := &config.Config{
+		Server: config.ServerConfig{MaxRangeSize: 100 * 1024 * 1024},
+		Music:  config.MusicConfig{Directory: tmpDir},
+	}
+	router := gin.New()
+	router.GET("/api/stream/:id", NewStreamHandler(scanner, cfg).StreamAudio)
+
+	req, _ := http.NewRequest("GET", "/api/stream/"+id, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	body := w.Body.Bytes()
+	if w.Code != http.StatusOK || len(body) != 44+2400 || w.Header().Get("Content-Length") != "2444" {
+		t.Fatalf("期望返回 2444 字节, 得到状态码 %d, %d 字节", w.Code, len(body))
+	}
+	if string(body[:4]) != "RIFF" || string(body[36:40]) != "data" || !bytes.Equal(body[44:], samples[1600:]) {
+		t.Error("期望返回新的 WAV 文件头和曲目对应的采样")
+	}
+
+	req, _ = http.NewRequest("GET", "/api/stream/"+id, nil)
+	req.Header.Set("Range", "bytes=44-53")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusPartialContent || w.Header().Get("Content-Range") != "bytes 44-53/2444" ||
+		!bytes.Equal(w.Body.Bytes(), samples[1600:1610]) {
+		t.Errorf("Range 请求结果不符合预期: 状态码 %d, %q", w.Code, w.Header().Get("Content-Range"))
+	}
+}
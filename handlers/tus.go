@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"zero-music/logger"
+	"zero-music/middleware"
+	"zero-music/models"
+	"zero-music/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// tusVersion 是支持的 tus 协议版本。
+	tusVersion = "1.0.0"
+	// tusExtensions 是支持的 tus 协议扩展。
+	tusExtensions = "creation,creation-with-upload,termination,expiration"
+	// tusContentType 是 tus 协议中发送上传数据时使用的内容类型。
+	tusContentType = "application/offset+octet-stream"
+)
+
+// TusHandler 实现 tus 1.0 断点续传上传协议（https://tus.io/protocols/resumable-upload），
+// 支持 creation、creation-with-upload、termination 和 expiration 扩展。上传完成的文件与普通上传一样放入音乐库。
+type TusHandler struct {
+	uploads *services.ResumableUploader
+}
+
+// NewTusHandler 创建一个新的 TusHandler 实例。
+func NewTusHandler(uploads *services.ResumableUploader) *TusHandler {
+	return &TusHandler{uploads: uploads}
+}
+
+// Protocol 是 tus 路由的中间件：为每个响应添加 Tus-Resumable 头，并拒绝协议版本不受支持的请求。
+// OPTIONS 请求用于查询服务器的能力，不要求客户端声明版本。
+func (h *TusHandler) Protocol(c *gin.Context) {
+	c.Header("Tus-Resumable", tusVersion)
+	if c.Request.Method != http.MethodOptions && c.GetHeader("Tus-Resumable") != tusVersion {
+		c.Header("Tus-Version", tusVersion)
+		c.AbortWithStatusJSON(http.StatusPreconditionFailed, NewPreconditionFailedError("不支持的 tus 协议版本"))
+		return
+	}
+	c.Next()
+}
+
+// Options 返回服务器支持的协议版本、扩展和文件大小上限。
+func (h *TusHandler) Options(c *gin.Context) {
+	c.Header("Tus-Version", tusVersion)
+	c.Header("Tus-Extension", tusExtensions)
+	if maxSize := h.uploads.MaxSize(); maxSize > 0 {
+		c.Header("Tus-Max-Size", strconv.FormatInt(maxSize, 10))
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Create 创建上传会话。Upload-Length 声明文件大小，Upload-Metadata 中的 filename 决定文件按音频还是
+// zip 压缩包处理。请求体类型为 application/offset+octet-stream 时同时接收第一块数据。
+// 返回 201，Location 头为会话的地址。
+func (h *TusHandler) Create(c *gin.Context) {
+	userID, ok := getUserIDOrAbort(c)
+	if !ok {
+		return
+	}
+	if c.GetHeader("Upload-Defer-Length") != "" {
+		c.JSON(http.StatusBadRequest, NewBadRequestError("不支持延迟声明文件大小"))
+		return
+	}
+	length, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || length < 1 {
+		c.JSON(http.StatusBadRequest, NewBadRequestError("Upload-Length 必须是正整数"))
+		return
+	}
+	metadata, err := models.ParseUploadMetadata(c.GetHeader("Upload-Metadata"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewBadRequestError(err.Error()))
+		return
+	}
+	if metadata["filename"] == "" {
+		c.JSON(http.StatusBadRequest, NewBadRequestError("Upload-Metadata 中缺少 filename"))
+		return
+	}
+
+	session, err := h.uploads.Create(userID, metadata["filename"], c.GetHeader("Upload-Metadata"), length)
+	if err != nil {
+		writeUploadError(c, err)
+		return
+	}
+	c.Header("Location", strings.TrimSuffix(c.Request.URL.Path, "/")+"/"+session.ID)
+
+	if c.ContentType() == tusContentType {
+		if session, ok = h.receive(c, userID, session.ID, 0); !ok {
+			return
+		}
+		c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	}
+	c.Header("Upload-Expires", session.ExpiresAt.UTC().Format(http.TimeFormat))
+	c.Status(http.StatusCreated)
+}
+
+// Head 返回上传会话已接收的字节数，客户端据此从中断的位置继续上传。
+func (h *TusHandler) Head(c *gin.Context) {
+	userID, ok := getUserIDOrAbort(c)
+	if !ok {
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	session, err := h.uploads.Get(userID, c.Param("id"))
+	if err != nil {
+		if errors.Is(err, services.ErrUploadSessionNotFound) {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		logger.WithRequestID(middleware.GetRequestID(c)).Errorf("读取上传会话失败: %v", err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(session.Length, 10))
+	if session.Metadata != "" {
+		c.Header("Upload-Metadata", session.Metadata)
+	}
+	c.Header("Upload-Expires", session.ExpiresAt.UTC().Format(http.TimeFormat))
+	c.Status(http.StatusOK)
+}
+
+// Patch 从 Upload-Offset 处接收一块上传数据，返回 204 和新的 Upload-Offset。
+// 文件全部接收后放入音乐库；所有歌曲都无法放入时按第一个失败的原因返回错误。
+func (h *TusHandler) Patch(c *gin.Context) {
+	userID, ok := getUserIDOrAbort(c)
+	if !ok {
+		return
+	}
+	if c.ContentType() != tusContentType {
+		c.JSON(http.StatusUnsupportedMediaType, NewUnsupportedMediaTypeError("Content-Type 必须是 "+tusContentType))
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, NewBadRequestError("Upload-Offset 必须是非负整数"))
+		return
+	}
+
+	session, ok := h.receive(c, userID, c.Param("id"), offset)
+	if !ok {
+		return
+	}
+	c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	c.Header("Upload-Expires", session.ExpiresAt.UTC().Format(http.TimeFormat))
+	c.Status(http.StatusNoContent)
+}
+
+// receive 将请求体作为从 offset 开始的上传数据写入会话，失败时写入错误响应并返回 false。
+func (h *TusHandler) receive(c *gin.Context, userID int64, id string, offset int64) (*models.UploadSession, bool) {
+	// 一块数据的上传耗时可能超过服务器的读写超时，取消本请求的超时限制
+	rc := http.NewResponseController(c.Writer)
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	session, results, err := h.uploads.Append(c.Request.Context(), userID, id, offset, c.Request.Body)
+	switch {
+	case errors.Is(err, services.ErrUploadSessionNotFound):
+		c.JSON(http.StatusNotFound, NewNotFoundError("上传会话"))
+		return nil, false
+	case errors.Is(err, services.ErrUploadOffsetMismatch):
+		c.JSON(http.StatusConflict, NewConflictError(err.Error()))
+		return nil, false
+	case errors.Is(err, services.ErrUploadSessionBusy):
+		c.JSON(http.StatusLocked, NewLockedError(err.Error()))
+		return nil, false
+	case err != nil:
+		logger.WithRequestID(middleware.GetRequestID(c)).Errorf("接收上传数据失败: %v", err)
+		c.JSON(http.StatusInternalServerError, NewInternalError(err))
+		return nil, false
+	}
+
+	if results != nil && len(session.SongIDs) == 0 {
+		for _, result := range results {
+			if result.Err() != nil {
+				writeUploadError(c, result.Err())
+				return nil, false
+			}
+		}
+	}
+	return session, true
+}
+
+// Delete 终止上传，删除会话和已接收的数据。
+func (h *TusHandler) Delete(c *gin.Context) {
+	userID, ok := getUserIDOrAbort(c)
+	if !ok {
+		return
+	}
+	if err := h.uploads.Terminate(userID, c.Param("id")); err != nil {
+		switch {
+		case errors.Is(err, services.ErrUploadSessionNotFound):
+			c.JSON(http.StatusNotFound, NewNotFoundError("上传会话"))
+		case errors.Is(err, services.ErrUploadSessionBusy):
+			c.JSON(http.StatusLocked, NewLockedError(err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, NewInternalError(err))
+		}
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Get 以 JSON 返回上传会话的状态，上传完成后包含放入音乐库的歌曲 ID。不属于 tus 协议。
+func (h *TusHandler) Get(c *gin.Context) {
+	userID, ok := getUserIDOrAbort(c)
+	if !ok {
+		return
+	}
+	session, err := h.uploads.Get(userID, c.Param("id"))
+	if err != nil {
+		if errors.Is(err, services.ErrUploadSessionNotFound) {
+			c.JSON(http.StatusNotFound, NewNotFoundError("上传会话"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, NewInternalError(err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": session})
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"zero-music/models"
+	"zero-music/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// memoryUploadSessionRepository 是用于测试的内存上传会话仓储。
+type memoryUploadSessionRepository struct {
+	sessions map[string]*models.UploadSession
+}
+
+func (r *memoryUploadSessionRepository) Create(session *models.UploadSession) error {
+	stored := *session
+	r.sessions[session.ID] = &stored
+	return nil
+}
+
+func (r *memoryUploadSessionRepository) FindByID(id string) (*models.UploadSession, error) {
+	session, ok := r.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+	found := *session
+	return &found, nil
+}
+
+func (r *memoryUploadSessionRepository) UpdateOffset(id string, offset int64, expiresAt time.Time) error {
+	r.sessions[id].Offset, r.sessions[id].ExpiresAt = offset, expiresAt
+	return nil
+}
+
+func (r *memoryUploadSessionRepository) Complete(id string, songIDs []string) error {
+	r.sessions[id].SongIDs = songIDs
+	return nil
+}
+
+func (r *memoryUploadSessionRepository) Delete(id string) (bool, error) {
+	_, ok := r.sessions[id]
+	delete(r.sessions, id)
+	return ok, nil
+}
+
+func (r *memoryUploadSessionRepository) ListExpired(now time.Time) ([]*models.UploadSession, error) {
+	return nil, nil
+}
+
+func setupTusTestEnv(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	scanner := services.NewMusicScanner(t.TempDir(), []string{".mp3"}, 5)
+	if _, err := scanner.Scan(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	uploader, err := services.NewUploader(scanner, &memoryUploadRepository{quotas: make(map[int64]int64)}, models.DefaultLibraryName, "{artist}/{title}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	uploader.SetLimits(1<<20, 0)
+	resumable, err := services.NewResumableUploader(uploader, &memoryUploadSessionRepository{sessions: make(map[string]*models.UploadSession)}, t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := NewTusHandler(resumable)
+
+	router := gin.New()
+	tus := router.Group("/api/v1/uploads/tus", func(c *gin.Context) { c.Set("user_id", int64(1)) }, handler.Protocol)
+	tus.OPTIONS("", handler.Options)
+	tus.POST("", handler.Create)
+	tus.HEAD("/:id", handler.Head)
+	tus.PATCH("/:id", handler.Patch)
+	tus.DELETE("/:id", handler.Delete)
+	tus.GET("/:id", handler.Get)
+	return router
+}
+
+// tusRequest 发送带有 Tus-Resumable 头的请求。
+func tusRequest(router *gin.Engine, method, url string, body []byte, headers map[string]string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, url, bytes.NewReader(body))
+	req.Header.Set("Tus-Resumable", tusVersion)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// TestTusUpload 测试按 tus 协议创建上传、分块发送、查询进度和中断后续传，完成后歌曲放入音乐库。
+func TestTusUpload(t *testing.T) {
+	router := setupTusTestEnv(t)
+	data := append([]byte{0xFF, 0xFB, 0x90, 0x00}, bytes.Repeat([]byte{0}, 60)...)
+	metadata := "filename " + base64.StdEncoding.EncodeToString([]byte("My Song.mp3"))
+
+	w := tusRequest(router, http.MethodOptions, "/api/v1/uploads/tus", nil, nil)
+	if w.Code != http.StatusNoContent || w.Header().Get("Tus-Version") != "1.0.0" || w.Header().Get("Tus-Max-Size") != strconv.Itoa(1<<20) {
+		t.Fatalf("OPTIONS 返回了意外的响应: %d %v", w.Code, w.Header())
+	}
+
+	// 创建上传并同时发送第一块数据
+	w = tusRequest(router, http.MethodPost, "/api/v1/uploads/tus", data[:20], map[string]string{
+		"Upload-Length":   strconv.Itoa(len(data)),
+		"Upload-Metadata": metadata,
+		"Content-Type":    tusContentType,
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("期望状态码 %d, 得到 %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	location := w.Header().Get("Location")
+	if location == "" || w.Header().Get("Upload-Offset") != "20" || w.Header().Get("Upload-Expires") == "" || w.Header().Get("Tus-Resumable") != "1.0.0" {
+		t.Fatalf("创建上传返回了意外的响应头: %v", w.Header())
+	}
+
+	// 偏移量不一致时返回 409
+	w = tusRequest(router, http.MethodPatch, location, data[10:], map[string]string{"Upload-Offset": "10", "Content-Type": tusContentType})
+	if w.Code != http.StatusConflict {
+		t.Errorf("期望状态码 %d, 得到 %d", http.StatusConflict, w.Code)
+	}
+	// 内容类型错误时返回 415
+	w = tusRequest(router, http.MethodPatch, location, data[20:], map[string]string{"Upload-Offset": "20", "Content-Type": "application/octet-stream"})
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("期望状态码 %d, 得到 %d", http.StatusUnsupportedMediaType, w.Code)
+	}
+
+	// 查询已接收的字节数后续传
+	w = tusRequest(router, http.MethodHead, location, nil, nil)
+	if w.Code != http.StatusOK || w.Header().Get("Upload-Offset") != "20" || w.Header().Get("Upload-Length") != strconv.Itoa(len(data)) ||
+		w.Header().Get("Upload-Metadata") != metadata || w.Header().Get("Cache-Control") != "no-store" {
+		t.Fatalf("HEAD 返回了意外的响应: %d %v", w.Code, w.Header())
+	}
+	w = tusRequest(router, http.MethodPatch, location, data[20:], map[string]string{"Upload-Offset": "20", "Content-Type": tusContentType})
+	if w.Code != http.StatusNoContent || w.Header().Get("Upload-Offset") != strconv.Itoa(len(data)) {
+		t.Fatalf("期望续传完成, 得到 %d %v: %s", w.Code, w.Header(), w.Body.String())
+	}
+
+	// 完成后可以查询放入音乐库的歌曲
+	w = tusRequest(router, http.MethodGet, location, nil, nil)
+	var resp struct {
+		Data models.UploadSession `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || len(resp.Data.SongIDs) != 1 || resp.Data.SongIDs[0] != models.LibrarySongID(models.DefaultLibraryName, "Unknown/My Song.mp3") {
+		t.Errorf("期望会话记录上传的歌曲, 得到 %d: %s", w.Code, w.Body.String())
+	}
+
+	// 终止上传后会话不再存在
+	w = tusRequest(router, http.MethodDelete, location, nil, nil)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("期望状态码 %d, 得到 %d", http.StatusNoContent, w.Code)
+	}
+	w = tusRequest(router, http.MethodHead, location, nil, nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("期望状态码 %d, 得到 %d", http.StatusNotFound, w.Code)
+	}
+}
+
+// TestTusUpload_Rejects 测试协议版本、文件大小和文件格式不符合要求的请求被拒绝。
+func TestTusUpload_Rejects(t *testing.T) {
+	router := setupTusTestEnv(t)
+	metadata := "filename " + base64.StdEncoding.EncodeToString([]byte("song.mp3"))
+
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/uploads/tus", nil)
+	req.Header.Set("Upload-Length", "10")
+	req.Header.Set("Upload-Metadata", metadata)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusPreconditionFailed || w.Header().Get("Tus-Version") != "1.0.0" {
+		t.Errorf("期望缺少 Tus-Resumable 时返回 %d, 得到 %d", http.StatusPreconditionFailed, w.Code)
+	}
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		code    int
+	}{
+		{"缺少文件大小", map[string]string{"Upload-Metadata": metadata}, http.StatusBadRequest},
+		{"延迟声明文件大小", map[string]string{"Upload-Defer-Length": "1", "Upload-Metadata": metadata}, http.StatusBadRequest},
+		{"缺少文件名", map[string]string{"Upload-Length": "10"}, http.StatusBadRequest},
+		{"元数据格式错误", map[string]string{"Upload-Length": "10", "Upload-Metadata": "filename !!"}, http.StatusBadRequest},
+		{"超过大小上限", map[string]string{"Upload-Length": strconv.Itoa(1<<20 + 1), "Upload-Metadata": metadata}, http.StatusRequestEntityTooLarge},
+		{"不支持的格式", map[string]string{"Upload-Length": "10", "Upload-Metadata": "filename " + base64.StdEncoding.EncodeToString([]byte("a.txt"))}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := tusRequest(router, http.MethodPost, "/api/v1/uploads/tus", nil, tt.headers)
+			if w.Code != tt.code {
+				t.Errorf("期望状态码 %d, 得到 %d: %s", tt.code, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	// 内容不是音频的文件在接收完后被拒绝
+	w = tusRequest(router, http.MethodPost, "/api/v1/uploads/tus", []byte("<html>"), map[string]string{
+		"Upload-Length":   "6",
+		"Upload-Metadata": metadata,
+		"Content-Type":    tusContentType,
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("期望状态码 %d, 得到 %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+
+	w = tusRequest(router, http.MethodPatch, "/api/v1/uploads/tus/missing", []byte("x"), map[string]string{"Upload-Offset": "0", "Content-Type": tusContentType})
+	if w.Code != http.StatusNotFound {
+		t.Errorf("期望状态码 %d, 得到 %d", http.StatusNotFound, w.Code)
+	}
+}
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"zero-music/logger"
+	"zero-music/middleware"
+	"zero-music/repository"
+	"zero-music/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxUploadFilesPerRequest 是一次上传请求中允许的最大文件数，压缩包算作一个文件。
+const maxUploadFilesPerRequest = 100
+
+// UploadHandler 负责处理上传歌曲到音乐库和上传配额相关的请求。
+type UploadHandler struct {
+	uploader *services.Uploader
+	users    repository.UserRepository
+}
+
+// NewUploadHandler 创建一个新的 UploadHandler 实例。
+func NewUploadHandler(uploader *services.Uploader, users repository.UserRepository) *UploadHandler {
+	return &UploadHandler{uploader: uploader, users: users}
+}
+
+// UpdateQuotaRequest 是设置用户上传配额的请求。
+type UpdateQuotaRequest struct {
+	// Quota 是允许上传的总大小（字节），0 表示不限制
+	Quota *int64 `json:"quota" binding:"required"`
+}
+
+// Upload 接收 multipart/form-data 中的一个或多个文件（音频文件或专辑的 zip 压缩包），
+// 按路径模板放入音乐库并立即加入索引。文件以流的方式写入磁盘，不会整体读入内存。
+// 至少一首歌曲上传成功时返回 201 和每个文件的结果；全部失败时按第一个失败的原因返回错误。
+func (h *UploadHandler) Upload(c *gin.Context) {
+	userID, ok := getUserIDOrAbort(c)
+	if !ok {
+		return
+	}
+	// 上传大文件的耗时可能超过服务器的读写超时，取消本请求的超时限制
+	rc := http.NewResponseController(c.Writer)
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewBadRequestError("请使用 multipart/form-data 上传文件"))
+		return
+	}
+
+	var results []*services.UploadedFile
+	files := 0
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, NewBadRequestError("读取上传内容失败"))
+			return
+		}
+		if part.FileName() == "" {
+			part.Close()
+			continue
+		}
+		if files++; files > maxUploadFilesPerRequest {
+			part.Close()
+			break
+		}
+
+		uploaded, err := h.uploader.Upload(c.Request.Context(), userID, part.FileName(), part)
+		part.Close()
+		if err != nil {
+			logger.WithRequestID(middleware.GetRequestID(c)).Errorf("上传歌曲失败: %v", err)
+			c.JSON(http.StatusInternalServerError, NewInternalError(err))
+			return
+		}
+		results = append(results, uploaded...)
+	}
+	if len(results) == 0 {
+		c.JSON(http.StatusBadRequest, NewBadRequestError("请上传音频文件或 zip 压缩包"))
+		return
+	}
+
+	songIDs := make([]string, 0, len(results))
+	var firstErr error
+	for _, result := range results {
+		if err := result.Err(); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		songIDs = append(songIDs, result.SongID)
+	}
+	if len(songIDs) == 0 {
+		writeUploadError(c, firstErr)
+		return
+	}
+
+	quota, err := h.uploader.Quota(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, NewInternalError(err))
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"uploaded": len(songIDs),
+			"failed":   len(results) - len(songIDs),
+			"song_ids": songIDs,
+			"results":  results,
+			"quota":    quota,
+		},
+	})
+}
+
+// writeUploadError 根据上传失败的原因返回对应的错误响应。
+func writeUploadError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUploadTooLarge), errors.Is(err, services.ErrUploadQuotaExceeded):
+		c.JSON(http.StatusRequestEntityTooLarge, NewPayloadTooLargeError(err.Error()))
+	case errors.Is(err, services.ErrUnsupportedUpload), errors.Is(err, services.ErrInvalidAudioFile), errors.Is(err, services.ErrEmptyArchive):
+		c.JSON(http.StatusBadRequest, NewBadRequestError(err.Error()))
+	default:
+		logger.WithRequestID(middleware.GetRequestID(c)).Errorf("上传歌曲失败: %v", err)
+		c.JSON(http.StatusInternalServerError, NewInternalError(err))
+	}
+}
+
+// GetUploads 返回当前用户上传的文件和上传配额。
+func (h *UploadHandler) GetUploads(c *gin.Context) {
+	userID, ok := getUserIDOrAbort(c)
+	if !ok {
+		return
+	}
+	uploads, err := h.uploader.List(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, NewInternalError(err))
+		return
+	}
+	quota, err := h.uploader.Quota(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, NewInternalError(err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"uploads": uploads,
+			"quota":   quota,
+		},
+	})
+}
+
+// GetUserQuota 返回指定用户的上传配额和已使用的空间。
+func (h *UploadHandler) GetUserQuota(c *gin.Context) {
+	userID, ok := h.parseUserID(c)
+	if !ok {
+		return
+	}
+	quota, err := h.uploader.Quota(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, NewInternalError(err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": quota})
+}
+
+// UpdateUserQuota 为指定用户单独设置上传配额（字节），0 表示不限制。
+func (h *UploadHandler) UpdateUserQuota(c *gin.Context) {
+	userID, ok := h.parseUserID(c)
+	if !ok {
+		return
+	}
+	var req UpdateQuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, NewBadRequestError("请求参数错误"))
+		return
+	}
+	if *req.Quota < 0 {
+		c.JSON(http.StatusBadRequest, NewBadRequestError("配额不能为负数"))
+		return
+	}
+	quota, err := h.uploader.SetQuota(userID, req.Quota)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, NewInternalError(err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": quota})
+}
+
+// DeleteUserQuota 删除为指定用户单独设置的上传配额，恢复使用默认配额。
+func (h *UploadHandler) DeleteUserQuota(c *gin.Context) {
+	userID, ok := h.parseUserID(c)
+	if !ok {
+		return
+	}
+	quota, err := h.uploader.SetQuota(userID, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, NewInternalError(err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": quota})
+}
+
+// parseUserID 解析路径中的用户 ID 并检查用户是否存在。
+func (h *UploadHandler) parseUserID(c *gin.Context) (int64, bool) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || userID <= 0 {
+		c.JSON(http.StatusBadRequest, NewBadRequestError("无效的用户ID"))
+		return 0, false
+	}
+	user, err := h.users.FindByID(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, NewInternalError(err))
+		return 0, false
+	}
+	if user == nil {
+		c.JSON(http.StatusNotFound, NewNotFoundError("用户"))
+		return 0, false
+	}
+	return userID, true
+}
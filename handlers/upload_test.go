@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"zero-music/models"
+	"zero-music/repository"
+	"zero-music/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// memoryUploadRepository 是用于测试的内存上传记录仓储。
+type memoryUploadRepository struct {
+	uploads []*models.Upload
+	quotas  map[int64]int64
+}
+
+func (r *memoryUploadRepository) Create(upload *models.Upload) error {
+	upload.ID = int64(len(r.uploads) + 1)
+	r.uploads = append(r.uploads, upload)
+	return nil
+}
+
+func (r *memoryUploadRepository) ListByUser(userID int64) ([]*models.Upload, error) {
+	uploads := make([]*models.Upload, 0)
+	for _, upload := range r.uploads {
+		if upload.UserID == userID {
+			uploads = append(uploads, upload)
+		}
+	}
+	return uploads, nil
+}
+
+func (r *memoryUploadRepository) Usage(userID int64) (int64, error) {
+	var used int64
+	for _, upload := range r.uploads {
+		if upload.UserID == userID {
+			used += upload.Size
+		}
+	}
+	return used, nil
+}
+
+func (r *memoryUploadRepository) GetQuota(userID int64) (int64, bool, error) {
+	quota, ok := r.quotas[userID]
+	return quota, ok, nil
+}
+
+func (r *memoryUploadRepository) SetQuota(userID int64, quota int64) error {
+	r.quotas[userID] = quota
+	return nil
+}
+
+func (r *memoryUploadRepository) DeleteQuota(userID int64) (bool, error) {
+	_, ok := r.quotas[userID]
+	delete(r.quotas, userID)
+	return ok, nil
+}
+
+func (r *memoryUploadRepository) UpdateLocation(library, relPath, newLibrary, newRelPath string) error {
+	for _, upload := range r.uploads {
+		if upload.Library == newLibrary && upload.RelPath == newRelPath {
+			return errors.New("UNIQUE constraint failed: uploads.library, uploads.rel_path")
+		}
+	}
+	for _, upload := range r.uploads {
+		if upload.Library == library && upload.RelPath == relPath {
+			upload.Library, upload.RelPath = newLibrary, newRelPath
+		}
+	}
+	return nil
+}
+
+// staticUserRepository 是只支持按 ID 查找用户的测试仓储。
+type staticUserRepository struct {
+	repository.UserRepository
+	users map[int64]*models.User
+}
+
+func (r *staticUserRepository) FindByID(id int64) (*models.User, error) {
+	return r.users[id], nil
+}
+
+func setupUploadTestEnv(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	scanner := services.NewMusicScanner(t.TempDir(), []string{".mp3"}, 5)
+	if _, err := scanner.Scan(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	uploader, err := services.NewUploader(scanner, &memoryUploadRepository{quotas: make(map[int64]int64)}, models.DefaultLibraryName, "{artist}/{title}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	uploader.SetLimits(1<<20, 0)
+	handler := NewUploadHandler(uploader, &staticUserRepository{users: map[int64]*models.User{1: {ID: 1}}})
+
+	router := gin.New()
+	uploads := router.Group("/api/v1/uploads", func(c *gin.Context) { c.Set("user_id", int64(1)) })
+	uploads.POST("", handler.Upload)
+	uploads.GET("", handler.GetUploads)
+	router.GET("/api/admin/users/:id/quota", handler.GetUserQuota)
+	router.PUT("/api/admin/users/:id/quota", handler.UpdateUserQuota)
+	router.DELETE("/api/admin/users/:id/quota", handler.DeleteUserQuota)
+	return router
+}
+
+// multipartBody 构造包含给定文件的 multipart/form-data 请求体。
+func multipartBody(t *testing.T, files map[string][]byte) (*bytes.Buffer, string) {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("note", "ignored")
+	for name, data := range files {
+		part, err := writer.CreateFormFile("files", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(data)
+	}
+	writer.Close()
+	return &body, writer.FormDataContentType()
+}
+
+// TestUploadSongs 测试上传成功返回歌曲 ID，全部失败时按原因返回错误状态码。
+func TestUploadSongs(t *testing.T) {
+	router := setupUploadTestEnv(t)
+	mp3 := []byte{0xFF, 0xFB, 0x90, 0x00}
+
+	body, contentType := multipartBody(t, map[string][]byte{"Good.mp3": mp3, "bad.mp3": []byte("<html>")})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/uploads", body)
+	req.Header.Set("Content-Type", contentType)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("期望状态码 %d, 得到 %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data struct {
+			Uploaded int                     `json:"uploaded"`
+			Failed   int                     `json:"failed"`
+			SongIDs  []string                `json:"song_ids"`
+			Results  []services.UploadedFile `json:"results"`
+			Quota    models.UploadQuota      `json:"quota"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	wantID := models.LibrarySongID(models.DefaultLibraryName, "Unknown/Good.mp3")
+	if resp.Data.Uploaded != 1 || resp.Data.Failed != 1 || len(resp.Data.SongIDs) != 1 || resp.Data.SongIDs[0] != wantID {
+		t.Errorf("期望 1 首成功 1 首失败, 得到 %+v", resp.Data)
+	}
+	if resp.Data.Quota.Used != int64(len(mp3)) {
+		t.Errorf("期望已使用 %d 字节, 得到 %d", len(mp3), resp.Data.Quota.Used)
+	}
+
+	tests := []struct {
+		name  string
+		files map[string][]byte
+		code  int
+	}{
+		{"格式不支持", map[string][]byte{"a.txt": []byte("text")}, http.StatusBadRequest},
+		{"超过大小上限", map[string][]byte{"big.mp3": make([]byte, 1<<20+1)}, http.StatusRequestEntityTooLarge},
+		{"没有文件", map[string][]byte{}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, contentType := multipartBody(t, tt.files)
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/v1/uploads", body)
+			req.Header.Set("Content-Type", contentType)
+			router.ServeHTTP(w, req)
+			if w.Code != tt.code {
+				t.Errorf("期望状态码 %d, 得到 %d: %s", tt.code, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/uploads", bytes.NewBufferString(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("期望非 multipart 请求返回 400, 得到 %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/uploads", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte(wantID)) {
+		t.Errorf("期望上传记录包含 %s, 得到 %d: %s", wantID, w.Code, w.Body.String())
+	}
+}
+
+// TestUserQuota 测试管理员设置、查询和重置用户的上传配额，超出配额时上传返回 413。
+func TestUserQuota(t *testing.T) {
+	router := setupUploadTestEnv(t)
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := request("PUT", "/api/admin/users/1/quota", `{"quota":2}`); w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte(`"custom":true`)) {
+		t.Fatalf("期望设置配额成功, 得到 %d: %s", w.Code, w.Body.String())
+	}
+
+	body, contentType := multipartBody(t, map[string][]byte{"a.mp3": {0xFF, 0xFB, 0x90, 0x00}})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/uploads", body)
+	req.Header.Set("Content-Type", contentType)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("期望超出配额返回 413, 得到 %d: %s", w.Code, w.Body.String())
+	}
+
+	if w := request("GET", "/api/admin/users/1/quota", ""); w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte(`"limit":2`)) {
+		t.Errorf("期望查询到配额, 得到 %d: %s", w.Code, w.Body.String())
+	}
+	if w := request("DELETE", "/api/admin/users/1/quota", ""); w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte(`"custom":false`)) {
+		t.Errorf("期望恢复默认配额, 得到 %d: %s", w.Code, w.Body.String())
+	}
+
+	for _, tt := range []struct {
+		method, path, body string
+		code               int
+	}{
+		{"PUT", "/api/admin/users/1/quota", `{"quota":-1}`, http.StatusBadRequest},
+		{"PUT", "/api/admin/users/1/quota", `{}`, http.StatusBadRequest},
+		{"PUT", "/api/admin/users/abc/quota", `{"quota":1}`, http.StatusBadRequest},
+		{"GET", "/api/admin/users/2/quota", "", http.StatusNotFound},
+	} {
+		if w := request(tt.method, tt.path, tt.body); w.Code != tt.code {
+			t.Errorf("%s %s: 期望状态码 %d, 得到 %d", tt.method, tt.path, tt.code, w.Code)
+		}
+	}
+}
@@ -1,8 +1,16 @@
 package handlers
 
 import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"zero-music/logger"
 	"zero-music/middleware"
@@ -19,6 +27,7 @@ type UserHandler struct {
 	favoriteRepo repository.FavoriteRepository
 	playStats    repository.PlayStatsRepository
 	playlistRepo repository.PlaylistRepository
+	publicURL    string
 }
 
 // NewUserHandler 创建用户处理器
@@ -36,6 +45,12 @@ func NewUserHandler(
 	}
 }
 
+// SetPublicURL 设置客户端访问服务的基础地址（不以 / 结尾），导出播放列表时用于生成音频流地址。
+// 为空时根据请求推断。
+func (h *UserHandler) SetPublicURL(publicURL string) {
+	h.publicURL = publicURL
+}
+
 // RecordPlayRequest 记录播放请求
 type RecordPlayRequest struct {
 	SongID   string `json:"song_id" binding:"required"`
@@ -628,3 +643,343 @@ func (h *UserHandler) ReorderPlaylistSongs(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "重排序成功"})
 }
+
+// --- 播放列表导出 ---
+
+// exportContentTypes 是各导出格式对应的 Content-Type。
+var exportContentTypes = map[string]string{
+	models.PlaylistFormatM3U8: "audio/x-mpegurl; charset=utf-8",
+	models.PlaylistFormatXSPF: "application/xspf+xml; charset=utf-8",
+	models.PlaylistFormatJSON: "application/json; charset=utf-8",
+}
+
+// 导出的播放列表中歌曲位置的写法。
+const (
+	exportPathsRelative = "relative"
+	exportPathsStream   = "stream"
+)
+
+// parseExportOptions 解析导出格式和歌曲位置的写法，参数无效时写入 400 响应并返回 false。
+func parseExportOptions(c *gin.Context) (format, paths string, ok bool) {
+	format = strings.ToLower(c.DefaultQuery("format", models.PlaylistFormatM3U8))
+	if !slices.Contains(models.ExportFormats, format) {
+		c.JSON(http.StatusBadRequest, NewBadRequestError("不支持的导出格式: "+format))
+		return "", "", false
+	}
+	paths = strings.ToLower(c.DefaultQuery("paths", exportPathsRelative))
+	if paths != exportPathsRelative && paths != exportPathsStream {
+		c.JSON(http.StatusBadRequest, NewBadRequestError("paths 只能是 relative 或 stream"))
+		return "", "", false
+	}
+	return format, paths, true
+}
+
+// streamBaseURL 返回音频流地址的前缀。优先使用配置的服务地址，否则根据请求推断：
+// 反向代理设置的 X-Forwarded-Proto 只接受 http 和 https，其他值被忽略。
+func (h *UserHandler) streamBaseURL(c *gin.Context) string {
+	if h.publicURL != "" {
+		return h.publicURL + "/api/v1/stream/"
+	}
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if forwarded := c.GetHeader("X-Forwarded-Proto"); forwarded != "" {
+		proto, _, _ := strings.Cut(forwarded, ",")
+		if proto = strings.ToLower(strings.TrimSpace(proto)); proto == "http" || proto == "https" {
+			scheme = proto
+		}
+	}
+	return scheme + "://" + c.Request.Host + "/api/v1/stream/"
+}
+
+// buildPlaylistExport 生成用户播放列表的导出内容，已不在歌曲库中的歌曲会被跳过。
+func (h *UserHandler) buildPlaylistExport(c *gin.Context, playlist *models.UserPlaylist, paths string) (*models.PlaylistExport, error) {
+	songIDs, err := h.playlistRepo.GetSongs(playlist.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &models.PlaylistExport{
+		Name:        playlist.Name,
+		Description: playlist.Description,
+		ExportedAt:  time.Now().UTC(),
+		Tracks:      make([]models.PlaylistExportTrack, 0, len(songIDs)),
+	}
+	baseURL := h.streamBaseURL(c)
+	for _, sid := range songIDs {
+		song := h.scanner.GetSongByID(sid)
+		if song == nil {
+			continue
+		}
+		location := song.LibraryRelPath()
+		if paths == exportPathsStream {
+			location = baseURL + song.ID
+		}
+		export.Tracks = append(export.Tracks, models.PlaylistExportTrack{
+			ID:       song.ID,
+			Title:    song.Title,
+			Artist:   song.Artist,
+			Album:    song.Album,
+			Duration: song.Duration,
+			Location: location,
+		})
+	}
+	return export, nil
+}
+
+// exportFileName 返回导出文件的文件名，去掉名称中不能用于文件名的字符。
+func exportFileName(name, format string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < ' ' {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if name == "" {
+		name = "playlist"
+	}
+	return name + "." + format
+}
+
+// setAttachment 设置下载文件名，非 ASCII 文件名按 RFC 2231 编码。
+func setAttachment(c *gin.Context, filename string) {
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+}
+
+// ExportPlaylist 将播放列表导出为 M3U8、XSPF 或 JSON 文件，歌曲位置可以是音乐库内的相对路径或音频流地址
+func (h *UserHandler) ExportPlaylist(c *gin.Context) {
+	userID, ok := getUserIDOrAbort(c)
+	if !ok {
+		return
+	}
+
+	playlistID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewBadRequestError("无效的播放列表ID"))
+		return
+	}
+
+	format, paths, ok := parseExportOptions(c)
+	if !ok {
+		return
+	}
+
+	// 检查权限
+	if !h.checkPlaylistOwnership(c, playlistID, userID) {
+		return
+	}
+
+	playlist, err := h.playlistRepo.FindByID(playlistID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, NewInternalError(err))
+		return
+	}
+
+	export, err := h.buildPlaylistExport(c, playlist, paths)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, NewInternalError(err))
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := export.Write(&buf, format); err != nil {
+		c.JSON(http.StatusInternalServerError, NewInternalError(err))
+		return
+	}
+
+	setAttachment(c, exportFileName(playlist.Name, format))
+	c.Data(http.StatusOK, exportContentTypes[format], buf.Bytes())
+}
+
+// ExportAllPlaylists 将用户的所有播放列表按指定格式导出并打包为 zip 文件，同名播放列表的文件名会加上序号
+func (h *UserHandler) ExportAllPlaylists(c *gin.Context) {
+	userID, ok := getUserIDOrAbort(c)
+	if !ok {
+		return
+	}
+
+	format, paths, ok := parseExportOptions(c)
+	if !ok {
+		return
+	}
+
+	playlists, err := h.playlistRepo.GetByUserID(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, NewInternalError(err))
+		return
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	used := make(map[string]bool, len(playlists))
+	for _, playlist := range playlists {
+		export, err := h.buildPlaylistExport(c, playlist, paths)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, NewInternalError(err))
+			return
+		}
+
+		name := exportFileName(playlist.Name, format)
+		for n := 2; used[strings.ToLower(name)]; n++ {
+			name = exportFileName(fmt.Sprintf("%s (%d)", playlist.Name, n), format)
+		}
+		used[strings.ToLower(name)] = true
+
+		w, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: playlist.UpdatedAt})
+		if err == nil {
+			err = export.Write(w, format)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, NewInternalError(err))
+			return
+		}
+	}
+	if err := archive.Close(); err != nil {
+		c.JSON(http.StatusInternalServerError, NewInternalError(err))
+		return
+	}
+
+	setAttachment(c, fmt.Sprintf("playlists-%s.zip", time.Now().Format("20060102")))
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+// --- 播放列表导入 ---
+
+// maxPlaylistImportSize 是导入的播放列表文件的最大大小。
+const maxPlaylistImportSize = 5 << 20
+
+// maxPlaylistNameLength 是播放列表名称的最大长度，与 CreatePlaylistRequest 的校验一致。
+const maxPlaylistNameLength = 100
+
+// PlaylistImportReport 是导入播放列表的结果。
+type PlaylistImportReport struct {
+	// Playlist 是创建的播放列表，dry_run 时为 nil
+	Playlist  *models.UserPlaylist `json:"playlist"`
+	Name      string               `json:"name"`
+	Format    string               `json:"format"`
+	Total     int                  `json:"total"`
+	Matched   int                  `json:"matched"`
+	Ambiguous int                  `json:"ambiguous"`
+	Missing   int                  `json:"missing"`
+	// Added 是加入播放列表的歌曲数量，文件中重复的歌曲只加入一次
+	Added   int                `json:"added"`
+	Entries []models.SongMatch `json:"entries"`
+}
+
+// ImportPlaylist 从上传的 M3U/M3U8、PLS、XSPF、CSV 或 JSON 文件创建播放列表。
+// 条目按路径或标题、艺术家和时长对应到歌曲库中的歌曲，只有唯一匹配的歌曲会加入播放列表；
+// 响应中列出每个条目的匹配结果，dry_run=true 时只返回匹配结果而不创建播放列表。
+func (h *UserHandler) ImportPlaylist(c *gin.Context) {
+	userID, ok := getUserIDOrAbort(c)
+	if !ok {
+		return
+	}
+
+	// 为表单中的其他字段留出空间
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxPlaylistImportSize+(1<<20))
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewBadRequestError("请上传播放列表文件"))
+		return
+	}
+	if fileHeader.Size > maxPlaylistImportSize {
+		c.JSON(http.StatusBadRequest, NewBadRequestError(fmt.Sprintf("文件不能超过 %d MB", maxPlaylistImportSize>>20)))
+		return
+	}
+
+	format := strings.ToLower(strings.TrimSpace(c.PostForm("format")))
+	if format != "" && !slices.Contains(models.ImportFormats, format) {
+		c.JSON(http.StatusBadRequest, NewBadRequestError("不支持的导入格式: "+format))
+		return
+	}
+	tolerance := models.DefaultMatchDurationTolerance
+	if value := c.PostForm("duration_tolerance"); value != "" {
+		tolerance, err = strconv.Atoi(value)
+		if err != nil || tolerance < 0 || tolerance > 60 {
+			c.JSON(http.StatusBadRequest, NewBadRequestError("duration_tolerance 必须是 0 到 60 之间的整数"))
+			return
+		}
+	}
+	dryRun, _ := strconv.ParseBool(c.PostForm("dry_run"))
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, NewInternalError(err))
+		return
+	}
+	data, err := io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, NewInternalError(err))
+		return
+	}
+
+	parsed, err := models.ParsePlaylistImport(fileHeader.Filename, format, data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewBadRequestError(err.Error()))
+		return
+	}
+
+	report := &PlaylistImportReport{
+		Name:    parsed.Name,
+		Format:  parsed.Format,
+		Total:   len(parsed.Entries),
+		Entries: make([]models.SongMatch, len(parsed.Entries)),
+	}
+	if name := strings.TrimSpace(c.PostForm("name")); name != "" {
+		report.Name = name
+	}
+	if runes := []rune(report.Name); len(runes) > maxPlaylistNameLength {
+		report.Name = string(runes[:maxPlaylistNameLength])
+	}
+
+	matcher := models.NewSongMatcher(h.scanner.GetSongs())
+	matcher.DurationTolerance = tolerance
+	var songIDs []string
+	for i, entry := range parsed.Entries {
+		match := matcher.Match(entry)
+		report.Entries[i] = match
+		switch match.Status {
+		case models.MatchStatusMatched:
+			report.Matched++
+			if !slices.Contains(songIDs, match.SongID) {
+				songIDs = append(songIDs, match.SongID)
+			}
+		case models.MatchStatusAmbiguous:
+			report.Ambiguous++
+		default:
+			report.Missing++
+		}
+	}
+
+	if dryRun {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    0,
+			"message": "success",
+			"data":    report,
+		})
+		return
+	}
+
+	playlist, err := h.playlistRepo.Create(userID, report.Name, fmt.Sprintf("从 %s 导入", fileHeader.Filename), false, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, NewInternalError(err))
+		return
+	}
+	for _, songID := range songIDs {
+		if err := h.playlistRepo.AddSong(playlist.ID, songID); err != nil {
+			logger.Warnf("导入播放列表 %d 时添加歌曲 %s 失败: %v", playlist.ID, songID, err)
+			continue
+		}
+		report.Added++
+	}
+	playlist.SongCount = report.Added
+	report.Playlist = playlist
+
+	c.JSON(http.StatusCreated, gin.H{
+		"code":    0,
+		"message": "导入成功",
+		"data":    report,
+	})
+}
@@ -0,0 +1,345 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"zero-music/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// memoryPlaylistRepository 是保存在内存中的 PlaylistRepository 实现，用于处理器测试。
+type memoryPlaylistRepository struct {
+	playlists []*models.UserPlaylist
+	songs     map[int64][]string
+}
+
+func newMemoryPlaylistRepository() *memoryPlaylistRepository {
+	return &memoryPlaylistRepository{songs: make(map[int64][]string)}
+}
+
+func (r *memoryPlaylistRepository) Create(userID int64, name, description string, isSmart bool, smartRules string) (*models.UserPlaylist, error) {
+	playlist := &models.UserPlaylist{
+		ID:          int64(len(r.playlists) + 1),
+		UserID:      userID,
+		Name:        name,
+		Description: description,
+		IsSmart:     isSmart,
+		SmartRules:  smartRules,
+	}
+	r.playlists = append(r.playlists, playlist)
+	return playlist, nil
+}
+
+func (r *memoryPlaylistRepository) FindByID(id int64) (*models.UserPlaylist, error) {
+	for _, playlist := range r.playlists {
+		if playlist.ID == id {
+			return playlist, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryPlaylistRepository) GetByUserID(userID int64) ([]*models.UserPlaylist, error) {
+	var playlists []*models.UserPlaylist
+	for _, playlist := range r.playlists {
+		if playlist.UserID == userID {
+			playlists = append(playlists, playlist)
+		}
+	}
+	return playlists, nil
+}
+
+func (r *memoryPlaylistRepository) Update(playlist *models.UserPlaylist) error { return nil }
+
+func (r *memoryPlaylistRepository) Delete(id int64) error {
+	r.playlists = slices.DeleteFunc(r.playlists, func(p *models.UserPlaylist) bool { return p.ID == id })
+	delete(r.songs, id)
+	return nil
+}
+
+func (r *memoryPlaylistRepository) AddSong(playlistID int64, songID string) error {
+	if slices.Contains(r.songs[playlistID], songID) {
+		return nil
+	}
+	r.songs[playlistID] = append(r.songs[playlistID], songID)
+	return nil
+}
+
+func (r *memoryPlaylistRepository) RemoveSong(playlistID int64, songID string) error {
+	r.songs[playlistID] = slices.DeleteFunc(r.songs[playlistID], func(id string) bool { return id == songID })
+	return nil
+}
+
+func (r *memoryPlaylistRepository) GetSongs(playlistID int64) ([]string, error) {
+	return r.songs[playlistID], nil
+}
+
+func (r *memoryPlaylistRepository) ReorderSongs(playlistID int64, songIDs []string) error {
+	r.songs[playlistID] = songIDs
+	return nil
+}
+
+func (r *memoryPlaylistRepository) IsOwner(playlistID, userID int64) (bool, error) {
+	playlist, err := r.FindByID(playlistID)
+	if err != nil {
+		return false, err
+	}
+	return playlist.UserID == userID, nil
+}
+
+// setupUserTestEnv 初始化一个以 userID 登录的用户处理器测试环境。
+func setupUserTestEnv(userID int64, songs []*models.Song, playlists *memoryPlaylistRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	handler := NewUserHandler(&staticScanner{songs: songs}, nil, nil, playlists)
+	router := gin.New()
+	user := router.Group("/api/v1/user", func(c *gin.Context) { c.Set("user_id", userID) })
+	user.GET("/playlists/export", handler.ExportAllPlaylists)
+	user.GET("/playlists/:id/export", handler.ExportPlaylist)
+	user.POST("/playlists/import", handler.ImportPlaylist)
+	return router
+}
+
+// TestExportPlaylist 测试导出单个播放列表。
+func TestExportPlaylist(t *testing.T) {
+	songs := []*models.Song{
+		{ID: "a", Title: "Song", Artist: "Artist", Duration: 215, RelPath: "Artist/01 Song.flac", Library: "lossless"},
+		{ID: "b", Title: "Other", Artist: "Someone", Duration: 180, RelPath: "other.mp3", Library: models.DefaultLibraryName},
+	}
+	playlists := newMemoryPlaylistRepository()
+	mine, _ := playlists.Create(1, "通勤", "", false, "")
+	playlists.AddSong(mine.ID, "b")
+	playlists.AddSong(mine.ID, "missing")
+	playlists.AddSong(mine.ID, "a")
+	playlists.Create(2, "Other", "", false, "")
+	router := setupUserTestEnv(1, songs, playlists)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/user/playlists/1/export", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("期望状态码 %d, 得到 %d", http.StatusOK, w.Code)
+	}
+	// 默认导出 M3U8，已不在歌曲库中的歌曲被跳过，默认库以外的歌曲路径带有库名
+	expected := "#EXTM3U\n#PLAYLIST:通勤\n#EXTINF:180,Someone - Other\nother.mp3\n#EXTINF:215,Artist - Song\nlossless/Artist/01 Song.flac\n"
+	if w.Body.String() != expected {
+		t.Errorf("导出内容不正确:\n%s", w.Body.String())
+	}
+	if disposition := w.Header().Get("Content-Disposition"); !strings.Contains(disposition, "attachment") || !strings.Contains(disposition, "%E9%80%9A%E5%8B%A4.m3u8") {
+		t.Errorf("Content-Disposition 不正确: %s", disposition)
+	}
+
+	// 使用音频流地址
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/user/playlists/1/export?format=xspf&paths=stream", nil)
+	req.Host = "music.example.com"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("期望状态码 %d, 得到 %d", http.StatusOK, w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "<location>https://music.example.com/api/v1/stream/b</location>") {
+		t.Errorf("XSPF 中应使用音频流地址:\n%s", w.Body.String())
+	}
+
+	testCases := map[string]int{
+		"/api/v1/user/playlists/1/export?format=pls":  http.StatusBadRequest,
+		"/api/v1/user/playlists/1/export?paths=full":  http.StatusBadRequest,
+		"/api/v1/user/playlists/abc/export":           http.StatusBadRequest,
+		"/api/v1/user/playlists/99/export":            http.StatusNotFound,
+		"/api/v1/user/playlists/2/export?format=json": http.StatusForbidden,
+	}
+	for target, status := range testCases {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != status {
+			t.Errorf("%s: 期望状态码 %d, 得到 %d", target, status, w.Code)
+		}
+	}
+}
+
+// TestExportPlaylist_StreamBaseURL 测试音频流地址不使用客户端伪造的协议，配置了服务地址时不使用请求中的主机名。
+func TestExportPlaylist_StreamBaseURL(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	songs := []*models.Song{{ID: "a", Title: "Song", RelPath: "song.mp3"}}
+	playlists := newMemoryPlaylistRepository()
+	mine, _ := playlists.Create(1, "Mix", "", false, "")
+	playlists.AddSong(mine.ID, "a")
+
+	tests := []struct {
+		name      string
+		publicURL string
+		proto     string
+		expected  string
+	}{
+		{"代理设置的协议", "", "HTTPS", "https://evil.example.com/api/v1/stream/a"},
+		{"忽略其他协议", "", "javascript", "http://evil.example.com/api/v1/stream/a"},
+		{"使用配置的服务地址", "https://music.example.com/zero", "http", "https://music.example.com/zero/api/v1/stream/a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewUserHandler(&staticScanner{songs: songs}, nil, nil, playlists)
+			handler.SetPublicURL(tt.publicURL)
+			router := gin.New()
+			router.GET("/api/v1/user/playlists/:id/export", func(c *gin.Context) { c.Set("user_id", int64(1)) }, handler.ExportPlaylist)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/user/playlists/1/export?paths=stream", nil)
+			req.Host = "evil.example.com"
+			req.Header.Set("X-Forwarded-Proto", tt.proto)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "\n"+tt.expected+"\n") {
+				t.Errorf("期望音频流地址 %s, 得到 %d:\n%s", tt.expected, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+// TestExportAllPlaylists 测试将所有播放列表打包导出。
+func TestExportAllPlaylists(t *testing.T) {
+	songs := []*models.Song{{ID: "a", Title: "Song", RelPath: "song.mp3"}}
+	playlists := newMemoryPlaylistRepository()
+	for _, name := range []string{"Mix", "mix", "A/B"} {
+		playlist, _ := playlists.Create(1, name, "", false, "")
+		playlists.AddSong(playlist.ID, "a")
+	}
+	playlists.Create(2, "Other", "", false, "")
+	router := setupUserTestEnv(1, songs, playlists)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/user/playlists/export?format=json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("期望状态码 %d, 得到 %d", http.StatusOK, w.Code)
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "application/zip" {
+		t.Errorf("期望 Content-Type 为 application/zip, 得到 %s", contentType)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatalf("读取 zip 失败: %v", err)
+	}
+	var names []string
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+		r, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(r)
+		r.Close()
+		if !strings.Contains(string(data), `"location": "song.mp3"`) {
+			t.Errorf("%s 的内容不正确: %s", file.Name, data)
+		}
+	}
+	// 同名（不区分大小写）的播放列表加上序号，文件名中的 / 被替换
+	if !slices.Equal(names, []string{"Mix.json", "mix (2).json", "A_B.json"}) {
+		t.Errorf("zip 中的文件不正确: %v", names)
+	}
+}
+
+// newImportRequest 构造上传播放列表文件的请求。
+func newImportRequest(t *testing.T, filename, content string, fields map[string]string) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte(content))
+	for key, value := range fields {
+		writer.WriteField(key, value)
+	}
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/user/playlists/import", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+// TestImportPlaylist 测试从文件导入播放列表。
+func TestImportPlaylist(t *testing.T) {
+	songs := []*models.Song{
+		{ID: "a", Title: "Song", Artist: "Artist", Album: "First", Duration: 200},
+		{ID: "b", Title: "Song", Artist: "Artist", Album: "Second", Duration: 201},
+		{ID: "c", Title: "Other", Artist: "Someone", Duration: 180, RelPath: "Someone/Other.flac"},
+	}
+	playlists := newMemoryPlaylistRepository()
+	router := setupUserTestEnv(1, songs, playlists)
+
+	csv := "Track Name,Artist Name(s),Album Name,Duration (ms)\n" +
+		"Other,Someone,,181000\n" +
+		"Song,Artist,,200000\n" +
+		"Song,Artist,Second,201000\n" +
+		"Other (Live),Someone,,180000\n" +
+		"Missing,Nobody,,100000\n"
+
+	// dry_run 只返回匹配结果
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newImportRequest(t, "Liked.csv", csv, map[string]string{"dry_run": "true", "duration_tolerance": "0"}))
+	if w.Code != http.StatusOK {
+		t.Fatalf("期望状态码 %d, 得到 %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if len(playlists.playlists) != 0 {
+		t.Fatal("dry_run 不应创建播放列表")
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, newImportRequest(t, "Liked.csv", csv, map[string]string{"name": "From Streaming"}))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("期望状态码 %d, 得到 %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var response struct {
+		Data PlaylistImportReport `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	report := response.Data
+	if report.Total != 5 || report.Matched != 3 || report.Ambiguous != 1 || report.Missing != 1 || report.Added != 2 {
+		t.Errorf("导入统计不正确: %+v", report)
+	}
+	statuses := make([]string, len(report.Entries))
+	for i, entry := range report.Entries {
+		statuses[i] = entry.Status
+	}
+	expected := []string{models.MatchStatusMatched, models.MatchStatusAmbiguous, models.MatchStatusMatched, models.MatchStatusMatched, models.MatchStatusMissing}
+	if !slices.Equal(statuses, expected) {
+		t.Errorf("期望匹配结果 %v, 得到 %v", expected, statuses)
+	}
+	if len(report.Entries[1].Candidates) != 2 {
+		t.Errorf("期望 2 个候选歌曲, 得到 %d", len(report.Entries[1].Candidates))
+	}
+	if report.Playlist == nil || report.Playlist.Name != "From Streaming" {
+		t.Fatalf("播放列表不正确: %+v", report.Playlist)
+	}
+	// 重复匹配的歌曲只加入一次
+	if songIDs := playlists.songs[report.Playlist.ID]; !slices.Equal(songIDs, []string{"c", "b"}) {
+		t.Errorf("播放列表中的歌曲不正确: %v", songIDs)
+	}
+
+	testCases := map[string]*http.Request{
+		"不支持的扩展名": newImportRequest(t, "list.txt", "Song", nil),
+		"无效的格式":   newImportRequest(t, "list.csv", csv, map[string]string{"format": "wpl"}),
+		"无效的容差":   newImportRequest(t, "list.csv", csv, map[string]string{"duration_tolerance": "-1"}),
+		"没有条目":    newImportRequest(t, "list.m3u", "#EXTM3U\n", nil),
+		"没有文件":    httptest.NewRequest(http.MethodPost, "/api/v1/user/playlists/import", nil),
+	}
+	for name, req := range testCases {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: 期望状态码 %d, 得到 %d", name, http.StatusBadRequest, w.Code)
+		}
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"zero-music/handlers"
 	"zero-music/logger"
 	"zero-music/middleware"
+	"zero-music/models"
 	"zero-music/repository"
 	"zero-music/services"
 
@@ -50,15 +51,105 @@ func ProvideConfig(params *Params) (*config.Config, error) {
 	return cfg, nil
 }
 
-// ProvideScanner 提供音乐扫描器实例
-func ProvideScanner(cfg *config.Config) services.Scanner {
-	return services.NewMusicScanner(
-		cfg.Music.Directory,
+// ProvideMusicScanner 提供音乐扫描器实例
+// 启动时先从数据库恢复歌曲库，再在后台与磁盘对账，仅重新解析发生变化的文件。
+func ProvideMusicScanner(lc fx.Lifecycle, cfg *config.Config, songRepo repository.SongRepository) *services.MusicScanner {
+	libraries := make([]services.Library, 0, len(cfg.Music.LibraryList()))
+	for _, lib := range cfg.Music.LibraryList() {
+		libraries = append(libraries, services.Library{Name: lib.Name, Directory: lib.Directory})
+	}
+	scanner := services.NewLibraryScanner(
+		libraries,
 		cfg.Music.SupportedFormats,
 		cfg.Music.CacheTTLMinutes,
+		songRepo,
+	)
+	scanner.SetConcurrency(cfg.Music.ScanConcurrency)
+	scanner.SetAliasRetention(time.Duration(cfg.Music.IDAliasRetentionDays) * 24 * time.Hour)
+	scanner.SetArtworkPatterns(cfg.Cover.FilePatterns, cfg.Cover.ArtistImagePatterns)
+	scanner.SetArtistSeparators(cfg.Music.ArtistSeparators)
+
+	reconcileCtx, cancel := context.WithCancel(context.Background())
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			if err := scanner.Restore(); err != nil {
+				logger.Warnf("从数据库恢复歌曲库失败: %v", err)
+			} else {
+				logger.Infof("已从数据库恢复 %d 首歌曲", scanner.GetSongCount())
+			}
+
+			go func() {
+				if _, err := scanner.Rescan(reconcileCtx, services.ScanModeIncremental); err != nil {
+					logger.Warnf("歌曲库对账失败: %v", err)
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			cancel()
+			return nil
+		},
+	})
+
+	return scanner
+}
+
+// ProvideScanner 提供供处理器使用的扫描器接口，歌曲列表中不包含被隐藏的重复副本
+func ProvideScanner(scanner *services.MusicScanner, duplicates *services.DuplicateService) services.Scanner {
+	return services.NewVisibleScanner(scanner, duplicates)
+}
+
+// ProvideDuplicateService 提供重复歌曲服务，加载管理员选定的首选副本
+func ProvideDuplicateService(scanner *services.MusicScanner, repo repository.DuplicateRepository) *services.DuplicateService {
+	duplicates := services.NewDuplicateService(scanner, repo)
+	if err := duplicates.Load(); err != nil {
+		logger.Warn(err)
+	}
+	return duplicates
+}
+
+// ProvideMetadataOverrideService 提供元数据覆盖服务，加载管理员保存的元数据修正
+func ProvideMetadataOverrideService(scanner *services.MusicScanner, repo repository.MetadataOverrideRepository) *services.MetadataOverrideService {
+	overrides := services.NewMetadataOverrideService(scanner, repo)
+	if err := overrides.Load(); err != nil {
+		logger.Warn(err)
+	}
+	return overrides
+}
+
+// ProvideLibraryWatcher 提供音乐目录监听器
+func ProvideLibraryWatcher(cfg *config.Config, scanner *services.MusicScanner) *services.LibraryWatcher {
+	return services.NewLibraryWatcher(
+		scanner,
+		time.Duration(cfg.Music.WatchDebounceMillis)*time.Millisecond,
+		time.Duration(cfg.Music.WatchPollIntervalSeconds)*time.Second,
 	)
 }
 
+// ProvideScanJobManager 提供后台扫描任务管理器
+func ProvideScanJobManager(lc fx.Lifecycle, scanner *services.MusicScanner) *services.ScanJobManager {
+	manager := services.NewScanJobManager(scanner)
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			manager.Stop()
+			return nil
+		},
+	})
+	return manager
+}
+
+// ProvideLoudnessJobManager 提供后台响度分析任务管理器
+func ProvideLoudnessJobManager(lc fx.Lifecycle, cfg *config.Config, scanner *services.MusicScanner) *services.LoudnessJobManager {
+	manager := services.NewLoudnessJobManager(scanner, cfg.Music.ScanConcurrency)
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			manager.Stop()
+			return nil
+		},
+	})
+	return manager
+}
+
 // ProvideDBManager 提供数据库管理器实例
 func ProvideDBManager(lc fx.Lifecycle, cfg *config.Config) (*database.DBManager, error) {
 	dbCfg := &database.DBConfig{
@@ -118,6 +209,21 @@ func ProvidePlaylistRepository(db database.DB) repository.PlaylistRepository {
 	return repository.NewSQLitePlaylistRepository(db)
 }
 
+// ProvideSongRepository 提供歌曲库仓储实例
+func ProvideSongRepository(db database.DB) repository.SongRepository {
+	return repository.NewSQLiteSongRepository(db)
+}
+
+// ProvideDuplicateRepository 提供重复歌曲首选项仓储实例
+func ProvideDuplicateRepository(db database.DB) repository.DuplicateRepository {
+	return repository.NewSQLiteDuplicateRepository(db)
+}
+
+// ProvideMetadataOverrideRepository 提供元数据覆盖仓储实例
+func ProvideMetadataOverrideRepository(db database.DB) repository.MetadataOverrideRepository {
+	return repository.NewSQLiteMetadataOverrideRepository(db)
+}
+
 // ProvidePlaylistHandler 提供播放列表处理器
 func ProvidePlaylistHandler(scanner services.Scanner) *handlers.PlaylistHandler {
 	return handlers.NewPlaylistHandler(scanner)
@@ -141,12 +247,15 @@ func ProvideAuthHandler(cfg *config.Config, userRepo repository.UserRepository,
 
 // ProvideUserHandler 提供用户处理器
 func ProvideUserHandler(
+	cfg *config.Config,
 	scanner services.Scanner,
 	favoriteRepo repository.FavoriteRepository,
 	playStats repository.PlayStatsRepository,
 	playlistRepo repository.PlaylistRepository,
 ) *handlers.UserHandler {
-	return handlers.NewUserHandler(scanner, favoriteRepo, playStats, playlistRepo)
+	handler := handlers.NewUserHandler(scanner, favoriteRepo, playStats, playlistRepo)
+	handler.SetPublicURL(cfg.Server.PublicURL)
+	return handler
 }
 
 // ProvideSearchHandler 提供搜索处理器
@@ -154,6 +263,155 @@ func ProvideSearchHandler(scanner services.Scanner) *handlers.SearchHandler {
 	return handlers.NewSearchHandler(scanner)
 }
 
+// ProvideCoverService 提供封面服务，元数据覆盖中的封面从数据库读取
+func ProvideCoverService(cfg *config.Config, overrides *services.MetadataOverrideService) *services.CoverService {
+	covers := services.NewCoverService(cfg.Cover.CacheDirectory, cfg.Cover.MaxThumbnailSize)
+	covers.SetOverrideCovers(overrides.Cover)
+	return covers
+}
+
+// ProvideTagEditor 提供标签编辑器
+func ProvideTagEditor(scanner *services.MusicScanner) *services.TagEditor {
+	return services.NewTagEditor(scanner)
+}
+
+// ProvideCoverHandler 提供封面处理器
+func ProvideCoverHandler(scanner services.Scanner, covers *services.CoverService) *handlers.CoverHandler {
+	return handlers.NewCoverHandler(scanner, covers)
+}
+
+// ProvideLyricsHandler 提供歌词处理器
+func ProvideLyricsHandler(scanner services.Scanner) *handlers.LyricsHandler {
+	return handlers.NewLyricsHandler(scanner)
+}
+
+// ProvideSharedPlaylistHandler 提供共享播放列表处理器
+func ProvideSharedPlaylistHandler(library *services.MusicScanner, scanner services.Scanner) *handlers.SharedPlaylistHandler {
+	return handlers.NewSharedPlaylistHandler(library, scanner)
+}
+
+// ProvideUploadRepository 提供上传记录仓储
+func ProvideUploadRepository(db database.DB) repository.UploadRepository {
+	return repository.NewSQLiteUploadRepository(db)
+}
+
+// ProvideRoleCapabilities 提供各角色被授予的权限
+func ProvideRoleCapabilities(cfg *config.Config) (models.RoleCapabilities, error) {
+	capabilities := make(models.RoleCapabilities, len(cfg.Auth.RoleCapabilities))
+	for role, names := range cfg.Auth.RoleCapabilities {
+		if !models.ValidRole(models.Role(role)) {
+			return nil, fmt.Errorf("未知的角色: %s", role)
+		}
+		for _, name := range names {
+			if !models.ValidCapability(models.Capability(name)) {
+				return nil, fmt.Errorf("未知的权限: %s", name)
+			}
+			capabilities[models.Role(role)] = append(capabilities[models.Role(role)], models.Capability(name))
+		}
+	}
+	return capabilities, nil
+}
+
+// ProvideUploader 提供上传服务
+func ProvideUploader(cfg *config.Config, scanner *services.MusicScanner, repo repository.UploadRepository) (*services.Uploader, error) {
+	uploader, err := services.NewUploader(scanner, repo, cfg.Upload.Library, cfg.Upload.PathTemplate)
+	if err != nil {
+		return nil, err
+	}
+	uploader.SetLimits(int64(cfg.Upload.MaxFileSizeMB)<<20, int64(cfg.Upload.DefaultQuotaMB)<<20)
+	return uploader, nil
+}
+
+// ProvideUploadSessionRepository 提供断点续传上传会话仓储
+func ProvideUploadSessionRepository(db database.DB) repository.UploadSessionRepository {
+	return repository.NewSQLiteUploadSessionRepository(db)
+}
+
+// ProvideTrashRepository 提供回收站仓储
+func ProvideTrashRepository(db database.DB) repository.TrashRepository {
+	return repository.NewSQLiteTrashRepository(db)
+}
+
+// ProvideResumableUploader 提供断点续传上传服务，并定期清理过期的上传会话
+func ProvideResumableUploader(lc fx.Lifecycle, cfg *config.Config, uploader *services.Uploader, repo repository.UploadSessionRepository) (*services.ResumableUploader, error) {
+	resumable, err := services.NewResumableUploader(
+		uploader,
+		repo,
+		cfg.Upload.StagingDirectory,
+		time.Duration(cfg.Upload.SessionExpiryHours)*time.Hour,
+	)
+	if err != nil {
+		return nil, err
+	}
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			resumable.Start(time.Hour)
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			resumable.Stop()
+			return nil
+		},
+	})
+	return resumable, nil
+}
+
+// ProvideSongManager 提供歌曲移动和删除服务，并在应用生命周期内定期清理回收站
+func ProvideSongManager(
+	lc fx.Lifecycle,
+	cfg *config.Config,
+	scanner *services.MusicScanner,
+	trash repository.TrashRepository,
+	uploads repository.UploadRepository,
+	duplicates *services.DuplicateService,
+) (*services.SongManager, error) {
+	songs, err := services.NewSongManager(
+		scanner,
+		trash,
+		uploads,
+		duplicates,
+		cfg.Music.TrashDirectory,
+		time.Duration(cfg.Music.TrashRetentionDays)*24*time.Hour,
+	)
+	if err != nil {
+		return nil, err
+	}
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			songs.Start(time.Hour)
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			songs.Stop()
+			return nil
+		},
+	})
+	return songs, nil
+}
+
+// ProvideTusHandler 提供断点续传（tus 协议）上传处理器
+func ProvideTusHandler(resumable *services.ResumableUploader) *handlers.TusHandler {
+	return handlers.NewTusHandler(resumable)
+}
+
+// ProvideUploadHandler 提供上传处理器
+func ProvideUploadHandler(uploader *services.Uploader, userRepo repository.UserRepository) *handlers.UploadHandler {
+	return handlers.NewUploadHandler(uploader, userRepo)
+}
+
+// ProvideAdminHandler 提供管理员处理器
+func ProvideAdminHandler(
+	scans *services.ScanJobManager,
+	loudness *services.LoudnessJobManager,
+	duplicates *services.DuplicateService,
+	scanner *services.MusicScanner,
+	tags *services.TagEditor,
+	overrides *services.MetadataOverrideService,
+	songs *services.SongManager,
+) *handlers.AdminHandler {
+	return handlers.NewAdminHandler(scans, loudness, duplicates, scanner, tags, overrides, songs)
+}
+
 // ProvideRouter 提供 Gin 路由器
 func ProvideRouter(
 	cfg *config.Config,
@@ -163,7 +421,14 @@ func ProvideRouter(
 	authHandler *handlers.AuthHandler,
 	userHandler *handlers.UserHandler,
 	searchHandler *handlers.SearchHandler,
+	coverHandler *handlers.CoverHandler,
+	lyricsHandler *handlers.LyricsHandler,
+	sharedPlaylistHandler *handlers.SharedPlaylistHandler,
+	adminHandler *handlers.AdminHandler,
+	uploadHandler *handlers.UploadHandler,
+	tusHandler *handlers.TusHandler,
 	jwtManager *middleware.JWTManager,
+	capabilities models.RoleCapabilities,
 ) *gin.Engine {
 	router := gin.Default()
 
@@ -190,6 +455,18 @@ func ProvideRouter(
 		v1.GET("/songs", playlistHandler.GetAllSongs)
 		v1.GET("/song/:id", playlistHandler.GetSongByID)
 
+		// 音乐目录中的共享播放列表（公开，只读）
+		v1.GET("/playlists", sharedPlaylistHandler.GetSharedPlaylists)
+		v1.GET("/playlists/:id", sharedPlaylistHandler.GetSharedPlaylist)
+
+		// 封面路由（公开）
+		v1.GET("/song/:id/cover", coverHandler.GetSongCover)
+		v1.GET("/albums/:name/cover", coverHandler.GetAlbumCover)
+		v1.GET("/artists/:name/image", coverHandler.GetArtistImage)
+
+		// 歌词路由（公开）
+		v1.GET("/song/:id/lyrics", lyricsHandler.GetSongLyrics)
+
 		// 音频流路由（公开，可选认证）
 		v1.GET("/stream/:id", streamHandler.StreamAudio)
 
@@ -225,13 +502,69 @@ func ProvideRouter(
 			// 用户播放列表
 			user.GET("/playlists", userHandler.GetPlaylists)
 			user.POST("/playlists", userHandler.CreatePlaylist)
+			user.GET("/playlists/export", userHandler.ExportAllPlaylists)
+			user.POST("/playlists/import", userHandler.ImportPlaylist)
 			user.GET("/playlists/:id", userHandler.GetPlaylist)
+			user.GET("/playlists/:id/export", userHandler.ExportPlaylist)
 			user.PUT("/playlists/:id", userHandler.UpdatePlaylist)
 			user.DELETE("/playlists/:id", userHandler.DeletePlaylist)
 			user.POST("/playlists/:id/songs", userHandler.AddSongToPlaylist)
 			user.DELETE("/playlists/:id/songs/:songId", userHandler.RemoveSongFromPlaylist)
 			user.PUT("/playlists/:id/reorder", userHandler.ReorderPlaylistSongs)
 		}
+
+		// 上传歌曲到音乐库（需要 upload 权限）
+		uploads := v1.Group("/uploads")
+		uploads.Use(middleware.JWTAuth(jwtManager), middleware.RequireCapability(capabilities, models.CapabilityUpload))
+		{
+			uploads.POST("", uploadHandler.Upload)
+			uploads.GET("", uploadHandler.GetUploads)
+
+			// 断点续传上传（tus 1.0 协议）
+			tus := uploads.Group("/tus", tusHandler.Protocol)
+			tus.OPTIONS("", tusHandler.Options)
+			tus.POST("", tusHandler.Create)
+			tus.HEAD("/:id", tusHandler.Head)
+			tus.PATCH("/:id", tusHandler.Patch)
+			tus.DELETE("/:id", tusHandler.Delete)
+			tus.GET("/:id", tusHandler.Get)
+		}
+
+		// 管理员路由
+		admin := v1.Group("/admin")
+		admin.Use(middleware.JWTAuth(jwtManager), middleware.AdminOnly())
+		{
+			// 音乐库扫描
+			admin.POST("/library/scan", adminHandler.StartScan)
+			admin.GET("/library/scan", adminHandler.GetScanStatus)
+			admin.DELETE("/library/scan", adminHandler.CancelScan)
+			// 响度分析
+			admin.POST("/library/loudness", adminHandler.StartLoudnessAnalysis)
+			admin.GET("/library/loudness", adminHandler.GetLoudnessStatus)
+			admin.DELETE("/library/loudness", adminHandler.CancelLoudnessAnalysis)
+			// 重复歌曲
+			admin.GET("/library/duplicates", adminHandler.GetDuplicates)
+			admin.PUT("/library/duplicates/:id/preferred", adminHandler.PreferDuplicate)
+			admin.DELETE("/library/duplicates/:id/preferred", adminHandler.ClearDuplicatePreference)
+			// 歌曲库健康报告
+			admin.GET("/library/issues", adminHandler.GetLibraryIssues)
+			// 标签编辑
+			admin.PUT("/songs/tags", adminHandler.BulkUpdateSongTags)
+			admin.PUT("/songs/:id/tags", adminHandler.UpdateSongTags)
+			// 移动和删除歌曲
+			admin.POST("/songs/:id/move", adminHandler.MoveSong)
+			admin.DELETE("/songs/:id", adminHandler.DeleteSong)
+			admin.GET("/trash", adminHandler.GetTrash)
+			// 元数据覆盖
+			admin.GET("/overrides", adminHandler.GetMetadataOverrides)
+			admin.GET("/songs/:id/overrides", adminHandler.GetSongMetadataOverride)
+			admin.PUT("/songs/:id/overrides", adminHandler.UpdateSongMetadataOverride)
+			admin.DELETE("/songs/:id/overrides", adminHandler.DeleteSongMetadataOverride)
+			// 上传配额
+			admin.GET("/users/:id/quota", uploadHandler.GetUserQuota)
+			admin.PUT("/users/:id/quota", uploadHandler.UpdateUserQuota)
+			admin.DELETE("/users/:id/quota", uploadHandler.DeleteUserQuota)
+		}
 	}
 
 	return router
@@ -279,13 +612,30 @@ func initLogger(lc fx.Lifecycle, params *Params) error {
 	return nil
 }
 
+// startLibraryWatcher 启动音乐目录监听
+func startLibraryWatcher(lc fx.Lifecycle, watcher *services.LibraryWatcher) {
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			watcher.Start()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			logger.Info("正在停止音乐目录监听...")
+			watcher.Stop()
+			return nil
+		},
+	})
+}
+
 // startHTTPServer 启动 HTTP 服务器
 func startHTTPServer(lc fx.Lifecycle, srv *http.Server, cfg *config.Config) {
 	lc.Append(fx.Hook{
 		OnStart: func(ctx context.Context) error {
 			logger.Info("Zero Music 服务器启动中...")
 			logger.Infof("服务地址: http://localhost:%d", cfg.Server.Port)
-			logger.Infof("音乐目录: %s", cfg.Music.Directory)
+			for _, lib := range cfg.Music.LibraryList() {
+				logger.Infof("音乐库 %s: %s", lib.Name, lib.Directory)
+			}
 
 			go func() {
 				if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -317,13 +667,31 @@ func main() {
 			ProvideConfig,
 			ProvideDBManager,
 			ProvideDB,
+			ProvideMusicScanner,
 			ProvideScanner,
+			ProvideDuplicateService,
+			ProvideMetadataOverrideService,
+			ProvideLibraryWatcher,
+			ProvideScanJobManager,
+			ProvideLoudnessJobManager,
+			ProvideCoverService,
+			ProvideTagEditor,
+			ProvideUploader,
+			ProvideResumableUploader,
+			ProvideSongManager,
+			ProvideRoleCapabilities,
 			ProvideJWTManager,
 			// Repository 层
 			ProvideUserRepository,
 			ProvideFavoriteRepository,
 			ProvidePlayStatsRepository,
 			ProvidePlaylistRepository,
+			ProvideSongRepository,
+			ProvideDuplicateRepository,
+			ProvideMetadataOverrideRepository,
+			ProvideUploadRepository,
+			ProvideUploadSessionRepository,
+			ProvideTrashRepository,
 			// Handler 层
 			ProvidePlaylistHandler,
 			ProvideStreamHandler,
@@ -331,12 +699,19 @@ func main() {
 			ProvideAuthHandler,
 			ProvideUserHandler,
 			ProvideSearchHandler,
+			ProvideCoverHandler,
+			ProvideLyricsHandler,
+			ProvideSharedPlaylistHandler,
+			ProvideAdminHandler,
+			ProvideUploadHandler,
+			ProvideTusHandler,
 			ProvideRouter,
 			ProvideHTTPServer,
 		),
 		// 调用初始化函数
 		fx.Invoke(
 			initLogger,
+			startLibraryWatcher,
 			startHTTPServer,
 		),
 	)
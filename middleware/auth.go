@@ -186,6 +186,32 @@ func AdminOnly() gin.HandlerFunc {
 	}
 }
 
+// RequireCapability 仅拥有指定权限的角色可访问中间件
+func RequireCapability(capabilities models.RoleCapabilities, capability models.Capability) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, exists := GetCurrentRole(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    401,
+				"message": "请先登录",
+			})
+			c.Abort()
+			return
+		}
+
+		if !capabilities.Has(role, capability) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":    403,
+				"message": "无权限访问",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // GetCurrentUserID 从上下文获取当前用户ID
 func GetCurrentUserID(c *gin.Context) (int64, bool) {
 	userID, exists := c.Get("user_id")
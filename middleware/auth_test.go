@@ -276,6 +276,36 @@ func TestAdminOnly_NoRole(t *testing.T) {
 	assert.Equal(t, http.StatusUnauthorized, w.Code)
 }
 
+func TestRequireCapability(t *testing.T) {
+	capabilities := models.RoleCapabilities{models.RoleUser: {models.CapabilityUpload}}
+	tests := []struct {
+		name         string
+		capabilities models.RoleCapabilities
+		role         models.Role
+		expectedCode int
+	}{
+		{"granted to user", capabilities, models.RoleUser, http.StatusOK},
+		{"not granted", models.RoleCapabilities{}, models.RoleUser, http.StatusForbidden},
+		{"admin always allowed", models.RoleCapabilities{}, models.RoleAdmin, http.StatusOK},
+		{"no role", capabilities, "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			if tt.role != "" {
+				c.Set("role", tt.role)
+			}
+
+			RequireCapability(tt.capabilities, models.CapabilityUpload)(c)
+
+			assert.Equal(t, tt.expectedCode != http.StatusOK, c.IsAborted())
+			assert.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
+
 func TestGetCurrentUserID(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ArtistSplitter 按分隔符把艺术家标签（如 "A feat. B"、"A; B"）拆分为多位艺术家。
+type ArtistSplitter struct {
+	pattern *regexp.Regexp
+}
+
+// NewArtistSplitter 根据分隔符创建 ArtistSplitter，分隔符不区分大小写。
+// 以字母或数字开头、结尾的分隔符（如 feat.）在该侧必须有空白，避免拆开艺术家名称中的单词（如 Defeat.）；
+// 其他分隔符（如 ;、/）两侧的空白可有可无。没有有效的分隔符时不拆分。
+func NewArtistSplitter(separators []string) *ArtistSplitter {
+	cleaned := make([]string, 0, len(separators))
+	for _, separator := range separators {
+		if separator = strings.TrimSpace(separator); separator != "" {
+			cleaned = append(cleaned, separator)
+		}
+	}
+	if len(cleaned) == 0 {
+		return &ArtistSplitter{}
+	}
+	// 较长的分隔符优先匹配，例如同时配置了 feat 和 featuring
+	sort.SliceStable(cleaned, func(i, j int) bool {
+		return len(cleaned[i]) > len(cleaned[j])
+	})
+
+	alternatives := make([]string, 0, len(cleaned))
+	for _, separator := range cleaned {
+		prefix, suffix := `\s*`, `\s*`
+		if first, _ := utf8.DecodeRuneInString(separator); isWordRune(first) {
+			// 也允许紧跟在左括号之后，如 "A (feat. B)"
+			prefix = `(?:\s+|\s*[(\[（])`
+		}
+		if last, _ := utf8.DecodeLastRuneInString(separator); isWordRune(last) {
+			suffix = `\s+`
+		}
+		alternatives = append(alternatives, prefix+regexp.QuoteMeta(separator)+suffix)
+	}
+	return &ArtistSplitter{pattern: regexp.MustCompile(`(?i)(?:` + strings.Join(alternatives, "|") + `)`)}
+}
+
+// isWordRune 判断字符是否属于单词（字母或数字）。
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Split 拆分艺术家标签，返回去除首尾空白、按出现顺序去重（不区分大小写）后的艺术家列表。
+// artist 为空时返回 nil。
+func (s *ArtistSplitter) Split(artist string) []string {
+	parts := []string{artist}
+	if s != nil && s.pattern != nil {
+		parts = s.pattern.Split(artist, -1)
+	}
+
+	var artists []string
+	seen := make(map[string]bool, len(parts))
+	for _, part := range parts {
+		name := trimUnpairedBrackets(strings.TrimSpace(part))
+		key := strings.ToLower(name)
+		if name == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		artists = append(artists, name)
+	}
+	return artists
+}
+
+// trimUnpairedBrackets 去掉拆分后残留在名称两端的不成对括号，如 "A (feat. B)" 拆出的 "A (" 和 "B)"。
+func trimUnpairedBrackets(name string) string {
+	for _, pair := range [][2]string{{"(", ")"}, {"[", "]"}, {"（", "）"}} {
+		open, close := pair[0], pair[1]
+		if strings.HasSuffix(name, open) {
+			name = strings.TrimSpace(strings.TrimSuffix(name, open))
+		}
+		if strings.HasPrefix(name, close) {
+			name = strings.TrimSpace(strings.TrimPrefix(name, close))
+		}
+		if strings.HasPrefix(name, open) && !strings.Contains(name, close) {
+			name = strings.TrimSpace(strings.TrimPrefix(name, open))
+		}
+		if strings.HasSuffix(name, close) && !strings.Contains(name, open) {
+			name = strings.TrimSpace(strings.TrimSuffix(name, close))
+		}
+	}
+	return name
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArtistSplitter_Split(t *testing.T) {
+	splitter := NewArtistSplitter([]string{";", "feat.", "ft.", "featuring", "、", " "})
+
+	testCases := map[string][]string{
+		"A; B":              {"A", "B"},
+		"A feat. B":         {"A", "B"},
+		"A FEAT. B ft. C":   {"A", "B", "C"},
+		"A (feat. B)":       {"A", "B"},
+		"A featuring B":     {"A", "B"},
+		"周杰伦、费玉清":           {"周杰伦", "费玉清"},
+		"A; a;  ; B":        {"A", "B"},
+		"Defeat. The Band":  {"Defeat. The Band"},
+		"Featuring Artists": {"Featuring Artists"},
+		"AC/DC":             {"AC/DC"},
+		"Simon & Garfunkel": {"Simon & Garfunkel"},
+		"":                  nil,
+	}
+	for input, expected := range testCases {
+		assert.Equal(t, expected, splitter.Split(input), input)
+	}
+
+	// 没有分隔符时不拆分
+	assert.Equal(t, []string{"A; B"}, NewArtistSplitter(nil).Split(" A; B "))
+	var nilSplitter *ArtistSplitter
+	assert.Equal(t, []string{"A feat. B"}, nilSplitter.Split("A feat. B"))
+}
+
+func TestSong_AlbumArtistName(t *testing.T) {
+	song := &Song{Artist: "A feat. B"}
+	assert.Equal(t, []string{"A feat. B"}, song.ArtistNames())
+	assert.Equal(t, "A feat. B", song.AlbumArtistName())
+
+	song.Artists = []string{"A", "B"}
+	assert.Equal(t, "A", song.AlbumArtistName())
+
+	song.AlbumArtist = "Various Artists"
+	assert.Equal(t, "Various Artists", song.AlbumArtistName())
+
+	assert.Empty(t, (&Song{}).AlbumArtistName())
+}
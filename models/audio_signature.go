@@ -0,0 +1,67 @@
+package models
+
+import (
+	"bytes"
+	"io"
+)
+
+// audioSignatureSize 是检查文件签名时读取的字节数。
+const audioSignatureSize = 16
+
+// asfHeaderGUID 是 ASF（WMA）文件头对象的 GUID。
+var asfHeaderGUID = []byte{0x30, 0x26, 0xB2, 0x75, 0x8E, 0x66, 0xCF, 0x11, 0xA6, 0xD9, 0x00, 0xAA, 0x00, 0x62, 0xCE, 0x6C}
+
+// audioSignatures 按扩展名检查文件开头的魔数。header 是文件开头（或 ID3v2 标签之后）的字节。
+var audioSignatures = map[string]func(header []byte, afterID3 bool) bool{
+	".mp3": func(h []byte, afterID3 bool) bool {
+		// ID3v2 标签之后可能是填充而不是帧同步字，有标签且不是带标签的 FLAC 即视为 MP3
+		if afterID3 {
+			return !bytes.HasPrefix(h, []byte("fLaC"))
+		}
+		return len(h) >= 2 && h[0] == 0xFF && h[1]&0xE0 == 0xE0
+	},
+	".flac": func(h []byte, _ bool) bool { return bytes.HasPrefix(h, []byte("fLaC")) },
+	".ogg":  isOgg,
+	".oga":  isOgg,
+	".opus": isOgg,
+	".m4a":  isMP4,
+	".m4b":  isMP4,
+	".mp4":  isMP4,
+	".wav": func(h []byte, _ bool) bool {
+		return len(h) >= 12 && (string(h[:4]) == "RIFF" || string(h[:4]) == "RF64") && string(h[8:12]) == "WAVE"
+	},
+	".aif":  isAIFF,
+	".aiff": isAIFF,
+	".aifc": isAIFF,
+	".aac": func(h []byte, _ bool) bool {
+		return bytes.HasPrefix(h, []byte("ADIF")) || len(h) >= 2 && h[0] == 0xFF && h[1]&0xF6 == 0xF0
+	},
+	".ape": func(h []byte, _ bool) bool { return bytes.HasPrefix(h, []byte("MAC ")) },
+	".wv":  func(h []byte, _ bool) bool { return bytes.HasPrefix(h, []byte("wvpk")) },
+	".dsf": func(h []byte, _ bool) bool { return bytes.HasPrefix(h, []byte("DSD ")) },
+	".wma": func(h []byte, _ bool) bool { return bytes.HasPrefix(h, asfHeaderGUID) },
+}
+
+func isOgg(h []byte, _ bool) bool { return bytes.HasPrefix(h, []byte("OggS")) }
+
+func isMP4(h []byte, _ bool) bool { return len(h) >= 8 && string(h[4:8]) == "ftyp" }
+
+func isAIFF(h []byte, _ bool) bool {
+	return len(h) >= 12 && string(h[:4]) == "FORM" && (string(h[8:12]) == "AIFF" || string(h[8:12]) == "AIFC")
+}
+
+// HasAudioSignature 判断文件内容的魔数是否与扩展名 ext 对应的音频格式一致，用于拒绝伪装成音频的文件。
+// 文件开头的 ID3v2 标签会被跳过；没有已知签名的格式返回 false。
+func HasAudioSignature(ext string, r io.ReaderAt) bool {
+	match, ok := audioSignatures[ext]
+	if !ok {
+		return false
+	}
+	offset := skipID3v2(r)
+	header := make([]byte, audioSignatureSize)
+	n, err := r.ReadAt(header, offset)
+	if err != nil && err != io.EOF {
+		return false
+	}
+	return match(header[:n], offset > 0)
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHasAudioSignature(t *testing.T) {
+	id3 := []byte{'I', 'D', '3', 3, 0, 0, 0, 0, 0, 4, 0, 0, 0, 0}
+	tests := []struct {
+		ext  string
+		data []byte
+		want bool
+	}{
+		{".mp3", []byte{0xFF, 0xFB, 0x90, 0x00}, true},
+		{".mp3", append(append([]byte{}, id3...), 0xFF, 0xFB), true},
+		{".mp3", append(append([]byte{}, id3...), []byte("fLaC")...), false},
+		{".mp3", []byte("<html>"), false},
+		{".flac", []byte("fLaC\x00\x00\x00\x22"), true},
+		{".flac", append(append([]byte{}, id3...), []byte("fLaC")...), true},
+		{".ogg", []byte("OggS\x00\x02"), true},
+		{".opus", []byte("RIFF"), false},
+		{".m4a", []byte("\x00\x00\x00\x20ftypM4A "), true},
+		{".wav", []byte("RIFF\x24\x00\x00\x00WAVEfmt "), true},
+		{".wav", []byte("RIFF\x24\x00\x00\x00AVI LIST"), false},
+		{".aiff", []byte("FORM\x00\x00\x00\x00AIFF"), true},
+		{".txt", []byte("hello"), false},
+		{".mp3", nil, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, HasAudioSignature(tt.ext, bytes.NewReader(tt.data)), "%s %q", tt.ext, tt.data)
+	}
+}
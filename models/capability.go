@@ -0,0 +1,35 @@
+package models
+
+import "slices"
+
+// Capability 是可以授予角色的操作权限。
+type Capability string
+
+const (
+	// CapabilityUpload 允许上传歌曲到音乐库。
+	CapabilityUpload Capability = "upload"
+)
+
+// Capabilities 是所有已知的权限。
+var Capabilities = []Capability{CapabilityUpload}
+
+// ValidRole 判断 role 是否是已知的角色。
+func ValidRole(role Role) bool {
+	return role == RoleUser || role == RoleAdmin
+}
+
+// ValidCapability 判断 capability 是否是已知的权限。
+func ValidCapability(capability Capability) bool {
+	return slices.Contains(Capabilities, capability)
+}
+
+// RoleCapabilities 记录每个角色被授予的权限。管理员始终拥有所有权限。
+type RoleCapabilities map[Role][]Capability
+
+// Has 判断 role 是否拥有 capability。
+func (rc RoleCapabilities) Has(role Role, capability Capability) bool {
+	if role == RoleAdmin {
+		return true
+	}
+	return slices.Contains(rc[role], capability)
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoleCapabilities(t *testing.T) {
+	capabilities := RoleCapabilities{RoleUser: {CapabilityUpload}}
+	assert.True(t, capabilities.Has(RoleUser, CapabilityUpload))
+	assert.True(t, RoleCapabilities{}.Has(RoleAdmin, CapabilityUpload), "管理员始终拥有所有权限")
+	assert.False(t, RoleCapabilities{}.Has(RoleUser, CapabilityUpload))
+	assert.False(t, capabilities.Has("guest", CapabilityUpload))
+	assert.True(t, ValidCapability("upload"))
+	assert.False(t, ValidCapability("delete"))
+	assert.False(t, ValidRole("guest"))
+}
@@ -0,0 +1,326 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// CueFramesPerSecond 是 CUE 时间 mm:ss:ff 中每秒的帧数（CD 扇区）。
+const CueFramesPerSecond = 75
+
+// CueSheet 是解析后的 CUE 文件，描述一个或多个整轨音频文件中的曲目。
+type CueSheet struct {
+	Title      string
+	Performer  string
+	Songwriter string
+	Genre      string
+	Date       string
+	// AlbumGain 和 AlbumPeak 来自 REM REPLAYGAIN_ALBUM_GAIN / REPLAYGAIN_ALBUM_PEAK。
+	AlbumGain *float64
+	AlbumPeak *float64
+	Files     []CueFile
+}
+
+// CueFile 是 CUE 中的一个 FILE 段及其包含的曲目。
+type CueFile struct {
+	// Name 是 FILE 命令中的文件名，相对于 CUE 文件所在目录。
+	Name   string
+	Tracks []CueTrack
+}
+
+// CueTrack 是 CUE 中的一个音频曲目。
+type CueTrack struct {
+	Number     int
+	Title      string
+	Performer  string
+	Songwriter string
+	// StartMs 是 INDEX 01 的位置（毫秒）。
+	StartMs   int64
+	TrackGain *float64
+	TrackPeak *float64
+}
+
+// errInvalidCue 表示内容不是可用的 CUE 文件。
+var errInvalidCue = errors.New("无效的 CUE 文件")
+
+// ParseCueSheet 解析 CUE 文件的内容，数据的编码与歌词文件一样自动识别（UTF-8、UTF-16 或 GBK）。
+// 只保留 AUDIO 曲目；缺少 INDEX 01 的曲目会被忽略。
+func ParseCueSheet(data []byte) (*CueSheet, error) {
+	sheet := &CueSheet{}
+	var file *CueFile
+	var track *CueTrack
+
+	for _, raw := range strings.Split(DecodeLyricsFile(data), "\n") {
+		fields := cueFields(strings.TrimSpace(raw))
+		if len(fields) == 0 {
+			continue
+		}
+		command := strings.ToUpper(fields[0])
+		arg := ""
+		if len(fields) > 1 {
+			arg = fields[1]
+		}
+
+		switch command {
+		case "FILE":
+			sheet.Files = append(sheet.Files, CueFile{Name: arg})
+			file = &sheet.Files[len(sheet.Files)-1]
+			track = nil
+		case "TRACK":
+			track = nil
+			if file == nil || len(fields) < 3 || !strings.EqualFold(fields[2], "AUDIO") {
+				continue
+			}
+			number, err := strconv.Atoi(arg)
+			if err != nil || number <= 0 {
+				continue
+			}
+			file.Tracks = append(file.Tracks, CueTrack{Number: number, StartMs: -1})
+			track = &file.Tracks[len(file.Tracks)-1]
+		case "INDEX":
+			if track != nil && len(fields) >= 3 && arg == "01" {
+				if ms, ok := parseCueTime(fields[2]); ok {
+					track.StartMs = ms
+				}
+			}
+		case "TITLE", "PERFORMER", "SONGWRITER":
+			target := &sheet.Title
+			switch {
+			case track != nil && command == "TITLE":
+				target = &track.Title
+			case track != nil && command == "PERFORMER":
+				target = &track.Performer
+			case track != nil:
+				target = &track.Songwriter
+			case command == "PERFORMER":
+				target = &sheet.Performer
+			case command == "SONGWRITER":
+				target = &sheet.Songwriter
+			}
+			*target = strings.TrimSpace(arg)
+		case "REM":
+			if len(fields) < 3 {
+				continue
+			}
+			value := fields[2]
+			switch strings.ToUpper(arg) {
+			case "GENRE":
+				sheet.Genre = value
+			case "DATE":
+				sheet.Date = value
+			case "REPLAYGAIN_ALBUM_GAIN":
+				sheet.AlbumGain = parseGainValue(strings.Join(fields[2:], " "))
+			case "REPLAYGAIN_ALBUM_PEAK":
+				sheet.AlbumPeak = parsePeakValue(value)
+			case "REPLAYGAIN_TRACK_GAIN":
+				if track != nil {
+					track.TrackGain = parseGainValue(strings.Join(fields[2:], " "))
+				}
+			case "REPLAYGAIN_TRACK_PEAK":
+				if track != nil {
+					track.TrackPeak = parsePeakValue(value)
+				}
+			}
+		}
+	}
+
+	audioFiles := 0
+	for i := range sheet.Files {
+		tracks := sheet.Files[i].Tracks[:0]
+		for _, t := range sheet.Files[i].Tracks {
+			if t.StartMs >= 0 {
+				tracks = append(tracks, t)
+			}
+		}
+		sheet.Files[i].Tracks = tracks
+		audioFiles += len(tracks)
+	}
+	if audioFiles == 0 {
+		return nil, errInvalidCue
+	}
+	return sheet, nil
+}
+
+// cueFields 将 CUE 命令行拆分为字段，双引号中的空白不拆分。
+func cueFields(line string) []string {
+	var fields []string
+	for line != "" {
+		line = strings.TrimLeftFunc(line, unicode.IsSpace)
+		if line == "" {
+			break
+		}
+		if line[0] == '"' {
+			end := strings.IndexByte(line[1:], '"')
+			if end < 0 {
+				fields = append(fields, line[1:])
+				break
+			}
+			fields = append(fields, line[1:end+1])
+			line = line[end+2:]
+			continue
+		}
+		end := strings.IndexFunc(line, unicode.IsSpace)
+		if end < 0 {
+			fields = append(fields, line)
+			break
+		}
+		fields = append(fields, line[:end])
+		line = line[end:]
+	}
+	return fields
+}
+
+// parseCueTime 解析 mm:ss:ff 形式的时间，返回毫秒数。
+func parseCueTime(value string) (int64, bool) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return 0, false
+	}
+	var n [3]int64
+	for i, part := range parts {
+		v, err := strconv.ParseInt(part, 10, 64)
+		if err != nil || v < 0 {
+			return 0, false
+		}
+		n[i] = v
+	}
+	if n[1] >= 60 || n[2] >= CueFramesPerSecond {
+		return 0, false
+	}
+	frames := (n[0]*60+n[1])*CueFramesPerSecond + n[2]
+	return int64(math.Round(float64(frames) * 1000 / CueFramesPerSecond)), true
+}
+
+// CueFrames 将毫秒换算为 CUE 帧数。CUE 时间都是整帧，毫秒值取整带来的误差远小于半帧，换算回来是精确的。
+func CueFrames(ms int64) int64 {
+	return int64(math.Round(float64(ms) * CueFramesPerSecond / 1000))
+}
+
+// MatchFile 返回 CUE 中引用了音频文件 fileName 的 FILE 段。文件名不区分大小写；
+// 找不到同名文件时按去掉扩展名的文件名匹配（CUE 引用 .wav 而文件已转换为 .flac 的常见情况）。
+func (c *CueSheet) MatchFile(fileName string) *CueFile {
+	stem := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	var byStem *CueFile
+	for i := range c.Files {
+		name := filepath.Base(filepath.FromSlash(strings.ReplaceAll(c.Files[i].Name, "\\", "/")))
+		if strings.EqualFold(name, fileName) {
+			return &c.Files[i]
+		}
+		if byStem == nil && strings.EqualFold(strings.TrimSuffix(name, filepath.Ext(name)), stem) {
+			byStem = &c.Files[i]
+		}
+	}
+	return byStem
+}
+
+// IsCueTrack 判断歌曲是否为 CUE 文件描述的整轨文件中的一段。
+func (s *Song) IsCueTrack() bool {
+	return s.CueSheetPath != ""
+}
+
+// CueTrackSongs 根据 CUE 中的 FILE 段将整轨歌曲 image 拆分为虚拟曲目。
+// 每个曲目从 INDEX 01 开始，到下一曲目的 INDEX 01 结束（曲间的空白归入前一曲目），最后一个曲目到文件结尾。
+// CUE 中没有的信息沿用整轨文件的标签；整轨文件的增益作为专辑增益。
+func CueTrackSongs(image *Song, cuePath string, sheet *CueSheet, file *CueFile) []*Song {
+	imageMs := int64(image.Duration) * 1000
+	songs := make([]*Song, 0, len(file.Tracks))
+	for i, track := range file.Tracks {
+		endMs := int64(0)
+		if i+1 < len(file.Tracks) {
+			endMs = file.Tracks[i+1].StartMs
+			if endMs <= track.StartMs {
+				continue
+			}
+		}
+
+		song := *image
+		song.CueSheetPath = cuePath
+		song.StartMs, song.EndMs = track.StartMs, endMs
+		song.ID = LibrarySongID(image.Library, fmt.Sprintf("%s#%d", image.RelPath, track.Number))
+		song.Track = track.Number
+		song.Title = firstNonEmpty(track.Title, fmt.Sprintf("Track %02d", track.Number))
+		song.Artist = firstNonEmpty(track.Performer, sheet.Performer, image.Artist)
+		song.Artists = nil
+		song.AlbumArtist = firstNonEmpty(sheet.Performer, image.AlbumArtist)
+		song.Album = firstNonEmpty(sheet.Title, image.Album)
+		song.Composer = firstNonEmpty(track.Songwriter, sheet.Songwriter, image.Composer)
+		song.Genre = firstNonEmpty(sheet.Genre, image.Genre)
+		if len(sheet.Date) >= 4 {
+			if year, err := strconv.Atoi(sheet.Date[:4]); err == nil {
+				song.Year = year
+			}
+		}
+
+		switch {
+		case endMs > 0:
+			song.Duration = int((endMs - track.StartMs) / 1000)
+			song.DurationEstimated = false
+		case imageMs > track.StartMs:
+			song.Duration = int((imageMs - track.StartMs) / 1000)
+		default:
+			song.Duration = 0
+		}
+		song.DurationFormatted = FormatDuration(song.Duration)
+		song.Issues = slices.Clone(image.Issues)
+		song.clearIssues(IssueZeroDuration)
+		if song.Duration == 0 {
+			song.addIssue(IssueZeroDuration, "")
+		}
+		song.checkMissingTags(
+			track.Title,
+			firstNonEmpty(track.Performer, sheet.Performer, image.tagValue(IssueMissingArtist, image.Artist)),
+			firstNonEmpty(sheet.Title, image.tagValue(IssueMissingAlbum, image.Album)),
+		)
+
+		song.ReplayGain = cueReplayGain(image.ReplayGain, sheet, track)
+		// 整轨文件的歌词对应整张专辑，不适用于单个曲目
+		song.HasLyrics, song.EmbeddedLyrics, song.LyricsPath = false, false, ""
+		if image.ContentHash != "" {
+			hash := sha256.Sum256([]byte(fmt.Sprintf("%s#%d", image.ContentHash, track.Number)))
+			song.ContentHash = hex.EncodeToString(hash[:])
+		}
+		songs = append(songs, &song)
+	}
+	return songs
+}
+
+// cueReplayGain 返回虚拟曲目的音量信息：CUE 中的 REM REPLAYGAIN_* 优先，
+// 其次将整轨文件的增益视为专辑增益。
+func cueReplayGain(image *ReplayGain, sheet *CueSheet, track CueTrack) *ReplayGain {
+	rg := &ReplayGain{
+		Source:    LoudnessSourceReplayGain,
+		TrackGain: track.TrackGain,
+		TrackPeak: track.TrackPeak,
+		AlbumGain: sheet.AlbumGain,
+		AlbumPeak: sheet.AlbumPeak,
+	}
+	if rg.AlbumGain == nil && image != nil && image.Source != LoudnessSourceAnalysis {
+		rg.Source = image.Source
+		rg.AlbumGain, rg.AlbumPeak = image.AlbumGain, image.AlbumPeak
+		if rg.AlbumGain == nil {
+			rg.AlbumGain, rg.AlbumPeak = image.TrackGain, image.TrackPeak
+		}
+	}
+	if rg.TrackGain == nil && rg.AlbumGain == nil {
+		return nil
+	}
+	return rg
+}
+
+// firstNonEmpty 返回第一个非空字符串。
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
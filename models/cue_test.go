@@ -0,0 +1,129 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCueSheet = `REM GENRE "Jazz"
+REM DATE 1959
+REM REPLAYGAIN_ALBUM_GAIN -7.50 dB
+PERFORMER "Miles Davis"
+TITLE "Kind of Blue"
+FILE "Kind of Blue.wav" WAVE
+  TRACK 01 AUDIO
+    TITLE "So What"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Freddie Freeloader"
+    PERFORMER "Miles Davis Sextet"
+    REM REPLAYGAIN_TRACK_GAIN -6.20 dB
+    INDEX 00 09:20:50
+    INDEX 01 09:22:37
+  TRACK 03 AUDIO
+    INDEX 01 19:09:00
+`
+
+func TestParseCueSheet(t *testing.T) {
+	sheet, err := ParseCueSheet([]byte(testCueSheet))
+	require.NoError(t, err)
+	assert.Equal(t, "Kind of Blue", sheet.Title)
+	assert.Equal(t, "Miles Davis", sheet.Performer)
+	assert.Equal(t, "Jazz", sheet.Genre)
+	assert.Equal(t, "1959", sheet.Date)
+	assert.Equal(t, -7.5, *sheet.AlbumGain)
+
+	require.Len(t, sheet.Files, 1)
+	tracks := sheet.Files[0].Tracks
+	require.Len(t, tracks, 3)
+	assert.Equal(t, "So What", tracks[0].Title)
+	assert.Equal(t, int64(0), tracks[0].StartMs)
+	// INDEX 00 不是曲目的开始位置，37 帧约为 493 毫秒
+	assert.Equal(t, int64(562493), tracks[1].StartMs)
+	assert.Equal(t, int64(562*75+37), CueFrames(tracks[1].StartMs))
+	assert.Equal(t, "Miles Davis Sextet", tracks[1].Performer)
+	assert.Equal(t, -6.2, *tracks[1].TrackGain)
+	assert.Equal(t, 3, tracks[2].Number)
+
+	// 数据轨和缺少 INDEX 01 的曲目被忽略
+	_, err = ParseCueSheet([]byte("FILE \"a.bin\" BINARY\n  TRACK 01 MODE1/2352\n    INDEX 01 00:00:00\n  TRACK 02 AUDIO\n"))
+	assert.Error(t, err)
+	_, err = ParseCueSheet([]byte("not a cue sheet"))
+	assert.Error(t, err)
+}
+
+func TestCueSheetMatchFile(t *testing.T) {
+	sheet, err := ParseCueSheet([]byte("FILE \"CDImage.wav\" WAVE\n TRACK 01 AUDIO\n INDEX 01 00:00:00\nFILE \"disc\\\\side b.flac\" WAVE\n TRACK 02 AUDIO\n INDEX 01 00:00:00\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "CDImage.wav", sheet.MatchFile("cdimage.wav").Name)
+	// 引用的 .wav 已经转换为 .flac
+	assert.Equal(t, "CDImage.wav", sheet.MatchFile("CDImage.flac").Name)
+	assert.Equal(t, 2, sheet.MatchFile("side B.flac").Tracks[0].Number)
+	assert.Nil(t, sheet.MatchFile("other.flac"))
+}
+
+func TestCueTrackSongs(t *testing.T) {
+	sheet, err := ParseCueSheet([]byte(testCueSheet))
+	require.NoError(t, err)
+	imageGain := -9.0
+	image := &Song{
+		ID:          LibrarySongID("default", "Jazz/Kind of Blue.wav"),
+		FilePath:    "/music/Jazz/Kind of Blue.wav",
+		Library:     "default",
+		RelPath:     "Jazz/Kind of Blue.wav",
+		Title:       "Kind of Blue",
+		Artist:      "Various",
+		Composer:    "Davis",
+		Duration:    1500,
+		ContentHash: "abc",
+		HasLyrics:   true,
+		LyricsPath:  "/music/Jazz/Kind of Blue.lrc",
+		ReplayGain:  &ReplayGain{TrackGain: &imageGain, Source: LoudnessSourceReplayGain},
+		Issues:      []SongIssue{{Kind: IssueMissingTitle}, {Kind: IssueMissingAlbum}},
+	}
+
+	songs := CueTrackSongs(image, "/music/Jazz/Kind of Blue.cue", sheet, &sheet.Files[0])
+	require.Len(t, songs, 3)
+	first, second, last := songs[0], songs[1], songs[2]
+
+	assert.True(t, first.IsCueTrack())
+	assert.False(t, image.IsCueTrack())
+	assert.Equal(t, LibrarySongID("default", "Jazz/Kind of Blue.wav#1"), first.ID)
+	assert.NotEqual(t, first.ContentHash, second.ContentHash)
+	assert.Equal(t, image.FilePath, second.FilePath)
+
+	assert.Equal(t, "So What", first.Title)
+	assert.Equal(t, "Miles Davis", first.Artist)
+	assert.Equal(t, "Miles Davis Sextet", second.Artist)
+	assert.Equal(t, "Miles Davis", second.AlbumArtist)
+	assert.Equal(t, "Kind of Blue", second.Album)
+	assert.Equal(t, "Davis", second.Composer)
+	assert.Equal(t, "Jazz", second.Genre)
+	assert.Equal(t, 1959, second.Year)
+	assert.Equal(t, 2, second.Track)
+	assert.Equal(t, "Track 03", last.Title)
+
+	assert.Equal(t, int64(562493), first.EndMs)
+	assert.Equal(t, 562, first.Duration)
+	assert.Equal(t, int64(0), last.EndMs)
+	assert.Equal(t, 1500-1149, last.Duration)
+
+	// CUE 中的标题和专辑补全了整轨文件缺少的标签
+	assert.Empty(t, first.Issues)
+	assert.Equal(t, []SongIssue{{Kind: IssueMissingTitle}}, last.Issues)
+	assert.False(t, second.HasLyrics)
+	assert.Empty(t, second.LyricsPath)
+
+	// CUE 中的增益优先，没有曲目增益时只有专辑增益
+	assert.Equal(t, -6.2, *second.ReplayGain.TrackGain)
+	assert.Equal(t, -7.5, *second.ReplayGain.AlbumGain)
+	assert.Nil(t, first.ReplayGain.TrackGain)
+
+	// CUE 中没有增益时，整轨文件的增益作为专辑增益
+	sheet.AlbumGain = nil
+	songs = CueTrackSongs(image, "/music/Jazz/Kind of Blue.cue", sheet, &sheet.Files[0])
+	assert.Equal(t, -9.0, *songs[0].ReplayGain.AlbumGain)
+	assert.Nil(t, songs[0].ReplayGain.TrackGain)
+}
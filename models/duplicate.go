@@ -0,0 +1,232 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// 判断歌曲重复的依据。
+const (
+	// DuplicateReasonContentHash 表示文件内容完全相同。抽样指纹（见 Song.UpdateContentHash）相同的歌曲
+	// 还要比较完整文件的哈希，只在未抽样的位置不同的文件不会被判断为相同内容。
+	DuplicateReasonContentHash = "content_hash"
+	// DuplicateReasonMetadata 表示艺术家和标题相同、时长接近，通常是同一首歌的不同格式或版本。
+	DuplicateReasonMetadata = "metadata"
+)
+
+// DuplicateDurationTolerance 是按元数据判断重复时允许的时长差（秒）。
+const DuplicateDurationTolerance = 2
+
+// DuplicateCopy 是一组重复歌曲中的一个副本。
+type DuplicateCopy struct {
+	*Song
+	// Quality 是音质分数，分数越高越好
+	Quality int `json:"quality"`
+	// Preferred 表示管理员选定了该副本作为首选副本
+	Preferred bool `json:"preferred"`
+	// Hidden 表示该副本因为同组有首选副本而不出现在歌曲列表和搜索结果中
+	Hidden bool `json:"hidden"`
+}
+
+// DuplicateGroup 是一组可能重复的歌曲，副本按音质从高到低排列。
+type DuplicateGroup struct {
+	Reasons []string         `json:"reasons"`
+	Copies  []*DuplicateCopy `json:"copies"`
+}
+
+// SongIDs 返回组内所有副本的 ID。
+func (g *DuplicateGroup) SongIDs() []string {
+	ids := make([]string, len(g.Copies))
+	for i, c := range g.Copies {
+		ids[i] = c.ID
+	}
+	return ids
+}
+
+// lossyCodecWeights 是有损编码的效率系数：相同比特率下编码效率更高的格式得分更高。
+var lossyCodecWeights = map[string]float64{
+	CodecOpus:   1.6,
+	CodecAAC:    1.3,
+	CodecVorbis: 1.3,
+	CodecMP3:    1.0,
+}
+
+// losslessQualityBase 使任何无损格式的音质分数都高于有损格式。
+const losslessQualityBase = 100000
+
+// DuplicateQuality 返回歌曲副本的音质分数。无损格式总是优先，之间按采样率和位深比较；
+// 有损格式按比特率乘以编码效率系数比较。
+func DuplicateQuality(s *Song) int {
+	if s.Lossless {
+		return losslessQualityBase + s.SampleRate/100*max(s.BitDepth, 1)
+	}
+	weight, ok := lossyCodecWeights[s.Codec]
+	if !ok {
+		weight = 1
+	}
+	return int(float64(s.Bitrate) * weight)
+}
+
+// FileHasher 返回歌曲文件完整内容的哈希，无法读取文件时返回空字符串。
+type FileHasher func(s *Song) string
+
+// FindDuplicates 将内容完全相同，或者艺术家和标题规范化后相同且时长相差不超过 DuplicateDurationTolerance 秒的歌曲分为一组。
+// 内容先按抽样指纹比较，只有指纹相同的歌曲才通过 fileHash 计算完整文件的哈希进行确认。
+// 两种关系可以传递，每首歌曲最多出现在一个组中。结果按首个副本的艺术家和标题排序。
+func FindDuplicates(songs []*Song, fileHash FileHasher) []*DuplicateGroup {
+	parent := make([]int, len(songs))
+	reasons := make([]map[string]bool, len(songs))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	addReason := func(root int, reason string) {
+		if reasons[root] == nil {
+			reasons[root] = make(map[string]bool)
+		}
+		reasons[root][reason] = true
+	}
+	union := func(a, b int, reason string) {
+		ra, rb := find(a), find(b)
+		if ra != rb {
+			parent[rb] = ra
+			for r := range reasons[rb] {
+				addReason(ra, r)
+			}
+		}
+		addReason(ra, reason)
+	}
+
+	byHash := make(map[string][]int)
+	byKey := make(map[string][]int)
+	for i, song := range songs {
+		if song.ContentHash != "" {
+			byHash[song.ContentHash] = append(byHash[song.ContentHash], i)
+		}
+		if key := duplicateKey(song); key != "" && song.Duration > 0 {
+			byKey[key] = append(byKey[key], i)
+		}
+	}
+	for _, indexes := range byHash {
+		if len(indexes) < 2 {
+			continue
+		}
+		// 抽样指纹相同的歌曲才计算完整文件的哈希
+		confirmed := make(map[string]int)
+		for _, i := range indexes {
+			key := contentKey(songs[i], fileHash)
+			if key == "" {
+				continue
+			}
+			if j, ok := confirmed[key]; ok {
+				union(j, i, DuplicateReasonContentHash)
+			} else {
+				confirmed[key] = i
+			}
+		}
+	}
+	for _, indexes := range byKey {
+		sort.Slice(indexes, func(a, b int) bool { return songs[indexes[a]].Duration < songs[indexes[b]].Duration })
+		for k := 1; k < len(indexes); k++ {
+			if songs[indexes[k]].Duration-songs[indexes[k-1]].Duration <= DuplicateDurationTolerance {
+				union(indexes[k-1], indexes[k], DuplicateReasonMetadata)
+			}
+		}
+	}
+
+	members := make(map[int][]int)
+	for i := range songs {
+		root := find(i)
+		members[root] = append(members[root], i)
+	}
+	var groups []*DuplicateGroup
+	for root, indexes := range members {
+		if len(indexes) < 2 {
+			continue
+		}
+		group := &DuplicateGroup{}
+		for r := range reasons[root] {
+			group.Reasons = append(group.Reasons, r)
+		}
+		sort.Strings(group.Reasons)
+		for _, i := range indexes {
+			group.Copies = append(group.Copies, &DuplicateCopy{Song: songs[i], Quality: DuplicateQuality(songs[i])})
+		}
+		sort.SliceStable(group.Copies, func(a, b int) bool {
+			if group.Copies[a].Quality != group.Copies[b].Quality {
+				return group.Copies[a].Quality > group.Copies[b].Quality
+			}
+			return group.Copies[a].FilePath < group.Copies[b].FilePath
+		})
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(a, b int) bool {
+		sa, sb := groups[a].Copies[0].Song, groups[b].Copies[0].Song
+		if firstArtist(sa) != firstArtist(sb) {
+			return firstArtist(sa) < firstArtist(sb)
+		}
+		if sa.Title != sb.Title {
+			return sa.Title < sb.Title
+		}
+		return sa.FilePath < sb.FilePath
+	})
+	return groups
+}
+
+// contentKey 返回按内容判断重复时使用的键：完整文件的哈希，CUE 曲目还包括曲目在整轨文件中的位置。
+// 无法计算哈希时返回空字符串。
+func contentKey(s *Song, fileHash FileHasher) string {
+	hash := fileHash(s)
+	if hash == "" || !s.IsCueTrack() {
+		return hash
+	}
+	return fmt.Sprintf("%s#%d-%d", hash, s.StartMs, s.EndMs)
+}
+
+// duplicateKey 返回按元数据判断重复时使用的键：规范化后的第一位艺术家和标题，缺少任一项时返回空字符串。
+func duplicateKey(s *Song) string {
+	artist := normalizeDuplicateText(firstArtist(s))
+	artist = strings.TrimPrefix(artist, "the ")
+	title := normalizeDuplicateText(s.Title)
+	if artist == "" || title == "" {
+		return ""
+	}
+	return artist + "\x00" + title
+}
+
+// firstArtist 返回歌曲的第一位艺术家，使 "A" 和 "A feat. B" 的同名曲目可以配对。
+func firstArtist(s *Song) string {
+	if names := s.ArtistNames(); len(names) > 0 {
+		return names[0]
+	}
+	return ""
+}
+
+// normalizeDuplicateText 转换为小写并将全角字符转为半角，标点和连续的空白视为单个空格。
+func normalizeDuplicateText(text string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(text) {
+		if r >= 0xff01 && r <= 0xff5e {
+			r -= 0xfee0 // 全角字符转换为半角
+		}
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteRune(unicode.ToLower(r))
+			space = false
+		} else {
+			space = true
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	songs := []*Song{
+		{ID: "mp3", Artist: "The Beatles", Title: "Let It Be", Duration: 243, FilePath: "/a/let it be.mp3", Codec: CodecMP3, Bitrate: 320},
+		{ID: "flac", Artist: "Beatles feat. Someone", Artists: []string{"Beatles", "Someone"}, Title: "Let it be!", Duration: 244, FilePath: "/b/let it be.flac", Codec: CodecFLAC, Lossless: true, SampleRate: 44100, BitDepth: 16},
+		{ID: "live", Artist: "The Beatles", Title: "Let It Be", Duration: 290, FilePath: "/c/live.mp3", Codec: CodecMP3, Bitrate: 192},
+		{ID: "copy", Artist: "Unknown", Title: "Track 1", Duration: 100, FilePath: "/c/copy.opus", ContentHash: "abc", Codec: CodecOpus, Bitrate: 128},
+		{ID: "copy2", Artist: "Other", Title: "Track 2", Duration: 100, FilePath: "/d/copy.opus", ContentHash: "abc", Codec: CodecOpus, Bitrate: 128},
+		{ID: "untitled", Title: "Let It Be", Duration: 243, FilePath: "/e/untitled.mp3"},
+		{ID: "sampled", Artist: "Another", Title: "Track 3", Duration: 100, FilePath: "/e/sampled.opus", ContentHash: "abc", Codec: CodecOpus, Bitrate: 128},
+		{ID: "unreadable", Artist: "Missing", Title: "Track 4", Duration: 100, FilePath: "/e/missing.opus", ContentHash: "abc"},
+	}
+	// 抽样指纹相同但完整内容不同的文件，以及无法读取的文件不算重复
+	fullHashes := map[string]string{"/c/copy.opus": "full", "/d/copy.opus": "full", "/e/sampled.opus": "other"}
+	var hashed []string
+	fileHash := func(s *Song) string {
+		hashed = append(hashed, s.ID)
+		return fullHashes[s.FilePath]
+	}
+
+	groups := FindDuplicates(songs, fileHash)
+	require.Len(t, groups, 2)
+	assert.ElementsMatch(t, []string{"copy", "copy2", "sampled", "unreadable"}, hashed, "只对抽样指纹相同的歌曲计算完整哈希")
+
+	assert.Equal(t, []string{"flac", "mp3"}, groups[0].SongIDs(), "无损副本排在前面，现场版时长不同不算重复")
+	assert.Equal(t, []string{DuplicateReasonMetadata}, groups[0].Reasons)
+
+	assert.Equal(t, []string{"copy", "copy2"}, groups[1].SongIDs(), "音质相同时按路径排序")
+	assert.Equal(t, []string{DuplicateReasonContentHash}, groups[1].Reasons)
+}
+
+func TestFindDuplicates_CueTracks(t *testing.T) {
+	track := func(id, path string, start, end int64) *Song {
+		return &Song{ID: id, Title: id, Artist: "Artist " + id, Duration: 10, FilePath: path, CueSheetPath: path + ".cue", StartMs: start, EndMs: end, ContentHash: "image#" + id[len(id)-1:]}
+	}
+	songs := []*Song{
+		track("a1", "/a/album.flac", 0, 1000),
+		track("a2", "/a/album.flac", 1000, 0),
+		track("b1", "/b/album.flac", 0, 1000),
+		track("b2", "/b/album.flac", 1000, 0),
+	}
+	// 两个整轨文件内容相同，对应位置的曲目分别成组
+	groups := FindDuplicates(songs, func(s *Song) string { return "image" })
+	require.Len(t, groups, 2)
+	assert.Equal(t, []string{"a1", "b1"}, groups[0].SongIDs())
+	assert.Equal(t, []string{"a2", "b2"}, groups[1].SongIDs())
+}
+
+func TestDuplicateQuality(t *testing.T) {
+	hires := &Song{Lossless: true, SampleRate: 96000, BitDepth: 24}
+	cd := &Song{Lossless: true, SampleRate: 44100, BitDepth: 16}
+	opus := &Song{Codec: CodecOpus, Bitrate: 192}
+	mp3 := &Song{Codec: CodecMP3, Bitrate: 256}
+
+	assert.Greater(t, DuplicateQuality(hires), DuplicateQuality(cd))
+	assert.Greater(t, DuplicateQuality(cd), DuplicateQuality(opus))
+	assert.Greater(t, DuplicateQuality(opus), DuplicateQuality(mp3), "相同听感下 Opus 需要的比特率更低")
+}
+
+func TestNormalizeDuplicateText(t *testing.T) {
+	assert.Equal(t, "let it be", normalizeDuplicateText("  Let It—Be! "))
+	assert.Equal(t, "abc 123", normalizeDuplicateText("ＡＢＣ　１２３"))
+	assert.Equal(t, "晴天", normalizeDuplicateText("《晴天》"))
+}
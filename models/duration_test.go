@@ -0,0 +1,237 @@
+package models
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeAudioFile 将 data 写入临时目录中的 name 文件并返回解析过音频属性的歌曲。
+func writeAudioFile(t *testing.T, name string, data []byte) *Song {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, data, 0644))
+	song := NewSong(path, int64(len(data)))
+	song.parseAudioProperties()
+	return song
+}
+
+func flacStream(sampleRate uint32, bitDepth int, totalSamples uint64) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("fLaC")
+	buf.Write([]byte{0x80, 0, 0, 34}) // 最后一个元数据块，类型 STREAMINFO
+	info := make([]byte, 34)
+	packed := uint64(sampleRate)<<44 | 1<<41 | uint64(bitDepth-1)<<36 | totalSamples
+	binary.BigEndian.PutUint64(info[10:18], packed)
+	buf.Write(info)
+	buf.Write(make([]byte, 256))
+	return buf.Bytes()
+}
+
+func oggPage(serial uint32, granule uint64, packet []byte) []byte {
+	header := make([]byte, 27)
+	copy(header, "OggS")
+	binary.LittleEndian.PutUint64(header[6:14], granule)
+	binary.LittleEndian.PutUint32(header[14:18], serial)
+	header[26] = 1
+	page := append(header, byte(len(packet)))
+	return append(page, packet...)
+}
+
+func buildMP4Atom(name string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	atom := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint32(atom[:4], uint32(8+len(body)))
+	copy(atom[4:], name)
+	return append(atom, body...)
+}
+
+func buildMP4Header(timescale, duration uint32) []byte {
+	header := make([]byte, 100)
+	binary.BigEndian.PutUint32(header[12:16], timescale)
+	binary.BigEndian.PutUint32(header[16:20], duration)
+	return header
+}
+
+func buildMP4SampleEntry(codec string, channels, sampleSize uint16, sampleRate uint32) []byte {
+	stsd := make([]byte, 8+36)
+	binary.BigEndian.PutUint32(stsd[4:8], 1)
+	entry := stsd[8:]
+	binary.BigEndian.PutUint32(entry[0:4], 36)
+	copy(entry[4:8], codec)
+	binary.BigEndian.PutUint16(entry[24:26], channels)
+	binary.BigEndian.PutUint16(entry[26:28], sampleSize)
+	binary.BigEndian.PutUint32(entry[32:36], sampleRate<<16)
+	return stsd
+}
+
+func buildMP4File(mvhdDuration uint32, stsd []byte, timescale, mdhdDuration uint32) []byte {
+	trak := buildMP4Atom("trak", buildMP4Atom("mdia",
+		buildMP4Atom("mdhd", buildMP4Header(timescale, mdhdDuration)),
+		buildMP4Atom("minf", buildMP4Atom("stbl", buildMP4Atom("stsd", stsd))),
+	))
+	moov := buildMP4Atom("moov", buildMP4Atom("mvhd", buildMP4Header(1000, mvhdDuration)), trak)
+	return append(buildMP4Atom("ftyp", []byte("M4A \x00\x00\x00\x00")), moov...)
+}
+
+func TestParseDuration_FLAC(t *testing.T) {
+	song := writeAudioFile(t, "hires.flac", flacStream(96000, 24, 96000*185))
+	assert.Equal(t, 185, song.Duration)
+	assert.False(t, song.DurationEstimated)
+	assert.Equal(t, CodecFLAC, song.Codec)
+	assert.True(t, song.Lossless)
+	assert.Equal(t, 96000, song.SampleRate)
+	assert.Equal(t, 24, song.BitDepth)
+	assert.Equal(t, 2, song.Channels)
+
+	// fLaC 标记之前带有 ID3v2 标签
+	id3 := append([]byte{'I', 'D', '3', 3, 0, 0, 0, 0, 0, 20}, make([]byte, 20)...)
+	song = writeAudioFile(t, "tagged.flac", append(id3, flacStream(44100, 16, 44100*61)...))
+	assert.Equal(t, 61, song.Duration)
+	assert.False(t, song.DurationEstimated)
+	assert.Equal(t, 16, song.BitDepth)
+}
+
+func TestParseDuration_Ogg(t *testing.T) {
+	vorbis := make([]byte, 30)
+	copy(vorbis, "\x01vorbis")
+	vorbis[11] = 2
+	binary.LittleEndian.PutUint32(vorbis[12:16], 44100)
+	binary.LittleEndian.PutUint32(vorbis[20:24], 192000)
+	data := append(oggPage(7, 0, vorbis), make([]byte, 1024)...)
+	data = append(data, oggPage(7, 44100*200, []byte{0})...)
+	// 其他逻辑流的页面不影响结果
+	data = append(data, oggPage(9, 1<<40, []byte{0})...)
+	song := writeAudioFile(t, "song.ogg", data)
+	assert.Equal(t, 200, song.Duration)
+	assert.False(t, song.DurationEstimated)
+	assert.Equal(t, CodecVorbis, song.Codec)
+	assert.False(t, song.Lossless)
+	assert.Equal(t, BitrateModeVBR, song.BitrateMode)
+	assert.Equal(t, 44100, song.SampleRate)
+
+	opus := make([]byte, 19)
+	copy(opus, "OpusHead")
+	opus[8], opus[9] = 1, 2
+	binary.LittleEndian.PutUint16(opus[10:12], 312)
+	binary.LittleEndian.PutUint32(opus[12:16], 44100)
+	data = append(oggPage(3, 0, opus), oggPage(3, 48000*60+312, []byte{0})...)
+	song = writeAudioFile(t, "song.opus", data)
+	assert.Equal(t, 60, song.Duration)
+	assert.False(t, song.DurationEstimated)
+	assert.Equal(t, CodecOpus, song.Codec)
+	assert.Equal(t, 2, song.Channels)
+	assert.Equal(t, 44100, song.SampleRate)
+}
+
+func TestParseDuration_MP4(t *testing.T) {
+	data := buildMP4File(245500, buildMP4SampleEntry("mp4a", 2, 16, 44100), 44100, 44100*245)
+	song := writeAudioFile(t, "song.m4a", data)
+	assert.Equal(t, 245, song.Duration)
+	assert.False(t, song.DurationEstimated)
+	assert.Equal(t, CodecAAC, song.Codec)
+	assert.False(t, song.Lossless)
+	assert.Equal(t, 44100, song.SampleRate)
+	assert.Zero(t, song.BitDepth)
+
+	// mvhd 中没有时长时使用轨道的 mdhd，采样率超出 16 位时以轨道时间刻度为准
+	data = buildMP4File(0, buildMP4SampleEntry("alac", 2, 24, 0), 96000, 96000*90)
+	song = writeAudioFile(t, "hires.m4a", data)
+	assert.Equal(t, 90, song.Duration)
+	assert.False(t, song.DurationEstimated)
+	assert.Equal(t, CodecALAC, song.Codec)
+	assert.True(t, song.Lossless)
+	assert.Equal(t, 96000, song.SampleRate)
+	assert.Equal(t, 24, song.BitDepth)
+}
+
+func TestParseDuration_WAV(t *testing.T) {
+	const byteRate = 96000 * 3 * 2 // 24 位 96kHz 立体声
+	var buf bytes.Buffer
+	buf.WriteString("RIFF\x00\x00\x00\x00WAVE")
+	buf.WriteString("fmt ")
+	fmtChunk := make([]byte, 4+16)
+	binary.LittleEndian.PutUint32(fmtChunk[:4], 16)
+	binary.LittleEndian.PutUint16(fmtChunk[4:6], 1)
+	binary.LittleEndian.PutUint16(fmtChunk[6:8], 2)
+	binary.LittleEndian.PutUint32(fmtChunk[8:12], 96000)
+	binary.LittleEndian.PutUint32(fmtChunk[12:16], byteRate)
+	binary.LittleEndian.PutUint16(fmtChunk[18:20], 24)
+	buf.Write(fmtChunk)
+	buf.WriteString("data")
+	size := make([]byte, 4)
+	binary.LittleEndian.PutUint32(size, byteRate*3)
+	buf.Write(size)
+	buf.Write(make([]byte, byteRate*3))
+
+	song := writeAudioFile(t, "song.wav", buf.Bytes())
+	assert.Equal(t, 3, song.Duration)
+	assert.False(t, song.DurationEstimated)
+	assert.Equal(t, CodecPCM, song.Codec)
+	assert.True(t, song.Lossless)
+	assert.Equal(t, BitrateModeCBR, song.BitrateMode)
+	assert.Equal(t, 4608, song.Bitrate)
+	assert.Equal(t, 24, song.BitDepth)
+}
+
+func TestParseDuration_AIFF(t *testing.T) {
+	comm := make([]byte, 18)
+	binary.BigEndian.PutUint16(comm[0:2], 2)
+	binary.BigEndian.PutUint32(comm[2:6], 44100*30)
+	binary.BigEndian.PutUint16(comm[6:8], 16)
+	// 44100 的 80 位扩展精度表示
+	copy(comm[8:], []byte{0x40, 0x0e, 0xac, 0x44, 0, 0, 0, 0, 0, 0})
+
+	var buf bytes.Buffer
+	buf.WriteString("FORM\x00\x00\x00\x00AIFF")
+	buf.WriteString("COMM\x00\x00\x00\x12")
+	buf.Write(comm)
+
+	song := writeAudioFile(t, "song.aiff", buf.Bytes())
+	assert.Equal(t, 30, song.Duration)
+	assert.False(t, song.DurationEstimated)
+	assert.Equal(t, CodecPCM, song.Codec)
+	assert.Equal(t, 44100, song.SampleRate)
+	assert.Equal(t, 1411, song.Bitrate)
+}
+
+func TestParseDuration_FallsBackToEstimate(t *testing.T) {
+	song := writeAudioFile(t, "broken.flac", bytes.Repeat([]byte{0x55}, 250000))
+	assert.True(t, song.DurationEstimated)
+	assert.Equal(t, 2, song.Duration)
+	assert.Empty(t, song.Codec)
+	assert.False(t, song.Lossless)
+}
+
+// mp3Frames 生成 count 个 MPEG-1 Layer III、44.1kHz 立体声的静音帧，各帧依次使用 bitrateIndexes 中的比特率。
+func mp3Frames(count int, bitrateIndexes ...byte) []byte {
+	var buf bytes.Buffer
+	bitrates := map[byte]int{9: 128, 14: 320}
+	for i := 0; i < count; i++ {
+		index := bitrateIndexes[i%len(bitrateIndexes)]
+		frame := make([]byte, 144*bitrates[index]*1000/44100)
+		copy(frame, []byte{0xff, 0xfb, index << 4, 0x00})
+		buf.Write(frame)
+	}
+	return buf.Bytes()
+}
+
+func TestParseDuration_MP3(t *testing.T) {
+	song := writeAudioFile(t, "cbr.mp3", mp3Frames(500, 9))
+	assert.False(t, song.DurationEstimated)
+	assert.Equal(t, 13, song.Duration)
+	assert.Equal(t, CodecMP3, song.Codec)
+	assert.Equal(t, BitrateModeCBR, song.BitrateMode)
+	assert.Equal(t, 128, song.Bitrate)
+	assert.Equal(t, 44100, song.SampleRate)
+	assert.Equal(t, 2, song.Channels)
+
+	song = writeAudioFile(t, "vbr.mp3", mp3Frames(500, 9, 14))
+	assert.Equal(t, BitrateModeVBR, song.BitrateMode)
+	assert.InDelta(t, 224, song.Bitrate, 1)
+}
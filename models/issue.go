@@ -0,0 +1,116 @@
+package models
+
+import (
+	"errors"
+	"io/fs"
+	"slices"
+)
+
+// 歌曲库健康检查发现的问题类型。
+const (
+	// IssueTagError 表示标签存在但无法解析。
+	IssueTagError = "tag_error"
+	// IssueZeroDuration 表示无法得到歌曲的时长。
+	IssueZeroDuration = "zero_duration"
+	// IssueMissingTitle 表示没有标题标签，标题取自文件名。
+	IssueMissingTitle = "missing_title"
+	// IssueMissingArtist 表示没有艺术家标签。
+	IssueMissingArtist = "missing_artist"
+	// IssueMissingAlbum 表示没有专辑标签。
+	IssueMissingAlbum = "missing_album"
+	// IssueUnsupportedCodec 表示无法识别或解析音频流。
+	IssueUnsupportedCodec = "unsupported_codec"
+	// IssuePermissionDenied 表示没有读取文件的权限。
+	IssuePermissionDenied = "permission_denied"
+	// IssueUnreadable 表示由于权限以外的原因无法读取文件，或者播放列表文件无法解析。
+	IssueUnreadable = "unreadable"
+	// IssueUnresolvedEntry 表示播放列表文件中的条目无法对应到歌曲库中的歌曲。
+	IssueUnresolvedEntry = "unresolved_playlist_entry"
+)
+
+// IssueKinds 是所有的问题类型，按严重程度从高到低排列。
+var IssueKinds = []string{
+	IssuePermissionDenied,
+	IssueUnreadable,
+	IssueTagError,
+	IssueUnsupportedCodec,
+	IssueZeroDuration,
+	IssueMissingTitle,
+	IssueMissingArtist,
+	IssueMissingAlbum,
+	IssueUnresolvedEntry,
+}
+
+// SongIssue 是解析歌曲文件时发现的一个问题。
+type SongIssue struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message,omitempty"`
+}
+
+// LibraryIssue 是歌曲库中某个文件的一个问题。
+type LibraryIssue struct {
+	Kind     string `json:"kind"`
+	Message  string `json:"message,omitempty"`
+	Library  string `json:"library"`
+	FilePath string `json:"file_path"`
+	RelPath  string `json:"rel_path,omitempty"`
+	// SongID 和 Title 在文件无法访问、没有对应的歌曲时为空
+	SongID string `json:"song_id,omitempty"`
+	Title  string `json:"title,omitempty"`
+}
+
+// FileIssue 返回访问文件出错时对应的问题：权限不足或其他原因无法读取。
+func FileIssue(err error) SongIssue {
+	if errors.Is(err, fs.ErrPermission) {
+		return SongIssue{Kind: IssuePermissionDenied, Message: err.Error()}
+	}
+	return SongIssue{Kind: IssueUnreadable, Message: err.Error()}
+}
+
+// LibraryIssues 返回歌曲记录的所有问题。
+func (s *Song) LibraryIssues() []*LibraryIssue {
+	issues := make([]*LibraryIssue, len(s.Issues))
+	for i, issue := range s.Issues {
+		issues[i] = &LibraryIssue{
+			Kind:     issue.Kind,
+			Message:  issue.Message,
+			Library:  s.Library,
+			FilePath: s.FilePath,
+			RelPath:  s.RelPath,
+			SongID:   s.ID,
+			Title:    s.Title,
+		}
+	}
+	return issues
+}
+
+// addIssue 记录一个问题，同一类型只记录一次。
+func (s *Song) addIssue(kind, message string) {
+	for _, issue := range s.Issues {
+		if issue.Kind == kind {
+			return
+		}
+	}
+	s.Issues = append(s.Issues, SongIssue{Kind: kind, Message: message})
+}
+
+// clearIssues 删除指定类型的问题。
+func (s *Song) clearIssues(kinds ...string) {
+	var kept []SongIssue
+	for _, issue := range s.Issues {
+		if !slices.Contains(kinds, issue.Kind) {
+			kept = append(kept, issue)
+		}
+	}
+	s.Issues = kept
+}
+
+// tagValue 返回来自标签的值：记录了 missing 类型的问题时，value 只是默认值，返回空字符串。
+func (s *Song) tagValue(missing, value string) string {
+	for _, issue := range s.Issues {
+		if issue.Kind == missing {
+			return ""
+		}
+	}
+	return value
+}
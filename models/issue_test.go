@@ -0,0 +1,55 @@
+package models
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func issueKinds(s *Song) []string {
+	var kinds []string
+	for _, issue := range s.Issues {
+		kinds = append(kinds, issue.Kind)
+	}
+	return kinds
+}
+
+func TestUpdateMetadata_Issues(t *testing.T) {
+	dir := t.TempDir()
+
+	// 没有标签、也无法识别音频流的文件
+	path := filepath.Join(dir, "noise.mp3")
+	require.NoError(t, os.WriteFile(path, make([]byte, 4096), 0644))
+	song := NewSong(path, 4096)
+	require.NoError(t, song.UpdateMetadata())
+	assert.ElementsMatch(t, []string{IssueMissingTitle, IssueMissingArtist, IssueMissingAlbum, IssueUnsupportedCodec, IssueZeroDuration}, issueKinds(song))
+
+	// 太短而无法读取标签的文件
+	short := filepath.Join(dir, "short.mp3")
+	require.NoError(t, os.WriteFile(short, []byte("ID3"), 0644))
+	song = NewSong(short, 3)
+	assert.Error(t, song.UpdateMetadata())
+	assert.Contains(t, issueKinds(song), IssueTagError)
+
+	// 重新解析时之前的问题被清除
+	song.FilePath = filepath.Join(dir, "missing.mp3")
+	assert.Error(t, song.UpdateMetadata())
+	assert.Equal(t, []string{IssueUnreadable}, issueKinds(song))
+
+	issues := song.LibraryIssues()
+	require.Len(t, issues, 1)
+	assert.Equal(t, song.ID, issues[0].SongID)
+	assert.Equal(t, song.FilePath, issues[0].FilePath)
+}
+
+func TestFileIssue(t *testing.T) {
+	denied := &fs.PathError{Op: "open", Path: "/music/a.mp3", Err: fs.ErrPermission}
+	assert.Equal(t, IssuePermissionDenied, FileIssue(denied).Kind)
+	assert.Equal(t, IssuePermissionDenied, FileIssue(fmt.Errorf("打开文件失败: %w", denied)).Kind)
+	assert.Equal(t, IssueUnreadable, FileIssue(fs.ErrNotExist).Kind)
+}
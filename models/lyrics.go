@@ -0,0 +1,282 @@
+package models
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/dhowden/tag"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+const (
+	// LyricsSourceLRC 表示歌词来自与音频文件同名的 .lrc 文件
+	LyricsSourceLRC = "lrc"
+	// LyricsSourceEmbedded 表示歌词来自音频文件的标签（USLT、SYLT、Vorbis LYRICS 等）
+	LyricsSourceEmbedded = "embedded"
+)
+
+// LyricsLine 是一行带时间的歌词。
+type LyricsLine struct {
+	// TimeMs 是该行开始的时间（毫秒），已应用 LRC 的 offset。
+	TimeMs int64 `json:"time_ms"`
+	// Text 是该行的歌词文本，可以为空（表示间奏）。
+	Text string `json:"text"`
+}
+
+// Lyrics 是一首歌曲的歌词。
+type Lyrics struct {
+	// Source 是歌词的来源（lrc 或 embedded）。
+	Source string `json:"source"`
+	// Synced 标识歌词是否带有时间轴。
+	Synced bool `json:"synced"`
+	// Language 是歌词的语言代码（如 eng、chi），未知时为空。
+	Language string `json:"language,omitempty"`
+	// OffsetMs 是 LRC 文件中声明的时间偏移（毫秒），已应用到 Lines 中。
+	OffsetMs int64 `json:"offset_ms,omitempty"`
+	// Lines 是按时间排序的歌词行，仅在 Synced 为 true 时存在。
+	Lines []LyricsLine `json:"lines,omitempty"`
+	// Text 是纯文本形式的歌词，带时间轴的歌词为各行文本的拼接。
+	Text string `json:"text"`
+}
+
+var (
+	// lrcTimeRegex 匹配行首的 [mm:ss]、[mm:ss.xx] 或 [mm:ss:xx] 时间标签。
+	lrcTimeRegex = regexp.MustCompile(`^\[(\d+):(\d{1,2})(?:[.:](\d{1,3}))?\]`)
+	// lrcTagRegex 匹配 [ar:艺术家]、[offset:+500] 等元数据标签。
+	lrcTagRegex = regexp.MustCompile(`^\[([A-Za-z#]+):(.*)\]$`)
+	// lrcWordTimeRegex 匹配增强型 LRC 中的逐字时间标签 <mm:ss.xx>。
+	lrcWordTimeRegex = regexp.MustCompile(`<\d+:\d{1,2}(?:[.:]\d{1,3})?>`)
+)
+
+// ParseLRC 解析 LRC 格式的歌词。没有任何时间标签的文本按纯文本歌词处理。
+// [offset:N] 为正时歌词提前 N 毫秒出现，为负时推迟。
+func ParseLRC(text string) *Lyrics {
+	lyrics := &Lyrics{}
+	var plain []string
+
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	for _, raw := range strings.Split(strings.ReplaceAll(text, "\r", "\n"), "\n") {
+		line := strings.TrimSpace(raw)
+
+		var times []int64
+		for {
+			m := lrcTimeRegex.FindStringSubmatch(line)
+			if m == nil {
+				break
+			}
+			times = append(times, lrcTimestamp(m[1], m[2], m[3]))
+			line = line[len(m[0]):]
+		}
+
+		if len(times) == 0 {
+			if m := lrcTagRegex.FindStringSubmatch(line); m != nil {
+				if strings.EqualFold(m[1], "offset") {
+					if offset, err := strconv.ParseInt(strings.TrimSpace(m[2]), 10, 64); err == nil {
+						lyrics.OffsetMs = offset
+					}
+				}
+				continue
+			}
+			plain = append(plain, line)
+			continue
+		}
+
+		line = strings.TrimSpace(lrcWordTimeRegex.ReplaceAllString(line, ""))
+		for _, t := range times {
+			lyrics.Lines = append(lyrics.Lines, LyricsLine{TimeMs: t, Text: line})
+		}
+	}
+
+	if len(lyrics.Lines) == 0 {
+		lyrics.OffsetMs = 0
+		lyrics.Text = strings.Trim(strings.Join(plain, "\n"), "\n")
+		return lyrics
+	}
+
+	lyrics.Synced = true
+	for i := range lyrics.Lines {
+		lyrics.Lines[i].TimeMs = max(0, lyrics.Lines[i].TimeMs-lyrics.OffsetMs)
+	}
+	lyrics.finishSynced()
+	return lyrics
+}
+
+// lrcTimestamp 将时间标签的分、秒和小数部分转换为毫秒。小数部分按位数解释为十分之一秒、百分之一秒或毫秒。
+func lrcTimestamp(minutes, seconds, fraction string) int64 {
+	m, _ := strconv.ParseInt(minutes, 10, 64)
+	s, _ := strconv.ParseInt(seconds, 10, 64)
+	ms := (m*60 + s) * 1000
+	if fraction != "" {
+		f, _ := strconv.ParseInt(fraction, 10, 64)
+		for i := len(fraction); i < 3; i++ {
+			f *= 10
+		}
+		ms += f
+	}
+	return ms
+}
+
+// finishSynced 按时间排序歌词行并生成纯文本。同一时间的多行保持原有顺序（如原文和译文）。
+func (l *Lyrics) finishSynced() {
+	sort.SliceStable(l.Lines, func(i, j int) bool {
+		return l.Lines[i].TimeMs < l.Lines[j].TimeMs
+	})
+	texts := make([]string, 0, len(l.Lines))
+	for _, line := range l.Lines {
+		texts = append(texts, line.Text)
+	}
+	l.Text = strings.Trim(strings.Join(texts, "\n"), "\n")
+}
+
+// DecodeLyricsFile 将歌词文件的内容解码为字符串。支持带 BOM 的 UTF-8 和 UTF-16；
+// 不是合法 UTF-8 的内容按 GB18030 解码，兼容常见的 GBK 编码歌词文件。
+func DecodeLyricsFile(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xef, 0xbb, 0xbf}):
+		return string(data[3:])
+	case bytes.HasPrefix(data, []byte{0xff, 0xfe}), bytes.HasPrefix(data, []byte{0xfe, 0xff}):
+		return decodeUTF16(data)
+	case utf8.Valid(data):
+		return string(data)
+	}
+	decoded, err := simplifiedchinese.GB18030.NewDecoder().Bytes(data)
+	if err != nil {
+		return strings.ToValidUTF8(string(data), "�")
+	}
+	return string(decoded)
+}
+
+// decodeUTF16 解码 UTF-16 文本，开头的 BOM 决定字节序，没有 BOM 时按大端序处理。
+func decodeUTF16(data []byte) string {
+	var order binary.ByteOrder = binary.BigEndian
+	if bytes.HasPrefix(data, []byte{0xff, 0xfe}) {
+		order = binary.LittleEndian
+		data = data[2:]
+	} else if bytes.HasPrefix(data, []byte{0xfe, 0xff}) {
+		data = data[2:]
+	}
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[i*2:])
+	}
+	return string(utf16.Decode(units))
+}
+
+// embeddedLyricsText 返回标签中的非同步歌词文本（ID3 USLT、MP4 ©lyr、Vorbis LYRICS/UNSYNCEDLYRICS）。
+func embeddedLyricsText(metadata tag.Metadata) string {
+	if text := metadata.Lyrics(); strings.TrimSpace(text) != "" {
+		return text
+	}
+	if metadata.Format() == tag.VORBIS {
+		if text, ok := metadata.Raw()["unsyncedlyrics"].(string); ok {
+			return text
+		}
+	}
+	return ""
+}
+
+// embeddedSYLT 返回标签中的 ID3 同步歌词帧（SYLT，ID3v2.2 中为 SLT）的原始内容。
+func embeddedSYLT(metadata tag.Metadata) []byte {
+	raw := metadata.Raw()
+	for _, name := range []string{"SYLT", "SLT"} {
+		if data, ok := raw[name].([]byte); ok && len(data) > 0 {
+			return data
+		}
+	}
+	return nil
+}
+
+// HasEmbeddedLyrics 判断标签中是否嵌入了歌词。
+func HasEmbeddedLyrics(metadata tag.Metadata) bool {
+	return embeddedLyricsText(metadata) != "" || embeddedSYLT(metadata) != nil
+}
+
+// EmbeddedLyrics 从标签中读取歌词。同步歌词（SYLT）优先；非同步歌词如果本身是 LRC 格式，也会解析出时间轴。
+// 没有歌词时返回 nil。
+func EmbeddedLyrics(metadata tag.Metadata) *Lyrics {
+	if data := embeddedSYLT(metadata); data != nil {
+		if lyrics, err := parseSYLT(data); err == nil && len(lyrics.Lines) > 0 {
+			return lyrics
+		}
+	}
+	text := embeddedLyricsText(metadata)
+	if text == "" {
+		return nil
+	}
+	lyrics := ParseLRC(text)
+	lyrics.Source = LyricsSourceEmbedded
+	return lyrics
+}
+
+// errUnsupportedSYLT 表示 SYLT 帧使用了无法换算为毫秒的时间戳格式。
+var errUnsupportedSYLT = errors.New("不支持以 MPEG 帧为单位的同步歌词时间戳")
+
+// parseSYLT 解析 ID3v2 SYLT 帧：
+// 文本编码(1) 语言(3) 时间戳格式(1) 内容类型(1) 内容描述(以结束符结尾)，之后重复 [文本 结束符 时间戳(4)]。
+func parseSYLT(data []byte) (*Lyrics, error) {
+	if len(data) < 6 {
+		return nil, fmt.Errorf("SYLT 帧过短")
+	}
+	encoding, language, format := data[0], strings.TrimRight(string(data[1:4]), "\x00 "), data[4]
+	if format != 2 {
+		return nil, errUnsupportedSYLT
+	}
+
+	_, rest := splitID3Text(data[6:], encoding)
+	lyrics := &Lyrics{Source: LyricsSourceEmbedded, Synced: true, Language: language}
+	for len(rest) > 0 {
+		var text []byte
+		text, rest = splitID3Text(rest, encoding)
+		if len(rest) < 4 {
+			break
+		}
+		lyrics.Lines = append(lyrics.Lines, LyricsLine{
+			TimeMs: int64(binary.BigEndian.Uint32(rest[:4])),
+			Text:   strings.TrimSpace(decodeID3Text(text, encoding)),
+		})
+		rest = rest[4:]
+	}
+	lyrics.finishSynced()
+	return lyrics, nil
+}
+
+// splitID3Text 按照文本编码对应的结束符切分出一个字符串，返回字符串内容和剩余数据。
+// UTF-16 编码的结束符是对齐的两个零字节。
+func splitID3Text(data []byte, encoding byte) ([]byte, []byte) {
+	if encoding == 1 || encoding == 2 {
+		for i := 0; i+1 < len(data); i += 2 {
+			if data[i] == 0 && data[i+1] == 0 {
+				return data[:i], data[i+2:]
+			}
+		}
+		return data, nil
+	}
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return data[:i], data[i+1:]
+	}
+	return data, nil
+}
+
+// decodeID3Text 按 ID3v2 文本编码解码字符串：0 为 ISO-8859-1，1 为带 BOM 的 UTF-16，2 为 UTF-16BE，3 为 UTF-8。
+func decodeID3Text(data []byte, encoding byte) string {
+	switch encoding {
+	case 0:
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	case 1, 2:
+		return decodeUTF16(data)
+	default:
+		return string(data)
+	}
+}
@@ -0,0 +1,138 @@
+package models
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dhowden/tag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func TestParseLRC(t *testing.T) {
+	lyrics := ParseLRC("[ti:Song]\r\n[ar:Artist]\r\n[offset:+500]\r\n" +
+		"[00:12.30]First line\r\n" +
+		"[00:05.00][01:00.5]Chorus\r\n" +
+		"[00:20.123]<00:20.123>Word <00:21.00>by word\r\n" +
+		"[00:00.20]\r\n" +
+		"not a timed line\r\n")
+
+	assert.True(t, lyrics.Synced)
+	assert.Equal(t, int64(500), lyrics.OffsetMs)
+	assert.Equal(t, []LyricsLine{
+		{TimeMs: 0, Text: ""},
+		{TimeMs: 4500, Text: "Chorus"},
+		{TimeMs: 11800, Text: "First line"},
+		{TimeMs: 19623, Text: "Word by word"},
+		{TimeMs: 60000, Text: "Chorus"},
+	}, lyrics.Lines)
+	assert.Equal(t, "Chorus\nFirst line\nWord by word\nChorus", lyrics.Text)
+
+	// 负的 offset 推迟歌词
+	lyrics = ParseLRC("[offset:-250]\n[00:01.00]Late")
+	assert.Equal(t, int64(1250), lyrics.Lines[0].TimeMs)
+
+	// 没有时间标签的文本按纯文本处理
+	lyrics = ParseLRC("[ar:Artist]\nLine one\nLine two\n")
+	assert.False(t, lyrics.Synced)
+	assert.Empty(t, lyrics.Lines)
+	assert.Zero(t, lyrics.OffsetMs)
+	assert.Equal(t, "Line one\nLine two", lyrics.Text)
+}
+
+func TestDecodeLyricsFile(t *testing.T) {
+	gbk, err := simplifiedchinese.GBK.NewEncoder().Bytes([]byte("[00:01.00]你好"))
+	require.NoError(t, err)
+	assert.Equal(t, "[00:01.00]你好", DecodeLyricsFile(gbk))
+
+	assert.Equal(t, "歌词", DecodeLyricsFile(append([]byte{0xef, 0xbb, 0xbf}, "歌词"...)))
+	assert.Equal(t, "ab", DecodeLyricsFile([]byte{0xff, 0xfe, 'a', 0, 'b', 0}))
+}
+
+// id3Tag 生成包含给定帧的 ID3v2.3 标签。
+func id3Tag(frames map[string][]byte) []byte {
+	var body bytes.Buffer
+	for _, id := range []string{"TIT2", "USLT", "SYLT"} {
+		data, ok := frames[id]
+		if !ok {
+			continue
+		}
+		header := make([]byte, 10)
+		copy(header, id)
+		binary.BigEndian.PutUint32(header[4:8], uint32(len(data)))
+		body.Write(header)
+		body.Write(data)
+	}
+	size := body.Len()
+	tagData := []byte{'I', 'D', '3', 3, 0, 0, byte(size >> 21 & 0x7f), byte(size >> 14 & 0x7f), byte(size >> 7 & 0x7f), byte(size & 0x7f)}
+	return append(tagData, body.Bytes()...)
+}
+
+// syltFrame 生成使用 UTF-8 编码、毫秒时间戳的 SYLT 帧。
+func syltFrame(lines ...LyricsLine) []byte {
+	frame := []byte{3, 'e', 'n', 'g', 2, 1, 0} // 编码、语言、时间戳格式、内容类型、空描述
+	for _, line := range lines {
+		frame = append(frame, line.Text...)
+		frame = append(frame, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(frame[len(frame)-4:], uint32(line.TimeMs))
+	}
+	return frame
+}
+
+func readTestTag(t *testing.T, data []byte) (*Song, tag.Metadata) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "song.mp3")
+	require.NoError(t, os.WriteFile(path, append(data, make([]byte, 256)...), 0644))
+	song := NewSong(path, int64(len(data)))
+	require.NoError(t, song.UpdateMetadata())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	metadata, err := tag.ReadFrom(file)
+	require.NoError(t, err)
+	return song, metadata
+}
+
+func TestEmbeddedLyrics(t *testing.T) {
+	uslt := append([]byte{3, 'c', 'h', 'i', 0}, "[00:02.00]第二行\n[00:01.00]第一行"...)
+	song, metadata := readTestTag(t, id3Tag(map[string][]byte{"USLT": uslt}))
+	assert.True(t, song.EmbeddedLyrics)
+	assert.True(t, song.HasLyrics)
+	lyrics := EmbeddedLyrics(metadata)
+	require.NotNil(t, lyrics)
+	assert.Equal(t, LyricsSourceEmbedded, lyrics.Source)
+	assert.True(t, lyrics.Synced)
+	assert.Equal(t, "第一行\n第二行", lyrics.Text)
+
+	// SYLT 优先于 USLT
+	sylt := syltFrame(LyricsLine{TimeMs: 3000, Text: "\nSecond"}, LyricsLine{TimeMs: 1000, Text: "First"})
+	song, metadata = readTestTag(t, id3Tag(map[string][]byte{"USLT": uslt, "SYLT": sylt}))
+	assert.True(t, song.HasLyrics)
+	lyrics = EmbeddedLyrics(metadata)
+	require.NotNil(t, lyrics)
+	assert.Equal(t, "eng", lyrics.Language)
+	assert.Equal(t, []LyricsLine{{TimeMs: 1000, Text: "First"}, {TimeMs: 3000, Text: "Second"}}, lyrics.Lines)
+
+	song, metadata = readTestTag(t, id3Tag(map[string][]byte{"TIT2": append([]byte{0}, "Title"...)}))
+	assert.False(t, song.HasLyrics)
+	assert.Nil(t, EmbeddedLyrics(metadata))
+}
+
+func TestParseSYLT_UTF16(t *testing.T) {
+	frame := []byte{1, 'e', 'n', 'g', 2, 1, 0xff, 0xfe, 0, 0} // UTF-16 编码，描述只有 BOM
+	frame = append(frame, 0xff, 0xfe, 'H', 0, 'i', 0, 0, 0)
+	frame = binary.BigEndian.AppendUint32(frame, 1500)
+
+	lyrics, err := parseSYLT(frame)
+	require.NoError(t, err)
+	assert.Equal(t, []LyricsLine{{TimeMs: 1500, Text: "Hi"}}, lyrics.Lines)
+
+	frame[4] = 1 // 以 MPEG 帧为单位的时间戳
+	_, err = parseSYLT(frame)
+	assert.ErrorIs(t, err, errUnsupportedSYLT)
+}
@@ -0,0 +1,204 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// 可以覆盖的元数据字段，也是歌曲 overridden 列表中的字段名。
+const (
+	OverrideFieldTitle  = "title"
+	OverrideFieldArtist = "artist"
+	OverrideFieldAlbum  = "album"
+	OverrideFieldGenre  = "genre"
+	OverrideFieldYear   = "year"
+	OverrideFieldCover  = "cover"
+)
+
+// OverrideFields 是所有可以覆盖的元数据字段。
+var OverrideFields = []string{
+	OverrideFieldTitle, OverrideFieldArtist, OverrideFieldAlbum, OverrideFieldGenre, OverrideFieldYear, OverrideFieldCover,
+}
+
+// MetadataOverride 是管理员为歌曲保存的元数据修正，保存在数据库中而不写入音频文件，
+// 适用于只读挂载的音乐库。为 nil 的字段不覆盖，使用从文件读取的值。
+type MetadataOverride struct {
+	SongID string  `json:"song_id"`
+	Title  *string `json:"title,omitempty"`
+	Artist *string `json:"artist,omitempty"`
+	Album  *string `json:"album,omitempty"`
+	Genre  *string `json:"genre,omitempty"`
+	Year   *int    `json:"year,omitempty"`
+	// CoverMIME 是覆盖封面的 MIME 类型，为空时不覆盖封面。图片内容单独存储，按需读取
+	CoverMIME string `json:"cover_mime,omitempty"`
+	// CoverHash 是覆盖封面内容的哈希，用于计算封面的 ETag 和缩略图缓存键
+	CoverHash string    `json:"-"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Fields 返回被覆盖的字段，顺序与 OverrideFields 相同。
+func (o *MetadataOverride) Fields() []string {
+	var fields []string
+	for _, field := range OverrideFields {
+		if o.has(field) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// IsEmpty 判断是否没有覆盖任何字段。
+func (o *MetadataOverride) IsEmpty() bool {
+	return len(o.Fields()) == 0
+}
+
+// has 判断是否覆盖了 field。
+func (o *MetadataOverride) has(field string) bool {
+	switch field {
+	case OverrideFieldTitle:
+		return o.Title != nil
+	case OverrideFieldArtist:
+		return o.Artist != nil
+	case OverrideFieldAlbum:
+		return o.Album != nil
+	case OverrideFieldGenre:
+		return o.Genre != nil
+	case OverrideFieldYear:
+		return o.Year != nil
+	case OverrideFieldCover:
+		return o.CoverMIME != ""
+	}
+	return false
+}
+
+// Apply 将覆盖的字段写入歌曲，并在 Overridden 中记录被覆盖的字段。
+// 覆盖艺术家后，调用方需要重新拆分 Artists。
+func (o *MetadataOverride) Apply(song *Song) {
+	if o.Title != nil {
+		song.Title = *o.Title
+	}
+	if o.Artist != nil {
+		song.Artist = *o.Artist
+	}
+	if o.Album != nil {
+		song.Album = *o.Album
+	}
+	if o.Genre != nil {
+		song.Genre = *o.Genre
+	}
+	if o.Year != nil {
+		song.Year = *o.Year
+	}
+	if o.CoverMIME != "" {
+		song.HasCover = true
+		song.CoverSource = CoverSourceOverride
+		song.CoverPath = ""
+		song.CoverVersion = o.CoverHash
+	}
+	song.Overridden = o.Fields()
+}
+
+// MetadataOverrideEdit 描述对元数据覆盖的修改。为 nil 的字段保持原有的覆盖，
+// Clear 中列出的字段取消覆盖，恢复为从文件读取的值。
+type MetadataOverrideEdit struct {
+	Title  *string `json:"title"`
+	Artist *string `json:"artist"`
+	Album  *string `json:"album"`
+	Genre  *string `json:"genre"`
+	Year   *int    `json:"year"`
+	// Cover 是 JPEG 或 PNG 格式的封面图片，JSON 中使用 base64 编码
+	Cover *[]byte  `json:"cover"`
+	Clear []string `json:"clear"`
+}
+
+// Validate 检查修改是否有效，并去掉文本首尾的空白。
+func (e *MetadataOverrideEdit) Validate() error {
+	if e.Title == nil && e.Artist == nil && e.Album == nil && e.Genre == nil && e.Year == nil && e.Cover == nil && len(e.Clear) == 0 {
+		return errors.New("没有需要修改的字段")
+	}
+	for _, text := range []*string{e.Title, e.Artist, e.Album, e.Genre} {
+		if text == nil {
+			continue
+		}
+		*text = strings.TrimSpace(*text)
+		if strings.ContainsRune(*text, 0) || len(*text) > 1024 {
+			return errors.New("元数据文本无效")
+		}
+	}
+	if e.Year != nil && (*e.Year < 0 || *e.Year > 9999) {
+		return errors.New("年份必须在 0 到 9999 之间")
+	}
+	if e.Cover != nil {
+		if len(*e.Cover) == 0 {
+			return errors.New("封面图片不能为空，取消封面覆盖请使用 clear")
+		}
+		if len(*e.Cover) > MaxCoverSize {
+			return fmt.Errorf("封面图片不能超过 %d MB", MaxCoverSize>>20)
+		}
+		if e.CoverMIME() == "" {
+			return errors.New("封面图片只能是 JPEG 或 PNG 格式")
+		}
+	}
+	for _, field := range e.Clear {
+		if !slices.Contains(OverrideFields, field) {
+			return fmt.Errorf("未知的字段: %s", field)
+		}
+	}
+	return nil
+}
+
+// CoverMIME 返回封面图片的 MIME 类型，不是 JPEG 或 PNG 时返回空字符串。
+func (e *MetadataOverrideEdit) CoverMIME() string {
+	if e.Cover == nil {
+		return ""
+	}
+	switch mimeType := http.DetectContentType(*e.Cover); mimeType {
+	case "image/jpeg", "image/png":
+		return mimeType
+	default:
+		return ""
+	}
+}
+
+// ApplyTo 将修改合并到 override 中，先取消 Clear 中的字段，再设置新的值。
+// 封面图片由调用方单独保存，这里只更新其 MIME 类型；coverHash 是新封面内容的哈希。
+func (e *MetadataOverrideEdit) ApplyTo(override *MetadataOverride, coverHash string) {
+	for _, field := range e.Clear {
+		switch field {
+		case OverrideFieldTitle:
+			override.Title = nil
+		case OverrideFieldArtist:
+			override.Artist = nil
+		case OverrideFieldAlbum:
+			override.Album = nil
+		case OverrideFieldGenre:
+			override.Genre = nil
+		case OverrideFieldYear:
+			override.Year = nil
+		case OverrideFieldCover:
+			override.CoverMIME, override.CoverHash = "", ""
+		}
+	}
+	if e.Title != nil {
+		override.Title = e.Title
+	}
+	if e.Artist != nil {
+		override.Artist = e.Artist
+	}
+	if e.Album != nil {
+		override.Album = e.Album
+	}
+	if e.Genre != nil {
+		override.Genre = e.Genre
+	}
+	if e.Year != nil {
+		override.Year = e.Year
+	}
+	if e.Cover != nil {
+		override.CoverMIME, override.CoverHash = e.CoverMIME(), coverHash
+	}
+}
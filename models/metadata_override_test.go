@@ -0,0 +1,59 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetadataOverride_Apply(t *testing.T) {
+	title, year := "Fixed", 0
+	override := &MetadataOverride{SongID: "a", Title: &title, Year: &year, CoverMIME: "image/png", CoverHash: "abc"}
+	song := &Song{ID: "a", Title: "Old", Artist: "Artist", Year: 2001, CoverSource: CoverSourceSidecar, CoverPath: "/music/cover.jpg"}
+
+	override.Apply(song)
+	assert.Equal(t, "Fixed", song.Title)
+	assert.Equal(t, "Artist", song.Artist)
+	assert.Equal(t, 0, song.Year)
+	assert.True(t, song.HasCover)
+	assert.Equal(t, CoverSourceOverride, song.CoverSource)
+	assert.Equal(t, "abc", song.CoverVersion)
+	assert.Empty(t, song.CoverPath)
+	assert.Equal(t, []string{OverrideFieldTitle, OverrideFieldYear, OverrideFieldCover}, song.Overridden)
+}
+
+func TestMetadataOverrideEdit_ApplyTo(t *testing.T) {
+	artist, album := "Old Artist", "Old Album"
+	override := &MetadataOverride{SongID: "a", Artist: &artist, Album: &album, CoverMIME: "image/jpeg", CoverHash: "old"}
+
+	var edit MetadataOverrideEdit
+	require.NoError(t, json.Unmarshal([]byte(`{"artist":" New Artist ","genre":"","clear":["album","cover"]}`), &edit))
+	require.NoError(t, edit.Validate())
+	edit.ApplyTo(override, "")
+
+	assert.Equal(t, "New Artist", *override.Artist)
+	assert.Nil(t, override.Album)
+	assert.Equal(t, "", *override.Genre)
+	assert.Empty(t, override.CoverMIME)
+	assert.Equal(t, []string{OverrideFieldArtist, OverrideFieldGenre}, override.Fields())
+
+	edit = MetadataOverrideEdit{Clear: []string{OverrideFieldArtist, OverrideFieldGenre}}
+	require.NoError(t, edit.Validate())
+	edit.ApplyTo(override, "")
+	assert.True(t, override.IsEmpty())
+}
+
+func TestMetadataOverrideEdit_Validate(t *testing.T) {
+	year := -1
+	empty := []byte{}
+	notImage := []byte("not an image")
+
+	assert.Error(t, (&MetadataOverrideEdit{}).Validate())
+	assert.Error(t, (&MetadataOverrideEdit{Year: &year}).Validate())
+	assert.Error(t, (&MetadataOverrideEdit{Cover: &empty}).Validate())
+	assert.Error(t, (&MetadataOverrideEdit{Cover: &notImage}).Validate())
+	assert.Error(t, (&MetadataOverrideEdit{Clear: []string{"composer"}}).Validate())
+	assert.NoError(t, (&MetadataOverrideEdit{Clear: []string{OverrideFieldCover}}).Validate())
+}
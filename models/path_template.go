@@ -0,0 +1,115 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxPathSegmentBytes 是渲染后单级目录或文件名的最大字节数，为扩展名和去重后缀留出余量。
+const maxPathSegmentBytes = 200
+
+// pathTemplateFields 是路径模板支持的占位符及其取值。
+var pathTemplateFields = map[string]func(song *Song) string{
+	"albumartist": func(song *Song) string { return song.AlbumArtistName() },
+	"artist":      func(song *Song) string { return song.Artist },
+	"album":       func(song *Song) string { return song.Album },
+	"title":       func(song *Song) string { return song.Title },
+	"genre":       func(song *Song) string { return song.Genre },
+	"year":        func(song *Song) string { return positive(song.Year, 1) },
+	"track":       func(song *Song) string { return positive(song.Track, 2) },
+	"disc":        func(song *Song) string { return positive(song.Disc, 1) },
+}
+
+// positive 将正数格式化为至少 width 位的数字，0 或负数返回空字符串。
+func positive(n, width int) string {
+	if n <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%0*d", width, n)
+}
+
+// pathTemplatePlaceholder 匹配路径模板中的占位符，如 {album}。
+var pathTemplatePlaceholder = regexp.MustCompile(`\{([a-z]+)\}`)
+
+// PathTemplate 根据歌曲的标签生成其在音乐库中的相对路径（不含扩展名），如 "{albumartist}/{album}/{track} {title}"。
+// 各级目录用 / 分隔；标签值中的 / 等不能出现在文件名中的字符会被替换。
+type PathTemplate struct {
+	segments []string
+}
+
+// ParsePathTemplate 解析路径模板，检查占位符是否有效、模板是否包含标题。
+func ParsePathTemplate(template string) (*PathTemplate, error) {
+	template = strings.Trim(strings.TrimSpace(template), "/")
+	if template == "" {
+		return nil, fmt.Errorf("路径模板不能为空")
+	}
+	if !strings.Contains(template, "{title}") {
+		return nil, fmt.Errorf("路径模板必须包含 {title}")
+	}
+	segments := strings.Split(template, "/")
+	for _, segment := range segments {
+		if strings.TrimSpace(segment) == "" || segment == "." || segment == ".." {
+			return nil, fmt.Errorf("路径模板中的目录无效: %q", template)
+		}
+		for _, match := range pathTemplatePlaceholder.FindAllStringSubmatch(segment, -1) {
+			if _, ok := pathTemplateFields[match[1]]; !ok {
+				return nil, fmt.Errorf("路径模板中的占位符未知: {%s}", match[1])
+			}
+		}
+		if rest := pathTemplatePlaceholder.ReplaceAllString(segment, ""); strings.ContainsAny(rest, "{}") {
+			return nil, fmt.Errorf("路径模板中的占位符格式错误: %q", segment)
+		}
+	}
+	return &PathTemplate{segments: segments}, nil
+}
+
+// Render 返回歌曲的相对路径（使用 / 分隔，不含扩展名）。标签为空的占位符被省略，
+// 省略后为空的目录使用 "Unknown"。
+func (t *PathTemplate) Render(song *Song) string {
+	parts := make([]string, len(t.segments))
+	for i, segment := range t.segments {
+		rendered := pathTemplatePlaceholder.ReplaceAllStringFunc(segment, func(placeholder string) string {
+			value := pathTemplateFields[placeholder[1:len(placeholder)-1]](song)
+			return sanitizePathSegment(value)
+		})
+		parts[i] = cleanPathSegment(rendered)
+	}
+	return strings.Join(parts, "/")
+}
+
+// ValidPathSegment 判断 segment 是否可以作为文件名或目录名，即不包含路径分隔符、Windows 保留字符和控制字符。
+func ValidPathSegment(segment string) bool {
+	return segment != "" && sanitizePathSegment(segment) == segment
+}
+
+// sanitizePathSegment 替换不能出现在文件名中的字符（路径分隔符、Windows 保留字符和控制字符）。
+func sanitizePathSegment(value string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, value)
+}
+
+// cleanPathSegment 合并空白、去掉首尾的空白、点和连字符（占位符为空时留下的 "-" 等），并限制长度。结果为空时返回 "Unknown"。
+func cleanPathSegment(segment string) string {
+	segment = strings.Join(strings.Fields(segment), " ")
+	segment = strings.TrimFunc(segment, func(r rune) bool {
+		return r == '.' || r == '-' || unicode.IsSpace(r)
+	})
+	if len(segment) > maxPathSegmentBytes {
+		cut := maxPathSegmentBytes
+		for cut > 0 && !utf8.RuneStart(segment[cut]) {
+			cut--
+		}
+		segment = strings.TrimSpace(segment[:cut])
+	}
+	if segment == "" {
+		return "Unknown"
+	}
+	return segment
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePathTemplate_Invalid(t *testing.T) {
+	for _, template := range []string{
+		"",
+		"{artist}/{album}",
+		"{artist}/{unknown} {title}",
+		"{artist}//{title}",
+		"../{title}",
+		"{artist}/{title",
+	} {
+		_, err := ParsePathTemplate(template)
+		assert.Error(t, err, template)
+	}
+}
+
+func TestPathTemplate_Render(t *testing.T) {
+	template, err := ParsePathTemplate("{albumartist}/{album}/{disc}-{track} {title}")
+	require.NoError(t, err)
+
+	song := &Song{Title: "Back in Black", Artist: "AC/DC", Album: "Back in Black", Track: 6, Disc: 1}
+	assert.Equal(t, "AC_DC/Back in Black/1-06 Back in Black", template.Render(song))
+
+	// 专辑艺术家优先，空的占位符被省略
+	song = &Song{Title: "Intro", Artist: "A feat. B", Artists: []string{"A", "B"}, AlbumArtist: "Various Artists", Album: "Mix"}
+	assert.Equal(t, "Various Artists/Mix/Intro", template.Render(song))
+
+	// 不能出现在路径中的字符、隐藏文件和过长的名称
+	song = &Song{Title: "..hidden?", Artist: "", Album: "  "}
+	assert.Equal(t, "Unknown/Unknown/hidden_", template.Render(song))
+
+	song = &Song{Title: strings.Repeat("歌", 100), Artist: "A", Album: "B"}
+	rendered := template.Render(song)
+	name := rendered[strings.LastIndex(rendered, "/")+1:]
+	assert.LessOrEqual(t, len(name), maxPathSegmentBytes)
+	assert.True(t, strings.HasPrefix(name, "歌"))
+}
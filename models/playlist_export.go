@@ -0,0 +1,115 @@
+package models
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// PlaylistFormatJSON 是导出播放列表时使用的 JSON 格式。
+const PlaylistFormatJSON = "json"
+
+// ExportFormats 是导出播放列表支持的格式。
+var ExportFormats = []string{PlaylistFormatM3U8, PlaylistFormatXSPF, PlaylistFormatJSON}
+
+// xspfNamespace 是 XSPF 1 的 XML 命名空间。
+const xspfNamespace = "http://xspf.org/ns/0/"
+
+// PlaylistExport 是导出的播放列表。
+type PlaylistExport struct {
+	Name        string                `json:"name"`
+	Description string                `json:"description,omitempty"`
+	ExportedAt  time.Time             `json:"exported_at"`
+	Tracks      []PlaylistExportTrack `json:"tracks"`
+}
+
+// PlaylistExportTrack 是导出的播放列表中的一首歌曲。
+type PlaylistExportTrack struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Artist string `json:"artist,omitempty"`
+	Album  string `json:"album,omitempty"`
+	// Duration 是时长（秒）
+	Duration int `json:"duration,omitempty"`
+	// Location 是音乐库内的相对路径或者音频流的 URL
+	Location string `json:"location"`
+}
+
+// Write 按格式写出播放列表。
+func (p *PlaylistExport) Write(w io.Writer, format string) error {
+	switch format {
+	case PlaylistFormatM3U8:
+		return p.writeM3U8(w)
+	case PlaylistFormatXSPF:
+		return p.writeXSPF(w)
+	case PlaylistFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(p)
+	default:
+		return fmt.Errorf("不支持的导出格式: %s", format)
+	}
+}
+
+// writeM3U8 写出带 #EXTINF 扩展信息的 UTF-8 M3U 播放列表。
+func (p *PlaylistExport) writeM3U8(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintln(b, "#EXTM3U")
+	fmt.Fprintf(b, "#PLAYLIST:%s\n", singleLine(p.Name))
+	for _, track := range p.Tracks {
+		duration := track.Duration
+		if duration <= 0 {
+			duration = -1
+		}
+		title := singleLine(track.Title)
+		if track.Artist != "" {
+			title = singleLine(track.Artist) + " - " + title
+		}
+		fmt.Fprintf(b, "#EXTINF:%d,%s\n", duration, title)
+		fmt.Fprintln(b, singleLine(track.Location))
+	}
+	return b.Flush()
+}
+
+// writeXSPF 写出 XSPF 播放列表。相对路径按 URI 的要求逐段转义。
+func (p *PlaylistExport) writeXSPF(w io.Writer) error {
+	doc := XSPFPlaylist{Version: "1", Xmlns: xspfNamespace, Title: p.Name, Tracks: make([]XSPFTrack, len(p.Tracks))}
+	for i, track := range p.Tracks {
+		location := track.Location
+		if !strings.Contains(location, "://") {
+			segments := strings.Split(location, "/")
+			for j, segment := range segments {
+				segments[j] = url.PathEscape(segment)
+			}
+			location = strings.Join(segments, "/")
+		}
+		doc.Tracks[i] = XSPFTrack{
+			Location: []string{location},
+			Title:    track.Title,
+			Creator:  track.Artist,
+			Album:    track.Album,
+			Duration: int64(track.Duration) * 1000,
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// singleLine 将换行替换为空格，避免破坏按行分隔的格式。
+func singleLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPlaylistExport() *PlaylistExport {
+	return &PlaylistExport{
+		Name:       "Road\nTrip",
+		ExportedAt: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		Tracks: []PlaylistExportTrack{
+			{ID: "a", Title: "Song", Artist: "Artist", Album: "Album", Duration: 215, Location: "Artist/Album/01 Song.flac"},
+			{ID: "b", Title: "Other", Location: "http://localhost:8080/api/v1/stream/b"},
+		},
+	}
+}
+
+func TestPlaylistExport_M3U8(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, newTestPlaylistExport().Write(&buf, PlaylistFormatM3U8))
+
+	assert.Equal(t, "#EXTM3U\n#PLAYLIST:Road Trip\n"+
+		"#EXTINF:215,Artist - Song\nArtist/Album/01 Song.flac\n"+
+		"#EXTINF:-1,Other\nhttp://localhost:8080/api/v1/stream/b\n", buf.String())
+
+	// 导出的文件可以被重新解析
+	parsed, err := ParsePlaylistFile("trip.m3u8", buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, "Road Trip", parsed.Name)
+	assert.Equal(t, []PlaylistEntry{
+		{Position: 1, Location: "Artist/Album/01 Song.flac", Title: "Artist - Song", Duration: 215},
+		{Position: 2, Location: "http://localhost:8080/api/v1/stream/b", Title: "Other"},
+	}, parsed.Entries)
+}
+
+func TestPlaylistExport_XSPF(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, newTestPlaylistExport().Write(&buf, PlaylistFormatXSPF))
+
+	// 相对路径按 URI 转义
+	assert.Contains(t, buf.String(), "<location>Artist/Album/01%20Song.flac</location>")
+	assert.Contains(t, buf.String(), "<duration>215000</duration>")
+	assert.Contains(t, buf.String(), `xmlns="http://xspf.org/ns/0/"`)
+
+	parsed, err := ParsePlaylistFile("trip.xspf", buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, []PlaylistEntry{
+		{Position: 1, Location: "Artist/Album/01 Song.flac", Title: "Artist - Song", Duration: 215},
+		{Position: 2, Location: "http://localhost:8080/api/v1/stream/b", Title: "Other"},
+	}, parsed.Entries)
+}
+
+func TestPlaylistExport_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	export := newTestPlaylistExport()
+	require.NoError(t, export.Write(&buf, PlaylistFormatJSON))
+
+	var decoded PlaylistExport
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, *export, decoded)
+
+	assert.Error(t, export.Write(&buf, PlaylistFormatPLS))
+}
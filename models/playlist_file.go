@@ -0,0 +1,284 @@
+package models
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 播放列表文件的格式。
+const (
+	PlaylistFormatM3U  = "m3u"
+	PlaylistFormatM3U8 = "m3u8"
+	PlaylistFormatPLS  = "pls"
+	PlaylistFormatXSPF = "xspf"
+)
+
+// errInvalidPlaylist 表示内容不是可用的播放列表文件。
+var errInvalidPlaylist = errors.New("无效的播放列表文件")
+
+// SharedPlaylist 是音乐目录中的播放列表文件，对所有用户只读共享。
+type SharedPlaylist struct {
+	// ID 由音乐库名称和播放列表文件的相对路径生成，与歌曲 ID 的生成方式相同
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Library  string `json:"library"`
+	RelPath  string `json:"rel_path"`
+	FilePath string `json:"-"`
+	Format   string `json:"format"`
+	// SongIDs 是按播放列表顺序解析出的歌曲 ID
+	SongIDs   []string `json:"-"`
+	SongCount int      `json:"song_count"`
+	// Unresolved 是无法对应到歌曲库中歌曲的条目
+	Unresolved      []PlaylistEntry `json:"unresolved,omitempty"`
+	UnresolvedCount int             `json:"unresolved_count"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+}
+
+// Clone 返回播放列表的副本。
+func (p *SharedPlaylist) Clone() *SharedPlaylist {
+	copied := *p
+	copied.SongIDs = slices.Clone(p.SongIDs)
+	copied.Unresolved = slices.Clone(p.Unresolved)
+	return &copied
+}
+
+// LibraryIssues 返回播放列表中每个无法解析的条目对应的问题。
+func (p *SharedPlaylist) LibraryIssues() []*LibraryIssue {
+	issues := make([]*LibraryIssue, len(p.Unresolved))
+	for i, entry := range p.Unresolved {
+		issues[i] = &LibraryIssue{
+			Kind:     IssueUnresolvedEntry,
+			Message:  fmt.Sprintf("第 %d 项 %s: %s", entry.Position, entry.Location, entry.Reason),
+			Library:  p.Library,
+			FilePath: p.FilePath,
+			RelPath:  p.RelPath,
+			Title:    p.Name,
+		}
+	}
+	return issues
+}
+
+// PlaylistEntry 是播放列表文件中的一个条目。
+type PlaylistEntry struct {
+	// Position 是条目在播放列表中的序号，从 1 开始
+	Position int    `json:"position"`
+	Location string `json:"location"`
+	Title    string `json:"title,omitempty"`
+	// Duration 是播放列表中记录的时长（秒），没有记录时为 0
+	Duration int `json:"duration,omitempty"`
+	// Reason 是条目无法解析的原因
+	Reason string `json:"reason,omitempty"`
+}
+
+// PlaylistFile 是解析后的播放列表文件。
+type PlaylistFile struct {
+	Name    string
+	Format  string
+	Entries []PlaylistEntry
+}
+
+// PlaylistFormat 根据扩展名返回播放列表文件的格式，不是播放列表文件时返回空字符串。
+func PlaylistFormat(path string) string {
+	switch format := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), ".")); format {
+	case PlaylistFormatM3U, PlaylistFormatM3U8, PlaylistFormatPLS, PlaylistFormatXSPF:
+		return format
+	default:
+		return ""
+	}
+}
+
+// ParsePlaylistFile 按扩展名解析播放列表文件。M3U 和 PLS 的编码与歌词文件一样自动识别（UTF-8、UTF-16 或 GBK）。
+// 播放列表没有记录名称时使用文件名作为名称。
+func ParsePlaylistFile(path string, data []byte) (*PlaylistFile, error) {
+	playlist := &PlaylistFile{Format: PlaylistFormat(path)}
+	var err error
+	switch playlist.Format {
+	case PlaylistFormatM3U, PlaylistFormatM3U8:
+		err = playlist.parseM3U(DecodeLyricsFile(data))
+	case PlaylistFormatPLS:
+		err = playlist.parsePLS(DecodeLyricsFile(data))
+	case PlaylistFormatXSPF:
+		err = playlist.parseXSPF(data)
+	default:
+		return nil, fmt.Errorf("不支持的播放列表格式: %s", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if playlist.Name == "" {
+		playlist.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return playlist, nil
+}
+
+// parseM3U 解析 M3U/M3U8 播放列表，支持 #EXTINF 和 #PLAYLIST 扩展指令。
+func (p *PlaylistFile) parseM3U(text string) error {
+	var pending PlaylistEntry
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#PLAYLIST:"):
+			p.Name = strings.TrimSpace(strings.TrimPrefix(line, "#PLAYLIST:"))
+		case strings.HasPrefix(line, "#EXTINF:"):
+			// #EXTINF:时长 属性,艺术家 - 标题
+			info := strings.TrimPrefix(line, "#EXTINF:")
+			duration, title, _ := strings.Cut(info, ",")
+			if fields := strings.Fields(duration); len(fields) > 0 {
+				if seconds, err := strconv.Atoi(fields[0]); err == nil && seconds > 0 {
+					pending.Duration = seconds
+				}
+			}
+			pending.Title = strings.TrimSpace(title)
+		case strings.HasPrefix(line, "#"):
+		default:
+			pending.Location = line
+			p.add(pending)
+			pending = PlaylistEntry{}
+		}
+	}
+	return nil
+}
+
+// parsePLS 解析 PLS 播放列表，条目按 FileN 的编号排序。
+func (p *PlaylistFile) parsePLS(text string) error {
+	entries := make(map[int]*PlaylistEntry)
+	header := false
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.EqualFold(line, "[playlist]") {
+			header = true
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		var field string
+		for _, prefix := range []string{"file", "title", "length"} {
+			if strings.HasPrefix(key, prefix) {
+				field = prefix
+				break
+			}
+		}
+		n, err := strconv.Atoi(strings.TrimPrefix(key, field))
+		if field == "" || err != nil {
+			continue
+		}
+		entry := entries[n]
+		if entry == nil {
+			entry = &PlaylistEntry{}
+			entries[n] = entry
+		}
+		switch field {
+		case "file":
+			entry.Location = value
+		case "title":
+			entry.Title = value
+		case "length":
+			if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+				entry.Duration = seconds
+			}
+		}
+	}
+	if !header {
+		return errInvalidPlaylist
+	}
+
+	numbers := make([]int, 0, len(entries))
+	for n := range entries {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+	for _, n := range numbers {
+		if entries[n].Location != "" {
+			p.add(*entries[n])
+		}
+	}
+	return nil
+}
+
+// XSPFPlaylist 是 XSPF 播放列表的 XML 结构。
+type XSPFPlaylist struct {
+	XMLName xml.Name    `xml:"playlist"`
+	Version string      `xml:"version,attr,omitempty"`
+	Xmlns   string      `xml:"xmlns,attr,omitempty"`
+	Title   string      `xml:"title,omitempty"`
+	Tracks  []XSPFTrack `xml:"trackList>track"`
+}
+
+// XSPFTrack 是 XSPF 播放列表中的一个曲目，Duration 以毫秒为单位。
+type XSPFTrack struct {
+	Location []string `xml:"location"`
+	Title    string   `xml:"title,omitempty"`
+	Creator  string   `xml:"creator,omitempty"`
+	Album    string   `xml:"album,omitempty"`
+	Duration int64    `xml:"duration,omitempty"`
+}
+
+// parseXSPF 解析 XSPF 播放列表，每个曲目使用第一个 location。
+func (p *PlaylistFile) parseXSPF(data []byte) error {
+	var doc XSPFPlaylist
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	if err := decoder.Decode(&doc); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidPlaylist, err)
+	}
+	p.Name = strings.TrimSpace(doc.Title)
+	for _, track := range doc.Tracks {
+		if len(track.Location) == 0 || strings.TrimSpace(track.Location[0]) == "" {
+			continue
+		}
+		location := strings.TrimSpace(track.Location[0])
+		if !strings.Contains(location, "://") {
+			// XSPF 中的相对位置是经过转义的 URI
+			if unescaped, err := url.PathUnescape(location); err == nil {
+				location = unescaped
+			}
+		}
+		title := strings.TrimSpace(track.Title)
+		if creator := strings.TrimSpace(track.Creator); creator != "" && title != "" {
+			title = creator + " - " + title
+		}
+		p.add(PlaylistEntry{
+			Location: location,
+			Title:    title,
+			Duration: int(track.Duration / 1000),
+		})
+	}
+	return nil
+}
+
+// add 添加一个条目并编号。
+func (p *PlaylistFile) add(entry PlaylistEntry) {
+	entry.Position = len(p.Entries) + 1
+	p.Entries = append(p.Entries, entry)
+}
+
+// ResolvePlaylistLocation 将播放列表中的位置解析为本地文件的绝对路径：相对路径相对于播放列表所在的目录 dir，
+// 支持 file:// URL 和 Windows 风格的分隔符。位置是远程地址时返回错误。
+func ResolvePlaylistLocation(dir, location string) (string, error) {
+	if scheme, _, ok := strings.Cut(location, "://"); ok && !strings.ContainsAny(scheme, `/\`) {
+		u, err := url.Parse(location)
+		if err != nil || !strings.EqualFold(u.Scheme, "file") {
+			return "", errors.New("不是本地文件")
+		}
+		location = u.Path
+	}
+	if filepath.Separator == '/' {
+		location = strings.ReplaceAll(location, `\`, "/")
+	}
+	if !filepath.IsAbs(location) {
+		location = filepath.Join(dir, location)
+	}
+	return filepath.Clean(location), nil
+}
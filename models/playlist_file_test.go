@@ -0,0 +1,85 @@
+package models
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePlaylistFile_M3U(t *testing.T) {
+	data := "\ufeff#EXTM3U\r\n#PLAYLIST:Road Trip\r\n#EXTINF:215 tvg-id=\"x\",Artist - Song\r\nmusic/song.mp3\r\n\r\n# comment\r\nother.flac\r\n"
+	playlist, err := ParsePlaylistFile("/music/lists/trip.m3u8", []byte(data))
+	require.NoError(t, err)
+
+	assert.Equal(t, "Road Trip", playlist.Name)
+	assert.Equal(t, PlaylistFormatM3U8, playlist.Format)
+	assert.Equal(t, []PlaylistEntry{
+		{Position: 1, Location: "music/song.mp3", Title: "Artist - Song", Duration: 215},
+		{Position: 2, Location: "other.flac"},
+	}, playlist.Entries)
+
+	// 没有 #PLAYLIST 时使用文件名
+	playlist, err = ParsePlaylistFile("/music/Favourites.M3U", []byte("a.mp3\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "Favourites", playlist.Name)
+	assert.Equal(t, PlaylistFormatM3U, playlist.Format)
+}
+
+func TestParsePlaylistFile_PLS(t *testing.T) {
+	data := "[playlist]\nFile2=b.mp3\nTitle2=B\nFile1=a.mp3\nLength1=-1\nLength2=180\nNumberOfEntries=2\nVersion=2\n"
+	playlist, err := ParsePlaylistFile("/music/list.pls", []byte(data))
+	require.NoError(t, err)
+	assert.Equal(t, []PlaylistEntry{
+		{Position: 1, Location: "a.mp3"},
+		{Position: 2, Location: "b.mp3", Title: "B", Duration: 180},
+	}, playlist.Entries)
+
+	_, err = ParsePlaylistFile("/music/list.pls", []byte("File1=a.mp3\n"))
+	assert.Error(t, err)
+}
+
+func TestParsePlaylistFile_XSPF(t *testing.T) {
+	data := `<?xml version="1.0" encoding="UTF-8"?>
+<playlist version="1" xmlns="http://xspf.org/ns/0/">
+  <title>晚安</title>
+  <trackList>
+    <track><location>Some%20Artist/01%20Song.flac</location><creator>Some Artist</creator><title>Song</title><duration>241500</duration></track>
+    <track><location>file:///music/a%20b.mp3</location></track>
+    <track><title>No location</title></track>
+  </trackList>
+</playlist>`
+	playlist, err := ParsePlaylistFile("/music/night.xspf", []byte(data))
+	require.NoError(t, err)
+	assert.Equal(t, "晚安", playlist.Name)
+	assert.Equal(t, []PlaylistEntry{
+		{Position: 1, Location: "Some Artist/01 Song.flac", Title: "Some Artist - Song", Duration: 241},
+		{Position: 2, Location: "file:///music/a%20b.mp3"},
+	}, playlist.Entries)
+
+	_, err = ParsePlaylistFile("/music/broken.xspf", []byte("<playlist><trackList>"))
+	assert.Error(t, err)
+}
+
+func TestResolvePlaylistLocation(t *testing.T) {
+	dir := filepath.FromSlash("/music/lists")
+	testCases := map[string]string{
+		"../Album/song.mp3":             "/music/Album/song.mp3",
+		`..\Album\song.mp3`:             "/music/Album/song.mp3",
+		"/data/other.flac":              "/data/other.flac",
+		"file:///music/a%20b.mp3":       "/music/a b.mp3",
+		"local.mp3":                     "/music/lists/local.mp3",
+		"./sub/../local.mp3":            "/music/lists/local.mp3",
+		"http://radio.example.com/live": "",
+	}
+	for location, want := range testCases {
+		got, err := ResolvePlaylistLocation(dir, location)
+		if want == "" {
+			assert.Error(t, err, location)
+			continue
+		}
+		require.NoError(t, err, location)
+		assert.Equal(t, filepath.FromSlash(want), got, location)
+	}
+}
@@ -0,0 +1,348 @@
+package models
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// PlaylistFormatCSV 是导入播放列表时支持的 CSV 格式。
+const PlaylistFormatCSV = "csv"
+
+// ImportFormats 是导入播放列表支持的格式。
+var ImportFormats = []string{PlaylistFormatM3U, PlaylistFormatM3U8, PlaylistFormatPLS, PlaylistFormatXSPF, PlaylistFormatCSV, PlaylistFormatJSON}
+
+// errNoImportEntries 表示导入的文件中没有任何条目。
+var errNoImportEntries = errors.New("文件中没有可导入的歌曲")
+
+// ImportEntry 是导入的播放列表文件中的一个条目。
+type ImportEntry struct {
+	// Position 是条目在文件中的序号，从 1 开始
+	Position int    `json:"position"`
+	Title    string `json:"title,omitempty"`
+	Artist   string `json:"artist,omitempty"`
+	Album    string `json:"album,omitempty"`
+	// Duration 是文件中记录的时长（秒），没有记录时为 0
+	Duration int `json:"duration,omitempty"`
+	// Location 是文件路径或 URL，CSV 和第三方 JSON 中通常没有
+	Location string `json:"location,omitempty"`
+	// SongID 是本服务导出的 JSON 中记录的歌曲 ID
+	SongID string `json:"song_id,omitempty"`
+}
+
+// PlaylistImport 是解析后的待导入播放列表。
+type PlaylistImport struct {
+	Name    string
+	Format  string
+	Entries []ImportEntry
+}
+
+// ImportFormat 根据扩展名返回导入文件的格式，不支持时返回空字符串。
+func ImportFormat(filename string) string {
+	format := strings.ToLower(strings.TrimPrefix(filepath.Ext(filename), "."))
+	if format == "tsv" {
+		return PlaylistFormatCSV
+	}
+	for _, supported := range ImportFormats {
+		if format == supported {
+			return format
+		}
+	}
+	return ""
+}
+
+// ParsePlaylistImport 按格式解析待导入的播放列表文件，format 为空时根据文件名判断。
+// 支持 M3U/M3U8、PLS、XSPF，流媒体服务导出工具常用的带表头 CSV，以及 JSON：
+// 本服务导出的格式、条目数组，或者条目位于 tracks/items/songs 字段中的对象。
+// 没有记录名称时使用文件名作为播放列表名称。
+func ParsePlaylistImport(filename, format string, data []byte) (*PlaylistImport, error) {
+	if format == "" {
+		format = ImportFormat(filename)
+	}
+	playlist := &PlaylistImport{Format: format}
+	var err error
+	switch format {
+	case PlaylistFormatM3U, PlaylistFormatM3U8, PlaylistFormatPLS, PlaylistFormatXSPF:
+		err = playlist.parsePlaylistFile(format, data)
+	case PlaylistFormatCSV:
+		err = playlist.parseCSV(DecodeLyricsFile(data))
+	case PlaylistFormatJSON:
+		err = playlist.parseJSON(data)
+	default:
+		return nil, fmt.Errorf("不支持的导入格式: %s", filepath.Ext(filename))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(playlist.Entries) == 0 {
+		return nil, errNoImportEntries
+	}
+	for i := range playlist.Entries {
+		playlist.Entries[i].Position = i + 1
+	}
+	if playlist.Name == "" {
+		playlist.Name = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	}
+	return playlist, nil
+}
+
+// parsePlaylistFile 解析播放列表文件。标题是 "艺术家 - 标题" 时拆分为艺术家和标题，
+// 没有标题时使用文件名。
+func (p *PlaylistImport) parsePlaylistFile(format string, data []byte) error {
+	parsed, err := ParsePlaylistFile("import."+format, data)
+	if err != nil {
+		return err
+	}
+	if parsed.Name != "import" {
+		p.Name = parsed.Name
+	}
+	for _, entry := range parsed.Entries {
+		imported := ImportEntry{Location: entry.Location, Duration: entry.Duration}
+		if artist, title, ok := strings.Cut(entry.Title, " - "); ok {
+			imported.Artist, imported.Title = strings.TrimSpace(artist), strings.TrimSpace(title)
+		} else {
+			imported.Title = entry.Title
+		}
+		if imported.Title == "" {
+			base := path.Base(strings.ReplaceAll(entry.Location, `\`, "/"))
+			imported.Title = strings.TrimSuffix(base, path.Ext(base))
+		}
+		p.Entries = append(p.Entries, imported)
+	}
+	return nil
+}
+
+// importField 是 CSV 列或 JSON 字段对应的条目字段。
+type importField int
+
+const (
+	importFieldNone importField = iota
+	importFieldTitle
+	importFieldArtist
+	importFieldAlbum
+	importFieldDuration
+	importFieldDurationMS
+	importFieldLocation
+	importFieldID
+)
+
+// importFieldNames 是各字段的常见列名，只保留字母和数字并转换为小写后比较，
+// 例如 "Track Name"、"Artist Name(s)"、"Duration (ms)"。
+var importFieldNames = map[string]importField{
+	"title":        importFieldTitle,
+	"name":         importFieldTitle,
+	"track":        importFieldTitle,
+	"trackname":    importFieldTitle,
+	"tracktitle":   importFieldTitle,
+	"song":         importFieldTitle,
+	"songname":     importFieldTitle,
+	"songtitle":    importFieldTitle,
+	"artist":       importFieldArtist,
+	"artists":      importFieldArtist,
+	"artistname":   importFieldArtist,
+	"artistnames":  importFieldArtist,
+	"creator":      importFieldArtist,
+	"album":        importFieldAlbum,
+	"albumname":    importFieldAlbum,
+	"albumtitle":   importFieldAlbum,
+	"duration":     importFieldDuration,
+	"length":       importFieldDuration,
+	"time":         importFieldDuration,
+	"durations":    importFieldDuration,
+	"durationms":   importFieldDurationMS,
+	"lengthms":     importFieldDurationMS,
+	"milliseconds": importFieldDurationMS,
+	"location":     importFieldLocation,
+	"path":         importFieldLocation,
+	"file":         importFieldLocation,
+	"id":           importFieldID,
+}
+
+// lookupImportField 返回列名对应的字段。
+func lookupImportField(name string) importField {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			b.WriteRune(r)
+		}
+	}
+	return importFieldNames[b.String()]
+}
+
+// set 将字段的值写入条目。
+func (e *ImportEntry) set(field importField, value string) {
+	value = strings.TrimSpace(value)
+	switch field {
+	case importFieldTitle:
+		e.Title = value
+	case importFieldArtist:
+		e.Artist = value
+	case importFieldAlbum:
+		e.Album = value
+	case importFieldDuration:
+		e.Duration = parseImportDuration(value, false)
+	case importFieldDurationMS:
+		e.Duration = parseImportDuration(value, true)
+	case importFieldLocation:
+		e.Location = value
+	case importFieldID:
+		if ValidIDRegex.MatchString(value) {
+			e.SongID = value
+		}
+	}
+}
+
+// longestSongSeconds 用于判断没有注明单位的时长是否以毫秒记录。
+const longestSongSeconds = 10000
+
+// parseImportDuration 解析时长并返回秒数，支持 "215"、"3:35"、"1:02:03"，以及以毫秒记录的数值。
+// 没有注明单位的数值超过 longestSongSeconds 时视为毫秒。无法解析时返回 0。
+func parseImportDuration(value string, ms bool) int {
+	if strings.Contains(value, ":") {
+		seconds := 0.0
+		for _, part := range strings.Split(value, ":") {
+			n, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil || n < 0 {
+				return 0
+			}
+			seconds = seconds*60 + n
+		}
+		return int(math.Round(seconds))
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n <= 0 {
+		return 0
+	}
+	if ms || n > longestSongSeconds {
+		n /= 1000
+	}
+	return int(math.Round(n))
+}
+
+// parseCSV 解析第一行为表头的 CSV，分隔符可以是逗号、分号或制表符。没有标题列时返回错误。
+func (p *PlaylistImport) parseCSV(text string) error {
+	header, _, _ := strings.Cut(text, "\n")
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.Comma = ','
+	for _, comma := range []rune{';', '\t'} {
+		if strings.Count(header, string(comma)) > strings.Count(header, string(reader.Comma)) {
+			reader.Comma = comma
+		}
+	}
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	columns, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("读取 CSV 表头失败: %w", err)
+	}
+	fields := make([]importField, len(columns))
+	hasTitle := false
+	for i, column := range columns {
+		fields[i] = lookupImportField(column)
+		hasTitle = hasTitle || fields[i] == importFieldTitle
+	}
+	if !hasTitle {
+		return errors.New("CSV 中没有标题列")
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("解析 CSV 失败: %w", err)
+		}
+		var entry ImportEntry
+		for i, value := range record {
+			if i < len(fields) {
+				entry.set(fields[i], value)
+			}
+		}
+		if entry.Title != "" {
+			p.Entries = append(p.Entries, entry)
+		}
+	}
+	return nil
+}
+
+// parseJSON 解析 JSON 格式的播放列表。
+func (p *PlaylistImport) parseJSON(data []byte) error {
+	var doc any
+	if err := json.Unmarshal(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), &doc); err != nil {
+		return fmt.Errorf("解析 JSON 失败: %w", err)
+	}
+
+	items, ok := doc.([]any)
+	if object, isObject := doc.(map[string]any); isObject {
+		for key, value := range object {
+			switch strings.ToLower(key) {
+			case "name", "title":
+				p.Name, _ = value.(string)
+			case "tracks", "items", "songs":
+				items, ok = value.([]any)
+			}
+		}
+	}
+	if !ok {
+		return errors.New("JSON 中没有歌曲列表")
+	}
+
+	for _, item := range items {
+		object, isObject := item.(map[string]any)
+		if !isObject {
+			continue
+		}
+		// 流媒体服务的接口导出中歌曲信息位于 track 字段
+		if track, isObject := object["track"].(map[string]any); isObject {
+			object = track
+		}
+		var entry ImportEntry
+		for key, value := range object {
+			field := lookupImportField(key)
+			if field == importFieldNone {
+				continue
+			}
+			if number, isNumber := value.(float64); isNumber && (field == importFieldDuration || field == importFieldDurationMS) {
+				value = strconv.FormatFloat(number, 'f', -1, 64)
+			}
+			entry.set(field, jsonText(value))
+		}
+		if entry.Title != "" || entry.Location != "" || entry.SongID != "" {
+			p.Entries = append(p.Entries, entry)
+		}
+	}
+	return nil
+}
+
+// jsonText 将 JSON 值转换为文本：对象取 name 字段，数组中的各项用 ", " 连接。
+func jsonText(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case map[string]any:
+		return jsonText(v["name"])
+	case []any:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			if text := jsonText(item); text != "" {
+				parts = append(parts, text)
+			}
+		}
+		return strings.Join(parts, ", ")
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePlaylistImport_M3U(t *testing.T) {
+	data := "#EXTM3U\n#PLAYLIST:Road Trip\n#EXTINF:215,Artist - Song\nmusic/song.mp3\nC:\\Music\\Other Song.flac\n"
+	playlist, err := ParsePlaylistImport("trip.m3u8", "", []byte(data))
+	require.NoError(t, err)
+
+	assert.Equal(t, "Road Trip", playlist.Name)
+	assert.Equal(t, []ImportEntry{
+		{Position: 1, Title: "Song", Artist: "Artist", Duration: 215, Location: "music/song.mp3"},
+		{Position: 2, Title: "Other Song", Location: `C:\Music\Other Song.flac`},
+	}, playlist.Entries)
+
+	// 没有 #PLAYLIST 时使用文件名
+	playlist, err = ParsePlaylistImport("Favourites.m3u", "", []byte("a.mp3\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "Favourites", playlist.Name)
+}
+
+func TestParsePlaylistImport_CSV(t *testing.T) {
+	// 流媒体服务导出工具常见的列名
+	data := "\ufeff\"Track URI\",\"Track Name\",\"Artist Name(s)\",\"Album Name\",\"Duration (ms)\"\n" +
+		"spotify:track:1,\"Song, Part 1\",\"Artist A, Artist B\",Album,215400\n" +
+		"spotify:track:2,,Nobody,,1000\n"
+	playlist, err := ParsePlaylistImport("Liked Songs.csv", "", []byte(data))
+	require.NoError(t, err)
+
+	assert.Equal(t, "Liked Songs", playlist.Name)
+	assert.Equal(t, PlaylistFormatCSV, playlist.Format)
+	assert.Equal(t, []ImportEntry{
+		{Position: 1, Title: "Song, Part 1", Artist: "Artist A, Artist B", Album: "Album", Duration: 215},
+	}, playlist.Entries)
+
+	// 分号分隔，时长为 m:ss
+	playlist, err = ParsePlaylistImport("list.txt", PlaylistFormatCSV, []byte("title;artist;length\nSong;Artist;3:35\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []ImportEntry{{Position: 1, Title: "Song", Artist: "Artist", Duration: 215}}, playlist.Entries)
+
+	_, err = ParsePlaylistImport("list.csv", "", []byte("a,b\n1,2\n"))
+	assert.Error(t, err)
+}
+
+func TestParsePlaylistImport_JSON(t *testing.T) {
+	// 本服务导出的格式
+	exported := `{"name":"Mix","tracks":[{"id":"0123456789abcdef0123456789abcdef","title":"Song","artist":"Artist","duration":215,"location":"a/song.mp3"}]}`
+	playlist, err := ParsePlaylistImport("mix.json", "", []byte(exported))
+	require.NoError(t, err)
+	assert.Equal(t, "Mix", playlist.Name)
+	assert.Equal(t, []ImportEntry{
+		{Position: 1, Title: "Song", Artist: "Artist", Duration: 215, Location: "a/song.mp3", SongID: "0123456789abcdef0123456789abcdef"},
+	}, playlist.Entries)
+
+	// 流媒体服务接口的导出：歌曲位于 track 字段，艺术家和专辑是对象
+	api := `{"items":[{"added_at":"2024-01-01","track":{"name":"Song","artists":[{"name":"A"},{"name":"B"}],"album":{"name":"Album"},"duration_ms":215000}}]}`
+	playlist, err = ParsePlaylistImport("export.json", "", []byte(api))
+	require.NoError(t, err)
+	assert.Equal(t, "export", playlist.Name)
+	assert.Equal(t, []ImportEntry{{Position: 1, Title: "Song", Artist: "A, B", Album: "Album", Duration: 215}}, playlist.Entries)
+
+	// 条目数组，没有注明单位的较大时长视为毫秒
+	playlist, err = ParsePlaylistImport("list.json", "", []byte(`[{"trackName":"Song","artistName":"Artist","duration":215000}]`))
+	require.NoError(t, err)
+	assert.Equal(t, []ImportEntry{{Position: 1, Title: "Song", Artist: "Artist", Duration: 215}}, playlist.Entries)
+
+	_, err = ParsePlaylistImport("list.json", "", []byte(`{"name":"empty"}`))
+	assert.Error(t, err)
+	_, err = ParsePlaylistImport("list.json", "", []byte(`[]`))
+	assert.Error(t, err)
+	_, err = ParsePlaylistImport("list.txt", "", []byte("a"))
+	assert.Error(t, err)
+}
+
+func TestParseImportDuration(t *testing.T) {
+	testCases := []struct {
+		value    string
+		ms       bool
+		expected int
+	}{
+		{"215", false, 215},
+		{"215.6", false, 216},
+		{"3:35", false, 215},
+		{"1:02:03", false, 3723},
+		{"215000", true, 215},
+		{"215000", false, 215},
+		{"", false, 0},
+		{"abc", false, 0},
+		{"3:xx", false, 0},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, parseImportDuration(tc.value, tc.ms), tc.value)
+	}
+}
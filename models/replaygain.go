@@ -0,0 +1,174 @@
+package models
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/dhowden/tag"
+)
+
+const (
+	// LoudnessSourceReplayGain 表示音量信息来自 REPLAYGAIN_* 标签（ID3 TXXX、Vorbis 注释、MP4 自定义字段）
+	LoudnessSourceReplayGain = "replaygain"
+	// LoudnessSourceR128 表示音量信息来自 Opus 的 R128_TRACK_GAIN / R128_ALBUM_GAIN 标签
+	LoudnessSourceR128 = "r128"
+	// LoudnessSourceITunes 表示音量信息来自 iTunes 音量平衡（iTunNORM）
+	LoudnessSourceITunes = "itunes"
+	// LoudnessSourceAnalysis 表示音量信息由服务器按 EBU R128 分析音频得出
+	LoudnessSourceAnalysis = "analysis"
+
+	// ReplayGainReferenceLUFS 是 ReplayGain 2.0 的参考响度，增益表示把歌曲调整到该响度所需的分贝数
+	ReplayGainReferenceLUFS = -18.0
+	// r128ReferenceLUFS 是 Opus R128 增益标签的参考响度
+	r128ReferenceLUFS = -23.0
+)
+
+// ReplayGain 是用于音量平衡的增益和峰值信息，未知的字段为 nil。
+type ReplayGain struct {
+	// TrackGain 是单曲增益（dB）。
+	TrackGain *float64 `json:"track_gain,omitempty"`
+	// TrackPeak 是单曲的采样峰值，1.0 表示满幅。
+	TrackPeak *float64 `json:"track_peak,omitempty"`
+	// AlbumGain 是专辑增益（dB）。
+	AlbumGain *float64 `json:"album_gain,omitempty"`
+	// AlbumPeak 是专辑的采样峰值。
+	AlbumPeak *float64 `json:"album_peak,omitempty"`
+	// Loudness 是 EBU R128 综合响度（LUFS），仅由服务器分析得出时存在。
+	Loudness *float64 `json:"loudness,omitempty"`
+	// Source 是音量信息的来源（replaygain、r128、itunes 或 analysis）。
+	Source string `json:"source"`
+}
+
+// loudnessTagNames 是读取音量信息时关心的标签名（小写）。
+var loudnessTagNames = map[string]bool{
+	"replaygain_track_gain": true,
+	"replaygain_track_peak": true,
+	"replaygain_album_gain": true,
+	"replaygain_album_peak": true,
+	"r128_track_gain":       true,
+	"r128_album_gain":       true,
+	"itunnorm":              true,
+}
+
+// loudnessTags 从原始标签中收集音量相关的字段，返回小写标签名 -> 值。
+// ID3 的 REPLAYGAIN_* 存放在 TXXX 帧的描述中，iTunNORM 存放在 COMM 帧的描述中；
+// Vorbis 注释和 MP4 自定义字段以标签名为键。
+func loudnessTags(metadata tag.Metadata) map[string]string {
+	values := make(map[string]string)
+	for key, value := range metadata.Raw() {
+		name, text := strings.ToLower(key), ""
+		switch v := value.(type) {
+		case *tag.Comm:
+			if !strings.HasPrefix(key, "TXX") && !strings.HasPrefix(key, "COM") {
+				continue
+			}
+			name, text = strings.ToLower(v.Description), v.Text
+		case string:
+			text = v
+		default:
+			continue
+		}
+		if loudnessTagNames[name] {
+			values[name] = strings.TrimSpace(text)
+		}
+	}
+	return values
+}
+
+// ReplayGainFromTags 从标签中读取音量信息，按 REPLAYGAIN_*、Opus R128、iTunNORM 的优先级选择来源。
+// 没有可用的音量信息时返回 nil。
+func ReplayGainFromTags(metadata tag.Metadata) *ReplayGain {
+	values := loudnessTags(metadata)
+	if len(values) == 0 {
+		return nil
+	}
+
+	rg := &ReplayGain{
+		Source:    LoudnessSourceReplayGain,
+		TrackGain: parseGainValue(values["replaygain_track_gain"]),
+		TrackPeak: parsePeakValue(values["replaygain_track_peak"]),
+		AlbumGain: parseGainValue(values["replaygain_album_gain"]),
+		AlbumPeak: parsePeakValue(values["replaygain_album_peak"]),
+	}
+	if rg.TrackGain != nil || rg.AlbumGain != nil {
+		return rg
+	}
+
+	rg = &ReplayGain{
+		Source:    LoudnessSourceR128,
+		TrackGain: parseR128Gain(values["r128_track_gain"]),
+		AlbumGain: parseR128Gain(values["r128_album_gain"]),
+	}
+	if rg.TrackGain != nil || rg.AlbumGain != nil {
+		return rg
+	}
+
+	if gain := parseITunNORM(values["itunnorm"]); gain != nil {
+		return &ReplayGain{Source: LoudnessSourceITunes, TrackGain: gain}
+	}
+	return nil
+}
+
+// parseGainValue 解析 "-6.52 dB" 形式的增益，超出 ±64 dB 的值视为无效。
+func parseGainValue(text string) *float64 {
+	text = strings.TrimSpace(text)
+	if len(text) >= 2 && strings.EqualFold(text[len(text)-2:], "db") {
+		text = strings.TrimSpace(text[:len(text)-2])
+	}
+	gain, err := strconv.ParseFloat(text, 64)
+	if err != nil || math.IsNaN(gain) || math.Abs(gain) > 64 {
+		return nil
+	}
+	return &gain
+}
+
+// parsePeakValue 解析线性峰值，负数或超过 10（约 +20 dBFS）的值视为无效。
+func parsePeakValue(text string) *float64 {
+	peak, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	if err != nil || math.IsNaN(peak) || peak < 0 || peak > 10 {
+		return nil
+	}
+	return &peak
+}
+
+// parseR128Gain 解析 Opus 的 R128 增益：以 1/256 dB 为单位的整数，参考响度为 -23 LUFS，
+// 换算为以 -18 LUFS 为参考的 ReplayGain 增益。
+func parseR128Gain(text string) *float64 {
+	value, err := strconv.ParseInt(strings.TrimSpace(text), 10, 16)
+	if err != nil {
+		return nil
+	}
+	gain := roundGain(float64(value)/256 + ReplayGainReferenceLUFS - r128ReferenceLUFS)
+	return &gain
+}
+
+// parseITunNORM 解析 iTunes 音量平衡信息。iTunNORM 由 10 个十六进制数组成，
+// 前两个是左右声道以 1/1000 W 为单位的调整量，增益取两者中更安静（数值更大）的一个。
+func parseITunNORM(text string) *float64 {
+	fields := strings.Fields(text)
+	if len(fields) < 2 {
+		return nil
+	}
+	var adjustment uint64
+	for _, field := range fields[:2] {
+		value, err := strconv.ParseUint(field, 16, 32)
+		if err != nil {
+			return nil
+		}
+		adjustment = max(adjustment, value)
+	}
+	if adjustment == 0 {
+		return nil
+	}
+	gain := roundGain(-10 * math.Log10(float64(adjustment)/1000))
+	if math.Abs(gain) > 64 {
+		return nil
+	}
+	return &gain
+}
+
+// roundGain 将增益保留两位小数，与常见的 ReplayGain 标签格式一致。
+func roundGain(gain float64) float64 {
+	return math.Round(gain*100) / 100
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/dhowden/tag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rawMetadata 是只提供原始标签的 tag.Metadata。
+type rawMetadata struct {
+	tag.Metadata
+	raw map[string]interface{}
+}
+
+func (m rawMetadata) Raw() map[string]interface{} { return m.raw }
+
+func TestReplayGainFromTags(t *testing.T) {
+	// ID3：REPLAYGAIN_* 存放在 TXXX 帧中，同时存在的 iTunNORM 被忽略
+	rg := ReplayGainFromTags(rawMetadata{raw: map[string]interface{}{
+		"TXXX":   &tag.Comm{Description: "REPLAYGAIN_TRACK_GAIN", Text: "-6.52 dB"},
+		"TXXX_0": &tag.Comm{Description: "replaygain_track_peak", Text: "0.988553"},
+		"TXXX_1": &tag.Comm{Description: "REPLAYGAIN_ALBUM_GAIN", Text: "+1.20 dB"},
+		"COMM":   &tag.Comm{Description: "iTunNORM", Text: " 00000400 00000400 00000000 00000000"},
+		"TIT2":   "Title",
+	}})
+	require.NotNil(t, rg)
+	assert.Equal(t, LoudnessSourceReplayGain, rg.Source)
+	assert.Equal(t, -6.52, *rg.TrackGain)
+	assert.Equal(t, 0.988553, *rg.TrackPeak)
+	assert.Equal(t, 1.2, *rg.AlbumGain)
+	assert.Nil(t, rg.AlbumPeak)
+
+	// Vorbis 注释的键为小写
+	rg = ReplayGainFromTags(rawMetadata{raw: map[string]interface{}{
+		"replaygain_album_gain": "-3.1",
+		"replaygain_album_peak": "1.05",
+	}})
+	require.NotNil(t, rg)
+	assert.Nil(t, rg.TrackGain)
+	assert.Equal(t, -3.1, *rg.AlbumGain)
+	assert.Equal(t, 1.05, *rg.AlbumPeak)
+
+	// Opus R128 增益以 -23 LUFS 为参考，换算后加 5 dB
+	rg = ReplayGainFromTags(rawMetadata{raw: map[string]interface{}{"r128_track_gain": "-1280"}})
+	require.NotNil(t, rg)
+	assert.Equal(t, LoudnessSourceR128, rg.Source)
+	assert.Equal(t, 0.0, *rg.TrackGain)
+
+	// iTunNORM 取两个声道中调整量更大的一个：2000/1000 W 对应 -3.01 dB
+	rg = ReplayGainFromTags(rawMetadata{raw: map[string]interface{}{
+		"COMM": &tag.Comm{Description: "iTunNORM", Text: " 000003E8 000007D0 00000000"},
+	}})
+	require.NotNil(t, rg)
+	assert.Equal(t, LoudnessSourceITunes, rg.Source)
+	assert.Equal(t, -3.01, *rg.TrackGain)
+
+	// 无效的值不构成音量信息
+	assert.Nil(t, ReplayGainFromTags(rawMetadata{raw: map[string]interface{}{
+		"replaygain_track_gain": "loud",
+		"itunnorm":              "zzzz",
+	}}))
+	assert.Nil(t, ReplayGainFromTags(rawMetadata{raw: map[string]interface{}{"TIT2": "Title"}}))
+}
+
+func TestParseGainAndPeak(t *testing.T) {
+	assert.Equal(t, -6.52, *parseGainValue("-6.52 dB"))
+	assert.Equal(t, 2.0, *parseGainValue("+2DB"))
+	assert.Nil(t, parseGainValue("-100 dB"))
+	assert.Nil(t, parseGainValue(""))
+	assert.Equal(t, 0.5, *parsePeakValue(" 0.5 "))
+	assert.Nil(t, parsePeakValue("-0.1"))
+	assert.Nil(t, parseR128Gain("40000")) // 超出 16 位
+}
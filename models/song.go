@@ -3,14 +3,16 @@ package models
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/dhowden/tag"
-	"github.com/tcolgate/mp3"
 )
 
 const (
@@ -18,40 +20,106 @@ const (
 	SongIDBytes = 16
 	// SongIDHexLength 是歌曲 ID 的十六进制字符长度（16 字节 = 32 个十六进制字符）
 	SongIDHexLength = SongIDBytes * 2
+	// DefaultLibraryName 是只配置了单个音乐目录时使用的音乐库名称
+	DefaultLibraryName = "default"
+
+	// CoverSourceEmbedded 表示封面嵌入在音频文件的标签中
+	CoverSourceEmbedded = "embedded"
+	// CoverSourceSidecar 表示封面来自歌曲所在目录中的图片文件（如 cover.jpg）
+	CoverSourceSidecar = "sidecar"
+	// CoverSourceOverride 表示封面来自管理员保存在数据库中的元数据覆盖
+	CoverSourceOverride = "override"
 )
 
 // Song 定义了歌曲的基本信息结构。
 type Song struct {
-	// ID 是歌曲的唯一标识符，通过文件路径的 SHA256 哈希生成。
+	// ID 是歌曲的唯一标识符，由扫描器根据库内相对路径的 SHA256 哈希生成，
+	// 因此移动或重新挂载整个音乐目录不会改变 ID。
 	ID string `json:"id"`
 	// Title 是歌曲的标题，通常从文件名中提取。
 	Title string `json:"title"`
 	// Artist 是歌曲的艺术家，默认为 "Unknown"。
 	Artist string `json:"artist"`
+	// Artists 是按分隔符拆分艺术家标签得到的各位艺术家（如 "A feat. B" 拆分为 A 和 B），由扫描器填充。
+	Artists []string `json:"artists,omitempty"`
+	// AlbumArtist 是专辑艺术家（ID3 TPE2、Vorbis ALBUMARTIST、MP4 aART），没有该标签时为空。
+	AlbumArtist string `json:"album_artist,omitempty"`
+	// Composer 是作曲者。
+	Composer string `json:"composer,omitempty"`
 	// Album 是歌曲所属的专辑，默认为 "Unknown"。
 	Album string `json:"album"`
 	// Duration 是歌曲的时长（以秒为单位），默认为 0。
 	Duration int `json:"duration"`
+	// DurationEstimated 标识时长是否由文件大小估算得出（无法从文件头读取精确时长时）。
+	DurationEstimated bool `json:"duration_estimated"`
 	// DurationFormatted 是格式化后的时长字符串（如 "3:45"）。
 	DurationFormatted string `json:"duration_formatted"`
 	// FilePath 是歌曲文件的绝对路径。
 	FilePath string `json:"file_path"`
 	// FileName 是歌曲的文件名。
 	FileName string `json:"file_name"`
+	// Library 是歌曲所属音乐库的名称。
+	Library string `json:"library"`
+	// RelPath 是歌曲文件相对于音乐库根目录的路径（使用 / 分隔）。
+	RelPath string `json:"rel_path,omitempty"`
+	// ContentHash 是文件内容的抽样指纹，用于识别被重命名或移动的文件。
+	ContentHash string `json:"content_hash,omitempty"`
 	// FileSize 是歌曲文件的大小（以字节为单位）。
 	FileSize int64 `json:"file_size"`
 	// AddedAt 是歌曲文件最后修改的时间。
 	AddedAt time.Time `json:"added_at"`
 	// Format 是音频文件的格式/扩展名（如 .mp3, .flac）。
 	Format string `json:"format"`
-	// HasCover 标识该歌曲是否有嵌入的封面图片。
+	// Codec 是音频编码（如 mp3、flac、aac、alac、pcm），无法识别时为空。
+	Codec string `json:"codec,omitempty"`
+	// Lossless 标识音频是否为无损编码。
+	Lossless bool `json:"lossless"`
+	// Bitrate 是比特率（kbps），可变比特率编码为平均值。
+	Bitrate int `json:"bitrate,omitempty"`
+	// BitrateMode 是比特率模式（cbr 或 vbr），无法判断时为空。
+	BitrateMode string `json:"bitrate_mode,omitempty"`
+	// SampleRate 是采样率（Hz）。
+	SampleRate int `json:"sample_rate,omitempty"`
+	// BitDepth 是采样位深，仅对无损编码有意义。
+	BitDepth int `json:"bit_depth,omitempty"`
+	// Channels 是声道数。
+	Channels int `json:"channels,omitempty"`
+	// ReplayGain 是用于音量平衡的增益和峰值，来自标签或服务器的响度分析，没有时为 nil。
+	ReplayGain *ReplayGain `json:"replay_gain,omitempty"`
+	// HasCover 标识该歌曲是否有封面图片（嵌入的封面或目录中的封面文件）。
 	HasCover bool `json:"has_cover"`
+	// CoverSource 是封面的来源（embedded、sidecar 或 override），没有封面时为空。
+	CoverSource string `json:"cover_source,omitempty"`
+	// CoverPath 是目录中封面文件的路径，仅在封面来源为 sidecar 时有效。
+	CoverPath string `json:"-"`
+	// CoverVersion 是覆盖封面内容的哈希，仅在封面来源为 override 时有效。
+	CoverVersion string `json:"-"`
+	// ArtistImagePath 是艺术家目录中艺术家图片（如 artist.jpg）的路径，没有时为空。
+	ArtistImagePath string `json:"-"`
+	// HasLyrics 标识该歌曲是否有歌词（同名 .lrc 文件或嵌入的歌词标签）。
+	HasLyrics bool `json:"has_lyrics"`
+	// EmbeddedLyrics 标识音频文件的标签中是否嵌入了歌词（USLT、SYLT 等）。
+	EmbeddedLyrics bool `json:"-"`
+	// LyricsPath 是与音频文件同名的 .lrc 歌词文件的路径，没有时为空。
+	LyricsPath string `json:"-"`
 	// Year 是歌曲的发行年份。
 	Year int `json:"year,omitempty"`
 	// Track 是歌曲在专辑中的曲目编号。
 	Track int `json:"track,omitempty"`
+	// Disc 是歌曲所在的碟片编号。
+	Disc int `json:"disc,omitempty"`
 	// Genre 是歌曲的流派。
 	Genre string `json:"genre,omitempty"`
+	// CueSheetPath 是描述该曲目的 CUE 文件路径。不为空时歌曲是整轨文件 FilePath 中的一段。
+	CueSheetPath string `json:"-"`
+	// StartMs 是 CUE 曲目在整轨文件中的起始位置（毫秒）。
+	StartMs int64 `json:"start_ms,omitempty"`
+	// EndMs 是 CUE 曲目在整轨文件中的结束位置（毫秒），为 0 时到文件结尾。
+	EndMs int64 `json:"end_ms,omitempty"`
+	// Issues 是解析文件时发现的问题（标签损坏、缺少标签、无法识别音频流等），由歌曲库健康报告使用。
+	Issues []SongIssue `json:"-"`
+	// Overridden 是被管理员的元数据覆盖替换的字段（如 title、cover），由扫描器在返回歌曲时填充，不持久化。
+	Overridden []string `json:"overridden,omitempty"`
 }
 
 // NewSong 根据给定的文件路径和文件大小创建一个新的 Song 实例。
@@ -90,18 +158,31 @@ func NewSong(filePath string, fileSize int64) *Song {
 	return song
 }
 
-// UpdateMetadata 尝试从文件中读取 ID3 标签等元数据并更新歌曲信息。
-func (s *Song) UpdateMetadata() {
+// UpdateMetadata 尝试从文件中读取 ID3 标签等元数据并更新歌曲信息，并解析歌曲时长。
+// 文件没有标签不视为错误；文件无法打开或标签无法解析时返回错误，歌曲保留原有信息。
+func (s *Song) UpdateMetadata() error {
+	s.Issues = nil
 	file, err := os.Open(s.FilePath)
 	if err != nil {
-		return
+		issue := FileIssue(err)
+		s.addIssue(issue.Kind, issue.Message)
+		return fmt.Errorf("打开文件失败: %w", err)
 	}
 	defer file.Close()
 
+	// 时长等音频属性来自音频流本身，与标签是否存在无关
+	defer s.parseAudioProperties()
+
 	metadata, metaErr := tag.ReadFrom(file)
+	if errors.Is(metaErr, tag.ErrNoTagsFound) {
+		s.checkMissingTags("", "", "")
+		return nil
+	}
 	if metaErr != nil {
-		return
+		s.addIssue(IssueTagError, metaErr.Error())
+		return fmt.Errorf("读取标签失败: %w", metaErr)
 	}
+	s.checkMissingTags(metadata.Title(), metadata.Artist(), metadata.Album())
 
 	if metadata.Title() != "" {
 		s.Title = metadata.Title()
@@ -112,6 +193,8 @@ func (s *Song) UpdateMetadata() {
 	if metadata.Album() != "" {
 		s.Album = metadata.Album()
 	}
+	s.AlbumArtist = strings.TrimSpace(metadata.AlbumArtist())
+	s.Composer = strings.TrimSpace(metadata.Composer())
 	if metadata.Genre() != "" {
 		s.Genre = metadata.Genre()
 	}
@@ -122,55 +205,124 @@ func (s *Song) UpdateMetadata() {
 	if track != 0 {
 		s.Track = track
 	}
+	disc, _ := metadata.Disc()
+	if disc != 0 {
+		s.Disc = disc
+	}
+
+	s.ReplayGain = ReplayGainFromTags(metadata)
 
-	// 检查是否有封面
-	s.HasCover = metadata.Picture() != nil
+	// 检查是否有嵌入的封面，目录中的封面文件由扫描器另行查找
+	if metadata.Picture() != nil {
+		s.HasCover = true
+		s.CoverSource = CoverSourceEmbedded
+		s.CoverPath = ""
+	}
 
-	// 解析时长
-	s.parseDuration()
+	// 检查是否有嵌入的歌词，同名的 .lrc 文件由扫描器另行查找
+	s.EmbeddedLyrics = HasEmbeddedLyrics(metadata)
+	s.HasLyrics = s.EmbeddedLyrics || s.LyricsPath != ""
+	return nil
 }
 
-// parseDuration 解析音频文件的时长
-func (s *Song) parseDuration() {
-	// 对于 MP3 文件使用 mp3 库解析
-	if s.Format == ".mp3" {
-		s.Duration = s.parseMP3Duration()
-	} else {
-		// 对于其他格式，尝试根据文件大小和比特率估算
-		s.Duration = s.estimateDuration()
+// ArtistNames 返回歌曲的所有艺术家。尚未拆分艺术家标签的记录返回 Artist 本身。
+func (s *Song) ArtistNames() []string {
+	if len(s.Artists) > 0 {
+		return s.Artists
+	}
+	if s.Artist == "" {
+		return nil
 	}
+	return []string{s.Artist}
+}
 
+// AlbumArtistName 返回用于专辑分组的艺术家：优先使用专辑艺术家，没有时使用第一位艺术家，
+// 使 "A" 和 "A feat. B" 的曲目归入同一张专辑。
+func (s *Song) AlbumArtistName() string {
+	if s.AlbumArtist != "" {
+		return s.AlbumArtist
+	}
+	if names := s.ArtistNames(); len(names) > 0 {
+		return names[0]
+	}
+	return ""
+}
+
+// parseAudioProperties 解析音频文件的时长和技术属性。
+// 优先读取容器或流头部记录的精确时长，无法读取时才根据文件大小估算，并将 DurationEstimated 置为 true。
+func (s *Song) parseAudioProperties() {
+	s.clearIssues(IssueUnsupportedCodec, IssueZeroDuration)
+	info, err := s.readAudioInfo()
+	if err != nil {
+		s.addIssue(IssueUnsupportedCodec, err.Error())
+		info = &audioInfo{}
+	}
+
+	s.Codec = info.Codec
+	s.Lossless = IsLosslessCodec(info.Codec)
+	s.BitrateMode = info.BitrateMode
+	s.SampleRate = info.SampleRate
+	s.BitDepth = info.BitDepth
+	s.Channels = info.Channels
+	s.Bitrate = info.Bitrate
+
+	s.DurationEstimated = info.Duration <= 0
+	if s.DurationEstimated {
+		s.Duration = s.estimateDuration()
+	} else {
+		s.Duration = int(info.Duration.Seconds())
+		if s.Bitrate == 0 && s.FileSize > 0 {
+			// 压缩格式没有固定的比特率，使用整个文件的平均比特率
+			s.Bitrate = int(math.Round(float64(s.FileSize) * 8 / info.Duration.Seconds() / 1000))
+		}
+	}
 	s.DurationFormatted = FormatDuration(s.Duration)
+	if s.Duration == 0 {
+		s.addIssue(IssueZeroDuration, "")
+	}
 }
 
-// parseMP3Duration 解析 MP3 文件的时长
-func (s *Song) parseMP3Duration() int {
+// readAudioInfo 使用与文件格式对应的解析器读取音频属性，不支持的格式或解析失败时返回错误。
+func (s *Song) readAudioInfo() (*audioInfo, error) {
+	parse, ok := audioParsers[s.Format]
+	if !ok {
+		return nil, fmt.Errorf("不支持解析 %s 格式的音频流", s.Format)
+	}
+
 	file, err := os.Open(s.FilePath)
 	if err != nil {
-		return 0
+		return nil, err
 	}
 	defer file.Close()
 
-	decoder := mp3.NewDecoder(file)
-	var frame mp3.Frame
-	var totalDuration time.Duration
-	skipped := 0
-
-	for {
-		if err := decoder.Decode(&frame, &skipped); err != nil {
-			break
-		}
-		totalDuration += frame.Duration()
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
 	}
+	info, err := parse(file, stat.Size())
+	if err != nil {
+		return nil, fmt.Errorf("解析音频流失败: %w", err)
+	}
+	return info, nil
+}
 
-	return int(totalDuration.Seconds())
+// checkMissingTags 根据标签中的标题、艺术家和专辑记录缺少的标签。
+func (s *Song) checkMissingTags(title, artist, album string) {
+	s.clearIssues(IssueMissingTitle, IssueMissingArtist, IssueMissingAlbum)
+	if strings.TrimSpace(title) == "" {
+		s.addIssue(IssueMissingTitle, "")
+	}
+	if strings.TrimSpace(artist) == "" {
+		s.addIssue(IssueMissingArtist, "")
+	}
+	if strings.TrimSpace(album) == "" {
+		s.addIssue(IssueMissingAlbum, "")
+	}
 }
 
-// estimateDuration 根据文件大小估算时长（用于非 MP3 格式）
+// estimateDuration 根据文件大小和格式的典型比特率估算时长，仅在无法读取精确时长时使用。
 func (s *Song) estimateDuration() int {
-	// 假设平均比特率为 256 kbps
 	// 时长（秒）= 文件大小（字节）/ (比特率 / 8)
-	// 对于 256 kbps: 时长 = 文件大小 / 32000
 	if s.FileSize == 0 {
 		return 0
 	}
@@ -180,8 +332,8 @@ func (s *Song) estimateDuration() int {
 	case ".flac":
 		// FLAC 通常是 800-1200 kbps
 		bytesPerSecond = 125000 // ~1000 kbps
-	case ".wav":
-		// WAV 16bit 44.1kHz 立体声
+	case ".wav", ".aif", ".aiff", ".aifc":
+		// 16bit 44.1kHz 立体声
 		bytesPerSecond = 176400
 	case ".m4a", ".aac":
 		// AAC 通常是 128-256 kbps
@@ -220,6 +372,84 @@ func generateID(filePath string) string {
 	return hex.EncodeToString(hash[:SongIDBytes])
 }
 
+// LibraryRelPath 返回 filePath 相对于音乐库根目录 root 的路径，统一使用 / 分隔，
+// 使同一文件在不同操作系统或挂载点下得到相同的结果。无法计算相对路径时返回 filePath 本身。
+func LibraryRelPath(root, filePath string) string {
+	rel, err := filepath.Rel(root, filePath)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(filePath)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// LibrarySongID 根据音乐库名称和库内相对路径生成歌曲 ID。
+// 默认库的 ID 只由相对路径决定，单目录配置改为多库配置后默认库中的歌曲 ID 保持不变；
+// 其他库的 ID 包含库名，不同库中相同的相对路径不会冲突。
+func LibrarySongID(library, relPath string) string {
+	if library == "" || library == DefaultLibraryName {
+		return generateID(relPath)
+	}
+	return generateID(library + ":" + relPath)
+}
+
+// LibraryRelPath 返回带音乐库名称的相对路径：默认库中的歌曲为库内相对路径，其他库中的歌曲加上 "库名/" 前缀，
+// 使导出的播放列表能够区分不同库中相同的相对路径。
+func (s *Song) LibraryRelPath() string {
+	if s.Library == "" || s.Library == DefaultLibraryName {
+		return s.RelPath
+	}
+	return s.Library + "/" + s.RelPath
+}
+
+// contentHashSampleSize 是计算内容指纹时从文件头、中、尾各读取的字节数。
+const contentHashSampleSize = 64 * 1024
+
+// UpdateContentHash 计算文件内容的抽样指纹（文件大小加上头、中、尾三段数据的 SHA256）。
+// 抽样避免了对整个大文件求哈希，同时足以识别被原样移动或重命名的文件。
+func (s *Song) UpdateContentHash() {
+	file, err := os.Open(s.FilePath)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return
+	}
+	size := info.Size()
+
+	hasher := sha256.New()
+	fmt.Fprintf(hasher, "%d:", size)
+	buf := make([]byte, contentHashSampleSize)
+	for _, offset := range []int64{0, size/2 - contentHashSampleSize/2, size - contentHashSampleSize} {
+		if offset < 0 {
+			offset = 0
+		}
+		n, err := file.ReadAt(buf, offset)
+		if err != nil && err != io.EOF {
+			return
+		}
+		hasher.Write(buf[:n])
+	}
+	s.ContentHash = hex.EncodeToString(hasher.Sum(nil))
+}
+
+// FileHash 计算文件完整内容的 SHA256，用于确认抽样指纹相同的文件是否逐字节相同。
+func FileHash(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
 // ValidIDPattern 返回用于验证歌曲 ID 格式的正则表达式字符串
 // ID 应为 32 个十六进制字符（16 字节的十六进制编码）
 func ValidIDPattern() string {
@@ -0,0 +1,326 @@
+package models
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// 导入条目的匹配结果。
+const (
+	// MatchStatusMatched 表示条目对应到了唯一的歌曲。
+	MatchStatusMatched = "matched"
+	// MatchStatusAmbiguous 表示有多首得分接近的歌曲，需要用户自己选择。
+	MatchStatusAmbiguous = "ambiguous"
+	// MatchStatusMissing 表示歌曲库中没有对应的歌曲。
+	MatchStatusMissing = "missing"
+)
+
+// DefaultMatchDurationTolerance 是按元数据匹配时默认允许的时长差（秒）。
+// 流媒体服务和本地文件的时长常因编码器延迟和首尾静音相差几秒。
+const DefaultMatchDurationTolerance = 5
+
+const (
+	// minMatchSimilarity 是标题或艺术家被视为相同的最低相似度。
+	minMatchSimilarity = 0.85
+	// ambiguousScoreMargin 是最高分与其他候选的分差小于该值时视为无法区分。
+	ambiguousScoreMargin = 0.05
+	// scoreEpsilon 用于避免浮点误差影响分差的比较。
+	scoreEpsilon = 1e-9
+	// maxMatchCandidates 是结果中最多列出的候选歌曲数量。
+	maxMatchCandidates = 5
+)
+
+// titleDecorationPattern 匹配标题中常见的版本说明，如 "(Remastered 2011)"、"[Live]"、" - 2011 Remaster"。
+var titleDecorationPattern = regexp.MustCompile(`\s*[(\[（【][^)\]）】]*[)\]）】]|\s+-\s+.*$`)
+
+// MatchCandidate 是导入条目的一个候选歌曲。
+type MatchCandidate struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Artist   string `json:"artist"`
+	Album    string `json:"album"`
+	Duration int    `json:"duration"`
+	// Score 是匹配得分，1 表示标题和艺术家完全相同
+	Score float64 `json:"score"`
+}
+
+// SongMatch 是导入条目的匹配结果。
+type SongMatch struct {
+	ImportEntry
+	Status string `json:"status"`
+	// SongID 是匹配到的歌曲，只在 Status 为 matched 时有值
+	SongID string `json:"matched_song_id,omitempty"`
+	// Candidates 是得分最接近的候选歌曲，只在 Status 为 ambiguous 时有值
+	Candidates []MatchCandidate `json:"candidates,omitempty"`
+}
+
+// SongMatcher 将导入的播放列表条目对应到歌曲库中的歌曲。
+type SongMatcher struct {
+	songs     []*Song
+	byID      map[string]*Song
+	byRelPath map[string]*Song // 带音乐库名称的相对路径（见 Song.LibraryRelPath），也包括其他库中歌曲的库内相对路径
+	byPath    map[string]*Song
+	// titles 和 bareTitles 是与 songs 对应的规范化标题，以及去掉版本说明后的标题
+	titles     []string
+	bareTitles []string
+	// titleIndex 是标题中每个词的前几个字符到歌曲下标的索引，模糊匹配只比较与条目标题有相同索引键的歌曲
+	titleIndex map[string][]int
+	// DurationTolerance 是按元数据匹配时允许的时长差（秒）
+	DurationTolerance int
+}
+
+// NewSongMatcher 为 songs 创建 SongMatcher。
+func NewSongMatcher(songs []*Song) *SongMatcher {
+	m := &SongMatcher{
+		songs:             songs,
+		byID:              make(map[string]*Song, len(songs)),
+		byRelPath:         make(map[string]*Song, len(songs)),
+		byPath:            make(map[string]*Song, len(songs)),
+		titles:            make([]string, len(songs)),
+		bareTitles:        make([]string, len(songs)),
+		titleIndex:        make(map[string][]int),
+		DurationTolerance: DefaultMatchDurationTolerance,
+	}
+	// 其他库中歌曲的库内相对路径与带库名的路径冲突时，带库名的路径优先
+	for _, song := range songs {
+		if song.CueSheetPath == "" && song.LibraryRelPath() != song.RelPath {
+			if _, taken := m.byRelPath[strings.ToLower(song.RelPath)]; !taken {
+				m.byRelPath[strings.ToLower(song.RelPath)] = song
+			}
+		}
+	}
+	for i, song := range songs {
+		m.byID[song.ID] = song
+		if song.CueSheetPath == "" {
+			// 整轨文件的路径对应多首歌曲，不能按路径匹配
+			m.byRelPath[strings.ToLower(song.LibraryRelPath())] = song
+			m.byPath[strings.ToLower(song.FilePath)] = song
+		}
+		m.titles[i] = normalizeDuplicateText(song.Title)
+		m.bareTitles[i] = bareTitle(song.Title)
+		for _, key := range titleKeys(m.titles[i]) {
+			m.titleIndex[key] = append(m.titleIndex[key], i)
+		}
+	}
+	return m
+}
+
+// Match 匹配一个条目。先按歌曲 ID、音频流地址和文件路径精确匹配，
+// 都不成功时按标题、艺术家和时长模糊匹配。
+func (m *SongMatcher) Match(entry ImportEntry) SongMatch {
+	match := SongMatch{ImportEntry: entry, Status: MatchStatusMissing}
+	if song := m.matchLocation(entry); song != nil {
+		match.Status = MatchStatusMatched
+		match.SongID = song.ID
+		return match
+	}
+
+	candidates := m.candidates(entry)
+	switch {
+	case len(candidates) == 0:
+	case len(candidates) == 1 || candidates[0].Score-candidates[1].Score >= ambiguousScoreMargin-scoreEpsilon:
+		match.Status = MatchStatusMatched
+		match.SongID = candidates[0].ID
+	default:
+		match.Status = MatchStatusAmbiguous
+		for _, candidate := range candidates[:min(len(candidates), maxMatchCandidates)] {
+			if candidates[0].Score-candidate.Score >= ambiguousScoreMargin-scoreEpsilon {
+				break
+			}
+			match.Candidates = append(match.Candidates, candidate)
+		}
+	}
+	return match
+}
+
+// matchLocation 按歌曲 ID、音频流地址（.../stream/<id>）或文件路径精确匹配。
+// 路径不区分大小写，可以是绝对路径、音乐库内的相对路径或导出时使用的带库名的相对路径；
+// 在其他目录下整理的相对路径按最长的后缀匹配。
+func (m *SongMatcher) matchLocation(entry ImportEntry) *Song {
+	if song := m.byID[entry.SongID]; song != nil {
+		return song
+	}
+	location := strings.TrimSpace(entry.Location)
+	if location == "" {
+		return nil
+	}
+	if u, err := url.Parse(location); err == nil && u.Scheme != "" && len(u.Scheme) > 1 {
+		if !strings.EqualFold(u.Scheme, "file") {
+			return m.byID[path.Base(u.Path)]
+		}
+		location = u.Path
+	}
+
+	location = strings.ToLower(strings.ReplaceAll(location, `\`, "/"))
+	if song := m.byPath[location]; song != nil {
+		return song
+	}
+	location = strings.TrimPrefix(path.Clean("/"+location), "/")
+	for location != "" {
+		if song := m.byRelPath[location]; song != nil {
+			return song
+		}
+		_, location, _ = strings.Cut(location, "/")
+	}
+	return nil
+}
+
+// candidates 返回按得分从高到低排列的候选歌曲。标题相似且艺术家相符（条目或歌曲没有艺术家时不比较）、
+// 时长差在容差以内（任一方没有时长时不比较）的歌曲才是候选。
+func (m *SongMatcher) candidates(entry ImportEntry) []MatchCandidate {
+	title := normalizeDuplicateText(entry.Title)
+	if title == "" {
+		return nil
+	}
+	bare := bareTitle(entry.Title)
+	artist := normalizeDuplicateText(entry.Artist)
+	album := normalizeDuplicateText(entry.Album)
+
+	seen := make(map[int]bool)
+	var candidates []MatchCandidate
+	for _, key := range titleKeys(title) {
+		for _, i := range m.titleIndex[key] {
+			if seen[i] {
+				continue
+			}
+			seen[i] = true
+			song := m.songs[i]
+			if entry.Duration > 0 && song.Duration > 0 && abs(entry.Duration-song.Duration) > m.DurationTolerance {
+				continue
+			}
+
+			var score float64
+			switch {
+			case title == m.titles[i]:
+				score = 1
+			case bare == m.titles[i] || title == m.bareTitles[i]:
+				// 只有一方带版本说明时得分略低，双方的版本说明不同时更低，
+				// 使 "Song" 和 "Song - Remastered" 优先匹配 "Song" 而不是 "Song (Live)"
+				score = 1 - ambiguousScoreMargin
+			case bare != "" && bare == m.bareTitles[i]:
+				score = 1 - 2*ambiguousScoreMargin
+			default:
+				score = similarity(title, m.titles[i])
+			}
+			if score < minMatchSimilarity {
+				continue
+			}
+			if artist != "" && song.Artist != "" && song.Artist != "Unknown" {
+				similarity := artistSimilarity(artist, song)
+				if similarity < minMatchSimilarity {
+					continue
+				}
+				score *= similarity
+			} else {
+				// 无法比较艺术家的候选排在艺术家相符的候选之后
+				score *= minMatchSimilarity
+			}
+			// 专辑和时长只用于区分得分接近的候选
+			if album != "" && album == normalizeDuplicateText(song.Album) {
+				score += ambiguousScoreMargin
+			}
+			if entry.Duration > 0 && song.Duration > 0 {
+				score += ambiguousScoreMargin * (1 - float64(abs(entry.Duration-song.Duration))/float64(m.DurationTolerance+1))
+			}
+			candidates = append(candidates, MatchCandidate{
+				ID:       song.ID,
+				Title:    song.Title,
+				Artist:   song.Artist,
+				Album:    song.Album,
+				Duration: song.Duration,
+				Score:    score,
+			})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].ID < candidates[j].ID
+	})
+	return candidates
+}
+
+// bareTitle 返回去掉版本说明后规范化的标题。
+func bareTitle(title string) string {
+	return normalizeDuplicateText(titleDecorationPattern.ReplaceAllString(title, ""))
+}
+
+// titleKeyLength 是标题索引键的字符数，使词尾的拼写差异仍能找到候选。
+const titleKeyLength = 3
+
+// titleKeys 返回规范化标题中每个词的前 titleKeyLength 个字符，已去重。
+func titleKeys(title string) []string {
+	words := strings.Fields(title)
+	for i, word := range words {
+		if runes := []rune(word); len(runes) > titleKeyLength {
+			words[i] = string(runes[:titleKeyLength])
+		}
+	}
+	sort.Strings(words)
+	return slices.Compact(words)
+}
+
+// artistSimilarity 比较规范化后的艺术家。条目中列出了多位艺术家（如 "A, B"）时，
+// 歌曲的任意一位艺术家作为完整的词出现在其中即视为相符，反之亦然。
+func artistSimilarity(artist string, song *Song) float64 {
+	best := similarity(strings.TrimPrefix(artist, "the "), strings.TrimPrefix(normalizeDuplicateText(song.Artist), "the "))
+	padded := " " + artist + " "
+	for _, name := range song.ArtistNames() {
+		name = normalizeDuplicateText(name)
+		if name != "" && (strings.Contains(padded, " "+name+" ") || strings.Contains(" "+name+" ", padded)) {
+			return 1
+		}
+	}
+	return best
+}
+
+// similarity 返回两个字符串基于编辑距离的相似度，范围为 0 到 1。
+func similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+	// 长度相差过大时不可能相似，不必计算编辑距离
+	if float64(min(len(ra), len(rb)))/float64(longest) < minMatchSimilarity {
+		return 0
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+// levenshtein 返回两个字符序列的编辑距离。
+func levenshtein(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// abs 返回整数的绝对值。
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestMatcher() *SongMatcher {
+	return NewSongMatcher([]*Song{
+		{ID: "yesterday", Title: "Yesterday", Artist: "The Beatles", Album: "Help!", Duration: 125, RelPath: "Beatles/Help/Yesterday.flac", FilePath: "/music/Beatles/Help/Yesterday.flac"},
+		{ID: "yesterday-live", Title: "Yesterday (Live)", Artist: "The Beatles", Album: "Live", Duration: 150, RelPath: "Beatles/Live/Yesterday.flac"},
+		{ID: "song-a", Title: "Song", Artist: "Artist A", Album: "First", Duration: 200},
+		{ID: "song-a2", Title: "Song", Artist: "Artist A", Album: "Second", Duration: 201},
+		{ID: "duet", Title: "Duet", Artist: "A feat. B", Artists: []string{"A", "B"}, Duration: 180},
+	})
+}
+
+func TestSongMatcher_Location(t *testing.T) {
+	m := newTestMatcher()
+
+	testCases := map[string]ImportEntry{
+		"yesterday":      {Location: "beatles/help/yesterday.FLAC"},
+		"yesterday-live": {Location: `D:\Backup\Beatles\Live\Yesterday.flac`},
+		"song-a":         {Location: "http://example.com/api/v1/stream/song-a"},
+		"song-a2":        {SongID: "song-a2", Title: "whatever"},
+	}
+	for expected, entry := range testCases {
+		match := m.Match(entry)
+		assert.Equal(t, MatchStatusMatched, match.Status, expected)
+		assert.Equal(t, expected, match.SongID)
+	}
+
+	// 路径匹配不到时按元数据匹配
+	match := m.Match(ImportEntry{Location: "/elsewhere/unknown.mp3", Title: "Yesterday", Artist: "Beatles"})
+	assert.Equal(t, "yesterday", match.SongID)
+}
+
+func TestSongMatcher_LibraryPath(t *testing.T) {
+	m := NewSongMatcher([]*Song{
+		{ID: "default", Title: "Song", RelPath: "Artist/Song.flac", FilePath: "/music/Artist/Song.flac"},
+		{ID: "lossless", Title: "Song", RelPath: "Artist/Song.flac", FilePath: "/lossless/Artist/Song.flac", Library: "lossless"},
+		{ID: "only-lossless", Title: "Other", RelPath: "Artist/Other.flac", FilePath: "/lossless/Artist/Other.flac", Library: "lossless"},
+	})
+
+	// 导出的相对路径中，默认库以外的歌曲带有库名前缀
+	testCases := map[string]string{
+		"Artist/Song.flac":           "default",
+		"lossless/Artist/Song.flac":  "lossless",
+		"Lossless/Artist/Other.flac": "only-lossless",
+		"Artist/Other.flac":          "only-lossless",
+	}
+	for location, expected := range testCases {
+		match := m.Match(ImportEntry{Location: location})
+		assert.Equal(t, MatchStatusMatched, match.Status, location)
+		assert.Equal(t, expected, match.SongID, location)
+	}
+}
+
+func TestSongMatcher_Fuzzy(t *testing.T) {
+	m := newTestMatcher()
+
+	testCases := []struct {
+		entry    ImportEntry
+		expected string
+	}{
+		// 版本说明和 "The" 前缀不影响匹配，完全相同的标题优先
+		{ImportEntry{Title: "Yesterday - Remastered 2009", Artist: "Beatles"}, "yesterday"},
+		{ImportEntry{Title: "yesterday", Artist: "the beatles"}, "yesterday"},
+		// 拼写错误
+		{ImportEntry{Title: "Yesterdy", Artist: "The Beatles"}, "yesterday"},
+		// 时长区分版本
+		{ImportEntry{Title: "Yesterday", Artist: "The Beatles", Duration: 152}, "yesterday-live"},
+		// 多位艺术家
+		{ImportEntry{Title: "Duet", Artist: "B, A"}, "duet"},
+		// 专辑区分同名歌曲
+		{ImportEntry{Title: "Song", Artist: "Artist A", Album: "Second"}, "song-a2"},
+	}
+	for _, tc := range testCases {
+		match := m.Match(tc.entry)
+		assert.Equal(t, MatchStatusMatched, match.Status, tc.entry.Title)
+		assert.Equal(t, tc.expected, match.SongID, tc.entry.Title)
+	}
+}
+
+func TestSongMatcher_AmbiguousAndMissing(t *testing.T) {
+	m := newTestMatcher()
+
+	match := m.Match(ImportEntry{Title: "Song", Artist: "Artist A"})
+	assert.Equal(t, MatchStatusAmbiguous, match.Status)
+	assert.Empty(t, match.SongID)
+	if assert.Len(t, match.Candidates, 2) {
+		assert.ElementsMatch(t, []string{"song-a", "song-a2"}, []string{match.Candidates[0].ID, match.Candidates[1].ID})
+	}
+
+	testCases := []ImportEntry{
+		{Title: "Yesterday", Artist: "Someone Else"},
+		{Title: "Yesterday", Artist: "The Beatles", Duration: 300},
+		{Title: "Completely Different"},
+		{Location: "http://example.com/radio.mp3"},
+	}
+	for _, entry := range testCases {
+		match := m.Match(entry)
+		assert.Equal(t, MatchStatusMissing, match.Status, entry.Title)
+		assert.Empty(t, match.Candidates)
+	}
+}
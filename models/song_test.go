@@ -49,3 +49,36 @@ func TestValidIDRegex(t *testing.T) {
 		assert.False(t, ValidIDRegex.MatchString(id), "ID should be invalid: %s", id)
 	}
 }
+
+func TestLibrarySongID(t *testing.T) {
+	root := filepath.Join("srv", "music")
+	rel := LibraryRelPath(root, filepath.Join(root, "Artist", "Album", "01.flac"))
+	assert.Equal(t, "Artist/Album/01.flac", rel)
+
+	// 同一相对路径在不同根目录下得到相同的 ID
+	moved := LibraryRelPath("mnt", filepath.Join("mnt", "Artist", "Album", "01.flac"))
+	assert.Equal(t, LibrarySongID(DefaultLibraryName, rel), LibrarySongID(DefaultLibraryName, moved))
+	assert.Regexp(t, ValidIDPattern(), LibrarySongID(DefaultLibraryName, rel))
+
+	// 不同库中相同的相对路径得到不同的 ID，默认库与未指定库名等价
+	assert.NotEqual(t, LibrarySongID(DefaultLibraryName, rel), LibrarySongID("lossless", rel))
+	assert.Equal(t, LibrarySongID(DefaultLibraryName, rel), LibrarySongID("", rel))
+}
+
+func TestUpdateContentHash(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.mp3")
+	b := filepath.Join(dir, "b.mp3")
+	c := filepath.Join(dir, "c.mp3")
+	assert.NoError(t, os.WriteFile(a, []byte("same content"), 0644))
+	assert.NoError(t, os.WriteFile(b, []byte("same content"), 0644))
+	assert.NoError(t, os.WriteFile(c, []byte("different content"), 0644))
+
+	songA, songB, songC := NewSong(a, 12), NewSong(b, 12), NewSong(c, 17)
+	for _, s := range []*Song{songA, songB, songC} {
+		s.UpdateContentHash()
+		assert.NotEmpty(t, s.ContentHash)
+	}
+	assert.Equal(t, songA.ContentHash, songB.ContentHash)
+	assert.NotEqual(t, songA.ContentHash, songC.ContentHash)
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// MaxCoverSize 是写入标签的封面图片的最大大小。
+const MaxCoverSize = 10 << 20
+
+// TagEdit 描述对歌曲标签的修改。为 nil 的字段保持不变；字符串为空、数字为 0 表示删除该标签。
+type TagEdit struct {
+	Title       *string `json:"title"`
+	Artist      *string `json:"artist"`
+	Album       *string `json:"album"`
+	AlbumArtist *string `json:"album_artist"`
+	Genre       *string `json:"genre"`
+	Year        *int    `json:"year"`
+	Track       *int    `json:"track"`
+	Disc        *int    `json:"disc"`
+	// Cover 是 JPEG 或 PNG 格式的封面图片，JSON 中使用 base64 编码；为空时删除嵌入的封面
+	Cover *[]byte `json:"cover"`
+}
+
+// IsEmpty 判断是否没有修改任何标签。
+func (e *TagEdit) IsEmpty() bool {
+	return e.Title == nil && e.Artist == nil && e.Album == nil && e.AlbumArtist == nil && e.Genre == nil &&
+		e.Year == nil && e.Track == nil && e.Disc == nil && e.Cover == nil
+}
+
+// Validate 检查修改是否有效，并去掉文本首尾的空白。
+func (e *TagEdit) Validate() error {
+	if e.IsEmpty() {
+		return errors.New("没有需要修改的标签")
+	}
+	for _, text := range []*string{e.Title, e.Artist, e.Album, e.AlbumArtist, e.Genre} {
+		if text == nil {
+			continue
+		}
+		*text = strings.TrimSpace(*text)
+		if strings.ContainsRune(*text, 0) || len(*text) > 1024 {
+			return errors.New("标签文本无效")
+		}
+	}
+	if e.Year != nil && (*e.Year < 0 || *e.Year > 9999) {
+		return errors.New("年份必须在 0 到 9999 之间")
+	}
+	if e.Track != nil && (*e.Track < 0 || *e.Track > 999) {
+		return errors.New("曲目编号必须在 0 到 999 之间")
+	}
+	if e.Disc != nil && (*e.Disc < 0 || *e.Disc > 999) {
+		return errors.New("碟片编号必须在 0 到 999 之间")
+	}
+	if e.Cover != nil && len(*e.Cover) > 0 {
+		if len(*e.Cover) > MaxCoverSize {
+			return fmt.Errorf("封面图片不能超过 %d MB", MaxCoverSize>>20)
+		}
+		if e.CoverMIME() == "" {
+			return errors.New("封面图片只能是 JPEG 或 PNG 格式")
+		}
+	}
+	return nil
+}
+
+// CoverMIME 返回封面图片的 MIME 类型，不是 JPEG 或 PNG 时返回空字符串。
+func (e *TagEdit) CoverMIME() string {
+	if e.Cover == nil {
+		return ""
+	}
+	switch mimeType := http.DetectContentType(*e.Cover); mimeType {
+	case "image/jpeg", "image/png":
+		return mimeType
+	default:
+		return ""
+	}
+}
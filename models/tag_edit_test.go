@@ -0,0 +1,36 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagEdit_JSON(t *testing.T) {
+	var edit TagEdit
+	require.NoError(t, json.Unmarshal([]byte(`{"title":" Song ","genre":"","year":0,"cover":"iVBORw0KGgo="}`), &edit))
+
+	require.NoError(t, edit.Validate())
+	assert.Equal(t, "Song", *edit.Title)
+	assert.Equal(t, "", *edit.Genre)
+	assert.Equal(t, 0, *edit.Year)
+	assert.Nil(t, edit.Artist)
+	assert.Nil(t, edit.Track)
+	assert.Equal(t, "image/png", edit.CoverMIME())
+}
+
+func TestTagEdit_Validate(t *testing.T) {
+	year, track := 10000, -1
+	text := "a\x00b"
+	notImage := []byte("not an image")
+	empty := []byte{}
+
+	assert.Error(t, (&TagEdit{}).Validate())
+	assert.Error(t, (&TagEdit{Year: &year}).Validate())
+	assert.Error(t, (&TagEdit{Track: &track}).Validate())
+	assert.Error(t, (&TagEdit{Title: &text}).Validate())
+	assert.Error(t, (&TagEdit{Cover: &notImage}).Validate())
+	assert.NoError(t, (&TagEdit{Cover: &empty}).Validate(), "空封面表示删除")
+}
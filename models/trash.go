@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// TrashedSong 是通过 API 删除的歌曲的墓碑记录。文件被移入回收站，保留期结束后彻底删除；
+// 记录本身一直保留，播放历史中已删除的歌曲 ID 仍可据此查到歌曲原来的信息。
+type TrashedSong struct {
+	ID     int64  `json:"id"`
+	SongID string `json:"song_id"`
+	// Library 和 RelPath 是文件删除前在音乐库中的位置
+	Library string `json:"library"`
+	RelPath string `json:"rel_path"`
+	Title   string `json:"title"`
+	Artist  string `json:"artist"`
+	Album   string `json:"album"`
+	Size    int64  `json:"size"`
+	// TrashPath 是文件在回收站目录中的相对路径（使用 / 分隔）
+	TrashPath string `json:"-"`
+	// DeletedBy 是执行删除的管理员的用户 ID
+	DeletedBy int64     `json:"deleted_by"`
+	DeletedAt time.Time `json:"deleted_at"`
+	// PurgedAt 是文件从回收站中彻底删除的时间，仍在回收站中时为 nil
+	PurgedAt *time.Time `json:"purged_at,omitempty"`
+}
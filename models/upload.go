@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Upload 记录用户上传到音乐库的一个文件，用于统计用户占用的存储空间。
+type Upload struct {
+	ID     int64  `json:"id"`
+	UserID int64  `json:"user_id"`
+	SongID string `json:"song_id"`
+	// Library 是文件所在音乐库的名称
+	Library string `json:"library"`
+	// RelPath 是文件相对于音乐库根目录的路径（使用 / 分隔）
+	RelPath   string    `json:"rel_path"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UploadQuota 是用户的上传存储配额。
+type UploadQuota struct {
+	// Used 是用户已上传文件的总大小（字节）
+	Used int64 `json:"used"`
+	// Limit 是允许上传的总大小（字节），0 表示不限制
+	Limit int64 `json:"limit"`
+	// Custom 表示 Limit 是管理员为该用户单独设置的配额，而不是默认配额
+	Custom bool `json:"custom"`
+}
+
+// Allows 判断再上传 size 字节后是否仍在配额之内。
+func (q *UploadQuota) Allows(size int64) bool {
+	return q.Limit <= 0 || q.Used+size <= q.Limit
+}
+
+// Remaining 返回剩余可上传的字节数，不限制时返回 -1。
+func (q *UploadQuota) Remaining() int64 {
+	if q.Limit <= 0 {
+		return -1
+	}
+	return max(q.Limit-q.Used, 0)
+}
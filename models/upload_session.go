@@ -0,0 +1,67 @@
+package models
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// UploadSession 是一个可断点续传的上传（tus 协议），记录文件的总大小和已接收的字节数。
+// 已接收的数据暂存在磁盘上，全部接收后文件被放入音乐库，会话保留到过期以便客户端确认上传结果。
+type UploadSession struct {
+	ID     string `json:"id"`
+	UserID int64  `json:"user_id"`
+	// Filename 是客户端在 Upload-Metadata 中提供的文件名，决定文件按音频还是 zip 压缩包处理
+	Filename string `json:"filename"`
+	// Metadata 是客户端提供的原始 Upload-Metadata 头，在 HEAD 请求中原样返回
+	Metadata string `json:"-"`
+	// Length 是文件的总大小（字节）
+	Length int64 `json:"length"`
+	// Offset 是已接收的字节数
+	Offset int64 `json:"offset"`
+	// SongIDs 是文件放入音乐库后得到的歌曲 ID，上传完成前为空
+	SongIDs   []string  `json:"song_ids,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Completed 判断文件是否已全部接收。
+func (s *UploadSession) Completed() bool {
+	return s.Offset >= s.Length
+}
+
+// Expired 判断会话在 now 时是否已过期。
+func (s *UploadSession) Expired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
+
+// ParseUploadMetadata 解析 tus 协议的 Upload-Metadata 头。头由逗号分隔的键值对组成，
+// 键和值之间用空格分隔，值使用 Base64 编码且可以省略，如 "filename d29ybGQ=,is_confidential"。
+func ParseUploadMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		fields := strings.Fields(pair)
+		if len(fields) == 0 || len(fields) > 2 {
+			return nil, fmt.Errorf("Upload-Metadata 格式错误: %q", pair)
+		}
+		key := fields[0]
+		if _, exists := metadata[key]; exists {
+			return nil, fmt.Errorf("Upload-Metadata 中的键重复: %s", key)
+		}
+		value := ""
+		if len(fields) == 2 {
+			decoded, err := base64.StdEncoding.DecodeString(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("Upload-Metadata 中 %s 的值不是有效的 Base64: %w", key, err)
+			}
+			value = string(decoded)
+		}
+		metadata[key] = value
+	}
+	return metadata, nil
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUploadMetadata(t *testing.T) {
+	metadata, err := ParseUploadMetadata("filename 5LiT6L6RLnppcA==, filetype YXBwbGljYXRpb24vemlw,is_confidential")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"filename": "专辑.zip", "filetype": "application/zip", "is_confidential": ""}, metadata)
+
+	metadata, err = ParseUploadMetadata("")
+	require.NoError(t, err)
+	assert.Empty(t, metadata)
+
+	for _, header := range []string{"filename not-base64!", "filename YQ== extra", "a YQ==,a Yg==", "a YQ==,,b Yg=="} {
+		_, err := ParseUploadMetadata(header)
+		assert.Error(t, err, header)
+	}
+}
+
+func TestUploadSession(t *testing.T) {
+	now := time.Now()
+	session := &UploadSession{Length: 100, Offset: 40, ExpiresAt: now.Add(time.Hour)}
+	assert.False(t, session.Completed())
+	assert.False(t, session.Expired(now))
+	assert.True(t, session.Expired(now.Add(time.Hour)))
+
+	session.Offset = 100
+	assert.True(t, session.Completed())
+}
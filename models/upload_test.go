@@ -0,0 +1,21 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUploadQuota(t *testing.T) {
+	unlimited := &UploadQuota{Used: 100}
+	assert.True(t, unlimited.Allows(1<<40))
+	assert.Equal(t, int64(-1), unlimited.Remaining())
+
+	quota := &UploadQuota{Used: 80, Limit: 100}
+	assert.True(t, quota.Allows(20))
+	assert.False(t, quota.Allows(21))
+	assert.Equal(t, int64(20), quota.Remaining())
+
+	over := &UploadQuota{Used: 120, Limit: 100}
+	assert.Equal(t, int64(0), over.Remaining())
+}
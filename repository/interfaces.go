@@ -3,6 +3,8 @@
 package repository
 
 import (
+	"time"
+
 	"zero-music/models"
 )
 
@@ -110,3 +112,121 @@ type PlaylistRepository interface {
 	// IsOwner 检查是否是播放列表所有者。
 	IsOwner(playlistID, userID int64) (bool, error)
 }
+
+// SongRepository 定义了音乐库歌曲数据访问接口。
+// 扫描器通过它持久化扫描结果，启动时从中加载而不必重新解析全部文件。
+type SongRepository interface {
+	// LoadAll 加载所有已持久化的歌曲。
+	LoadAll() ([]*models.Song, error)
+
+	// Save 在一个事务中写入新增或变更的歌曲、删除已移除的歌曲，并刷新专辑和艺术家汇总。
+	Save(upserts []*models.Song, removedIDs []string) error
+
+	// Count 获取已持久化的歌曲数量。
+	Count() (int, error)
+
+	// RenameIDs 在一个事务中将收藏、播放统计、播放历史、播放列表、重复歌曲首选项和元数据覆盖中的旧歌曲 ID 改写为新 ID，
+	// 并记录旧 ID 到新 ID 的别名。renames 的键为旧 ID，值为新 ID。
+	RenameIDs(renames map[string]string) error
+
+	// LoadAliases 加载所有歌曲 ID 别名（旧 ID -> 新 ID）。
+	LoadAliases() (map[string]string, error)
+
+	// KeepAlias 将旧 ID 的别名标记为永久保留，PurgeAliases 不会删除永久保留的别名。
+	KeepAlias(oldID string) error
+
+	// PurgeAliases 删除创建时间早于 before 且未被永久保留的别名，返回删除的数量。
+	PurgeAliases(before time.Time) (int64, error)
+}
+
+// DuplicateRepository 定义了重复歌曲首选副本的数据访问接口。
+// 管理员为一组重复歌曲选定首选副本后，同组的其他副本被记录为隐藏。
+type DuplicateRepository interface {
+	// LoadHidden 加载所有被隐藏的歌曲 ID 及其首选副本的 ID。
+	LoadHidden() (map[string]string, error)
+
+	// SetPreferred 在一个事务中将 preferredID 设为一组重复歌曲的首选副本，隐藏 hiddenIDs 中的歌曲。
+	// 这些歌曲原有的首选项会被替换，首选副本本身不再被隐藏。
+	SetPreferred(preferredID string, hiddenIDs []string) error
+
+	// ClearPreferred 取消以 preferredID 为首选副本的首选项，返回重新显示的歌曲数量。
+	ClearPreferred(preferredID string) (int64, error)
+}
+
+// MetadataOverrideRepository 定义了歌曲元数据覆盖的数据访问接口。
+// 覆盖按歌曲 ID 保存，扫描器返回歌曲时将其应用在从文件读取的元数据之上。
+type MetadataOverrideRepository interface {
+	// LoadAll 加载所有元数据覆盖，不包含封面图片的内容。
+	LoadAll() ([]*models.MetadataOverride, error)
+
+	// Save 写入歌曲的元数据覆盖。cover 不为 nil 时替换封面图片；覆盖中没有封面时删除已保存的图片。
+	Save(override *models.MetadataOverride, cover []byte) error
+
+	// Delete 删除歌曲的元数据覆盖，返回是否存在。
+	Delete(songID string) (bool, error)
+
+	// GetCover 读取覆盖封面的图片内容，没有时返回 nil。
+	GetCover(songID string) ([]byte, error)
+}
+
+// UploadRepository 定义了用户上传记录和上传配额的数据访问接口。
+type UploadRepository interface {
+	// Create 记录一个上传的文件，同一音乐库中的同一路径只保留最新的记录。
+	Create(upload *models.Upload) error
+
+	// ListByUser 返回用户上传的文件，按上传时间倒序排列。
+	ListByUser(userID int64) ([]*models.Upload, error)
+
+	// Usage 返回用户已上传文件的总大小（字节）。
+	Usage(userID int64) (int64, error)
+
+	// GetQuota 返回管理员为用户单独设置的配额（字节），没有设置时第二个返回值为 false。
+	GetQuota(userID int64) (int64, bool, error)
+
+	// SetQuota 为用户单独设置配额（字节），0 表示不限制。
+	SetQuota(userID int64, quota int64) error
+
+	// DeleteQuota 删除用户单独设置的配额，恢复使用默认配额，返回是否存在。
+	DeleteQuota(userID int64) (bool, error)
+
+	// UpdateLocation 将位于 library 中 relPath 的上传记录更新为移动后的音乐库 newLibrary 和相对路径 newRelPath。
+	// 目标位置已有其他上传记录时返回唯一约束错误，不修改任何记录。
+	UpdateLocation(library, relPath, newLibrary, newRelPath string) error
+}
+
+// UploadSessionRepository 定义了断点续传上传会话的数据访问接口。
+type UploadSessionRepository interface {
+	// Create 创建一个上传会话。
+	Create(session *models.UploadSession) error
+
+	// FindByID 根据 ID 查找上传会话，不存在时返回 nil。
+	FindByID(id string) (*models.UploadSession, error)
+
+	// UpdateOffset 更新已接收的字节数并顺延过期时间。
+	UpdateOffset(id string, offset int64, expiresAt time.Time) error
+
+	// Complete 记录文件放入音乐库后得到的歌曲 ID。
+	Complete(id string, songIDs []string) error
+
+	// Delete 删除上传会话，返回是否存在。
+	Delete(id string) (bool, error)
+
+	// ListExpired 返回在 now 之前过期的上传会话。
+	ListExpired(now time.Time) ([]*models.UploadSession, error)
+}
+
+// TrashRepository 定义了已删除歌曲的墓碑记录的数据访问接口。
+type TrashRepository interface {
+	// Trash 在一个事务中记录被删除的歌曲，并清理收藏、播放列表、播放统计、重复歌曲首选项、
+	// 元数据覆盖、上传记录和 ID 别名中对它的引用。播放历史保留，可通过墓碑记录查到歌曲原来的信息。
+	Trash(entry *models.TrashedSong) error
+
+	// List 返回仍在回收站中的歌曲，按删除时间倒序排列。
+	List() ([]*models.TrashedSong, error)
+
+	// ListDeletedBefore 返回在 before 之前删除、仍在回收站中的歌曲。
+	ListDeletedBefore(before time.Time) ([]*models.TrashedSong, error)
+
+	// MarkPurged 记录歌曲文件已从回收站中彻底删除。
+	MarkPurged(id int64) error
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"zero-music/database"
+)
+
+// SQLiteDuplicateRepository 是 DuplicateRepository 的 SQLite 实现。
+type SQLiteDuplicateRepository struct {
+	db database.DB
+}
+
+// NewSQLiteDuplicateRepository 创建 SQLite 重复歌曲首选项仓储实例。
+func NewSQLiteDuplicateRepository(db database.DB) *SQLiteDuplicateRepository {
+	return &SQLiteDuplicateRepository{db: db}
+}
+
+// LoadHidden 加载所有被隐藏的歌曲 ID 及其首选副本的 ID。
+func (r *SQLiteDuplicateRepository) LoadHidden() (map[string]string, error) {
+	rows, err := r.db.Query(`SELECT song_id, preferred_id FROM duplicate_preferences`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hidden := make(map[string]string)
+	for rows.Next() {
+		var songID, preferredID string
+		if err := rows.Scan(&songID, &preferredID); err != nil {
+			return nil, err
+		}
+		hidden[songID] = preferredID
+	}
+	return hidden, rows.Err()
+}
+
+// SetPreferred 将 preferredID 设为一组重复歌曲的首选副本，隐藏 hiddenIDs 中的歌曲。
+func (r *SQLiteDuplicateRepository) SetPreferred(preferredID string, hiddenIDs []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// 首选副本之前可能被隐藏，或者是其他副本的首选副本
+	if _, err := tx.Exec(`DELETE FROM duplicate_preferences WHERE song_id = ? OR preferred_id = ?`, preferredID, preferredID); err != nil {
+		return err
+	}
+	for _, id := range hiddenIDs {
+		if id == preferredID {
+			continue
+		}
+		if _, err := tx.Exec(`DELETE FROM duplicate_preferences WHERE preferred_id = ?`, id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(
+			`INSERT OR REPLACE INTO duplicate_preferences (song_id, preferred_id, created_at) VALUES (?, ?, CURRENT_TIMESTAMP)`,
+			id, preferredID,
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ClearPreferred 取消以 preferredID 为首选副本的首选项。
+func (r *SQLiteDuplicateRepository) ClearPreferred(preferredID string) (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM duplicate_preferences WHERE preferred_id = ?`, preferredID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"testing"
+)
+
+func TestSQLiteDuplicateRepository_SetAndClear(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewSQLiteDuplicateRepository(db)
+
+	if err := repo.SetPreferred("a", []string{"a", "b", "c"}); err != nil {
+		t.Fatalf("SetPreferred failed: %v", err)
+	}
+	hidden, err := repo.LoadHidden()
+	if err != nil {
+		t.Fatalf("LoadHidden failed: %v", err)
+	}
+	if len(hidden) != 2 || hidden["b"] != "a" || hidden["c"] != "a" {
+		t.Errorf("Expected b and c hidden in favour of a, got %v", hidden)
+	}
+
+	// 改选之前被隐藏的副本：原有的首选项被替换
+	if err := repo.SetPreferred("b", []string{"a", "c"}); err != nil {
+		t.Fatalf("SetPreferred failed: %v", err)
+	}
+	hidden, _ = repo.LoadHidden()
+	if len(hidden) != 2 || hidden["a"] != "b" || hidden["c"] != "b" {
+		t.Errorf("Expected a and c hidden in favour of b, got %v", hidden)
+	}
+
+	cleared, err := repo.ClearPreferred("b")
+	if err != nil {
+		t.Fatalf("ClearPreferred failed: %v", err)
+	}
+	if cleared != 2 {
+		t.Errorf("Expected 2 songs shown again, got %d", cleared)
+	}
+	if hidden, _ = repo.LoadHidden(); len(hidden) != 0 {
+		t.Errorf("Expected no hidden songs, got %v", hidden)
+	}
+}
+
+func TestSQLiteSongRepository_RenameIDsMigratesDuplicatePreferences(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	duplicates := NewSQLiteDuplicateRepository(db)
+	if err := duplicates.SetPreferred("keep", []string{"drop"}); err != nil {
+		t.Fatalf("SetPreferred failed: %v", err)
+	}
+	if err := NewSQLiteSongRepository(db).RenameIDs(map[string]string{"keep": "keep2", "drop": "drop2"}); err != nil {
+		t.Fatalf("RenameIDs failed: %v", err)
+	}
+	hidden, _ := duplicates.LoadHidden()
+	if len(hidden) != 1 || hidden["drop2"] != "keep2" {
+		t.Errorf("Expected preference migrated to new IDs, got %v", hidden)
+	}
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"zero-music/database"
+	"zero-music/models"
+)
+
+// SQLiteMetadataOverrideRepository 是 MetadataOverrideRepository 的 SQLite 实现。
+type SQLiteMetadataOverrideRepository struct {
+	db database.DB
+}
+
+// NewSQLiteMetadataOverrideRepository 创建 SQLite 元数据覆盖仓储实例。
+func NewSQLiteMetadataOverrideRepository(db database.DB) *SQLiteMetadataOverrideRepository {
+	return &SQLiteMetadataOverrideRepository{db: db}
+}
+
+// LoadAll 加载所有元数据覆盖，不包含封面图片的内容。
+func (r *SQLiteMetadataOverrideRepository) LoadAll() ([]*models.MetadataOverride, error) {
+	rows, err := r.db.Query(`
+		SELECT song_id, title, artist, album, genre, year, cover_mime, cover_hash, updated_at
+		FROM metadata_overrides
+		ORDER BY song_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var overrides []*models.MetadataOverride
+	for rows.Next() {
+		o := &models.MetadataOverride{}
+		var title, artist, album, genre sql.NullString
+		var year sql.NullInt64
+		if err := rows.Scan(&o.SongID, &title, &artist, &album, &genre, &year, &o.CoverMIME, &o.CoverHash, &o.UpdatedAt); err != nil {
+			return nil, err
+		}
+		o.Title, o.Artist, o.Album, o.Genre = nullStringPtr(title), nullStringPtr(artist), nullStringPtr(album), nullStringPtr(genre)
+		if year.Valid {
+			value := int(year.Int64)
+			o.Year = &value
+		}
+		overrides = append(overrides, o)
+	}
+	return overrides, rows.Err()
+}
+
+// Save 写入歌曲的元数据覆盖。cover 为 nil 时保留已保存的封面图片，覆盖中没有封面时删除图片。
+func (r *SQLiteMetadataOverrideRepository) Save(override *models.MetadataOverride, cover []byte) error {
+	if override.UpdatedAt.IsZero() {
+		override.UpdatedAt = time.Now()
+	}
+	_, err := r.db.Exec(`
+		INSERT INTO metadata_overrides (song_id, title, artist, album, genre, year, cover, cover_mime, cover_hash, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(song_id) DO UPDATE SET
+			title = excluded.title,
+			artist = excluded.artist,
+			album = excluded.album,
+			genre = excluded.genre,
+			year = excluded.year,
+			cover = CASE
+				WHEN excluded.cover_mime = '' THEN NULL
+				WHEN excluded.cover IS NULL THEN metadata_overrides.cover
+				ELSE excluded.cover
+			END,
+			cover_mime = excluded.cover_mime,
+			cover_hash = excluded.cover_hash,
+			updated_at = excluded.updated_at
+	`, override.SongID, override.Title, override.Artist, override.Album, override.Genre, override.Year,
+		cover, override.CoverMIME, override.CoverHash, override.UpdatedAt)
+	return err
+}
+
+// Delete 删除歌曲的元数据覆盖。
+func (r *SQLiteMetadataOverrideRepository) Delete(songID string) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM metadata_overrides WHERE song_id = ?`, songID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// GetCover 读取覆盖封面的图片内容，没有时返回 nil。
+func (r *SQLiteMetadataOverrideRepository) GetCover(songID string) ([]byte, error) {
+	var cover []byte
+	err := r.db.QueryRow(`SELECT cover FROM metadata_overrides WHERE song_id = ?`, songID).Scan(&cover)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return cover, err
+}
+
+// nullStringPtr 将可为 NULL 的数据库值转换为指针，NULL 对应 nil。
+func nullStringPtr(value sql.NullString) *string {
+	if !value.Valid {
+		return nil
+	}
+	return &value.String
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"bytes"
+	"testing"
+
+	"zero-music/models"
+)
+
+func TestSQLiteMetadataOverrideRepository_SaveAndLoad(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewSQLiteMetadataOverrideRepository(db)
+
+	title, genre, year := "Fixed Title", "", 1999
+	cover := []byte("\x89PNG\r\n\x1a\ncover")
+	override := &models.MetadataOverride{SongID: "song1", Title: &title, Genre: &genre, Year: &year, CoverMIME: "image/png", CoverHash: "h1"}
+	if err := repo.Save(override, cover); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	overrides, err := repo.LoadAll()
+	if err != nil {
+		t.Fatalf("LoadAll failed: %v", err)
+	}
+	if len(overrides) != 1 {
+		t.Fatalf("Expected 1 override, got %d", len(overrides))
+	}
+	loaded := overrides[0]
+	if loaded.Title == nil || *loaded.Title != title || loaded.Genre == nil || *loaded.Genre != "" || loaded.Year == nil || *loaded.Year != 1999 {
+		t.Errorf("Override fields mismatch: %+v", loaded)
+	}
+	if loaded.Artist != nil || loaded.Album != nil {
+		t.Errorf("Expected artist and album not overridden, got %+v", loaded)
+	}
+	if loaded.CoverMIME != "image/png" || loaded.CoverHash != "h1" || loaded.UpdatedAt.IsZero() {
+		t.Errorf("Cover fields mismatch: %+v", loaded)
+	}
+
+	// 不传入封面时保留已保存的图片
+	loaded.Title = nil
+	if err := repo.Save(loaded, nil); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if data, err := repo.GetCover("song1"); err != nil || !bytes.Equal(data, cover) {
+		t.Errorf("Expected cover kept, got %q %v", data, err)
+	}
+	overrides, _ = repo.LoadAll()
+	if overrides[0].Title != nil {
+		t.Errorf("Expected title override cleared, got %q", *overrides[0].Title)
+	}
+
+	// 取消封面覆盖时删除图片
+	loaded.CoverMIME, loaded.CoverHash = "", ""
+	if err := repo.Save(loaded, nil); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if data, err := repo.GetCover("song1"); err != nil || data != nil {
+		t.Errorf("Expected cover removed, got %q %v", data, err)
+	}
+
+	deleted, err := repo.Delete("song1")
+	if err != nil || !deleted {
+		t.Fatalf("Expected override deleted, got %v %v", deleted, err)
+	}
+	if deleted, _ = repo.Delete("song1"); deleted {
+		t.Error("Expected second delete to report missing override")
+	}
+	if data, err := repo.GetCover("missing"); err != nil || data != nil {
+		t.Errorf("Expected no cover for missing song, got %q %v", data, err)
+	}
+}
+
+func TestSQLiteSongRepository_RenameIDsMigratesMetadataOverrides(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewSQLiteMetadataOverrideRepository(db)
+	title := "Fixed"
+	if err := repo.Save(&models.MetadataOverride{SongID: "old", Title: &title}, nil); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := NewSQLiteSongRepository(db).RenameIDs(map[string]string{"old": "new"}); err != nil {
+		t.Fatalf("RenameIDs failed: %v", err)
+	}
+	overrides, _ := repo.LoadAll()
+	if len(overrides) != 1 || overrides[0].SongID != "new" {
+		t.Errorf("Expected override migrated to new ID, got %+v", overrides)
+	}
+}
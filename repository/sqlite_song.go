@@ -0,0 +1,308 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"zero-music/database"
+	"zero-music/models"
+)
+
+// SQLiteSongRepository 是 SongRepository 的 SQLite 实现。
+type SQLiteSongRepository struct {
+	db database.DB
+}
+
+// NewSQLiteSongRepository 创建 SQLite 歌曲仓储实例。
+func NewSQLiteSongRepository(db database.DB) *SQLiteSongRepository {
+	return &SQLiteSongRepository{db: db}
+}
+
+// LoadAll 加载所有已持久化的歌曲。
+func (r *SQLiteSongRepository) LoadAll() ([]*models.Song, error) {
+	rows, err := r.db.Query(`
+		SELECT id, file_path, file_name, title, artist, album, genre, year, track, disc,
+		       duration, duration_estimated, file_size, mod_time, format, has_cover, library, rel_path, content_hash,
+		       codec, lossless, bitrate, bitrate_mode, sample_rate, bit_depth, channels,
+		       cover_source, cover_path, artist_image_path, has_lyrics, embedded_lyrics, lyrics_path,
+		       artists, album_artist, composer,
+		       track_gain, track_peak, album_gain, album_peak, loudness, loudness_source,
+		       cue_sheet_path, start_ms, end_ms, issues
+		FROM songs
+		ORDER BY file_path
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var songs []*models.Song
+	for rows.Next() {
+		s := &models.Song{}
+		var modTime int64
+		var artists, loudnessSource, issues string
+		var trackGain, trackPeak, albumGain, albumPeak, loudness sql.NullFloat64
+		if err := rows.Scan(&s.ID, &s.FilePath, &s.FileName, &s.Title, &s.Artist, &s.Album,
+			&s.Genre, &s.Year, &s.Track, &s.Disc, &s.Duration, &s.DurationEstimated, &s.FileSize, &modTime, &s.Format, &s.HasCover, &s.Library, &s.RelPath, &s.ContentHash,
+			&s.Codec, &s.Lossless, &s.Bitrate, &s.BitrateMode, &s.SampleRate, &s.BitDepth, &s.Channels,
+			&s.CoverSource, &s.CoverPath, &s.ArtistImagePath, &s.HasLyrics, &s.EmbeddedLyrics, &s.LyricsPath,
+			&artists, &s.AlbumArtist, &s.Composer,
+			&trackGain, &trackPeak, &albumGain, &albumPeak, &loudness, &loudnessSource,
+			&s.CueSheetPath, &s.StartMs, &s.EndMs, &issues); err != nil {
+			return nil, err
+		}
+		if loudnessSource != "" {
+			s.ReplayGain = &models.ReplayGain{
+				TrackGain: nullFloatPtr(trackGain),
+				TrackPeak: nullFloatPtr(trackPeak),
+				AlbumGain: nullFloatPtr(albumGain),
+				AlbumPeak: nullFloatPtr(albumPeak),
+				Loudness:  nullFloatPtr(loudness),
+				Source:    loudnessSource,
+			}
+		}
+		if artists != "" {
+			if err := json.Unmarshal([]byte(artists), &s.Artists); err != nil {
+				return nil, fmt.Errorf("解析歌曲 %s 的艺术家列表失败: %w", s.ID, err)
+			}
+		}
+		if issues != "" {
+			if err := json.Unmarshal([]byte(issues), &s.Issues); err != nil {
+				return nil, fmt.Errorf("解析歌曲 %s 的问题列表失败: %w", s.ID, err)
+			}
+		}
+		s.AddedAt = time.Unix(0, modTime)
+		s.DurationFormatted = models.FormatDuration(s.Duration)
+		songs = append(songs, s)
+	}
+	return songs, rows.Err()
+}
+
+// Save 在一个事务中写入新增或变更的歌曲、删除已移除的歌曲，并刷新专辑和艺术家汇总。
+func (r *SQLiteSongRepository) Save(upserts []*models.Song, removedIDs []string) error {
+	if len(upserts) == 0 && len(removedIDs) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if len(removedIDs) > 0 {
+		stmt, err := tx.Prepare(`DELETE FROM songs WHERE id = ?`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for _, id := range removedIDs {
+			if _, err := stmt.Exec(id); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(upserts) > 0 {
+		stmt, err := tx.Prepare(`
+			INSERT OR REPLACE INTO songs (id, file_path, file_name, title, artist, album, genre, year, track, disc,
+			                              duration, duration_estimated, file_size, mod_time, format, has_cover, library, rel_path, content_hash,
+			                              codec, lossless, bitrate, bitrate_mode, sample_rate, bit_depth, channels,
+			                              cover_source, cover_path, artist_image_path, has_lyrics, embedded_lyrics, lyrics_path,
+			                              artists, album_artist, composer,
+			                              track_gain, track_peak, album_gain, album_peak, loudness, loudness_source,
+			                              cue_sheet_path, start_ms, end_ms, issues, scanned_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?,
+			        ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for _, s := range upserts {
+			artists, err := encodeArtists(s.Artists)
+			if err != nil {
+				return err
+			}
+			issues, err := encodeIssues(s.Issues)
+			if err != nil {
+				return err
+			}
+			rg := s.ReplayGain
+			if rg == nil {
+				rg = &models.ReplayGain{}
+			}
+			if _, err := stmt.Exec(s.ID, s.FilePath, s.FileName, s.Title, s.Artist, s.Album, s.Genre,
+				s.Year, s.Track, s.Disc, s.Duration, s.DurationEstimated, s.FileSize, s.AddedAt.UnixNano(), s.Format, s.HasCover, s.Library, s.RelPath, s.ContentHash,
+				s.Codec, s.Lossless, s.Bitrate, s.BitrateMode, s.SampleRate, s.BitDepth, s.Channels,
+				s.CoverSource, s.CoverPath, s.ArtistImagePath, s.HasLyrics, s.EmbeddedLyrics, s.LyricsPath,
+				artists, s.AlbumArtist, s.Composer,
+				floatPtrValue(rg.TrackGain), floatPtrValue(rg.TrackPeak), floatPtrValue(rg.AlbumGain), floatPtrValue(rg.AlbumPeak),
+				floatPtrValue(rg.Loudness), rg.Source,
+				s.CueSheetPath, s.StartMs, s.EndMs, issues); err != nil {
+				return err
+			}
+		}
+	}
+
+	// 专辑和艺术家表完全由 songs 表派生，每次变更后整体重建。
+	// 专辑按专辑艺术家（没有时为第一位艺术家）分组，艺术家按拆分后的每一位艺术家统计，与 Song 的方法保持一致
+	summaries := []string{
+		`DELETE FROM albums`,
+		`INSERT INTO albums (name, artist, year, song_count)
+		 SELECT album, album_artist_name, MAX(year), COUNT(*) FROM (
+		     SELECT album, year, CASE WHEN album_artist != '' THEN album_artist
+		                              ELSE COALESCE(json_extract(NULLIF(artists, ''), '$[0]'), artist) END AS album_artist_name
+		     FROM songs
+		 ) WHERE album != '' GROUP BY album, album_artist_name`,
+		`DELETE FROM artists`,
+		`INSERT INTO artists (name, song_count)
+		 SELECT name, COUNT(*) FROM (
+		     SELECT COALESCE(a.value, s.artist) AS name
+		     FROM songs s LEFT JOIN json_each(CASE WHEN s.artists != '' THEN s.artists ELSE '[]' END) a
+		 ) WHERE name != '' GROUP BY name`,
+	}
+	for _, query := range summaries {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// encodeArtists 将艺术家列表编码为 JSON 文本存储，没有拆分结果时存储空字符串。
+func encodeArtists(artists []string) (string, error) {
+	if len(artists) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(artists)
+	if err != nil {
+		return "", fmt.Errorf("编码艺术家列表失败: %w", err)
+	}
+	return string(data), nil
+}
+
+// encodeIssues 将问题列表编码为 JSON，没有问题时返回空字符串。
+func encodeIssues(issues []models.SongIssue) (string, error) {
+	if len(issues) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(issues)
+	if err != nil {
+		return "", fmt.Errorf("编码问题列表失败: %w", err)
+	}
+	return string(data), nil
+}
+
+// nullFloatPtr 将可为 NULL 的数据库值转换为指针，NULL 对应 nil。
+func nullFloatPtr(value sql.NullFloat64) *float64 {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Float64
+}
+
+// floatPtrValue 将指针转换为数据库参数，nil 写入 NULL。
+func floatPtrValue(value *float64) interface{} {
+	if value == nil {
+		return nil
+	}
+	return *value
+}
+
+// Count 获取已持久化的歌曲数量。
+func (r *SQLiteSongRepository) Count() (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM songs`).Scan(&count)
+	return count, err
+}
+
+// RenameIDs 在一个事务中改写用户数据中的歌曲 ID 并记录别名。
+// 对于带唯一约束的表，如果用户已经拥有新 ID 的记录，则合并或丢弃旧记录，避免约束冲突。
+func (r *SQLiteSongRepository) RenameIDs(renames map[string]string) error {
+	if len(renames) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	queries := []string{
+		// 播放统计：先把旧记录累加到已存在的新记录上，再迁移剩余的旧记录
+		`UPDATE play_stats SET
+			play_count = play_count + (SELECT o.play_count FROM play_stats o WHERE o.user_id = play_stats.user_id AND o.song_id = ?1),
+			total_play_time = total_play_time + (SELECT o.total_play_time FROM play_stats o WHERE o.user_id = play_stats.user_id AND o.song_id = ?1),
+			last_played_at = NULLIF(MAX(COALESCE(last_played_at, ''), COALESCE((SELECT o.last_played_at FROM play_stats o WHERE o.user_id = play_stats.user_id AND o.song_id = ?1), '')), '')
+		 WHERE song_id = ?2 AND EXISTS (SELECT 1 FROM play_stats o WHERE o.user_id = play_stats.user_id AND o.song_id = ?1)`,
+		`UPDATE OR IGNORE play_stats SET song_id = ?2 WHERE song_id = ?1`,
+		`DELETE FROM play_stats WHERE song_id = ?1`,
+		`UPDATE play_history SET song_id = ?2 WHERE song_id = ?1`,
+		`UPDATE OR IGNORE favorites SET song_id = ?2 WHERE song_id = ?1`,
+		`DELETE FROM favorites WHERE song_id = ?1`,
+		`UPDATE OR IGNORE playlist_songs SET song_id = ?2 WHERE song_id = ?1`,
+		`DELETE FROM playlist_songs WHERE song_id = ?1`,
+		`UPDATE OR IGNORE duplicate_preferences SET song_id = ?2 WHERE song_id = ?1`,
+		`DELETE FROM duplicate_preferences WHERE song_id = ?1`,
+		`UPDATE duplicate_preferences SET preferred_id = ?2 WHERE preferred_id = ?1`,
+		`UPDATE OR IGNORE metadata_overrides SET song_id = ?2 WHERE song_id = ?1`,
+		`DELETE FROM metadata_overrides WHERE song_id = ?1`,
+		`UPDATE uploads SET song_id = ?2 WHERE song_id = ?1`,
+		// 别名：指向旧 ID 的别名改为指向新 ID，新 ID 自身不能再作为别名
+		`UPDATE song_id_aliases SET new_id = ?2 WHERE new_id = ?1`,
+		`DELETE FROM song_id_aliases WHERE old_id = ?2`,
+		`INSERT OR REPLACE INTO song_id_aliases (old_id, new_id, created_at) VALUES (?1, ?2, CURRENT_TIMESTAMP)`,
+	}
+
+	for oldID, newID := range renames {
+		if oldID == newID {
+			continue
+		}
+		for _, query := range queries {
+			if _, err := tx.Exec(query, oldID, newID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// LoadAliases 加载所有歌曲 ID 别名。
+func (r *SQLiteSongRepository) LoadAliases() (map[string]string, error) {
+	rows, err := r.db.Query(`SELECT old_id, new_id FROM song_id_aliases`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	aliases := make(map[string]string)
+	for rows.Next() {
+		var oldID, newID string
+		if err := rows.Scan(&oldID, &newID); err != nil {
+			return nil, err
+		}
+		aliases[oldID] = newID
+	}
+	return aliases, rows.Err()
+}
+
+// KeepAlias 将旧 ID 的别名标记为永久保留。
+func (r *SQLiteSongRepository) KeepAlias(oldID string) error {
+	_, err := r.db.Exec(`UPDATE song_id_aliases SET permanent = TRUE WHERE old_id = ?`, oldID)
+	return err
+}
+
+// PurgeAliases 删除过渡期已结束的别名，永久保留的别名不会被删除。
+func (r *SQLiteSongRepository) PurgeAliases(before time.Time) (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM song_id_aliases WHERE created_at < ? AND NOT permanent`, before.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,339 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"zero-music/models"
+)
+
+func newTestSong(id, path, artist, album string) *models.Song {
+	return &models.Song{
+		ID:                id,
+		Title:             "title-" + id,
+		Artist:            artist,
+		Artists:           []string{artist},
+		Composer:          "Composer",
+		Album:             album,
+		Duration:          215,
+		DurationEstimated: true,
+		FilePath:          path,
+		FileName:          "song.mp3",
+		FileSize:          1024,
+		AddedAt:           time.Unix(1700000000, 123),
+		Format:            ".mp3",
+		HasCover:          true,
+		CoverSource:       models.CoverSourceSidecar,
+		CoverPath:         "/music/cover.jpg",
+		ArtistImagePath:   "/music/artist.jpg",
+		HasLyrics:         true,
+		EmbeddedLyrics:    true,
+		LyricsPath:        "/music/a.lrc",
+		Year:              2020,
+		Track:             3,
+		Disc:              1,
+		Library:           "lossless",
+		Codec:             models.CodecFLAC,
+		Lossless:          true,
+		Bitrate:           2304,
+		BitrateMode:       models.BitrateModeVBR,
+		SampleRate:        96000,
+		BitDepth:          24,
+		Channels:          2,
+	}
+}
+
+func TestSQLiteSongRepository_SaveAndLoad(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewSQLiteSongRepository(db)
+
+	song := newTestSong("song1", "/music/a.mp3", "Artist", "Album")
+	song.AlbumArtist = "Various Artists"
+	trackGain, trackPeak := -6.5, 0.98
+	song.ReplayGain = &models.ReplayGain{TrackGain: &trackGain, TrackPeak: &trackPeak, Source: models.LoudnessSourceReplayGain}
+	song.CueSheetPath, song.StartMs, song.EndMs = "/music/album.cue", 183000, 401253
+	song.Issues = []models.SongIssue{{Kind: models.IssueTagError, Message: "bad frame"}, {Kind: models.IssueMissingAlbum}}
+	if err := repo.Save([]*models.Song{song}, nil); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	songs, err := repo.LoadAll()
+	if err != nil {
+		t.Fatalf("LoadAll failed: %v", err)
+	}
+	if len(songs) != 1 {
+		t.Fatalf("Expected 1 song, got %d", len(songs))
+	}
+
+	loaded := songs[0]
+	if loaded.ID != song.ID || loaded.Title != song.Title || loaded.Artist != song.Artist {
+		t.Errorf("Loaded song mismatch: %+v", loaded)
+	}
+	if loaded.Track != 3 || loaded.Disc != 1 {
+		t.Errorf("Expected track 3 disc 1, got %d %d", loaded.Track, loaded.Disc)
+	}
+	if !loaded.AddedAt.Equal(song.AddedAt) {
+		t.Errorf("Expected mod time %v, got %v", song.AddedAt, loaded.AddedAt)
+	}
+	if loaded.DurationFormatted != "3:35" {
+		t.Errorf("Expected formatted duration 3:35, got %s", loaded.DurationFormatted)
+	}
+	if !loaded.HasCover {
+		t.Error("Expected HasCover to be true")
+	}
+	if !loaded.DurationEstimated {
+		t.Error("Expected DurationEstimated to be true")
+	}
+	if loaded.CoverSource != models.CoverSourceSidecar || loaded.CoverPath != song.CoverPath || loaded.ArtistImagePath != song.ArtistImagePath {
+		t.Errorf("Artwork mismatch: %+v", loaded)
+	}
+	if !loaded.HasLyrics || !loaded.EmbeddedLyrics || loaded.LyricsPath != song.LyricsPath {
+		t.Errorf("Lyrics mismatch: %+v", loaded)
+	}
+	if loaded.Codec != models.CodecFLAC || !loaded.Lossless || loaded.Bitrate != 2304 || loaded.BitrateMode != models.BitrateModeVBR ||
+		loaded.SampleRate != 96000 || loaded.BitDepth != 24 || loaded.Channels != 2 {
+		t.Errorf("Audio properties mismatch: %+v", loaded)
+	}
+	if len(loaded.Artists) != 1 || loaded.Artists[0] != "Artist" || loaded.AlbumArtist != "Various Artists" || loaded.Composer != "Composer" {
+		t.Errorf("Artist fields mismatch: %+v", loaded)
+	}
+	if rg := loaded.ReplayGain; rg == nil || rg.Source != models.LoudnessSourceReplayGain || rg.TrackGain == nil || *rg.TrackGain != -6.5 ||
+		rg.TrackPeak == nil || *rg.TrackPeak != 0.98 || rg.AlbumGain != nil || rg.AlbumPeak != nil || rg.Loudness != nil {
+		t.Errorf("ReplayGain mismatch: %+v", loaded.ReplayGain)
+	}
+	if loaded.CueSheetPath != song.CueSheetPath || loaded.StartMs != 183000 || loaded.EndMs != 401253 {
+		t.Errorf("Cue track mismatch: %+v", loaded)
+	}
+	if len(loaded.Issues) != 2 || loaded.Issues[0] != song.Issues[0] || loaded.Issues[1].Kind != models.IssueMissingAlbum {
+		t.Errorf("Issues mismatch: %+v", loaded.Issues)
+	}
+	if loaded.Library != "lossless" {
+		t.Errorf("Expected library lossless, got %s", loaded.Library)
+	}
+}
+
+func TestSQLiteSongRepository_SaveUpdatesAndRemoves(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewSQLiteSongRepository(db)
+
+	song1 := newTestSong("song1", "/music/a.mp3", "Artist", "Album")
+	song2 := newTestSong("song2", "/music/b.mp3", "Artist", "Album")
+	if err := repo.Save([]*models.Song{song1, song2}, nil); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	song1.Title = "Updated"
+	if err := repo.Save([]*models.Song{song1}, []string{"song2"}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	count, err := repo.Count()
+	if err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 song, got %d", count)
+	}
+
+	songs, _ := repo.LoadAll()
+	if songs[0].Title != "Updated" {
+		t.Errorf("Expected title Updated, got %s", songs[0].Title)
+	}
+}
+
+func TestSQLiteSongRepository_SaveCueTracks(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewSQLiteSongRepository(db)
+
+	// 同一个整轨文件拆分出的曲目共用 file_path
+	var tracks []*models.Song
+	for i, id := range []string{"track1", "track2", "track3"} {
+		track := newTestSong(id, "/music/album.flac", "Artist", "Album")
+		track.CueSheetPath = "/music/album.cue"
+		track.Track = i + 1
+		track.StartMs = int64(i) * 180000
+		track.EndMs = int64(i+1) * 180000
+		tracks = append(tracks, track)
+	}
+	if err := repo.Save(tracks, nil); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	// 再次保存同一批曲目不会互相覆盖
+	if err := repo.Save(tracks[1:], nil); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	songs, err := repo.LoadAll()
+	if err != nil {
+		t.Fatalf("LoadAll failed: %v", err)
+	}
+	if len(songs) != 3 {
+		t.Fatalf("Expected 3 tracks, got %d", len(songs))
+	}
+	starts := map[string]int64{}
+	for _, song := range songs {
+		starts[song.ID] = song.StartMs
+	}
+	for _, track := range tracks {
+		if start, ok := starts[track.ID]; !ok || start != track.StartMs {
+			t.Errorf("Track %s mismatch: start %d, found %v", track.ID, start, ok)
+		}
+	}
+}
+
+func TestSQLiteSongRepository_SaveRebuildsSummaries(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewSQLiteSongRepository(db)
+
+	songs := []*models.Song{
+		newTestSong("song1", "/music/a.mp3", "Artist A", "Album 1"),
+		newTestSong("song2", "/music/b.mp3", "Artist A", "Album 1"),
+		newTestSong("song3", "/music/c.mp3", "Artist B", "Album 2"),
+	}
+	if err := repo.Save(songs, nil); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	var albumCount, artistCount, songCount int
+	db.QueryRow(`SELECT COUNT(*) FROM albums`).Scan(&albumCount)
+	db.QueryRow(`SELECT COUNT(*) FROM artists`).Scan(&artistCount)
+	db.QueryRow(`SELECT song_count FROM albums WHERE name = ? AND artist = ?`, "Album 1", "Artist A").Scan(&songCount)
+
+	if albumCount != 2 {
+		t.Errorf("Expected 2 albums, got %d", albumCount)
+	}
+	if artistCount != 2 {
+		t.Errorf("Expected 2 artists, got %d", artistCount)
+	}
+	if songCount != 2 {
+		t.Errorf("Expected Album 1 to have 2 songs, got %d", songCount)
+	}
+
+	// 多位艺术家分别计入艺术家表，专辑按专辑艺术家分组
+	featured := newTestSong("song4", "/music/d.mp3", "Artist A feat. Artist C", "Album 1")
+	featured.Artists = []string{"Artist A", "Artist C"}
+	compilation := newTestSong("song5", "/music/e.mp3", "Artist D", "Hits")
+	compilation.AlbumArtist = "Various Artists"
+	if err := repo.Save([]*models.Song{featured, compilation}, nil); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	var featuredCount int
+	db.QueryRow(`SELECT song_count FROM albums WHERE name = ? AND artist = ?`, "Album 1", "Artist A").Scan(&songCount)
+	db.QueryRow(`SELECT song_count FROM artists WHERE name = ?`, "Artist C").Scan(&featuredCount)
+	db.QueryRow(`SELECT COUNT(*) FROM albums WHERE name = ? AND artist = ?`, "Hits", "Various Artists").Scan(&albumCount)
+	if songCount != 3 || featuredCount != 1 || albumCount != 1 {
+		t.Errorf("Expected Album 1 with 3 songs, Artist C with 1 song and Hits by Various Artists, got %d, %d, %d", songCount, featuredCount, albumCount)
+	}
+
+	// 删除后汇总应同步更新
+	if err := repo.Save(nil, []string{"song3", "song4", "song5"}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	db.QueryRow(`SELECT COUNT(*) FROM artists`).Scan(&artistCount)
+	if artistCount != 1 {
+		t.Errorf("Expected 1 artist after removal, got %d", artistCount)
+	}
+}
+
+func TestSQLiteSongRepository_RenameIDs(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewSQLiteSongRepository(db)
+
+	statements := []string{
+		`INSERT INTO users (id, username, email, password_hash) VALUES (1, 'u1', 'u1@test.com', 'x'), (2, 'u2', 'u2@test.com', 'x')`,
+		// 用户 1 同时收藏了新旧两个 ID，用户 2 只收藏了旧 ID
+		`INSERT INTO favorites (user_id, song_id) VALUES (1, 'old'), (1, 'new'), (2, 'old')`,
+		`INSERT INTO play_stats (user_id, song_id, play_count, total_play_time, last_played_at) VALUES
+			(1, 'old', 3, 300, '2024-01-02 00:00:00'), (1, 'new', 2, 200, '2024-01-01 00:00:00'), (2, 'old', 1, 100, NULL)`,
+		`INSERT INTO play_history (user_id, song_id) VALUES (1, 'old'), (2, 'old')`,
+		`INSERT INTO playlists (id, user_id, name) VALUES (1, 1, 'p')`,
+		`INSERT INTO playlist_songs (playlist_id, song_id, position) VALUES (1, 'old', 0)`,
+		`INSERT INTO song_id_aliases (old_id, new_id) VALUES ('older', 'old')`,
+	}
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to seed data: %v", err)
+		}
+	}
+
+	if err := repo.RenameIDs(map[string]string{"old": "new"}); err != nil {
+		t.Fatalf("RenameIDs failed: %v", err)
+	}
+
+	counts := map[string]int{
+		`SELECT COUNT(*) FROM favorites WHERE song_id = 'old'`:      0,
+		`SELECT COUNT(*) FROM favorites WHERE song_id = 'new'`:      2,
+		`SELECT COUNT(*) FROM play_stats WHERE song_id = 'old'`:     0,
+		`SELECT COUNT(*) FROM play_history WHERE song_id = 'new'`:   2,
+		`SELECT COUNT(*) FROM playlist_songs WHERE song_id = 'new'`: 1,
+	}
+	for query, expected := range counts {
+		var count int
+		if err := db.QueryRow(query).Scan(&count); err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if count != expected {
+			t.Errorf("%s: expected %d, got %d", query, expected, count)
+		}
+	}
+
+	var playCount, totalTime int
+	var lastPlayed time.Time
+	err := db.QueryRow(`SELECT play_count, total_play_time, last_played_at FROM play_stats WHERE user_id = 1 AND song_id = 'new'`).
+		Scan(&playCount, &totalTime, &lastPlayed)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if playCount != 5 || totalTime != 500 || !lastPlayed.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected merged stats (5, 500, 2024-01-02), got (%d, %d, %v)", playCount, totalTime, lastPlayed)
+	}
+
+	aliases, err := repo.LoadAliases()
+	if err != nil {
+		t.Fatalf("LoadAliases failed: %v", err)
+	}
+	if aliases["old"] != "new" || aliases["older"] != "new" {
+		t.Errorf("Expected both aliases to point to new, got %v", aliases)
+	}
+}
+
+func TestSQLiteSongRepository_PurgeAliases(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewSQLiteSongRepository(db)
+
+	if _, err := db.Exec(`INSERT INTO song_id_aliases (old_id, new_id, created_at) VALUES
+		('expired', 'a', '2020-01-01 00:00:00'), ('recent', 'b', CURRENT_TIMESTAMP), ('moved', 'c', '2020-01-01 00:00:00')`); err != nil {
+		t.Fatalf("Failed to seed aliases: %v", err)
+	}
+	if err := repo.KeepAlias("moved"); err != nil {
+		t.Fatalf("KeepAlias failed: %v", err)
+	}
+
+	purged, err := repo.PurgeAliases(time.Now().Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatalf("PurgeAliases failed: %v", err)
+	}
+	if purged != 1 {
+		t.Errorf("Expected 1 purged alias, got %d", purged)
+	}
+
+	aliases, err := repo.LoadAliases()
+	if err != nil {
+		t.Fatalf("LoadAliases failed: %v", err)
+	}
+	if aliases["recent"] != "b" || aliases["moved"] != "c" || len(aliases) != 2 {
+		t.Errorf("Expected the recent and kept aliases to remain, got %v", aliases)
+	}
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"zero-music/database"
+	"zero-music/models"
+)
+
+// SQLiteTrashRepository 是 TrashRepository 的 SQLite 实现。
+type SQLiteTrashRepository struct {
+	db database.DB
+}
+
+// NewSQLiteTrashRepository 创建 SQLite 回收站仓储实例。
+func NewSQLiteTrashRepository(db database.DB) *SQLiteTrashRepository {
+	return &SQLiteTrashRepository{db: db}
+}
+
+// trashedSongColumns 是查询墓碑记录时读取的列，与 scanTrashedSong 的顺序一致。
+const trashedSongColumns = `id, song_id, library, rel_path, title, artist, album, size, trash_path, deleted_by, deleted_at, purged_at`
+
+// Trash 在一个事务中记录被删除的歌曲并清理用户数据中对它的引用。
+func (r *SQLiteTrashRepository) Trash(entry *models.TrashedSong) error {
+	if entry.DeletedAt.IsZero() {
+		entry.DeletedAt = time.Now().UTC()
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO trashed_songs (song_id, library, rel_path, title, artist, album, size, trash_path, deleted_by, deleted_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, entry.SongID, entry.Library, entry.RelPath, entry.Title, entry.Artist, entry.Album, entry.Size,
+		entry.TrashPath, entry.DeletedBy, entry.DeletedAt)
+	if err != nil {
+		return err
+	}
+
+	queries := []string{
+		// 引用被删除歌曲的播放列表更新修改时间，客户端据此刷新
+		`UPDATE playlists SET updated_at = CURRENT_TIMESTAMP
+		 WHERE id IN (SELECT playlist_id FROM playlist_songs WHERE song_id = ?1)`,
+		`DELETE FROM playlist_songs WHERE song_id = ?1`,
+		`DELETE FROM favorites WHERE song_id = ?1`,
+		`DELETE FROM play_stats WHERE song_id = ?1`,
+		// 被删除的歌曲是首选副本时，同组被隐藏的副本重新显示
+		`DELETE FROM duplicate_preferences WHERE song_id = ?1 OR preferred_id = ?1`,
+		`DELETE FROM metadata_overrides WHERE song_id = ?1`,
+		// 删除上传记录，释放上传者的配额
+		`DELETE FROM uploads WHERE song_id = ?1`,
+		`DELETE FROM song_id_aliases WHERE new_id = ?1`,
+	}
+	for _, query := range queries {
+		if _, err := tx.Exec(query, entry.SongID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if id, err := result.LastInsertId(); err == nil {
+		entry.ID = id
+	}
+	return nil
+}
+
+// List 返回仍在回收站中的歌曲，按删除时间倒序排列。
+func (r *SQLiteTrashRepository) List() ([]*models.TrashedSong, error) {
+	return r.query(`SELECT ` + trashedSongColumns + ` FROM trashed_songs
+		WHERE purged_at IS NULL ORDER BY deleted_at DESC, id DESC`)
+}
+
+// ListDeletedBefore 返回在 before 之前删除、仍在回收站中的歌曲。
+func (r *SQLiteTrashRepository) ListDeletedBefore(before time.Time) ([]*models.TrashedSong, error) {
+	return r.query(`SELECT `+trashedSongColumns+` FROM trashed_songs
+		WHERE purged_at IS NULL AND deleted_at < ? ORDER BY deleted_at`, before.UTC())
+}
+
+// MarkPurged 记录歌曲文件已从回收站中彻底删除。
+func (r *SQLiteTrashRepository) MarkPurged(id int64) error {
+	_, err := r.db.Exec(`UPDATE trashed_songs SET purged_at = ? WHERE id = ?`, time.Now().UTC(), id)
+	return err
+}
+
+// query 执行查询并读取墓碑记录。
+func (r *SQLiteTrashRepository) query(query string, args ...any) ([]*models.TrashedSong, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]*models.TrashedSong, 0)
+	for rows.Next() {
+		e := &models.TrashedSong{}
+		var purgedAt sql.NullTime
+		if err := rows.Scan(&e.ID, &e.SongID, &e.Library, &e.RelPath, &e.Title, &e.Artist, &e.Album, &e.Size,
+			&e.TrashPath, &e.DeletedBy, &e.DeletedAt, &purgedAt); err != nil {
+			return nil, err
+		}
+		if purgedAt.Valid {
+			e.PurgedAt = &purgedAt.Time
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
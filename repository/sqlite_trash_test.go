@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"zero-music/models"
+)
+
+func TestSQLiteTrashRepository_TrashCleansReferences(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewSQLiteTrashRepository(db)
+
+	statements := []string{
+		`INSERT INTO users (id, username, email, password_hash) VALUES (1, 'u1', 'u1@test.com', 'x')`,
+		`INSERT INTO favorites (user_id, song_id) VALUES (1, 'gone'), (1, 'kept')`,
+		`INSERT INTO play_stats (user_id, song_id, play_count) VALUES (1, 'gone', 3), (1, 'kept', 1)`,
+		`INSERT INTO play_history (user_id, song_id) VALUES (1, 'gone'), (1, 'kept')`,
+		`INSERT INTO playlists (id, user_id, name, updated_at) VALUES (1, 1, 'p', '2020-01-01 00:00:00'), (2, 1, 'q', '2020-01-01 00:00:00')`,
+		`INSERT INTO playlist_songs (playlist_id, song_id, position) VALUES (1, 'gone', 0), (1, 'kept', 1), (2, 'kept', 0)`,
+		`INSERT INTO duplicate_preferences (song_id, preferred_id) VALUES ('copy', 'gone'), ('other', 'kept')`,
+		`INSERT INTO metadata_overrides (song_id, title) VALUES ('gone', 'Fixed')`,
+		`INSERT INTO uploads (user_id, song_id, library, rel_path, size) VALUES (1, 'gone', 'default', 'a.mp3', 100)`,
+		`INSERT INTO song_id_aliases (old_id, new_id) VALUES ('older', 'gone')`,
+	}
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to seed data: %v", err)
+		}
+	}
+
+	entry := &models.TrashedSong{SongID: "gone", Library: "default", RelPath: "a.mp3", Title: "A", Size: 100, TrashPath: "1-gone/a.mp3", DeletedBy: 1}
+	if err := repo.Trash(entry); err != nil {
+		t.Fatalf("Trash failed: %v", err)
+	}
+	if entry.ID == 0 {
+		t.Error("Expected ID to be set")
+	}
+
+	counts := map[string]int{
+		`SELECT COUNT(*) FROM favorites WHERE song_id = 'gone'`:                   0,
+		`SELECT COUNT(*) FROM favorites WHERE song_id = 'kept'`:                   1,
+		`SELECT COUNT(*) FROM play_stats WHERE song_id = 'gone'`:                  0,
+		`SELECT COUNT(*) FROM play_history WHERE song_id = 'gone'`:                1,
+		`SELECT COUNT(*) FROM playlist_songs WHERE song_id = 'gone'`:              0,
+		`SELECT COUNT(*) FROM playlist_songs WHERE song_id = 'kept'`:              2,
+		`SELECT COUNT(*) FROM playlists WHERE updated_at > '2020-01-01 00:00:00'`: 1,
+		`SELECT COUNT(*) FROM duplicate_preferences`:                              1,
+		`SELECT COUNT(*) FROM metadata_overrides`:                                 0,
+		`SELECT COUNT(*) FROM uploads`:                                            0,
+		`SELECT COUNT(*) FROM song_id_aliases`:                                    0,
+	}
+	for query, expected := range counts {
+		var count int
+		if err := db.QueryRow(query).Scan(&count); err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if count != expected {
+			t.Errorf("%s: expected %d, got %d", query, expected, count)
+		}
+	}
+
+	list, err := repo.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(list) != 1 || list[0].SongID != "gone" || list[0].TrashPath != "1-gone/a.mp3" || list[0].PurgedAt != nil {
+		t.Fatalf("Unexpected trash: %+v", list)
+	}
+
+	if expired, err := repo.ListDeletedBefore(time.Now().Add(-time.Hour)); err != nil || len(expired) != 0 {
+		t.Errorf("Expected no expired entries, got %v %v", expired, err)
+	}
+	expired, err := repo.ListDeletedBefore(time.Now().Add(time.Hour))
+	if err != nil || len(expired) != 1 {
+		t.Fatalf("Expected one expired entry, got %v %v", expired, err)
+	}
+
+	if err := repo.MarkPurged(expired[0].ID); err != nil {
+		t.Fatalf("MarkPurged failed: %v", err)
+	}
+	if list, _ := repo.List(); len(list) != 0 {
+		t.Errorf("Expected purged entry to leave the trash, got %+v", list)
+	}
+	// 墓碑记录本身保留
+	var count int
+	db.QueryRow(`SELECT COUNT(*) FROM trashed_songs WHERE song_id = 'gone' AND purged_at IS NOT NULL`).Scan(&count)
+	if count != 1 {
+		t.Errorf("Expected tombstone to be kept after purge, got %d", count)
+	}
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"zero-music/database"
+	"zero-music/models"
+)
+
+// SQLiteUploadRepository 是 UploadRepository 的 SQLite 实现。
+type SQLiteUploadRepository struct {
+	db database.DB
+}
+
+// NewSQLiteUploadRepository 创建 SQLite 上传记录仓储实例。
+func NewSQLiteUploadRepository(db database.DB) *SQLiteUploadRepository {
+	return &SQLiteUploadRepository{db: db}
+}
+
+// Create 记录一个上传的文件。同一路径上已有的记录（如覆盖了被删除的旧文件）会被替换。
+func (r *SQLiteUploadRepository) Create(upload *models.Upload) error {
+	if upload.CreatedAt.IsZero() {
+		upload.CreatedAt = time.Now()
+	}
+	result, err := r.db.Exec(`
+		INSERT INTO uploads (user_id, song_id, library, rel_path, size, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(library, rel_path) DO UPDATE SET
+			user_id = excluded.user_id,
+			song_id = excluded.song_id,
+			size = excluded.size,
+			created_at = excluded.created_at
+	`, upload.UserID, upload.SongID, upload.Library, upload.RelPath, upload.Size, upload.CreatedAt)
+	if err != nil {
+		return err
+	}
+	if id, err := result.LastInsertId(); err == nil {
+		upload.ID = id
+	}
+	return nil
+}
+
+// ListByUser 返回用户上传的文件，按上传时间倒序排列。
+func (r *SQLiteUploadRepository) ListByUser(userID int64) ([]*models.Upload, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, song_id, library, rel_path, size, created_at
+		FROM uploads
+		WHERE user_id = ?
+		ORDER BY created_at DESC, id DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	uploads := make([]*models.Upload, 0)
+	for rows.Next() {
+		u := &models.Upload{}
+		if err := rows.Scan(&u.ID, &u.UserID, &u.SongID, &u.Library, &u.RelPath, &u.Size, &u.CreatedAt); err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, u)
+	}
+	return uploads, rows.Err()
+}
+
+// Usage 返回用户已上传文件的总大小（字节）。
+func (r *SQLiteUploadRepository) Usage(userID int64) (int64, error) {
+	var used int64
+	err := r.db.QueryRow(`SELECT COALESCE(SUM(size), 0) FROM uploads WHERE user_id = ?`, userID).Scan(&used)
+	return used, err
+}
+
+// GetQuota 返回管理员为用户单独设置的配额（字节）。
+func (r *SQLiteUploadRepository) GetQuota(userID int64) (int64, bool, error) {
+	var quota int64
+	err := r.db.QueryRow(`SELECT quota_bytes FROM user_quotas WHERE user_id = ?`, userID).Scan(&quota)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return quota, true, nil
+}
+
+// SetQuota 为用户单独设置配额（字节），0 表示不限制。
+func (r *SQLiteUploadRepository) SetQuota(userID int64, quota int64) error {
+	_, err := r.db.Exec(`
+		INSERT INTO user_quotas (user_id, quota_bytes, updated_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(user_id) DO UPDATE SET
+			quota_bytes = excluded.quota_bytes,
+			updated_at = excluded.updated_at
+	`, userID, quota)
+	return err
+}
+
+// DeleteQuota 删除用户单独设置的配额。
+func (r *SQLiteUploadRepository) DeleteQuota(userID int64) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM user_quotas WHERE user_id = ?`, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// UpdateLocation 将位于 library 中 relPath 的上传记录更新为移动后的位置。
+// 目标位置已有其他上传记录时返回唯一约束错误，由调用方撤销移动，不会覆盖其他用户的记录。
+func (r *SQLiteUploadRepository) UpdateLocation(library, relPath, newLibrary, newRelPath string) error {
+	_, err := r.db.Exec(`
+		UPDATE uploads SET library = ?, rel_path = ? WHERE library = ? AND rel_path = ?
+	`, newLibrary, newRelPath, library, relPath)
+	return err
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"zero-music/database"
+	"zero-music/models"
+)
+
+// SQLiteUploadSessionRepository 是 UploadSessionRepository 的 SQLite 实现。
+type SQLiteUploadSessionRepository struct {
+	db database.DB
+}
+
+// NewSQLiteUploadSessionRepository 创建 SQLite 上传会话仓储实例。
+func NewSQLiteUploadSessionRepository(db database.DB) *SQLiteUploadSessionRepository {
+	return &SQLiteUploadSessionRepository{db: db}
+}
+
+// uploadSessionColumns 是查询上传会话时读取的列，与 scanUploadSession 的顺序一致。
+const uploadSessionColumns = `id, user_id, filename, metadata, length, upload_offset, song_ids, created_at, updated_at, expires_at`
+
+// Create 创建一个上传会话。
+func (r *SQLiteUploadSessionRepository) Create(session *models.UploadSession) error {
+	now := time.Now().UTC()
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = now
+	}
+	session.UpdatedAt = now
+	_, err := r.db.Exec(`
+		INSERT INTO upload_sessions (id, user_id, filename, metadata, length, upload_offset, created_at, updated_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, session.ID, session.UserID, session.Filename, session.Metadata, session.Length, session.Offset,
+		session.CreatedAt, session.UpdatedAt, session.ExpiresAt.UTC())
+	return err
+}
+
+// FindByID 根据 ID 查找上传会话。
+func (r *SQLiteUploadSessionRepository) FindByID(id string) (*models.UploadSession, error) {
+	row := r.db.QueryRow(`SELECT `+uploadSessionColumns+` FROM upload_sessions WHERE id = ?`, id)
+	session, err := scanUploadSession(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return session, err
+}
+
+// UpdateOffset 更新已接收的字节数并顺延过期时间。
+func (r *SQLiteUploadSessionRepository) UpdateOffset(id string, offset int64, expiresAt time.Time) error {
+	_, err := r.db.Exec(`
+		UPDATE upload_sessions SET upload_offset = ?, expires_at = ?, updated_at = ? WHERE id = ?
+	`, offset, expiresAt.UTC(), time.Now().UTC(), id)
+	return err
+}
+
+// Complete 记录文件放入音乐库后得到的歌曲 ID。
+func (r *SQLiteUploadSessionRepository) Complete(id string, songIDs []string) error {
+	data, err := json.Marshal(songIDs)
+	if err != nil {
+		return fmt.Errorf("编码歌曲 ID 列表失败: %w", err)
+	}
+	_, err = r.db.Exec(`
+		UPDATE upload_sessions SET song_ids = ?, updated_at = ? WHERE id = ?
+	`, string(data), time.Now().UTC(), id)
+	return err
+}
+
+// Delete 删除上传会话。
+func (r *SQLiteUploadSessionRepository) Delete(id string) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM upload_sessions WHERE id = ?`, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// ListExpired 返回在 now 之前过期的上传会话。
+func (r *SQLiteUploadSessionRepository) ListExpired(now time.Time) ([]*models.UploadSession, error) {
+	rows, err := r.db.Query(`
+		SELECT `+uploadSessionColumns+` FROM upload_sessions WHERE expires_at <= ? ORDER BY expires_at
+	`, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make([]*models.UploadSession, 0)
+	for rows.Next() {
+		session, err := scanUploadSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// scanUploadSession 从查询结果中读取一个上传会话。
+func scanUploadSession(row interface{ Scan(dest ...any) error }) (*models.UploadSession, error) {
+	s := &models.UploadSession{}
+	var songIDs string
+	if err := row.Scan(&s.ID, &s.UserID, &s.Filename, &s.Metadata, &s.Length, &s.Offset, &songIDs,
+		&s.CreatedAt, &s.UpdatedAt, &s.ExpiresAt); err != nil {
+		return nil, err
+	}
+	if songIDs != "" {
+		if err := json.Unmarshal([]byte(songIDs), &s.SongIDs); err != nil {
+			return nil, fmt.Errorf("解析上传会话 %s 的歌曲 ID 列表失败: %w", s.ID, err)
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"zero-music/models"
+)
+
+func TestSQLiteUploadSessionRepository(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewSQLiteUploadSessionRepository(db)
+	now := time.Now()
+
+	sessions := []*models.UploadSession{
+		{ID: "active", UserID: 1, Filename: "album.zip", Metadata: "filename YWxidW0uemlw", Length: 1000, ExpiresAt: now.Add(time.Hour)},
+		{ID: "stale", UserID: 1, Filename: "song.mp3", Length: 10, ExpiresAt: now.Add(-time.Minute)},
+	}
+	for _, session := range sessions {
+		if err := repo.Create(session); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	found, err := repo.FindByID("active")
+	if err != nil || found == nil {
+		t.Fatalf("FindByID failed: %v %v", found, err)
+	}
+	if found.Filename != "album.zip" || found.Metadata != "filename YWxidW0uemlw" || found.Length != 1000 || found.Offset != 0 || found.SongIDs != nil {
+		t.Errorf("Unexpected session: %+v", found)
+	}
+	if missing, err := repo.FindByID("missing"); err != nil || missing != nil {
+		t.Errorf("Expected nil for missing session, got %v %v", missing, err)
+	}
+
+	expiresAt := now.Add(2 * time.Hour).Truncate(time.Second)
+	if err := repo.UpdateOffset("active", 1000, expiresAt); err != nil {
+		t.Fatalf("UpdateOffset failed: %v", err)
+	}
+	if err := repo.Complete("active", []string{"song1", "song2"}); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	found, _ = repo.FindByID("active")
+	if found.Offset != 1000 || !found.ExpiresAt.Equal(expiresAt) || len(found.SongIDs) != 2 || found.SongIDs[1] != "song2" {
+		t.Errorf("Unexpected session after update: %+v", found)
+	}
+
+	expired, err := repo.ListExpired(now)
+	if err != nil {
+		t.Fatalf("ListExpired failed: %v", err)
+	}
+	if len(expired) != 1 || expired[0].ID != "stale" {
+		t.Errorf("Expected only stale session to be expired, got %+v", expired)
+	}
+
+	if deleted, err := repo.Delete("stale"); err != nil || !deleted {
+		t.Errorf("Expected stale session to be deleted, got %v %v", deleted, err)
+	}
+	if deleted, _ := repo.Delete("stale"); deleted {
+		t.Error("Expected second delete to report missing session")
+	}
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"testing"
+
+	"zero-music/models"
+)
+
+func TestSQLiteUploadRepository_UsageAndList(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewSQLiteUploadRepository(db)
+
+	uploads := []*models.Upload{
+		{UserID: 1, SongID: "song1", Library: "default", RelPath: "A/B/01 One.mp3", Size: 100},
+		{UserID: 1, SongID: "song2", Library: "default", RelPath: "A/B/02 Two.mp3", Size: 200},
+		{UserID: 2, SongID: "song3", Library: "default", RelPath: "C/D/01 Three.mp3", Size: 50},
+	}
+	for _, upload := range uploads {
+		if err := repo.Create(upload); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if upload.ID == 0 {
+			t.Error("Expected ID to be set")
+		}
+	}
+
+	if used, err := repo.Usage(1); err != nil || used != 300 {
+		t.Errorf("Expected usage 300, got %d %v", used, err)
+	}
+	if used, err := repo.Usage(3); err != nil || used != 0 {
+		t.Errorf("Expected usage 0 for user without uploads, got %d %v", used, err)
+	}
+
+	// 同一路径上的新上传替换原有记录
+	if err := repo.Create(&models.Upload{UserID: 2, SongID: "song2", Library: "default", RelPath: "A/B/02 Two.mp3", Size: 300}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if used, _ := repo.Usage(1); used != 100 {
+		t.Errorf("Expected usage 100 after path taken over, got %d", used)
+	}
+
+	list, err := repo.ListByUser(2)
+	if err != nil {
+		t.Fatalf("ListByUser failed: %v", err)
+	}
+	if len(list) != 2 || list[0].SongID != "song2" || list[0].Size != 300 || list[1].RelPath != "C/D/01 Three.mp3" {
+		t.Errorf("Unexpected uploads: %+v", list)
+	}
+}
+
+func TestSQLiteUploadRepository_Quota(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewSQLiteUploadRepository(db)
+
+	if _, custom, err := repo.GetQuota(1); err != nil || custom {
+		t.Fatalf("Expected no custom quota, got %v %v", custom, err)
+	}
+	if err := repo.SetQuota(1, 1024); err != nil {
+		t.Fatalf("SetQuota failed: %v", err)
+	}
+	if err := repo.SetQuota(1, 2048); err != nil {
+		t.Fatalf("SetQuota failed: %v", err)
+	}
+	if quota, custom, err := repo.GetQuota(1); err != nil || !custom || quota != 2048 {
+		t.Errorf("Expected quota 2048, got %d %v %v", quota, custom, err)
+	}
+
+	if deleted, err := repo.DeleteQuota(1); err != nil || !deleted {
+		t.Errorf("Expected quota deleted, got %v %v", deleted, err)
+	}
+	if deleted, err := repo.DeleteQuota(1); err != nil || deleted {
+		t.Errorf("Expected nothing to delete, got %v %v", deleted, err)
+	}
+	if _, custom, _ := repo.GetQuota(1); custom {
+		t.Error("Expected default quota after delete")
+	}
+}
+
+func TestSQLiteUploadRepository_UpdateLocation(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewSQLiteUploadRepository(db)
+	if err := repo.Create(&models.Upload{UserID: 1, SongID: "song1", Library: "default", RelPath: "A/old.mp3", Size: 10}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := repo.UpdateLocation("default", "A/old.mp3", "lossy", "B/new.mp3"); err != nil {
+		t.Fatalf("UpdateLocation failed: %v", err)
+	}
+	list, _ := repo.ListByUser(1)
+	if len(list) != 1 || list[0].Library != "lossy" || list[0].RelPath != "B/new.mp3" {
+		t.Errorf("Unexpected uploads: %+v", list)
+	}
+
+	// 目标位置上其他用户的上传记录不会被覆盖
+	if err := repo.Create(&models.Upload{UserID: 2, SongID: "song2", Library: "default", RelPath: "C/other.mp3", Size: 20}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := repo.UpdateLocation("lossy", "B/new.mp3", "default", "C/other.mp3"); err == nil {
+		t.Fatal("Expected a constraint error when the target is taken")
+	}
+	if used, _ := repo.Usage(2); used != 20 {
+		t.Errorf("Expected other user's upload to be kept, got usage %d", used)
+	}
+	list, _ = repo.ListByUser(1)
+	if len(list) != 1 || list[0].RelPath != "B/new.mp3" {
+		t.Errorf("Expected failed update to leave the record unchanged: %+v", list)
+	}
+}
@@ -74,6 +74,134 @@ func setupTestDB(t *testing.T) *sql.DB {
 			UNIQUE(playlist_id, song_id),
 			FOREIGN KEY (playlist_id) REFERENCES playlists(id) ON DELETE CASCADE
 		)`,
+		`CREATE TABLE IF NOT EXISTS songs (
+			id TEXT PRIMARY KEY,
+			file_path TEXT NOT NULL,
+			file_name TEXT NOT NULL,
+			title TEXT NOT NULL DEFAULT '',
+			artist TEXT NOT NULL DEFAULT '',
+			artists TEXT NOT NULL DEFAULT '',
+			album_artist TEXT NOT NULL DEFAULT '',
+			composer TEXT NOT NULL DEFAULT '',
+			album TEXT NOT NULL DEFAULT '',
+			genre TEXT NOT NULL DEFAULT '',
+			year INTEGER DEFAULT 0,
+			track INTEGER DEFAULT 0,
+			disc INTEGER DEFAULT 0,
+			duration INTEGER DEFAULT 0,
+			duration_estimated BOOLEAN DEFAULT FALSE,
+			codec TEXT NOT NULL DEFAULT '',
+			lossless BOOLEAN DEFAULT FALSE,
+			bitrate INTEGER DEFAULT 0,
+			bitrate_mode TEXT NOT NULL DEFAULT '',
+			sample_rate INTEGER DEFAULT 0,
+			bit_depth INTEGER DEFAULT 0,
+			channels INTEGER DEFAULT 0,
+			track_gain REAL,
+			track_peak REAL,
+			album_gain REAL,
+			album_peak REAL,
+			loudness REAL,
+			loudness_source TEXT NOT NULL DEFAULT '',
+			cue_sheet_path TEXT NOT NULL DEFAULT '',
+			start_ms INTEGER NOT NULL DEFAULT 0,
+			end_ms INTEGER NOT NULL DEFAULT 0,
+			issues TEXT NOT NULL DEFAULT '',
+			file_size INTEGER DEFAULT 0,
+			mod_time INTEGER DEFAULT 0,
+			format TEXT NOT NULL DEFAULT '',
+			has_cover BOOLEAN DEFAULT FALSE,
+			cover_source TEXT NOT NULL DEFAULT '',
+			cover_path TEXT NOT NULL DEFAULT '',
+			artist_image_path TEXT NOT NULL DEFAULT '',
+			has_lyrics BOOLEAN DEFAULT FALSE,
+			embedded_lyrics BOOLEAN DEFAULT FALSE,
+			lyrics_path TEXT NOT NULL DEFAULT '',
+			library TEXT NOT NULL DEFAULT '',
+			rel_path TEXT NOT NULL DEFAULT '',
+			content_hash TEXT NOT NULL DEFAULT '',
+			scanned_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS albums (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			artist TEXT NOT NULL,
+			year INTEGER DEFAULT 0,
+			song_count INTEGER DEFAULT 0,
+			UNIQUE(name, artist)
+		)`,
+		`CREATE TABLE IF NOT EXISTS artists (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT UNIQUE NOT NULL,
+			song_count INTEGER DEFAULT 0
+		)`,
+		`CREATE TABLE IF NOT EXISTS song_id_aliases (
+			old_id TEXT PRIMARY KEY,
+			new_id TEXT NOT NULL,
+			permanent BOOLEAN NOT NULL DEFAULT FALSE,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS duplicate_preferences (
+			song_id TEXT PRIMARY KEY,
+			preferred_id TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS metadata_overrides (
+			song_id TEXT PRIMARY KEY,
+			title TEXT,
+			artist TEXT,
+			album TEXT,
+			genre TEXT,
+			year INTEGER,
+			cover BLOB,
+			cover_mime TEXT NOT NULL DEFAULT '',
+			cover_hash TEXT NOT NULL DEFAULT '',
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS uploads (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			song_id TEXT NOT NULL,
+			library TEXT NOT NULL,
+			rel_path TEXT NOT NULL,
+			size INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(library, rel_path),
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+		`CREATE TABLE IF NOT EXISTS user_quotas (
+			user_id INTEGER PRIMARY KEY,
+			quota_bytes INTEGER NOT NULL DEFAULT 0,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+		`CREATE TABLE IF NOT EXISTS upload_sessions (
+			id TEXT PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			filename TEXT NOT NULL,
+			metadata TEXT NOT NULL DEFAULT '',
+			length INTEGER NOT NULL,
+			upload_offset INTEGER NOT NULL DEFAULT 0,
+			song_ids TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			expires_at DATETIME NOT NULL,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+		`CREATE TABLE IF NOT EXISTS trashed_songs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			song_id TEXT NOT NULL,
+			library TEXT NOT NULL,
+			rel_path TEXT NOT NULL,
+			title TEXT NOT NULL DEFAULT '',
+			artist TEXT NOT NULL DEFAULT '',
+			album TEXT NOT NULL DEFAULT '',
+			size INTEGER NOT NULL DEFAULT 0,
+			trash_path TEXT NOT NULL,
+			deleted_by INTEGER NOT NULL DEFAULT 0,
+			deleted_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			purged_at DATETIME
+		)`,
 	}
 
 	for _, schema := range schemas {
@@ -0,0 +1,324 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // 注册 GIF 解码器，GIF 封面生成 PNG 缩略图
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"zero-music/logger"
+	"zero-music/models"
+
+	"github.com/dhowden/tag"
+)
+
+const (
+	// thumbnailJPEGQuality 是生成 JPEG 缩略图时使用的压缩质量。
+	thumbnailJPEGQuality = 85
+	// maxCoverPixels 是允许解码的封面图片最大像素数，防止超大图片耗尽内存。
+	maxCoverPixels = 8192 * 8192
+)
+
+// ErrNoCover 表示歌曲没有可用的封面图片。
+var ErrNoCover = errors.New("没有封面图片")
+
+// Cover 是一张封面图片及其 MIME 类型。
+type Cover struct {
+	Data     []byte
+	MIMEType string
+}
+
+// CoverService 负责读取歌曲封面，并生成和缓存缩略图。
+type CoverService struct {
+	cacheDir       string
+	maxSize        int
+	overrideCovers func(songID string) (*Cover, error) // 读取元数据覆盖中的封面，为 nil 时没有覆盖封面
+}
+
+// NewCoverService 创建一个新的 CoverService 实例，缩略图缓存在 cacheDir 目录下。
+func NewCoverService(cacheDir string, maxSize int) *CoverService {
+	return &CoverService{
+		cacheDir: cacheDir,
+		maxSize:  maxSize,
+	}
+}
+
+// SetOverrideCovers 设置读取元数据覆盖中封面图片的函数。
+func (s *CoverService) SetOverrideCovers(load func(songID string) (*Cover, error)) {
+	s.overrideCovers = load
+}
+
+// MaxSize 返回允许请求的最大缩略图边长。
+func (s *CoverService) MaxSize() int {
+	return s.maxSize
+}
+
+// imageSource 描述一张可以生成缩略图的原图。
+type imageSource struct {
+	version string // 原图的版本标识，原图变化后随之改变，用于计算 ETag 和缓存键
+	load    func() (*Cover, error)
+}
+
+// songSource 返回歌曲封面的原图来源。
+func (s *CoverService) songSource(song *models.Song) imageSource {
+	switch song.CoverSource {
+	case models.CoverSourceSidecar:
+		return fileSource(song.CoverPath)
+	case models.CoverSourceOverride:
+		return imageSource{
+			version: fmt.Sprintf("override|%s|%s", song.ID, song.CoverVersion),
+			load: func() (*Cover, error) {
+				if s.overrideCovers == nil {
+					return nil, ErrNoCover
+				}
+				return s.overrideCovers(song.ID)
+			},
+		}
+	}
+	return imageSource{
+		version: fmt.Sprintf("embedded|%s|%d|%d", song.ID, song.FileSize, song.AddedAt.UnixNano()),
+		load:    func() (*Cover, error) { return readEmbeddedCover(song.FilePath) },
+	}
+}
+
+// fileSource 返回图片文件的原图来源。同一目录下的歌曲共享同一个封面文件，因此也共享缩略图缓存。
+func fileSource(path string) imageSource {
+	version := "file|" + path
+	if info, err := os.Stat(path); err == nil {
+		version = fmt.Sprintf("file|%s|%d|%d", path, info.Size(), info.ModTime().UnixNano())
+	}
+	return imageSource{
+		version: version,
+		load:    func() (*Cover, error) { return readImageFile(path) },
+	}
+}
+
+// ETag 返回歌曲封面在指定尺寸下的实体标签，size 为 0 表示原图。
+// 嵌入的封面由歌曲 ID、文件大小和修改时间计算，目录中的封面文件由其路径、大小和修改时间计算，
+// 元数据覆盖中的封面由图片内容的哈希计算，原图变化后自动失效，且无需读取图片即可响应条件请求。
+func (s *CoverService) ETag(song *models.Song, size int) string {
+	return `"` + s.cacheKey(s.songSource(song), size) + `"`
+}
+
+// Get 返回歌曲的封面。size 为 0 时返回原图，否则返回长边不超过 size 像素的缩略图。
+// 原图本身不超过 size 时直接返回原图，不会放大。
+func (s *CoverService) Get(song *models.Song, size int) (*Cover, error) {
+	return s.get(s.songSource(song), size)
+}
+
+// ImageETag 返回图片文件（如艺术家图片）在指定尺寸下的实体标签。
+func (s *CoverService) ImageETag(path string, size int) string {
+	return `"` + s.cacheKey(fileSource(path), size) + `"`
+}
+
+// GetImage 返回图片文件（如艺术家图片），size 的含义与 Get 相同。
+func (s *CoverService) GetImage(path string, size int) (*Cover, error) {
+	return s.get(fileSource(path), size)
+}
+
+// cacheKey 返回缩略图缓存的键。
+func (s *CoverService) cacheKey(src imageSource, size int) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d", src.version, size)))
+	return hex.EncodeToString(sum[:16])
+}
+
+// get 读取原图并按需生成和缓存缩略图。
+func (s *CoverService) get(src imageSource, size int) (*Cover, error) {
+	if size > 0 {
+		if cover, ok := s.readCache(src, size); ok {
+			return cover, nil
+		}
+	}
+
+	original, err := src.load()
+	if err != nil {
+		return nil, err
+	}
+	if size <= 0 {
+		return original, nil
+	}
+
+	thumbnail, err := makeThumbnail(original, size)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.writeCache(src, size, thumbnail); err != nil {
+		logger.Warnf("缓存封面缩略图失败: %v", err)
+	}
+	return thumbnail, nil
+}
+
+// cachePath 返回缩略图缓存文件的路径。
+func (s *CoverService) cachePath(src imageSource, size int) string {
+	return filepath.Join(s.cacheDir, s.cacheKey(src, size)+".thumb")
+}
+
+// readCache 读取缓存的缩略图，缓存不存在时返回 false。
+func (s *CoverService) readCache(src imageSource, size int) (*Cover, bool) {
+	data, err := os.ReadFile(s.cachePath(src, size))
+	if err != nil {
+		return nil, false
+	}
+	return &Cover{Data: data, MIMEType: http.DetectContentType(data)}, true
+}
+
+// writeCache 将缩略图写入缓存。先写入临时文件再重命名，避免并发请求读到不完整的文件。
+func (s *CoverService) writeCache(src imageSource, size int, cover *Cover) error {
+	if err := os.MkdirAll(s.cacheDir, 0o755); err != nil {
+		return fmt.Errorf("创建缓存目录失败: %w", err)
+	}
+	tmp, err := os.CreateTemp(s.cacheDir, ".thumb-*")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(cover.Data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("写入临时文件失败: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("关闭临时文件失败: %w", err)
+	}
+	return os.Rename(tmp.Name(), s.cachePath(src, size))
+}
+
+// readImageFile 读取图片文件，文件不存在时返回 ErrNoCover。
+func readImageFile(path string) (*Cover, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoCover
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取图片文件失败: %w", err)
+	}
+	return &Cover{Data: data, MIMEType: http.DetectContentType(data)}, nil
+}
+
+// readEmbeddedCover 读取音频文件中嵌入的封面图片。
+func readEmbeddedCover(filePath string) (*Cover, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("打开文件失败: %w", err)
+	}
+	defer file.Close()
+
+	metadata, err := tag.ReadFrom(file)
+	if errors.Is(err, tag.ErrNoTagsFound) {
+		return nil, ErrNoCover
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取标签失败: %w", err)
+	}
+
+	picture := metadata.Picture()
+	if picture == nil || len(picture.Data) == 0 {
+		return nil, ErrNoCover
+	}
+
+	// 标签中记录的 MIME 类型并不总是可靠（例如只写了 "jpg"），无效时根据内容识别
+	mimeType := strings.ToLower(picture.MIMEType)
+	if !strings.HasPrefix(mimeType, "image/") {
+		mimeType = http.DetectContentType(picture.Data)
+	}
+	return &Cover{Data: picture.Data, MIMEType: mimeType}, nil
+}
+
+// makeThumbnail 将封面缩小到长边不超过 size 像素。JPEG 原图生成 JPEG 缩略图，其他格式生成 PNG 以保留透明度。
+// 无法解码的格式（如 WebP）原样返回。
+func makeThumbnail(original *Cover, size int) (*Cover, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(original.Data))
+	if err != nil {
+		logger.Warnf("无法解码封面图片，返回原图: %v", err)
+		return original, nil
+	}
+	if config.Width <= size && config.Height <= size {
+		return original, nil
+	}
+	if config.Width*config.Height > maxCoverPixels {
+		return nil, fmt.Errorf("封面图片尺寸过大: %dx%d", config.Width, config.Height)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(original.Data))
+	if err != nil {
+		return nil, fmt.Errorf("解码封面图片失败: %w", err)
+	}
+
+	width, height := fitWithin(config.Width, config.Height, size)
+	dst := resizeImage(src, width, height)
+
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: thumbnailJPEGQuality})
+		if err != nil {
+			return nil, fmt.Errorf("编码 JPEG 缩略图失败: %w", err)
+		}
+		return &Cover{Data: buf.Bytes(), MIMEType: "image/jpeg"}, nil
+	}
+	if err := png.Encode(&buf, dst); err != nil {
+		return nil, fmt.Errorf("编码 PNG 缩略图失败: %w", err)
+	}
+	return &Cover{Data: buf.Bytes(), MIMEType: "image/png"}, nil
+}
+
+// fitWithin 按比例计算长边等于 size 时的宽高。
+func fitWithin(width, height, size int) (int, int) {
+	if width >= height {
+		return size, max(1, (height*size+width/2)/width)
+	}
+	return max(1, (width*size+height/2)/height), size
+}
+
+// resizeImage 使用区域平均（盒式滤波）将图片缩小到指定尺寸。
+// 在预乘 alpha 的 RGBA 空间中求平均，透明像素不会使边缘变暗。
+func resizeImage(src image.Image, width, height int) *image.RGBA {
+	bounds := src.Bounds()
+	rgba, ok := src.(*image.RGBA)
+	if !ok || bounds.Min != (image.Point{}) {
+		rgba = image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+	}
+
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := y*srcH/height, (y+1)*srcH/height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0, x1 := x*srcW/width, (x+1)*srcW/width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, a uint64
+			for sy := y0; sy < y1; sy++ {
+				row := rgba.Pix[sy*rgba.Stride+x0*4 : sy*rgba.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					r += uint64(row[i])
+					g += uint64(row[i+1])
+					b += uint64(row[i+2])
+					a += uint64(row[i+3])
+				}
+			}
+
+			n := uint64((x1 - x0) * (y1 - y0))
+			offset := y*dst.Stride + x*4
+			dst.Pix[offset] = uint8((r + n/2) / n)
+			dst.Pix[offset+1] = uint8((g + n/2) / n)
+			dst.Pix[offset+2] = uint8((b + n/2) / n)
+			dst.Pix[offset+3] = uint8((a + n/2) / n)
+		}
+	}
+	return dst
+}
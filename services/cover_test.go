@@ -0,0 +1,217 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"zero-music/models"
+)
+
+// encodeTestImage 生成一张 width×height 的纯色图片，format 为 "png" 或 "jpeg"。
+func encodeTestImage(t *testing.T, format string, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = 200, 100, 50, 255
+	}
+	var buf bytes.Buffer
+	var err error
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, img, nil)
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// writeSongWithCover 写入一个带 ID3v2.3 APIC 封面帧的 MP3 文件，picture 为空时不写入封面。
+func writeSongWithCover(t *testing.T, dir, name, mimeType string, picture []byte) *models.Song {
+	t.Helper()
+	var frames bytes.Buffer
+	writeFrame := func(id string, body []byte) {
+		header := make([]byte, 10)
+		copy(header, id)
+		binary.BigEndian.PutUint32(header[4:8], uint32(len(body)))
+		frames.Write(header)
+		frames.Write(body)
+	}
+	writeFrame("TIT2", append([]byte{0}, "Cover Song"...))
+	if picture != nil {
+		body := append([]byte{0}, mimeType...)
+		body = append(body, 0, 3, 0) // 结束符、封面类型（封面正面）、空描述
+		writeFrame("APIC", append(body, picture...))
+	}
+
+	size := frames.Len()
+	data := []byte{'I', 'D', '3', 3, 0, 0, byte(size >> 21 & 0x7f), byte(size >> 14 & 0x7f), byte(size >> 7 & 0x7f), byte(size & 0x7f)}
+	data = append(data, frames.Bytes()...)
+	data = append(data, make([]byte, 1024)...)
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	song := models.NewSong(path, int64(len(data)))
+	if err := song.UpdateMetadata(); err != nil {
+		t.Fatal(err)
+	}
+	return song
+}
+
+func TestCoverService_Original(t *testing.T) {
+	picture := encodeTestImage(t, "png", 40, 20)
+	song := writeSongWithCover(t, t.TempDir(), "song.mp3", "image/png", picture)
+	if !song.HasCover {
+		t.Fatal("期望歌曲带有封面")
+	}
+
+	service := NewCoverService(t.TempDir(), 1024)
+	cover, err := service.Get(song, 0)
+	if err != nil {
+		t.Fatalf("读取封面失败: %v", err)
+	}
+	if cover.MIMEType != "image/png" || !bytes.Equal(cover.Data, picture) {
+		t.Errorf("期望原样返回 PNG 封面, 得到 %s (%d 字节)", cover.MIMEType, len(cover.Data))
+	}
+
+	// 不放大比请求尺寸小的图片
+	cover, err = service.Get(song, 100)
+	if err != nil {
+		t.Fatalf("读取封面失败: %v", err)
+	}
+	if !bytes.Equal(cover.Data, picture) {
+		t.Error("期望原图小于请求尺寸时返回原图")
+	}
+}
+
+func TestCoverService_Thumbnail(t *testing.T) {
+	testCases := []struct {
+		format   string
+		mimeType string
+	}{
+		{"png", "image/png"},
+		// 标签中的 MIME 类型无效时根据内容识别
+		{"jpeg", "jpg"},
+	}
+	for _, tc := range testCases {
+		dir := t.TempDir()
+		song := writeSongWithCover(t, dir, "song.mp3", tc.mimeType, encodeTestImage(t, tc.format, 300, 150))
+		cacheDir := filepath.Join(dir, "covers")
+		service := NewCoverService(cacheDir, 1024)
+
+		cover, err := service.Get(song, 64)
+		if err != nil {
+			t.Fatalf("%s: 生成缩略图失败: %v", tc.format, err)
+		}
+		if cover.MIMEType != "image/"+tc.format {
+			t.Errorf("%s: 期望 MIME 类型 image/%s, 得到 %s", tc.format, tc.format, cover.MIMEType)
+		}
+		config, format, err := image.DecodeConfig(bytes.NewReader(cover.Data))
+		if err != nil {
+			t.Fatalf("%s: 解码缩略图失败: %v", tc.format, err)
+		}
+		if format != tc.format || config.Width != 64 || config.Height != 32 {
+			t.Errorf("%s: 期望 64x32 的缩略图, 得到 %s %dx%d", tc.format, format, config.Width, config.Height)
+		}
+
+		// 删除源文件后仍能从磁盘缓存读取
+		if err := os.Remove(song.FilePath); err != nil {
+			t.Fatal(err)
+		}
+		cached, err := service.Get(song, 64)
+		if err != nil {
+			t.Fatalf("%s: 读取缓存的缩略图失败: %v", tc.format, err)
+		}
+		if !bytes.Equal(cached.Data, cover.Data) || cached.MIMEType != cover.MIMEType {
+			t.Errorf("%s: 缓存的缩略图与生成的不一致", tc.format)
+		}
+	}
+}
+
+func TestCoverService_NoCover(t *testing.T) {
+	song := writeSongWithCover(t, t.TempDir(), "song.mp3", "", nil)
+	if song.HasCover {
+		t.Fatal("期望歌曲没有封面")
+	}
+	_, err := NewCoverService(t.TempDir(), 1024).Get(song, 0)
+	if !errors.Is(err, ErrNoCover) {
+		t.Errorf("期望 ErrNoCover, 得到 %v", err)
+	}
+}
+
+func TestCoverService_ETag(t *testing.T) {
+	service := NewCoverService(t.TempDir(), 1024)
+	song := &models.Song{ID: "abc", FileSize: 100}
+
+	etag := service.ETag(song, 64)
+	if etag != service.ETag(song, 64) {
+		t.Error("期望同一歌曲和尺寸的 ETag 稳定")
+	}
+	if etag == service.ETag(song, 128) {
+		t.Error("期望不同尺寸的 ETag 不同")
+	}
+	song.FileSize = 200
+	if etag == service.ETag(song, 64) {
+		t.Error("期望文件变化后 ETag 改变")
+	}
+}
+
+func TestResizeImage_AveragesPixels(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	src.Set(0, 0, color.RGBA{255, 255, 255, 255})
+	src.Set(1, 1, color.RGBA{255, 255, 255, 255})
+	src.Set(0, 1, color.RGBA{0, 0, 0, 255})
+	src.Set(1, 0, color.RGBA{0, 0, 0, 255})
+
+	dst := resizeImage(src, 1, 1)
+	if got := dst.RGBAAt(0, 0); got != (color.RGBA{128, 128, 128, 255}) {
+		t.Errorf("期望平均颜色为中灰, 得到 %v", got)
+	}
+}
+
+func TestCoverService_Sidecar(t *testing.T) {
+	dir := t.TempDir()
+	coverPath := filepath.Join(dir, "cover.jpg")
+	if err := os.WriteFile(coverPath, encodeTestImage(t, "jpeg", 200, 200), 0644); err != nil {
+		t.Fatal(err)
+	}
+	song := &models.Song{ID: "abc", FilePath: filepath.Join(dir, "missing.mp3"), HasCover: true,
+		CoverSource: models.CoverSourceSidecar, CoverPath: coverPath}
+
+	service := NewCoverService(filepath.Join(dir, "covers"), 1024)
+	cover, err := service.Get(song, 50)
+	if err != nil {
+		t.Fatalf("读取目录封面失败: %v", err)
+	}
+	if cover.MIMEType != "image/jpeg" {
+		t.Errorf("期望 MIME 类型 image/jpeg, 得到 %s", cover.MIMEType)
+	}
+	if service.ETag(song, 50) != service.ImageETag(coverPath, 50) {
+		t.Error("期望目录封面与同一图片文件的 ETag 一致")
+	}
+
+	etag := service.ETag(song, 50)
+	if err := os.WriteFile(coverPath, encodeTestImage(t, "png", 10, 10), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if etag == service.ETag(song, 50) {
+		t.Error("期望封面文件变化后 ETag 改变")
+	}
+
+	if err := os.Remove(coverPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Get(song, 0); !errors.Is(err, ErrNoCover) {
+		t.Errorf("期望封面文件删除后返回 ErrNoCover, 得到 %v", err)
+	}
+}
@@ -0,0 +1,388 @@
+package services
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"strings"
+	"zero-music/models"
+)
+
+// cueTrackOpener 在整轨文件中定位曲目 [startMs, endMs) 并返回只包含该曲目的虚拟音频流，endMs 为 0 表示到文件结尾。
+type cueTrackOpener func(r io.ReaderAt, size, startMs, endMs int64) (*io.SectionReader, error)
+
+// cueTrackOpeners 按扩展名选择截取曲目的方式，只支持无需重新编码就能在曲目边界切分的格式。
+var cueTrackOpeners = map[string]cueTrackOpener{
+	".wav":  openWAVTrack,
+	".flac": openFLACTrack,
+}
+
+// errCueTrackRange 表示曲目的时间超出了整轨文件的范围。
+var errCueTrackRange = errors.New("曲目超出音频范围")
+
+// canSplitCue 判断整轨文件是否可以按 CUE 曲目拆分播放。
+func canSplitCue(path string) bool {
+	_, ok := cueTrackOpeners[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+// OpenCueTrack 返回 CUE 曲目对应的虚拟音频流。流由新生成的文件头和整轨文件中曲目对应的音频数据组成，
+// 本身是一个完整的音频文件，可以按字节定位，因此可以直接用于 Range 请求。r 和 size 是整轨文件的内容和大小。
+func OpenCueTrack(r io.ReaderAt, size int64, song *models.Song) (*io.SectionReader, error) {
+	open, ok := cueTrackOpeners[strings.ToLower(filepath.Ext(song.FilePath))]
+	if !ok {
+		return nil, errUnsupportedPCM
+	}
+	return open(r, size, song.StartMs, song.EndMs)
+}
+
+// cueSample 将 CUE 时间换算为采样位置。CUE 时间以 1/75 秒为单位，常见采样率下换算是精确的。
+func cueSample(ms int64, sampleRate int) int64 {
+	return models.CueFrames(ms) * int64(sampleRate) / models.CueFramesPerSecond
+}
+
+// prefixedReaderAt 将内存中的文件头与原文件中从 offset 开始的数据拼接为一个 io.ReaderAt。
+type prefixedReaderAt struct {
+	header []byte
+	r      io.ReaderAt
+	offset int64
+}
+
+func (p *prefixedReaderAt) ReadAt(b []byte, off int64) (int, error) {
+	n := 0
+	if off < int64(len(p.header)) {
+		n = copy(b, p.header[off:])
+		if n == len(b) {
+			return n, nil
+		}
+		off = int64(len(p.header))
+	}
+	m, err := p.r.ReadAt(b[n:], p.offset+off-int64(len(p.header)))
+	return n + m, err
+}
+
+// newPrefixedSection 返回由 header 和原文件中 [start, end) 组成的虚拟文件。
+func newPrefixedSection(header []byte, r io.ReaderAt, start, end int64) *io.SectionReader {
+	return io.NewSectionReader(&prefixedReaderAt{header: header, r: r, offset: start}, 0, int64(len(header))+end-start)
+}
+
+// openWAVTrack 按采样精确截取 WAV 文件中的曲目，新的文件头沿用原文件的 fmt 块。
+func openWAVTrack(r io.ReaderAt, size, startMs, endMs int64) (*io.SectionReader, error) {
+	header := make([]byte, 12)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, fmt.Errorf("读取 WAV 文件头失败: %w", err)
+	}
+	if string(header[:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("不是 WAV 文件")
+	}
+
+	var format []byte
+	chunk := make([]byte, 8)
+	for pos := int64(12); pos+8 <= size; {
+		if _, err := r.ReadAt(chunk, pos); err != nil {
+			return nil, fmt.Errorf("读取 WAV 块失败: %w", err)
+		}
+		chunkSize := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+		pos += 8
+		switch string(chunk[:4]) {
+		case "fmt ":
+			if chunkSize < 16 || chunkSize > 1024 {
+				return nil, fmt.Errorf("WAV fmt 块大小无效: %d", chunkSize)
+			}
+			format = make([]byte, chunkSize)
+			if _, err := r.ReadAt(format, pos); err != nil {
+				return nil, fmt.Errorf("读取 WAV fmt 块失败: %w", err)
+			}
+		case "data":
+			if format == nil {
+				return nil, fmt.Errorf("WAV 文件缺少 fmt 块")
+			}
+			// 流式录制的文件可能没有回填 data 块大小
+			dataEnd := pos + chunkSize
+			if chunkSize == 0 || chunkSize == math.MaxUint32 || dataEnd > size {
+				dataEnd = size
+			}
+			return wavTrackSection(r, format, pos, dataEnd, startMs, endMs)
+		}
+		pos += chunkSize + chunkSize%2
+	}
+	return nil, fmt.Errorf("WAV 文件缺少 data 块")
+}
+
+// wavTrackSection 截取 data 块 [dataStart, dataEnd) 中曲目对应的采样，并生成新的 RIFF 文件头。
+func wavTrackSection(r io.ReaderAt, format []byte, dataStart, dataEnd, startMs, endMs int64) (*io.SectionReader, error) {
+	sampleRate := int(binary.LittleEndian.Uint32(format[4:8]))
+	blockAlign := int64(binary.LittleEndian.Uint16(format[12:14]))
+	if sampleRate == 0 || blockAlign == 0 {
+		return nil, fmt.Errorf("WAV fmt 块无效: 采样率 %d, 块对齐 %d", sampleRate, blockAlign)
+	}
+
+	total := (dataEnd - dataStart) / blockAlign
+	first := min(cueSample(startMs, sampleRate), total)
+	last := total
+	if endMs > 0 {
+		last = min(cueSample(endMs, sampleRate), total)
+	}
+	if last <= first {
+		return nil, errCueTrackRange
+	}
+	dataSize := (last - first) * blockAlign
+	if dataSize > math.MaxUint32-64 {
+		return nil, fmt.Errorf("曲目过大")
+	}
+
+	formatSize := int64(len(format))
+	header := make([]byte, 0, 28+formatSize+formatSize%2)
+	header = append(header, "RIFF"...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(4+8+formatSize+formatSize%2+8+dataSize))
+	header = append(header, "WAVEfmt "...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(formatSize))
+	header = append(header, format...)
+	if formatSize%2 == 1 {
+		header = append(header, 0)
+	}
+	header = append(header, "data"...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(dataSize))
+
+	start := dataStart + first*blockAlign
+	return newPrefixedSection(header, r, start, start+dataSize), nil
+}
+
+// flacStreamHeader 是截取 FLAC 曲目需要的文件头信息。
+type flacStreamHeader struct {
+	streamInfo   []byte // STREAMINFO 块的内容
+	sampleRate   int
+	channels     int
+	bitDepth     int
+	blockSize    int   // 固定块大小的流中每帧的采样数
+	totalSamples int64 // 0 表示未知
+	audioStart   int64 // 第一个音频帧的位置
+}
+
+// openFLACTrack 截取 FLAC 文件中的曲目。FLAC 只能在帧边界切分，曲目从开始时间之后的第一帧开始，
+// 到结束时间之后的第一帧之前结束，相邻曲目之间既不重叠也不遗漏。新文件只保留 STREAMINFO 元数据块，
+// 其中的总采样数更新为曲目的长度，MD5 置为 0（表示未知）；帧头中的帧号保持原样，解码器可以正常处理。
+func openFLACTrack(r io.ReaderAt, size, startMs, endMs int64) (*io.SectionReader, error) {
+	info, err := readFLACStreamHeader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	start, startSample, ok := findFLACFrame(r, size, info, cueSample(startMs, info.sampleRate))
+	if !ok {
+		return nil, errCueTrackRange
+	}
+	end, endSample := size, info.totalSamples
+	if endMs > 0 {
+		if offset, sample, ok := findFLACFrame(r, size, info, cueSample(endMs, info.sampleRate)); ok {
+			end, endSample = offset, sample
+		}
+	}
+	if end <= start {
+		return nil, errCueTrackRange
+	}
+	samples := int64(0)
+	if endSample > startSample {
+		samples = endSample - startSample
+	}
+
+	streamInfo := make([]byte, 34)
+	copy(streamInfo, info.streamInfo)
+	streamInfo[13] = streamInfo[13]&0xf0 | byte(samples>>32&0x0f)
+	binary.BigEndian.PutUint32(streamInfo[14:18], uint32(samples))
+	clear(streamInfo[18:34])
+
+	header := append([]byte("fLaC\x80\x00\x00\x22"), streamInfo...)
+	return newPrefixedSection(header, r, start, end), nil
+}
+
+// readFLACStreamHeader 读取 FLAC 文件的 STREAMINFO 和第一个音频帧的位置，文件开头的 ID3v2 标签会被跳过。
+func readFLACStreamHeader(r io.ReaderAt, size int64) (*flacStreamHeader, error) {
+	pos := int64(0)
+	magic := make([]byte, 10)
+	if _, err := r.ReadAt(magic, 0); err != nil {
+		return nil, fmt.Errorf("读取 FLAC 文件头失败: %w", err)
+	}
+	if string(magic[:3]) == "ID3" {
+		pos = 10 + (int64(magic[6])<<21 | int64(magic[7])<<14 | int64(magic[8])<<7 | int64(magic[9]))
+		if magic[5]&0x10 != 0 {
+			pos += 10 // 标签尾
+		}
+		if _, err := r.ReadAt(magic[:4], pos); err != nil {
+			return nil, fmt.Errorf("读取 FLAC 文件头失败: %w", err)
+		}
+	}
+	if string(magic[:4]) != "fLaC" {
+		return nil, fmt.Errorf("不是 FLAC 文件")
+	}
+	pos += 4
+
+	info := &flacStreamHeader{}
+	block := make([]byte, 4)
+	for last := false; !last; {
+		if _, err := r.ReadAt(block, pos); err != nil {
+			return nil, fmt.Errorf("读取 FLAC 元数据块失败: %w", err)
+		}
+		last = block[0]&0x80 != 0
+		blockSize := int64(block[1])<<16 | int64(block[2])<<8 | int64(block[3])
+		if block[0]&0x7f == 0 {
+			if blockSize < 34 {
+				return nil, fmt.Errorf("FLAC STREAMINFO 块大小无效: %d", blockSize)
+			}
+			info.streamInfo = make([]byte, 34)
+			if _, err := r.ReadAt(info.streamInfo, pos+4); err != nil {
+				return nil, fmt.Errorf("读取 FLAC STREAMINFO 失败: %w", err)
+			}
+		}
+		pos += 4 + blockSize
+	}
+	if info.streamInfo == nil {
+		return nil, fmt.Errorf("FLAC 文件缺少 STREAMINFO 块")
+	}
+
+	packed := binary.BigEndian.Uint64(info.streamInfo[10:18])
+	info.sampleRate = int(packed >> 44)
+	info.channels = int(packed>>41&0x7) + 1
+	info.bitDepth = int(packed>>36&0x1f) + 1
+	info.totalSamples = int64(packed & (1<<36 - 1))
+	info.blockSize = int(binary.BigEndian.Uint16(info.streamInfo[2:4]))
+	info.audioStart = pos
+	if info.sampleRate == 0 || info.blockSize < 16 || pos >= size {
+		return nil, fmt.Errorf("FLAC STREAMINFO 无效")
+	}
+	return info, nil
+}
+
+// findFLACFrame 返回第一个起始采样不早于 sample 的音频帧的位置及其起始采样。
+// 帧的位置随起始采样单调递增，因此可以对文件位置二分查找，每次从中点向后搜索最近的帧头。
+func findFLACFrame(r io.ReaderAt, size int64, info *flacStreamHeader, sample int64) (int64, int64, bool) {
+	lo, hi := info.audioStart, size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		offset, first, ok := nextFLACFrame(r, mid, hi, info)
+		if !ok || first >= sample {
+			hi = mid
+		} else {
+			lo = offset + 1
+		}
+	}
+	return nextFLACFrame(r, lo, size, info)
+}
+
+// flacScanChunk 是搜索帧头时每次读取的字节数，帧头最长 16 字节。
+const flacScanChunk = 64 * 1024
+
+// nextFLACFrame 从 pos 开始向后搜索第一个有效的帧头（不超过 limit），返回其位置和起始采样。
+func nextFLACFrame(r io.ReaderAt, pos, limit int64, info *flacStreamHeader) (int64, int64, bool) {
+	buf := make([]byte, flacScanChunk)
+	for pos < limit {
+		n, err := r.ReadAt(buf, pos)
+		if n < 2 {
+			return 0, 0, false
+		}
+		for i := 0; i+1 < n && pos+int64(i) < limit; i++ {
+			if buf[i] != 0xff || buf[i+1]&0xfe != 0xf8 {
+				continue
+			}
+			if first, ok := parseFLACFrameHeader(buf[i:n], info); ok {
+				return pos + int64(i), first, true
+			}
+		}
+		if err != nil {
+			return 0, 0, false
+		}
+		// 保留末尾可能被截断的帧头
+		pos += int64(n) - 16
+	}
+	return 0, 0, false
+}
+
+// parseFLACFrameHeader 校验 data 开头的帧头并返回帧的起始采样。音频数据中可能出现与同步码相同的字节，
+// 因此除了 CRC-8，还要求帧头的声道数和采样位数与 STREAMINFO 一致。
+func parseFLACFrameHeader(data []byte, info *flacStreamHeader) (int64, bool) {
+	if len(data) < 6 || data[1]&0x02 != 0 || data[3]&0x01 != 0 {
+		return 0, false
+	}
+	blockCode, rateCode := data[2]>>4, data[2]&0x0f
+	channelCode, depthCode := int(data[3]>>4), int(data[3]>>1&0x07)
+	if blockCode == 0 || rateCode == 15 || channelCode > 10 || depthCode == 3 {
+		return 0, false
+	}
+	channels := 2
+	if channelCode < 8 {
+		channels = channelCode + 1
+	}
+	if channels != info.channels || (depthCode != 0 && flacBitDepths[depthCode] != info.bitDepth) {
+		return 0, false
+	}
+
+	// 帧号或样本号，使用 UTF-8 形式的变长编码
+	pos := 4
+	number := int64(data[pos])
+	extra := 0
+	switch {
+	case number < 0x80:
+	case number&0xe0 == 0xc0:
+		number, extra = number&0x1f, 1
+	case number&0xf0 == 0xe0:
+		number, extra = number&0x0f, 2
+	case number&0xf8 == 0xf0:
+		number, extra = number&0x07, 3
+	case number&0xfc == 0xf8:
+		number, extra = number&0x03, 4
+	case number&0xfe == 0xfc:
+		number, extra = number&0x01, 5
+	case number == 0xfe:
+		number, extra = 0, 6
+	default:
+		return 0, false
+	}
+	pos++
+	for ; extra > 0; extra-- {
+		if pos >= len(data) || data[pos]&0xc0 != 0x80 {
+			return 0, false
+		}
+		number = number<<6 | int64(data[pos]&0x3f)
+		pos++
+	}
+
+	switch blockCode {
+	case 6:
+		pos++
+	case 7:
+		pos += 2
+	}
+	switch rateCode {
+	case 12:
+		pos++
+	case 13, 14:
+		pos += 2
+	}
+	if pos >= len(data) || flacCRC8(data[:pos]) != data[pos] {
+		return 0, false
+	}
+
+	if data[1]&0x01 != 0 {
+		return number, true // 可变块大小的流中为样本号
+	}
+	return number * int64(info.blockSize), true
+}
+
+// flacCRC8 计算帧头使用的 CRC-8（多项式 x^8 + x^2 + x + 1）。
+func flacCRC8(data []byte) byte {
+	var crc byte
+	for _, b := range data {
+		crc ^= b
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x07
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+	"zero-music/models"
+)
+
+// decodeCueTrack 截取曲目并解码，返回第一个声道的采样。
+func decodeCueTrack(t *testing.T, data []byte, song *models.Song) []float64 {
+	t.Helper()
+	track, err := OpenCueTrack(bytes.NewReader(data), int64(len(data)), song)
+	if err != nil {
+		t.Fatalf("截取曲目失败: %v", err)
+	}
+	content, err := io.ReadAll(track)
+	if err != nil || int64(len(content)) != track.Size() {
+		t.Fatalf("读取曲目失败: %d/%d 字节, %v", len(content), track.Size(), err)
+	}
+	sink := &collectSink{}
+	decode := pcmDecoders[song.Format]
+	if err := decode(bytes.NewReader(content), sink); err != nil {
+		t.Fatalf("解码曲目失败: %v", err)
+	}
+	return sink.samples[0]
+}
+
+// TestOpenCueTrack_WAV 测试 WAV 曲目按采样精确截取：两个 CUE 帧在 44.1kHz 下是 1176 个采样。
+func TestOpenCueTrack_WAV(t *testing.T) {
+	const frames = 3000
+	data := encodeTestWAV(44100, 2, frames, func(ch, i int) float64 { return float64(i%1000) / 1000 })
+	song := &models.Song{FilePath: "/music/image.wav", Format: ".wav", CueSheetPath: "/music/image.cue", EndMs: 27}
+
+	first := decodeCueTrack(t, data, song)
+	song.StartMs, song.EndMs = 27, 0
+	second := decodeCueTrack(t, data, song)
+	if len(first) != 1176 || len(second) != frames-1176 {
+		t.Fatalf("期望曲目分别为 1176 和 %d 个采样, 得到 %d 和 %d", frames-1176, len(first), len(second))
+	}
+	for i, v := range append(first, second...) {
+		want := math.Round(float64(i%1000)/1000*32767) / 32768
+		if v != want {
+			t.Fatalf("第 %d 个采样期望 %v, 得到 %v", i, want, v)
+		}
+	}
+
+	song.StartMs = 1000 // 超出文件长度
+	if _, err := OpenCueTrack(bytes.NewReader(data), int64(len(data)), song); err == nil {
+		t.Error("期望超出范围的曲目返回错误")
+	}
+}
+
+// TestOpenCueTrack_FLAC 测试 FLAC 曲目在边界之后的第一个帧处切分，相邻曲目拼接后与原始采样一致。
+func TestOpenCueTrack_FLAC(t *testing.T) {
+	const blockSize, n = 256, 3000
+	left := make([]int64, n)
+	right := make([]int64, n)
+	for i := range left {
+		left[i] = int64(i%700 - 350)
+		right[i] = -left[i]
+	}
+	// 0xfff8 形式的采样与帧同步码相同，不能被误认为帧头
+	for i := 0; i < n; i += 97 {
+		left[i] = -8
+	}
+	frames := make([][2]flacTestSubframe, (n+blockSize-1)/blockSize)
+	for i := range frames {
+		frames[i] = [2]flacTestSubframe{{kind: "verbatim"}, {kind: "verbatim"}}
+	}
+	id3 := []byte{'I', 'D', '3', 3, 0, 0, 0, 0, 0, 5, 0, 0, 0, 0, 0}
+	data := append(id3, encodeTestFLAC(left, right, blockSize, 1, frames)...)
+
+	song := &models.Song{FilePath: "/music/image.flac", Format: ".flac", CueSheetPath: "/music/image.cue", EndMs: 27}
+	first := decodeCueTrack(t, data, song)
+	song.StartMs, song.EndMs = 27, 0
+	second := decodeCueTrack(t, data, song)
+
+	// 第 1176 个采样之后的第一个帧从 1280 开始
+	if len(first) != 1280 || len(second) != n-1280 {
+		t.Fatalf("期望曲目分别为 1280 和 %d 个采样, 得到 %d 和 %d", n-1280, len(first), len(second))
+	}
+	for i, v := range append(first, second...) {
+		if v*32768 != float64(left[i]) {
+			t.Fatalf("第 %d 个采样期望 %d, 得到 %v", i, left[i], v*32768)
+		}
+	}
+
+	track, err := OpenCueTrack(bytes.NewReader(data), int64(len(data)), song)
+	if err != nil {
+		t.Fatalf("截取曲目失败: %v", err)
+	}
+	header := make([]byte, 42)
+	if _, err := track.ReadAt(header, 0); err != nil {
+		t.Fatal(err)
+	}
+	info, err := readFLACStreamHeader(bytes.NewReader(header), 42+1)
+	if err != nil {
+		t.Fatalf("读取新的 STREAMINFO 失败: %v", err)
+	}
+	if info.totalSamples != n-1280 {
+		t.Errorf("期望总采样数 %d, 得到 %d", n-1280, info.totalSamples)
+	}
+}
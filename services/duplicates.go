@@ -0,0 +1,226 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+	"zero-music/logger"
+	"zero-music/models"
+	"zero-music/repository"
+)
+
+// ErrSongNotFound 表示歌曲不在歌曲库中。
+var ErrSongNotFound = errors.New("歌曲不存在")
+
+// ErrNotDuplicate 表示歌曲没有重复的副本。
+var ErrNotDuplicate = errors.New("歌曲没有重复的副本")
+
+// DuplicateReport 是重复歌曲报告。
+type DuplicateReport struct {
+	Groups []*models.DuplicateGroup `json:"groups"`
+	// GroupCount 是重复组的数量，DuplicateCount 是除每组中音质最好的副本以外的副本数量
+	GroupCount     int `json:"group_count"`
+	DuplicateCount int `json:"duplicate_count"`
+	// HiddenCount 是因为管理员选定了首选副本而被隐藏的副本数量
+	HiddenCount int `json:"hidden_count"`
+}
+
+// DuplicateService 生成重复歌曲报告，并记录管理员为重复歌曲选定的首选副本。
+// 有首选副本时，同组的其他副本不出现在歌曲列表和搜索结果中，但仍然可以按 ID 访问和播放。
+type DuplicateService struct {
+	scanner Scanner
+	repo    repository.DuplicateRepository
+	mu      sync.RWMutex
+	hidden  map[string]string // 被隐藏的歌曲 ID -> 首选副本 ID
+
+	hashMu     sync.Mutex
+	fileHashes map[string]fileHashEntry // 文件路径 -> 完整内容的哈希
+}
+
+// fileHashEntry 是缓存的完整文件哈希，文件大小或修改时间变化后失效。
+type fileHashEntry struct {
+	size    int64
+	modTime time.Time
+	hash    string
+}
+
+// NewDuplicateService 创建一个新的 DuplicateService，scanner 必须返回未经过滤的完整歌曲列表。
+func NewDuplicateService(scanner Scanner, repo repository.DuplicateRepository) *DuplicateService {
+	return &DuplicateService{scanner: scanner, repo: repo, hidden: make(map[string]string), fileHashes: make(map[string]fileHashEntry)}
+}
+
+// Load 从存储加载首选项。
+func (s *DuplicateService) Load() error {
+	hidden, err := s.repo.LoadHidden()
+	if err != nil {
+		return fmt.Errorf("加载重复歌曲首选项失败: %w", err)
+	}
+	s.mu.Lock()
+	s.hidden = hidden
+	s.mu.Unlock()
+	return nil
+}
+
+// Report 返回当前歌曲库的重复歌曲报告。
+func (s *DuplicateService) Report() *DuplicateReport {
+	groups := models.FindDuplicates(s.scanner.GetSongs(), s.fileHash)
+	report := &DuplicateReport{Groups: groups, GroupCount: len(groups)}
+	if report.Groups == nil {
+		report.Groups = []*models.DuplicateGroup{}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, group := range groups {
+		report.DuplicateCount += len(group.Copies) - 1
+		ids := group.SongIDs()
+		for _, c := range group.Copies {
+			if preferred, ok := s.hidden[c.ID]; ok && slices.Contains(ids, preferred) {
+				c.Hidden = true
+				report.HiddenCount++
+			}
+		}
+		for _, c := range group.Copies {
+			for _, other := range group.Copies {
+				if other.Hidden && s.hidden[other.ID] == c.ID {
+					c.Preferred = true
+					break
+				}
+			}
+		}
+	}
+	return report
+}
+
+// Prefer 将歌曲设为其所在重复组的首选副本，隐藏同组的其他副本，返回更新后的重复组。
+func (s *DuplicateService) Prefer(songID string) (*models.DuplicateGroup, error) {
+	song := s.scanner.GetSongByID(songID)
+	if song == nil {
+		return nil, ErrSongNotFound
+	}
+	// 旧 ID 通过别名解析到当前的 ID
+	songID = song.ID
+	group := s.findGroup(songID)
+	if group == nil {
+		return nil, ErrNotDuplicate
+	}
+
+	var hiddenIDs []string
+	for _, id := range group.SongIDs() {
+		if id != songID {
+			hiddenIDs = append(hiddenIDs, id)
+		}
+	}
+	if err := s.repo.SetPreferred(songID, hiddenIDs); err != nil {
+		return nil, fmt.Errorf("保存首选副本失败: %w", err)
+	}
+	if err := s.Load(); err != nil {
+		return nil, err
+	}
+	return s.findGroup(songID), nil
+}
+
+// ClearPreference 取消以歌曲为首选副本的首选项，返回重新显示的副本数量。
+// 歌曲已不在歌曲库中时按原 ID 取消，以便清理首选副本被删除后遗留的首选项。
+func (s *DuplicateService) ClearPreference(songID string) (int, error) {
+	// 旧 ID 通过别名解析到当前的 ID
+	if song := s.scanner.GetSongByID(songID); song != nil {
+		songID = song.ID
+	}
+	cleared, err := s.repo.ClearPreferred(songID)
+	if err != nil {
+		return 0, fmt.Errorf("取消首选副本失败: %w", err)
+	}
+	if err := s.Load(); err != nil {
+		return 0, err
+	}
+	return int(cleared), nil
+}
+
+// fileHash 返回歌曲文件完整内容的哈希。哈希按路径缓存，文件的大小和修改时间不变时不重新读取文件。
+func (s *DuplicateService) fileHash(song *models.Song) string {
+	info, err := os.Stat(song.FilePath)
+	if err != nil {
+		return ""
+	}
+	s.hashMu.Lock()
+	defer s.hashMu.Unlock()
+	if entry, ok := s.fileHashes[song.FilePath]; ok && entry.size == info.Size() && entry.modTime.Equal(info.ModTime()) {
+		return entry.hash
+	}
+	hash, err := models.FileHash(song.FilePath)
+	if err != nil {
+		logger.Warnf("计算文件哈希失败 %s: %v", song.FilePath, err)
+		return ""
+	}
+	s.fileHashes[song.FilePath] = fileHashEntry{size: info.Size(), modTime: info.ModTime(), hash: hash}
+	return hash
+}
+
+// findGroup 返回歌曲所在的重复组，不在任何组中时返回 nil。
+func (s *DuplicateService) findGroup(songID string) *models.DuplicateGroup {
+	for _, group := range s.Report().Groups {
+		if slices.Contains(group.SongIDs(), songID) {
+			return group
+		}
+	}
+	return nil
+}
+
+// Visible 返回 songs 中没有被隐藏的歌曲。首选副本已不在歌曲库中时，被隐藏的副本重新显示。
+func (s *DuplicateService) Visible(songs []*models.Song) []*models.Song {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.hidden) == 0 {
+		return songs
+	}
+
+	present := make(map[string]bool, len(songs))
+	for _, song := range songs {
+		present[song.ID] = true
+	}
+	visible := make([]*models.Song, 0, len(songs))
+	for _, song := range songs {
+		preferred, ok := s.hidden[song.ID]
+		if ok && (present[preferred] || s.scanner.GetSongByID(preferred) != nil) {
+			continue
+		}
+		visible = append(visible, song)
+	}
+	return visible
+}
+
+// visibleScanner 包装 Scanner，歌曲列表中不包含被隐藏的重复副本。
+type visibleScanner struct {
+	Scanner
+	duplicates *DuplicateService
+}
+
+// NewVisibleScanner 返回歌曲列表中不包含被隐藏的重复副本的 Scanner，供歌曲列表和搜索等接口使用。
+// 按 ID 查找不受影响，被隐藏的副本仍然可以播放，播放列表中的引用也继续有效。
+func NewVisibleScanner(scanner Scanner, duplicates *DuplicateService) Scanner {
+	return &visibleScanner{Scanner: scanner, duplicates: duplicates}
+}
+
+// Scan 扫描音乐目录并返回没有被隐藏的歌曲。
+func (v *visibleScanner) Scan(ctx context.Context) ([]*models.Song, error) {
+	songs, err := v.Scanner.Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return v.duplicates.Visible(songs), nil
+}
+
+// GetSongs 返回没有被隐藏的歌曲。
+func (v *visibleScanner) GetSongs() []*models.Song {
+	return v.duplicates.Visible(v.Scanner.GetSongs())
+}
+
+// GetSongCount 返回没有被隐藏的歌曲数量。
+func (v *visibleScanner) GetSongCount() int {
+	return len(v.GetSongs())
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"zero-music/models"
+)
+
+// memoryDuplicateRepository 是保存在内存中的 DuplicateRepository 实现。
+type memoryDuplicateRepository struct {
+	hidden map[string]string
+}
+
+func newMemoryDuplicateRepository() *memoryDuplicateRepository {
+	return &memoryDuplicateRepository{hidden: make(map[string]string)}
+}
+
+func (r *memoryDuplicateRepository) LoadHidden() (map[string]string, error) {
+	hidden := make(map[string]string, len(r.hidden))
+	for id, preferred := range r.hidden {
+		hidden[id] = preferred
+	}
+	return hidden, nil
+}
+
+func (r *memoryDuplicateRepository) SetPreferred(preferredID string, hiddenIDs []string) error {
+	for id, preferred := range r.hidden {
+		if id == preferredID || preferred == preferredID {
+			delete(r.hidden, id)
+		}
+	}
+	for _, id := range hiddenIDs {
+		r.hidden[id] = preferredID
+	}
+	return nil
+}
+
+func (r *memoryDuplicateRepository) ClearPreferred(preferredID string) (int64, error) {
+	var cleared int64
+	for id, preferred := range r.hidden {
+		if preferred == preferredID {
+			delete(r.hidden, id)
+			cleared++
+		}
+	}
+	return cleared, nil
+}
+
+// fixedScanner 是返回固定歌曲列表的 Scanner 实现。
+type fixedScanner struct {
+	songs []*models.Song
+}
+
+func (s *fixedScanner) Scan(ctx context.Context) ([]*models.Song, error) { return s.songs, nil }
+func (s *fixedScanner) Refresh(ctx context.Context) error                { return nil }
+func (s *fixedScanner) GetSongs() []*models.Song                         { return s.songs }
+func (s *fixedScanner) GetSongCount() int                                { return len(s.songs) }
+
+func (s *fixedScanner) GetSongByID(id string) *models.Song {
+	for _, song := range s.songs {
+		if song.ID == id {
+			return song
+		}
+	}
+	return nil
+}
+
+// TestDuplicateService_PreferHidesOtherCopies 测试选定首选副本后同组的其他副本从歌曲列表中隐藏。
+func TestDuplicateService_PreferHidesOtherCopies(t *testing.T) {
+	scanner := &fixedScanner{songs: []*models.Song{
+		{ID: "flac", Artist: "Artist", Title: "Song", Duration: 200, FilePath: "/music/song.flac", Codec: models.CodecFLAC, Lossless: true, SampleRate: 44100, BitDepth: 16},
+		{ID: "mp3", Artist: "Artist", Title: "Song", Duration: 201, FilePath: "/music/song.mp3", Codec: models.CodecMP3, Bitrate: 320},
+		{ID: "other", Artist: "Artist", Title: "Other", Duration: 200, FilePath: "/music/other.mp3"},
+	}}
+	repo := newMemoryDuplicateRepository()
+	duplicates := NewDuplicateService(scanner, repo)
+	visible := NewVisibleScanner(scanner, duplicates)
+
+	report := duplicates.Report()
+	if report.GroupCount != 1 || report.DuplicateCount != 1 || report.HiddenCount != 0 {
+		t.Fatalf("期望 1 组重复歌曲且没有隐藏的副本, 得到 %+v", report)
+	}
+
+	if _, err := duplicates.Prefer("other"); !errors.Is(err, ErrNotDuplicate) {
+		t.Errorf("期望 ErrNotDuplicate, 得到 %v", err)
+	}
+	if _, err := duplicates.Prefer("missing"); !errors.Is(err, ErrSongNotFound) {
+		t.Errorf("期望 ErrSongNotFound, 得到 %v", err)
+	}
+
+	group, err := duplicates.Prefer("mp3")
+	if err != nil {
+		t.Fatalf("选定首选副本失败: %v", err)
+	}
+	if group.Copies[1].ID != "mp3" || !group.Copies[1].Preferred || !group.Copies[0].Hidden {
+		t.Errorf("期望 mp3 为首选副本、flac 被隐藏, 得到 %+v %+v", group.Copies[0], group.Copies[1])
+	}
+	if visible.GetSongCount() != 2 || visible.GetSongByID("flac") == nil {
+		t.Errorf("期望歌曲列表隐藏 flac 但仍可按 ID 查找, 得到 %d 首", visible.GetSongCount())
+	}
+	songs, _ := visible.Scan(context.Background())
+	for _, song := range songs {
+		if song.ID == "flac" {
+			t.Error("期望扫描结果不包含被隐藏的副本")
+		}
+	}
+
+	// 首选副本不在歌曲库中时，被隐藏的副本重新显示
+	scanner.songs = scanner.songs[:1]
+	if visible.GetSongCount() != 1 {
+		t.Errorf("期望首选副本被删除后 flac 重新显示, 得到 %d 首", visible.GetSongCount())
+	}
+	scanner.songs = append(scanner.songs, &models.Song{ID: "mp3", Artist: "Artist", Title: "Song", Duration: 201, FilePath: "/music/song.mp3"})
+
+	restored, err := duplicates.ClearPreference("mp3")
+	if err != nil || restored != 1 {
+		t.Fatalf("期望重新显示 1 个副本, 得到 %d, %v", restored, err)
+	}
+	if visible.GetSongCount() != 2 {
+		t.Errorf("期望取消首选项后显示全部歌曲, 得到 %d 首", visible.GetSongCount())
+	}
+}
+
+// TestDuplicateService_ConfirmsContentHash 测试抽样指纹相同的文件需要完整内容相同才按内容判断为重复。
+func TestDuplicateService_ConfirmsContentHash(t *testing.T) {
+	tmpDir := t.TempDir()
+	data := make([]byte, 1<<20)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	// 只在抽样范围之外不同的文件有相同的抽样指纹
+	changed := append([]byte(nil), data...)
+	changed[300*1024] ^= 0xFF
+
+	var songs []*models.Song
+	for i, content := range [][]byte{data, data, changed} {
+		path := filepath.Join(tmpDir, fmt.Sprintf("%d.mp3", i))
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		song := &models.Song{ID: fmt.Sprintf("song%d", i), Artist: "Artist", Title: fmt.Sprintf("Title %d", i), FilePath: path}
+		song.UpdateContentHash()
+		songs = append(songs, song)
+	}
+	if songs[0].ContentHash != songs[2].ContentHash {
+		t.Fatal("期望抽样指纹相同")
+	}
+
+	report := NewDuplicateService(&fixedScanner{songs: songs}, newMemoryDuplicateRepository()).Report()
+	if report.GroupCount != 1 {
+		t.Fatalf("期望 1 组重复歌曲, 得到 %+v", report)
+	}
+	group := report.Groups[0]
+	if ids := group.SongIDs(); len(ids) != 2 || ids[0] != "song0" || ids[1] != "song1" {
+		t.Errorf("期望只有内容完全相同的 song0 和 song1 成组, 得到 %v", ids)
+	}
+	if len(group.Reasons) != 1 || group.Reasons[0] != models.DuplicateReasonContentHash {
+		t.Errorf("期望重复原因为 %s, 得到 %v", models.DuplicateReasonContentHash, group.Reasons)
+	}
+}
+
+// aliasScanner 在 fixedScanner 的基础上通过别名解析旧 ID。
+type aliasScanner struct {
+	*fixedScanner
+	aliases map[string]string
+}
+
+func (s *aliasScanner) GetSongByID(id string) *models.Song {
+	if newID, ok := s.aliases[id]; ok {
+		id = newID
+	}
+	return s.fixedScanner.GetSongByID(id)
+}
+
+// TestDuplicateService_ClearPreferenceByAlias 测试可以用首选副本移动前的旧 ID 取消首选项。
+func TestDuplicateService_ClearPreferenceByAlias(t *testing.T) {
+	scanner := &aliasScanner{fixedScanner: &fixedScanner{songs: []*models.Song{
+		{ID: "flac", Artist: "Artist", Title: "Song", Duration: 200, FilePath: "/music/song.flac"},
+		{ID: "mp3", Artist: "Artist", Title: "Song", Duration: 200, FilePath: "/music/song.mp3"},
+	}}, aliases: map[string]string{"old-mp3": "mp3"}}
+	duplicates := NewDuplicateService(scanner, newMemoryDuplicateRepository())
+
+	if _, err := duplicates.Prefer("old-mp3"); err != nil {
+		t.Fatalf("选定首选副本失败: %v", err)
+	}
+	restored, err := duplicates.ClearPreference("old-mp3")
+	if err != nil || restored != 1 {
+		t.Fatalf("期望重新显示 1 个副本, 得到 %d, %v", restored, err)
+	}
+}
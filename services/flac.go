@@ -0,0 +1,537 @@
+package services
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// flacStreamInfo 是 FLAC STREAMINFO 元数据块中解码需要的字段。
+type flacStreamInfo struct {
+	sampleRate int
+	channels   int
+	bitDepth   int
+}
+
+// flacReader 逐帧解码 FLAC 音频。只做解码需要的校验，不校验帧的 CRC。
+type flacReader struct {
+	bits   flacBitReader
+	info   flacStreamInfo
+	frames int
+}
+
+// flacBitDepths 是帧头中采样位数代码对应的位数，0 表示使用 STREAMINFO 中的值。
+var flacBitDepths = [...]int{0, 8, 12, 0, 16, 20, 24, 32}
+
+// decodeFLAC 解码 FLAC 文件并将采样交给 sink。
+func decodeFLAC(r io.Reader, sink pcmSink) error {
+	fr, err := newFLACReader(r)
+	if err != nil {
+		return err
+	}
+	if err := sink.begin(fr.info.sampleRate, fr.info.channels); err != nil {
+		return err
+	}
+
+	samples := make([][]float64, fr.info.channels)
+	for {
+		block, bitDepth, err := fr.next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		scale := 1 / float64(int64(1)<<(bitDepth-1))
+		for ch := range samples {
+			samples[ch] = samples[ch][:0]
+			for _, v := range block[ch] {
+				samples[ch] = append(samples[ch], float64(v)*scale)
+			}
+		}
+		sink.write(samples)
+	}
+}
+
+// newFLACReader 读取 FLAC 文件头和元数据块，文件开头的 ID3v2 标签会被跳过。
+func newFLACReader(r io.Reader) (*flacReader, error) {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(br, magic); err != nil {
+		return nil, fmt.Errorf("读取 FLAC 文件头失败: %w", err)
+	}
+	if string(magic[:3]) == "ID3" {
+		header := make([]byte, 6)
+		if _, err := io.ReadFull(br, header); err != nil {
+			return nil, fmt.Errorf("读取 ID3 标签失败: %w", err)
+		}
+		size := int64(header[2])<<21 | int64(header[3])<<14 | int64(header[4])<<7 | int64(header[5])
+		if header[1]&0x10 != 0 {
+			size += 10 // 标签尾
+		}
+		if _, err := io.CopyN(io.Discard, br, size); err != nil {
+			return nil, fmt.Errorf("跳过 ID3 标签失败: %w", err)
+		}
+		if _, err := io.ReadFull(br, magic); err != nil {
+			return nil, fmt.Errorf("读取 FLAC 文件头失败: %w", err)
+		}
+	}
+	if string(magic) != "fLaC" {
+		return nil, fmt.Errorf("不是 FLAC 文件")
+	}
+
+	fr := &flacReader{bits: flacBitReader{r: br}}
+	seenInfo := false
+	header := make([]byte, 4)
+	for last := false; !last; {
+		if _, err := io.ReadFull(br, header); err != nil {
+			return nil, fmt.Errorf("读取 FLAC 元数据块失败: %w", err)
+		}
+		last = header[0]&0x80 != 0
+		size := int64(header[1])<<16 | int64(header[2])<<8 | int64(header[3])
+		if header[0]&0x7f != 0 {
+			if _, err := io.CopyN(io.Discard, br, size); err != nil {
+				return nil, fmt.Errorf("读取 FLAC 元数据块失败: %w", err)
+			}
+			continue
+		}
+		if size < 34 {
+			return nil, fmt.Errorf("FLAC STREAMINFO 块大小无效: %d", size)
+		}
+		body := make([]byte, size)
+		if _, err := io.ReadFull(br, body); err != nil {
+			return nil, fmt.Errorf("读取 FLAC STREAMINFO 失败: %w", err)
+		}
+		packed := binary.BigEndian.Uint64(body[10:18])
+		fr.info = flacStreamInfo{
+			sampleRate: int(packed >> 44),
+			channels:   int(packed>>41&0x7) + 1,
+			bitDepth:   int(packed>>36&0x1f) + 1,
+		}
+		seenInfo = true
+	}
+	if !seenInfo {
+		return nil, fmt.Errorf("FLAC 文件缺少 STREAMINFO 块")
+	}
+	if fr.info.sampleRate == 0 || fr.info.bitDepth < 4 {
+		return nil, fmt.Errorf("FLAC STREAMINFO 无效: 采样率 %d, %d 位", fr.info.sampleRate, fr.info.bitDepth)
+	}
+	return fr, nil
+}
+
+// next 解码下一帧，返回每个声道的整数采样和采样位数。流结束时返回 io.EOF。
+func (fr *flacReader) next() (block [][]int64, bitDepth int, err error) {
+	b := &fr.bits
+	b.align()
+	sync, err := b.read(15) // 14 位同步码和 1 位保留位
+	if errors.Is(err, io.EOF) {
+		return nil, 0, io.EOF
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("读取 FLAC 帧头失败: %w", err)
+	}
+	if sync != 0x3ffe<<1 {
+		if fr.frames > 0 {
+			// 音频帧之后的 ID3v1 等尾部数据
+			return nil, 0, io.EOF
+		}
+		return nil, 0, fmt.Errorf("FLAC 帧同步码无效")
+	}
+	// 帧已经开始，之后遇到文件结尾说明文件被截断
+	defer func() {
+		if errors.Is(err, io.EOF) {
+			err = fmt.Errorf("FLAC 帧不完整: %w", io.ErrUnexpectedEOF)
+		}
+	}()
+
+	header, err := b.read(17) // 阻塞策略、块大小、采样率、声道、位数、保留位
+	if err != nil {
+		return nil, 0, fmt.Errorf("读取 FLAC 帧头失败: %w", err)
+	}
+	blockCode := int(header >> 12 & 0xf)
+	rateCode := int(header >> 8 & 0xf)
+	channelCode := int(header >> 4 & 0xf)
+	depthCode := int(header >> 1 & 0x7)
+
+	// 帧号或样本号，使用 UTF-8 形式的变长编码
+	first, err := b.read(8)
+	if err != nil {
+		return nil, 0, fmt.Errorf("读取 FLAC 帧头失败: %w", err)
+	}
+	for mask := uint64(0x80); first&mask != 0 && mask > 1; mask >>= 1 {
+		if mask != 0x80 {
+			if _, err := b.read(8); err != nil {
+				return nil, 0, fmt.Errorf("读取 FLAC 帧头失败: %w", err)
+			}
+		}
+	}
+
+	var blockSize int
+	switch {
+	case blockCode == 1:
+		blockSize = 192
+	case blockCode >= 2 && blockCode <= 5:
+		blockSize = 576 << (blockCode - 2)
+	case blockCode == 6 || blockCode == 7:
+		v, err := b.read(uint(8 * (blockCode - 5)))
+		if err != nil {
+			return nil, 0, fmt.Errorf("读取 FLAC 帧头失败: %w", err)
+		}
+		blockSize = int(v) + 1
+	case blockCode >= 8:
+		blockSize = 256 << (blockCode - 8)
+	default:
+		return nil, 0, fmt.Errorf("FLAC 块大小代码无效")
+	}
+	// 帧头中的采样率只用于校验，采样率以 STREAMINFO 为准
+	switch rateCode {
+	case 12:
+		_, err = b.read(8)
+	case 13, 14:
+		_, err = b.read(16)
+	case 15:
+		err = fmt.Errorf("采样率代码无效")
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("读取 FLAC 帧头失败: %w", err)
+	}
+	if _, err := b.read(8); err != nil { // 帧头 CRC-8
+		return nil, 0, fmt.Errorf("读取 FLAC 帧头失败: %w", err)
+	}
+
+	bitDepth = flacBitDepths[depthCode]
+	if depthCode == 0 {
+		bitDepth = fr.info.bitDepth
+	}
+	if bitDepth == 0 {
+		return nil, 0, fmt.Errorf("FLAC 采样位数代码无效")
+	}
+	channels := channelCode + 1
+	if channelCode >= 8 {
+		if channelCode > 10 {
+			return nil, 0, fmt.Errorf("FLAC 声道代码无效")
+		}
+		channels = 2
+	}
+	if channels != fr.info.channels {
+		return nil, 0, fmt.Errorf("FLAC 帧的声道数与 STREAMINFO 不一致")
+	}
+
+	block = make([][]int64, channels)
+	for ch := range block {
+		depth := bitDepth
+		// 差值声道比原始声道多一位
+		if (channelCode == 8 || channelCode == 10) && ch == 1 || channelCode == 9 && ch == 0 {
+			depth++
+		}
+		block[ch] = make([]int64, blockSize)
+		if err := fr.subframe(block[ch], depth); err != nil {
+			return nil, 0, fmt.Errorf("解码 FLAC 子帧失败: %w", err)
+		}
+	}
+
+	var left, right []int64
+	if channels == 2 {
+		left, right = block[0], block[1]
+	}
+	switch channelCode {
+	case 8: // 左声道 + 差值
+		for i := range right {
+			right[i] = left[i] - right[i]
+		}
+	case 9: // 差值 + 右声道
+		for i := range left {
+			left[i] += right[i]
+		}
+	case 10: // 中间 + 差值
+		for i := range left {
+			mid, side := left[i]<<1|right[i]&1, right[i]
+			left[i], right[i] = (mid+side)>>1, (mid-side)>>1
+		}
+	}
+
+	b.align()
+	if _, err := b.read(16); err != nil { // 帧尾 CRC-16
+		return nil, 0, fmt.Errorf("读取 FLAC 帧尾失败: %w", err)
+	}
+	fr.frames++
+	return block, bitDepth, nil
+}
+
+// subframe 解码一个声道的子帧。
+func (fr *flacReader) subframe(out []int64, depth int) error {
+	b := &fr.bits
+	header, err := b.read(8)
+	if err != nil {
+		return err
+	}
+	if header&0x80 != 0 {
+		return fmt.Errorf("子帧填充位无效")
+	}
+	kind := int(header >> 1 & 0x3f)
+	wasted := 0
+	if header&1 != 0 {
+		zeros, err := b.unary()
+		if err != nil {
+			return err
+		}
+		wasted = int(zeros) + 1
+		depth -= wasted
+	}
+	if depth <= 0 || depth > 33 {
+		return fmt.Errorf("子帧采样位数无效: %d", depth)
+	}
+
+	switch {
+	case kind == 0: // CONSTANT
+		v, err := b.signed(uint(depth))
+		if err != nil {
+			return err
+		}
+		for i := range out {
+			out[i] = v
+		}
+	case kind == 1: // VERBATIM
+		for i := range out {
+			if out[i], err = b.signed(uint(depth)); err != nil {
+				return err
+			}
+		}
+	case kind >= 8 && kind <= 12: // FIXED
+		order := kind - 8
+		if err := fr.warmup(out, order, depth); err != nil {
+			return err
+		}
+		if err := fr.residual(out, order); err != nil {
+			return err
+		}
+		restoreFixed(out, order)
+	case kind >= 32: // LPC
+		order := kind - 31
+		if err := fr.warmup(out, order, depth); err != nil {
+			return err
+		}
+		precision, err := b.read(4)
+		if err != nil {
+			return err
+		}
+		if precision == 0xf {
+			return fmt.Errorf("LPC 系数精度无效")
+		}
+		shift, err := b.signed(5)
+		if err != nil {
+			return err
+		}
+		if shift < 0 {
+			return fmt.Errorf("LPC 移位无效")
+		}
+		coeffs := make([]int64, order)
+		for i := range coeffs {
+			if coeffs[i], err = b.signed(uint(precision) + 1); err != nil {
+				return err
+			}
+		}
+		if err := fr.residual(out, order); err != nil {
+			return err
+		}
+		for i := order; i < len(out); i++ {
+			var sum int64
+			for j, c := range coeffs {
+				sum += c * out[i-1-j]
+			}
+			out[i] += sum >> uint(shift)
+		}
+	default:
+		return fmt.Errorf("子帧类型无效: %d", kind)
+	}
+
+	if wasted > 0 {
+		for i := range out {
+			out[i] <<= uint(wasted)
+		}
+	}
+	return nil
+}
+
+// warmup 读取预测器的前 order 个原始采样。
+func (fr *flacReader) warmup(out []int64, order, depth int) error {
+	if order > len(out) {
+		return fmt.Errorf("预测阶数大于块大小")
+	}
+	var err error
+	for i := 0; i < order; i++ {
+		if out[i], err = fr.bits.signed(uint(depth)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// residual 读取 Rice 编码的预测残差，写入 out[order:]。
+func (fr *flacReader) residual(out []int64, order int) error {
+	b := &fr.bits
+	method, err := b.read(2)
+	if err != nil {
+		return err
+	}
+	if method > 1 {
+		return fmt.Errorf("残差编码方式无效")
+	}
+	paramBits, escape := uint(4), uint64(0xf)
+	if method == 1 {
+		paramBits, escape = 5, 0x1f
+	}
+	partitionOrder, err := b.read(4)
+	if err != nil {
+		return err
+	}
+	partitions := 1 << partitionOrder
+	partitionSize := len(out) >> partitionOrder
+	if partitionSize<<partitionOrder != len(out) || partitionSize < order {
+		return fmt.Errorf("残差分区无效")
+	}
+
+	i := order
+	for p := 0; p < partitions; p++ {
+		end := (p + 1) * partitionSize
+		param, err := b.read(paramBits)
+		if err != nil {
+			return err
+		}
+		if param == escape {
+			raw, err := b.read(5)
+			if err != nil {
+				return err
+			}
+			for ; i < end; i++ {
+				if out[i], err = b.signed(uint(raw)); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		for ; i < end; i++ {
+			high, err := b.unary()
+			if err != nil {
+				return err
+			}
+			low, err := b.read(uint(param))
+			if err != nil {
+				return err
+			}
+			u := high<<param | low
+			out[i] = int64(u>>1) ^ -int64(u&1)
+		}
+	}
+	return nil
+}
+
+// restoreFixed 按固定阶预测器把残差还原为采样。
+func restoreFixed(out []int64, order int) {
+	for i := order; i < len(out); i++ {
+		switch order {
+		case 1:
+			out[i] += out[i-1]
+		case 2:
+			out[i] += 2*out[i-1] - out[i-2]
+		case 3:
+			out[i] += 3*out[i-1] - 3*out[i-2] + out[i-3]
+		case 4:
+			out[i] += 4*out[i-1] - 6*out[i-2] + 4*out[i-3] - out[i-4]
+		}
+	}
+}
+
+// flacBitReader 按大端位序读取比特。
+type flacBitReader struct {
+	r     io.ByteReader
+	cache uint64
+	n     uint
+}
+
+// read 读取 n（不超过 32）位无符号整数。在字节边界上没有更多数据时返回 io.EOF。
+func (b *flacBitReader) read(n uint) (uint64, error) {
+	if n == 0 {
+		return 0, nil
+	}
+	for b.n < n {
+		c, err := b.r.ReadByte()
+		if err != nil {
+			if errors.Is(err, io.EOF) && b.n > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		b.cache = b.cache<<8 | uint64(c)
+		b.n += 8
+	}
+	b.n -= n
+	v := b.cache >> b.n
+	b.cache &= 1<<b.n - 1
+	return v, nil
+}
+
+// signed 读取 n 位有符号整数。
+func (b *flacBitReader) signed(n uint) (int64, error) {
+	if n == 0 {
+		return 0, nil
+	}
+	if n > 32 {
+		high, err := b.read(n - 32)
+		if err != nil {
+			return 0, err
+		}
+		low, err := b.read(32)
+		if err != nil {
+			return 0, err
+		}
+		return int64((high<<32|low)<<(64-n)) >> (64 - n), nil
+	}
+	v, err := b.read(n)
+	if err != nil {
+		return 0, err
+	}
+	return int64(v<<(64-n)) >> (64 - n), nil
+}
+
+// unary 读取一元编码：返回 1 之前 0 的个数。
+func (b *flacBitReader) unary() (uint64, error) {
+	var zeros uint64
+	for {
+		if b.n == 0 {
+			c, err := b.r.ReadByte()
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					err = io.ErrUnexpectedEOF
+				}
+				return 0, err
+			}
+			if c == 0 {
+				zeros += 8
+				continue
+			}
+			b.cache, b.n = uint64(c), 8
+		}
+		for b.n > 0 {
+			b.n--
+			if b.cache>>b.n&1 != 0 {
+				b.cache &= 1<<b.n - 1
+				return zeros, nil
+			}
+			zeros++
+		}
+		b.cache = 0
+	}
+}
+
+// align 丢弃到下一个字节边界为止的比特。
+func (b *flacBitReader) align() {
+	b.n -= b.n % 8
+	b.cache &= 1<<b.n - 1
+}
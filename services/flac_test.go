@@ -0,0 +1,257 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// bitWriter 按大端位序写入比特，用于构造测试用的 FLAC 数据。
+type bitWriter struct {
+	buf   bytes.Buffer
+	cache uint64
+	n     uint
+}
+
+func (w *bitWriter) write(value uint64, bits uint) {
+	for i := int(bits) - 1; i >= 0; i-- {
+		w.cache = w.cache<<1 | value>>uint(i)&1
+		w.n++
+		if w.n == 8 {
+			w.buf.WriteByte(byte(w.cache))
+			w.cache, w.n = 0, 0
+		}
+	}
+}
+
+func (w *bitWriter) signed(value int64, bits uint) {
+	w.write(uint64(value)&(1<<bits-1), bits)
+}
+
+// rice 以参数 k 写入 Rice 编码的有符号整数。
+func (w *bitWriter) rice(value int64, k uint) {
+	u := uint64(value<<1) ^ uint64(value>>63)
+	for q := u >> k; q > 0; q-- {
+		w.write(0, 1)
+	}
+	w.write(1, 1)
+	w.write(u, k)
+}
+
+func (w *bitWriter) align() {
+	if w.n > 0 {
+		w.write(0, 8-w.n)
+	}
+}
+
+// flacTestSubframe 描述测试编码器写入的子帧。
+type flacTestSubframe struct {
+	kind   string // constant、verbatim、fixed 或 lpc
+	order  int
+	wasted uint
+}
+
+// encodeTestFLAC 将 16 位采样编码为 FLAC。每个帧对应 frames 中的一项，声道代码 channelCode 决定立体声的存储方式。
+// 编码器只用于测试，残差不分区，帧尾的 CRC 写为 0。
+func encodeTestFLAC(left, right []int64, blockSize int, channelCode int, frames [][2]flacTestSubframe) []byte {
+	var w bitWriter
+	w.buf.WriteString("fLaC")
+	w.write(1<<7|0, 8) // 最后一个元数据块，类型为 STREAMINFO
+	w.write(34, 24)
+	w.write(uint64(blockSize), 16)
+	w.write(uint64(blockSize), 16)
+	w.write(0, 24)
+	w.write(0, 24)
+	w.write(44100, 20)
+	w.write(1, 3) // 2 声道
+	w.write(15, 5)
+	w.write(uint64(len(left)), 36)
+	w.write(0, 64)
+	w.write(0, 64) // MD5
+
+	for f, subframes := range frames {
+		start, end := f*blockSize, min((f+1)*blockSize, len(left))
+		l, r := left[start:end], right[start:end]
+		ch0, ch1 := l, r
+		depth0, depth1 := uint(16), uint(16)
+		side := make([]int64, len(l))
+		for i := range l {
+			side[i] = l[i] - r[i]
+		}
+		switch channelCode {
+		case 8:
+			ch1, depth1 = side, 17
+		case 9:
+			ch0, depth0 = side, 17
+		case 10:
+			mid := make([]int64, len(l))
+			for i := range l {
+				mid[i] = (l[i] + r[i]) >> 1
+			}
+			ch0, ch1, depth1 = mid, side, 17
+		}
+
+		frameStart := w.buf.Len()
+		w.write(0x3ffe, 14)
+		w.write(0, 2)
+		w.write(7, 4) // 块大小在帧头末尾以 16 位给出
+		w.write(0, 4) // 采样率取自 STREAMINFO
+		w.write(uint64(channelCode), 4)
+		w.write(4, 3) // 16 位
+		w.write(0, 1)
+		w.write(uint64(f), 8) // 帧号
+		w.write(uint64(len(l)-1), 16)
+		w.write(uint64(flacCRC8(w.buf.Bytes()[frameStart:])), 8)
+
+		for i, channel := range [][]int64{ch0, ch1} {
+			depth := []uint{depth0, depth1}[i]
+			writeTestSubframe(&w, channel, depth, subframes[i])
+		}
+		w.align()
+		w.write(0, 16)
+	}
+	return w.buf.Bytes()
+}
+
+func writeTestSubframe(w *bitWriter, samples []int64, depth uint, sub flacTestSubframe) {
+	kind := map[string]uint64{"constant": 0, "verbatim": 1, "fixed": 8 + uint64(sub.order), "lpc": 31 + uint64(sub.order)}[sub.kind]
+	w.write(0, 1)
+	w.write(kind, 6)
+	if sub.wasted > 0 {
+		w.write(1, 1)
+		w.write(1, sub.wasted) // sub.wasted-1 个 0 后跟 1
+		shifted := make([]int64, len(samples))
+		for i, v := range samples {
+			shifted[i] = v >> sub.wasted
+		}
+		samples, depth = shifted, depth-sub.wasted
+	} else {
+		w.write(0, 1)
+	}
+
+	switch sub.kind {
+	case "constant":
+		w.signed(samples[0], depth)
+		return
+	case "verbatim":
+		for _, v := range samples {
+			w.signed(v, depth)
+		}
+		return
+	}
+
+	for _, v := range samples[:sub.order] {
+		w.signed(v, depth)
+	}
+	residual := make([]int64, 0, len(samples))
+	if sub.kind == "lpc" {
+		// 系数全为 1 阶差分：s[i] = s[i-1]
+		w.write(1, 4) // 精度 2 位
+		w.signed(0, 5)
+		w.signed(1, 2)
+		for i := sub.order; i < len(samples); i++ {
+			residual = append(residual, samples[i]-samples[i-1])
+		}
+	} else {
+		for i := sub.order; i < len(samples); i++ {
+			var predicted int64
+			switch sub.order {
+			case 1:
+				predicted = samples[i-1]
+			case 2:
+				predicted = 2*samples[i-1] - samples[i-2]
+			}
+			residual = append(residual, samples[i]-predicted)
+		}
+	}
+	w.write(0, 2) // 4 位 Rice 参数
+	w.write(0, 4) // 不分区
+	w.write(4, 4)
+	for _, v := range residual {
+		w.rice(v, 4)
+	}
+}
+
+// TestDecodeFLAC 测试各种子帧类型和立体声存储方式都能无损还原。
+func TestDecodeFLAC(t *testing.T) {
+	const blockSize = 64
+	n := blockSize*3 + 10
+	left := make([]int64, n)
+	right := make([]int64, n)
+	for i := range left {
+		left[i] = int64((i*37)%200 - 100)
+		right[i] = int64((i*53)%300-150) * 4 // 低两位为 0，可以用 wasted bits 编码
+	}
+	// 第一帧为常量
+	for i := 0; i < blockSize; i++ {
+		left[i], right[i] = -5, -5
+	}
+
+	frames := [][2]flacTestSubframe{
+		{{kind: "constant"}, {kind: "constant"}},
+		{{kind: "fixed", order: 2}, {kind: "verbatim"}},
+		{{kind: "lpc", order: 1}, {kind: "fixed", order: 1}},
+		{{kind: "verbatim"}, {kind: "fixed", order: 0}},
+	}
+	for _, channelCode := range []int{1, 8, 9, 10} {
+		data := encodeTestFLAC(left, right, blockSize, channelCode, frames)
+		sink := &collectSink{}
+		if err := decodeFLAC(bufio.NewReader(bytes.NewReader(data)), sink); err != nil {
+			t.Fatalf("声道代码 %d: 解码失败: %v", channelCode, err)
+		}
+		if sink.sampleRate != 44100 || len(sink.samples) != 2 || len(sink.samples[0]) != n {
+			t.Fatalf("声道代码 %d: 期望 44100Hz 立体声 %d 帧, 得到 %dHz %d 声道 %d 帧",
+				channelCode, n, sink.sampleRate, len(sink.samples), len(sink.samples[0]))
+		}
+		for i := 0; i < n; i++ {
+			if sink.samples[0][i]*32768 != float64(left[i]) || sink.samples[1][i]*32768 != float64(right[i]) {
+				t.Fatalf("声道代码 %d: 第 %d 帧期望 (%d, %d), 得到 (%v, %v)",
+					channelCode, i, left[i], right[i], sink.samples[0][i]*32768, sink.samples[1][i]*32768)
+			}
+		}
+	}
+
+	// wasted bits
+	frames = [][2]flacTestSubframe{{{kind: "verbatim"}, {kind: "fixed", order: 1, wasted: 2}}}
+	data := encodeTestFLAC(left[blockSize:2*blockSize], right[blockSize:2*blockSize], blockSize, 1, frames)
+	sink := &collectSink{}
+	if err := decodeFLAC(bytes.NewReader(data), sink); err != nil {
+		t.Fatalf("解码失败: %v", err)
+	}
+	for i, v := range sink.samples[1] {
+		if v*32768 != float64(right[blockSize+i]) {
+			t.Fatalf("wasted bits: 第 %d 帧期望 %d, 得到 %v", i, right[blockSize+i], v*32768)
+		}
+	}
+}
+
+// TestDecodeFLAC_ID3AndErrors 测试跳过文件开头的 ID3v2 标签，以及无效数据返回错误。
+func TestDecodeFLAC_ID3AndErrors(t *testing.T) {
+	samples := []int64{1, 2, 3, 4}
+	flac := encodeTestFLAC(samples, samples, 4, 1, [][2]flacTestSubframe{{{kind: "verbatim"}, {kind: "verbatim"}}})
+
+	id3 := []byte{'I', 'D', '3', 3, 0, 0, 0, 0, 0, 5, 0, 0, 0, 0, 0}
+	sink := &collectSink{}
+	if err := decodeFLAC(bytes.NewReader(append(id3, flac...)), sink); err != nil {
+		t.Fatalf("解码失败: %v", err)
+	}
+	if len(sink.samples[0]) != 4 {
+		t.Errorf("期望 4 帧, 得到 %d", len(sink.samples[0]))
+	}
+
+	if err := decodeFLAC(bytes.NewReader([]byte("OggS....")), &collectSink{}); err == nil {
+		t.Error("期望非 FLAC 数据返回错误")
+	}
+	truncated := flac[:len(flac)-6]
+	if err := decodeFLAC(bytes.NewReader(truncated), &collectSink{}); err == nil {
+		t.Error("期望截断的帧返回错误")
+	}
+
+	// STREAMINFO 中的采样率为 0
+	broken := bytes.Clone(flac)
+	binary.BigEndian.PutUint32(broken[18:22], binary.BigEndian.Uint32(broken[18:22])&0x00000fff)
+	if err := decodeFLAC(bytes.NewReader(broken), &collectSink{}); err == nil {
+		t.Error("期望无效的 STREAMINFO 返回错误")
+	}
+}
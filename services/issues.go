@@ -0,0 +1,61 @@
+package services
+
+import (
+	"slices"
+	"strings"
+	"zero-music/models"
+)
+
+// IssueFilter 是歌曲库问题列表的筛选条件，零值表示不限制。
+type IssueFilter struct {
+	// Kinds 是要列出的问题类型
+	Kinds []string
+	// Library 是音乐库名称
+	Library string
+	// Query 匹配库内相对路径或标题，不区分大小写
+	Query string
+}
+
+// IssueReport 是歌曲库健康报告。
+type IssueReport struct {
+	Issues []*models.LibraryIssue `json:"issues"`
+	// Total 是符合筛选条件的问题数量，Files 是这些问题涉及的文件数量
+	Total int `json:"total"`
+	Files int `json:"files"`
+	// Counts 是按问题类型统计的数量，只按音乐库和关键词筛选，不受 Kinds 影响
+	Counts map[string]int `json:"counts"`
+}
+
+// NewIssueReport 按筛选条件生成歌曲库健康报告，Issues 只包含 offset 开始的最多 limit 个问题。
+func NewIssueReport(issues []*models.LibraryIssue, filter IssueFilter, limit, offset int) *IssueReport {
+	report := &IssueReport{Issues: []*models.LibraryIssue{}, Counts: make(map[string]int, len(models.IssueKinds))}
+	for _, kind := range models.IssueKinds {
+		report.Counts[kind] = 0
+	}
+
+	query := strings.ToLower(strings.TrimSpace(filter.Query))
+	files := make(map[string]bool)
+	var matched []*models.LibraryIssue
+	for _, issue := range issues {
+		if filter.Library != "" && issue.Library != filter.Library {
+			continue
+		}
+		if query != "" && !strings.Contains(strings.ToLower(issue.RelPath), query) && !strings.Contains(strings.ToLower(issue.Title), query) {
+			continue
+		}
+		report.Counts[issue.Kind]++
+		if len(filter.Kinds) > 0 && !slices.Contains(filter.Kinds, issue.Kind) {
+			continue
+		}
+		matched = append(matched, issue)
+		files[issue.FilePath] = true
+	}
+
+	report.Total = len(matched)
+	report.Files = len(files)
+	if offset < len(matched) {
+		end := min(offset+limit, len(matched))
+		report.Issues = matched[offset:end]
+	}
+	return report
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"zero-music/models"
+)
+
+// TestMusicScanner_Issues 测试扫描记录文件的问题，文件删除后问题随之消失。
+func TestMusicScanner_Issues(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "Album", "noise.mp3")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, make([]byte, 4096), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	scanner := NewMusicScanner(dir, []string{".mp3"}, 5)
+	if _, err := scanner.Scan(context.Background()); err != nil {
+		t.Fatalf("扫描失败: %v", err)
+	}
+	issues := scanner.Issues()
+	if len(issues) == 0 {
+		t.Fatal("期望记录无法识别的文件的问题")
+	}
+	kinds := make(map[string]bool)
+	for _, issue := range issues {
+		kinds[issue.Kind] = true
+		if issue.RelPath != "Album/noise.mp3" || issue.SongID == "" {
+			t.Errorf("问题应指向歌曲和相对路径, 得到 %+v", issue)
+		}
+	}
+	if !kinds[models.IssueMissingTitle] || !kinds[models.IssueUnsupportedCodec] {
+		t.Errorf("期望缺少标题和无法识别音频流, 得到 %v", kinds)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := scanner.ApplyChanges(context.Background(), []string{path}); err != nil {
+		t.Fatalf("应用变化失败: %v", err)
+	}
+	if issues := scanner.Issues(); len(issues) != 0 {
+		t.Errorf("期望文件删除后没有问题, 得到 %d 个", len(issues))
+	}
+}
+
+// TestNewIssueReport 测试按类型、音乐库和关键词筛选问题并分页。
+func TestNewIssueReport(t *testing.T) {
+	issues := []*models.LibraryIssue{
+		{Kind: models.IssuePermissionDenied, Library: "default", FilePath: "/music/locked.flac", RelPath: "locked.flac"},
+		{Kind: models.IssueMissingTitle, Library: "default", FilePath: "/music/a.mp3", RelPath: "a.mp3", SongID: "a", Title: "a"},
+		{Kind: models.IssueMissingAlbum, Library: "default", FilePath: "/music/a.mp3", RelPath: "a.mp3", SongID: "a", Title: "a"},
+		{Kind: models.IssueMissingAlbum, Library: "default", FilePath: "/music/Live/b.mp3", RelPath: "Live/b.mp3", SongID: "b", Title: "Encore"},
+		{Kind: models.IssueMissingAlbum, Library: "vinyl", FilePath: "/vinyl/c.flac", RelPath: "c.flac", SongID: "c", Title: "c"},
+	}
+
+	report := NewIssueReport(issues, IssueFilter{}, 50, 0)
+	if report.Total != 5 || report.Files != 4 || report.Counts[models.IssueMissingAlbum] != 3 || report.Counts[models.IssueZeroDuration] != 0 {
+		t.Errorf("未筛选时统计错误: %+v", report)
+	}
+
+	report = NewIssueReport(issues, IssueFilter{Kinds: []string{models.IssueMissingAlbum}, Library: "default"}, 1, 1)
+	if report.Total != 2 || report.Files != 2 || len(report.Issues) != 1 || report.Issues[0].SongID != "b" {
+		t.Errorf("按类型和音乐库筛选错误: %+v", report)
+	}
+	// 按类型统计不受类型筛选影响
+	if report.Counts[models.IssuePermissionDenied] != 1 || report.Counts[models.IssueMissingAlbum] != 2 {
+		t.Errorf("统计应包含所有类型: %v", report.Counts)
+	}
+
+	report = NewIssueReport(issues, IssueFilter{Query: "encore"}, 50, 0)
+	if report.Total != 1 || report.Issues[0].SongID != "b" {
+		t.Errorf("按关键词筛选错误: %+v", report)
+	}
+
+	if report = NewIssueReport(issues, IssueFilter{}, 50, 10); report.Issues == nil || len(report.Issues) != 0 {
+		t.Errorf("超出范围的分页应返回空列表, 得到 %+v", report.Issues)
+	}
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// 响度测量参照 ITU-R BS.1770-4 / EBU R128：K 计权后按 400ms 窗口、100ms 步进计算块响度，
+// 先以 -70 LUFS 绝对门限、再以低于平均响度 10 LU 的相对门限筛选块，剩余块的平均能量即为综合响度。
+const (
+	loudnessAbsoluteGate = -70.0
+	loudnessRelativeGate = -10.0
+	// loudnessSegmentsPerBlock 是每个 400ms 测量块包含的 100ms 片段数
+	loudnessSegmentsPerBlock = 4
+)
+
+// errNoLoudness 表示音频太短或太安静，无法得出综合响度。
+var errNoLoudness = errors.New("无法测量响度")
+
+// loudnessMeasurement 是一首歌曲的响度测量结果。
+type loudnessMeasurement struct {
+	// blocks 是各测量块经过声道加权的平均能量，用于计算单曲和专辑的综合响度
+	blocks []float64
+	// peak 是采样峰值，1.0 表示满幅
+	peak float64
+}
+
+// analyzeLoudness 解码音频文件并测量响度。
+func analyzeLoudness(path string) (*loudnessMeasurement, error) {
+	meter := &loudnessMeter{}
+	if err := decodePCMFile(path, meter); err != nil {
+		return nil, err
+	}
+	if meter.filters == nil {
+		return nil, fmt.Errorf("音频没有采样")
+	}
+	return &loudnessMeasurement{blocks: meter.blocks, peak: meter.peak}, nil
+}
+
+// integratedLoudness 计算测量块的门限综合响度（LUFS）。没有块通过绝对门限时返回 errNoLoudness。
+func integratedLoudness(blocks []float64) (float64, error) {
+	absolute := loudnessToEnergy(loudnessAbsoluteGate)
+	var sum float64
+	var count int
+	for _, energy := range blocks {
+		if energy > absolute {
+			sum += energy
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, errNoLoudness
+	}
+
+	relative := sum / float64(count) * math.Pow(10, loudnessRelativeGate/10)
+	threshold := math.Max(absolute, relative)
+	sum, count = 0, 0
+	for _, energy := range blocks {
+		if energy > threshold {
+			sum += energy
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, errNoLoudness
+	}
+	return energyToLoudness(sum / float64(count)), nil
+}
+
+// loudnessEnergyOffset 是 BS.1770 中能量换算为 LUFS 时的常数项。
+const loudnessEnergyOffset = -0.691
+
+func energyToLoudness(energy float64) float64 {
+	return loudnessEnergyOffset + 10*math.Log10(energy)
+}
+
+func loudnessToEnergy(loudness float64) float64 {
+	return math.Pow(10, (loudness-loudnessEnergyOffset)/10)
+}
+
+// loudnessMeter 是实现 pcmSink 的响度表。
+type loudnessMeter struct {
+	weights []float64
+	filters []kWeightingFilter
+
+	segmentSize int
+	segmentPos  int
+	segment     float64
+	// recent 保存最近的 100ms 片段能量，凑满 4 个即得到一个测量块
+	recent []float64
+	blocks []float64
+	peak   float64
+}
+
+func (m *loudnessMeter) begin(sampleRate, channels int) error {
+	if sampleRate < 1000 || channels <= 0 {
+		return fmt.Errorf("不支持的音频参数: 采样率 %d, %d 声道", sampleRate, channels)
+	}
+	m.weights = loudnessChannelWeights(channels)
+	m.filters = make([]kWeightingFilter, channels)
+	for i := range m.filters {
+		m.filters[i] = newKWeightingFilter(float64(sampleRate))
+	}
+	m.segmentSize = sampleRate / 10
+	return nil
+}
+
+func (m *loudnessMeter) write(samples [][]float64) {
+	frames := len(samples[0])
+	for i := 0; i < frames; i++ {
+		for ch, channel := range samples {
+			x := channel[i]
+			if a := math.Abs(x); a > m.peak {
+				m.peak = a
+			}
+			y := m.filters[ch].process(x)
+			m.segment += m.weights[ch] * y * y
+		}
+		m.segmentPos++
+		if m.segmentPos == m.segmentSize {
+			m.endSegment()
+		}
+	}
+}
+
+// endSegment 结束当前 100ms 片段，必要时生成新的测量块。
+func (m *loudnessMeter) endSegment() {
+	m.recent = append(m.recent, m.segment)
+	m.segment, m.segmentPos = 0, 0
+	if len(m.recent) < loudnessSegmentsPerBlock {
+		return
+	}
+	var sum float64
+	for _, energy := range m.recent {
+		sum += energy
+	}
+	m.blocks = append(m.blocks, sum/float64(loudnessSegmentsPerBlock*m.segmentSize))
+	m.recent = append(m.recent[:0], m.recent[1:]...)
+}
+
+// loudnessChannelWeights 返回各声道的加权系数。5.0/5.1 声道按 WAV/FLAC 的声道顺序
+// 给环绕声道 +1.5 dB 的权重，并忽略低音声道；其他布局所有声道权重相同。
+func loudnessChannelWeights(channels int) []float64 {
+	switch channels {
+	case 5:
+		return []float64{1, 1, 1, 1.41, 1.41}
+	case 6:
+		return []float64{1, 1, 1, 0, 1.41, 1.41}
+	}
+	weights := make([]float64, channels)
+	for i := range weights {
+		weights[i] = 1
+	}
+	return weights
+}
+
+// biquad 是转置直接 II 型的二阶 IIR 滤波器。
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	z1, z2             float64
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.z1
+	f.z1 = f.b1*x - f.a1*y + f.z2
+	f.z2 = f.b2*x - f.a2*y
+	return y
+}
+
+// kWeightingFilter 是 BS.1770 的 K 计权滤波器：高架滤波器模拟头部的声学效应，高通滤波器模拟 RLB 计权。
+type kWeightingFilter struct {
+	shelf, highPass biquad
+}
+
+// newKWeightingFilter 按采样率计算 K 计权滤波器系数，使 48kHz 以外的采样率得到与标准一致的频响。
+func newKWeightingFilter(sampleRate float64) kWeightingFilter {
+	const (
+		shelfFreq = 1681.974450955533
+		shelfGain = 3.999843853973347
+		shelfQ    = 0.7071752369554196
+		passFreq  = 38.13547087602444
+		passQ     = 0.5003270373238773
+	)
+
+	k := math.Tan(math.Pi * shelfFreq / sampleRate)
+	vh := math.Pow(10, shelfGain/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/shelfQ + k*k
+	shelf := biquad{
+		b0: (vh + vb*k/shelfQ + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/shelfQ + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/shelfQ + k*k) / a0,
+	}
+
+	k = math.Tan(math.Pi * passFreq / sampleRate)
+	a0 = 1 + k/passQ + k*k
+	highPass := biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/passQ + k*k) / a0,
+	}
+	return kWeightingFilter{shelf: shelf, highPass: highPass}
+}
+
+func (f *kWeightingFilter) process(x float64) float64 {
+	return f.highPass.process(f.shelf.process(x))
+}
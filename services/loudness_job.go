@@ -0,0 +1,316 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+	"zero-music/logger"
+	"zero-music/models"
+)
+
+// LoudnessStatus 是响度分析任务在某一时刻的状态快照。
+type LoudnessStatus struct {
+	State      ScanState  `json:"state"`
+	Force      bool       `json:"force"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Total      int64      `json:"total"`    // 需要分析的歌曲数量
+	Analyzed   int64      `json:"analyzed"` // 已分析成功的歌曲数量
+	Failed     int64      `json:"failed"`   // 无法解码或分析的歌曲数量
+	ElapsedMs  int64      `json:"elapsed_ms"`
+}
+
+// loudnessJob 是一次由管理接口发起的响度分析。
+type loudnessJob struct {
+	force      bool
+	startedAt  time.Time
+	finishedAt time.Time
+	total      atomic.Int64
+	analyzed   atomic.Int64
+	failed     atomic.Int64
+	cancel     context.CancelFunc
+	done       chan struct{}
+	state      ScanState
+}
+
+// LoudnessJobManager 管理后台响度分析任务，为没有音量标签的 WAV/FLAC 歌曲按 EBU R128 计算
+// ReplayGain 增益和峰值。同一时间只有一个任务在运行。
+type LoudnessJobManager struct {
+	scanner     *MusicScanner
+	concurrency int
+	mu          sync.Mutex
+	current     *loudnessJob
+}
+
+// NewLoudnessJobManager 创建一个新的 LoudnessJobManager 实例，concurrency 是同时分析的专辑数量。
+func NewLoudnessJobManager(scanner *MusicScanner, concurrency int) *LoudnessJobManager {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	return &LoudnessJobManager{scanner: scanner, concurrency: concurrency}
+}
+
+// Start 在后台发起一次响度分析并返回任务状态。force 为 true 时重新分析之前由服务器分析过的歌曲。
+// 已有任务在运行时不会发起新的分析，而是返回正在运行的任务，attached 为 true。
+func (m *LoudnessJobManager) Start(force bool) (status LoudnessStatus, attached bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.current != nil && m.current.state == ScanStateRunning {
+		return m.current.status(), true
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &loudnessJob{
+		force:     force,
+		startedAt: time.Now(),
+		cancel:    cancel,
+		done:      make(chan struct{}),
+		state:     ScanStateRunning,
+	}
+	m.current = job
+
+	go m.run(ctx, job)
+	return job.status(), false
+}
+
+// run 按专辑分组并发分析，每分析完一张专辑就写入歌曲库。
+func (m *LoudnessJobManager) run(ctx context.Context, job *loudnessJob) {
+	defer close(job.done)
+	defer job.cancel()
+
+	groups := loudnessGroups(m.scanner.GetSongs(), job.force)
+	for _, group := range groups {
+		job.total.Add(int64(len(group.songs)))
+	}
+	logger.Infof("开始分析 %d 首歌曲的响度", job.total.Load())
+
+	albums := make(chan *loudnessGroup)
+	var wg sync.WaitGroup
+	for i := 0; i < m.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for group := range albums {
+				analyzed := group.analyze(ctx, job)
+				if len(analyzed) > 0 {
+					m.scanner.UpdateReplayGain(analyzed)
+				}
+			}
+		}()
+	}
+feed:
+	for _, group := range groups {
+		select {
+		case albums <- group:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(albums)
+	wg.Wait()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job.finishedAt = time.Now()
+	if ctx.Err() != nil {
+		job.state = ScanStateCancelled
+		logger.Infof("响度分析已取消")
+		return
+	}
+	job.state = ScanStateCompleted
+	logger.Infof("响度分析完成: 成功 %d, 失败 %d", job.analyzed.Load(), job.failed.Load())
+}
+
+// Cancel 取消正在运行的分析并等待其结束，没有正在运行的任务时 ok 为 false。
+// 已分析完成的专辑会保留结果。
+func (m *LoudnessJobManager) Cancel() (status LoudnessStatus, ok bool) {
+	m.mu.Lock()
+	job := m.current
+	if job == nil || job.state != ScanStateRunning {
+		m.mu.Unlock()
+		return m.Status(), false
+	}
+	m.mu.Unlock()
+
+	job.cancel()
+	<-job.done
+	return m.Status(), true
+}
+
+// Status 返回最近一次分析任务的状态，尚未发起过分析时状态为 idle。
+func (m *LoudnessJobManager) Status() LoudnessStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.current == nil {
+		return LoudnessStatus{State: ScanStateIdle}
+	}
+	return m.current.status()
+}
+
+// Wait 等待当前任务结束或 ctx 取消，并返回此时的任务状态。
+func (m *LoudnessJobManager) Wait(ctx context.Context) (LoudnessStatus, error) {
+	m.mu.Lock()
+	job := m.current
+	m.mu.Unlock()
+	if job != nil {
+		select {
+		case <-job.done:
+		case <-ctx.Done():
+			return m.Status(), ctx.Err()
+		}
+	}
+	return m.Status(), nil
+}
+
+// Stop 取消正在运行的分析并等待其结束，用于服务关闭。
+func (m *LoudnessJobManager) Stop() {
+	m.Cancel()
+}
+
+// status 返回任务的状态快照。调用此函数前必须持有 LoudnessJobManager 的锁。
+func (j *loudnessJob) status() LoudnessStatus {
+	startedAt := j.startedAt
+	status := LoudnessStatus{
+		State:     j.state,
+		Force:     j.force,
+		StartedAt: &startedAt,
+		Total:     j.total.Load(),
+		Analyzed:  j.analyzed.Load(),
+		Failed:    j.failed.Load(),
+	}
+	if j.state == ScanStateRunning {
+		status.ElapsedMs = time.Since(j.startedAt).Milliseconds()
+		return status
+	}
+
+	finishedAt := j.finishedAt
+	status.FinishedAt = &finishedAt
+	status.ElapsedMs = j.finishedAt.Sub(j.startedAt).Milliseconds()
+	return status
+}
+
+// loudnessGroup 是一组一起分析的歌曲，通常是同一张专辑。
+type loudnessGroup struct {
+	songs []*models.Song
+	// album 为 true 时组内是一张专辑的全部曲目，全部分析成功后计算专辑增益
+	album bool
+}
+
+// loudnessGroups 选出需要分析的歌曲并按专辑分组：没有音量信息的 WAV/FLAC 歌曲，
+// force 为 true 时还包括之前由服务器分析过的歌曲。只有专辑的全部曲目都需要分析时才计算专辑增益，
+// 以免与标签中已有的专辑增益不一致；没有专辑信息的歌曲单独成组。
+// CUE 曲目每次扫描都会重新生成，不保留分析结果，因此不参与分析。
+func loudnessGroups(songs []*models.Song, force bool) []*loudnessGroup {
+	needsAnalysis := func(song *models.Song) bool {
+		if !canDecodePCM(song.FilePath) || song.IsCueTrack() {
+			return false
+		}
+		return song.ReplayGain == nil || force && song.ReplayGain.Source == models.LoudnessSourceAnalysis
+	}
+
+	type albumKey struct{ library, artist, album string }
+	albums := make(map[albumKey]*loudnessGroup)
+	var groups []*loudnessGroup
+	for _, song := range songs {
+		if song.Album == "" || song.Album == "Unknown" {
+			if needsAnalysis(song) {
+				groups = append(groups, &loudnessGroup{songs: []*models.Song{song}})
+			}
+			continue
+		}
+		key := albumKey{songLibrary(song), song.AlbumArtistName(), song.Album}
+		group, ok := albums[key]
+		if !ok {
+			group = &loudnessGroup{album: true}
+			albums[key] = group
+			groups = append(groups, group)
+		}
+		if needsAnalysis(song) {
+			group.songs = append(group.songs, song)
+		} else {
+			group.album = false
+		}
+	}
+
+	result := groups[:0]
+	for _, group := range groups {
+		if len(group.songs) > 0 {
+			result = append(result, group)
+		}
+	}
+	return result
+}
+
+// analyze 依次分析组内歌曲，返回带有新音量信息的歌曲副本。ctx 取消时返回 nil，不写入不完整的专辑。
+func (g *loudnessGroup) analyze(ctx context.Context, job *loudnessJob) []*models.Song {
+	measurements := make([]*loudnessMeasurement, len(g.songs))
+	complete := g.album
+	for i, song := range g.songs {
+		if ctx.Err() != nil {
+			return nil
+		}
+		m, err := analyzeLoudness(song.FilePath)
+		if err != nil {
+			logger.Warnf("分析响度失败 %s: %v", song.FilePath, err)
+			job.failed.Add(1)
+			complete = false
+			continue
+		}
+		measurements[i] = m
+		job.analyzed.Add(1)
+	}
+
+	var albumGain, albumPeak *float64
+	if complete {
+		var blocks []float64
+		var peak float64
+		for _, m := range measurements {
+			blocks = append(blocks, m.blocks...)
+			peak = math.Max(peak, m.peak)
+		}
+		if loudness, err := integratedLoudness(blocks); err == nil {
+			albumGain = floatPtr(roundDecibels(models.ReplayGainReferenceLUFS - loudness))
+		}
+		albumPeak = floatPtr(roundPeak(peak))
+	}
+
+	var analyzed []*models.Song
+	for i, song := range g.songs {
+		m := measurements[i]
+		if m == nil {
+			continue
+		}
+		rg := &models.ReplayGain{
+			Source:    models.LoudnessSourceAnalysis,
+			TrackPeak: floatPtr(roundPeak(m.peak)),
+			AlbumGain: albumGain,
+			AlbumPeak: albumPeak,
+		}
+		// 静音或过短的歌曲无法得出增益，只记录峰值，避免每次分析都重试
+		if loudness, err := integratedLoudness(m.blocks); err == nil {
+			rg.Loudness = floatPtr(roundDecibels(loudness))
+			rg.TrackGain = floatPtr(roundDecibels(models.ReplayGainReferenceLUFS - loudness))
+		}
+		copied := *song
+		copied.ReplayGain = rg
+		analyzed = append(analyzed, &copied)
+	}
+	return analyzed
+}
+
+// roundDecibels 将响度或增益保留两位小数，与常见的 ReplayGain 标签格式一致。
+func roundDecibels(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// roundPeak 将峰值保留六位小数。
+func roundPeak(value float64) float64 {
+	return math.Round(value*1e6) / 1e6
+}
+
+func floatPtr(value float64) *float64 {
+	return &value
+}
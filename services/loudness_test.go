@@ -0,0 +1,353 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"zero-music/models"
+)
+
+// encodeTestWAV 生成 16 位整数 PCM 的 WAV 文件，sample 返回 [-1, 1) 范围内的采样值。
+func encodeTestWAV(sampleRate, channels, frames int, sample func(ch, i int) float64) []byte {
+	var buf bytes.Buffer
+	dataSize := frames * channels * 2
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+dataSize))
+	buf.WriteString("WAVEfmt ")
+	writeLE(&buf, uint32(16), uint16(wavFormatPCM), uint16(channels), uint32(sampleRate),
+		uint32(sampleRate*channels*2), uint16(channels*2), uint16(16))
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(dataSize))
+	for i := 0; i < frames; i++ {
+		for ch := 0; ch < channels; ch++ {
+			binary.Write(&buf, binary.LittleEndian, int16(math.Round(sample(ch, i)*32767)))
+		}
+	}
+	return buf.Bytes()
+}
+
+// writeLE 依次以小端序写入 values。
+func writeLE(buf *bytes.Buffer, values ...any) {
+	for _, v := range values {
+		binary.Write(buf, binary.LittleEndian, v)
+	}
+}
+
+// sineWave 返回幅度为 level dBFS 的正弦波。
+func sineWave(sampleRate int, freq, level float64) func(ch, i int) float64 {
+	amplitude := math.Pow(10, level/20)
+	return func(ch, i int) float64 {
+		return amplitude * math.Sin(2*math.Pi*freq*float64(i)/float64(sampleRate))
+	}
+}
+
+// measureSamples 用响度表测量 WAV 数据。
+func measureSamples(t *testing.T, data []byte) *loudnessMeter {
+	t.Helper()
+	meter := &loudnessMeter{}
+	if err := decodeWAV(bytes.NewReader(data), meter); err != nil {
+		t.Fatalf("解码 WAV 失败: %v", err)
+	}
+	return meter
+}
+
+// TestLoudnessMeter_Sine 按 EBU Tech 3341 的方法验证：-20 dBFS 的 1kHz 立体声正弦波应测得 -20 LUFS。
+func TestLoudnessMeter_Sine(t *testing.T) {
+	tests := []struct {
+		name       string
+		sampleRate int
+		channels   int
+		want       float64
+	}{
+		{"立体声 48kHz", 48000, 2, -20},
+		{"立体声 44.1kHz", 44100, 2, -20},
+		{"单声道", 48000, 1, -23.01}, // 只有一个声道时能量减半
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := encodeTestWAV(tt.sampleRate, tt.channels, tt.sampleRate*5, sineWave(tt.sampleRate, 997, -20))
+			meter := measureSamples(t, data)
+
+			loudness, err := integratedLoudness(meter.blocks)
+			if err != nil {
+				t.Fatalf("测量响度失败: %v", err)
+			}
+			if math.Abs(loudness-tt.want) > 0.1 {
+				t.Errorf("期望响度 %.2f LUFS, 得到 %.2f", tt.want, loudness)
+			}
+			if math.Abs(meter.peak-0.1) > 0.001 {
+				t.Errorf("期望峰值约为 0.1, 得到 %f", meter.peak)
+			}
+			// 5 秒音频以 100ms 步进产生 47 个 400ms 测量块
+			if len(meter.blocks) != 47 {
+				t.Errorf("期望 47 个测量块, 得到 %d", len(meter.blocks))
+			}
+		})
+	}
+}
+
+// TestIntegratedLoudness_Gating 测试绝对门限和相对门限。
+func TestIntegratedLoudness_Gating(t *testing.T) {
+	loud := loudnessToEnergy(-20)
+	quiet := loudnessToEnergy(-40) // 低于相对门限
+	silent := loudnessToEnergy(-80)
+
+	loudness, err := integratedLoudness([]float64{loud, loud, quiet, silent, silent})
+	if err != nil {
+		t.Fatalf("测量响度失败: %v", err)
+	}
+	if math.Abs(loudness+20) > 1e-9 {
+		t.Errorf("期望安静和静音的块被门限排除, 得到 %.2f LUFS", loudness)
+	}
+
+	if _, err := integratedLoudness([]float64{silent, silent}); !errors.Is(err, errNoLoudness) {
+		t.Errorf("期望静音返回 errNoLoudness, 得到 %v", err)
+	}
+	if _, err := integratedLoudness(nil); !errors.Is(err, errNoLoudness) {
+		t.Errorf("期望没有测量块时返回 errNoLoudness, 得到 %v", err)
+	}
+}
+
+// collectSink 收集解码出的全部采样。
+type collectSink struct {
+	sampleRate int
+	samples    [][]float64
+}
+
+func (s *collectSink) begin(sampleRate, channels int) error {
+	s.sampleRate = sampleRate
+	s.samples = make([][]float64, channels)
+	return nil
+}
+
+func (s *collectSink) write(samples [][]float64) {
+	for ch := range samples {
+		s.samples[ch] = append(s.samples[ch], samples[ch]...)
+	}
+}
+
+// TestDecodeWAV_Formats 测试 24 位整数和 32 位浮点 WAV 的解码，以及跳过 data 之前的其他块。
+func TestDecodeWAV_Formats(t *testing.T) {
+	build := func(format, bitDepth int, samples []byte) []byte {
+		var buf bytes.Buffer
+		buf.WriteString("RIFF\x00\x00\x00\x00WAVE")
+		buf.WriteString("LIST")
+		binary.Write(&buf, binary.LittleEndian, uint32(3))
+		buf.WriteString("abc\x00") // 奇数大小的块带一个填充字节
+		buf.WriteString("fmt ")
+		blockAlign := bitDepth / 8
+		writeLE(&buf, uint32(16), uint16(format), uint16(1), uint32(8000),
+			uint32(8000*blockAlign), uint16(blockAlign), uint16(bitDepth))
+		buf.WriteString("data")
+		binary.Write(&buf, binary.LittleEndian, uint32(len(samples)))
+		buf.Write(samples)
+		return buf.Bytes()
+	}
+
+	int24 := build(wavFormatPCM, 24, []byte{0x00, 0x00, 0x40, 0x00, 0x00, 0xc0})
+	float32Data := make([]byte, 8)
+	binary.LittleEndian.PutUint32(float32Data, math.Float32bits(0.25))
+	binary.LittleEndian.PutUint32(float32Data[4:], math.Float32bits(-1))
+	float32WAV := build(wavFormatIEEEFloat, 32, float32Data)
+
+	for name, tt := range map[string]struct {
+		data []byte
+		want []float64
+	}{
+		"24 位整数": {int24, []float64{0.5, -0.5}},
+		"32 位浮点": {float32WAV, []float64{0.25, -1}},
+	} {
+		sink := &collectSink{}
+		if err := decodeWAV(bytes.NewReader(tt.data), sink); err != nil {
+			t.Fatalf("%s: 解码失败: %v", name, err)
+		}
+		if sink.sampleRate != 8000 || len(sink.samples) != 1 {
+			t.Fatalf("%s: 期望 8000Hz 单声道, 得到 %dHz %d 声道", name, sink.sampleRate, len(sink.samples))
+		}
+		if len(sink.samples[0]) != len(tt.want) || sink.samples[0][0] != tt.want[0] || sink.samples[0][1] != tt.want[1] {
+			t.Errorf("%s: 期望采样 %v, 得到 %v", name, tt.want, sink.samples[0])
+		}
+	}
+
+	if err := decodeWAV(bytes.NewReader(build(wavFormatPCM, 12, nil)), &collectSink{}); err == nil {
+		t.Error("期望不支持的采样位数返回错误")
+	}
+}
+
+// TestLoudnessGroups 测试按专辑分组以及何时计算专辑增益。
+func TestLoudnessGroups(t *testing.T) {
+	tagged := &models.ReplayGain{Source: models.LoudnessSourceReplayGain}
+	analyzed := &models.ReplayGain{Source: models.LoudnessSourceAnalysis}
+	songs := []*models.Song{
+		{ID: "a1", FilePath: "/music/a/1.flac", Album: "A"},
+		{ID: "a2", FilePath: "/music/a/2.wav", Album: "A"},
+		{ID: "b1", FilePath: "/music/b/1.flac", Album: "B"},
+		{ID: "b2", FilePath: "/music/b/2.flac", Album: "B", ReplayGain: tagged},
+		{ID: "c1", FilePath: "/music/c/1.flac", Album: "C", ReplayGain: analyzed},
+		{ID: "d1", FilePath: "/music/d/1.mp3", Album: "D"},
+		{ID: "s1", FilePath: "/music/single.flac", Album: "Unknown"},
+	}
+
+	ids := func(groups []*loudnessGroup) map[string]bool {
+		result := make(map[string]bool)
+		for _, group := range groups {
+			for _, song := range group.songs {
+				result[song.ID] = group.album
+			}
+		}
+		return result
+	}
+
+	got := ids(loudnessGroups(songs, false))
+	want := map[string]bool{"a1": true, "a2": true, "b1": false, "s1": false}
+	if len(got) != len(want) {
+		t.Fatalf("期望分析 %v, 得到 %v", want, got)
+	}
+	for id, album := range want {
+		if gotAlbum, ok := got[id]; !ok || gotAlbum != album {
+			t.Errorf("歌曲 %s: 期望分析且专辑增益为 %v, 得到 %v (%v)", id, album, gotAlbum, ok)
+		}
+	}
+
+	// force 时重新分析服务器分析过的歌曲，但不覆盖标签中的信息
+	got = ids(loudnessGroups(songs, true))
+	if album, ok := got["c1"]; !ok || !album {
+		t.Errorf("期望 force 时重新分析 c1 并计算专辑增益, 得到 %v", got)
+	}
+	if _, ok := got["b2"]; ok {
+		t.Error("不应重新分析带有 ReplayGain 标签的歌曲")
+	}
+}
+
+// TestLoudnessJobManager 测试后台分析为 WAV 歌曲写入增益，并在全量扫描后保留结果。
+func TestLoudnessJobManager(t *testing.T) {
+	tmpDir := t.TempDir()
+	for name, level := range map[string]float64{"loud.wav": -20, "quiet.wav": -30} {
+		data := encodeTestWAV(48000, 2, 48000*2, sineWave(48000, 997, level))
+		if err := os.WriteFile(filepath.Join(tmpDir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 无法解码的文件应被跳过而不是计为失败
+	if err := os.WriteFile(filepath.Join(tmpDir, "other.mp3"), []byte("fake mp3"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := newMemorySongRepository()
+	scanner := NewLibraryScanner([]Library{{Name: "default", Directory: tmpDir}}, []string{".wav", ".mp3"}, 5, repo)
+	if _, err := scanner.Scan(context.Background()); err != nil {
+		t.Fatalf("扫描失败: %v", err)
+	}
+
+	manager := NewLoudnessJobManager(scanner, 2)
+	if status := manager.Status(); status.State != ScanStateIdle {
+		t.Fatalf("期望初始状态为 idle, 得到 %s", status.State)
+	}
+	manager.Start(false)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	status, err := manager.Wait(ctx)
+	if err != nil {
+		t.Fatalf("等待响度分析结束失败: %v", err)
+	}
+	if status.State != ScanStateCompleted || status.Total != 2 || status.Analyzed != 2 || status.Failed != 0 {
+		t.Fatalf("期望 2 首歌曲分析完成, 得到 %+v", status)
+	}
+
+	wantGain := map[string]float64{"loud.wav": 2, "quiet.wav": 12}
+	check := func(when string) {
+		t.Helper()
+		for _, song := range scanner.GetSongs() {
+			want, ok := wantGain[filepath.Base(song.FilePath)]
+			if !ok {
+				if song.ReplayGain != nil {
+					t.Errorf("%s: 不应为 %s 写入音量信息", when, song.FilePath)
+				}
+				continue
+			}
+			rg := song.ReplayGain
+			if rg == nil || rg.Source != models.LoudnessSourceAnalysis || rg.TrackGain == nil || rg.TrackPeak == nil || rg.Loudness == nil {
+				t.Fatalf("%s: %s 缺少分析结果: %+v", when, song.FilePath, rg)
+			}
+			if math.Abs(*rg.TrackGain-want) > 0.1 {
+				t.Errorf("%s: %s 期望增益 %.2f dB, 得到 %.2f", when, song.FilePath, want, *rg.TrackGain)
+			}
+			if rg.AlbumGain != nil {
+				t.Errorf("%s: 没有专辑信息的歌曲不应有专辑增益", when)
+			}
+			if stored := repo.songs[song.ID]; stored.ReplayGain == nil {
+				t.Errorf("%s: 分析结果没有写入存储", when)
+			}
+		}
+	}
+	check("分析后")
+
+	// 全量扫描重新解析标签，文件未变化时保留分析结果
+	if _, err := scanner.Rescan(context.Background(), ScanModeFull); err != nil {
+		t.Fatalf("全量扫描失败: %v", err)
+	}
+	check("全量扫描后")
+
+	// 已分析过的歌曲不再重复分析
+	manager.Start(false)
+	status, err = manager.Wait(ctx)
+	if err != nil {
+		t.Fatalf("等待响度分析结束失败: %v", err)
+	}
+	if status.Total != 0 {
+		t.Errorf("期望没有需要分析的歌曲, 得到 %d", status.Total)
+	}
+
+	if _, ok := manager.Cancel(); ok {
+		t.Error("没有正在运行的分析时取消应返回 false")
+	}
+}
+
+// TestLoudnessGroup_AlbumGain 测试专辑增益按所有曲目的测量块合并计算。
+func TestLoudnessGroup_AlbumGain(t *testing.T) {
+	tmpDir := t.TempDir()
+	var songs []*models.Song
+	for i, level := range []float64{-20, -30} {
+		path := filepath.Join(tmpDir, fmt.Sprintf("%02d.wav", i+1))
+		data := encodeTestWAV(48000, 2, 48000*2, sineWave(48000, 997, level))
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		songs = append(songs, &models.Song{ID: fmt.Sprintf("%d", i), FilePath: path, Album: "Album"})
+	}
+
+	group := &loudnessGroup{songs: songs, album: true}
+	analyzed := group.analyze(context.Background(), &loudnessJob{})
+	if len(analyzed) != 2 {
+		t.Fatalf("期望 2 首歌曲的结果, 得到 %d", len(analyzed))
+	}
+	// 两首歌曲时长相同，合并后的响度为 10*log10((1+0.1)/2) - 20 ≈ -22.60 LUFS
+	for _, song := range analyzed {
+		rg := song.ReplayGain
+		if rg.AlbumGain == nil || math.Abs(*rg.AlbumGain-4.6) > 0.1 {
+			t.Errorf("期望专辑增益约为 4.60 dB, 得到 %v", rg.AlbumGain)
+		}
+		if rg.AlbumPeak == nil || math.Abs(*rg.AlbumPeak-0.1) > 0.001 {
+			t.Errorf("期望专辑峰值约为 0.1, 得到 %v", rg.AlbumPeak)
+		}
+	}
+	if songs[0].ReplayGain != nil {
+		t.Error("分析结果应写入副本而不是原有记录")
+	}
+
+	// 有曲目无法分析时不计算专辑增益
+	if err := os.WriteFile(songs[1].FilePath, []byte("not a wav"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	analyzed = group.analyze(context.Background(), &loudnessJob{})
+	if len(analyzed) != 1 || analyzed[0].ReplayGain.AlbumGain != nil {
+		t.Errorf("期望只有一首歌曲的单曲结果, 得到 %+v", analyzed)
+	}
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"zero-music/logger"
+	"zero-music/models"
+
+	"github.com/dhowden/tag"
+)
+
+// ErrNoLyrics 表示歌曲没有可用的歌词。
+var ErrNoLyrics = errors.New("没有歌词")
+
+// LoadLyrics 读取歌曲的歌词。同名的 .lrc 文件优先，无法读取时回退到音频文件中嵌入的歌词。
+func LoadLyrics(song *models.Song) (*models.Lyrics, error) {
+	if song.LyricsPath != "" {
+		lyrics, err := readLRCFile(song.LyricsPath)
+		if err == nil {
+			return lyrics, nil
+		}
+		if !song.EmbeddedLyrics {
+			return nil, err
+		}
+		logger.Warnf("读取歌词文件失败，使用嵌入的歌词: %v", err)
+	}
+	if song.EmbeddedLyrics {
+		return readEmbeddedLyrics(song.FilePath)
+	}
+	return nil, ErrNoLyrics
+}
+
+// readLRCFile 读取并解析 .lrc 歌词文件，文件不存在时返回 ErrNoLyrics。
+func readLRCFile(path string) (*models.Lyrics, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoLyrics
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取歌词文件失败: %w", err)
+	}
+	lyrics := models.ParseLRC(models.DecodeLyricsFile(data))
+	lyrics.Source = models.LyricsSourceLRC
+	return lyrics, nil
+}
+
+// readEmbeddedLyrics 读取音频文件标签中嵌入的歌词。
+func readEmbeddedLyrics(filePath string) (*models.Lyrics, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("打开文件失败: %w", err)
+	}
+	defer file.Close()
+
+	metadata, err := tag.ReadFrom(file)
+	if errors.Is(err, tag.ErrNoTagsFound) {
+		return nil, ErrNoLyrics
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取标签失败: %w", err)
+	}
+
+	lyrics := models.EmbeddedLyrics(metadata)
+	if lyrics == nil {
+		return nil, ErrNoLyrics
+	}
+	return lyrics, nil
+}
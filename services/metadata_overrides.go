@@ -0,0 +1,132 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+	"zero-music/models"
+	"zero-music/repository"
+)
+
+// ErrNoMetadataOverride 表示歌曲没有元数据覆盖。
+var ErrNoMetadataOverride = errors.New("歌曲没有元数据覆盖")
+
+// MetadataOverrideService 管理管理员保存在数据库中的元数据修正。覆盖不写入音频文件，
+// 由扫描器在返回歌曲时应用在从文件读取的元数据之上，适用于只读挂载的音乐库。
+type MetadataOverrideService struct {
+	library *MusicScanner
+	repo    repository.MetadataOverrideRepository
+	mu      sync.Mutex // 串行化读取-合并-保存，避免并发修改丢失字段
+}
+
+// NewMetadataOverrideService 创建一个新的 MetadataOverrideService 实例。
+func NewMetadataOverrideService(library *MusicScanner, repo repository.MetadataOverrideRepository) *MetadataOverrideService {
+	return &MetadataOverrideService{library: library, repo: repo}
+}
+
+// Load 从存储加载所有元数据覆盖并交给扫描器。
+func (s *MetadataOverrideService) Load() error {
+	overrides, err := s.repo.LoadAll()
+	if err != nil {
+		return fmt.Errorf("加载元数据覆盖失败: %w", err)
+	}
+	s.library.SetMetadataOverrides(overrides)
+	return nil
+}
+
+// List 返回所有元数据覆盖，按歌曲 ID 排序。
+func (s *MetadataOverrideService) List() []*models.MetadataOverride {
+	return s.library.MetadataOverrides()
+}
+
+// Get 返回歌曲的元数据覆盖。
+func (s *MetadataOverrideService) Get(songID string) (*models.MetadataOverride, error) {
+	song := s.library.GetSongByID(songID)
+	if song == nil {
+		return nil, ErrSongNotFound
+	}
+	override := s.library.MetadataOverride(song.ID)
+	if override == nil {
+		return nil, ErrNoMetadataOverride
+	}
+	return override, nil
+}
+
+// Update 将修改合并到歌曲的元数据覆盖中并保存，返回应用覆盖后的歌曲。
+// 所有字段都取消覆盖后删除该歌曲的覆盖记录。
+func (s *MetadataOverrideService) Update(songID string, edit *models.MetadataOverrideEdit) (*models.Song, error) {
+	if err := edit.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	song := s.library.GetSongByID(songID)
+	if song == nil {
+		return nil, ErrSongNotFound
+	}
+	override := s.library.MetadataOverride(song.ID)
+	if override == nil {
+		override = &models.MetadataOverride{SongID: song.ID}
+	}
+
+	var cover []byte
+	var coverHash string
+	if edit.Cover != nil {
+		cover = *edit.Cover
+		sum := sha256.Sum256(cover)
+		coverHash = hex.EncodeToString(sum[:16])
+	}
+	edit.ApplyTo(override, coverHash)
+	override.UpdatedAt = time.Now()
+
+	if override.IsEmpty() {
+		if _, err := s.repo.Delete(song.ID); err != nil {
+			return nil, fmt.Errorf("删除元数据覆盖失败: %w", err)
+		}
+		s.library.SetMetadataOverride(song.ID, nil)
+	} else {
+		if err := s.repo.Save(override, cover); err != nil {
+			return nil, fmt.Errorf("保存元数据覆盖失败: %w", err)
+		}
+		s.library.SetMetadataOverride(song.ID, override)
+	}
+	return s.library.GetSongByID(song.ID), nil
+}
+
+// Delete 删除歌曲的全部元数据覆盖，返回恢复为文件中元数据的歌曲。
+func (s *MetadataOverrideService) Delete(songID string) (*models.Song, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	song := s.library.GetSongByID(songID)
+	if song == nil {
+		return nil, ErrSongNotFound
+	}
+	deleted, err := s.repo.Delete(song.ID)
+	if err != nil {
+		return nil, fmt.Errorf("删除元数据覆盖失败: %w", err)
+	}
+	if !deleted {
+		return nil, ErrNoMetadataOverride
+	}
+	s.library.SetMetadataOverride(song.ID, nil)
+	return s.library.GetSongByID(song.ID), nil
+}
+
+// Cover 读取元数据覆盖中的封面图片，没有时返回 ErrNoCover。
+func (s *MetadataOverrideService) Cover(songID string) (*Cover, error) {
+	data, err := s.repo.GetCover(songID)
+	if err != nil {
+		return nil, fmt.Errorf("读取覆盖封面失败: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, ErrNoCover
+	}
+	return &Cover{Data: data, MIMEType: http.DetectContentType(data)}, nil
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"zero-music/models"
+)
+
+// memoryMetadataOverrideRepository 是保存在内存中的 MetadataOverrideRepository 实现。
+type memoryMetadataOverrideRepository struct {
+	overrides map[string]models.MetadataOverride
+	covers    map[string][]byte
+}
+
+func newMemoryMetadataOverrideRepository() *memoryMetadataOverrideRepository {
+	return &memoryMetadataOverrideRepository{overrides: make(map[string]models.MetadataOverride), covers: make(map[string][]byte)}
+}
+
+func (r *memoryMetadataOverrideRepository) LoadAll() ([]*models.MetadataOverride, error) {
+	var overrides []*models.MetadataOverride
+	for _, override := range r.overrides {
+		copied := override
+		overrides = append(overrides, &copied)
+	}
+	return overrides, nil
+}
+
+func (r *memoryMetadataOverrideRepository) Save(override *models.MetadataOverride, cover []byte) error {
+	r.overrides[override.SongID] = *override
+	if override.CoverMIME == "" {
+		delete(r.covers, override.SongID)
+	} else if cover != nil {
+		r.covers[override.SongID] = cover
+	}
+	return nil
+}
+
+func (r *memoryMetadataOverrideRepository) Delete(songID string) (bool, error) {
+	_, ok := r.overrides[songID]
+	delete(r.overrides, songID)
+	delete(r.covers, songID)
+	return ok, nil
+}
+
+func (r *memoryMetadataOverrideRepository) GetCover(songID string) ([]byte, error) {
+	return r.covers[songID], nil
+}
+
+// TestMetadataOverrideService 测试覆盖应用在返回的歌曲上，持久化的歌曲记录保持文件中的值，删除覆盖后恢复。
+func TestMetadataOverrideService(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeID3Song(t, filepath.Join(tmpDir, "a.mp3"), map[string]string{"TIT2": "Old Title", "TPE1": "Artist", "TALB": "Album"})
+
+	songRepo := newMemorySongRepository()
+	scanner := NewMusicScannerWithRepository(tmpDir, []string{".mp3"}, 5, songRepo)
+	scanner.SetArtistSeparators([]string{"&"})
+	songs, err := scanner.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("扫描失败: %v", err)
+	}
+	id := songs[0].ID
+
+	repo := newMemoryMetadataOverrideRepository()
+	service := NewMetadataOverrideService(scanner, repo)
+	if _, err := service.Get(id); !errors.Is(err, ErrNoMetadataOverride) {
+		t.Errorf("期望 ErrNoMetadataOverride, 得到 %v", err)
+	}
+
+	title, artist := "Fixed Title", "A & B"
+	cover := encodeTestImage(t, "png", 4, 4)
+	song, err := service.Update(id, &models.MetadataOverrideEdit{Title: &title, Artist: &artist, Cover: &cover})
+	if err != nil {
+		t.Fatalf("保存元数据覆盖失败: %v", err)
+	}
+	if song.Title != "Fixed Title" || song.Album != "Album" || !slices.Equal(song.Artists, []string{"A", "B"}) {
+		t.Errorf("期望返回应用覆盖后的歌曲, 得到 %+v", song)
+	}
+	if !slices.Equal(song.Overridden, []string{models.OverrideFieldTitle, models.OverrideFieldArtist, models.OverrideFieldCover}) {
+		t.Errorf("期望标记被覆盖的字段, 得到 %v", song.Overridden)
+	}
+	if listed := scanner.GetSongs()[0]; listed.Title != "Fixed Title" || listed.CoverSource != models.CoverSourceOverride {
+		t.Errorf("期望歌曲列表应用覆盖, 得到 %+v", listed)
+	}
+	if stored := songRepo.songs[id]; stored.Title != "Old Title" || len(stored.Overridden) != 0 {
+		t.Errorf("期望持久化的歌曲保持文件中的值, 得到 %+v", stored)
+	}
+
+	// 覆盖的封面通过封面服务读取
+	covers := NewCoverService(t.TempDir(), 1024)
+	covers.SetOverrideCovers(service.Cover)
+	got, err := covers.Get(song, 0)
+	if err != nil || !bytes.Equal(got.Data, cover) || got.MIMEType != "image/png" {
+		t.Errorf("期望读取覆盖的封面, 得到 %v", err)
+	}
+
+	// 重新加载后覆盖仍然生效
+	reloaded := NewMusicScannerWithRepository(tmpDir, []string{".mp3"}, 5, songRepo)
+	if err := reloaded.Restore(); err != nil {
+		t.Fatalf("恢复歌曲库失败: %v", err)
+	}
+	if err := NewMetadataOverrideService(reloaded, repo).Load(); err != nil {
+		t.Fatalf("加载元数据覆盖失败: %v", err)
+	}
+	if song := reloaded.GetSongByID(id); song == nil || song.Title != "Fixed Title" {
+		t.Errorf("期望重新加载后覆盖生效, 得到 %+v", song)
+	}
+
+	// 取消部分字段的覆盖
+	song, err = service.Update(id, &models.MetadataOverrideEdit{Clear: []string{models.OverrideFieldArtist, models.OverrideFieldCover}})
+	if err != nil {
+		t.Fatalf("修改元数据覆盖失败: %v", err)
+	}
+	if song.Artist != "Artist" || song.CoverSource == models.CoverSourceOverride || !slices.Equal(song.Overridden, []string{models.OverrideFieldTitle}) {
+		t.Errorf("期望艺术家和封面恢复, 得到 %+v", song)
+	}
+	if _, ok := repo.covers[id]; ok {
+		t.Error("期望删除覆盖的封面")
+	}
+
+	song, err = service.Delete(id)
+	if err != nil {
+		t.Fatalf("删除元数据覆盖失败: %v", err)
+	}
+	if song.Title != "Old Title" || len(song.Overridden) != 0 {
+		t.Errorf("期望恢复为文件中的元数据, 得到 %+v", song)
+	}
+	if _, err := service.Delete(id); !errors.Is(err, ErrNoMetadataOverride) {
+		t.Errorf("期望 ErrNoMetadataOverride, 得到 %v", err)
+	}
+	if _, err := service.Update("0123456789abcdef0123456789abcdef", &models.MetadataOverrideEdit{Title: &title}); !errors.Is(err, ErrSongNotFound) {
+		t.Errorf("期望 ErrSongNotFound, 得到 %v", err)
+	}
+}
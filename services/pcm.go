@@ -0,0 +1,169 @@
+package services
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// pcmSink 接收解码后的音频采样，采样值归一化到 [-1, 1)。
+type pcmSink interface {
+	// begin 在第一批采样之前调用一次，告知采样率和声道数。
+	begin(sampleRate, channels int) error
+	// write 接收一批采样，每个声道一组，各组长度相同。
+	write(samples [][]float64)
+}
+
+// pcmDecoder 将音频流完整解码并依次交给 sink。
+type pcmDecoder func(r io.Reader, sink pcmSink) error
+
+// pcmDecoders 按扩展名选择解码器，只支持可以在服务器上直接解码的无损格式。
+var pcmDecoders = map[string]pcmDecoder{
+	".wav":  decodeWAV,
+	".flac": decodeFLAC,
+}
+
+// errUnsupportedPCM 表示文件格式不支持解码。
+var errUnsupportedPCM = errors.New("不支持解码该音频格式")
+
+// canDecodePCM 判断文件是否可以解码为 PCM 采样。
+func canDecodePCM(path string) bool {
+	_, ok := pcmDecoders[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+// decodePCMFile 解码音频文件并将采样交给 sink。
+func decodePCMFile(path string, sink pcmSink) error {
+	decode, ok := pcmDecoders[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return errUnsupportedPCM
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("打开文件失败: %w", err)
+	}
+	defer file.Close()
+	return decode(bufio.NewReaderSize(file, 64*1024), sink)
+}
+
+// pcmBlockFrames 是每批交给 sink 的采样帧数。
+const pcmBlockFrames = 4096
+
+// WAV fmt 块中的格式代码，与 models 包中读取文件头时使用的一致。
+const (
+	wavFormatPCM        = 0x0001
+	wavFormatIEEEFloat  = 0x0003
+	wavFormatExtensible = 0xfffe
+)
+
+// decodeWAV 解码 WAV 文件中的整数 PCM（8/16/24/32 位）或浮点（32/64 位）采样。
+func decodeWAV(r io.Reader, sink pcmSink) error {
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return fmt.Errorf("读取 WAV 文件头失败: %w", err)
+	}
+	if string(header[:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return fmt.Errorf("不是 WAV 文件")
+	}
+
+	var format, channels, bitDepth int
+	var sampleRate int
+	chunk := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			return fmt.Errorf("WAV 文件缺少 data 块: %w", err)
+		}
+		size := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+		switch string(chunk[:4]) {
+		case "fmt ":
+			if size < 16 || size > 1024 {
+				return fmt.Errorf("WAV fmt 块大小无效: %d", size)
+			}
+			body := make([]byte, size+size%2)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return fmt.Errorf("读取 WAV fmt 块失败: %w", err)
+			}
+			format = int(binary.LittleEndian.Uint16(body[0:2]))
+			if format == wavFormatExtensible && size >= 26 {
+				format = int(binary.LittleEndian.Uint16(body[24:26]))
+			}
+			channels = int(binary.LittleEndian.Uint16(body[2:4]))
+			sampleRate = int(binary.LittleEndian.Uint32(body[4:8]))
+			bitDepth = int(binary.LittleEndian.Uint16(body[14:16]))
+			continue
+		case "data":
+			if channels == 0 {
+				return fmt.Errorf("WAV 文件缺少 fmt 块")
+			}
+			return decodeWAVData(r, size, format, channels, sampleRate, bitDepth, sink)
+		}
+		// 跳过其他块，块按 2 字节对齐
+		if _, err := io.CopyN(io.Discard, r, size+size%2); err != nil {
+			return fmt.Errorf("WAV 文件缺少 data 块: %w", err)
+		}
+	}
+}
+
+// decodeWAVData 解码 data 块中交错存放的采样。流式录制的文件可能没有回填 data 块大小，此时读到文件末尾。
+func decodeWAVData(r io.Reader, size int64, format, channels, sampleRate, bitDepth int, sink pcmSink) error {
+	var convert func(b []byte) float64
+	switch {
+	case format == wavFormatPCM && bitDepth == 8:
+		convert = func(b []byte) float64 { return (float64(b[0]) - 128) / 128 }
+	case format == wavFormatPCM && bitDepth == 16:
+		convert = func(b []byte) float64 { return float64(int16(binary.LittleEndian.Uint16(b))) / (1 << 15) }
+	case format == wavFormatPCM && bitDepth == 24:
+		convert = func(b []byte) float64 {
+			return float64(int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24)>>8) / (1 << 23)
+		}
+	case format == wavFormatPCM && bitDepth == 32:
+		convert = func(b []byte) float64 { return float64(int32(binary.LittleEndian.Uint32(b))) / (1 << 31) }
+	case format == wavFormatIEEEFloat && bitDepth == 32:
+		convert = func(b []byte) float64 { return float64(math.Float32frombits(binary.LittleEndian.Uint32(b))) }
+	case format == wavFormatIEEEFloat && bitDepth == 64:
+		convert = func(b []byte) float64 { return math.Float64frombits(binary.LittleEndian.Uint64(b)) }
+	default:
+		return fmt.Errorf("不支持的 WAV 采样格式: 格式 %#x, %d 位", format, bitDepth)
+	}
+	if err := sink.begin(sampleRate, channels); err != nil {
+		return err
+	}
+
+	if size == 0 || size == math.MaxUint32 {
+		size = math.MaxInt64
+	}
+	r = io.LimitReader(r, size)
+
+	sampleBytes := bitDepth / 8
+	frameBytes := sampleBytes * channels
+	buf := make([]byte, pcmBlockFrames*frameBytes)
+	samples := make([][]float64, channels)
+	for {
+		n, err := io.ReadFull(r, buf)
+		frames := n / frameBytes
+		if frames > 0 {
+			for ch := range samples {
+				samples[ch] = samples[ch][:0]
+			}
+			for i := 0; i < frames; i++ {
+				frame := buf[i*frameBytes:]
+				for ch := range samples {
+					samples[ch] = append(samples[ch], convert(frame[ch*sampleBytes:]))
+				}
+			}
+			sink.write(samples)
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("读取 WAV 采样失败: %w", err)
+		}
+	}
+}
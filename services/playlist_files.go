@@ -0,0 +1,118 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"zero-music/models"
+)
+
+// unresolvedNotInLibrary 是条目指向的文件不在歌曲库中时记录的原因。
+const unresolvedNotInLibrary = "文件不在歌曲库中"
+
+// playlistFile 是音乐目录中的一个播放列表文件及其解析结果。
+type playlistFile struct {
+	library string
+	path    string
+	relPath string
+	size    int64
+	modTime time.Time
+	parsed  *models.PlaylistFile
+	err     error // 读取或解析失败的原因，失败时 parsed 为 nil
+}
+
+// loadPlaylistFile 读取并解析播放列表文件。文件大小和修改时间都未变化时直接复用 previous。
+func loadPlaylistFile(lib Library, path string, info os.FileInfo, previous *playlistFile) *playlistFile {
+	if previous != nil && previous.size == info.Size() && previous.modTime.Equal(info.ModTime()) {
+		return previous
+	}
+	file := &playlistFile{
+		library: lib.Name,
+		path:    path,
+		relPath: models.LibraryRelPath(lib.Directory, path),
+		size:    info.Size(),
+		modTime: info.ModTime(),
+	}
+	data, err := os.ReadFile(path)
+	if err == nil {
+		file.parsed, err = models.ParsePlaylistFile(path, data)
+	}
+	file.err = err
+	return file
+}
+
+// issue 返回播放列表文件无法读取或解析时对应的问题，没有问题时返回 nil。
+func (f *playlistFile) issue() *models.LibraryIssue {
+	if f.err == nil {
+		return nil
+	}
+	issue := models.FileIssue(f.err)
+	return &models.LibraryIssue{
+		Kind:     issue.Kind,
+		Message:  issue.Message,
+		Library:  f.library,
+		FilePath: f.path,
+		RelPath:  f.relPath,
+	}
+}
+
+// resolvePlaylists 将播放列表文件中的条目对应到歌曲库中的歌曲，结果按音乐库和相对路径排序。
+// 路径完全相同的文件优先，其次忽略大小写匹配（在 Windows 上整理的播放列表中很常见）；
+// 拆分为 CUE 曲目的整轨文件对应其中的所有曲目。无法解析的条目记录在 Unresolved 中。
+func resolvePlaylists(files map[string]*playlistFile, songs []*models.Song) []*models.SharedPlaylist {
+	byPath := make(map[string][]string, len(songs))
+	byFoldedPath := make(map[string][]string, len(songs))
+	for _, song := range songs {
+		byPath[song.FilePath] = append(byPath[song.FilePath], song.ID)
+		folded := strings.ToLower(song.FilePath)
+		byFoldedPath[folded] = append(byFoldedPath[folded], song.ID)
+	}
+
+	playlists := make([]*models.SharedPlaylist, 0, len(files))
+	for _, file := range files {
+		if file.parsed == nil {
+			continue
+		}
+		playlist := &models.SharedPlaylist{
+			ID:        models.LibrarySongID(file.library, file.relPath),
+			Name:      file.parsed.Name,
+			Library:   file.library,
+			RelPath:   file.relPath,
+			FilePath:  file.path,
+			Format:    file.parsed.Format,
+			UpdatedAt: file.modTime,
+		}
+		dir := filepath.Dir(file.path)
+		for _, entry := range file.parsed.Entries {
+			path, err := models.ResolvePlaylistLocation(dir, entry.Location)
+			if err != nil {
+				entry.Reason = err.Error()
+				playlist.Unresolved = append(playlist.Unresolved, entry)
+				continue
+			}
+			ids, ok := byPath[path]
+			if !ok {
+				ids, ok = byFoldedPath[strings.ToLower(path)]
+			}
+			if !ok {
+				entry.Reason = unresolvedNotInLibrary
+				playlist.Unresolved = append(playlist.Unresolved, entry)
+				continue
+			}
+			playlist.SongIDs = append(playlist.SongIDs, ids...)
+		}
+		playlist.SongCount = len(playlist.SongIDs)
+		playlist.UnresolvedCount = len(playlist.Unresolved)
+		playlists = append(playlists, playlist)
+	}
+
+	sort.Slice(playlists, func(i, j int) bool {
+		if playlists[i].Library != playlists[j].Library {
+			return playlists[i].Library < playlists[j].Library
+		}
+		return playlists[i].RelPath < playlists[j].RelPath
+	})
+	return playlists
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"zero-music/models"
+)
+
+// TestMusicScanner_PlaylistFiles 测试扫描音乐目录中的播放列表文件、解析条目，以及文件变化后的更新。
+func TestMusicScanner_PlaylistFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.mp3", "Sub/B.mp3"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, 4096), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	listPath := filepath.Join(dir, "Lists", "mix.m3u8")
+	if err := os.MkdirAll(filepath.Dir(listPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	list := "#EXTM3U\n#PLAYLIST:Mix\n../a.mp3\n..\\sub\\b.mp3\nhttp://radio.example.com/live\n../missing.mp3\n"
+	if err := os.WriteFile(listPath, []byte(list), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	scanner := NewMusicScanner(dir, []string{".mp3"}, 5)
+	if _, err := scanner.Scan(context.Background()); err != nil {
+		t.Fatalf("扫描失败: %v", err)
+	}
+	if scanner.GetSongCount() != 2 {
+		t.Fatalf("播放列表文件不应出现在歌曲列表中, 得到 %d 首歌曲", scanner.GetSongCount())
+	}
+
+	playlists := scanner.Playlists()
+	if len(playlists) != 1 {
+		t.Fatalf("期望 1 个播放列表, 得到 %d 个", len(playlists))
+	}
+	playlist := playlists[0]
+	wantIDs := []string{models.LibrarySongID(models.DefaultLibraryName, "a.mp3"), models.LibrarySongID(models.DefaultLibraryName, "Sub/B.mp3")}
+	if playlist.Name != "Mix" || playlist.RelPath != "Lists/mix.m3u8" || playlist.Format != models.PlaylistFormatM3U8 {
+		t.Errorf("播放列表信息错误: %+v", playlist)
+	}
+	if len(playlist.SongIDs) != 2 || playlist.SongIDs[0] != wantIDs[0] || playlist.SongIDs[1] != wantIDs[1] {
+		t.Errorf("期望解析出 a.mp3 和忽略大小写匹配的 Sub/B.mp3, 得到 %v", playlist.SongIDs)
+	}
+	if playlist.UnresolvedCount != 2 || playlist.Unresolved[0].Position != 3 || playlist.Unresolved[1].Reason != unresolvedNotInLibrary {
+		t.Errorf("期望 2 个无法解析的条目, 得到 %+v", playlist.Unresolved)
+	}
+	if got := scanner.GetPlaylistByID(playlist.ID); got == nil || got.SongCount != 2 {
+		t.Errorf("按 ID 查找播放列表失败: %+v", got)
+	}
+
+	unresolved := 0
+	for _, issue := range scanner.Issues() {
+		if issue.Kind == models.IssueUnresolvedEntry && issue.FilePath == listPath {
+			unresolved++
+		}
+	}
+	if unresolved != 2 {
+		t.Errorf("期望健康报告中有 2 个无法解析的条目, 得到 %d 个", unresolved)
+	}
+
+	// 补上缺失的歌曲后，条目随之解析
+	missing := filepath.Join(dir, "missing.mp3")
+	if err := os.WriteFile(missing, make([]byte, 4096), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := scanner.ApplyChanges(context.Background(), []string{missing}); err != nil {
+		t.Fatalf("应用变化失败: %v", err)
+	}
+	if playlist = scanner.GetPlaylistByID(playlist.ID); playlist.SongCount != 3 || playlist.UnresolvedCount != 1 {
+		t.Errorf("期望新增歌曲后解析出 3 首歌曲, 得到 %+v", playlist)
+	}
+
+	if err := os.RemoveAll(filepath.Dir(listPath)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := scanner.ApplyChanges(context.Background(), []string{filepath.Dir(listPath)}); err != nil {
+		t.Fatalf("应用变化失败: %v", err)
+	}
+	if playlists := scanner.Playlists(); len(playlists) != 0 {
+		t.Errorf("期望播放列表随目录删除, 得到 %d 个", len(playlists))
+	}
+}
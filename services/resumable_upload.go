@@ -0,0 +1,358 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"zero-music/logger"
+	"zero-music/models"
+	"zero-music/repository"
+)
+
+var (
+	// ErrUploadSessionNotFound 表示上传会话不存在、已过期或属于其他用户。
+	ErrUploadSessionNotFound = errors.New("上传会话不存在或已过期")
+	// ErrUploadOffsetMismatch 表示请求的偏移量与已接收的字节数不一致。
+	ErrUploadOffsetMismatch = errors.New("上传偏移量与已接收的字节数不一致")
+	// ErrUploadSessionBusy 表示上传会话正在被另一个请求写入。
+	ErrUploadSessionBusy = errors.New("上传会话正在接收数据")
+)
+
+// uploadSessionIDBytes 是上传会话 ID 的随机字节数。
+const uploadSessionIDBytes = 16
+
+// ResumableUploader 实现可断点续传的上传（tus 协议）。客户端先创建上传会话并声明文件大小，
+// 再分多次发送数据；连接中断后可以查询已接收的字节数并从该位置继续。已接收的数据暂存在磁盘上，
+// 全部接收后交给 Uploader 放入音乐库。长时间没有新数据的会话过期后被清理。
+type ResumableUploader struct {
+	uploader  *Uploader
+	repo      repository.UploadSessionRepository
+	directory string
+	expiry    time.Duration
+
+	mu     sync.Mutex
+	active map[string]bool // 正在接收数据或正在删除的会话，同一会话同时只允许一个请求写入
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewResumableUploader 创建一个新的 ResumableUploader 实例，数据暂存在 directory 中，
+// 会话在最后一次接收数据 expiry 之后过期。
+func NewResumableUploader(uploader *Uploader, repo repository.UploadSessionRepository, directory string, expiry time.Duration) (*ResumableUploader, error) {
+	if err := os.MkdirAll(directory, 0o755); err != nil {
+		return nil, fmt.Errorf("创建上传暂存目录失败: %w", err)
+	}
+	if expiry <= 0 {
+		expiry = 24 * time.Hour
+	}
+	return &ResumableUploader{
+		uploader:  uploader,
+		repo:      repo,
+		directory: directory,
+		expiry:    expiry,
+		active:    make(map[string]bool),
+	}, nil
+}
+
+// MaxSize 返回单个文件的大小上限（字节），0 表示不限制。
+func (r *ResumableUploader) MaxSize() int64 {
+	return r.uploader.maxFileSize
+}
+
+// Create 为用户创建一个上传会话。文件名决定文件按音频还是 zip 压缩包处理；
+// 文件大小在创建时就与大小上限和用户配额比较，避免接收完才发现无法放入音乐库。
+func (r *ResumableUploader) Create(userID int64, filename, metadata string, length int64) (*models.UploadSession, error) {
+	filename = path.Base(strings.ReplaceAll(filename, `\`, "/"))
+	if filename == "" || filename == "." || filename == "/" || !r.uploader.accepts(filename) {
+		return nil, ErrUnsupportedUpload
+	}
+	if length <= 0 {
+		return nil, fmt.Errorf("文件大小必须大于 0: %d", length)
+	}
+	if r.uploader.maxFileSize > 0 && length > r.uploader.maxFileSize {
+		return nil, ErrUploadTooLarge
+	}
+	quota, err := r.uploader.Quota(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !quota.Allows(length) {
+		return nil, ErrUploadQuotaExceeded
+	}
+
+	id, err := newUploadSessionID()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(r.stagingPath(id), nil, 0o644); err != nil {
+		return nil, fmt.Errorf("创建上传暂存文件失败: %w", err)
+	}
+	session := &models.UploadSession{
+		ID:        id,
+		UserID:    userID,
+		Filename:  filename,
+		Metadata:  metadata,
+		Length:    length,
+		ExpiresAt: r.expiresAt(),
+	}
+	if err := r.repo.Create(session); err != nil {
+		os.Remove(r.stagingPath(id))
+		return nil, fmt.Errorf("保存上传会话失败: %w", err)
+	}
+	return session, nil
+}
+
+// Get 返回用户的上传会话。会话不存在、已过期或属于其他用户时返回 ErrUploadSessionNotFound。
+func (r *ResumableUploader) Get(userID int64, id string) (*models.UploadSession, error) {
+	session, err := r.repo.FindByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("读取上传会话失败: %w", err)
+	}
+	if session == nil || session.UserID != userID || session.Expired(time.Now()) {
+		return nil, ErrUploadSessionNotFound
+	}
+	return session, nil
+}
+
+// Append 从 offset 处接收上传数据，offset 必须等于已接收的字节数。超出文件大小的数据被忽略。
+// 连接中断时已写入的数据仍被保留，返回的会话记录了实际接收到的位置。文件全部接收后立即放入音乐库，
+// 并返回每首歌曲的结果；所有歌曲都失败时会话被删除，客户端需要重新上传。
+// 数据已全部接收但还没有放入音乐库的会话（服务在两步之间中断）在再次调用时放入音乐库。
+func (r *ResumableUploader) Append(ctx context.Context, userID int64, id string, offset int64, body io.Reader) (*models.UploadSession, []*UploadedFile, error) {
+	if !r.acquire(id) {
+		return nil, nil, ErrUploadSessionBusy
+	}
+	defer r.release(id)
+
+	session, err := r.Get(userID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if offset != session.Offset {
+		return session, nil, ErrUploadOffsetMismatch
+	}
+	if session.Completed() {
+		if len(session.SongIDs) > 0 {
+			return session, nil, nil
+		}
+		// 上一次请求在保存进度之后、放入音乐库之前中断，重新放入音乐库
+		results, err := r.finish(ctx, session)
+		return session, results, err
+	}
+
+	written, writeErr := r.write(session, body)
+	session.Offset += written
+	session.ExpiresAt = r.expiresAt()
+	if err := r.repo.UpdateOffset(id, session.Offset, session.ExpiresAt); err != nil {
+		return nil, nil, fmt.Errorf("保存上传进度失败: %w", err)
+	}
+	if writeErr != nil {
+		return session, nil, writeErr
+	}
+	if !session.Completed() {
+		return session, nil, nil
+	}
+
+	results, err := r.finish(ctx, session)
+	return session, results, err
+}
+
+// write 将 body 追加到暂存文件的 session.Offset 处，返回写入的字节数。
+func (r *ResumableUploader) write(session *models.UploadSession, body io.Reader) (int64, error) {
+	file, err := os.OpenFile(r.stagingPath(session.ID), os.O_WRONLY, 0)
+	if err != nil {
+		return 0, fmt.Errorf("打开上传暂存文件失败: %w", err)
+	}
+	// 上一次请求可能在写入数据之后、保存进度之前中断，丢弃记录的位置之后的数据
+	if err := file.Truncate(session.Offset); err != nil {
+		file.Close()
+		return 0, fmt.Errorf("截断上传暂存文件失败: %w", err)
+	}
+	if _, err := file.Seek(session.Offset, io.SeekStart); err != nil {
+		file.Close()
+		return 0, fmt.Errorf("定位上传暂存文件失败: %w", err)
+	}
+	written, err := io.Copy(file, io.LimitReader(body, session.Length-session.Offset))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return written, fmt.Errorf("接收上传数据失败: %w", err)
+	}
+	return written, nil
+}
+
+// finish 将接收完的文件交给 Uploader 放入音乐库，并删除暂存文件。
+func (r *ResumableUploader) finish(ctx context.Context, session *models.UploadSession) ([]*UploadedFile, error) {
+	stagingPath := r.stagingPath(session.ID)
+	defer os.Remove(stagingPath)
+
+	file, err := os.Open(stagingPath)
+	if err != nil {
+		r.discard(session.ID)
+		return nil, fmt.Errorf("读取上传暂存文件失败: %w", err)
+	}
+	results, err := r.uploader.Upload(ctx, session.UserID, session.Filename, file)
+	file.Close()
+	if err != nil {
+		r.discard(session.ID)
+		return nil, err
+	}
+
+	songIDs := make([]string, 0, len(results))
+	for _, result := range results {
+		if result.Err() == nil {
+			songIDs = append(songIDs, result.SongID)
+		}
+	}
+	if len(songIDs) == 0 {
+		r.discard(session.ID)
+		return results, nil
+	}
+	if err := r.repo.Complete(session.ID, songIDs); err != nil {
+		logger.Warnf("保存上传会话 %s 的结果失败: %v", session.ID, err)
+	}
+	session.SongIDs = songIDs
+	return results, nil
+}
+
+// Terminate 删除用户的上传会话和已接收的数据。
+func (r *ResumableUploader) Terminate(userID int64, id string) error {
+	if !r.acquire(id) {
+		return ErrUploadSessionBusy
+	}
+	defer r.release(id)
+
+	if _, err := r.Get(userID, id); err != nil {
+		return err
+	}
+	if err := os.Remove(r.stagingPath(id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除上传暂存文件失败: %w", err)
+	}
+	if _, err := r.repo.Delete(id); err != nil {
+		return fmt.Errorf("删除上传会话失败: %w", err)
+	}
+	return nil
+}
+
+// Cleanup 删除在 now 之前过期的会话及其暂存文件，正在接收数据的会话会被跳过，返回删除的会话数量。
+func (r *ResumableUploader) Cleanup(now time.Time) (int, error) {
+	sessions, err := r.repo.ListExpired(now)
+	if err != nil {
+		return 0, fmt.Errorf("读取过期的上传会话失败: %w", err)
+	}
+	removed := 0
+	for _, session := range sessions {
+		if !r.acquire(session.ID) {
+			continue
+		}
+		if err := os.Remove(r.stagingPath(session.ID)); err != nil && !os.IsNotExist(err) {
+			logger.Warnf("删除上传暂存文件失败 %s: %v", session.ID, err)
+		} else if _, err := r.repo.Delete(session.ID); err != nil {
+			logger.Warnf("删除过期的上传会话失败 %s: %v", session.ID, err)
+		} else {
+			removed++
+		}
+		r.release(session.ID)
+	}
+	return removed, nil
+}
+
+// Start 启动后台任务，每隔 interval 清理一次过期的会话。
+func (r *ResumableUploader) Start(interval time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	r.cancel = cancel
+	r.done = done
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if removed, err := r.Cleanup(time.Now()); err != nil {
+				logger.Warn(err)
+			} else if removed > 0 {
+				logger.Infof("已清理 %d 个过期的上传会话", removed)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop 停止清理过期会话的后台任务并等待其退出。
+func (r *ResumableUploader) Stop() {
+	r.mu.Lock()
+	cancel, done := r.cancel, r.done
+	r.cancel, r.done = nil, nil
+	r.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+// acquire 标记会话正在被使用，会话已被其他请求使用时返回 false。
+func (r *ResumableUploader) acquire(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.active[id] {
+		return false
+	}
+	r.active[id] = true
+	return true
+}
+
+// release 取消会话正在被使用的标记。
+func (r *ResumableUploader) release(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.active, id)
+}
+
+// discard 删除放入音乐库失败的会话。
+func (r *ResumableUploader) discard(id string) {
+	if _, err := r.repo.Delete(id); err != nil {
+		logger.Warnf("删除上传会话失败 %s: %v", id, err)
+	}
+}
+
+// stagingPath 返回会话的暂存文件路径。
+func (r *ResumableUploader) stagingPath(id string) string {
+	return filepath.Join(r.directory, id+".part")
+}
+
+// expiresAt 返回从现在起计算的会话过期时间，精确到秒（与 HTTP 日期的精度一致）。
+func (r *ResumableUploader) expiresAt() time.Time {
+	return time.Now().Add(r.expiry).Truncate(time.Second)
+}
+
+// newUploadSessionID 生成随机的上传会话 ID。
+func newUploadSessionID() (string, error) {
+	b := make([]byte, uploadSessionIDBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("生成上传会话 ID 失败: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"zero-music/models"
+)
+
+// memoryUploadSessionRepository 是用于测试的内存上传会话仓储。
+type memoryUploadSessionRepository struct {
+	sessions map[string]*models.UploadSession
+}
+
+func newMemoryUploadSessionRepository() *memoryUploadSessionRepository {
+	return &memoryUploadSessionRepository{sessions: make(map[string]*models.UploadSession)}
+}
+
+func (r *memoryUploadSessionRepository) Create(session *models.UploadSession) error {
+	stored := *session
+	r.sessions[session.ID] = &stored
+	return nil
+}
+
+func (r *memoryUploadSessionRepository) FindByID(id string) (*models.UploadSession, error) {
+	session, ok := r.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+	found := *session
+	return &found, nil
+}
+
+func (r *memoryUploadSessionRepository) UpdateOffset(id string, offset int64, expiresAt time.Time) error {
+	if session, ok := r.sessions[id]; ok {
+		session.Offset, session.ExpiresAt = offset, expiresAt
+	}
+	return nil
+}
+
+func (r *memoryUploadSessionRepository) Complete(id string, songIDs []string) error {
+	if session, ok := r.sessions[id]; ok {
+		session.SongIDs = songIDs
+	}
+	return nil
+}
+
+func (r *memoryUploadSessionRepository) Delete(id string) (bool, error) {
+	_, ok := r.sessions[id]
+	delete(r.sessions, id)
+	return ok, nil
+}
+
+func (r *memoryUploadSessionRepository) ListExpired(now time.Time) ([]*models.UploadSession, error) {
+	var expired []*models.UploadSession
+	for _, session := range r.sessions {
+		if session.Expired(now) {
+			expired = append(expired, session)
+		}
+	}
+	return expired, nil
+}
+
+func newTestResumableUploader(t *testing.T) (*ResumableUploader, *memoryUploadSessionRepository, *MusicScanner) {
+	t.Helper()
+	uploader, scanner, _ := newTestUploader(t)
+	repo := newMemoryUploadSessionRepository()
+	resumable, err := NewResumableUploader(uploader, repo, t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatalf("创建断点续传服务失败: %v", err)
+	}
+	return resumable, repo, scanner
+}
+
+// failingReader 返回 data 之后模拟连接中断。
+type failingReader struct {
+	data []byte
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, errors.New("connection reset")
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+// TestResumableUploader_Resume 测试分块接收、连接中断后从已接收的位置继续，接收完后文件放入音乐库。
+func TestResumableUploader_Resume(t *testing.T) {
+	resumable, repo, scanner := newTestResumableUploader(t)
+	data := id3SongData(t, map[string]string{"TIT2": "Song", "TPE1": "Artist", "TALB": "Album"})
+	ctx := context.Background()
+
+	session, err := resumable.Create(1, "song.mp3", "filename c29uZy5tcDM=", int64(len(data)))
+	if err != nil {
+		t.Fatalf("创建上传会话失败: %v", err)
+	}
+
+	// 第一块数据发送到一半时连接中断，已写入的数据被保留
+	half := len(data) / 2
+	session, _, err = resumable.Append(ctx, 1, session.ID, 0, &failingReader{data: data[:half]})
+	if err == nil {
+		t.Fatal("期望连接中断时返回错误")
+	}
+	if session.Offset != int64(half) {
+		t.Fatalf("期望已接收 %d 字节, 得到 %d", half, session.Offset)
+	}
+
+	// 偏移量与已接收的字节数不一致
+	if _, _, err := resumable.Append(ctx, 1, session.ID, 0, bytes.NewReader(data)); !errors.Is(err, ErrUploadOffsetMismatch) {
+		t.Errorf("期望 ErrUploadOffsetMismatch, 得到 %v", err)
+	}
+	// 其他用户看不到会话
+	if _, err := resumable.Get(2, session.ID); !errors.Is(err, ErrUploadSessionNotFound) {
+		t.Errorf("期望其他用户得到 ErrUploadSessionNotFound, 得到 %v", err)
+	}
+
+	// 从中断的位置继续，超出文件大小的数据被忽略
+	session, results, err := resumable.Append(ctx, 1, session.ID, int64(half), io.MultiReader(bytes.NewReader(data[half:]), bytes.NewReader([]byte("extra"))))
+	if err != nil {
+		t.Fatalf("继续上传失败: %v", err)
+	}
+	if !session.Completed() || len(results) != 1 || results[0].Err() != nil {
+		t.Fatalf("期望上传完成并放入音乐库, 得到 %+v %+v", session, results)
+	}
+	if results[0].RelPath != "Artist/Album/Song.mp3" || scanner.GetSongByID(results[0].SongID) == nil {
+		t.Errorf("期望歌曲加入索引, 得到 %+v", results[0])
+	}
+	if _, err := os.Stat(resumable.stagingPath(session.ID)); !os.IsNotExist(err) {
+		t.Errorf("期望暂存文件被删除, 得到 %v", err)
+	}
+
+	// 完成后会话保留到过期，记录放入音乐库的歌曲
+	stored, err := resumable.Get(1, session.ID)
+	if err != nil || len(stored.SongIDs) != 1 || stored.SongIDs[0] != results[0].SongID {
+		t.Errorf("期望会话记录歌曲 ID, 得到 %+v %v", stored, err)
+	}
+	if _, results, err := resumable.Append(ctx, 1, session.ID, session.Length, bytes.NewReader(nil)); err != nil || results != nil {
+		t.Errorf("期望重复确认已完成的上传不做任何处理, 得到 %v %v", results, err)
+	}
+
+	// 过期后会话被清理
+	if removed, err := resumable.Cleanup(time.Now().Add(2 * time.Hour)); err != nil || removed != 1 {
+		t.Errorf("期望清理 1 个会话, 得到 %d %v", removed, err)
+	}
+	if len(repo.sessions) != 0 {
+		t.Errorf("期望会话被删除, 剩余 %d 个", len(repo.sessions))
+	}
+}
+
+// TestResumableUploader_ResumeUnfinished 测试数据已全部接收、但放入音乐库之前服务中断的会话在再次确认时放入音乐库。
+func TestResumableUploader_ResumeUnfinished(t *testing.T) {
+	resumable, repo, scanner := newTestResumableUploader(t)
+	data := id3SongData(t, map[string]string{"TIT2": "Song", "TPE1": "Artist", "TALB": "Album"})
+	ctx := context.Background()
+
+	session, err := resumable.Create(1, "song.mp3", "", int64(len(data)))
+	if err != nil {
+		t.Fatalf("创建上传会话失败: %v", err)
+	}
+	// 模拟保存进度之后、放入音乐库之前服务中断
+	if err := os.WriteFile(resumable.stagingPath(session.ID), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.UpdateOffset(session.ID, session.Length, session.ExpiresAt); err != nil {
+		t.Fatal(err)
+	}
+
+	session, results, err := resumable.Append(ctx, 1, session.ID, session.Length, bytes.NewReader(nil))
+	if err != nil {
+		t.Fatalf("继续上传失败: %v", err)
+	}
+	if len(results) != 1 || results[0].Err() != nil || scanner.GetSongByID(results[0].SongID) == nil {
+		t.Fatalf("期望文件放入音乐库, 得到 %+v", results)
+	}
+	if stored, err := resumable.Get(1, session.ID); err != nil || len(stored.SongIDs) != 1 {
+		t.Errorf("期望会话记录歌曲 ID, 得到 %+v %v", stored, err)
+	}
+	if _, err := os.Stat(resumable.stagingPath(session.ID)); !os.IsNotExist(err) {
+		t.Errorf("期望暂存文件被删除, 得到 %v", err)
+	}
+}
+
+// TestResumableUploader_Rejects 测试创建会话时检查格式、大小上限和配额，以及放入音乐库失败时删除会话。
+func TestResumableUploader_Rejects(t *testing.T) {
+	resumable, repo, _ := newTestResumableUploader(t)
+	resumable.uploader.SetLimits(1<<20, 2<<20)
+	ctx := context.Background()
+
+	if _, err := resumable.Create(1, "cover.jpg", "", 10); !errors.Is(err, ErrUnsupportedUpload) {
+		t.Errorf("期望 ErrUnsupportedUpload, 得到 %v", err)
+	}
+	if _, err := resumable.Create(1, "", "", 10); !errors.Is(err, ErrUnsupportedUpload) {
+		t.Errorf("期望缺少文件名时返回 ErrUnsupportedUpload, 得到 %v", err)
+	}
+	if _, err := resumable.Create(1, "big.mp3", "", 1<<20+1); !errors.Is(err, ErrUploadTooLarge) {
+		t.Errorf("期望 ErrUploadTooLarge, 得到 %v", err)
+	}
+	resumable.uploader.SetLimits(0, 1<<20)
+	if _, err := resumable.Create(1, "big.zip", "", 1<<20+1); !errors.Is(err, ErrUploadQuotaExceeded) {
+		t.Errorf("期望 ErrUploadQuotaExceeded, 得到 %v", err)
+	}
+
+	// 内容不是音频的文件在接收完后被拒绝，会话随之删除
+	data := []byte("not really audio")
+	session, err := resumable.Create(1, "fake.mp3", "", int64(len(data)))
+	if err != nil {
+		t.Fatalf("创建上传会话失败: %v", err)
+	}
+	_, results, err := resumable.Append(ctx, 1, session.ID, 0, bytes.NewReader(data))
+	if err != nil || len(results) != 1 || !errors.Is(results[0].Err(), ErrInvalidAudioFile) {
+		t.Fatalf("期望 ErrInvalidAudioFile, 得到 %+v %v", results, err)
+	}
+	if _, ok := repo.sessions[session.ID]; ok {
+		t.Error("期望放入音乐库失败的会话被删除")
+	}
+
+	// 终止上传会删除会话和暂存文件
+	session, _ = resumable.Create(1, "song.flac", "", 100)
+	if err := resumable.Terminate(2, session.ID); !errors.Is(err, ErrUploadSessionNotFound) {
+		t.Errorf("期望其他用户无法终止上传, 得到 %v", err)
+	}
+	if err := resumable.Terminate(1, session.ID); err != nil {
+		t.Fatalf("终止上传失败: %v", err)
+	}
+	matches, _ := filepath.Glob(filepath.Join(resumable.directory, "*"))
+	if len(matches) != 0 || len(repo.sessions) != 0 {
+		t.Errorf("期望会话和暂存文件都被删除, 剩余 %v %d", matches, len(repo.sessions))
+	}
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+	"zero-music/logger"
+)
+
+// ScanState 定义了后台扫描任务的状态。
+type ScanState string
+
+const (
+	// ScanStateIdle 表示自启动以来尚未通过管理接口发起过扫描。
+	ScanStateIdle ScanState = "idle"
+	// ScanStateRunning 表示扫描正在进行。
+	ScanStateRunning ScanState = "running"
+	// ScanStateCompleted 表示扫描已成功完成。
+	ScanStateCompleted ScanState = "completed"
+	// ScanStateCancelled 表示扫描被取消。
+	ScanStateCancelled ScanState = "cancelled"
+	// ScanStateFailed 表示扫描因错误中止。
+	ScanStateFailed ScanState = "failed"
+)
+
+// ScanStatus 是后台扫描任务在某一时刻的状态快照。
+type ScanStatus struct {
+	State        ScanState   `json:"state"`
+	Mode         ScanMode    `json:"mode,omitempty"`
+	StartedAt    *time.Time  `json:"started_at,omitempty"`
+	FinishedAt   *time.Time  `json:"finished_at,omitempty"`
+	FilesVisited int64       `json:"files_visited"`
+	SongsParsed  int64       `json:"songs_parsed"`
+	Errors       int64       `json:"errors"`
+	ElapsedMs    int64       `json:"elapsed_ms"`
+	EtaMs        *int64      `json:"eta_ms"` // 无法估计时为 null
+	Result       *ScanResult `json:"result,omitempty"`
+	Error        string      `json:"error,omitempty"`
+}
+
+// scanJob 是一次由管理接口发起的后台扫描。
+type scanJob struct {
+	mode       ScanMode
+	startedAt  time.Time
+	finishedAt time.Time
+	expected   int64 // 扫描开始时已知的歌曲数量，用于估计剩余时间
+	progress   ScanProgress
+	cancel     context.CancelFunc
+	done       chan struct{}
+	state      ScanState
+	result     *ScanResult
+	err        error
+}
+
+// ScanJobManager 管理后台扫描任务，保证同一时间只有一个任务在运行。
+type ScanJobManager struct {
+	scanner *MusicScanner
+	mu      sync.Mutex
+	current *scanJob
+}
+
+// NewScanJobManager 创建一个新的 ScanJobManager 实例。
+func NewScanJobManager(scanner *MusicScanner) *ScanJobManager {
+	return &ScanJobManager{scanner: scanner}
+}
+
+// Start 在后台以指定模式发起一次扫描并返回任务状态。
+// 已有任务在运行时不会发起新的扫描，而是返回正在运行的任务，attached 为 true。
+func (m *ScanJobManager) Start(mode ScanMode) (status ScanStatus, attached bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.current != nil && m.current.state == ScanStateRunning {
+		return m.current.status(), true
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &scanJob{
+		mode:      mode,
+		startedAt: time.Now(),
+		expected:  int64(m.scanner.GetSongCount()),
+		cancel:    cancel,
+		done:      make(chan struct{}),
+		state:     ScanStateRunning,
+	}
+	m.current = job
+
+	go m.run(ctx, job)
+	return job.status(), false
+}
+
+// run 执行扫描并在结束后记录最终状态。
+func (m *ScanJobManager) run(ctx context.Context, job *scanJob) {
+	defer close(job.done)
+	defer job.cancel()
+
+	logger.Infof("开始后台扫描音乐库 (%s)", job.mode)
+	result, err := m.scanner.RescanWithProgress(ctx, job.mode, &job.progress)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job.finishedAt = time.Now()
+	job.result = result
+	job.err = err
+	switch {
+	case err == nil:
+		job.state = ScanStateCompleted
+	case errors.Is(err, context.Canceled):
+		job.state = ScanStateCancelled
+		logger.Infof("后台扫描已取消")
+	default:
+		job.state = ScanStateFailed
+		logger.Warnf("后台扫描失败: %v", err)
+	}
+}
+
+// Cancel 取消正在运行的扫描并等待其结束，没有正在运行的任务时 ok 为 false。
+func (m *ScanJobManager) Cancel() (status ScanStatus, ok bool) {
+	m.mu.Lock()
+	job := m.current
+	if job == nil || job.state != ScanStateRunning {
+		m.mu.Unlock()
+		return m.Status(), false
+	}
+	m.mu.Unlock()
+
+	job.cancel()
+	<-job.done
+	return m.Status(), true
+}
+
+// Status 返回最近一次扫描任务的状态，尚未发起过扫描时状态为 idle。
+func (m *ScanJobManager) Status() ScanStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.current == nil {
+		return ScanStatus{State: ScanStateIdle}
+	}
+	return m.current.status()
+}
+
+// Wait 等待当前任务结束或 ctx 取消，并返回此时的任务状态。
+func (m *ScanJobManager) Wait(ctx context.Context) (ScanStatus, error) {
+	m.mu.Lock()
+	job := m.current
+	m.mu.Unlock()
+	if job != nil {
+		select {
+		case <-job.done:
+		case <-ctx.Done():
+			return m.Status(), ctx.Err()
+		}
+	}
+	return m.Status(), nil
+}
+
+// Stop 取消正在运行的扫描并等待其结束，用于服务关闭。
+func (m *ScanJobManager) Stop() {
+	m.Cancel()
+}
+
+// status 返回任务的状态快照。调用此函数前必须持有 ScanJobManager 的锁。
+func (j *scanJob) status() ScanStatus {
+	startedAt := j.startedAt
+	status := ScanStatus{
+		State:        j.state,
+		Mode:         j.mode,
+		StartedAt:    &startedAt,
+		FilesVisited: j.progress.FilesVisited.Load(),
+		SongsParsed:  j.progress.SongsParsed.Load(),
+		Errors:       j.progress.Errors.Load(),
+	}
+
+	if j.state == ScanStateRunning {
+		elapsed := time.Since(j.startedAt)
+		status.ElapsedMs = elapsed.Milliseconds()
+		// 以上次扫描的歌曲数量作为总量，按已遍历文件的平均耗时估计剩余时间
+		if status.FilesVisited > 0 && j.expected > status.FilesVisited {
+			eta := elapsed.Milliseconds() * (j.expected - status.FilesVisited) / status.FilesVisited
+			status.EtaMs = &eta
+		}
+		return status
+	}
+
+	finishedAt := j.finishedAt
+	status.FinishedAt = &finishedAt
+	status.ElapsedMs = j.finishedAt.Sub(j.startedAt).Milliseconds()
+	eta := int64(0)
+	status.EtaMs = &eta
+	if j.result != nil {
+		result := *j.result
+		status.Result = &result
+	}
+	if j.err != nil {
+		status.Error = j.err.Error()
+	}
+	return status
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newScanJobTestScanner 创建一个包含 count 个测试文件的扫描器。
+func newScanJobTestScanner(t *testing.T, count int) *MusicScanner {
+	t.Helper()
+	tmpDir := t.TempDir()
+	for i := 0; i < count; i++ {
+		path := filepath.Join(tmpDir, fmt.Sprintf("song%02d.mp3", i))
+		if err := os.WriteFile(path, []byte(fmt.Sprintf("fake mp3 %d", i)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewMusicScanner(tmpDir, []string{".mp3"}, 5)
+}
+
+// waitForScanJob 等待当前扫描任务结束。
+func waitForScanJob(t *testing.T, manager *ScanJobManager) ScanStatus {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	status, err := manager.Wait(ctx)
+	if err != nil {
+		t.Fatalf("等待扫描结束失败: %v", err)
+	}
+	return status
+}
+
+// TestScanJobManager_Start 测试后台扫描完成后的状态与进度统计。
+func TestScanJobManager_Start(t *testing.T) {
+	scanner := newScanJobTestScanner(t, 5)
+	manager := NewScanJobManager(scanner)
+
+	if status := manager.Status(); status.State != ScanStateIdle {
+		t.Fatalf("期望初始状态为 idle, 得到 %s", status.State)
+	}
+
+	status, attached := manager.Start(ScanModeFull)
+	if attached {
+		t.Fatal("首次发起扫描不应附加到已有任务")
+	}
+	if status.Mode != ScanModeFull {
+		t.Errorf("期望扫描模式为 full, 得到 %s", status.Mode)
+	}
+
+	status = waitForScanJob(t, manager)
+	if status.State != ScanStateCompleted {
+		t.Fatalf("期望扫描完成, 得到 %s (%s)", status.State, status.Error)
+	}
+	if status.FilesVisited != 5 || status.SongsParsed != 5 {
+		t.Errorf("期望遍历并解析 5 个文件, 得到 visited=%d parsed=%d", status.FilesVisited, status.SongsParsed)
+	}
+	if status.Result == nil || status.Result.Added != 5 {
+		t.Errorf("期望结果中新增 5 首歌曲, 得到 %+v", status.Result)
+	}
+	if status.FinishedAt == nil || status.EtaMs == nil || *status.EtaMs != 0 {
+		t.Errorf("期望结束后记录完成时间且剩余时间为 0, 得到 %+v", status)
+	}
+	if scanner.GetSongCount() != 5 {
+		t.Errorf("期望歌曲库中有 5 首歌曲, 得到 %d", scanner.GetSongCount())
+	}
+}
+
+// TestScanJobManager_AttachAndCancel 测试扫描运行期间的重复请求会附加到已有任务，以及取消扫描。
+func TestScanJobManager_AttachAndCancel(t *testing.T) {
+	scanner := newScanJobTestScanner(t, 3)
+	manager := NewScanJobManager(scanner)
+
+	// 持有扫描锁，使后台任务停留在运行状态
+	scanner.scanMu.Lock()
+	first, attached := manager.Start(ScanModeFull)
+	if attached {
+		t.Fatal("首次发起扫描不应附加到已有任务")
+	}
+	second, attached := manager.Start(ScanModeIncremental)
+	if !attached {
+		t.Fatal("扫描运行期间的请求应附加到已有任务")
+	}
+	if second.Mode != ScanModeFull || !second.StartedAt.Equal(*first.StartedAt) {
+		t.Errorf("期望返回正在运行的任务, 得到 %+v", second)
+	}
+
+	manager.mu.Lock()
+	manager.current.cancel()
+	manager.mu.Unlock()
+	scanner.scanMu.Unlock()
+
+	status := waitForScanJob(t, manager)
+	if status.State != ScanStateCancelled {
+		t.Fatalf("期望扫描被取消, 得到 %s (%s)", status.State, status.Error)
+	}
+	if scanner.GetSongCount() != 0 {
+		t.Errorf("取消的扫描不应更新歌曲库, 得到 %d 首歌曲", scanner.GetSongCount())
+	}
+
+	if _, ok := manager.Cancel(); ok {
+		t.Error("没有正在运行的扫描时取消应返回 false")
+	}
+
+	// 上一个任务结束后可以发起新的扫描
+	if _, attached := manager.Start(ScanModeIncremental); attached {
+		t.Error("上一个任务结束后应发起新的扫描")
+	}
+	if status := waitForScanJob(t, manager); status.State != ScanStateCompleted {
+		t.Errorf("期望扫描完成, 得到 %s", status.State)
+	}
+}
+
+// TestScanJobStatus_ETA 测试根据已遍历文件数估计剩余时间。
+func TestScanJobStatus_ETA(t *testing.T) {
+	job := &scanJob{
+		mode:      ScanModeFull,
+		startedAt: time.Now().Add(-10 * time.Second),
+		expected:  100,
+		state:     ScanStateRunning,
+	}
+
+	if status := job.status(); status.EtaMs != nil {
+		t.Errorf("尚未遍历文件时不应估计剩余时间, 得到 %d", *status.EtaMs)
+	}
+
+	job.progress.FilesVisited.Store(25)
+	status := job.status()
+	if status.EtaMs == nil {
+		t.Fatal("期望估计剩余时间")
+	}
+	if eta := time.Duration(*status.EtaMs) * time.Millisecond; eta < 29*time.Second || eta > 31*time.Second {
+		t.Errorf("期望剩余时间约 30s, 得到 %v", eta)
+	}
+}
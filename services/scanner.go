@@ -3,30 +3,93 @@ package services
 import (
 	"context"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"zero-music/logger"
 	"zero-music/models"
+	"zero-music/repository"
 )
 
-// MusicScanner 负责扫描音乐目录并管理歌曲列表缓存。
+// Library 描述一个命名的音乐库根目录。
+type Library struct {
+	Name      string
+	Directory string
+}
+
+// MusicScanner 负责扫描一个或多个音乐库并管理歌曲列表缓存。
 // 它实现了 Scanner 接口。
 type MusicScanner struct {
-	directory        string
+	libraries        []Library
 	supportedFormats []string
 	songs            []*models.Song
 	songIndex        map[string]*models.Song // ID -> Song 的索引，用于快速查找
 	mu               sync.RWMutex
+	scanMu           sync.Mutex // 串行化扫描过程；扫描期间只在替换索引时短暂持有 mu
+	concurrency      int        // 并发解析元数据的工作协程数量
 	lastScan         time.Time
 	cacheTTL         time.Duration
-	lastDirModTime   time.Time
+	lastDirModTimes  map[string]time.Time      // 音乐库名称 -> 上次扫描时根目录的修改时间
+	songRepo         repository.SongRepository // 可选的持久化存储，为 nil 时仅在内存中缓存
+	lastResult       *ScanResult
+	aliases          map[string]string // 旧 ID -> 新 ID，文件重命名或移动后旧 ID 在过渡期内仍可解析
+	aliasRetention   time.Duration
+	coverPatterns    []string // 目录中封面文件的文件名模式
+	artistPatterns   []string // 艺术家目录中艺术家图片的文件名模式
+	artistSplitter   *models.ArtistSplitter
+	fileIssues       map[string]*models.LibraryIssue     // 路径 -> 无法获取文件信息的问题，这些文件不在歌曲库中
+	playlistFiles    map[string]*playlistFile            // 路径 -> 音乐目录中的播放列表文件
+	playlists        []*models.SharedPlaylist            // 由播放列表文件解析出的共享播放列表
+	overrides        map[string]*models.MetadataOverride // 歌曲 ID -> 管理员保存的元数据覆盖，返回歌曲时应用
+}
+
+// ScanMode 定义了扫描模式。
+type ScanMode string
+
+const (
+	// ScanModeFull 重新解析目录中的全部文件。
+	ScanModeFull ScanMode = "full"
+	// ScanModeIncremental 仅解析新增或大小、修改时间发生变化的文件。
+	ScanModeIncremental ScanMode = "incremental"
+)
+
+// ScanResult 描述了一次扫描对歌曲库所做的变更。
+type ScanResult struct {
+	Mode       ScanMode  `json:"mode"`
+	Added      int       `json:"added"`
+	Updated    int       `json:"updated"`
+	Removed    int       `json:"removed"`
+	Renamed    int       `json:"renamed"`
+	Unchanged  int       `json:"unchanged"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+// ScanProgress 记录一次扫描的实时进度，扫描进行中可以被并发读取。
+type ScanProgress struct {
+	// FilesVisited 是已遍历到的受支持音频文件数量。
+	FilesVisited atomic.Int64
+	// SongsParsed 是已重新解析元数据的歌曲数量。
+	SongsParsed atomic.Int64
+	// Errors 是扫描过程中出错但被跳过的文件数量。
+	Errors atomic.Int64
 }
 
-// NewMusicScanner 创建并返回一个新的 MusicScanner 实例。
+// NewMusicScanner 创建并返回一个只包含默认音乐库的 MusicScanner 实例。
 func NewMusicScanner(directory string, supportedFormats []string, cacheTTLMinutes int) *MusicScanner {
+	return NewLibraryScanner([]Library{{Name: models.DefaultLibraryName, Directory: directory}}, supportedFormats, cacheTTLMinutes, nil)
+}
+
+// NewLibraryScanner 创建一个索引多个音乐库的 MusicScanner，songRepo 为 nil 时仅在内存中缓存。
+func NewLibraryScanner(libraries []Library, supportedFormats []string, cacheTTLMinutes int, songRepo repository.SongRepository) *MusicScanner {
 	if len(supportedFormats) == 0 {
 		supportedFormats = []string{".mp3"}
 	}
@@ -34,136 +97,1067 @@ func NewMusicScanner(directory string, supportedFormats []string, cacheTTLMinute
 		cacheTTLMinutes = 5
 	}
 	return &MusicScanner{
-		directory:        directory,
+		libraries:        append([]Library(nil), libraries...),
 		supportedFormats: supportedFormats,
 		songs:            make([]*models.Song, 0),
 		songIndex:        make(map[string]*models.Song),
+		aliases:          make(map[string]string),
+		fileIssues:       make(map[string]*models.LibraryIssue),
+		playlistFiles:    make(map[string]*playlistFile),
+		playlists:        make([]*models.SharedPlaylist, 0),
+		overrides:        make(map[string]*models.MetadataOverride),
+		lastDirModTimes:  make(map[string]time.Time),
 		cacheTTL:         time.Duration(cacheTTLMinutes) * time.Minute,
+		concurrency:      runtime.NumCPU(),
+		songRepo:         songRepo,
+	}
+}
+
+// Libraries 返回扫描器索引的音乐库列表。
+func (s *MusicScanner) Libraries() []Library {
+	return append([]Library(nil), s.libraries...)
+}
+
+// libraryFor 返回包含 path 的音乐库，path 不在任何库中时返回 nil。
+func (s *MusicScanner) libraryFor(path string) *Library {
+	for i := range s.libraries {
+		rel, err := filepath.Rel(s.libraries[i].Directory, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return &s.libraries[i]
+		}
+	}
+	return nil
+}
+
+// songLibrary 返回歌曲所属的音乐库名称，旧版本的记录没有库名，视为默认库。
+func songLibrary(song *models.Song) string {
+	if song.Library == "" {
+		return models.DefaultLibraryName
+	}
+	return song.Library
+}
+
+// statLibraries 获取各音乐库根目录的信息，返回音乐库名称 -> 目录信息。
+// 暂时不可访问的库（例如未挂载的磁盘）会被跳过，其中的歌曲保持不变；所有库都不可访问时返回错误。
+func (s *MusicScanner) statLibraries() (map[string]os.FileInfo, error) {
+	if len(s.libraries) == 0 {
+		return nil, fmt.Errorf("未配置音乐库")
+	}
+
+	infos := make(map[string]os.FileInfo, len(s.libraries))
+	var firstErr error
+	for _, lib := range s.libraries {
+		info, err := os.Stat(lib.Directory)
+		if err != nil {
+			if os.IsNotExist(err) {
+				err = fmt.Errorf("音乐目录不存在: %s", lib.Directory)
+			} else {
+				err = fmt.Errorf("音乐目录不可访问: %w", err)
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			if len(s.libraries) > 1 {
+				logger.Warnf("跳过音乐库 %s: %v", lib.Name, err)
+			}
+			continue
+		}
+		infos[lib.Name] = info
+	}
+	if len(infos) == 0 {
+		return nil, firstErr
+	}
+	return infos, nil
+}
+
+// SetConcurrency 设置并发解析元数据的工作协程数量，小于 1 的值会被忽略。
+func (s *MusicScanner) SetConcurrency(n int) {
+	if n < 1 {
+		return
+	}
+	s.scanMu.Lock()
+	defer s.scanMu.Unlock()
+	s.concurrency = n
+}
+
+// SetAliasRetention 设置歌曲 ID 别名的保留时长，Restore 时会清理超过该时长的别名。
+// 小于等于 0 表示永久保留。
+func (s *MusicScanner) SetAliasRetention(d time.Duration) {
+	s.scanMu.Lock()
+	defer s.scanMu.Unlock()
+	s.aliasRetention = d
+}
+
+// SetArtworkPatterns 设置查找目录封面文件和艺术家图片的文件名模式（如 cover.*、artist.*），
+// 模式不区分大小写并按优先级排列，为空时不查找对应的图片。
+func (s *MusicScanner) SetArtworkPatterns(coverPatterns, artistPatterns []string) {
+	s.scanMu.Lock()
+	defer s.scanMu.Unlock()
+	s.coverPatterns = append([]string(nil), coverPatterns...)
+	s.artistPatterns = append([]string(nil), artistPatterns...)
+}
+
+// SetArtistSeparators 设置拆分艺术家标签的分隔符，为空时不拆分。
+// 修改后下一次扫描会按新的分隔符更新所有歌曲，包括文件未变化的歌曲。
+func (s *MusicScanner) SetArtistSeparators(separators []string) {
+	s.scanMu.Lock()
+	defer s.scanMu.Unlock()
+	s.artistSplitter = models.NewArtistSplitter(separators)
+}
+
+// NewMusicScannerWithRepository 创建一个将扫描结果持久化到 songRepo 的 MusicScanner。
+// 启动时可通过 Restore 从存储加载歌曲库，再通过增量 Rescan 仅处理磁盘上发生变化的文件。
+func NewMusicScannerWithRepository(directory string, supportedFormats []string, cacheTTLMinutes int, songRepo repository.SongRepository) *MusicScanner {
+	return NewLibraryScanner([]Library{{Name: models.DefaultLibraryName, Directory: directory}}, supportedFormats, cacheTTLMinutes, songRepo)
+}
+
+// Restore 从持久化存储加载上次的扫描结果，使服务在启动后无需等待完整扫描即可响应。
+// 加载的结果不会标记为新鲜的缓存，下一次 Scan 或 Rescan 会与磁盘进行对账。
+func (s *MusicScanner) Restore() error {
+	if s.songRepo == nil {
+		return nil
 	}
+
+	songs, err := s.songRepo.LoadAll()
+	if err != nil {
+		return fmt.Errorf("加载歌曲库失败: %w", err)
+	}
+
+	index := make(map[string]*models.Song, len(songs))
+	for _, song := range songs {
+		index[song.ID] = song
+	}
+
+	if s.aliasRetention > 0 {
+		if purged, err := s.songRepo.PurgeAliases(time.Now().Add(-s.aliasRetention)); err != nil {
+			logger.Warnf("清理过期的歌曲 ID 别名失败: %v", err)
+		} else if purged > 0 {
+			logger.Infof("已清理 %d 个过期的歌曲 ID 别名", purged)
+		}
+	}
+	aliases, err := s.songRepo.LoadAliases()
+	if err != nil {
+		return fmt.Errorf("加载歌曲 ID 别名失败: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.songs = songs
+	s.songIndex = index
+	s.aliases = aliases
+	return nil
+}
+
+// Rescan 按指定模式重新扫描音乐目录并返回本次扫描的变更统计。
+// 增量模式下，大小和修改时间均未变化的文件直接复用已有记录，只有新增或变化的文件才会重新解析元数据；
+// 完整模式下所有文件都会重新解析。两种模式都会移除磁盘上已不存在的歌曲。
+func (s *MusicScanner) Rescan(ctx context.Context, mode ScanMode) (*ScanResult, error) {
+	return s.RescanWithProgress(ctx, mode, nil)
+}
+
+// RescanWithProgress 与 Rescan 相同，但会在扫描过程中实时更新 progress，progress 可以为 nil。
+func (s *MusicScanner) RescanWithProgress(ctx context.Context, mode ScanMode, progress *ScanProgress) (*ScanResult, error) {
+	dirInfos, err := s.statLibraries()
+	if err != nil {
+		return nil, err
+	}
+
+	s.scanMu.Lock()
+	defer s.scanMu.Unlock()
+
+	return s.scanInternal(ctx, dirInfos, mode, progress)
+}
+
+// LastScanResult 返回最近一次扫描的变更统计，尚未扫描时返回 nil。
+func (s *MusicScanner) LastScanResult() *ScanResult {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.lastResult == nil {
+		return nil
+	}
+	result := *s.lastResult
+	return &result
+}
+
+// songLookup 是扫描开始时歌曲索引的快照，供扫描过程中查找已有记录。
+type songLookup struct {
+	songs  []*models.Song
+	byID   map[string]*models.Song
+	byPath map[string]*models.Song
+}
+
+// snapshot 返回当前歌曲索引的快照。调用此函数前必须持有锁。
+func (s *MusicScanner) snapshot() *songLookup {
+	lookup := &songLookup{
+		songs:  s.songs,
+		byID:   make(map[string]*models.Song, len(s.songIndex)),
+		byPath: make(map[string]*models.Song, len(s.songs)),
+	}
+	for id, song := range s.songIndex {
+		lookup.byID[id] = song
+	}
+	for _, song := range s.songs {
+		lookup.byPath[song.FilePath] = song
+	}
+	return lookup
+}
+
+// find 优先按稳定 ID 查找已有记录（音乐目录整体移动后路径会变化），
+// 找不到时再按绝对路径查找（兼容旧版本以绝对路径生成 ID 的记录）。
+func (l *songLookup) find(id, path string) *models.Song {
+	if song, ok := l.byID[id]; ok {
+		return song
+	}
+	return l.byPath[path]
 }
 
 // Scan 扫描音乐目录并返回歌曲列表（带缓存）。
 func (s *MusicScanner) Scan(ctx context.Context) ([]*models.Song, error) {
 	// 先在锁外获取目录信息，减少持锁时间
-	dirInfo, err := os.Stat(s.directory)
+	dirInfos, err := s.statLibraries()
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("音乐目录不存在: %s", s.directory)
-		}
-		return nil, fmt.Errorf("音乐目录不可访问: %w", err)
+		return nil, err
 	}
 
 	s.mu.RLock()
-	if s.canServeFromCacheWithDirInfo(dirInfo) {
-		songs := cloneSongs(s.songs)
+	if s.canServeFromCacheWithDirInfo(dirInfos) {
+		songs := s.cloneSongs(s.songs)
 		s.mu.RUnlock()
 		return songs, nil
 	}
 	s.mu.RUnlock()
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.scanMu.Lock()
+	defer s.scanMu.Unlock()
 
-	// 双重检查
-	if s.canServeFromCacheWithDirInfo(dirInfo) {
-		return cloneSongs(s.songs), nil
+	// 双重检查：等待期间其他调用者可能已经完成了扫描
+	s.mu.RLock()
+	if s.canServeFromCacheWithDirInfo(dirInfos) {
+		songs := s.cloneSongs(s.songs)
+		s.mu.RUnlock()
+		return songs, nil
 	}
+	s.mu.RUnlock()
 
-	return s.scanInternal(ctx, dirInfo)
+	// 缓存过期时只做增量扫描，未变化的文件（包括从存储恢复的记录）不会重新解析
+	if _, err := s.scanInternal(ctx, dirInfos, ScanModeIncremental, nil); err != nil {
+		return nil, err
+	}
+	return s.GetSongs(), nil
 }
 
-// canServeFromCacheWithDirInfo 检查是否可以从缓存返回（使用预先获取的目录信息）
-func (s *MusicScanner) canServeFromCacheWithDirInfo(dirInfo os.FileInfo) bool {
+// canServeFromCacheWithDirInfo 检查是否可以从缓存返回（使用预先获取的各音乐库目录信息）
+func (s *MusicScanner) canServeFromCacheWithDirInfo(dirInfos map[string]os.FileInfo) bool {
 	if len(s.songs) == 0 {
 		return false
 	}
 	if time.Since(s.lastScan) >= s.cacheTTL {
 		return false
 	}
-	if dirInfo.ModTime().After(s.lastDirModTime) {
-		return false
+	for name, info := range dirInfos {
+		if info.ModTime().After(s.lastDirModTimes[name]) {
+			return false
+		}
 	}
 	return true
 }
 
-// scanInternal 是实际的扫描逻辑。调用此函数前必须获取写锁。
-func (s *MusicScanner) scanInternal(ctx context.Context, dirInfo os.FileInfo) ([]*models.Song, error) {
+// scanInternal 是实际的扫描逻辑，只遍历 dirInfos 中可访问的音乐库。调用此函数前必须持有 scanMu。
+// 目录遍历和元数据解析都在数据锁之外进行，新的索引构建完成后才在写锁内一次性替换，
+// 因此扫描期间 GetSongs 等读操作不会被阻塞。progress 为 nil 时不对外报告进度。
+func (s *MusicScanner) scanInternal(ctx context.Context, dirInfos map[string]os.FileInfo, mode ScanMode, progress *ScanProgress) (*ScanResult, error) {
+	result := &ScanResult{Mode: mode, StartedAt: time.Now()}
+	if progress == nil {
+		progress = &ScanProgress{}
+	}
 
-	newSongs := make([]*models.Song, 0)
-	newIndex := make(map[string]*models.Song)
+	s.mu.RLock()
+	previous := s.snapshot()
+	previousPlaylists := maps.Clone(s.playlistFiles)
+	s.mu.RUnlock()
 
-	err := filepath.WalkDir(s.directory, func(path string, d os.DirEntry, walkErr error) error {
-		// 检查 context 是否被取消
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
+	pool := newMetadataPool(ctx, s.concurrency, progress)
+	var entries []*scanEntry
+	fileIssues := make(map[string]*models.LibraryIssue)
+	playlistFiles := make(map[string]*playlistFile)
 
-		if walkErr != nil {
-			// 记录具体的路径错误
-			return fmt.Errorf("访问路径 %s 失败: %w", path, walkErr)
+	var err error
+	for _, lib := range s.libraries {
+		if _, ok := dirInfos[lib.Name]; !ok {
+			continue
 		}
+		err = filepath.WalkDir(lib.Directory, func(path string, d os.DirEntry, walkErr error) error {
+			// 检查 context 是否被取消
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+			}
+
+			if walkErr != nil {
+				// 记录具体的路径错误
+				return fmt.Errorf("访问路径 %s 失败: %w", path, walkErr)
+			}
 
-		if d.IsDir() {
+			if !d.IsDir() && models.PlaylistFormat(path) != "" {
+				if info, err := d.Info(); err != nil {
+					fileIssues[path] = newFileIssue(lib, path, err)
+				} else {
+					playlistFiles[path] = loadPlaylistFile(lib, path, info, previousPlaylists[path])
+				}
+				return nil
+			}
+			if d.IsDir() || !s.isSupported(path) {
+				return nil
+			}
+
+			progress.FilesVisited.Add(1)
+			info, err := d.Info()
+			if err != nil {
+				// 记录获取文件信息失败，但不中断扫描
+				logger.Warnf("获取文件信息失败 %s: %v", path, err)
+				progress.Errors.Add(1)
+				fileIssues[path] = newFileIssue(lib, path, err)
+				return nil
+			}
+
+			entry := s.newScanEntry(lib, path, info, previous, mode, result)
+			entries = append(entries, entry)
+			if entry.needsWork() {
+				return pool.submit(entry)
+			}
 			return nil
+		})
+		if err != nil {
+			break
+		}
+	}
+	pool.wait()
+
+	if err != nil {
+		return nil, fmt.Errorf("扫描目录时出错: %w", err)
+	}
+
+	// 目录中的图片和歌词文件可能单独增删，分隔符配置也可能改变，未变化的歌曲也需要重新处理
+	finder := newSidecarFinder(s.coverPatterns, s.artistPatterns)
+	for _, entry := range entries {
+		finder.refresh(entry)
+		finder.splitCue(entry)
+		entry.splitArtists(s.artistSplitter)
+	}
+
+	newSongs := make([]*models.Song, 0, len(entries))
+	newIndex := make(map[string]*models.Song, len(entries))
+	dirty := make([]*models.Song, 0)
+
+	// 本次无法访问的库保留原有的歌曲，已从配置中移除的库则不再保留
+	configured := make(map[string]bool, len(s.libraries))
+	for _, lib := range s.libraries {
+		configured[lib.Name] = true
+	}
+	for _, song := range previous.songs {
+		name := songLibrary(song)
+		if _, scanned := dirInfos[name]; configured[name] && !scanned {
+			newSongs = append(newSongs, song)
+			newIndex[song.ID] = song
+		}
+	}
+
+	for _, entry := range entries {
+		dirty = append(dirty, entry.dirtySongs(previous)...)
+		for _, song := range entry.songs() {
+			newSongs = append(newSongs, song)
+			newIndex[song.ID] = song
 		}
+	}
 
-		ext := strings.ToLower(filepath.Ext(path))
-		for _, supported := range s.supportedFormats {
-			if ext == strings.ToLower(supported) {
-				info, err := d.Info()
-				if err != nil {
-					// 记录获取文件信息失败，但不中断扫描
-					logger.Warnf("获取文件信息失败 %s: %v", path, err)
-					return nil
+	s.mu.Lock()
+	removed := removedIDs(s.songIndex, newIndex)
+	renames := detectRenames(s.songIndex, removed, newIndex)
+	s.recordAliases(renames)
+	result.Removed = len(removed)
+	result.Renamed = len(renames)
+	result.DurationMs = time.Since(result.StartedAt).Milliseconds()
+	s.songs = newSongs
+	s.songIndex = newIndex
+	s.lastScan = time.Now()
+	for name, info := range dirInfos {
+		s.lastDirModTimes[name] = info.ModTime()
+	}
+	s.lastResult = result
+	for path, issue := range s.fileIssues {
+		if _, scanned := dirInfos[issue.Library]; scanned || !configured[issue.Library] {
+			delete(s.fileIssues, path)
+		}
+	}
+	maps.Copy(s.fileIssues, fileIssues)
+	for path, file := range s.playlistFiles {
+		if _, scanned := dirInfos[file.library]; scanned || !configured[file.library] {
+			delete(s.playlistFiles, path)
+		}
+	}
+	maps.Copy(s.playlistFiles, playlistFiles)
+	s.playlists = resolvePlaylists(s.playlistFiles, newSongs)
+	s.mu.Unlock()
+
+	s.persistChanges(dirty, removed, renames)
+
+	logger.Infof("音乐库扫描完成 (%s): 新增 %d, 更新 %d, 删除 %d, 重命名 %d, 未变化 %d, 耗时 %dms",
+		mode, result.Added, result.Updated, result.Removed, result.Renamed, result.Unchanged, result.DurationMs)
+
+	copied := *result
+	return &copied, nil
+}
+
+// isSupported 判断文件扩展名是否属于支持的音频格式。
+func (s *MusicScanner) isSupported(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, supported := range s.supportedFormats {
+		if ext == strings.ToLower(supported) {
+			return true
+		}
+	}
+	return false
+}
+
+// scanEntry 是一次扫描中单个文件的处理结果。
+type scanEntry struct {
+	path    string
+	root    string // 所属音乐库的根目录
+	library string
+	relPath string
+	id      string
+	size    int64
+	song    *models.Song
+	dirty   bool           // 记录是否发生变化，需要写入存储
+	prev    *models.Song   // 需要重新解析时的已有记录，用于保留不来自标签的数据
+	tracks  []*models.Song // 按 CUE 文件拆分出的曲目，不为空时代替整轨文件本身出现在歌曲库中
+}
+
+// needsWork 判断条目是否需要交给工作池处理：尚未解析元数据，或缺少内容指纹（旧版本的记录）。
+func (e *scanEntry) needsWork() bool {
+	return e.song == nil || e.song.ContentHash == ""
+}
+
+// mutable 返回可以修改的歌曲记录。复用的记录可能仍被当前索引引用，首次修改前先复制一份，并标记为需要写入存储。
+func (e *scanEntry) mutable() *models.Song {
+	if !e.dirty {
+		copied := *e.song
+		e.song = &copied
+		e.dirty = true
+	}
+	return e.song
+}
+
+// songs 返回条目在歌曲库中对应的歌曲：拆分后的整轨文件对应其中的各个曲目。
+func (e *scanEntry) songs() []*models.Song {
+	if len(e.tracks) > 0 {
+		return e.tracks
+	}
+	if e.song == nil {
+		return nil
+	}
+	return []*models.Song{e.song}
+}
+
+// dirtySongs 返回条目中需要写入存储的歌曲。曲目每次扫描都会重新生成，与已有记录不同时才需要写入。
+func (e *scanEntry) dirtySongs(previous *songLookup) []*models.Song {
+	if len(e.tracks) == 0 {
+		if e.dirty {
+			return []*models.Song{e.song}
+		}
+		return nil
+	}
+	var dirty []*models.Song
+	for _, track := range e.tracks {
+		if prev, ok := previous.byID[track.ID]; !ok || !reflect.DeepEqual(prev, track) {
+			dirty = append(dirty, track)
+		}
+	}
+	return dirty
+}
+
+// splitArtists 按分隔符重新拆分歌曲的艺术家标签。
+func (e *scanEntry) splitArtists(splitter *models.ArtistSplitter) {
+	for _, track := range e.tracks {
+		track.Artists = splitter.Split(track.Artist)
+	}
+	if e.song == nil {
+		return
+	}
+	artists := splitter.Split(e.song.Artist)
+	if slices.Equal(artists, e.song.Artists) {
+		return
+	}
+	e.mutable().Artists = artists
+}
+
+// newScanEntry 为文件创建扫描条目并累计扫描统计。
+// 增量模式下文件大小和修改时间均未变化时直接复用已有记录，否则返回 song 为 nil 的条目，等待元数据解析。
+// 复用的记录如果 ID 或路径已经过时（例如音乐目录被整体移动），会更新为当前值并标记为需要写入存储。
+func (s *MusicScanner) newScanEntry(lib Library, path string, info os.FileInfo, previous *songLookup, mode ScanMode, result *ScanResult) *scanEntry {
+	relPath := models.LibraryRelPath(lib.Directory, path)
+	entry := &scanEntry{
+		path:    path,
+		root:    lib.Directory,
+		library: lib.Name,
+		relPath: relPath,
+		id:      models.LibrarySongID(lib.Name, relPath),
+		size:    info.Size(),
+	}
+	prev := previous.find(entry.id, path)
+	if prev != nil && prev.IsCueTrack() {
+		// 拆分后的整轨文件本身不在歌曲库中，每次都重新解析其标签，再根据 CUE 文件重新生成曲目
+		if mode == ScanModeIncremental && prev.FileSize == info.Size() && prev.AddedAt.Equal(info.ModTime()) {
+			result.Unchanged++
+		} else {
+			result.Updated++
+		}
+		return entry
+	}
+	if prev != nil && mode == ScanModeIncremental && prev.FileSize == info.Size() && prev.AddedAt.Equal(info.ModTime()) {
+		result.Unchanged++
+		if prev.ID != entry.id || prev.FilePath != path || prev.RelPath != relPath || prev.Library != lib.Name {
+			copied := *prev
+			copied.ID, copied.FilePath, copied.RelPath, copied.Library = entry.id, path, relPath, lib.Name
+			prev = &copied
+			entry.dirty = true
+		}
+		entry.song = prev
+		return entry
+	}
+	if prev != nil {
+		entry.prev = prev
+		result.Updated++
+	} else {
+		result.Added++
+	}
+	return entry
+}
+
+// metadataPool 是解析歌曲元数据的有界工作池。
+type metadataPool struct {
+	ctx      context.Context
+	jobs     chan *scanEntry
+	wg       sync.WaitGroup
+	progress *ScanProgress
+}
+
+// newMetadataPool 创建并启动包含 workers 个工作协程的工作池，解析进度累计到 progress 中。
+func newMetadataPool(ctx context.Context, workers int, progress *ScanProgress) *metadataPool {
+	if workers <= 0 {
+		workers = 1
+	}
+	p := &metadataPool{ctx: ctx, jobs: make(chan *scanEntry, workers), progress: progress}
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for entry := range p.jobs {
+				var song *models.Song
+				if entry.song == nil {
+					song = models.NewSong(entry.path, entry.size)
+					if err := song.UpdateMetadata(); err != nil {
+						// 标签损坏时仍保留以文件名为标题的记录，歌曲依然可以播放
+						logger.Warnf("解析元数据失败 %s: %v", entry.path, err)
+						p.progress.Errors.Add(1)
+					}
+					keepAnalyzedLoudness(song, entry.prev)
+					p.progress.SongsParsed.Add(1)
+				} else {
+					// 复用的记录可能仍被当前索引引用，必须在副本上修改
+					copied := *entry.song
+					song = &copied
 				}
-				song := models.NewSong(path, info.Size())
-				song.UpdateMetadata()
-				newSongs = append(newSongs, song)
-				newIndex[song.ID] = song
-				break
+				song.ID, song.Library, song.RelPath = entry.id, entry.library, entry.relPath
+				if song.ContentHash == "" {
+					song.UpdateContentHash()
+				}
+				entry.song = song
+				entry.dirty = true
 			}
+		}()
+	}
+	return p
+}
+
+// keepAnalyzedLoudness 在文件内容未变化时保留服务器分析得出的音量信息，避免全量扫描后需要重新分析。
+// 标签中有音量信息时以标签为准。
+func keepAnalyzedLoudness(song, prev *models.Song) {
+	if song.ReplayGain != nil || prev == nil || prev.ReplayGain == nil || prev.ReplayGain.Source != models.LoudnessSourceAnalysis {
+		return
+	}
+	if prev.FileSize == song.FileSize && prev.AddedAt.Equal(song.AddedAt) {
+		song.ReplayGain = prev.ReplayGain
+	}
+}
+
+// submit 提交一个待解析的条目，工作池已满时阻塞，ctx 取消时返回错误。
+func (p *metadataPool) submit(entry *scanEntry) error {
+	select {
+	case p.jobs <- entry:
+		return nil
+	case <-p.ctx.Done():
+		return p.ctx.Err()
+	}
+}
+
+// wait 关闭任务队列并等待所有已提交的条目解析完成。
+func (p *metadataPool) wait() {
+	close(p.jobs)
+	p.wg.Wait()
+}
+
+// removedIDs 返回在 oldIndex 中存在但 newIndex 中已不存在的歌曲 ID，按 ID 排序。
+func removedIDs(oldIndex, newIndex map[string]*models.Song) []string {
+	var removed []string
+	for id := range oldIndex {
+		if _, ok := newIndex[id]; !ok {
+			removed = append(removed, id)
 		}
+	}
+	sort.Strings(removed)
+	return removed
+}
 
+// detectRenames 将被移除的歌曲与新出现的歌曲配对，返回旧 ID -> 新 ID。
+// 绝对路径相同说明只是 ID 的生成方式发生了变化（旧版本的记录）；
+// 内容指纹和文件大小都相同说明文件被重命名或移动到了库内的其他位置。
+// CUE 曲目与整轨文件共用路径，不按路径配对。
+func detectRenames(oldIndex map[string]*models.Song, removed []string, newIndex map[string]*models.Song) map[string]string {
+	if len(removed) == 0 {
 		return nil
-	})
+	}
 
-	if err != nil {
-		return nil, fmt.Errorf("扫描目录时出错: %v", err)
+	addedByPath := make(map[string]*models.Song)
+	addedByHash := make(map[string][]*models.Song)
+	for id, song := range newIndex {
+		if _, existed := oldIndex[id]; existed {
+			continue
+		}
+		if !song.IsCueTrack() {
+			addedByPath[song.FilePath] = song
+		}
+		if song.ContentHash != "" {
+			addedByHash[song.ContentHash] = append(addedByHash[song.ContentHash], song)
+		}
+	}
+	for _, candidates := range addedByHash {
+		sort.Slice(candidates, func(i, j int) bool { return candidates[i].FilePath < candidates[j].FilePath })
+	}
+
+	renames := make(map[string]string)
+	claimed := make(map[string]bool)
+	for _, oldID := range removed {
+		old := oldIndex[oldID]
+		if song, ok := addedByPath[old.FilePath]; ok && !old.IsCueTrack() && !claimed[song.ID] {
+			renames[oldID] = song.ID
+			claimed[song.ID] = true
+			continue
+		}
+		if old.ContentHash == "" {
+			continue
+		}
+		for _, song := range addedByHash[old.ContentHash] {
+			if !claimed[song.ID] && song.FileSize == old.FileSize {
+				renames[oldID] = song.ID
+				claimed[song.ID] = true
+				break
+			}
+		}
+	}
+	return renames
+}
+
+// recordAliases 将重命名记录为内存中的 ID 别名，并将元数据覆盖迁移到新 ID（新 ID 已有覆盖时保留新的）。
+// 调用此函数前必须持有写锁。
+func (s *MusicScanner) recordAliases(renames map[string]string) {
+	for oldID, newID := range renames {
+		if override, ok := s.overrides[oldID]; ok {
+			delete(s.overrides, oldID)
+			if _, exists := s.overrides[newID]; !exists {
+				copied := *override
+				copied.SongID = newID
+				s.overrides[newID] = &copied
+			}
+		}
+		for alias, target := range s.aliases {
+			if target == oldID {
+				s.aliases[alias] = newID
+			}
+		}
+		delete(s.aliases, newID)
+		s.aliases[oldID] = newID
 	}
+}
+
+// recordMove 记录歌曲从 oldID 移动到了 newID：旧 ID 成为新 ID 的永久别名，用户数据迁移到新 ID。
+// ApplyChanges 通常已经根据内容指纹识别出了移动，没有内容指纹的旧记录需要由移动文件的调用方显式记录。
+// 通过 API 移动的歌曲的旧 ID 可能已经被分享到外部，因此别名不随过渡期结束而清理。
+func (s *MusicScanner) recordMove(oldID, newID string) {
+	if oldID == newID {
+		return
+	}
+	s.scanMu.Lock()
+	defer s.scanMu.Unlock()
+
+	s.mu.Lock()
+	recorded := s.aliases[oldID] == newID
+	renames := map[string]string{oldID: newID}
+	if !recorded {
+		s.recordAliases(renames)
+	}
+	s.mu.Unlock()
+
+	if !recorded {
+		s.persistChanges(nil, nil, renames)
+	}
+	if s.songRepo == nil {
+		return
+	}
+	if err := s.songRepo.KeepAlias(oldID); err != nil {
+		logger.Warnf("永久保留歌曲 ID 别名失败 %s: %v", oldID, err)
+	}
+}
+
+// ApplyChanges 将指定路径上的变化增量应用到歌曲库，供文件系统监听器使用。
+// 路径可以是文件或目录：存在的目录会被递归处理，已不存在的路径会移除其下的所有歌曲。
+// 不属于任何音乐库的路径会被忽略。
+func (s *MusicScanner) ApplyChanges(ctx context.Context, paths []string) (*ScanResult, error) {
+	s.scanMu.Lock()
+	defer s.scanMu.Unlock()
 
+	result := &ScanResult{Mode: ScanModeIncremental, StartedAt: time.Now()}
+
+	s.mu.RLock()
+	previous := s.snapshot()
+	previousPlaylists := maps.Clone(s.playlistFiles)
+	s.mu.RUnlock()
+
+	pool := newMetadataPool(ctx, s.concurrency, &ScanProgress{})
+	touched := make(map[string]*scanEntry) // 路径 -> 新条目，nil 表示移除
+	var roots []string                     // 重新检查过的路径，其下原有的文件问题需要清除
+	fileIssues := make(map[string]*models.LibraryIssue)
+	playlistChanges := make(map[string]*playlistFile) // 路径 -> 重新解析的播放列表文件，nil 表示移除
+
+	for _, root := range paths {
+		if err := ctx.Err(); err != nil {
+			pool.wait()
+			return nil, err
+		}
+
+		lib := s.libraryFor(root)
+		if lib == nil {
+			continue
+		}
+		// 封面、艺术家图片或歌词文件的变化会影响所在目录及其子目录中的歌曲
+		if isSidecarFile(root) && root != lib.Directory {
+			root = filepath.Dir(root)
+		}
+
+		if _, err := os.Stat(root); err != nil {
+			if !os.IsNotExist(err) {
+				logger.Warnf("获取文件信息失败 %s: %v", root, err)
+				fileIssues[root] = newFileIssue(*lib, root, err)
+				continue
+			}
+			// 音乐库根目录消失通常是磁盘被卸载，保留其中的歌曲，等目录恢复后再对账
+			if root == lib.Directory {
+				logger.Warnf("音乐库 %s 的目录暂时不可访问: %s", lib.Name, root)
+				continue
+			}
+			roots = append(roots, root)
+			prefix := root + string(filepath.Separator)
+			for path := range previous.byPath {
+				if path == root || strings.HasPrefix(path, prefix) {
+					touched[path] = nil
+				}
+			}
+			for path := range previousPlaylists {
+				if path == root || strings.HasPrefix(path, prefix) {
+					playlistChanges[path] = nil
+				}
+			}
+			continue
+		}
+
+		roots = append(roots, root)
+		walkErr := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				logger.Warnf("访问路径 %s 失败: %v", path, err)
+				return nil
+			}
+			if !d.IsDir() && models.PlaylistFormat(path) != "" {
+				if info, err := d.Info(); err != nil {
+					fileIssues[path] = newFileIssue(*lib, path, err)
+				} else {
+					playlistChanges[path] = loadPlaylistFile(*lib, path, info, previousPlaylists[path])
+				}
+				return nil
+			}
+			if d.IsDir() || !s.isSupported(path) {
+				return nil
+			}
+			if _, seen := touched[path]; seen {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				logger.Warnf("获取文件信息失败 %s: %v", path, err)
+				fileIssues[path] = newFileIssue(*lib, path, err)
+				return nil
+			}
+			entry := s.newScanEntry(*lib, path, info, previous, ScanModeIncremental, result)
+			touched[path] = entry
+			if entry.needsWork() {
+				return pool.submit(entry)
+			}
+			return nil
+		})
+		if walkErr != nil {
+			logger.Warnf("处理路径 %s 失败: %v", root, walkErr)
+		}
+	}
+	pool.wait()
+
+	// 取消时部分条目尚未解析，不能据此更新索引
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	finder := newSidecarFinder(s.coverPatterns, s.artistPatterns)
+	for _, entry := range touched {
+		if entry != nil {
+			finder.refresh(entry)
+			finder.splitCue(entry)
+			entry.splitArtists(s.artistSplitter)
+		}
+	}
+
+	var dirty []*models.Song
+	for _, entry := range touched {
+		if entry != nil {
+			dirty = append(dirty, entry.dirtySongs(previous)...)
+		}
+	}
+
+	s.mu.Lock()
+	newSongs := make([]*models.Song, 0, len(s.songs))
+	newIndex := make(map[string]*models.Song, len(s.songIndex))
+	// 拆分后的整轨文件在列表中对应多首歌曲，新的曲目放在第一首原有曲目的位置
+	for _, song := range s.songs {
+		entry, ok := touched[song.FilePath]
+		if !ok {
+			newSongs = append(newSongs, song)
+			newIndex[song.ID] = song
+			continue
+		}
+		delete(touched, song.FilePath)
+		if entry == nil {
+			continue
+		}
+		for _, current := range entry.songs() {
+			newSongs = append(newSongs, current)
+			newIndex[current.ID] = current
+		}
+		// 同一路径上其余的原有曲目已被替换，随后直接跳过
+		touched[song.FilePath] = nil
+	}
+	for _, entry := range touched {
+		if entry == nil {
+			continue
+		}
+		for _, song := range entry.songs() {
+			newSongs = append(newSongs, song)
+			newIndex[song.ID] = song
+		}
+	}
+	removed := removedIDs(s.songIndex, newIndex)
+	renames := detectRenames(s.songIndex, removed, newIndex)
+	s.recordAliases(renames)
 	s.songs = newSongs
 	s.songIndex = newIndex
-	s.lastScan = time.Now()
-	s.lastDirModTime = dirInfo.ModTime()
+	for path := range s.fileIssues {
+		for _, root := range roots {
+			if path == root || strings.HasPrefix(path, root+string(filepath.Separator)) {
+				delete(s.fileIssues, path)
+				break
+			}
+		}
+	}
+	maps.Copy(s.fileIssues, fileIssues)
+	for path, file := range playlistChanges {
+		if file == nil {
+			delete(s.playlistFiles, path)
+		} else {
+			s.playlistFiles[path] = file
+		}
+	}
+	// 歌曲的增删也会影响播放列表条目的解析结果
+	s.playlists = resolvePlaylists(s.playlistFiles, newSongs)
+	s.mu.Unlock()
+
+	result.Removed = len(removed)
+	result.Renamed = len(renames)
+	s.persistChanges(dirty, removed, renames)
+
+	result.DurationMs = time.Since(result.StartedAt).Milliseconds()
+	return result, nil
+}
+
+// UpdateReplayGain 将响度分析的结果写入歌曲库并持久化，返回实际更新的歌曲数量。
+// 分析期间文件被修改、移动或删除的歌曲会被跳过，以免将旧文件的结果写入新记录。
+func (s *MusicScanner) UpdateReplayGain(analyzed []*models.Song) int {
+	s.scanMu.Lock()
+	defer s.scanMu.Unlock()
+
+	s.mu.Lock()
+	var dirty []*models.Song
+	updated := make(map[string]*models.Song, len(analyzed))
+	for _, song := range analyzed {
+		current, ok := s.songIndex[song.ID]
+		if !ok || current.FilePath != song.FilePath || current.FileSize != song.FileSize || !current.AddedAt.Equal(song.AddedAt) {
+			continue
+		}
+		copied := *current
+		copied.ReplayGain = song.ReplayGain
+		updated[song.ID] = &copied
+		dirty = append(dirty, &copied)
+	}
+	if len(dirty) > 0 {
+		// 替换而不是原地修改切片，GetSongs 之前返回的快照不受影响
+		newSongs := make([]*models.Song, len(s.songs))
+		for i, song := range s.songs {
+			if copied, ok := updated[song.ID]; ok {
+				song = copied
+				s.songIndex[song.ID] = song
+			}
+			newSongs[i] = song
+		}
+		s.songs = newSongs
+	}
+	s.mu.Unlock()
 
-	return cloneSongs(newSongs), nil
+	if len(dirty) > 0 {
+		s.persistChanges(dirty, nil, nil)
+	}
+	return len(dirty)
 }
 
 // Refresh 强制执行一次新的扫描,并刷新歌曲列表缓存。
 func (s *MusicScanner) Refresh(ctx context.Context) error {
 	// 在锁外获取目录信息
-	dirInfo, err := os.Stat(s.directory)
+	dirInfos, err := s.statLibraries()
 	if err != nil {
-		if os.IsNotExist(err) {
-			return fmt.Errorf("音乐目录不存在: %s", s.directory)
-		}
-		return fmt.Errorf("音乐目录不可访问: %w", err)
+		return err
 	}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.scanMu.Lock()
+	defer s.scanMu.Unlock()
 
-	_, err = s.scanInternal(ctx, dirInfo)
+	_, err = s.scanInternal(ctx, dirInfos, ScanModeFull, nil)
 	return err
 }
 
-// GetSongs 返回当前缓存的歌曲列表的深度拷贝。
+// persistChanges 将本次扫描中发生变化的歌曲写入存储，删除已不存在的歌曲，并迁移重命名歌曲的用户数据。
+// 用户数据先于歌曲表迁移：即使两步之间中断，下次扫描仍能从旧记录中识别出重命名并重新迁移。
+// 持久化失败不影响内存中的扫描结果，仅记录警告。调用此函数前必须持有 scanMu。
+func (s *MusicScanner) persistChanges(dirty []*models.Song, removed []string, renames map[string]string) {
+	if s.songRepo == nil {
+		return
+	}
+
+	if len(renames) > 0 {
+		if err := s.songRepo.RenameIDs(renames); err != nil {
+			logger.Warnf("迁移重命名歌曲的用户数据失败: %v", err)
+		} else {
+			logger.Infof("已将 %d 首重命名或移动的歌曲的用户数据迁移到新 ID", len(renames))
+		}
+	}
+
+	if err := s.songRepo.Save(dirty, removed); err != nil {
+		logger.Warnf("持久化歌曲库失败: %v", err)
+	}
+}
+
+// Issues 返回歌曲库健康报告中的所有问题：解析歌曲时发现的问题，以及无法访问的文件。
+// 结果按音乐库、路径和问题类型排序。
+func (s *MusicScanner) Issues() []*models.LibraryIssue {
+	s.mu.RLock()
+	issues := make([]*models.LibraryIssue, 0, len(s.fileIssues))
+	for _, issue := range s.fileIssues {
+		copied := *issue
+		issues = append(issues, &copied)
+	}
+	for _, song := range s.songs {
+		issues = append(issues, song.LibraryIssues()...)
+	}
+	for _, file := range s.playlistFiles {
+		if issue := file.issue(); issue != nil {
+			issues = append(issues, issue)
+		}
+	}
+	for _, playlist := range s.playlists {
+		issues = append(issues, playlist.LibraryIssues()...)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(issues, func(i, j int) bool {
+		a, b := issues[i], issues[j]
+		if a.Library != b.Library {
+			return a.Library < b.Library
+		}
+		if a.FilePath != b.FilePath {
+			return a.FilePath < b.FilePath
+		}
+		if a.SongID != b.SongID {
+			return a.SongID < b.SongID
+		}
+		return slices.Index(models.IssueKinds, a.Kind) < slices.Index(models.IssueKinds, b.Kind)
+	})
+	return issues
+}
+
+// newFileIssue 返回无法获取文件信息时对应的问题。
+func newFileIssue(lib Library, path string, err error) *models.LibraryIssue {
+	issue := models.FileIssue(err)
+	return &models.LibraryIssue{
+		Kind:     issue.Kind,
+		Message:  issue.Message,
+		Library:  lib.Name,
+		FilePath: path,
+		RelPath:  models.LibraryRelPath(lib.Directory, path),
+	}
+}
+
+// Playlists 返回音乐目录中的共享播放列表，按音乐库和相对路径排序。
+func (s *MusicScanner) Playlists() []*models.SharedPlaylist {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	playlists := make([]*models.SharedPlaylist, len(s.playlists))
+	for i, playlist := range s.playlists {
+		playlists[i] = playlist.Clone()
+	}
+	return playlists
+}
+
+// GetPlaylistByID 根据 ID 查找共享播放列表，未找到时返回 nil。
+func (s *MusicScanner) GetPlaylistByID(id string) *models.SharedPlaylist {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, playlist := range s.playlists {
+		if playlist.ID == id {
+			return playlist.Clone()
+		}
+	}
+	return nil
+}
+
+// GetSongs 返回当前缓存的歌曲列表的深度拷贝，元数据覆盖已经应用。
 func (s *MusicScanner) GetSongs() []*models.Song {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return cloneSongs(s.songs)
+	return s.cloneSongs(s.songs)
 }
 
 // GetSongCount 返回当前缓存的歌曲数量。
@@ -173,21 +1167,27 @@ func (s *MusicScanner) GetSongCount() int {
 	return len(s.songs)
 }
 
-// GetSongByID 根据 ID 查找并返回指定的歌曲。
+// GetSongByID 根据 ID 查找并返回指定的歌曲，旧 ID 会通过别名解析到重命名后的歌曲。
 // 如果未找到歌曲，则返回 nil。
 // 此方法使用索引进行高效查找。
 func (s *MusicScanner) GetSongByID(id string) *models.Song {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	song, ok := s.songIndex[id]
+	if !ok {
+		// 文件重命名或移动后，旧 ID 通过别名解析到新 ID
+		if newID, aliased := s.aliases[id]; aliased {
+			song, ok = s.songIndex[newID]
+		}
+	}
 	if !ok || song == nil {
 		return nil
 	}
-	copiedSong := *song
-	return &copiedSong
+	return s.cloneSong(song)
 }
 
-func cloneSongs(src []*models.Song) []*models.Song {
+// cloneSongs 复制歌曲列表并应用元数据覆盖。调用此函数前必须持有锁。
+func (s *MusicScanner) cloneSongs(src []*models.Song) []*models.Song {
 	if len(src) == 0 {
 		return []*models.Song{}
 	}
@@ -196,8 +1196,66 @@ func cloneSongs(src []*models.Song) []*models.Song {
 		if song == nil {
 			continue
 		}
-		copied := *song
-		dst[i] = &copied
+		dst[i] = s.cloneSong(song)
 	}
 	return dst
 }
+
+// cloneSong 复制歌曲并应用元数据覆盖。索引中始终保存从文件读取的值，覆盖只作用于返回的副本，
+// 因此修改或删除覆盖后无需重新解析文件。调用此函数前必须持有锁。
+func (s *MusicScanner) cloneSong(song *models.Song) *models.Song {
+	copied := *song
+	if override, ok := s.overrides[song.ID]; ok {
+		override.Apply(&copied)
+		if override.Artist != nil {
+			copied.Artists = s.artistSplitter.Split(copied.Artist)
+		}
+	}
+	return &copied
+}
+
+// SetMetadataOverrides 替换全部元数据覆盖。
+func (s *MusicScanner) SetMetadataOverrides(overrides []*models.MetadataOverride) {
+	index := make(map[string]*models.MetadataOverride, len(overrides))
+	for _, override := range overrides {
+		index[override.SongID] = override
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.overrides = index
+}
+
+// SetMetadataOverride 设置一首歌曲的元数据覆盖，override 为 nil 时删除。
+func (s *MusicScanner) SetMetadataOverride(songID string, override *models.MetadataOverride) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if override == nil {
+		delete(s.overrides, songID)
+		return
+	}
+	s.overrides[songID] = override
+}
+
+// MetadataOverride 返回歌曲的元数据覆盖的副本，没有时返回 nil。
+func (s *MusicScanner) MetadataOverride(songID string) *models.MetadataOverride {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if override, ok := s.overrides[songID]; ok {
+		copied := *override
+		return &copied
+	}
+	return nil
+}
+
+// MetadataOverrides 返回所有元数据覆盖的副本，按歌曲 ID 排序。
+func (s *MusicScanner) MetadataOverrides() []*models.MetadataOverride {
+	s.mu.RLock()
+	overrides := make([]*models.MetadataOverride, 0, len(s.overrides))
+	for _, override := range s.overrides {
+		copied := *override
+		overrides = append(overrides, &copied)
+	}
+	s.mu.RUnlock()
+	sort.Slice(overrides, func(i, j int) bool { return overrides[i].SongID < overrides[j].SongID })
+	return overrides
+}
@@ -2,10 +2,13 @@ package services
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+	"zero-music/models"
 )
 
 // TestNewMusicScanner 测试 NewMusicScanner 是否能正确创建一个扫描器实例。
@@ -14,8 +17,9 @@ func TestNewMusicScanner(t *testing.T) {
 	if scanner == nil {
 		t.Fatal("期望扫描器被成功创建")
 	}
-	if scanner.directory != "/test/dir" {
-		t.Errorf("期望目录为 /test/dir, 得到 %s", scanner.directory)
+	libraries := scanner.Libraries()
+	if len(libraries) != 1 || libraries[0].Directory != "/test/dir" || libraries[0].Name != models.DefaultLibraryName {
+		t.Errorf("期望只有目录为 /test/dir 的默认库, 得到 %+v", libraries)
 	}
 	if scanner.cacheTTL != 5*time.Minute {
 		t.Errorf("期望缓存 TTL 为 5m, 得到 %v", scanner.cacheTTL)
@@ -244,3 +248,670 @@ func TestMusicScanner_ConcurrentAccess(t *testing.T) {
 		<-done
 	}
 }
+
+// memorySongRepository 是用于测试的内存 SongRepository 实现。
+type memorySongRepository struct {
+	songs   map[string]*models.Song
+	aliases map[string]string
+	kept    map[string]bool
+}
+
+func newMemorySongRepository() *memorySongRepository {
+	return &memorySongRepository{songs: make(map[string]*models.Song), aliases: make(map[string]string), kept: make(map[string]bool)}
+}
+
+func (r *memorySongRepository) LoadAll() ([]*models.Song, error) {
+	songs := make([]*models.Song, 0, len(r.songs))
+	for _, song := range r.songs {
+		copied := *song
+		songs = append(songs, &copied)
+	}
+	return songs, nil
+}
+
+func (r *memorySongRepository) Save(upserts []*models.Song, removedIDs []string) error {
+	for _, id := range removedIDs {
+		delete(r.songs, id)
+	}
+	for _, song := range upserts {
+		copied := *song
+		r.songs[song.ID] = &copied
+	}
+	return nil
+}
+
+func (r *memorySongRepository) Count() (int, error) {
+	return len(r.songs), nil
+}
+
+func (r *memorySongRepository) RenameIDs(renames map[string]string) error {
+	for oldID, newID := range renames {
+		r.aliases[oldID] = newID
+	}
+	return nil
+}
+
+func (r *memorySongRepository) LoadAliases() (map[string]string, error) {
+	aliases := make(map[string]string, len(r.aliases))
+	for oldID, newID := range r.aliases {
+		aliases[oldID] = newID
+	}
+	return aliases, nil
+}
+
+func (r *memorySongRepository) KeepAlias(oldID string) error {
+	if _, ok := r.aliases[oldID]; ok {
+		r.kept[oldID] = true
+	}
+	return nil
+}
+
+// PurgeAliases 删除所有未被永久保留的别名，内存实现不记录别名的创建时间。
+func (r *memorySongRepository) PurgeAliases(before time.Time) (int64, error) {
+	var purged int64
+	for oldID := range r.aliases {
+		if !r.kept[oldID] {
+			delete(r.aliases, oldID)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// TestMusicScanner_RestoreAndReconcile 测试从存储恢复后对账只处理变化的文件。
+func TestMusicScanner_RestoreAndReconcile(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	keepFile := filepath.Join(tmpDir, "keep.mp3")
+	removeFile := filepath.Join(tmpDir, "remove.mp3")
+	for _, f := range []string{keepFile, removeFile} {
+		if err := os.WriteFile(f, []byte("fake mp3"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	repo := newMemorySongRepository()
+	first := NewMusicScannerWithRepository(tmpDir, []string{".mp3"}, 5, repo)
+	if _, err := first.Scan(context.Background()); err != nil {
+		t.Fatalf("扫描失败: %v", err)
+	}
+	if count, _ := repo.Count(); count != 2 {
+		t.Fatalf("期望持久化 2 首歌曲, 得到 %d", count)
+	}
+
+	// 修改存储中的标题，用于验证未变化的文件会直接复用存储记录。
+	for _, song := range repo.songs {
+		song.Title = "cached"
+	}
+	if err := os.Remove(removeFile); err != nil {
+		t.Fatal(err)
+	}
+	addFile := filepath.Join(tmpDir, "add.mp3")
+	if err := os.WriteFile(addFile, []byte("new fake mp3"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	second := NewMusicScannerWithRepository(tmpDir, []string{".mp3"}, 5, repo)
+	if err := second.Restore(); err != nil {
+		t.Fatalf("恢复失败: %v", err)
+	}
+	if second.GetSongCount() != 2 {
+		t.Fatalf("期望恢复 2 首歌曲, 得到 %d", second.GetSongCount())
+	}
+
+	result, err := second.Rescan(context.Background(), ScanModeIncremental)
+	if err != nil {
+		t.Fatalf("对账失败: %v", err)
+	}
+	if result.Added != 1 || result.Removed != 1 || result.Unchanged != 1 || result.Updated != 0 {
+		t.Errorf("扫描统计不符合预期: %+v", result)
+	}
+
+	titles := make(map[string]string)
+	for _, song := range second.GetSongs() {
+		titles[song.FileName] = song.Title
+	}
+	if len(titles) != 2 {
+		t.Fatalf("期望对账后 2 首歌曲, 得到 %d", len(titles))
+	}
+	if titles["keep.mp3"] != "cached" {
+		t.Errorf("期望未变化的文件复用存储记录, 得到标题 %q", titles["keep.mp3"])
+	}
+	if titles["add.mp3"] != "add" {
+		t.Errorf("期望新文件被解析, 得到标题 %q", titles["add.mp3"])
+	}
+	if count, _ := repo.Count(); count != 2 {
+		t.Errorf("期望存储中有 2 首歌曲, 得到 %d", count)
+	}
+}
+
+// TestMusicScanner_RescanModes 测试增量扫描只重新解析变化的文件，而完整扫描会重新解析全部文件。
+func TestMusicScanner_RescanModes(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	stableFile := filepath.Join(tmpDir, "stable.mp3")
+	changedFile := filepath.Join(tmpDir, "changed.mp3")
+	for _, f := range []string{stableFile, changedFile} {
+		if err := os.WriteFile(f, []byte("fake mp3"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scanner := NewMusicScanner(tmpDir, []string{".mp3"}, 5)
+	result, err := scanner.Rescan(context.Background(), ScanModeIncremental)
+	if err != nil {
+		t.Fatalf("扫描失败: %v", err)
+	}
+	if result.Added != 2 {
+		t.Errorf("期望首次扫描新增 2 首歌曲, 得到 %+v", result)
+	}
+
+	// 修改文件内容和修改时间。
+	if err := os.WriteFile(changedFile, []byte("fake mp3 with more bytes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(changedFile, future, future); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err = scanner.Rescan(context.Background(), ScanModeIncremental)
+	if err != nil {
+		t.Fatalf("增量扫描失败: %v", err)
+	}
+	if result.Updated != 1 || result.Unchanged != 1 || result.Added != 0 || result.Removed != 0 {
+		t.Errorf("增量扫描统计不符合预期: %+v", result)
+	}
+
+	result, err = scanner.Rescan(context.Background(), ScanModeFull)
+	if err != nil {
+		t.Fatalf("完整扫描失败: %v", err)
+	}
+	if result.Updated != 2 || result.Unchanged != 0 {
+		t.Errorf("完整扫描统计不符合预期: %+v", result)
+	}
+
+	if last := scanner.LastScanResult(); last == nil || last.Mode != ScanModeFull {
+		t.Errorf("期望 LastScanResult 返回最近一次完整扫描, 得到 %+v", last)
+	}
+}
+
+// TestMusicScanner_ApplyChanges 测试按路径增量更新歌曲索引。
+func TestMusicScanner_ApplyChanges(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	albumDir := filepath.Join(tmpDir, "album")
+	if err := os.MkdirAll(albumDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	oldFile := filepath.Join(albumDir, "old.mp3")
+	if err := os.WriteFile(oldFile, []byte("fake mp3"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	scanner := NewMusicScanner(tmpDir, []string{".mp3"}, 5)
+	if _, err := scanner.Scan(context.Background()); err != nil {
+		t.Fatalf("扫描失败: %v", err)
+	}
+
+	// 嵌套目录中新增文件，并删除旧文件。
+	nestedDir := filepath.Join(tmpDir, "new album", "cd1")
+	if err := os.MkdirAll(nestedDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(nestedDir, "track.mp3"), []byte("fake mp3"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(albumDir); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := scanner.ApplyChanges(context.Background(), []string{filepath.Join(tmpDir, "new album"), albumDir})
+	if err != nil {
+		t.Fatalf("应用变化失败: %v", err)
+	}
+	if result.Added != 1 || result.Removed != 1 {
+		t.Errorf("变更统计不符合预期: %+v", result)
+	}
+
+	songs := scanner.GetSongs()
+	if len(songs) != 1 || songs[0].FileName != "track.mp3" {
+		t.Fatalf("期望索引中只有 track.mp3, 得到 %d 首歌曲", len(songs))
+	}
+	if scanner.GetSongByID(songs[0].ID) == nil {
+		t.Error("新增歌曲应可通过 ID 查找")
+	}
+}
+
+// TestMusicScanner_ParallelScan 测试并发解析元数据时结果完整且保持遍历顺序。
+func TestMusicScanner_ParallelScan(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	const fileCount = 40
+	for i := 0; i < fileCount; i++ {
+		name := filepath.Join(tmpDir, fmt.Sprintf("track%02d.mp3", i))
+		if err := os.WriteFile(name, []byte("fake mp3"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scanner := NewMusicScanner(tmpDir, []string{".mp3"}, 5)
+	scanner.SetConcurrency(4)
+
+	// 扫描期间持续读取，验证读操作不会与扫描产生数据竞争。
+	stop := make(chan struct{})
+	readerDone := make(chan struct{})
+	go func() {
+		defer close(readerDone)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				scanner.GetSongs()
+			}
+		}
+	}()
+
+	result, err := scanner.Rescan(context.Background(), ScanModeFull)
+	close(stop)
+	<-readerDone
+	if err != nil {
+		t.Fatalf("扫描失败: %v", err)
+	}
+	if result.Added != fileCount {
+		t.Errorf("期望新增 %d 首歌曲, 得到 %d", fileCount, result.Added)
+	}
+
+	songs := scanner.GetSongs()
+	if len(songs) != fileCount {
+		t.Fatalf("期望 %d 首歌曲, 得到 %d", fileCount, len(songs))
+	}
+	for i, song := range songs {
+		if want := fmt.Sprintf("track%02d.mp3", i); song.FileName != want {
+			t.Errorf("第 %d 首歌曲期望为 %s, 得到 %s", i, want, song.FileName)
+		}
+	}
+}
+
+// TestMusicScanner_StableIDsAcrossRelocation 测试整体移动音乐目录后歌曲 ID 保持不变且无需重新解析。
+func TestMusicScanner_StableIDsAcrossRelocation(t *testing.T) {
+	oldDir := filepath.Join(t.TempDir(), "music")
+	newDir := filepath.Join(t.TempDir(), "moved")
+	if err := os.MkdirAll(filepath.Join(oldDir, "album"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(oldDir, "album", "song.mp3"), []byte("fake mp3"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := newMemorySongRepository()
+	first := NewMusicScannerWithRepository(oldDir, []string{".mp3"}, 5, repo)
+	songs, err := first.Scan(context.Background())
+	if err != nil || len(songs) != 1 {
+		t.Fatalf("扫描失败: %v, %d 首歌曲", err, len(songs))
+	}
+	originalID := songs[0].ID
+	if songs[0].RelPath != "album/song.mp3" {
+		t.Fatalf("期望相对路径 album/song.mp3, 得到 %s", songs[0].RelPath)
+	}
+
+	if err := os.Rename(oldDir, newDir); err != nil {
+		t.Fatal(err)
+	}
+
+	second := NewMusicScannerWithRepository(newDir, []string{".mp3"}, 5, repo)
+	if err := second.Restore(); err != nil {
+		t.Fatalf("恢复失败: %v", err)
+	}
+	result, err := second.Rescan(context.Background(), ScanModeIncremental)
+	if err != nil {
+		t.Fatalf("扫描失败: %v", err)
+	}
+	if result.Unchanged != 1 || result.Added != 0 || result.Removed != 0 {
+		t.Errorf("期望 1 首未变化, 得到 %+v", result)
+	}
+
+	song := second.GetSongByID(originalID)
+	if song == nil {
+		t.Fatal("移动目录后应该仍能通过原 ID 找到歌曲")
+	}
+	if song.FilePath != filepath.Join(newDir, "album", "song.mp3") {
+		t.Errorf("文件路径应该更新为新位置, 得到 %s", song.FilePath)
+	}
+	if repo.songs[originalID].FilePath != song.FilePath {
+		t.Error("存储中的文件路径应该同步更新")
+	}
+}
+
+// TestMusicScanner_DetectsRenamedFiles 测试按内容指纹识别重命名的文件并为旧 ID 建立别名。
+func TestMusicScanner_DetectsRenamedFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	oldPath := filepath.Join(tmpDir, "old name.mp3")
+	if err := os.WriteFile(oldPath, []byte("renamed content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "other.mp3"), []byte("other content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := newMemorySongRepository()
+	scanner := NewMusicScannerWithRepository(tmpDir, []string{".mp3"}, 5, repo)
+	if _, err := scanner.Rescan(context.Background(), ScanModeIncremental); err != nil {
+		t.Fatalf("扫描失败: %v", err)
+	}
+	oldID := models.LibrarySongID(models.DefaultLibraryName, "old name.mp3")
+	if scanner.GetSongByID(oldID) == nil {
+		t.Fatal("歌曲 ID 应该由相对路径生成")
+	}
+
+	if err := os.MkdirAll(filepath.Join(tmpDir, "sorted"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(oldPath, filepath.Join(tmpDir, "sorted", "new name.mp3")); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := scanner.Rescan(context.Background(), ScanModeIncremental)
+	if err != nil {
+		t.Fatalf("扫描失败: %v", err)
+	}
+	if result.Renamed != 1 {
+		t.Fatalf("期望识别出 1 个重命名, 得到 %+v", result)
+	}
+
+	newID := models.LibrarySongID(models.DefaultLibraryName, "sorted/new name.mp3")
+	if repo.aliases[oldID] != newID {
+		t.Errorf("期望存储记录别名 %s -> %s, 得到 %v", oldID, newID, repo.aliases)
+	}
+	song := scanner.GetSongByID(oldID)
+	if song == nil || song.ID != newID {
+		t.Fatalf("旧 ID 应该通过别名解析到新歌曲, 得到 %+v", song)
+	}
+}
+
+// TestMusicScanner_MigratesLegacyIDs 测试以绝对路径生成 ID 的旧记录在对账时迁移到新 ID。
+func TestMusicScanner_MigratesLegacyIDs(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "legacy.mp3")
+	if err := os.WriteFile(path, []byte("legacy content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	legacy := models.NewSong(path, info.Size())
+	legacy.Title = "cached"
+	repo := newMemorySongRepository()
+	repo.songs[legacy.ID] = legacy
+
+	scanner := NewMusicScannerWithRepository(tmpDir, []string{".mp3"}, 5, repo)
+	if err := scanner.Restore(); err != nil {
+		t.Fatalf("恢复失败: %v", err)
+	}
+	result, err := scanner.Rescan(context.Background(), ScanModeIncremental)
+	if err != nil {
+		t.Fatalf("扫描失败: %v", err)
+	}
+	if result.Unchanged != 1 || result.Renamed != 1 {
+		t.Fatalf("期望复用并迁移 1 首歌曲, 得到 %+v", result)
+	}
+
+	newID := models.LibrarySongID(models.DefaultLibraryName, "legacy.mp3")
+	song := scanner.GetSongByID(legacy.ID)
+	if song == nil || song.ID != newID {
+		t.Fatalf("旧 ID 应该解析到新 ID, 得到 %+v", song)
+	}
+	if song.Title != "cached" {
+		t.Error("未变化的文件不应该重新解析元数据")
+	}
+	if song.ContentHash == "" {
+		t.Error("迁移时应该补充内容指纹")
+	}
+	if _, ok := repo.songs[legacy.ID]; ok {
+		t.Error("存储中的旧记录应该被删除")
+	}
+	if repo.songs[newID] == nil {
+		t.Error("存储中应该写入新 ID 的记录")
+	}
+}
+
+// TestMusicScanner_MultipleLibraries 测试多个音乐库的索引、库名标记以及库暂时不可访问时保留原有歌曲。
+func TestMusicScanner_MultipleLibraries(t *testing.T) {
+	lossless := t.TempDir()
+	lossy := t.TempDir()
+	for _, dir := range []string{lossless, lossy} {
+		if err := os.WriteFile(filepath.Join(dir, "track.mp3"), []byte("fake mp3 "+dir), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scanner := NewLibraryScanner([]Library{
+		{Name: "lossless", Directory: lossless},
+		{Name: "lossy", Directory: lossy},
+	}, []string{".mp3"}, 5, nil)
+
+	songs, err := scanner.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("扫描失败: %v", err)
+	}
+	if len(songs) != 2 {
+		t.Fatalf("期望 2 首歌曲, 得到 %d", len(songs))
+	}
+	if songs[0].Library != "lossless" || songs[1].Library != "lossy" {
+		t.Errorf("歌曲应该标记所属的音乐库, 得到 %s, %s", songs[0].Library, songs[1].Library)
+	}
+	if songs[0].ID == songs[1].ID {
+		t.Error("不同库中相同相对路径的歌曲 ID 不应该冲突")
+	}
+
+	// 模拟磁盘被卸载：该库中的歌曲应该保留，而不是被当作已删除
+	if err := os.RemoveAll(lossy); err != nil {
+		t.Fatal(err)
+	}
+	result, err := scanner.Rescan(context.Background(), ScanModeIncremental)
+	if err != nil {
+		t.Fatalf("部分音乐库不可访问时扫描不应该失败: %v", err)
+	}
+	if result.Removed != 0 || scanner.GetSongCount() != 2 {
+		t.Errorf("期望保留不可访问库中的歌曲, 得到 %+v, 共 %d 首", result, scanner.GetSongCount())
+	}
+
+	if err := os.RemoveAll(lossless); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := scanner.Rescan(context.Background(), ScanModeIncremental); err == nil {
+		t.Error("所有音乐库都不可访问时应该返回错误")
+	}
+}
+
+// TestMusicScanner_SidecarFiles 测试扫描时发现目录中的封面文件、艺术家图片和同名歌词文件，并跟随它们的增删更新。
+func TestMusicScanner_SidecarFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	artistDir := filepath.Join(tmpDir, "Artist")
+	albumDir := filepath.Join(artistDir, "Album")
+	if err := os.MkdirAll(albumDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		filepath.Join(albumDir, "01.mp3"):      "fake mp3",
+		filepath.Join(albumDir, "Folder.PNG"):  "png",
+		filepath.Join(albumDir, "notes.txt"):   "text",
+		filepath.Join(albumDir, "01.LRC"):      "[00:01.00]line",
+		filepath.Join(albumDir, "02.lrc"):      "[00:01.00]other",
+		filepath.Join(artistDir, "artist.jpg"): "jpg",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scanner := NewMusicScanner(tmpDir, []string{".mp3"}, 5)
+	scanner.SetArtworkPatterns([]string{"cover.*", "folder.*"}, []string{"artist.*"})
+	if _, err := scanner.Rescan(context.Background(), ScanModeIncremental); err != nil {
+		t.Fatalf("扫描失败: %v", err)
+	}
+
+	song := scanner.GetSongs()[0]
+	if !song.HasCover || song.CoverSource != models.CoverSourceSidecar || song.CoverPath != filepath.Join(albumDir, "Folder.PNG") {
+		t.Errorf("期望使用目录中的 Folder.PNG 作为封面, 得到 %+v", song)
+	}
+	if song.ArtistImagePath != filepath.Join(artistDir, "artist.jpg") {
+		t.Errorf("期望找到艺术家图片, 得到 %q", song.ArtistImagePath)
+	}
+	if !song.HasLyrics || song.LyricsPath != filepath.Join(albumDir, "01.LRC") {
+		t.Errorf("期望找到同名歌词文件, 得到 %q", song.LyricsPath)
+	}
+
+	// 新增优先级更高的封面文件后，未变化的歌曲也会更新
+	coverPath := filepath.Join(albumDir, "cover.jpg")
+	if err := os.WriteFile(coverPath, []byte("jpg"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := scanner.ApplyChanges(context.Background(), []string{coverPath}); err != nil {
+		t.Fatalf("应用变化失败: %v", err)
+	}
+	if song = scanner.GetSongs()[0]; song.CoverPath != coverPath {
+		t.Errorf("期望封面更新为 cover.jpg, 得到 %q", song.CoverPath)
+	}
+
+	// 删除全部封面和歌词文件后不再有封面和歌词
+	for _, name := range []string{"cover.jpg", "Folder.PNG", "01.LRC"} {
+		if err := os.Remove(filepath.Join(albumDir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := scanner.Rescan(context.Background(), ScanModeIncremental); err != nil {
+		t.Fatalf("扫描失败: %v", err)
+	}
+	if song = scanner.GetSongs()[0]; song.HasCover || song.CoverSource != "" || song.CoverPath != "" || song.HasLyrics || song.LyricsPath != "" {
+		t.Errorf("期望封面和歌词被清除, 得到 %+v", song)
+	}
+}
+
+// writeID3Song 写入一个带 ID3v2.3 文本帧（UTF-8 编码）的 MP3 文件。
+func writeID3Song(t *testing.T, path string, frames map[string]string) {
+	t.Helper()
+	var body []byte
+	for id, text := range frames {
+		data := append([]byte{3}, text...)
+		size := len(data)
+		body = append(body, id...)
+		body = append(body, byte(size>>24), byte(size>>16), byte(size>>8), byte(size), 0, 0)
+		body = append(body, data...)
+	}
+	size := len(body)
+	header := []byte{'I', 'D', '3', 3, 0, 0, byte(size >> 21 & 0x7f), byte(size >> 14 & 0x7f), byte(size >> 7 & 0x7f), byte(size & 0x7f)}
+	data := append(append(header, body...), make([]byte, 1024)...)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestMusicScanner_ArtistFields 测试读取专辑艺术家和作曲者，并按配置的分隔符拆分艺术家。
+func TestMusicScanner_ArtistFields(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeID3Song(t, filepath.Join(tmpDir, "a.mp3"), map[string]string{
+		"TIT2": "Duet",
+		"TPE1": "A feat. B; C",
+		"TPE2": "Various Artists",
+		"TCOM": "Composer",
+	})
+
+	repo := newMemorySongRepository()
+	scanner := NewMusicScannerWithRepository(tmpDir, []string{".mp3"}, 5, repo)
+	scanner.SetArtistSeparators([]string{";", "feat."})
+	songs, err := scanner.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("扫描失败: %v", err)
+	}
+	if len(songs) != 1 {
+		t.Fatalf("期望 1 首歌曲, 得到 %d", len(songs))
+	}
+	song := songs[0]
+	if got := fmt.Sprint(song.Artists); got != "[A B C]" {
+		t.Errorf("期望艺术家 [A B C], 得到 %s", got)
+	}
+	if song.AlbumArtist != "Various Artists" || song.Composer != "Composer" || song.Artist != "A feat. B; C" {
+		t.Errorf("艺术家字段不符合预期: %+v", song)
+	}
+
+	// 修改分隔符后，文件未变化的歌曲也会重新拆分并写入存储
+	scanner.SetArtistSeparators([]string{";"})
+	if _, err := scanner.Rescan(context.Background(), ScanModeIncremental); err != nil {
+		t.Fatalf("增量扫描失败: %v", err)
+	}
+	if got := fmt.Sprint(scanner.GetSongByID(song.ID).Artists); got != "[A feat. B C]" {
+		t.Errorf("期望艺术家 [A feat. B C], 得到 %s", got)
+	}
+	if got := fmt.Sprint(repo.songs[song.ID].Artists); got != "[A feat. B C]" {
+		t.Errorf("期望存储中的艺术家同步更新, 得到 %s", got)
+	}
+}
+
+// TestMusicScanner_CueSheets 测试按 CUE 文件将整轨文件拆分为曲目，以及 CUE 文件变化时重新拆分。
+func TestMusicScanner_CueSheets(t *testing.T) {
+	tmpDir := t.TempDir()
+	imagePath := filepath.Join(tmpDir, "Album.wav")
+	if err := os.WriteFile(imagePath, encodeTestWAV(44100, 2, 44100*3, sineWave(44100, 440, -20)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cuePath := filepath.Join(tmpDir, "Album.cue")
+	cue := "PERFORMER \"Artist\"\nTITLE \"Album\"\nFILE \"Album.wav\" WAVE\n" +
+		"  TRACK 01 AUDIO\n    TITLE \"One\"\n    INDEX 01 00:00:00\n" +
+		"  TRACK 02 AUDIO\n    TITLE \"Two\"\n    INDEX 01 00:01:00\n"
+	if err := os.WriteFile(cuePath, []byte(cue), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := newMemorySongRepository()
+	scanner := NewLibraryScanner([]Library{{Name: models.DefaultLibraryName, Directory: tmpDir}}, []string{".wav"}, 5, repo)
+	if _, err := scanner.Rescan(context.Background(), ScanModeIncremental); err != nil {
+		t.Fatalf("扫描失败: %v", err)
+	}
+	songs := scanner.GetSongs()
+	if len(songs) != 2 || songs[0].Title != "One" || songs[1].Title != "Two" {
+		t.Fatalf("期望拆分为两个曲目, 得到 %+v", songs)
+	}
+	if !songs[1].IsCueTrack() || songs[1].StartMs != 1000 || songs[1].Album != "Album" || songs[1].Artist != "Artist" ||
+		len(songs[1].Artists) != 1 {
+		t.Errorf("曲目信息不符合预期: %+v", songs[1])
+	}
+	if scanner.GetSongByID(models.LibrarySongID(models.DefaultLibraryName, "Album.wav")) != nil || len(repo.songs) != 2 {
+		t.Errorf("整轨文件本身不应出现在歌曲库中, 存储中有 %d 首歌曲", len(repo.songs))
+	}
+
+	// 未变化时不重复写入存储
+	repo.songs = make(map[string]*models.Song)
+	result, err := scanner.Rescan(context.Background(), ScanModeIncremental)
+	if err != nil {
+		t.Fatalf("扫描失败: %v", err)
+	}
+	if result.Unchanged != 1 || result.Removed != 0 || len(repo.songs) != 0 || scanner.GetSongCount() != 2 {
+		t.Errorf("期望未变化, 得到 %+v, 写入 %d 首歌曲", result, len(repo.songs))
+	}
+
+	// 修改 CUE 文件后曲目随之更新，删除后恢复为整轨文件
+	if err := os.WriteFile(cuePath, []byte(strings.Replace(cue, "\"Two\"", "\"Deux\"", 1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := scanner.ApplyChanges(context.Background(), []string{cuePath}); err != nil {
+		t.Fatalf("应用变化失败: %v", err)
+	}
+	if songs = scanner.GetSongs(); len(songs) != 2 || songs[1].Title != "Deux" || len(repo.songs) != 1 {
+		t.Fatalf("期望第二个曲目更新为 Deux, 得到 %d 首歌曲, 写入 %d 首", len(songs), len(repo.songs))
+	}
+	if err := os.Remove(cuePath); err != nil {
+		t.Fatal(err)
+	}
+	result, err = scanner.ApplyChanges(context.Background(), []string{cuePath})
+	if err != nil {
+		t.Fatalf("应用变化失败: %v", err)
+	}
+	if songs = scanner.GetSongs(); len(songs) != 1 || songs[0].IsCueTrack() || result.Removed != 2 {
+		t.Errorf("期望恢复为整轨文件, 得到 %d 首歌曲, %+v", len(songs), result)
+	}
+}
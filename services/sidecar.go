@@ -0,0 +1,264 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"zero-music/logger"
+	"zero-music/models"
+)
+
+// imageExtensions 是可以作为封面或艺术家图片的文件扩展名。
+var imageExtensions = map[string]bool{
+	".jpg":  true,
+	".jpeg": true,
+	".png":  true,
+	".gif":  true,
+}
+
+// lyricsExtension 是歌词文件的扩展名。
+const lyricsExtension = ".lrc"
+
+// cueExtension 是 CUE 文件的扩展名。
+const cueExtension = ".cue"
+
+// isImageFile 判断路径是否为图片文件。
+func isImageFile(path string) bool {
+	return imageExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// isSidecarFile 判断路径是否为可能影响歌曲信息的附属文件（封面、艺术家图片、歌词或 CUE 文件）。
+func isSidecarFile(path string) bool {
+	ext := filepath.Ext(path)
+	return isImageFile(path) || strings.EqualFold(ext, lyricsExtension) || strings.EqualFold(ext, cueExtension)
+}
+
+// sidecars 是歌曲从附属文件中获得的信息：封面、艺术家图片和歌词。
+type sidecars struct {
+	hasCover        bool
+	coverSource     string
+	coverPath       string
+	artistImagePath string
+	hasLyrics       bool
+	lyricsPath      string
+}
+
+// songSidecars 返回歌曲当前记录的附属文件信息。
+func songSidecars(song *models.Song) sidecars {
+	return sidecars{
+		hasCover:        song.HasCover,
+		coverSource:     song.CoverSource,
+		coverPath:       song.CoverPath,
+		artistImagePath: song.ArtistImagePath,
+		hasLyrics:       song.HasLyrics,
+		lyricsPath:      song.LyricsPath,
+	}
+}
+
+// applyTo 将附属文件信息写入歌曲。
+func (a sidecars) applyTo(song *models.Song) {
+	song.HasCover = a.hasCover
+	song.CoverSource = a.coverSource
+	song.CoverPath = a.coverPath
+	song.ArtistImagePath = a.artistImagePath
+	song.HasLyrics = a.hasLyrics
+	song.LyricsPath = a.lyricsPath
+}
+
+// sidecarFinder 在歌曲所在目录中查找封面文件、同名的 .lrc 歌词文件和 CUE 文件，并在艺术家目录中查找艺术家图片。
+// 每个目录在一次扫描中只读取一次，可以被多个协程并发使用。
+type sidecarFinder struct {
+	coverPatterns  []string
+	artistPatterns []string
+	mu             sync.Mutex
+	dirs           map[string][]string         // 目录 -> 其中的文件名
+	sheets         map[string]*models.CueSheet // CUE 文件路径 -> 解析结果，无效的文件为 nil
+}
+
+// newSidecarFinder 创建一个使用给定文件名模式的 sidecarFinder，模式不区分大小写。
+func newSidecarFinder(coverPatterns, artistPatterns []string) *sidecarFinder {
+	return &sidecarFinder{
+		coverPatterns:  lowerAll(coverPatterns),
+		artistPatterns: lowerAll(artistPatterns),
+		dirs:           make(map[string][]string),
+		sheets:         make(map[string]*models.CueSheet),
+	}
+}
+
+func lowerAll(values []string) []string {
+	lowered := make([]string, len(values))
+	for i, v := range values {
+		lowered[i] = strings.ToLower(v)
+	}
+	return lowered
+}
+
+// refresh 重新查找条目对应歌曲的附属文件，有变化时更新歌曲并标记为需要写入存储。
+// 嵌入的封面优先于目录中的封面文件；同名的 .lrc 歌词文件优先于嵌入的歌词。
+func (f *sidecarFinder) refresh(entry *scanEntry) {
+	if entry.song == nil {
+		return
+	}
+	found := f.lookup(entry.song, entry.root)
+	if found == songSidecars(entry.song) {
+		return
+	}
+	found.applyTo(entry.mutable())
+}
+
+// lookup 返回歌曲应有的附属文件信息。
+func (f *sidecarFinder) lookup(song *models.Song, root string) sidecars {
+	dir := filepath.Dir(song.FilePath)
+	found := sidecars{
+		artistImagePath: f.findArtistImage(dir, root),
+		lyricsPath:      f.findLyrics(song.FilePath),
+	}
+	found.hasLyrics = song.EmbeddedLyrics || found.lyricsPath != ""
+
+	// 早期版本的记录没有封面来源，其封面只可能是嵌入的
+	if song.CoverSource == models.CoverSourceEmbedded || (song.HasCover && song.CoverSource == "") {
+		found.hasCover = true
+		found.coverSource = models.CoverSourceEmbedded
+		return found
+	}
+	if path := f.match(dir, f.coverPatterns); path != "" {
+		found.hasCover = true
+		found.coverSource = models.CoverSourceSidecar
+		found.coverPath = path
+	}
+	return found
+}
+
+// findArtistImage 依次在歌曲所在目录（艺术家/歌曲）和其上级目录（艺术家/专辑/歌曲）中查找艺术家图片，
+// 不会越过音乐库根目录。
+func (f *sidecarFinder) findArtistImage(dir, root string) string {
+	if path := f.match(dir, f.artistPatterns); path != "" {
+		return path
+	}
+	if dir == root || root == "" {
+		return ""
+	}
+	return f.match(filepath.Dir(dir), f.artistPatterns)
+}
+
+// findLyrics 返回与音频文件同名（不区分大小写）的 .lrc 歌词文件路径，没有时返回空字符串。
+func (f *sidecarFinder) findLyrics(audioPath string) string {
+	dir, base := filepath.Split(audioPath)
+	want := strings.TrimSuffix(base, filepath.Ext(base)) + lyricsExtension
+	for _, name := range f.files(filepath.Clean(dir)) {
+		if strings.EqualFold(name, want) {
+			return filepath.Join(dir, name)
+		}
+	}
+	return ""
+}
+
+// splitCue 查找描述条目对应整轨文件的 CUE 文件，找到时将歌曲拆分为其中的各个曲目。
+// 只拆分能够按曲目截取播放的格式，只有一个曲目的 CUE 文件不拆分。
+func (f *sidecarFinder) splitCue(entry *scanEntry) {
+	entry.tracks = nil
+	if entry.song == nil || !canSplitCue(entry.song.FilePath) {
+		return
+	}
+	cuePath, sheet, file := f.findCue(entry.song.FilePath)
+	if file == nil || len(file.Tracks) < 2 {
+		return
+	}
+	entry.tracks = models.CueTrackSongs(entry.song, cuePath, sheet, file)
+}
+
+// findCue 返回目录中引用了音频文件的 CUE 文件及其中对应的 FILE 段，与音频文件同名的 CUE 文件优先。
+func (f *sidecarFinder) findCue(audioPath string) (string, *models.CueSheet, *models.CueFile) {
+	dir, base := filepath.Split(audioPath)
+	dir = filepath.Clean(dir)
+	stem := strings.TrimSuffix(base, filepath.Ext(base))
+
+	var candidates []string
+	for _, name := range f.files(dir) {
+		if !strings.EqualFold(filepath.Ext(name), cueExtension) {
+			continue
+		}
+		cueStem := strings.TrimSuffix(name, filepath.Ext(name))
+		if strings.EqualFold(cueStem, stem) || strings.EqualFold(cueStem, base) {
+			candidates = append([]string{name}, candidates...)
+		} else {
+			candidates = append(candidates, name)
+		}
+	}
+	for _, name := range candidates {
+		path := filepath.Join(dir, name)
+		sheet := f.cueSheet(path)
+		if sheet == nil {
+			continue
+		}
+		if file := sheet.MatchFile(base); file != nil {
+			return path, sheet, file
+		}
+	}
+	return "", nil, nil
+}
+
+// cueSheet 返回解析后的 CUE 文件，文件无法读取或无效时返回 nil。每个文件在一次扫描中只解析一次。
+func (f *sidecarFinder) cueSheet(path string) *models.CueSheet {
+	f.mu.Lock()
+	sheet, ok := f.sheets[path]
+	f.mu.Unlock()
+	if ok {
+		return sheet
+	}
+
+	data, err := os.ReadFile(path)
+	if err == nil {
+		sheet, err = models.ParseCueSheet(data)
+	}
+	if err != nil {
+		logger.Warnf("读取 CUE 文件失败 %s: %v", path, err)
+	}
+
+	f.mu.Lock()
+	f.sheets[path] = sheet
+	f.mu.Unlock()
+	return sheet
+}
+
+// match 按模式的优先级返回目录中第一个匹配的图片路径，没有匹配时返回空字符串。
+func (f *sidecarFinder) match(dir string, patterns []string) string {
+	if len(patterns) == 0 {
+		return ""
+	}
+	names := f.files(dir)
+	for _, pattern := range patterns {
+		for _, name := range names {
+			if !isImageFile(name) {
+				continue
+			}
+			if ok, _ := filepath.Match(pattern, strings.ToLower(name)); ok {
+				return filepath.Join(dir, name)
+			}
+		}
+	}
+	return ""
+}
+
+// files 返回目录中按名称排序的附属文件名，目录无法读取时返回空列表。
+func (f *sidecarFinder) files(dir string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if names, ok := f.dirs[dir]; ok {
+		return names
+	}
+	var names []string
+	if entries, err := os.ReadDir(dir); err == nil {
+		for _, entry := range entries {
+			if !entry.IsDir() && isSidecarFile(entry.Name()) {
+				names = append(names, entry.Name())
+			}
+		}
+	}
+	sort.Strings(names)
+	f.dirs[dir] = names
+	return names
+}
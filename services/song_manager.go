@@ -0,0 +1,365 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"zero-music/logger"
+	"zero-music/models"
+	"zero-music/repository"
+)
+
+var (
+	// ErrCueTrackFile 表示歌曲是 CUE 曲目，与同一张专辑的其他曲目共用一个音频文件。
+	ErrCueTrackFile = errors.New("CUE 曲目与其他曲目共用音频文件，不能单独移动或删除")
+	// ErrInvalidSongPath 表示移动的目标路径无效。
+	ErrInvalidSongPath = errors.New("目标路径无效")
+	// ErrSongPathExists 表示移动的目标路径上已经有文件。
+	ErrSongPathExists = errors.New("目标路径已存在文件")
+)
+
+// SongManager 通过 API 移动和删除音乐库中的歌曲文件。
+// 移动后歌曲的 ID 随路径变化，旧 ID 作为永久别名继续可用，收藏、播放列表等用户数据迁移到新 ID；
+// 删除的文件移入回收站，用户数据中对歌曲的引用在同一个事务中清理，回收站中的文件保留期结束后被彻底删除。
+type SongManager struct {
+	library    *MusicScanner
+	trash      repository.TrashRepository
+	uploads    repository.UploadRepository
+	duplicates *DuplicateService
+	directory  string
+	retention  time.Duration
+	mu         sync.Mutex // 串行化文件操作，避免同时占用同一路径
+
+	lifecycle sync.Mutex
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+// NewSongManager 创建一个新的 SongManager 实例，删除的文件移入 directory，保留 retention 后彻底删除。
+func NewSongManager(library *MusicScanner, trash repository.TrashRepository, uploads repository.UploadRepository, duplicates *DuplicateService, directory string, retention time.Duration) (*SongManager, error) {
+	if err := os.MkdirAll(directory, 0o755); err != nil {
+		return nil, fmt.Errorf("创建回收站目录失败: %w", err)
+	}
+	return &SongManager{
+		library:    library,
+		trash:      trash,
+		uploads:    uploads,
+		duplicates: duplicates,
+		directory:  directory,
+		retention:  retention,
+	}, nil
+}
+
+// Move 将歌曲移动到名为 libraryName 的音乐库（为空时为歌曲所在的音乐库）中的 relPath，扩展名不能改变。
+// 同名的 .lrc 歌词文件随歌曲一起移动。返回移动后的歌曲，其 ID 由新路径生成，旧 ID 作为永久别名继续可用。
+func (m *SongManager) Move(ctx context.Context, id, libraryName, relPath string) (*models.Song, error) {
+	// 持有锁后再查找歌曲，避免并发的移动或删除已经改变了歌曲的位置
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	song := m.library.GetSongByID(id)
+	if song == nil {
+		return nil, ErrSongNotFound
+	}
+	if song.IsCueTrack() {
+		return nil, ErrCueTrackFile
+	}
+	if libraryName == "" {
+		libraryName = songLibrary(song)
+	}
+	target, ok := m.findLibrary(libraryName)
+	if !ok {
+		return nil, fmt.Errorf("%w: 音乐库不存在: %s", ErrInvalidSongPath, libraryName)
+	}
+	relPath, err := cleanSongPath(relPath, filepath.Ext(song.FilePath))
+	if err != nil {
+		return nil, err
+	}
+	dest := filepath.Join(target.Directory, filepath.FromSlash(relPath))
+	if dest == song.FilePath {
+		return song, nil
+	}
+
+	if _, err := os.Lstat(dest); err == nil {
+		return nil, ErrSongPathExists
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("检查目标文件失败: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return nil, fmt.Errorf("创建目录失败: %w", err)
+	}
+	if err := moveFile(song.FilePath, dest); err != nil {
+		return nil, fmt.Errorf("移动文件失败: %w", err)
+	}
+	// 上传记录在目标位置冲突时撤销移动，不覆盖其他用户的记录
+	if err := m.uploads.UpdateLocation(songLibrary(song), song.RelPath, target.Name, relPath); err != nil {
+		if restoreErr := moveFile(dest, song.FilePath); restoreErr != nil {
+			logger.Errorf("撤销移动文件失败 %s: %v", song.FilePath, restoreErr)
+		}
+		removeEmptyDirs(filepath.Dir(dest), target.Directory)
+		return nil, fmt.Errorf("更新上传记录的位置失败: %w", err)
+	}
+	changed := []string{song.FilePath, dest}
+	if song.LyricsPath != "" {
+		lyricsDest := strings.TrimSuffix(dest, filepath.Ext(dest)) + lyricsExtension
+		if err := moveFile(song.LyricsPath, lyricsDest); err != nil {
+			logger.Warnf("移动歌词文件失败 %s: %v", song.LyricsPath, err)
+		} else {
+			changed = append(changed, song.LyricsPath, lyricsDest)
+		}
+	}
+	if lib := m.library.libraryFor(song.FilePath); lib != nil {
+		removeEmptyDirs(filepath.Dir(song.FilePath), lib.Directory)
+	}
+
+	if _, err := m.library.ApplyChanges(ctx, changed); err != nil {
+		return nil, fmt.Errorf("更新歌曲库失败: %w", err)
+	}
+	newID := models.LibrarySongID(target.Name, relPath)
+	m.library.recordMove(song.ID, newID)
+	// 重复歌曲首选项已随歌曲 ID 迁移，重新加载内存中的隐藏列表
+	if err := m.duplicates.Load(); err != nil {
+		logger.Warn(err)
+	}
+
+	logger.Infof("已将歌曲 %s 移动到 %s:%s", song.ID, target.Name, relPath)
+	moved := m.library.GetSongByID(newID)
+	if moved == nil {
+		return nil, ErrSongNotFound
+	}
+	return moved, nil
+}
+
+// Delete 将歌曲文件（以及同名的 .lrc 歌词文件）移入回收站，记录墓碑并清理用户数据中对歌曲的引用。
+// userID 是执行删除的管理员。
+func (m *SongManager) Delete(ctx context.Context, id string, userID int64) (*models.TrashedSong, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	song := m.library.GetSongByID(id)
+	if song == nil {
+		return nil, ErrSongNotFound
+	}
+	if song.IsCueTrack() {
+		return nil, ErrCueTrackFile
+	}
+
+	// 每首删除的歌曲在回收站中有单独的目录，同名文件不会互相覆盖
+	entryDir := fmt.Sprintf("%d-%s", time.Now().UnixNano(), song.ID)
+	trashPath := path.Join(entryDir, filepath.Base(song.FilePath))
+	dest := filepath.Join(m.directory, filepath.FromSlash(trashPath))
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return nil, fmt.Errorf("创建回收站目录失败: %w", err)
+	}
+	if err := moveFile(song.FilePath, dest); err != nil {
+		os.Remove(filepath.Dir(dest))
+		return nil, fmt.Errorf("移动文件到回收站失败: %w", err)
+	}
+
+	entry := &models.TrashedSong{
+		SongID:    song.ID,
+		Library:   songLibrary(song),
+		RelPath:   song.RelPath,
+		Title:     song.Title,
+		Artist:    song.Artist,
+		Album:     song.Album,
+		Size:      song.FileSize,
+		TrashPath: trashPath,
+		DeletedBy: userID,
+	}
+	if err := m.trash.Trash(entry); err != nil {
+		if restoreErr := moveFile(dest, song.FilePath); restoreErr != nil {
+			logger.Errorf("从回收站恢复文件失败 %s: %v", song.FilePath, restoreErr)
+		}
+		os.Remove(filepath.Dir(dest))
+		return nil, fmt.Errorf("记录删除的歌曲失败: %w", err)
+	}
+
+	changed := []string{song.FilePath}
+	if song.LyricsPath != "" {
+		lyricsDest := strings.TrimSuffix(dest, filepath.Ext(dest)) + lyricsExtension
+		if err := moveFile(song.LyricsPath, lyricsDest); err != nil {
+			logger.Warnf("移动歌词文件到回收站失败 %s: %v", song.LyricsPath, err)
+		} else {
+			changed = append(changed, song.LyricsPath)
+		}
+	}
+	if lib := m.library.libraryFor(song.FilePath); lib != nil {
+		removeEmptyDirs(filepath.Dir(song.FilePath), lib.Directory)
+	}
+
+	// 数据库中的引用已经清理，同步内存中的元数据覆盖和重复歌曲首选项
+	m.library.SetMetadataOverride(song.ID, nil)
+	if err := m.duplicates.Load(); err != nil {
+		logger.Warn(err)
+	}
+	if _, err := m.library.ApplyChanges(ctx, changed); err != nil {
+		return nil, fmt.Errorf("更新歌曲库失败: %w", err)
+	}
+
+	logger.Infof("用户 %d 删除了歌曲 %s (%s)，文件已移入回收站", userID, song.ID, song.RelPath)
+	return entry, nil
+}
+
+// Trash 返回仍在回收站中的歌曲。
+func (m *SongManager) Trash() ([]*models.TrashedSong, error) {
+	entries, err := m.trash.List()
+	if err != nil {
+		return nil, fmt.Errorf("读取回收站失败: %w", err)
+	}
+	return entries, nil
+}
+
+// Purge 彻底删除在 now 之前已超过保留期的回收站文件，墓碑记录保留，返回删除的歌曲数量。
+func (m *SongManager) Purge(now time.Time) (int, error) {
+	entries, err := m.trash.ListDeletedBefore(now.Add(-m.retention))
+	if err != nil {
+		return 0, fmt.Errorf("读取回收站失败: %w", err)
+	}
+	purged := 0
+	for _, entry := range entries {
+		dir := filepath.Join(m.directory, filepath.FromSlash(path.Dir(entry.TrashPath)))
+		if err := os.RemoveAll(dir); err != nil {
+			logger.Warnf("删除回收站文件失败 %s: %v", dir, err)
+			continue
+		}
+		if err := m.trash.MarkPurged(entry.ID); err != nil {
+			logger.Warnf("更新回收站记录失败 %d: %v", entry.ID, err)
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// Start 启动后台任务，每隔 interval 清理一次超过保留期的回收站文件。
+func (m *SongManager) Start(interval time.Duration) {
+	m.lifecycle.Lock()
+	defer m.lifecycle.Unlock()
+	if m.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	m.cancel = cancel
+	m.done = done
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if purged, err := m.Purge(time.Now()); err != nil {
+				logger.Warn(err)
+			} else if purged > 0 {
+				logger.Infof("已从回收站中彻底删除 %d 首歌曲", purged)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop 停止清理回收站的后台任务并等待其退出。
+func (m *SongManager) Stop() {
+	m.lifecycle.Lock()
+	cancel, done := m.cancel, m.done
+	m.cancel, m.done = nil, nil
+	m.lifecycle.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+// findLibrary 根据名称查找音乐库。
+func (m *SongManager) findLibrary(name string) (Library, bool) {
+	for _, lib := range m.library.Libraries() {
+		if lib.Name == name {
+			return lib, true
+		}
+	}
+	return Library{}, false
+}
+
+// cleanSongPath 规范化音乐库内的相对路径（使用 / 分隔），拒绝空路径、绝对路径、指向库外的路径和改变扩展名的路径。
+func cleanSongPath(relPath, ext string) (string, error) {
+	relPath = strings.TrimSpace(strings.ReplaceAll(relPath, `\`, "/"))
+	if relPath == "" || path.IsAbs(relPath) || filepath.IsAbs(relPath) {
+		return "", fmt.Errorf("%w: 必须是音乐库内的相对路径", ErrInvalidSongPath)
+	}
+	relPath = path.Clean(relPath)
+	if relPath == "." || relPath == ".." || strings.HasPrefix(relPath, "../") {
+		return "", fmt.Errorf("%w: 必须是音乐库内的相对路径", ErrInvalidSongPath)
+	}
+	for _, segment := range strings.Split(relPath, "/") {
+		if !models.ValidPathSegment(segment) {
+			return "", fmt.Errorf("%w: 文件名中包含不允许的字符: %q", ErrInvalidSongPath, segment)
+		}
+	}
+	if !strings.EqualFold(path.Ext(relPath), ext) {
+		return "", fmt.Errorf("%w: 不能修改文件的扩展名 %s", ErrInvalidSongPath, ext)
+	}
+	return relPath, nil
+}
+
+// moveFile 移动文件。源和目标不在同一文件系统上时（如移动到另一块磁盘上的音乐库或回收站），复制后删除源文件。
+func moveFile(src, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	if err := copyFile(src, dst); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	return os.Remove(src)
+}
+
+// copyFile 复制文件内容，保留权限和修改时间。目标文件已存在时返回错误。
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+// removeEmptyDirs 从 dir 开始逐级向上删除空目录，直到遇到非空目录或 root。
+func removeEmptyDirs(dir, root string) {
+	for dir != root && strings.HasPrefix(dir, root+string(filepath.Separator)) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
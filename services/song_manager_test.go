@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"zero-music/models"
+)
+
+// memoryTrashRepository 是保存在内存中的 TrashRepository 实现。
+type memoryTrashRepository struct {
+	entries []*models.TrashedSong
+}
+
+func (r *memoryTrashRepository) Trash(entry *models.TrashedSong) error {
+	entry.ID = int64(len(r.entries) + 1)
+	entry.DeletedAt = time.Now()
+	stored := *entry
+	r.entries = append(r.entries, &stored)
+	return nil
+}
+
+func (r *memoryTrashRepository) List() ([]*models.TrashedSong, error) {
+	return r.ListDeletedBefore(time.Now().Add(time.Hour))
+}
+
+func (r *memoryTrashRepository) ListDeletedBefore(before time.Time) ([]*models.TrashedSong, error) {
+	var entries []*models.TrashedSong
+	for _, entry := range r.entries {
+		if entry.PurgedAt == nil && entry.DeletedAt.Before(before) {
+			found := *entry
+			entries = append(entries, &found)
+		}
+	}
+	return entries, nil
+}
+
+func (r *memoryTrashRepository) MarkPurged(id int64) error {
+	now := time.Now()
+	r.entries[id-1].PurgedAt = &now
+	return nil
+}
+
+func newTestSongManager(t *testing.T) (*SongManager, *MusicScanner, *memoryTrashRepository, string) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tmpDir, "Artist"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeID3Song(t, filepath.Join(tmpDir, "Artist", "song.mp3"), map[string]string{"TIT2": "Song", "TPE1": "Artist"})
+	if err := os.WriteFile(filepath.Join(tmpDir, "Artist", "song.lrc"), []byte("[00:01.00]la"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	scanner := NewMusicScannerWithRepository(tmpDir, []string{".mp3"}, 5, newMemorySongRepository())
+	if _, err := scanner.Scan(context.Background()); err != nil {
+		t.Fatalf("扫描失败: %v", err)
+	}
+	trash := &memoryTrashRepository{}
+	manager, err := NewSongManager(scanner, trash, newMemoryUploadRepository(), NewDuplicateService(scanner, newMemoryDuplicateRepository()),
+		t.TempDir(), 24*time.Hour)
+	if err != nil {
+		t.Fatalf("创建歌曲管理服务失败: %v", err)
+	}
+	return manager, scanner, trash, tmpDir
+}
+
+// TestSongManager_Move 测试移动歌曲后文件和歌词位于新路径，旧 ID 作为永久别名仍然可用。
+func TestSongManager_Move(t *testing.T) {
+	manager, scanner, _, tmpDir := newTestSongManager(t)
+	oldID := models.LibrarySongID(models.DefaultLibraryName, "Artist/song.mp3")
+
+	song, err := manager.Move(context.Background(), oldID, "", "Other/Album/renamed.mp3")
+	if err != nil {
+		t.Fatalf("移动失败: %v", err)
+	}
+	newID := models.LibrarySongID(models.DefaultLibraryName, "Other/Album/renamed.mp3")
+	if song.ID != newID || song.Title != "Song" {
+		t.Errorf("期望移动后的歌曲 ID 为 %s, 得到 %+v", newID, song)
+	}
+	if found := scanner.GetSongByID(oldID); found == nil || found.ID != newID {
+		t.Errorf("期望旧 ID 解析到移动后的歌曲, 得到 %+v", found)
+	}
+	for _, name := range []string{"Other/Album/renamed.mp3", "Other/Album/renamed.lrc"} {
+		if _, err := os.Stat(filepath.Join(tmpDir, filepath.FromSlash(name))); err != nil {
+			t.Errorf("期望文件 %s 存在: %v", name, err)
+		}
+	}
+	// 原来的空目录被删除
+	if _, err := os.Stat(filepath.Join(tmpDir, "Artist")); !os.IsNotExist(err) {
+		t.Errorf("期望空目录被删除, 得到 %v", err)
+	}
+	if scanner.GetSongCount() != 1 {
+		t.Errorf("期望歌曲库中有 1 首歌曲, 得到 %d", scanner.GetSongCount())
+	}
+
+	// 别名过渡期结束后，移动产生的别名仍然保留
+	restored := NewMusicScannerWithRepository(tmpDir, []string{".mp3"}, 5, scanner.songRepo)
+	restored.SetAliasRetention(time.Nanosecond)
+	if err := restored.Restore(); err != nil {
+		t.Fatalf("恢复歌曲库失败: %v", err)
+	}
+	if found := restored.GetSongByID(oldID); found == nil || found.ID != newID {
+		t.Errorf("期望旧 ID 在过渡期结束后仍然解析到移动后的歌曲, 得到 %+v", found)
+	}
+}
+
+// TestSongManager_MoveRejects 测试无效的目标路径、已存在的文件和不存在的歌曲被拒绝。
+func TestSongManager_MoveRejects(t *testing.T) {
+	manager, _, _, tmpDir := newTestSongManager(t)
+	id := models.LibrarySongID(models.DefaultLibraryName, "Artist/song.mp3")
+	if err := os.WriteFile(filepath.Join(tmpDir, "taken.mp3"), []byte{0xFF, 0xFB, 0x90, 0x00}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		library string
+		path    string
+		want    error
+	}{
+		{"空路径", "", " ", ErrInvalidSongPath},
+		{"绝对路径", "", "/tmp/song.mp3", ErrInvalidSongPath},
+		{"指向库外", "", "../song.mp3", ErrInvalidSongPath},
+		{"修改扩展名", "", "song.flac", ErrInvalidSongPath},
+		{"非法字符", "", "a:b/song.mp3", ErrInvalidSongPath},
+		{"音乐库不存在", "missing", "song.mp3", ErrInvalidSongPath},
+		{"目标已存在", "", "taken.mp3", ErrSongPathExists},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := manager.Move(context.Background(), id, tt.library, tt.path); !errors.Is(err, tt.want) {
+				t.Errorf("期望错误 %v, 得到 %v", tt.want, err)
+			}
+		})
+	}
+
+	if _, err := manager.Move(context.Background(), "missing", "", "song.mp3"); !errors.Is(err, ErrSongNotFound) {
+		t.Errorf("期望错误 %v, 得到 %v", ErrSongNotFound, err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "Artist", "song.mp3")); err != nil {
+		t.Errorf("期望被拒绝的移动不改变文件: %v", err)
+	}
+}
+
+// TestSongManager_MoveUploadConflict 测试目标位置上有其他用户的上传记录时撤销移动，两条上传记录都保持不变。
+func TestSongManager_MoveUploadConflict(t *testing.T) {
+	manager, scanner, _, tmpDir := newTestSongManager(t)
+	id := models.LibrarySongID(models.DefaultLibraryName, "Artist/song.mp3")
+	uploads := manager.uploads.(*memoryUploadRepository)
+	uploads.Create(&models.Upload{UserID: 1, SongID: id, Library: models.DefaultLibraryName, RelPath: "Artist/song.mp3", Size: 10})
+	uploads.Create(&models.Upload{UserID: 2, SongID: "stale", Library: models.DefaultLibraryName, RelPath: "Other/song.mp3", Size: 20})
+
+	if _, err := manager.Move(context.Background(), id, "", "Other/song.mp3"); err == nil {
+		t.Fatal("期望上传记录冲突时移动失败")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "Artist", "song.mp3")); err != nil {
+		t.Errorf("期望文件回到原来的位置: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "Other")); !os.IsNotExist(err) {
+		t.Errorf("期望创建的目录被删除, 得到 %v", err)
+	}
+	if scanner.GetSongByID(id) == nil {
+		t.Error("期望歌曲仍然可以按原来的 ID 访问")
+	}
+	if used, _ := uploads.Usage(2); used != 20 || uploads.uploads[0].RelPath != "Artist/song.mp3" {
+		t.Errorf("期望上传记录保持不变, 得到 %+v", uploads.uploads)
+	}
+}
+
+// TestSongManager_DeleteAndPurge 测试删除的歌曲移入回收站并从歌曲库中移除，保留期结束后文件被彻底删除。
+func TestSongManager_DeleteAndPurge(t *testing.T) {
+	manager, scanner, trash, tmpDir := newTestSongManager(t)
+	id := models.LibrarySongID(models.DefaultLibraryName, "Artist/song.mp3")
+
+	entry, err := manager.Delete(context.Background(), id, 7)
+	if err != nil {
+		t.Fatalf("删除失败: %v", err)
+	}
+	if entry.SongID != id || entry.RelPath != "Artist/song.mp3" || entry.Title != "Song" || entry.DeletedBy != 7 {
+		t.Errorf("回收站记录不正确: %+v", entry)
+	}
+	if scanner.GetSongByID(id) != nil || scanner.GetSongCount() != 0 {
+		t.Error("期望歌曲从歌曲库中移除")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "Artist")); !os.IsNotExist(err) {
+		t.Errorf("期望歌曲文件和歌词离开音乐库, 得到 %v", err)
+	}
+	trashed := filepath.Join(manager.directory, filepath.FromSlash(entry.TrashPath))
+	if _, err := os.Stat(trashed); err != nil {
+		t.Fatalf("期望文件位于回收站: %v", err)
+	}
+
+	if _, err := manager.Delete(context.Background(), id, 7); !errors.Is(err, ErrSongNotFound) {
+		t.Errorf("期望重复删除返回 %v, 得到 %v", ErrSongNotFound, err)
+	}
+
+	// 保留期内不会被清理
+	if purged, err := manager.Purge(time.Now()); err != nil || purged != 0 {
+		t.Errorf("期望保留期内不清理, 得到 %d %v", purged, err)
+	}
+	if purged, err := manager.Purge(time.Now().Add(25 * time.Hour)); err != nil || purged != 1 {
+		t.Fatalf("期望清理 1 首歌曲, 得到 %d %v", purged, err)
+	}
+	if _, err := os.Stat(filepath.Dir(trashed)); !os.IsNotExist(err) {
+		t.Errorf("期望回收站文件被删除, 得到 %v", err)
+	}
+	if list, _ := manager.Trash(); len(list) != 0 || trash.entries[0].PurgedAt == nil {
+		t.Errorf("期望记录标记为已彻底删除, 得到 %+v", list)
+	}
+}
+
+// TestSongManager_ConcurrentDelete 测试并发删除同一首歌曲时只有一次成功，其余返回歌曲不存在。
+func TestSongManager_ConcurrentDelete(t *testing.T) {
+	manager, _, trash, _ := newTestSongManager(t)
+	id := models.LibrarySongID(models.DefaultLibraryName, "Artist/song.mp3")
+
+	errs := make(chan error, 4)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			_, err := manager.Delete(context.Background(), id, 1)
+			errs <- err
+		}()
+	}
+	deleted := 0
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err == nil {
+			deleted++
+		} else if !errors.Is(err, ErrSongNotFound) {
+			t.Errorf("期望错误 %v, 得到 %v", ErrSongNotFound, err)
+		}
+	}
+	if deleted != 1 || len(trash.entries) != 1 {
+		t.Errorf("期望只删除一次, 得到 %d 次, 回收站记录 %d 条", deleted, len(trash.entries))
+	}
+}
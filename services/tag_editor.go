@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"zero-music/logger"
+	"zero-music/models"
+)
+
+// ErrCueTrackTags 表示歌曲是 CUE 曲目，其标签来自 CUE 文件而不是音频文件。
+var ErrCueTrackTags = errors.New("CUE 曲目的标签来自 CUE 文件，请直接编辑 CUE 文件")
+
+// TagEditResult 是修改一首歌曲标签的结果，失败时 Song 为 nil。
+type TagEditResult struct {
+	ID    string       `json:"id"`
+	Song  *models.Song `json:"song,omitempty"`
+	Error string       `json:"error,omitempty"`
+	err   error
+}
+
+// Err 返回修改失败的原因。
+func (r *TagEditResult) Err() error {
+	return r.err
+}
+
+// TagEditor 将标签修改写回音频文件，并立即更新歌曲库索引。
+// 歌曲 ID 由音乐库内的相对路径生成，写入标签不会改变 ID。
+type TagEditor struct {
+	library *MusicScanner
+	mu      sync.Mutex // 串行写入，避免同时改写同一个文件
+}
+
+// NewTagEditor 创建一个新的 TagEditor 实例。
+func NewTagEditor(library *MusicScanner) *TagEditor {
+	return &TagEditor{library: library}
+}
+
+// Edit 对 ids 中的每首歌曲应用同一组修改，返回与 ids 顺序相同的结果。单首歌曲失败不影响其他歌曲。
+// 写入完成后重新解析被修改的文件，结果中的歌曲是更新后的信息。
+func (e *TagEditor) Edit(ctx context.Context, ids []string, edit *models.TagEdit) ([]*TagEditResult, error) {
+	if err := edit.Validate(); err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	results := make([]*TagEditResult, len(ids))
+	var paths []string
+	written := make(map[string]bool)
+	for i, id := range ids {
+		result := &TagEditResult{ID: id}
+		results[i] = result
+
+		song := e.library.GetSongByID(id)
+		switch {
+		case song == nil:
+			result.err = ErrSongNotFound
+		case song.IsCueTrack():
+			result.err = ErrCueTrackTags
+		case !CanWriteTags(song.FilePath):
+			result.err = ErrUnsupportedTagFormat
+		case written[song.FilePath]:
+			// 同一首歌曲通过别名出现多次
+		default:
+			if err := WriteTags(song.FilePath, edit); err != nil {
+				logger.Warnf("写入标签失败 %s: %v", song.FilePath, err)
+				result.err = fmt.Errorf("写入标签失败: %w", err)
+				break
+			}
+			written[song.FilePath] = true
+			paths = append(paths, song.FilePath)
+		}
+		if result.err != nil {
+			result.Error = result.err.Error()
+		}
+	}
+
+	if len(paths) > 0 {
+		if _, err := e.library.ApplyChanges(ctx, paths); err != nil {
+			return nil, fmt.Errorf("更新歌曲库失败: %w", err)
+		}
+	}
+	for _, result := range results {
+		if result.err == nil {
+			result.Song = e.library.GetSongByID(result.ID)
+		}
+	}
+	return results, nil
+}
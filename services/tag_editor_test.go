@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"zero-music/models"
+)
+
+// TestTagEditor_Edit 测试写入标签后歌曲 ID 不变，索引立即更新；不支持的歌曲单独返回错误。
+func TestTagEditor_Edit(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeID3Song(t, filepath.Join(tmpDir, "a.mp3"), map[string]string{"TIT2": "Old Title", "TPE1": "Artist"})
+	if err := os.WriteFile(filepath.Join(tmpDir, "b.wav"), []byte("fake wav data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	scanner := NewMusicScanner(tmpDir, []string{".mp3", ".wav"}, 5)
+	songs, err := scanner.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("扫描失败: %v", err)
+	}
+	if len(songs) != 2 {
+		t.Fatalf("期望 2 首歌曲, 得到 %d", len(songs))
+	}
+	var mp3, wav *models.Song
+	for _, song := range songs {
+		if song.Format == ".mp3" {
+			mp3 = song
+		} else {
+			wav = song
+		}
+	}
+
+	title, disc := "New Title", 2
+	editor := NewTagEditor(scanner)
+	results, err := editor.Edit(context.Background(), []string{mp3.ID, wav.ID, "0123456789abcdef0123456789abcdef"}, &models.TagEdit{Title: &title, Disc: &disc})
+	if err != nil {
+		t.Fatalf("修改标签失败: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("期望 3 个结果, 得到 %d", len(results))
+	}
+
+	if err := results[0].Err(); err != nil {
+		t.Fatalf("期望 MP3 修改成功, 得到 %v", err)
+	}
+	if song := results[0].Song; song == nil || song.ID != mp3.ID || song.Title != "New Title" || song.Artist != "Artist" || song.Disc != 2 {
+		t.Errorf("期望返回更新后的歌曲且 ID 不变, 得到 %+v", song)
+	}
+	if song := scanner.GetSongByID(mp3.ID); song == nil || song.Title != "New Title" {
+		t.Errorf("期望索引立即更新, 得到 %+v", song)
+	}
+	if !errors.Is(results[1].Err(), ErrUnsupportedTagFormat) || results[1].Error == "" || results[1].Song != nil {
+		t.Errorf("期望 WAV 返回 ErrUnsupportedTagFormat, 得到 %+v", results[1])
+	}
+	if !errors.Is(results[2].Err(), ErrSongNotFound) {
+		t.Errorf("期望不存在的歌曲返回 ErrSongNotFound, 得到 %v", results[2].Err())
+	}
+
+	if _, err := editor.Edit(context.Background(), []string{mp3.ID}, &models.TagEdit{}); err == nil {
+		t.Error("期望没有修改任何标签时返回错误")
+	}
+}
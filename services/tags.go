@@ -0,0 +1,131 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"zero-music/models"
+)
+
+// ErrUnsupportedTagFormat 表示不支持改写该格式文件的标签。
+var ErrUnsupportedTagFormat = errors.New("不支持修改该格式文件的标签")
+
+// tagWriter 将修改后的标签和原文件的音频数据写入 w。
+type tagWriter func(src *os.File, size int64, w io.Writer, edit *models.TagEdit) error
+
+// tagWriters 按扩展名选择标签写入器：MP3 使用 ID3v2.4，FLAC 和 Ogg 使用 Vorbis 注释，MP4 使用 iTunes 元数据原子。
+var tagWriters = map[string]tagWriter{
+	".mp3":  writeID3Tags,
+	".flac": writeFLACTags,
+	".ogg":  writeOggTags,
+	".oga":  writeOggTags,
+	".opus": writeOggTags,
+	".m4a":  writeMP4Tags,
+	".m4b":  writeMP4Tags,
+	".mp4":  writeMP4Tags,
+}
+
+// CanWriteTags 判断是否支持改写 path 的标签。
+func CanWriteTags(path string) bool {
+	_, ok := tagWriters[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+// WriteTags 按 edit 修改音频文件的标签。新内容先写入同一目录下的临时文件，
+// 写入成功后再通过重命名原子地替换原文件，失败时原文件保持不变。
+func WriteTags(path string, edit *models.TagEdit) error {
+	writer, ok := tagWriters[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return ErrUnsupportedTagFormat
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("打开文件失败: %w", err)
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return fmt.Errorf("获取文件信息失败: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		return fmt.Errorf("设置临时文件权限失败: %w", err)
+	}
+	if err := writer(src, info.Size(), tmp, edit); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("写入临时文件失败: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("写入临时文件失败: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("替换文件失败: %w", err)
+	}
+	committed = true
+	return nil
+}
+
+// copyRange 将 src 中从 offset 开始的 length 个字节复制到 w，length 为负数时复制到文件结尾。
+func copyRange(w io.Writer, src *os.File, offset, length int64) error {
+	var r io.Reader = io.NewSectionReader(src, offset, 1<<62)
+	if length >= 0 {
+		r = io.NewSectionReader(src, offset, length)
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		return fmt.Errorf("复制音频数据失败: %w", err)
+	}
+	return nil
+}
+
+// numberWithTotal 返回写入 "编号/总数" 形式标签的文本：原有的值带有总数时保留总数。
+func numberWithTotal(previous string, n int) string {
+	if _, total, ok := strings.Cut(previous, "/"); ok && strings.TrimSpace(total) != "" {
+		return strconv.Itoa(n) + "/" + strings.TrimSpace(total)
+	}
+	return strconv.Itoa(n)
+}
+
+// flacPicture 生成 FLAC PICTURE 元数据块的内容（Ogg 中 METADATA_BLOCK_PICTURE 的内容与其相同），
+// 图片类型为封面正面。
+func flacPicture(data []byte, mimeType string) []byte {
+	var width, height, depth int
+	if config, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		width, height, depth = config.Width, config.Height, 24
+	}
+
+	var buf bytes.Buffer
+	put := func(v uint32) { binary.Write(&buf, binary.BigEndian, v) }
+	put(3) // 封面正面
+	put(uint32(len(mimeType)))
+	buf.WriteString(mimeType)
+	put(0) // 描述
+	put(uint32(width))
+	put(uint32(height))
+	put(uint32(depth))
+	put(0) // 索引颜色数
+	put(uint32(len(data)))
+	buf.Write(data)
+	return buf.Bytes()
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"zero-music/models"
+)
+
+// id3Padding 是写入 ID3v2 标签时保留的填充字节数。
+const id3Padding = 1024
+
+// errUnsupportedID3Version 表示原有的 ID3v2 标签版本无法转换为 ID3v2.4。
+var errUnsupportedID3Version = errors.New("不支持修改 ID3v2.2 标签")
+
+// id3Frame 是 ID3v2.4 格式的一个帧，flags 使用 ID3v2.4 的标志位布局。
+type id3Frame struct {
+	id    string
+	flags [2]byte
+	data  []byte
+}
+
+// writeID3Tags 将原有的 ID3v2.3/2.4 标签转换为 ID3v2.4 并应用修改，未修改的帧原样保留。
+// 文件末尾的 ID3v1 标签保持不变，读取时 ID3v2 优先。
+func writeID3Tags(src *os.File, size int64, w io.Writer, edit *models.TagEdit) error {
+	frames, audioOffset, err := readID3Frames(src, size)
+	if err != nil {
+		return err
+	}
+	frames = applyID3Edit(frames, edit)
+
+	var body bytes.Buffer
+	for _, frame := range frames {
+		header := make([]byte, 10)
+		copy(header, frame.id)
+		putSyncsafe(header[4:8], len(frame.data))
+		header[8], header[9] = frame.flags[0], frame.flags[1]
+		body.Write(header)
+		body.Write(frame.data)
+	}
+	body.Write(make([]byte, id3Padding))
+
+	header := []byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, 0}
+	putSyncsafe(header[6:10], body.Len())
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if _, err := w.Write(body.Bytes()); err != nil {
+		return err
+	}
+	return copyRange(w, src, audioOffset, -1)
+}
+
+// readID3Frames 读取文件开头的 ID3v2 标签，返回转换为 ID3v2.4 的帧和标签之后的偏移量。
+// 没有标签时返回空列表和 0。ID3v2.3 中压缩或加密的帧无法转换，会被丢弃。
+func readID3Frames(src *os.File, size int64) ([]id3Frame, int64, error) {
+	header := make([]byte, 10)
+	if _, err := src.ReadAt(header, 0); err != nil || string(header[:3]) != "ID3" {
+		return nil, 0, nil
+	}
+	version, flags := header[3], header[5]
+	tagSize := int64(syncsafe(header[6:10]))
+	audioOffset := id3v2End(src)
+	if version < 3 {
+		return nil, 0, errUnsupportedID3Version
+	}
+	if version > 4 || audioOffset > size {
+		return nil, 0, errors.New("无效的 ID3v2 标签")
+	}
+
+	body := make([]byte, tagSize)
+	if _, err := src.ReadAt(body, 10); err != nil {
+		return nil, 0, fmt.Errorf("读取 ID3v2 标签失败: %w", err)
+	}
+	if version == 3 && flags&0x80 != 0 {
+		body = bytes.ReplaceAll(body, []byte{0xff, 0x00}, []byte{0xff})
+	}
+	if flags&0x40 != 0 && len(body) >= 4 {
+		// 跳过扩展头：ID3v2.3 的长度不含自身，ID3v2.4 的长度是同步安全整数且包含自身
+		if version == 3 {
+			body = body[min(len(body), 4+int(binary.BigEndian.Uint32(body[:4]))):]
+		} else {
+			body = body[min(len(body), syncsafe(body[:4])):]
+		}
+	}
+
+	var frames []id3Frame
+	for len(body) >= 10 && body[0] != 0 {
+		id := string(body[:4])
+		var frameSize int
+		if version == 3 {
+			frameSize = int(binary.BigEndian.Uint32(body[4:8]))
+		} else {
+			frameSize = syncsafe(body[4:8])
+		}
+		if frameSize > len(body)-10 {
+			return nil, 0, errors.New("无效的 ID3v2 帧")
+		}
+		frame := id3Frame{id: id, flags: [2]byte{body[8], body[9]}, data: body[10 : 10+frameSize]}
+		body = body[10+frameSize:]
+
+		if version == 3 {
+			if frame.flags[1]&0xc0 != 0 {
+				continue // 压缩或加密的帧
+			}
+			// 状态标志整体右移一位，分组标志从 0x20 移到 0x40，分组字节在两个版本中都位于帧数据开头
+			frame.flags = [2]byte{frame.flags[0] >> 1 & 0x70, frame.flags[1] & 0x20 << 1}
+			switch id {
+			case "TYER":
+				frame.id = "TDRC"
+			case "TDAT", "TIME", "TRDA", "TSIZ":
+				continue // ID3v2.4 中已废弃，日期由 TDRC 表示
+			}
+		}
+		frames = append(frames, frame)
+	}
+	return frames, audioOffset, nil
+}
+
+// applyID3Edit 删除被修改的帧并追加新的帧。
+func applyID3Edit(frames []id3Frame, edit *models.TagEdit) []id3Frame {
+	text := func(id string, value *string) {
+		if value == nil {
+			return
+		}
+		frames = removeID3Frames(frames, id)
+		if *value != "" {
+			frames = append(frames, id3TextFrame(id, *value))
+		}
+	}
+	number := func(id string, value *int) {
+		if value == nil {
+			return
+		}
+		previous := id3Text(frames, id)
+		frames = removeID3Frames(frames, id)
+		if *value > 0 {
+			frames = append(frames, id3TextFrame(id, numberWithTotal(previous, *value)))
+		}
+	}
+
+	text("TIT2", edit.Title)
+	text("TPE1", edit.Artist)
+	text("TALB", edit.Album)
+	text("TPE2", edit.AlbumArtist)
+	text("TCON", edit.Genre)
+	if edit.Year != nil {
+		frames = removeID3Frames(frames, "TDRC", "TYER")
+		if *edit.Year > 0 {
+			frames = append(frames, id3TextFrame("TDRC", strconv.Itoa(*edit.Year)))
+		}
+	}
+	number("TRCK", edit.Track)
+	number("TPOS", edit.Disc)
+	if edit.Cover != nil {
+		frames = removeID3Frames(frames, "APIC")
+		if len(*edit.Cover) > 0 {
+			var data bytes.Buffer
+			data.WriteByte(3) // UTF-8
+			data.WriteString(edit.CoverMIME())
+			data.Write([]byte{0, 3, 0}) // 结束符、封面类型（封面正面）、空描述
+			data.Write(*edit.Cover)
+			frames = append(frames, id3Frame{id: "APIC", data: data.Bytes()})
+		}
+	}
+	return frames
+}
+
+// id3TextFrame 创建 UTF-8 编码的文本帧。
+func id3TextFrame(id, value string) id3Frame {
+	return id3Frame{id: id, data: append([]byte{3}, value...)}
+}
+
+// id3Text 返回第一个 id 帧的文本，没有该帧或无法解码时返回空字符串。只用于读取编号等 ASCII 内容。
+func id3Text(frames []id3Frame, id string) string {
+	for _, frame := range frames {
+		if frame.id == id && len(frame.data) > 1 && (frame.data[0] == 0 || frame.data[0] == 3) && frame.flags[1] == 0 {
+			return string(bytes.TrimRight(frame.data[1:], "\x00"))
+		}
+	}
+	return ""
+}
+
+// removeID3Frames 删除指定 ID 的所有帧。
+func removeID3Frames(frames []id3Frame, ids ...string) []id3Frame {
+	return slices.DeleteFunc(frames, func(frame id3Frame) bool {
+		return slices.Contains(ids, frame.id)
+	})
+}
+
+// id3v2End 返回文件开头 ID3v2 标签之后的偏移量，没有标签时返回 0。
+func id3v2End(src *os.File) int64 {
+	header := make([]byte, 10)
+	if _, err := src.ReadAt(header, 0); err != nil || string(header[:3]) != "ID3" {
+		return 0
+	}
+	end := 10 + int64(syncsafe(header[6:10]))
+	if header[5]&0x10 != 0 {
+		end += 10 // 标签尾部
+	}
+	return end
+}
+
+// syncsafe 解码 4 字节的同步安全整数。
+func syncsafe(b []byte) int {
+	return int(b[0]&0x7f)<<21 | int(b[1]&0x7f)<<14 | int(b[2]&0x7f)<<7 | int(b[3]&0x7f)
+}
+
+// putSyncsafe 将 n 编码为 4 字节的同步安全整数。
+func putSyncsafe(b []byte, n int) {
+	b[0], b[1], b[2], b[3] = byte(n>>21&0x7f), byte(n>>14&0x7f), byte(n>>7&0x7f), byte(n&0x7f)
+}
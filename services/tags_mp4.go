@@ -0,0 +1,333 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"zero-music/models"
+)
+
+// mp4Containers 是改写元数据时需要展开的原子：ilst 及通往它的路径，以及通往 stco/co64 的路径。
+var mp4Containers = map[string]bool{
+	"moov": true, "udta": true, "meta": true, "ilst": true,
+	"trak": true, "mdia": true, "minf": true, "stbl": true,
+}
+
+// iTunes 元数据中 data 原子的类型。
+const (
+	mp4DataImplicit = 0
+	mp4DataUTF8     = 1
+	mp4DataJPEG     = 13
+	mp4DataPNG      = 14
+)
+
+// mp4Box 是读入内存的 MP4 原子。容器原子的内容是 children（meta 的 4 字节版本和标志位在 prefix 中），
+// 其他原子的内容是 payload。
+type mp4Box struct {
+	kind     string
+	prefix   []byte
+	payload  []byte
+	children []*mp4Box
+}
+
+// parseMP4Boxes 解析 data 中的全部原子。
+func parseMP4Boxes(data []byte) ([]*mp4Box, error) {
+	var boxes []*mp4Box
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, errors.New("无效的 MP4 原子")
+		}
+		size, headerSize := uint64(binary.BigEndian.Uint32(data)), uint64(8)
+		switch size {
+		case 0:
+			size = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return nil, errors.New("无效的 MP4 原子")
+			}
+			size, headerSize = binary.BigEndian.Uint64(data[8:16]), 16
+		}
+		if size < headerSize || size > uint64(len(data)) {
+			return nil, errors.New("无效的 MP4 原子")
+		}
+		box := &mp4Box{kind: string(data[4:8])}
+		content := data[headerSize:size]
+		data = data[size:]
+
+		if mp4Containers[box.kind] {
+			// iTunes 的 meta 是完整原子（带版本和标志位），QuickTime 的 meta 不是
+			if box.kind == "meta" && len(content) >= 8 && string(content[4:8]) != "hdlr" {
+				box.prefix, content = content[:4], content[4:]
+			}
+			children, err := parseMP4Boxes(content)
+			if err != nil {
+				return nil, err
+			}
+			box.children = children
+		} else {
+			box.payload = content
+		}
+		boxes = append(boxes, box)
+	}
+	return boxes, nil
+}
+
+// size 返回原子编码后的大小。
+func (b *mp4Box) size() int {
+	n := 8 + len(b.prefix) + len(b.payload)
+	for _, child := range b.children {
+		n += child.size()
+	}
+	return n
+}
+
+// encode 将原子编码后追加到 buf。
+func (b *mp4Box) encode(buf *bytes.Buffer) {
+	binary.Write(buf, binary.BigEndian, uint32(b.size()))
+	buf.WriteString(b.kind)
+	buf.Write(b.prefix)
+	buf.Write(b.payload)
+	for _, child := range b.children {
+		child.encode(buf)
+	}
+}
+
+// child 返回第一个 kind 子原子，create 为 true 且不存在时创建。
+func (b *mp4Box) child(kind string, create bool) *mp4Box {
+	for _, child := range b.children {
+		if child.kind == kind {
+			return child
+		}
+	}
+	if !create {
+		return nil
+	}
+	child := &mp4Box{kind: kind}
+	b.children = append(b.children, child)
+	return child
+}
+
+// walk 依次访问原子及其所有后代。
+func (b *mp4Box) walk(visit func(*mp4Box)) {
+	visit(b)
+	for _, child := range b.children {
+		child.walk(visit)
+	}
+}
+
+// writeMP4Tags 改写 MP4 文件 moov/udta/meta/ilst 中的 iTunes 元数据，其他原子原样保留。
+// moov 位于音频数据之前时，其大小的变化会使数据偏移改变，所有 stco/co64 中的块偏移随之调整。
+func writeMP4Tags(src *os.File, size int64, w io.Writer, edit *models.TagEdit) error {
+	var moovOffset, moovSize int64 = -1, 0
+	header := make([]byte, 16)
+	for offset := int64(0); offset < size; {
+		if _, err := src.ReadAt(header[:8], offset); err != nil {
+			return fmt.Errorf("读取 MP4 原子失败: %w", err)
+		}
+		boxSize := int64(binary.BigEndian.Uint32(header[:4]))
+		kind := string(header[4:8])
+		switch boxSize {
+		case 0:
+			boxSize = size - offset
+		case 1:
+			if _, err := src.ReadAt(header[8:16], offset+8); err != nil {
+				return fmt.Errorf("读取 MP4 原子失败: %w", err)
+			}
+			boxSize = int64(binary.BigEndian.Uint64(header[8:16]))
+		}
+		if boxSize < 8 || offset+boxSize > size {
+			return errors.New("无效的 MP4 原子")
+		}
+		switch kind {
+		case "moov":
+			moovOffset, moovSize = offset, boxSize
+		case "moof":
+			return errors.New("不支持修改分片 MP4 文件的标签")
+		}
+		offset += boxSize
+	}
+	if moovOffset < 0 {
+		return errors.New("MP4 文件缺少 moov 原子")
+	}
+
+	data := make([]byte, moovSize)
+	if _, err := src.ReadAt(data, moovOffset); err != nil {
+		return fmt.Errorf("读取 moov 原子失败: %w", err)
+	}
+	boxes, err := parseMP4Boxes(data)
+	if err != nil {
+		return err
+	}
+	moov := boxes[0]
+
+	meta := moov.child("udta", true).child("meta", true)
+	if meta.child("hdlr", false) == nil {
+		meta.prefix = make([]byte, 4)
+		hdlr := &mp4Box{kind: "hdlr", payload: append(make([]byte, 8), "mdirappl\x00\x00\x00\x00\x00\x00\x00\x00\x00"...)}
+		meta.children = slices.Insert(meta.children, 0, hdlr)
+	}
+	applyMP4Edit(meta.child("ilst", true), edit)
+
+	delta := int64(moov.size()) - moovSize
+	if delta != 0 {
+		if err := shiftMP4ChunkOffsets(moov, moovOffset, delta); err != nil {
+			return err
+		}
+	}
+
+	var buf bytes.Buffer
+	moov.encode(&buf)
+	if err := copyRange(w, src, 0, moovOffset); err != nil {
+		return err
+	}
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	return copyRange(w, src, moovOffset+moovSize, -1)
+}
+
+// shiftMP4ChunkOffsets 将位于 moov 之后的块偏移增加 delta。
+func shiftMP4ChunkOffsets(moov *mp4Box, moovOffset, delta int64) error {
+	var err error
+	moov.walk(func(box *mp4Box) {
+		if (box.kind != "stco" && box.kind != "co64") || len(box.payload) < 8 || err != nil {
+			return
+		}
+		width := 4
+		if box.kind == "co64" {
+			width = 8
+		}
+		count := int(binary.BigEndian.Uint32(box.payload[4:8]))
+		if count > (len(box.payload)-8)/width {
+			err = errors.New("无效的块偏移表")
+			return
+		}
+		payload := slices.Clone(box.payload)
+		for i := 0; i < count; i++ {
+			entry := payload[8+i*width : 8+(i+1)*width]
+			if width == 8 {
+				if offset := int64(binary.BigEndian.Uint64(entry)); offset > moovOffset {
+					binary.BigEndian.PutUint64(entry, uint64(offset+delta))
+				}
+				continue
+			}
+			offset := int64(binary.BigEndian.Uint32(entry))
+			if offset <= moovOffset {
+				continue
+			}
+			if offset+delta > math.MaxUint32 {
+				err = errors.New("块偏移超出 stco 的范围")
+				return
+			}
+			binary.BigEndian.PutUint32(entry, uint32(offset+delta))
+		}
+		box.payload = payload
+	})
+	return err
+}
+
+// applyMP4Edit 修改 ilst 中的元数据项，已有的项在原位置替换，新的项追加在末尾。
+func applyMP4Edit(ilst *mp4Box, edit *models.TagEdit) {
+	set := func(kind string, item *mp4Box, aliases ...string) {
+		index := slices.IndexFunc(ilst.children, func(box *mp4Box) bool { return box.kind == kind })
+		ilst.children = slices.DeleteFunc(ilst.children, func(box *mp4Box) bool {
+			return box.kind == kind || slices.Contains(aliases, box.kind)
+		})
+		if item == nil {
+			return
+		}
+		if index < 0 || index > len(ilst.children) {
+			index = len(ilst.children)
+		}
+		ilst.children = slices.Insert(ilst.children, index, item)
+	}
+	text := func(kind string, value *string, aliases ...string) {
+		if value == nil {
+			return
+		}
+		var item *mp4Box
+		if *value != "" {
+			item = mp4DataItem(kind, mp4DataUTF8, []byte(*value))
+		}
+		set(kind, item, aliases...)
+	}
+	number := func(kind string, value *int, size int) {
+		if value == nil {
+			return
+		}
+		var item *mp4Box
+		if *value > 0 {
+			data := make([]byte, size)
+			// 保留原有的总数
+			if previous := mp4ItemData(ilst, kind); len(previous) >= 6 {
+				copy(data[4:6], previous[4:6])
+			}
+			binary.BigEndian.PutUint16(data[2:4], uint16(*value))
+			item = mp4DataItem(kind, mp4DataImplicit, data)
+		}
+		set(kind, item)
+	}
+
+	text("\xa9nam", edit.Title)
+	text("\xa9ART", edit.Artist)
+	text("\xa9alb", edit.Album)
+	text("aART", edit.AlbumArtist)
+	text("\xa9gen", edit.Genre, "gnre")
+	if edit.Year != nil {
+		value := ""
+		if *edit.Year > 0 {
+			value = fmt.Sprint(*edit.Year)
+		}
+		text("\xa9day", &value)
+	}
+	number("trkn", edit.Track, 8)
+	number("disk", edit.Disc, 6)
+	if edit.Cover != nil {
+		var item *mp4Box
+		if len(*edit.Cover) > 0 {
+			kind := uint32(mp4DataJPEG)
+			if edit.CoverMIME() == "image/png" {
+				kind = mp4DataPNG
+			}
+			item = mp4DataItem("covr", kind, *edit.Cover)
+		}
+		set("covr", item)
+	}
+}
+
+// mp4DataItem 创建包含一个 data 原子的元数据项。
+func mp4DataItem(kind string, dataType uint32, value []byte) *mp4Box {
+	payload := make([]byte, 8, 8+len(value))
+	binary.BigEndian.PutUint32(payload[:4], dataType)
+	return &mp4Box{kind: kind, payload: encodeMP4Box("data", append(payload, value...))}
+}
+
+// mp4ItemData 返回元数据项中第一个 data 原子的值，没有时返回 nil。
+func mp4ItemData(ilst *mp4Box, kind string) []byte {
+	item := ilst.child(kind, false)
+	if item == nil {
+		return nil
+	}
+	boxes, err := parseMP4Boxes(item.payload)
+	if err != nil {
+		return nil
+	}
+	for _, box := range boxes {
+		if box.kind == "data" && len(box.payload) >= 8 {
+			return box.payload[8:]
+		}
+	}
+	return nil
+}
+
+// encodeMP4Box 编码一个原子。
+func encodeMP4Box(kind string, payload []byte) []byte {
+	var buf bytes.Buffer
+	(&mp4Box{kind: kind, payload: payload}).encode(&buf)
+	return buf.Bytes()
+}
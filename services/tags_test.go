@@ -0,0 +1,320 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"zero-music/models"
+
+	"github.com/dhowden/tag"
+)
+
+// testAudio 是测试文件中标签之后的音频数据，写入标签后必须原样保留。
+var testAudio = bytes.Repeat([]byte("AUDIO-DATA"), 100)
+
+// readTestTags 使用 dhowden/tag 读取文件的标签。
+func readTestTags(t *testing.T, path string) tag.Metadata {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	metadata, err := tag.ReadFrom(f)
+	if err != nil {
+		t.Fatalf("读取标签失败: %v", err)
+	}
+	return metadata
+}
+
+// testTagEdit 返回测试使用的标签修改：设置标题、专辑、曲目、碟片和封面，删除流派。
+func testTagEdit(t *testing.T) *models.TagEdit {
+	t.Helper()
+	title, album, genre := "新标题", "New Album", ""
+	track, disc := 5, 2
+	cover := encodeTestImage(t, "png", 8, 8)
+	edit := &models.TagEdit{Title: &title, Album: &album, Genre: &genre, Track: &track, Disc: &disc, Cover: &cover}
+	if err := edit.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	return edit
+}
+
+// checkTestTags 检查 testTagEdit 的修改已写入，未修改的艺术家保持不变。
+func checkTestTags(t *testing.T, path string, wantTrackTotal int) {
+	t.Helper()
+	metadata := readTestTags(t, path)
+	if metadata.Title() != "新标题" || metadata.Album() != "New Album" {
+		t.Errorf("期望标题和专辑被修改, 得到 %q %q", metadata.Title(), metadata.Album())
+	}
+	if metadata.Artist() != "Old Artist" {
+		t.Errorf("期望艺术家保持不变, 得到 %q", metadata.Artist())
+	}
+	if metadata.Genre() != "" {
+		t.Errorf("期望流派被删除, 得到 %q", metadata.Genre())
+	}
+	if track, total := metadata.Track(); track != 5 || total != wantTrackTotal {
+		t.Errorf("期望曲目 5/%d, 得到 %d/%d", wantTrackTotal, track, total)
+	}
+	if disc, _ := metadata.Disc(); disc != 2 {
+		t.Errorf("期望碟片 2, 得到 %d", disc)
+	}
+	if picture := metadata.Picture(); picture == nil || picture.MIMEType != "image/png" {
+		t.Errorf("期望写入 PNG 封面, 得到 %+v", picture)
+	}
+}
+
+// checkTestAudio 检查文件以原有的音频数据结尾。
+func checkTestAudio(t *testing.T, path string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasSuffix(data, testAudio) {
+		t.Error("期望音频数据保持不变")
+	}
+}
+
+func TestWriteTags_MP3(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "song.mp3")
+	writeID3Song(t, path, map[string]string{
+		"TIT2": "Old Title",
+		"TPE1": "Old Artist",
+		"TCON": "Rock",
+		"TRCK": "3/12",
+		"TYER": "1999",
+		"TCOM": "Composer",
+	})
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write(testAudio)
+	f.Close()
+
+	if err := WriteTags(path, testTagEdit(t)); err != nil {
+		t.Fatalf("写入标签失败: %v", err)
+	}
+	checkTestTags(t, path, 12)
+	checkTestAudio(t, path)
+
+	metadata := readTestTags(t, path)
+	if metadata.Format() != tag.ID3v2_4 {
+		t.Errorf("期望写入 ID3v2.4, 得到 %s", metadata.Format())
+	}
+	if metadata.Composer() != "Composer" || metadata.Year() != 1999 {
+		t.Errorf("期望未修改的帧被保留, 得到作曲者 %q 年份 %d", metadata.Composer(), metadata.Year())
+	}
+
+	// 再次写入时读取的是 ID3v2.4 标签
+	year := 2024
+	if err := WriteTags(path, &models.TagEdit{Year: &year}); err != nil {
+		t.Fatalf("写入标签失败: %v", err)
+	}
+	if metadata := readTestTags(t, path); metadata.Year() != 2024 || metadata.Title() != "新标题" {
+		t.Errorf("期望年份 2024 且标题保持不变, 得到 %d %q", metadata.Year(), metadata.Title())
+	}
+	checkTestAudio(t, path)
+}
+
+// writeTestFLAC 写入一个带 VORBIS_COMMENT 块的 FLAC 文件。
+func writeTestFLAC(t *testing.T, path string, comments ...string) {
+	t.Helper()
+	var buf bytes.Buffer
+	buf.WriteString("fLaC")
+	block := func(kind byte, data []byte) {
+		n := len(data)
+		buf.Write([]byte{kind, byte(n >> 16), byte(n >> 8), byte(n)})
+		buf.Write(data)
+	}
+	block(0, make([]byte, 34))
+	block(flacBlockVorbisComment, (&vorbisComments{vendor: "test", comments: comments}).encode())
+	block(0x80|flacBlockPadding, make([]byte, 16))
+	buf.Write(testAudio)
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriteTags_FLAC(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "song.flac")
+	writeTestFLAC(t, path, "TITLE=Old Title", "ARTIST=Old Artist", "GENRE=Rock", "TRACKNUMBER=3/12")
+
+	if err := WriteTags(path, testTagEdit(t)); err != nil {
+		t.Fatalf("写入标签失败: %v", err)
+	}
+	checkTestTags(t, path, 12)
+	checkTestAudio(t, path)
+}
+
+// writeTestOgg 写入一个 Ogg Vorbis 文件：标识头独占第一页，注释头和 setup 头在第二页，
+// 之后是两页音频数据。
+func writeTestOgg(t *testing.T, path string, comments ...string) {
+	t.Helper()
+	ident := append(slices.Clone(vorbisIdentPrefix), make([]byte, 23)...)
+	comment := append(slices.Clone(vorbisCommentPrefix), (&vorbisComments{vendor: "test", comments: comments}).encode()...)
+	comment = append(comment, 1)
+	setup := append([]byte("\x05vorbis"), make([]byte, 300)...)
+
+	var buf bytes.Buffer
+	pages := paginateOggPackets([][]byte{ident}, 7, 0)
+	pages[0].flags = 0x02
+	pages = append(pages, paginateOggPackets([][]byte{comment, setup}, 7, 1)...)
+	pages = append(pages, paginateOggPackets([][]byte{testAudio[:500]}, 7, uint32(len(pages)))...)
+	pages = append(pages, paginateOggPackets([][]byte{testAudio[500:]}, 7, uint32(len(pages)))...)
+	for _, page := range pages {
+		buf.Write(page.encode())
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriteTags_Ogg(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "song.ogg")
+	writeTestOgg(t, path, "TITLE=Old Title", "ARTIST=Old Artist", "GENRE=Rock", "TRACKNUMBER=3")
+
+	edit := testTagEdit(t)
+	// 封面使注释头超过一页
+	cover := append(slices.Clone(*edit.Cover), make([]byte, 70000)...)
+	edit.Cover = &cover
+	if err := WriteTags(path, edit); err != nil {
+		t.Fatalf("写入标签失败: %v", err)
+	}
+	checkTestTags(t, path, 0)
+
+	// 所有页面的序号连续、校验和正确，音频数据保持不变
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var bodies []byte
+	for sequence := uint32(0); ; sequence++ {
+		start, _ := f.Seek(0, io.SeekCurrent)
+		page, err := readOggPage(f)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("读取 Ogg 页面失败: %v", err)
+		}
+		if page.sequence != sequence {
+			t.Fatalf("期望页面序号 %d, 得到 %d", sequence, page.sequence)
+		}
+		raw := make([]byte, 27+len(page.segments)+len(page.body))
+		f.ReadAt(raw, start)
+		if !bytes.Equal(raw, page.encode()) {
+			t.Fatalf("页面 %d 的校验和错误", sequence)
+		}
+		bodies = append(bodies, page.body...)
+	}
+	if !bytes.HasSuffix(bodies, testAudio) {
+		t.Error("期望音频数据保持不变")
+	}
+}
+
+// mp4TestBox 编码一个原子。
+func mp4TestBox(kind string, children ...[]byte) []byte {
+	return encodeMP4Box(kind, bytes.Join(children, nil))
+}
+
+// writeTestMP4 写入一个 moov 位于 mdat 之前的 M4A 文件，stco 中的块偏移指向 mdat 中的音频数据。
+func writeTestMP4(t *testing.T, path string) {
+	t.Helper()
+	ftyp := mp4TestBox("ftyp", []byte("M4A \x00\x00\x00\x00M4A mp42isom"))
+	item := func(kind string, dataType uint32, value []byte) []byte {
+		return mp4DataItem(kind, dataType, value).payload
+	}
+	ilst := mp4TestBox("ilst",
+		mp4TestBox("\xa9nam", item("\xa9nam", mp4DataUTF8, []byte("Old Title"))),
+		mp4TestBox("\xa9ART", item("\xa9ART", mp4DataUTF8, []byte("Old Artist"))),
+		mp4TestBox("\xa9gen", item("\xa9gen", mp4DataUTF8, []byte("Rock"))),
+		mp4TestBox("trkn", item("trkn", mp4DataImplicit, []byte{0, 0, 0, 3, 0, 12, 0, 0})),
+	)
+	hdlr := mp4TestBox("hdlr", make([]byte, 8), []byte("mdirappl"), make([]byte, 9))
+	meta := mp4TestBox("meta", make([]byte, 4), hdlr, ilst)
+
+	stco := func(offset uint32) []byte {
+		payload := make([]byte, 12)
+		binary.BigEndian.PutUint32(payload[4:8], 1)
+		binary.BigEndian.PutUint32(payload[8:12], offset)
+		return mp4TestBox("stco", payload)
+	}
+	moov := func(offset uint32) []byte {
+		trak := mp4TestBox("trak", mp4TestBox("mdia", mp4TestBox("minf", mp4TestBox("stbl", stco(offset)))))
+		return mp4TestBox("moov", mp4TestBox("mvhd", make([]byte, 100)), trak, mp4TestBox("udta", meta))
+	}
+	audioOffset := uint32(len(ftyp) + len(moov(0)) + 8)
+
+	data := bytes.Join([][]byte{ftyp, moov(audioOffset), mp4TestBox("mdat", testAudio)}, nil)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriteTags_MP4(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "song.m4a")
+	writeTestMP4(t, path)
+
+	if err := WriteTags(path, testTagEdit(t)); err != nil {
+		t.Fatalf("写入标签失败: %v", err)
+	}
+	checkTestTags(t, path, 12)
+	checkTestAudio(t, path)
+
+	// 块偏移随 moov 的大小变化调整，仍然指向音频数据
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := bytes.Index(data, []byte("stco"))
+	if index < 0 {
+		t.Fatal("期望保留 stco 原子")
+	}
+	offset := binary.BigEndian.Uint32(data[index+12 : index+16])
+	if int(offset) >= len(data) || !bytes.HasPrefix(data[offset:], testAudio) {
+		t.Errorf("块偏移 %d 没有指向音频数据", offset)
+	}
+}
+
+func TestWriteTags_Unsupported(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "song.wav")
+	if err := os.WriteFile(path, []byte("RIFF"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	title := "x"
+	if err := WriteTags(path, &models.TagEdit{Title: &title}); err != ErrUnsupportedTagFormat {
+		t.Errorf("期望 ErrUnsupportedTagFormat, 得到 %v", err)
+	}
+	if CanWriteTags(path) {
+		t.Error("期望不支持 WAV 文件")
+	}
+}
+
+func TestWriteTags_FailureKeepsOriginal(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "broken.flac")
+	if err := os.WriteFile(path, []byte("not a flac file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	title := "x"
+	if err := WriteTags(path, &models.TagEdit{Title: &title}); err == nil {
+		t.Fatal("期望写入失败")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "not a flac file" {
+		t.Error("期望原文件保持不变")
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".tmp") {
+			t.Errorf("期望临时文件被删除, 得到 %s", entry.Name())
+		}
+	}
+}
@@ -0,0 +1,462 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"zero-music/models"
+)
+
+// 写入 Vorbis 注释时使用的常量。
+const (
+	// vorbisVendor 是文件中原来没有 Vorbis 注释时写入的编码器名称。
+	vorbisVendor = "zero-music"
+	// flacPadding 是写入 FLAC 元数据时保留的 PADDING 块大小。
+	flacPadding = 4096
+	// flacMaxBlockSize 是 FLAC 元数据块的最大长度（24 位）。
+	flacMaxBlockSize = 1<<24 - 1
+)
+
+// FLAC 元数据块类型。
+const (
+	flacBlockPadding       = 1
+	flacBlockVorbisComment = 4
+	flacBlockPicture       = 6
+)
+
+// vorbisComments 是 Vorbis 注释：编码器名称和 "KEY=value" 形式的注释列表。
+type vorbisComments struct {
+	vendor   string
+	comments []string
+}
+
+// parseVorbisComments 解析 Vorbis 注释（长度使用小端序），不含 Vorbis 包头和结束标志位。
+func parseVorbisComments(data []byte) (*vorbisComments, error) {
+	errInvalid := errors.New("无效的 Vorbis 注释")
+	next := func() (string, bool) {
+		if len(data) < 4 {
+			return "", false
+		}
+		n := binary.LittleEndian.Uint32(data)
+		if uint64(n) > uint64(len(data)-4) {
+			return "", false
+		}
+		value := string(data[4 : 4+n])
+		data = data[4+n:]
+		return value, true
+	}
+
+	vendor, ok := next()
+	if !ok || len(data) < 4 {
+		return nil, errInvalid
+	}
+	count := binary.LittleEndian.Uint32(data)
+	data = data[4:]
+	vc := &vorbisComments{vendor: vendor}
+	for i := uint32(0); i < count; i++ {
+		comment, ok := next()
+		if !ok {
+			return nil, errInvalid
+		}
+		vc.comments = append(vc.comments, comment)
+	}
+	return vc, nil
+}
+
+// encode 编码为 Vorbis 注释。
+func (vc *vorbisComments) encode() []byte {
+	var buf bytes.Buffer
+	put := func(s string) {
+		binary.Write(&buf, binary.LittleEndian, uint32(len(s)))
+		buf.WriteString(s)
+	}
+	put(vc.vendor)
+	binary.Write(&buf, binary.LittleEndian, uint32(len(vc.comments)))
+	for _, comment := range vc.comments {
+		put(comment)
+	}
+	return buf.Bytes()
+}
+
+// get 返回第一个 key 注释的值，键不区分大小写。
+func (vc *vorbisComments) get(key string) string {
+	for _, comment := range vc.comments {
+		if name, value, ok := strings.Cut(comment, "="); ok && strings.EqualFold(name, key) {
+			return value
+		}
+	}
+	return ""
+}
+
+// remove 删除指定键的所有注释，键不区分大小写。
+func (vc *vorbisComments) remove(keys ...string) {
+	vc.comments = slices.DeleteFunc(vc.comments, func(comment string) bool {
+		name, _, _ := strings.Cut(comment, "=")
+		return slices.ContainsFunc(keys, func(key string) bool { return strings.EqualFold(name, key) })
+	})
+}
+
+// apply 应用标签修改。embedCover 为 true 时封面以 METADATA_BLOCK_PICTURE 注释写入（Ogg），
+// 否则由调用方写入 PICTURE 元数据块（FLAC）。
+func (vc *vorbisComments) apply(edit *models.TagEdit, embedCover bool) {
+	text := func(value *string, key string, aliases ...string) {
+		if value == nil {
+			return
+		}
+		vc.remove(append(aliases, key)...)
+		if *value != "" {
+			vc.comments = append(vc.comments, key+"="+*value)
+		}
+	}
+	// Vorbis 注释的编号只写数字，总数单独保存在 TRACKTOTAL/DISCTOTAL 中；
+	// 原有的 "编号/总数" 写法中的总数移到对应的键
+	number := func(value *int, key, totalKey string) {
+		if value == nil {
+			return
+		}
+		_, total, _ := strings.Cut(vc.get(key), "/")
+		vc.remove(key)
+		if *value <= 0 {
+			return
+		}
+		vc.comments = append(vc.comments, key+"="+strconv.Itoa(*value))
+		if total = strings.TrimSpace(total); total != "" && vc.get(totalKey) == "" {
+			vc.comments = append(vc.comments, totalKey+"="+total)
+		}
+	}
+
+	text(edit.Title, "TITLE")
+	text(edit.Artist, "ARTIST")
+	text(edit.Album, "ALBUM")
+	text(edit.AlbumArtist, "ALBUMARTIST", "ALBUM ARTIST")
+	text(edit.Genre, "GENRE")
+	if edit.Year != nil {
+		vc.remove("DATE", "YEAR")
+		if *edit.Year > 0 {
+			vc.comments = append(vc.comments, "DATE="+strconv.Itoa(*edit.Year))
+		}
+	}
+	number(edit.Track, "TRACKNUMBER", "TRACKTOTAL")
+	number(edit.Disc, "DISCNUMBER", "DISCTOTAL")
+	if embedCover && edit.Cover != nil {
+		vc.remove("METADATA_BLOCK_PICTURE", "COVERART", "COVERARTMIME")
+		if len(*edit.Cover) > 0 {
+			picture := base64.StdEncoding.EncodeToString(flacPicture(*edit.Cover, edit.CoverMIME()))
+			vc.comments = append(vc.comments, "METADATA_BLOCK_PICTURE="+picture)
+		}
+	}
+}
+
+// flacBlock 是 FLAC 文件的一个元数据块。
+type flacBlock struct {
+	kind byte
+	data []byte
+}
+
+// writeFLACTags 改写 FLAC 文件的 VORBIS_COMMENT 块，修改封面时替换所有 PICTURE 块。
+// 其他元数据块原样保留，原有的 PADDING 块合并为一个。文件开头非标准的 ID3v2 标签会被去掉，
+// 否则读取时会优先使用其中的旧标签。
+func writeFLACTags(src *os.File, size int64, w io.Writer, edit *models.TagEdit) error {
+	offset := id3v2End(src)
+	r := bufio.NewReader(io.NewSectionReader(src, offset, size-offset))
+	marker := make([]byte, 4)
+	if _, err := io.ReadFull(r, marker); err != nil || string(marker) != "fLaC" {
+		return errors.New("不是有效的 FLAC 文件")
+	}
+	offset += 4
+
+	var blocks []flacBlock
+	for last := false; !last; {
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return fmt.Errorf("读取 FLAC 元数据失败: %w", err)
+		}
+		last = header[0]&0x80 != 0
+		block := flacBlock{kind: header[0] & 0x7f, data: make([]byte, int(header[1])<<16|int(header[2])<<8|int(header[3]))}
+		if _, err := io.ReadFull(r, block.data); err != nil {
+			return fmt.Errorf("读取 FLAC 元数据失败: %w", err)
+		}
+		offset += 4 + int64(len(block.data))
+		blocks = append(blocks, block)
+	}
+	if len(blocks) == 0 || blocks[0].kind != 0 {
+		return errors.New("FLAC 文件缺少 STREAMINFO")
+	}
+
+	vc := &vorbisComments{vendor: vorbisVendor}
+	commentIndex := -1
+	for i, block := range blocks {
+		if block.kind == flacBlockVorbisComment {
+			parsed, err := parseVorbisComments(block.data)
+			if err != nil {
+				return err
+			}
+			vc, commentIndex = parsed, i
+			break
+		}
+	}
+	vc.apply(edit, false)
+	comment := flacBlock{kind: flacBlockVorbisComment, data: vc.encode()}
+	if commentIndex >= 0 {
+		blocks[commentIndex] = comment
+	} else {
+		blocks = slices.Insert(blocks, 1, comment)
+	}
+
+	blocks = slices.DeleteFunc(blocks, func(block flacBlock) bool {
+		return block.kind == flacBlockPadding || (edit.Cover != nil && block.kind == flacBlockPicture)
+	})
+	if edit.Cover != nil && len(*edit.Cover) > 0 {
+		blocks = append(blocks, flacBlock{kind: flacBlockPicture, data: flacPicture(*edit.Cover, edit.CoverMIME())})
+	}
+	blocks = append(blocks, flacBlock{kind: flacBlockPadding, data: make([]byte, flacPadding)})
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString("fLaC")
+	for i, block := range blocks {
+		if len(block.data) > flacMaxBlockSize {
+			return errors.New("FLAC 元数据块过大")
+		}
+		kind := block.kind
+		if i == len(blocks)-1 {
+			kind |= 0x80
+		}
+		n := len(block.data)
+		bw.Write([]byte{kind, byte(n >> 16), byte(n >> 8), byte(n)})
+		bw.Write(block.data)
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return copyRange(w, src, offset, -1)
+}
+
+// Ogg 流中注释头的前缀。
+var (
+	vorbisIdentPrefix   = []byte("\x01vorbis")
+	vorbisCommentPrefix = []byte("\x03vorbis")
+	opusIdentPrefix     = []byte("OpusHead")
+	opusCommentPrefix   = []byte("OpusTags")
+)
+
+// oggPageHeader 是 Ogg 页面头部的字段。
+type oggPageHeader struct {
+	flags    byte
+	granule  uint64
+	serial   uint32
+	sequence uint32
+	segments []byte
+}
+
+// oggPage 是一个完整的 Ogg 页面。
+type oggPage struct {
+	oggPageHeader
+	body []byte
+}
+
+// readOggPage 读取一个 Ogg 页面。
+func readOggPage(r io.Reader) (*oggPage, error) {
+	header := make([]byte, 27)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if string(header[:4]) != "OggS" || header[4] != 0 {
+		return nil, errors.New("无效的 Ogg 页面")
+	}
+	page := &oggPage{oggPageHeader: oggPageHeader{
+		flags:    header[5],
+		granule:  binary.LittleEndian.Uint64(header[6:14]),
+		serial:   binary.LittleEndian.Uint32(header[14:18]),
+		sequence: binary.LittleEndian.Uint32(header[18:22]),
+		segments: make([]byte, header[26]),
+	}}
+	if _, err := io.ReadFull(r, page.segments); err != nil {
+		return nil, err
+	}
+	bodySize := 0
+	for _, n := range page.segments {
+		bodySize += int(n)
+	}
+	page.body = make([]byte, bodySize)
+	if _, err := io.ReadFull(r, page.body); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// encode 编码页面并计算 CRC。
+func (p *oggPage) encode() []byte {
+	data := make([]byte, 27, 27+len(p.segments)+len(p.body))
+	copy(data, "OggS")
+	data[5] = p.flags
+	binary.LittleEndian.PutUint64(data[6:14], p.granule)
+	binary.LittleEndian.PutUint32(data[14:18], p.serial)
+	binary.LittleEndian.PutUint32(data[18:22], p.sequence)
+	data[26] = byte(len(p.segments))
+	data = append(data, p.segments...)
+	data = append(data, p.body...)
+	binary.LittleEndian.PutUint32(data[22:26], oggCRC(data))
+	return data
+}
+
+// oggCRCTable 是 Ogg 使用的 CRC-32 查找表（多项式 0x04c11db7，不反转）。
+var oggCRCTable = func() [256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		crc := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+// oggCRC 计算页面的校验和，CRC 字段必须为 0。
+func oggCRC(data []byte) uint32 {
+	var crc uint32
+	for _, b := range data {
+		crc = crc<<8 ^ oggCRCTable[byte(crc>>24)^b]
+	}
+	return crc
+}
+
+// writeOggTags 改写 Ogg Vorbis 或 Opus 文件的注释头。注释头和 Vorbis 的 setup 头重新分页，
+// 页数变化时后续页面的序号随之调整并重新计算校验和。只支持第一个逻辑流独占开头页面的文件。
+func writeOggTags(src *os.File, size int64, w io.Writer, edit *models.TagEdit) error {
+	r := bufio.NewReader(io.NewSectionReader(src, 0, size))
+	first, err := readOggPage(r)
+	if err != nil {
+		return fmt.Errorf("读取 Ogg 页面失败: %w", err)
+	}
+
+	var commentPrefix []byte
+	headerPackets := 0
+	switch {
+	case bytes.HasPrefix(first.body, vorbisIdentPrefix):
+		commentPrefix, headerPackets = vorbisCommentPrefix, 3
+	case bytes.HasPrefix(first.body, opusIdentPrefix):
+		commentPrefix, headerPackets = opusCommentPrefix, 2
+	default:
+		return ErrUnsupportedTagFormat
+	}
+	if len(first.segments) == 0 || first.segments[len(first.segments)-1] == 255 {
+		return errors.New("无效的 Ogg 标识头")
+	}
+
+	// 读取标识头之后的注释头（以及 Vorbis 的 setup 头），它们必须在某个页面的末尾结束
+	var packets [][]byte
+	var packet []byte
+	oldPages := 0
+	for len(packets) < headerPackets-1 {
+		page, err := readOggPage(r)
+		if err != nil {
+			return fmt.Errorf("读取 Ogg 页面失败: %w", err)
+		}
+		if page.serial != first.serial {
+			return errors.New("不支持多路复用的 Ogg 文件")
+		}
+		oldPages++
+		offset := 0
+		for i, n := range page.segments {
+			packet = append(packet, page.body[offset:offset+int(n)]...)
+			offset += int(n)
+			if n < 255 {
+				packets = append(packets, packet)
+				packet = nil
+				if len(packets) == headerPackets-1 && i != len(page.segments)-1 {
+					return errors.New("Ogg 注释头之后的音频数据与其共用页面")
+				}
+			}
+		}
+	}
+	if !bytes.HasPrefix(packets[0], commentPrefix) {
+		return errors.New("无效的 Ogg 注释头")
+	}
+
+	content := packets[0][len(commentPrefix):]
+	vc, err := parseVorbisComments(content)
+	if err != nil {
+		return err
+	}
+	vc.apply(edit, true)
+	comment := append(slices.Clone(commentPrefix), vc.encode()...)
+	if headerPackets == 3 {
+		comment = append(comment, 1) // Vorbis 注释头的结束标志位
+	}
+	packets[0] = comment
+
+	pages := paginateOggPackets(packets, first.serial, first.sequence+1)
+	bw := bufio.NewWriter(w)
+	bw.Write(first.encode())
+	for _, page := range pages {
+		bw.Write(page.encode())
+	}
+
+	delta := uint32(len(pages) - oldPages)
+	for {
+		page, err := readOggPage(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("读取 Ogg 页面失败: %w", err)
+		}
+		if page.serial == first.serial {
+			page.sequence += delta
+		}
+		bw.Write(page.encode())
+	}
+	return bw.Flush()
+}
+
+// paginateOggPackets 将头部数据包依次分页，每页最多 255 个分段；最后一个数据包在最后一页结束，
+// 使音频数据从新的页面开始。有数据包结束的页面 granule position 为 0，否则为 -1。
+func paginateOggPackets(packets [][]byte, serial, sequence uint32) []*oggPage {
+	type segment struct {
+		data []byte
+		end  bool // 数据包在该分段结束
+	}
+	var segments []segment
+	for _, packet := range packets {
+		for len(packet) >= 255 {
+			segments = append(segments, segment{data: packet[:255]})
+			packet = packet[255:]
+		}
+		segments = append(segments, segment{data: packet, end: true})
+	}
+
+	var pages []*oggPage
+	continued := false
+	for len(segments) > 0 {
+		n := min(len(segments), 255)
+		page := &oggPage{oggPageHeader: oggPageHeader{granule: ^uint64(0), serial: serial, sequence: sequence}}
+		if continued {
+			page.flags = 0x01
+		}
+		for _, seg := range segments[:n] {
+			page.segments = append(page.segments, byte(len(seg.data)))
+			page.body = append(page.body, seg.data...)
+			if seg.end {
+				page.granule = 0
+			}
+		}
+		continued = !segments[n-1].end
+		segments = segments[n:]
+		pages = append(pages, page)
+		sequence++
+	}
+	return pages
+}
@@ -0,0 +1,387 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"zero-music/logger"
+	"zero-music/models"
+	"zero-music/repository"
+)
+
+var (
+	// ErrUploadTooLarge 表示上传的文件超过单个文件的大小上限。
+	ErrUploadTooLarge = errors.New("上传的文件超过大小上限")
+	// ErrUploadQuotaExceeded 表示上传后会超出用户的存储配额。
+	ErrUploadQuotaExceeded = errors.New("超出上传配额")
+	// ErrUnsupportedUpload 表示上传的文件既不是支持的音频格式，也不是 zip 压缩包。
+	ErrUnsupportedUpload = errors.New("不支持的文件格式")
+	// ErrInvalidAudioFile 表示文件内容与扩展名对应的音频格式不符。
+	ErrInvalidAudioFile = errors.New("文件内容与音频格式不符")
+	// ErrEmptyArchive 表示压缩包中没有支持的音频文件。
+	ErrEmptyArchive = errors.New("压缩包中没有支持的音频文件")
+)
+
+const (
+	// maxUploadArchiveEntries 是 zip 压缩包中允许的最大条目数。
+	maxUploadArchiveEntries = 2000
+	// uploadArchiveExpansion 是压缩包解压后总大小与压缩包大小的最大比值。
+	// 音频数据几乎无法再压缩，正常的专辑压缩包解压后大小接近原来的大小，超出说明是压缩炸弹。
+	uploadArchiveExpansion = 2
+	// uploadTempPattern 是上传过程中临时文件的名称模式。扩展名不是音频格式，扫描器和目录监听会忽略这些文件。
+	uploadTempPattern = ".upload-*.part"
+)
+
+// UploadedFile 是上传的一个音频文件的结果，压缩包中的每首歌曲各对应一个结果。失败时 SongID 为空。
+type UploadedFile struct {
+	// Name 是上传的文件名，压缩包中的歌曲为 "压缩包名/条目路径"
+	Name    string       `json:"name"`
+	SongID  string       `json:"song_id,omitempty"`
+	RelPath string       `json:"rel_path,omitempty"`
+	Size    int64        `json:"size,omitempty"`
+	Song    *models.Song `json:"song,omitempty"`
+	Error   string       `json:"error,omitempty"`
+	err     error
+}
+
+// Err 返回上传失败的原因。
+func (f *UploadedFile) Err() error {
+	return f.err
+}
+
+// fail 记录上传失败的原因。
+func (f *UploadedFile) fail(err error) *UploadedFile {
+	f.err = err
+	f.Error = err.Error()
+	return f
+}
+
+// Uploader 将用户上传的音频文件按路径模板放入音乐库，并立即加入歌曲库索引。
+// 每个用户上传的文件总大小受配额限制。
+type Uploader struct {
+	library      *MusicScanner
+	repo         repository.UploadRepository
+	target       Library
+	template     *models.PathTemplate
+	maxFileSize  int64
+	defaultQuota int64
+	mu           sync.Mutex // 串行化配额检查、文件放置和记录上传，避免并发上传超出配额或占用同一路径
+}
+
+// NewUploader 创建一个新的 Uploader 实例，上传的歌曲放入名为 libraryName 的音乐库。
+func NewUploader(library *MusicScanner, repo repository.UploadRepository, libraryName, pathTemplate string) (*Uploader, error) {
+	template, err := models.ParsePathTemplate(pathTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("上传路径模板无效: %w", err)
+	}
+	for _, lib := range library.Libraries() {
+		if lib.Name == libraryName {
+			return &Uploader{library: library, repo: repo, target: lib, template: template}, nil
+		}
+	}
+	return nil, fmt.Errorf("上传目标音乐库不存在: %s", libraryName)
+}
+
+// SetLimits 设置单个文件的大小上限和默认的用户配额（字节），0 表示不限制。
+func (u *Uploader) SetLimits(maxFileSize, defaultQuota int64) {
+	u.maxFileSize = maxFileSize
+	u.defaultQuota = defaultQuota
+}
+
+// Quota 返回用户的上传配额和已使用的空间。
+func (u *Uploader) Quota(userID int64) (*models.UploadQuota, error) {
+	used, err := u.repo.Usage(userID)
+	if err != nil {
+		return nil, fmt.Errorf("统计上传空间失败: %w", err)
+	}
+	limit, custom, err := u.repo.GetQuota(userID)
+	if err != nil {
+		return nil, fmt.Errorf("读取上传配额失败: %w", err)
+	}
+	if !custom {
+		limit = u.defaultQuota
+	}
+	return &models.UploadQuota{Used: used, Limit: limit, Custom: custom}, nil
+}
+
+// SetQuota 为用户单独设置配额（字节），quota 为 nil 时恢复使用默认配额。
+func (u *Uploader) SetQuota(userID int64, quota *int64) (*models.UploadQuota, error) {
+	if quota == nil {
+		if _, err := u.repo.DeleteQuota(userID); err != nil {
+			return nil, fmt.Errorf("删除上传配额失败: %w", err)
+		}
+	} else if err := u.repo.SetQuota(userID, *quota); err != nil {
+		return nil, fmt.Errorf("保存上传配额失败: %w", err)
+	}
+	return u.Quota(userID)
+}
+
+// List 返回用户上传的文件。
+func (u *Uploader) List(userID int64) ([]*models.Upload, error) {
+	uploads, err := u.repo.ListByUser(userID)
+	if err != nil {
+		return nil, fmt.Errorf("读取上传记录失败: %w", err)
+	}
+	return uploads, nil
+}
+
+// Upload 读取名为 name 的上传文件并放入音乐库。文件可以是支持的音频格式，也可以是包含一张或多张专辑的 zip 压缩包，
+// 压缩包中的每首歌曲各返回一个结果，单首歌曲失败不影响其他歌曲。文件本身不可用时（格式不支持、超过大小上限、
+// 超出配额）返回一个失败的结果；只有更新歌曲库失败时才返回错误。
+// 接收和解压在锁外进行，一个缓慢的客户端不会阻塞其他用户的上传。
+func (u *Uploader) Upload(ctx context.Context, userID int64, name string, r io.Reader) ([]*UploadedFile, error) {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	failed := func(err error) ([]*UploadedFile, error) {
+		return []*UploadedFile{(&UploadedFile{Name: name}).fail(err)}, nil
+	}
+	if !u.accepts(name) {
+		return failed(ErrUnsupportedUpload)
+	}
+
+	tmpPath, size, err := u.receive(r, u.maxFileSize)
+	if err != nil {
+		return failed(err)
+	}
+
+	staged := []*stagedFile{{name: name, path: tmpPath, size: size}}
+	if isZipArchive(name) {
+		staged, err = u.extract(name, tmpPath, size)
+		os.Remove(tmpPath)
+		if err != nil {
+			return failed(err)
+		}
+	}
+	// 放入音乐库的文件已不在原来的路径上，只删除剩下的临时文件
+	defer func() {
+		for _, file := range staged {
+			if file.path != "" {
+				os.Remove(file.path)
+			}
+		}
+	}()
+
+	results := make([]*UploadedFile, 0, len(staged))
+	for _, file := range staged {
+		if file.err != nil {
+			results = append(results, (&UploadedFile{Name: file.name}).fail(file.err))
+			continue
+		}
+		results = append(results, u.place(userID, file))
+	}
+
+	var paths []string
+	for _, result := range results {
+		if result.Err() == nil {
+			paths = append(paths, filepath.Join(u.target.Directory, filepath.FromSlash(result.RelPath)))
+		}
+	}
+	if len(paths) > 0 {
+		if _, err := u.library.ApplyChanges(ctx, paths); err != nil {
+			return nil, fmt.Errorf("更新歌曲库失败: %w", err)
+		}
+	}
+	for _, result := range results {
+		if result.Err() == nil {
+			result.Song = u.library.GetSongByID(result.SongID)
+		}
+	}
+	return results, nil
+}
+
+// accepts 判断名为 name 的文件是否可以上传：支持的音频格式或 zip 压缩包。
+func (u *Uploader) accepts(name string) bool {
+	return isZipArchive(name) || u.library.isSupported(name)
+}
+
+// isZipArchive 根据扩展名判断文件是否是 zip 压缩包。
+func isZipArchive(name string) bool {
+	return strings.EqualFold(filepath.Ext(name), ".zip")
+}
+
+// receive 将 r 的内容写入音乐库目录中的临时文件，返回临时文件路径和大小。
+// 临时文件与音乐库在同一文件系统上，放置时可以直接重命名。
+func (u *Uploader) receive(r io.Reader, limit int64) (string, int64, error) {
+	tmp, err := os.CreateTemp(u.target.Directory, uploadTempPattern)
+	if err != nil {
+		return "", 0, fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	if limit > 0 {
+		r = io.LimitReader(r, limit+1)
+	}
+	size, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && limit > 0 && size > limit {
+		err = ErrUploadTooLarge
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		if errors.Is(err, ErrUploadTooLarge) {
+			return "", 0, err
+		}
+		return "", 0, fmt.Errorf("接收上传文件失败: %w", err)
+	}
+	return tmp.Name(), size, nil
+}
+
+// stagedFile 是已接收到临时文件、等待放入音乐库的一个音频文件。
+type stagedFile struct {
+	// name 是上传的文件名，压缩包中的歌曲为 "压缩包名/条目路径"
+	name string
+	// path 是临时文件的路径，解压失败时为空
+	path string
+	size int64
+	// err 是解压失败的原因
+	err error
+}
+
+// extract 将 zip 压缩包中支持的音频文件逐个解压到临时文件，其他文件（封面图片、CUE 等）被忽略。
+// 条目在压缩包中的路径不参与放置，存放位置只由标签和路径模板决定，因此不受路径穿越的影响。
+// 返回的临时文件由调用方删除。
+func (u *Uploader) extract(name, archivePath string, size int64) ([]*stagedFile, error) {
+	archive, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: 无法读取 zip 压缩包", ErrUnsupportedUpload)
+	}
+	defer archive.Close()
+
+	if len(archive.File) > maxUploadArchiveEntries {
+		return nil, fmt.Errorf("压缩包中的文件不能超过 %d 个", maxUploadArchiveEntries)
+	}
+
+	var staged []*stagedFile
+	cleanup := func() {
+		for _, file := range staged {
+			if file.path != "" {
+				os.Remove(file.path)
+			}
+		}
+	}
+	remaining := size*uploadArchiveExpansion + 1<<20
+	for _, entry := range archive.File {
+		base := path.Base(entry.Name)
+		if entry.FileInfo().IsDir() || strings.HasPrefix(base, ".") || strings.HasPrefix(entry.Name, "__MACOSX/") || !u.library.isSupported(base) {
+			continue
+		}
+		file := &stagedFile{name: name + "/" + entry.Name}
+		limit := u.maxFileSize
+		if limit <= 0 || remaining < limit {
+			limit = remaining
+		}
+
+		file.path, file.size, file.err = u.receiveEntry(entry, limit)
+		if file.err != nil && errors.Is(file.err, ErrUploadTooLarge) && limit == remaining {
+			cleanup()
+			return nil, errors.New("压缩包解压后的大小异常")
+		}
+		remaining -= file.size
+		staged = append(staged, file)
+	}
+	if len(staged) == 0 {
+		return nil, ErrEmptyArchive
+	}
+	return staged, nil
+}
+
+// receiveEntry 将压缩包中的一个条目解压到临时文件。
+func (u *Uploader) receiveEntry(entry *zip.File, limit int64) (string, int64, error) {
+	rc, err := entry.Open()
+	if err != nil {
+		return "", 0, fmt.Errorf("解压文件失败: %w", err)
+	}
+	defer rc.Close()
+	return u.receive(rc, limit)
+}
+
+// place 检查临时文件的内容和配额，按路径模板将其移动到音乐库中并记录上传。
+// 检查内容和读取标签在锁外进行，只有配额检查、选择存放位置和记录上传需要串行化。
+func (u *Uploader) place(userID int64, file *stagedFile) *UploadedFile {
+	result := &UploadedFile{Name: file.name, Size: file.size}
+	ext := strings.ToLower(filepath.Ext(file.name))
+
+	f, err := os.Open(file.path)
+	if err != nil {
+		return result.fail(fmt.Errorf("读取上传文件失败: %w", err))
+	}
+	valid := models.HasAudioSignature(ext, f)
+	f.Close()
+	if !valid {
+		return result.fail(ErrInvalidAudioFile)
+	}
+
+	// 读取临时文件的标签来生成存放路径，没有标题标签时使用上传的文件名
+	song := models.NewSong(file.path, file.size)
+	song.Format = ext
+	song.Title = strings.TrimSuffix(path.Base(file.name), filepath.Ext(file.name))
+	if err := song.UpdateMetadata(); err != nil {
+		logger.Warnf("读取上传文件的标签失败 %s: %v", file.name, err)
+	}
+	rendered := u.template.Render(song)
+	if err := os.Chmod(file.path, 0o644); err != nil {
+		return result.fail(fmt.Errorf("设置文件权限失败: %w", err))
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	quota, err := u.Quota(userID)
+	if err != nil {
+		return result.fail(err)
+	}
+	if !quota.Allows(file.size) {
+		return result.fail(ErrUploadQuotaExceeded)
+	}
+	relPath, dest, err := u.destination(rendered, ext)
+	if err != nil {
+		return result.fail(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return result.fail(fmt.Errorf("创建目录失败: %w", err))
+	}
+	if err := os.Rename(file.path, dest); err != nil {
+		return result.fail(fmt.Errorf("移动上传文件失败: %w", err))
+	}
+	file.path = ""
+
+	upload := &models.Upload{
+		UserID:  userID,
+		SongID:  models.LibrarySongID(u.target.Name, relPath),
+		Library: u.target.Name,
+		RelPath: relPath,
+		Size:    file.size,
+	}
+	if err := u.repo.Create(upload); err != nil {
+		os.Remove(dest)
+		return result.fail(fmt.Errorf("保存上传记录失败: %w", err))
+	}
+
+	logger.Infof("用户 %d 上传了歌曲 %s", userID, relPath)
+	result.SongID, result.RelPath = upload.SongID, relPath
+	return result
+}
+
+// destination 返回渲染后的相对路径对应的、尚未被占用的库内相对路径和绝对路径。
+// 同名文件已存在时在文件名后添加 " (2)"、" (3)" 等后缀。
+func (u *Uploader) destination(rendered, ext string) (string, string, error) {
+	for n := 1; n <= 1000; n++ {
+		relPath := rendered + ext
+		if n > 1 {
+			relPath = fmt.Sprintf("%s (%d)%s", rendered, n, ext)
+		}
+		dest := filepath.Join(u.target.Directory, filepath.FromSlash(relPath))
+		if _, err := os.Lstat(dest); os.IsNotExist(err) {
+			return relPath, dest, nil
+		} else if err != nil {
+			return "", "", fmt.Errorf("检查目标文件失败: %w", err)
+		}
+	}
+	return "", "", fmt.Errorf("同名文件过多: %s", rendered+ext)
+}
@@ -0,0 +1,310 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"zero-music/models"
+)
+
+// memoryUploadRepository 是用于测试的内存上传记录仓储。
+type memoryUploadRepository struct {
+	uploads []*models.Upload
+	quotas  map[int64]int64
+}
+
+func newMemoryUploadRepository() *memoryUploadRepository {
+	return &memoryUploadRepository{quotas: make(map[int64]int64)}
+}
+
+func (r *memoryUploadRepository) Create(upload *models.Upload) error {
+	upload.ID = int64(len(r.uploads) + 1)
+	r.uploads = append(r.uploads, upload)
+	return nil
+}
+
+func (r *memoryUploadRepository) ListByUser(userID int64) ([]*models.Upload, error) {
+	var uploads []*models.Upload
+	for _, upload := range r.uploads {
+		if upload.UserID == userID {
+			uploads = append(uploads, upload)
+		}
+	}
+	return uploads, nil
+}
+
+func (r *memoryUploadRepository) Usage(userID int64) (int64, error) {
+	var used int64
+	for _, upload := range r.uploads {
+		if upload.UserID == userID {
+			used += upload.Size
+		}
+	}
+	return used, nil
+}
+
+func (r *memoryUploadRepository) GetQuota(userID int64) (int64, bool, error) {
+	quota, ok := r.quotas[userID]
+	return quota, ok, nil
+}
+
+func (r *memoryUploadRepository) SetQuota(userID int64, quota int64) error {
+	r.quotas[userID] = quota
+	return nil
+}
+
+func (r *memoryUploadRepository) DeleteQuota(userID int64) (bool, error) {
+	_, ok := r.quotas[userID]
+	delete(r.quotas, userID)
+	return ok, nil
+}
+
+func (r *memoryUploadRepository) UpdateLocation(library, relPath, newLibrary, newRelPath string) error {
+	for _, upload := range r.uploads {
+		if upload.Library == newLibrary && upload.RelPath == newRelPath {
+			return errors.New("UNIQUE constraint failed: uploads.library, uploads.rel_path")
+		}
+	}
+	for _, upload := range r.uploads {
+		if upload.Library == library && upload.RelPath == relPath {
+			upload.Library, upload.RelPath = newLibrary, newRelPath
+		}
+	}
+	return nil
+}
+
+// id3SongData 返回带有给定 ID3 文本帧的 MP3 文件内容。
+func id3SongData(t *testing.T, frames map[string]string) []byte {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "song.mp3")
+	writeID3Song(t, path, frames)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func newTestUploader(t *testing.T) (*Uploader, *MusicScanner, string) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	scanner := NewMusicScanner(tmpDir, []string{".mp3", ".flac"}, 5)
+	if _, err := scanner.Scan(context.Background()); err != nil {
+		t.Fatalf("扫描失败: %v", err)
+	}
+	uploader, err := NewUploader(scanner, newMemoryUploadRepository(), models.DefaultLibraryName, "{albumartist}/{album}/{track} {title}")
+	if err != nil {
+		t.Fatalf("创建上传服务失败: %v", err)
+	}
+	return uploader, scanner, tmpDir
+}
+
+// TestUploader_Upload 测试上传的文件按标签放入路径模板对应的位置并立即加入索引，同名文件自动加后缀。
+func TestUploader_Upload(t *testing.T) {
+	uploader, scanner, tmpDir := newTestUploader(t)
+	data := id3SongData(t, map[string]string{"TIT2": "Song", "TPE1": "Artist", "TALB": "Album", "TRCK": "3"})
+
+	for i, want := range []string{"Artist/Album/03 Song.mp3", "Artist/Album/03 Song (2).mp3"} {
+		results, err := uploader.Upload(context.Background(), 1, "upload.mp3", bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("上传失败: %v", err)
+		}
+		if len(results) != 1 || results[0].Err() != nil {
+			t.Fatalf("期望上传成功, 得到 %+v", results[0])
+		}
+		result := results[0]
+		if result.RelPath != want || result.SongID != models.LibrarySongID(models.DefaultLibraryName, want) {
+			t.Errorf("第 %d 次上传: 期望存放在 %s, 得到 %s (%s)", i+1, want, result.RelPath, result.SongID)
+		}
+		if result.Song == nil || result.Song.Title != "Song" || scanner.GetSongByID(result.SongID) == nil {
+			t.Errorf("期望歌曲立即加入索引, 得到 %+v", result.Song)
+		}
+		if _, err := os.Stat(filepath.Join(tmpDir, filepath.FromSlash(want))); err != nil {
+			t.Errorf("期望文件存在: %v", err)
+		}
+	}
+
+	// 没有标签时使用文件名作为标题
+	results, _ := uploader.Upload(context.Background(), 1, `C:\Music\My Track.mp3`, bytes.NewReader([]byte{0xFF, 0xFB, 0x90, 0x00}))
+	if err := results[0].Err(); err != nil || results[0].RelPath != "Unknown/Unknown/My Track.mp3" {
+		t.Errorf("期望使用文件名作为标题, 得到 %+v", results[0])
+	}
+
+	quota, _ := uploader.Quota(1)
+	if quota.Used != int64(2*len(data)+4) {
+		t.Errorf("期望已使用 %d 字节, 得到 %d", 2*len(data)+4, quota.Used)
+	}
+
+	// 临时文件都已清理
+	matches, _ := filepath.Glob(filepath.Join(tmpDir, ".upload-*"))
+	if len(matches) != 0 {
+		t.Errorf("期望临时文件被删除, 得到 %v", matches)
+	}
+}
+
+// TestUploader_Rejects 测试拒绝不支持的格式、内容不符的文件、超过大小上限和超出配额的上传。
+func TestUploader_Rejects(t *testing.T) {
+	uploader, scanner, _ := newTestUploader(t)
+	uploader.SetLimits(1<<20, 0)
+	song := id3SongData(t, map[string]string{"TIT2": "Song"})
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"notes.txt", []byte("text"), ErrUnsupportedUpload},
+		{"fake.mp3", []byte("<html>not audio</html>"), ErrInvalidAudioFile},
+		{"fake.flac", song, ErrInvalidAudioFile},
+		{"huge.mp3", make([]byte, 1<<20+1), ErrUploadTooLarge},
+		{"broken.zip", []byte("PK not really"), ErrUnsupportedUpload},
+	}
+	for _, tt := range tests {
+		results, err := uploader.Upload(context.Background(), 1, tt.name, bytes.NewReader(tt.data))
+		if err != nil {
+			t.Fatalf("%s: 上传返回错误: %v", tt.name, err)
+		}
+		if len(results) != 1 || !errors.Is(results[0].Err(), tt.want) || results[0].Error == "" {
+			t.Errorf("%s: 期望 %v, 得到 %+v", tt.name, tt.want, results[0])
+		}
+	}
+
+	if _, err := uploader.SetQuota(1, &[]int64{int64(len(song)) + 10}[0]); err != nil {
+		t.Fatal(err)
+	}
+	results, _ := uploader.Upload(context.Background(), 1, "a.mp3", bytes.NewReader(song))
+	if err := results[0].Err(); err != nil {
+		t.Fatalf("期望配额内的上传成功, 得到 %v", err)
+	}
+	results, _ = uploader.Upload(context.Background(), 1, "b.mp3", bytes.NewReader(song))
+	if !errors.Is(results[0].Err(), ErrUploadQuotaExceeded) {
+		t.Errorf("期望超出配额, 得到 %v", results[0].Err())
+	}
+	// 配额只统计本人的上传
+	results, _ = uploader.Upload(context.Background(), 2, "b.mp3", bytes.NewReader(song))
+	if err := results[0].Err(); err != nil {
+		t.Errorf("期望其他用户不受影响, 得到 %v", err)
+	}
+	if scanner.GetSongCount() != 2 {
+		t.Errorf("期望被拒绝的文件不进入歌曲库, 得到 %d 首歌曲", scanner.GetSongCount())
+	}
+}
+
+// TestUploader_Archive 测试上传专辑的 zip 压缩包：音频文件按标签放置，其他文件被忽略，单首歌曲失败不影响其他歌曲。
+func TestUploader_Archive(t *testing.T) {
+	uploader, scanner, _ := newTestUploader(t)
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	entries := []struct {
+		name string
+		data []byte
+	}{
+		{"Album/01.mp3", id3SongData(t, map[string]string{"TIT2": "One", "TPE1": "A", "TPE2": "Band", "TALB": "LP", "TRCK": "1"})},
+		{"Album/02.mp3", id3SongData(t, map[string]string{"TIT2": "Two", "TPE1": "B", "TPE2": "Band", "TALB": "LP", "TRCK": "2"})},
+		{"Album/../../evil.mp3", []byte("not audio")},
+		{"Album/cover.jpg", []byte("\xff\xd8\xff")},
+		{"__MACOSX/Album/._01.mp3", []byte("resource fork")},
+	}
+	for _, entry := range entries {
+		w, err := archive.Create(entry.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(entry.data)
+	}
+	archive.Close()
+
+	results, err := uploader.Upload(context.Background(), 1, "album.zip", &buf)
+	if err != nil {
+		t.Fatalf("上传失败: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("期望 3 个结果, 得到 %d", len(results))
+	}
+	var placed []string
+	for _, result := range results {
+		if result.Err() == nil {
+			placed = append(placed, result.RelPath)
+		} else if !errors.Is(result.Err(), ErrInvalidAudioFile) || !strings.HasSuffix(result.Name, "evil.mp3") {
+			t.Errorf("期望只有 evil.mp3 失败, 得到 %+v", result)
+		}
+	}
+	if strings.Join(placed, "|") != "Band/LP/01 One.mp3|Band/LP/02 Two.mp3" {
+		t.Errorf("期望按专辑艺术家放置, 得到 %v", placed)
+	}
+	if scanner.GetSongCount() != 2 {
+		t.Errorf("期望 2 首歌曲加入索引, 得到 %d", scanner.GetSongCount())
+	}
+
+	var empty bytes.Buffer
+	archive = zip.NewWriter(&empty)
+	w, _ := archive.Create("readme.txt")
+	w.Write([]byte("hello"))
+	archive.Close()
+	results, _ = uploader.Upload(context.Background(), 1, "empty.zip", &empty)
+	if !errors.Is(results[0].Err(), ErrEmptyArchive) {
+		t.Errorf("期望 ErrEmptyArchive, 得到 %v", results[0].Err())
+	}
+}
+
+// TestUploader_StalledSender 测试一个客户端停止发送数据时，其他用户的上传不被阻塞，恢复发送后该上传正常完成。
+func TestUploader_StalledSender(t *testing.T) {
+	uploader, scanner, _ := newTestUploader(t)
+	song := id3SongData(t, map[string]string{"TIT2": "Song", "TPE1": "Artist"})
+
+	reader, writer := io.Pipe()
+	stalled := make(chan []*UploadedFile, 1)
+	go func() {
+		results, _ := uploader.Upload(context.Background(), 1, "stalled.mp3", reader)
+		stalled <- results
+	}()
+	if _, err := writer.Write(song[:10]); err != nil {
+		t.Fatal(err)
+	}
+
+	// 发送方停滞期间，另外两个并发的上传可以完成
+	done := make(chan error, 2)
+	for userID := int64(2); userID <= 3; userID++ {
+		go func(userID int64) {
+			results, err := uploader.Upload(context.Background(), userID, "song.mp3", bytes.NewReader(song))
+			if err == nil {
+				err = results[0].Err()
+			}
+			done <- err
+		}(userID)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("上传失败: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("其他用户的上传被停滞的发送方阻塞")
+		}
+	}
+
+	if _, err := writer.Write(song[10:]); err != nil {
+		t.Fatal(err)
+	}
+	writer.Close()
+	select {
+	case results := <-stalled:
+		if len(results) != 1 || results[0].Err() != nil {
+			t.Fatalf("期望恢复发送后上传成功, 得到 %+v", results)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("停滞的上传没有完成")
+	}
+	if scanner.GetSongCount() != 3 {
+		t.Errorf("期望歌曲库中有 3 首歌曲, 得到 %d", scanner.GetSongCount())
+	}
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+	"zero-music/logger"
+)
+
+// errWatchUnsupported 表示当前平台不支持文件系统事件监听。
+var errWatchUnsupported = errors.New("当前平台不支持文件系统监听")
+
+// treeWatcher 是目录树文件系统事件源的抽象，Linux 下由 inotify 实现。
+type treeWatcher interface {
+	// Run 阻塞读取事件并将发生变化的路径发送到 changes，直到 ctx 取消或出现不可恢复的错误。
+	Run(ctx context.Context, changes chan<- string) error
+	// Close 释放监听占用的资源。
+	Close() error
+}
+
+// LibraryWatcher 监听扫描器中所有音乐库目录的变化并实时更新歌曲索引。
+// 一批密集的事件（例如复制整张专辑）会在防抖时间内合并后统一处理；
+// 无法创建监听（如 inotify 监听数量耗尽）时退化为周期性的增量扫描。
+type LibraryWatcher struct {
+	scanner      *MusicScanner
+	debounce     time.Duration
+	pollInterval time.Duration
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewLibraryWatcher 创建一个新的 LibraryWatcher 实例。
+func NewLibraryWatcher(scanner *MusicScanner, debounce, pollInterval time.Duration) *LibraryWatcher {
+	if debounce <= 0 {
+		debounce = 2 * time.Second
+	}
+	if pollInterval <= 0 {
+		pollInterval = 5 * time.Minute
+	}
+	return &LibraryWatcher{
+		scanner:      scanner,
+		debounce:     debounce,
+		pollInterval: pollInterval,
+	}
+}
+
+// Start 在后台启动监听。重复调用不会启动多个监听。
+func (w *LibraryWatcher) Start() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	w.cancel = cancel
+	w.done = done
+	go func() {
+		defer close(done)
+		w.run(ctx)
+	}()
+}
+
+// Stop 停止监听并等待后台任务退出。
+func (w *LibraryWatcher) Stop() {
+	w.mu.Lock()
+	cancel, done := w.cancel, w.done
+	w.cancel, w.done = nil, nil
+	w.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+// run 优先使用文件系统事件监听，任一音乐库无法监听时退化为轮询。
+func (w *LibraryWatcher) run(ctx context.Context) {
+	// 退化为轮询前需要先让各个监听的 Run 退出，因此使用独立的 context
+	watchCtx, stopWatching := context.WithCancel(ctx)
+	defer stopWatching()
+
+	var watchers []treeWatcher
+	defer func() {
+		for _, tw := range watchers {
+			tw.Close()
+		}
+	}()
+	for _, lib := range w.scanner.Libraries() {
+		tw, err := newTreeWatcher(lib.Directory)
+		if err != nil {
+			logger.Warnf("无法监听音乐库 %s，改为每 %v 轮询一次: %v", lib.Name, w.pollInterval, err)
+			w.poll(ctx)
+			return
+		}
+		watchers = append(watchers, tw)
+		logger.Infof("已开始监听音乐库 %s: %s", lib.Name, lib.Directory)
+	}
+
+	changes := make(chan string, 256)
+	runErr := make(chan error, len(watchers))
+	for _, tw := range watchers {
+		go func(tw treeWatcher) {
+			runErr <- tw.Run(watchCtx, changes)
+		}(tw)
+	}
+
+	pending := make(map[string]struct{})
+	timer := time.NewTimer(w.debounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case path := <-changes:
+			pending[path] = struct{}{}
+			timer.Reset(w.debounce)
+		case <-timer.C:
+			w.apply(ctx, pending)
+			pending = make(map[string]struct{})
+		case err := <-runErr:
+			if ctx.Err() != nil {
+				return
+			}
+			logger.Warnf("文件系统监听中断，改为每 %v 轮询一次: %v", w.pollInterval, err)
+			stopWatching()
+			w.apply(ctx, pending)
+			w.poll(ctx)
+			return
+		}
+	}
+}
+
+// apply 将防抖期间累积的路径变化应用到扫描器。
+func (w *LibraryWatcher) apply(ctx context.Context, pending map[string]struct{}) {
+	if len(pending) == 0 {
+		return
+	}
+	paths := make([]string, 0, len(pending))
+	for path := range pending {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	result, err := w.scanner.ApplyChanges(ctx, paths)
+	if err != nil {
+		logger.Warnf("应用文件变化失败: %v", err)
+		return
+	}
+	if result.Added+result.Updated+result.Removed > 0 {
+		logger.Infof("音乐目录发生变化: 新增 %d, 更新 %d, 删除 %d", result.Added, result.Updated, result.Removed)
+	}
+}
+
+// poll 周期性地执行增量扫描，直到 ctx 取消。
+func (w *LibraryWatcher) poll(ctx context.Context) {
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := w.scanner.Rescan(ctx, ScanModeIncremental); err != nil && ctx.Err() == nil {
+				logger.Warnf("轮询扫描失败: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,147 @@
+//go:build linux
+
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// inotifyMask 是每个目录监听的事件集合。
+const inotifyMask = syscall.IN_CREATE | syscall.IN_CLOSE_WRITE | syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF
+
+// inotifyWatcher 使用 inotify 递归监听整个目录树。
+type inotifyWatcher struct {
+	fd   int
+	file *os.File
+
+	mu    sync.Mutex
+	paths map[int32]string // 监听描述符 -> 目录路径
+}
+
+// newTreeWatcher 为 root 下的所有目录创建 inotify 监听。
+func newTreeWatcher(root string) (treeWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("初始化 inotify 失败: %w", err)
+	}
+
+	w := &inotifyWatcher{
+		fd: fd,
+		// 非阻塞描述符交给 os.File 后由运行时轮询器管理，Close 可以中断阻塞的 Read
+		file:  os.NewFile(uintptr(fd), "inotify"),
+		paths: make(map[int32]string),
+	}
+	if err := w.addTree(root); err != nil {
+		w.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// addTree 递归为 root 及其所有子目录添加监听。
+func (w *inotifyWatcher) addTree(root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// 子目录在遍历过程中被删除时跳过即可
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		return w.add(path)
+	})
+}
+
+// add 为单个目录添加监听。监听数量耗尽（ENOSPC）时返回错误，由调用方退化为轮询。
+func (w *inotifyWatcher) add(dir string) error {
+	wd, err := syscall.InotifyAddWatch(w.fd, dir, inotifyMask)
+	if err != nil {
+		if errors.Is(err, syscall.ENOSPC) {
+			return fmt.Errorf("inotify 监听数量已达上限 (fs.inotify.max_user_watches): %w", err)
+		}
+		return fmt.Errorf("监听目录 %s 失败: %w", dir, err)
+	}
+	w.mu.Lock()
+	w.paths[int32(wd)] = dir
+	w.mu.Unlock()
+	return nil
+}
+
+// Run 读取 inotify 事件并将变化的路径发送到 changes。
+func (w *inotifyWatcher) Run(ctx context.Context, changes chan<- string) error {
+	go func() {
+		<-ctx.Done()
+		w.file.Close()
+	}()
+
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("读取 inotify 事件失败: %w", err)
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameBytes := buf[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(event.Len)]
+			offset += syscall.SizeofInotifyEvent + int(event.Len)
+
+			if event.Mask&syscall.IN_Q_OVERFLOW != 0 {
+				return errors.New("inotify 事件队列溢出")
+			}
+
+			w.mu.Lock()
+			dir, ok := w.paths[event.Wd]
+			if event.Mask&syscall.IN_IGNORED != 0 {
+				delete(w.paths, event.Wd)
+			}
+			w.mu.Unlock()
+			if !ok {
+				continue
+			}
+
+			path := dir
+			if name := string(bytes.TrimRight(nameBytes, "\x00")); name != "" {
+				path = filepath.Join(dir, name)
+			}
+
+			// 新建或移入的目录需要递归补充监听，其中已有的文件由扫描器在处理该目录路径时一并发现
+			if event.Mask&syscall.IN_ISDIR != 0 && event.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 {
+				if err := w.addTree(path); err != nil {
+					return err
+				}
+			}
+
+			if event.Mask&syscall.IN_IGNORED != 0 {
+				continue
+			}
+
+			select {
+			case changes <- path:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// Close 关闭 inotify 描述符，所有监听随之释放。
+func (w *inotifyWatcher) Close() error {
+	return w.file.Close()
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package services
+
+// newTreeWatcher 在非 Linux 平台上不可用，LibraryWatcher 会退化为轮询。
+func newTreeWatcher(root string) (treeWatcher, error) {
+	return nil, errWatchUnsupported
+}
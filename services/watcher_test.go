@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// waitForSongCount 在超时前轮询等待扫描器中的歌曲数量达到期望值。
+func waitForSongCount(t *testing.T, scanner *MusicScanner, want int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if scanner.GetSongCount() == want {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("期望歌曲数量为 %d, 得到 %d", want, scanner.GetSongCount())
+}
+
+// TestLibraryWatcher_NestedChanges 测试监听器能发现嵌套目录中新增和删除的文件。
+func TestLibraryWatcher_NestedChanges(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("文件系统事件监听仅在 Linux 上可用")
+	}
+
+	tmpDir := t.TempDir()
+	scanner := NewMusicScanner(tmpDir, []string{".mp3"}, 5)
+	if _, err := scanner.Rescan(context.Background(), ScanModeFull); err != nil {
+		t.Fatalf("扫描失败: %v", err)
+	}
+
+	watcher := NewLibraryWatcher(scanner, 50*time.Millisecond, time.Hour)
+	watcher.Start()
+	defer watcher.Stop()
+
+	// 等待监听建立
+	time.Sleep(100 * time.Millisecond)
+
+	albumDir := filepath.Join(tmpDir, "artist", "album")
+	if err := os.MkdirAll(albumDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	trackPath := filepath.Join(albumDir, "01.mp3")
+	if err := os.WriteFile(trackPath, []byte("fake mp3"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitForSongCount(t, scanner, 1)
+
+	if err := os.Remove(trackPath); err != nil {
+		t.Fatal(err)
+	}
+	waitForSongCount(t, scanner, 0)
+}
+
+// TestLibraryWatcher_StopIsIdempotent 测试重复停止监听不会阻塞或 panic。
+func TestLibraryWatcher_StopIsIdempotent(t *testing.T) {
+	scanner := NewMusicScanner(t.TempDir(), []string{".mp3"}, 5)
+	watcher := NewLibraryWatcher(scanner, 0, 0)
+	watcher.Start()
+	watcher.Stop()
+	watcher.Stop()
+}
@@ -22,8 +22,12 @@ func GetAudioMimeType(filename string) string {
 			return "audio/wav"
 		case ".m4a":
 			return "audio/mp4"
-		case ".ogg":
+		case ".ogg", ".oga":
 			return "audio/ogg"
+		case ".opus":
+			return "audio/opus"
+		case ".aif", ".aiff", ".aifc":
+			return "audio/aiff"
 		default:
 			return "application/octet-stream"
 		}